@@ -56,9 +56,32 @@ type Client interface {
 	// QueryImages retrieves the image information tracked by the Kelda daemon.
 	QueryImages() ([]db.Image, error)
 
+	// QueryMetrics retrieves the latest resource usage metrics of the machines
+	// and pods tracked by the Kelda daemon.
+	QueryMetrics() ([]db.Metrics, error)
+
+	// Profile collects a profile of the given type from the Kelda daemon. CPU
+	// profiles are sampled for the given duration.
+	Profile(profileType string, duration time.Duration) ([]byte, error)
+
+	// ProfileMinion collects a profile of the given type from a Kelda minion.
+	// Only defined on the daemon.
+	ProfileMinion(host, profileType string, duration time.Duration) (
+		[]byte, error)
+
 	// SetSecret sets the value of a named secret in the cluster. The value is
-	// encrypted and stored in Vault.
-	SetSecret(name, value string) error
+	// stored as a Kubernetes secret. If allowedHostnames is non-empty, only
+	// the containers with those hostnames may use the secret ("*" allows any
+	// container). Otherwise, the hostnames allowed to use an existing secret
+	// are unchanged.
+	SetSecret(name, value string, allowedHostnames []string) error
+
+	// ListSecrets retrieves the names and last modified times of the secrets
+	// in the cluster.
+	ListSecrets() (map[string]time.Time, error)
+
+	// DeleteSecret removes a named secret from the cluster.
+	DeleteSecret(name string) error
 
 	// Deploy makes a request to the Kelda daemon to deploy the given deployment.
 	// Only defined on the daemon.
@@ -162,6 +185,13 @@ func (c clientImpl) QueryImages() ([]db.Image, error) {
 	return rows, query(c.pbClient, db.ImageTable, &rows)
 }
 
+// QueryMetrics retrieves the latest resource usage metrics of the machines and
+// pods tracked by the Kelda daemon.
+func (c clientImpl) QueryMetrics() ([]db.Metrics, error) {
+	var rows []db.Metrics
+	return rows, query(c.pbClient, db.MetricsTable, &rows)
+}
+
 // QueryCounters retrieves the debugging counters tracked with the Kelda daemon.
 func (c clientImpl) QueryCounters() ([]pb.Counter, error) {
 	ctx, _ := context.WithTimeout(context.Background(), requestTimeout)
@@ -192,9 +222,61 @@ func parseCountersReply(reply *pb.CountersReply) (counters []pb.Counter) {
 	return counters
 }
 
-func (c clientImpl) SetSecret(name, value string) error {
+// Profile collects a profile of the given type from the Kelda daemon.
+func (c clientImpl) Profile(profileType string, duration time.Duration) (
+	[]byte, error) {
+	// The request blocks while CPU profiles are sampled.
+	ctx, _ := context.WithTimeout(context.Background(),
+		requestTimeout+duration)
+	reply, err := c.pbClient.Profile(ctx, &pb.ProfileRequest{
+		Type: profileType, Duration: int64(duration)})
+	if err != nil {
+		return nil, err
+	}
+	return reply.Profile, nil
+}
+
+// ProfileMinion collects a profile of the given type from a Kelda minion.
+func (c clientImpl) ProfileMinion(host, profileType string,
+	duration time.Duration) ([]byte, error) {
+	ctx, _ := context.WithTimeout(context.Background(),
+		requestTimeout+duration)
+	reply, err := c.pbClient.ProfileMinion(ctx, &pb.MinionProfileRequest{
+		Host: host, Type: profileType, Duration: int64(duration)})
+	if err != nil {
+		return nil, err
+	}
+	return reply.Profile, nil
+}
+
+func (c clientImpl) SetSecret(name, value string,
+	allowedHostnames []string) error {
+	ctx, _ := context.WithTimeout(context.Background(), requestTimeout)
+	_, err := c.pbClient.SetSecret(ctx, &pb.Secret{
+		Name: name, Value: []byte(value), AllowedHostnames: allowedHostnames})
+	return err
+}
+
+// ListSecrets retrieves the names and last modified times of the secrets in
+// the cluster.
+func (c clientImpl) ListSecrets() (map[string]time.Time, error) {
+	ctx, _ := context.WithTimeout(context.Background(), requestTimeout)
+	reply, err := c.pbClient.ListSecrets(ctx, &pb.ListSecretsRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := map[string]time.Time{}
+	for _, secret := range reply.Secrets {
+		secrets[secret.Name] = time.Unix(secret.LastModified, 0)
+	}
+	return secrets, nil
+}
+
+// DeleteSecret removes a named secret from the cluster.
+func (c clientImpl) DeleteSecret(name string) error {
 	ctx, _ := context.WithTimeout(context.Background(), requestTimeout)
-	_, err := c.pbClient.SetSecret(ctx, &pb.Secret{Name: name, Value: value})
+	_, err := c.pbClient.DeleteSecret(ctx, &pb.DeleteSecretRequest{Name: name})
 	return err
 }
 
@@ -3,6 +3,7 @@ package client
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/net/context"
@@ -53,6 +54,35 @@ func (c mockAPIClient) SetSecret(ctx context.Context, in *pb.Secret,
 	return &pb.SecretReply{}, nil
 }
 
+func (c mockAPIClient) ListSecrets(ctx context.Context,
+	in *pb.ListSecretsRequest, opts ...grpc.CallOption) (
+	*pb.ListSecretsReply, error) {
+
+	return &pb.ListSecretsReply{Secrets: []*pb.SecretInfo{
+		{Name: c.mockResponse, LastModified: 100},
+	}}, c.mockError
+}
+
+func (c mockAPIClient) DeleteSecret(ctx context.Context,
+	in *pb.DeleteSecretRequest, opts ...grpc.CallOption) (
+	*pb.SecretReply, error) {
+
+	return &pb.SecretReply{}, c.mockError
+}
+
+func (c mockAPIClient) Profile(ctx context.Context, in *pb.ProfileRequest,
+	opts ...grpc.CallOption) (*pb.ProfileReply, error) {
+
+	return &pb.ProfileReply{Profile: []byte(c.mockResponse)}, c.mockError
+}
+
+func (c mockAPIClient) ProfileMinion(ctx context.Context,
+	in *pb.MinionProfileRequest, opts ...grpc.CallOption) (
+	*pb.ProfileReply, error) {
+
+	return &pb.ProfileReply{Profile: []byte(c.mockResponse)}, c.mockError
+}
+
 func TestUnmarshalMachine(t *testing.T) {
 	t.Parallel()
 
@@ -115,6 +145,21 @@ func TestUnmarshalImage(t *testing.T) {
 	}, res)
 }
 
+func TestUnmarshalMetrics(t *testing.T) {
+	t.Parallel()
+
+	apiClient := mockAPIClient{
+		mockResponse: `[{"ID":1,"Machine":"m","PodName":"pod",` +
+			`"Time":"0001-01-01T00:00:00Z","CPUPercent":12.5,` +
+			`"MemoryUsed":10,"MemoryTotal":20,"NetworkRx":1}]`,
+	}
+	c := clientImpl{pbClient: apiClient}
+	res, err := c.QueryMetrics()
+	assert.NoError(t, err)
+	assert.Equal(t, []db.Metrics{{ID: 1, Machine: "m", PodName: "pod",
+		CPUPercent: 12.5, MemoryUsed: 10, MemoryTotal: 20, NetworkRx: 1}}, res)
+}
+
 func TestUnmarshalError(t *testing.T) {
 	t.Parallel()
 
@@ -139,3 +184,34 @@ func TestGrpcError(t *testing.T) {
 	_, err := c.QueryMachines()
 	assert.EqualError(t, err, "timeout")
 }
+
+func TestProfile(t *testing.T) {
+	t.Parallel()
+
+	c := clientImpl{pbClient: mockAPIClient{mockResponse: "profile"}}
+	res, err := c.Profile("heap", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("profile"), res)
+
+	res, err = c.ProfileMinion("host", "cpu", time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("profile"), res)
+
+	c = clientImpl{pbClient: mockAPIClient{mockError: assert.AnError}}
+	_, err = c.Profile("heap", 0)
+	assert.Equal(t, assert.AnError, err)
+}
+
+func TestListSecrets(t *testing.T) {
+	t.Parallel()
+
+	c := clientImpl{pbClient: mockAPIClient{mockResponse: "secret"}}
+	res, err := c.ListSecrets()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]time.Time{"secret": time.Unix(100, 0)}, res)
+
+	c = clientImpl{pbClient: mockAPIClient{mockError: assert.AnError}}
+	_, err = c.ListSecrets()
+	assert.Equal(t, assert.AnError, err)
+	assert.Equal(t, assert.AnError, c.DeleteSecret("secret"))
+}
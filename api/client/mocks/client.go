@@ -5,6 +5,7 @@ package mocks
 import db "github.com/kelda/kelda/db"
 import mock "github.com/stretchr/testify/mock"
 import pb "github.com/kelda/kelda/api/pb"
+import time "time"
 
 // Client is an autogenerated mock type for the Client type
 type Client struct {
@@ -39,6 +40,90 @@ func (_m *Client) Deploy(deployment string) error {
 	return r0
 }
 
+// DeleteSecret provides a mock function with given fields: name
+func (_m *Client) DeleteSecret(name string) error {
+	ret := _m.Called(name)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(name)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ListSecrets provides a mock function with given fields:
+func (_m *Client) ListSecrets() (map[string]time.Time, error) {
+	ret := _m.Called()
+
+	var r0 map[string]time.Time
+	if rf, ok := ret.Get(0).(func() map[string]time.Time); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]time.Time)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Profile provides a mock function with given fields: profileType, duration
+func (_m *Client) Profile(profileType string, duration time.Duration) ([]byte, error) {
+	ret := _m.Called(profileType, duration)
+
+	var r0 []byte
+	if rf, ok := ret.Get(0).(func(string, time.Duration) []byte); ok {
+		r0 = rf(profileType, duration)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, time.Duration) error); ok {
+		r1 = rf(profileType, duration)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ProfileMinion provides a mock function with given fields: host, profileType, duration
+func (_m *Client) ProfileMinion(host string, profileType string,
+	duration time.Duration) ([]byte, error) {
+	ret := _m.Called(host, profileType, duration)
+
+	var r0 []byte
+	if rf, ok := ret.Get(0).(func(string, string, time.Duration) []byte); ok {
+		r0 = rf(host, profileType, duration)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, time.Duration) error); ok {
+		r1 = rf(host, profileType, duration)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // QueryBlueprints provides a mock function with given fields:
 func (_m *Client) QueryBlueprints() ([]db.Blueprint, error) {
 	ret := _m.Called()
@@ -223,6 +308,29 @@ func (_m *Client) QueryMachines() ([]db.Machine, error) {
 	return r0, r1
 }
 
+// QueryMetrics provides a mock function with given fields:
+func (_m *Client) QueryMetrics() ([]db.Metrics, error) {
+	ret := _m.Called()
+
+	var r0 []db.Metrics
+	if rf, ok := ret.Get(0).(func() []db.Metrics); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]db.Metrics)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // QueryMinionCounters provides a mock function with given fields: _a0
 func (_m *Client) QueryMinionCounters(_a0 string) ([]pb.Counter, error) {
 	ret := _m.Called(_a0)
@@ -246,13 +354,13 @@ func (_m *Client) QueryMinionCounters(_a0 string) ([]pb.Counter, error) {
 	return r0, r1
 }
 
-// SetSecret provides a mock function with given fields: name, value
-func (_m *Client) SetSecret(name string, value string) error {
-	ret := _m.Called(name, value)
+// SetSecret provides a mock function with given fields: name, value, allowedHostnames
+func (_m *Client) SetSecret(name string, value string, allowedHostnames []string) error {
+	ret := _m.Called(name, value, allowedHostnames)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string, string) error); ok {
-		r0 = rf(name, value)
+	if rf, ok := ret.Get(0).(func(string, string, []string) error); ok {
+		r0 = rf(name, value, allowedHostnames)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -20,6 +20,13 @@ It has these top-level messages:
 	MinionCountersRequest
 	CountersReply
 	Counter
+	ProfileRequest
+	MinionProfileRequest
+	ProfileReply
+	ListSecretsRequest
+	ListSecretsReply
+	SecretInfo
+	DeleteSecretRequest
 */
 package pb
 
@@ -44,8 +51,9 @@ var _ = math.Inf
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
 type Secret struct {
-	Name  string `protobuf:"bytes,1,opt,name=Name" json:"Name,omitempty"`
-	Value string `protobuf:"bytes,2,opt,name=Value" json:"Value,omitempty"`
+	Name             string   `protobuf:"bytes,1,opt,name=Name" json:"Name,omitempty"`
+	Value            []byte   `protobuf:"bytes,2,opt,name=Value,proto3" json:"Value,omitempty"`
+	AllowedHostnames []string `protobuf:"bytes,3,rep,name=AllowedHostnames" json:"AllowedHostnames,omitempty"`
 }
 
 func (m *Secret) Reset()                    { *m = Secret{} }
@@ -60,11 +68,18 @@ func (m *Secret) GetName() string {
 	return ""
 }
 
-func (m *Secret) GetValue() string {
+func (m *Secret) GetValue() []byte {
 	if m != nil {
 		return m.Value
 	}
-	return ""
+	return nil
+}
+
+func (m *Secret) GetAllowedHostnames() []string {
+	if m != nil {
+		return m.AllowedHostnames
+	}
+	return nil
 }
 
 type SecretReply struct {
@@ -235,6 +250,143 @@ func (m *Counter) GetPrevValue() uint64 {
 	return 0
 }
 
+type ProfileRequest struct {
+	Type     string `protobuf:"bytes,1,opt,name=Type" json:"Type,omitempty"`
+	Duration int64  `protobuf:"varint,2,opt,name=Duration" json:"Duration,omitempty"`
+}
+
+func (m *ProfileRequest) Reset()                    { *m = ProfileRequest{} }
+func (m *ProfileRequest) String() string            { return proto.CompactTextString(m) }
+func (*ProfileRequest) ProtoMessage()               {}
+func (*ProfileRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{12} }
+
+func (m *ProfileRequest) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *ProfileRequest) GetDuration() int64 {
+	if m != nil {
+		return m.Duration
+	}
+	return 0
+}
+
+type MinionProfileRequest struct {
+	Host     string `protobuf:"bytes,1,opt,name=Host" json:"Host,omitempty"`
+	Type     string `protobuf:"bytes,2,opt,name=Type" json:"Type,omitempty"`
+	Duration int64  `protobuf:"varint,3,opt,name=Duration" json:"Duration,omitempty"`
+}
+
+func (m *MinionProfileRequest) Reset()                    { *m = MinionProfileRequest{} }
+func (m *MinionProfileRequest) String() string            { return proto.CompactTextString(m) }
+func (*MinionProfileRequest) ProtoMessage()               {}
+func (*MinionProfileRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{13} }
+
+func (m *MinionProfileRequest) GetHost() string {
+	if m != nil {
+		return m.Host
+	}
+	return ""
+}
+
+func (m *MinionProfileRequest) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *MinionProfileRequest) GetDuration() int64 {
+	if m != nil {
+		return m.Duration
+	}
+	return 0
+}
+
+type ProfileReply struct {
+	Profile []byte `protobuf:"bytes,1,opt,name=Profile,proto3" json:"Profile,omitempty"`
+}
+
+func (m *ProfileReply) Reset()                    { *m = ProfileReply{} }
+func (m *ProfileReply) String() string            { return proto.CompactTextString(m) }
+func (*ProfileReply) ProtoMessage()               {}
+func (*ProfileReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{14} }
+
+func (m *ProfileReply) GetProfile() []byte {
+	if m != nil {
+		return m.Profile
+	}
+	return nil
+}
+
+type ListSecretsRequest struct {
+}
+
+func (m *ListSecretsRequest) Reset()                    { *m = ListSecretsRequest{} }
+func (m *ListSecretsRequest) String() string            { return proto.CompactTextString(m) }
+func (*ListSecretsRequest) ProtoMessage()               {}
+func (*ListSecretsRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{15} }
+
+type ListSecretsReply struct {
+	Secrets []*SecretInfo `protobuf:"bytes,1,rep,name=Secrets" json:"Secrets,omitempty"`
+}
+
+func (m *ListSecretsReply) Reset()                    { *m = ListSecretsReply{} }
+func (m *ListSecretsReply) String() string            { return proto.CompactTextString(m) }
+func (*ListSecretsReply) ProtoMessage()               {}
+func (*ListSecretsReply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{16} }
+
+func (m *ListSecretsReply) GetSecrets() []*SecretInfo {
+	if m != nil {
+		return m.Secrets
+	}
+	return nil
+}
+
+type SecretInfo struct {
+	Name string `protobuf:"bytes,1,opt,name=Name" json:"Name,omitempty"`
+	// The Unix time in seconds at which the secret was last set.
+	LastModified int64 `protobuf:"varint,2,opt,name=LastModified" json:"LastModified,omitempty"`
+}
+
+func (m *SecretInfo) Reset()                    { *m = SecretInfo{} }
+func (m *SecretInfo) String() string            { return proto.CompactTextString(m) }
+func (*SecretInfo) ProtoMessage()               {}
+func (*SecretInfo) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{17} }
+
+func (m *SecretInfo) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *SecretInfo) GetLastModified() int64 {
+	if m != nil {
+		return m.LastModified
+	}
+	return 0
+}
+
+type DeleteSecretRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=Name" json:"Name,omitempty"`
+}
+
+func (m *DeleteSecretRequest) Reset()                    { *m = DeleteSecretRequest{} }
+func (m *DeleteSecretRequest) String() string            { return proto.CompactTextString(m) }
+func (*DeleteSecretRequest) ProtoMessage()               {}
+func (*DeleteSecretRequest) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{18} }
+
+func (m *DeleteSecretRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*Secret)(nil), "Secret")
 	proto.RegisterType((*SecretReply)(nil), "SecretReply")
@@ -248,6 +400,13 @@ func init() {
 	proto.RegisterType((*MinionCountersRequest)(nil), "MinionCountersRequest")
 	proto.RegisterType((*CountersReply)(nil), "CountersReply")
 	proto.RegisterType((*Counter)(nil), "Counter")
+	proto.RegisterType((*ProfileRequest)(nil), "ProfileRequest")
+	proto.RegisterType((*MinionProfileRequest)(nil), "MinionProfileRequest")
+	proto.RegisterType((*ProfileReply)(nil), "ProfileReply")
+	proto.RegisterType((*ListSecretsRequest)(nil), "ListSecretsRequest")
+	proto.RegisterType((*ListSecretsReply)(nil), "ListSecretsReply")
+	proto.RegisterType((*SecretInfo)(nil), "SecretInfo")
+	proto.RegisterType((*DeleteSecretRequest)(nil), "DeleteSecretRequest")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -266,9 +425,13 @@ type APIClient interface {
 	Version(ctx context.Context, in *VersionRequest, opts ...grpc.CallOption) (*VersionReply, error)
 	QueryCounters(ctx context.Context, in *CountersRequest, opts ...grpc.CallOption) (*CountersReply, error)
 	SetSecret(ctx context.Context, in *Secret, opts ...grpc.CallOption) (*SecretReply, error)
+	ListSecrets(ctx context.Context, in *ListSecretsRequest, opts ...grpc.CallOption) (*ListSecretsReply, error)
+	DeleteSecret(ctx context.Context, in *DeleteSecretRequest, opts ...grpc.CallOption) (*SecretReply, error)
+	Profile(ctx context.Context, in *ProfileRequest, opts ...grpc.CallOption) (*ProfileReply, error)
 	// Only defined on the daemon.
 	Deploy(ctx context.Context, in *DeployRequest, opts ...grpc.CallOption) (*DeployReply, error)
 	QueryMinionCounters(ctx context.Context, in *MinionCountersRequest, opts ...grpc.CallOption) (*CountersReply, error)
+	ProfileMinion(ctx context.Context, in *MinionProfileRequest, opts ...grpc.CallOption) (*ProfileReply, error)
 }
 
 type aPIClient struct {
@@ -315,6 +478,33 @@ func (c *aPIClient) SetSecret(ctx context.Context, in *Secret, opts ...grpc.Call
 	return out, nil
 }
 
+func (c *aPIClient) ListSecrets(ctx context.Context, in *ListSecretsRequest, opts ...grpc.CallOption) (*ListSecretsReply, error) {
+	out := new(ListSecretsReply)
+	err := grpc.Invoke(ctx, "/API/ListSecrets", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) DeleteSecret(ctx context.Context, in *DeleteSecretRequest, opts ...grpc.CallOption) (*SecretReply, error) {
+	out := new(SecretReply)
+	err := grpc.Invoke(ctx, "/API/DeleteSecret", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) Profile(ctx context.Context, in *ProfileRequest, opts ...grpc.CallOption) (*ProfileReply, error) {
+	out := new(ProfileReply)
+	err := grpc.Invoke(ctx, "/API/Profile", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *aPIClient) Deploy(ctx context.Context, in *DeployRequest, opts ...grpc.CallOption) (*DeployReply, error) {
 	out := new(DeployReply)
 	err := grpc.Invoke(ctx, "/API/Deploy", in, out, c.cc, opts...)
@@ -333,6 +523,15 @@ func (c *aPIClient) QueryMinionCounters(ctx context.Context, in *MinionCountersR
 	return out, nil
 }
 
+func (c *aPIClient) ProfileMinion(ctx context.Context, in *MinionProfileRequest, opts ...grpc.CallOption) (*ProfileReply, error) {
+	out := new(ProfileReply)
+	err := grpc.Invoke(ctx, "/API/ProfileMinion", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for API service
 
 type APIServer interface {
@@ -341,9 +540,13 @@ type APIServer interface {
 	Version(context.Context, *VersionRequest) (*VersionReply, error)
 	QueryCounters(context.Context, *CountersRequest) (*CountersReply, error)
 	SetSecret(context.Context, *Secret) (*SecretReply, error)
+	ListSecrets(context.Context, *ListSecretsRequest) (*ListSecretsReply, error)
+	DeleteSecret(context.Context, *DeleteSecretRequest) (*SecretReply, error)
+	Profile(context.Context, *ProfileRequest) (*ProfileReply, error)
 	// Only defined on the daemon.
 	Deploy(context.Context, *DeployRequest) (*DeployReply, error)
 	QueryMinionCounters(context.Context, *MinionCountersRequest) (*CountersReply, error)
+	ProfileMinion(context.Context, *MinionProfileRequest) (*ProfileReply, error)
 }
 
 func RegisterAPIServer(s *grpc.Server, srv APIServer) {
@@ -422,6 +625,60 @@ func _API_SetSecret_Handler(srv interface{}, ctx context.Context, dec func(inter
 	return interceptor(ctx, in, info, handler)
 }
 
+func _API_ListSecrets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSecretsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).ListSecrets(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/API/ListSecrets",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).ListSecrets(ctx, req.(*ListSecretsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _API_DeleteSecret_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteSecretRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).DeleteSecret(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/API/DeleteSecret",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).DeleteSecret(ctx, req.(*DeleteSecretRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _API_Profile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProfileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).Profile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/API/Profile",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).Profile(ctx, req.(*ProfileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _API_Deploy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(DeployRequest)
 	if err := dec(in); err != nil {
@@ -458,6 +715,24 @@ func _API_QueryMinionCounters_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _API_ProfileMinion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MinionProfileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).ProfileMinion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/API/ProfileMinion",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).ProfileMinion(ctx, req.(*MinionProfileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _API_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "API",
 	HandlerType: (*APIServer)(nil),
@@ -478,6 +753,18 @@ var _API_serviceDesc = grpc.ServiceDesc{
 			MethodName: "SetSecret",
 			Handler:    _API_SetSecret_Handler,
 		},
+		{
+			MethodName: "ListSecrets",
+			Handler:    _API_ListSecrets_Handler,
+		},
+		{
+			MethodName: "DeleteSecret",
+			Handler:    _API_DeleteSecret_Handler,
+		},
+		{
+			MethodName: "Profile",
+			Handler:    _API_Profile_Handler,
+		},
 		{
 			MethodName: "Deploy",
 			Handler:    _API_Deploy_Handler,
@@ -486,6 +773,10 @@ var _API_serviceDesc = grpc.ServiceDesc{
 			MethodName: "QueryMinionCounters",
 			Handler:    _API_QueryMinionCounters_Handler,
 		},
+		{
+			MethodName: "ProfileMinion",
+			Handler:    _API_ProfileMinion_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "pb/pb.proto",
@@ -494,31 +785,44 @@ var _API_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("pb/pb.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 402 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x53, 0x4d, 0xaf, 0x9a, 0x40,
-	0x14, 0x05, 0xf1, 0x89, 0x5e, 0xc4, 0x67, 0x6f, 0x3f, 0x42, 0x48, 0xd3, 0x92, 0xc9, 0x5b, 0x90,
-	0x98, 0x8c, 0x09, 0xa6, 0xeb, 0xa6, 0xd5, 0x45, 0xbb, 0x68, 0x63, 0xb1, 0x71, 0x2f, 0x66, 0xd2,
-	0x98, 0x22, 0x43, 0x61, 0x68, 0xe2, 0x5f, 0xe9, 0xaf, 0x6d, 0x98, 0x19, 0x10, 0x7c, 0xee, 0xee,
-	0x3d, 0xf7, 0x23, 0x67, 0xce, 0xb9, 0x03, 0x4e, 0x9e, 0x2c, 0xf3, 0x84, 0xe6, 0x05, 0x17, 0x9c,
-	0x44, 0x30, 0xda, 0xb1, 0x63, 0xc1, 0x04, 0x22, 0x0c, 0xbf, 0x1f, 0xce, 0xcc, 0x33, 0x03, 0x33,
-	0x9c, 0xc4, 0x32, 0xc6, 0x57, 0xf0, 0xb0, 0x3f, 0xa4, 0x15, 0xf3, 0x06, 0x12, 0x54, 0x09, 0x71,
-	0xc1, 0x51, 0x33, 0x31, 0xcb, 0xd3, 0x0b, 0x79, 0x0f, 0xf6, 0xe6, 0xf3, 0x8f, 0x8a, 0x15, 0x97,
-	0xba, 0xff, 0xe7, 0x21, 0x49, 0x9b, 0x25, 0x2a, 0x21, 0x11, 0x80, 0x2c, 0xcb, 0x76, 0x7c, 0x02,
-	0x57, 0xc2, 0x6b, 0x9e, 0x09, 0x96, 0x89, 0x52, 0xf7, 0xf6, 0x41, 0xb2, 0x04, 0x77, 0xc3, 0xf2,
-	0x94, 0x5f, 0x62, 0xf6, 0xa7, 0x62, 0xa5, 0xc0, 0x77, 0x00, 0x0a, 0x38, 0xb3, 0x4c, 0xe8, 0x99,
-	0x0e, 0x52, 0x93, 0x6a, 0x06, 0x6a, 0x52, 0x73, 0x98, 0xed, 0x59, 0x51, 0x9e, 0x78, 0xa6, 0x17,
-	0x90, 0x10, 0xa6, 0x2d, 0x52, 0xf3, 0xf0, 0xc0, 0xd6, 0xb9, 0xde, 0xd6, 0xa4, 0xe4, 0x05, 0x3c,
-	0xae, 0x79, 0x95, 0x09, 0x56, 0x94, 0xcd, 0xf0, 0x02, 0x5e, 0x7f, 0x3b, 0x65, 0x27, 0x9e, 0xdd,
-	0x14, 0x6a, 0xd5, 0xbe, 0xf0, 0xb2, 0x21, 0x24, 0x63, 0xf2, 0x01, 0xdc, 0x6b, 0x9b, 0x7a, 0xf2,
-	0xf8, 0xa8, 0x01, 0xcf, 0x0c, 0xac, 0xd0, 0x89, 0xc6, 0x54, 0x77, 0xc4, 0x6d, 0x85, 0x1c, 0xc1,
-	0xd6, 0x20, 0xce, 0xc1, 0xda, 0xfe, 0xfe, 0xa5, 0x97, 0xd6, 0x61, 0xeb, 0xce, 0xe0, 0x9e, 0x3b,
-	0x56, 0x60, 0x86, 0x43, 0xed, 0x0e, 0xbe, 0x85, 0xc9, 0xb6, 0x60, 0x7f, 0x55, 0x65, 0x28, 0x2b,
-	0x57, 0x20, 0xfa, 0x37, 0x00, 0xeb, 0xd3, 0xf6, 0x2b, 0x06, 0xf0, 0xa0, 0x2c, 0x1b, 0x53, 0x6d,
-	0x9e, 0xef, 0xd0, 0xab, 0x4b, 0xc4, 0xc0, 0x45, 0xab, 0x0f, 0x3e, 0xd2, 0xbe, 0x96, 0xbe, 0x4b,
-	0xbb, 0x52, 0x12, 0x03, 0x57, 0xe0, 0xca, 0xe1, 0xe6, 0xdd, 0x38, 0xa7, 0x37, 0x4a, 0xf9, 0x33,
-	0xda, 0x13, 0x85, 0x18, 0xf8, 0x04, 0x93, 0x1d, 0x13, 0xfa, 0xfc, 0x6c, 0xaa, 0x02, 0x7f, 0x4a,
-	0xbb, 0xc7, 0x65, 0x60, 0x08, 0x23, 0x65, 0x2c, 0xce, 0x68, 0xef, 0x24, 0xfc, 0x29, 0xed, 0x3a,
-	0x6e, 0xe0, 0x47, 0x78, 0x29, 0x49, 0xf4, 0x9d, 0xc2, 0x37, 0xf4, 0xae, 0x75, 0xcf, 0x09, 0x25,
-	0x23, 0xf9, 0x27, 0x56, 0xff, 0x03, 0x00, 0x00, 0xff, 0xff, 0x64, 0xa0, 0x22, 0xf6, 0x22, 0x03,
-	0x00, 0x00,
+	// 619 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x54, 0xd1, 0x6e, 0xd3, 0x30,
+	0x14, 0x4d, 0x97, 0x6d, 0x6d, 0x6f, 0x93, 0xae, 0xbb, 0xeb, 0x50, 0x15, 0x21, 0x88, 0xac, 0x21,
+	0x05, 0x26, 0x79, 0xd2, 0x06, 0x9a, 0x78, 0x82, 0xb1, 0x3e, 0x30, 0x69, 0x43, 0x25, 0x9b, 0xf6,
+	0xc0, 0x03, 0x52, 0xba, 0x79, 0x28, 0x22, 0x8d, 0x43, 0xe2, 0x82, 0xfa, 0xd5, 0xfc, 0x02, 0x72,
+	0xec, 0xa4, 0x49, 0x1a, 0xde, 0x7c, 0xcf, 0xb5, 0x8f, 0x9d, 0x73, 0xcf, 0x09, 0x0c, 0x92, 0xf9,
+	0x49, 0x32, 0xa7, 0x49, 0xca, 0x05, 0x27, 0xdf, 0x61, 0xf7, 0x96, 0x3d, 0xa4, 0x4c, 0x20, 0xc2,
+	0xf6, 0x97, 0x60, 0xc1, 0x26, 0x1d, 0xb7, 0xe3, 0xf5, 0xfd, 0x7c, 0x8d, 0x63, 0xd8, 0xb9, 0x0f,
+	0xa2, 0x25, 0x9b, 0x6c, 0xb9, 0x1d, 0xcf, 0xf2, 0x55, 0x81, 0x6f, 0x60, 0x74, 0x11, 0x45, 0xfc,
+	0x0f, 0x7b, 0xfc, 0xcc, 0x33, 0x11, 0x07, 0x0b, 0x96, 0x4d, 0x4c, 0xd7, 0xf4, 0xfa, 0xfe, 0x06,
+	0x4e, 0x6c, 0x18, 0x28, 0x7e, 0x9f, 0x25, 0xd1, 0x8a, 0xbc, 0x84, 0xee, 0xf4, 0xd3, 0xd7, 0x25,
+	0x4b, 0x57, 0x92, 0xfb, 0x2e, 0x98, 0x47, 0xc5, 0x85, 0xaa, 0x20, 0xa7, 0x00, 0x79, 0x3b, 0xdf,
+	0x8e, 0x47, 0x60, 0xe7, 0xf0, 0x25, 0x8f, 0x05, 0x8b, 0x45, 0xa6, 0xf7, 0xd6, 0x41, 0x72, 0x02,
+	0xf6, 0x94, 0x25, 0x11, 0x5f, 0xf9, 0xec, 0xd7, 0x92, 0x65, 0x02, 0x5f, 0x00, 0x28, 0x60, 0xc1,
+	0x62, 0xa1, 0xcf, 0x54, 0x10, 0xf9, 0xa8, 0xe2, 0x80, 0x7c, 0xd4, 0x08, 0x86, 0xf7, 0x2c, 0xcd,
+	0x42, 0x1e, 0x6b, 0x02, 0xe2, 0x81, 0x55, 0x22, 0xf2, 0x1d, 0x13, 0xe8, 0xea, 0x5a, 0xb3, 0x15,
+	0x25, 0xd9, 0x87, 0xbd, 0x4b, 0xbe, 0x8c, 0x05, 0x4b, 0xb3, 0xe2, 0xf0, 0x31, 0x1c, 0xde, 0x84,
+	0x71, 0xc8, 0xe3, 0x46, 0x43, 0x2a, 0x2c, 0x85, 0x29, 0x14, 0x96, 0x6b, 0xf2, 0x0e, 0xec, 0xf5,
+	0x36, 0xf5, 0xc9, 0xbd, 0x07, 0x0d, 0x4c, 0x3a, 0xae, 0xe9, 0x0d, 0x4e, 0x7b, 0x54, 0xef, 0xf0,
+	0xcb, 0x0e, 0x79, 0x80, 0xae, 0x06, 0x71, 0x04, 0xe6, 0xec, 0xe7, 0x0f, 0x4d, 0x2a, 0x97, 0xe5,
+	0x24, 0xb7, 0xda, 0x26, 0x69, 0xba, 0x1d, 0x6f, 0xbb, 0x98, 0xe4, 0x73, 0xe8, 0xcf, 0x52, 0xf6,
+	0x5b, 0x75, 0xb6, 0xf3, 0xce, 0x1a, 0x20, 0x1f, 0x61, 0x38, 0x4b, 0xf9, 0x53, 0x18, 0xb1, 0xca,
+	0x17, 0xdc, 0xad, 0x92, 0xd2, 0x23, 0x72, 0x8d, 0x0e, 0xf4, 0xa6, 0xcb, 0x34, 0x10, 0x52, 0x1c,
+	0x79, 0xa3, 0xe9, 0x97, 0x35, 0xf9, 0x06, 0x63, 0x25, 0xc5, 0x26, 0x4f, 0x53, 0x89, 0x92, 0x7b,
+	0xeb, 0x3f, 0xdc, 0x66, 0x83, 0xdb, 0x03, 0xab, 0x64, 0xd5, 0x33, 0xd2, 0x75, 0x4e, 0x6b, 0xf9,
+	0x45, 0x49, 0xc6, 0x80, 0xd7, 0x61, 0x26, 0x94, 0x0f, 0xcb, 0x31, 0xbd, 0x87, 0x51, 0x0d, 0x95,
+	0x1c, 0xaf, 0xa0, 0xab, 0x6b, 0xad, 0xfd, 0x80, 0xaa, 0xfa, 0x2a, 0x7e, 0xe2, 0x7e, 0xd1, 0x23,
+	0x53, 0x80, 0x35, 0xdc, 0x1a, 0x1c, 0x02, 0xd6, 0x75, 0x90, 0x89, 0x1b, 0xfe, 0x18, 0x3e, 0x85,
+	0xec, 0x51, 0x0b, 0x53, 0xc3, 0xc8, 0x6b, 0x38, 0x98, 0xb2, 0x88, 0x09, 0x56, 0x04, 0xa4, 0xd4,
+	0xa6, 0x49, 0x77, 0xfa, 0xd7, 0x04, 0xf3, 0x62, 0x76, 0x85, 0x2e, 0xec, 0xa8, 0xf0, 0xf4, 0xa8,
+	0x8e, 0x91, 0x33, 0xa0, 0xeb, 0xbc, 0x10, 0x03, 0x8f, 0x4b, 0xa7, 0xe2, 0x1e, 0xad, 0xbb, 0xda,
+	0xb1, 0x69, 0xd5, 0xd4, 0xc4, 0xc0, 0x33, 0xb0, 0xf3, 0xc3, 0x85, 0x03, 0x71, 0x44, 0x1b, 0x9e,
+	0x75, 0x86, 0xb4, 0x66, 0x4f, 0x62, 0xe0, 0x11, 0xf4, 0x6f, 0x99, 0x96, 0x0d, 0xbb, 0x5a, 0x1f,
+	0xc7, 0xa2, 0xd5, 0x98, 0x1b, 0x78, 0x0e, 0x83, 0x8a, 0xba, 0x78, 0x40, 0x37, 0x27, 0xe0, 0xec,
+	0xd3, 0xe6, 0x00, 0x88, 0x81, 0x6f, 0xc1, 0xaa, 0xaa, 0x82, 0x63, 0xda, 0x22, 0xd2, 0xc6, 0x75,
+	0xc7, 0xe5, 0xf0, 0x71, 0x8f, 0xd6, 0xcd, 0xe6, 0xd8, 0xb4, 0xea, 0x13, 0x62, 0xa0, 0x07, 0xbb,
+	0x2a, 0xfe, 0x38, 0xa4, 0xb5, 0x1f, 0x87, 0x63, 0xd1, 0xea, 0x7f, 0xc1, 0xc0, 0x0f, 0x70, 0x90,
+	0x0b, 0x54, 0xcf, 0x33, 0x3e, 0xa3, 0xad, 0x01, 0x6f, 0x11, 0xeb, 0x1c, 0x6c, 0x7d, 0xb9, 0x3a,
+	0x81, 0x87, 0xb4, 0x2d, 0x10, 0x1b, 0x6f, 0x9c, 0xef, 0xe6, 0xbf, 0xe7, 0xb3, 0x7f, 0x01, 0x00,
+	0x00, 0xff, 0xff, 0x4f, 0xe3, 0x93, 0x85, 0xad, 0x05, 0x00, 0x00,
 }
@@ -6,7 +6,11 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"regexp"
+	"sort"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/kelda/kelda/api"
 	"github.com/kelda/kelda/api/client"
@@ -17,6 +21,8 @@ import (
 	"github.com/kelda/kelda/counter"
 	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/minion/kubernetes"
+	"github.com/kelda/kelda/minion/pprofile"
+	"github.com/kelda/kelda/util/str"
 	"github.com/kelda/kelda/version"
 
 	"github.com/docker/distribution/reference"
@@ -26,6 +32,13 @@ import (
 
 var errDaemonOnlyRPC = errors.New("only defined on the daemon")
 
+// Machine labels are applied as node labels and as tags by each of the cloud
+// providers, so they're restricted to the format accepted by all of them.
+var labelKeyPattern = regexp.MustCompile(`^[a-z]([-_a-z0-9]*[a-z0-9])?$`)
+var labelValuePattern = regexp.MustCompile(`^[a-z0-9]([-_a-z0-9]*[a-z0-9])?$`)
+
+const maxLabelLength = 50
+
 type server struct {
 	conn db.Conn
 
@@ -90,7 +103,8 @@ func (s server) SetSecret(ctx context.Context, msg *pb.Secret) (*pb.SecretReply,
 			return &pb.SecretReply{}, err
 		}
 		defer leaderClient.Close()
-		return &pb.SecretReply{}, leaderClient.SetSecret(msg.Name, msg.Value)
+		return &pb.SecretReply{}, leaderClient.SetSecret(msg.Name,
+			string(msg.Value), msg.AllowedHostnames)
 	}
 
 	// We're running in the cluster, so write the secret into Kubernetes.
@@ -98,7 +112,74 @@ func (s server) SetSecret(ctx context.Context, msg *pb.Secret) (*pb.SecretReply,
 	if err != nil {
 		return &pb.SecretReply{}, err
 	}
-	return &pb.SecretReply{}, secretClient.Set(msg.Name, msg.Value)
+	return &pb.SecretReply{}, secretClient.Set(msg.Name, string(msg.Value),
+		msg.AllowedHostnames)
+}
+
+// ListSecrets returns the names and last modified times of the secrets stored
+// in the cluster. Like SetSecret, the daemon forwards the request to the
+// leader.
+func (s server) ListSecrets(ctx context.Context, _ *pb.ListSecretsRequest) (
+	*pb.ListSecretsReply, error) {
+
+	var secrets map[string]time.Time
+	if s.runningOnDaemon {
+		machines := s.conn.SelectFromMachine(nil)
+		leaderClient, err := newLeaderClient(machines, s.clientCreds)
+		if err != nil {
+			return &pb.ListSecretsReply{}, err
+		}
+		defer leaderClient.Close()
+
+		secrets, err = leaderClient.ListSecrets()
+		if err != nil {
+			return &pb.ListSecretsReply{}, err
+		}
+	} else {
+		secretClient, err := newSecretClient()
+		if err != nil {
+			return &pb.ListSecretsReply{}, err
+		}
+
+		secrets, err = secretClient.List()
+		if err != nil {
+			return &pb.ListSecretsReply{}, err
+		}
+	}
+
+	reply := &pb.ListSecretsReply{}
+	for name, lastModified := range secrets {
+		reply.Secrets = append(reply.Secrets, &pb.SecretInfo{
+			Name:         name,
+			LastModified: lastModified.Unix(),
+		})
+	}
+	sort.Slice(reply.Secrets, func(i, j int) bool {
+		return reply.Secrets[i].Name < reply.Secrets[j].Name
+	})
+	return reply, nil
+}
+
+// DeleteSecret removes the secret from the cluster. Like SetSecret, the daemon
+// forwards the request to the leader.
+func (s server) DeleteSecret(ctx context.Context, msg *pb.DeleteSecretRequest) (
+	*pb.SecretReply, error) {
+
+	if s.runningOnDaemon {
+		machines := s.conn.SelectFromMachine(nil)
+		leaderClient, err := newLeaderClient(machines, s.clientCreds)
+		if err != nil {
+			return &pb.SecretReply{}, err
+		}
+		defer leaderClient.Close()
+		return &pb.SecretReply{}, leaderClient.DeleteSecret(msg.Name)
+	}
+
+	secretClient, err := newSecretClient()
+	if err != nil {
+		return &pb.SecretReply{}, err
+	}
+	return &pb.SecretReply{}, secretClient.Delete(msg.Name)
 }
 
 // Query runs in two modes: daemon, or local. If in local mode, Query simply
@@ -145,6 +226,8 @@ func (s server) queryLocal(table db.TableType) (interface{}, error) {
 		return s.conn.SelectFromBlueprint(nil), nil
 	case db.ImageTable:
 		return s.conn.SelectFromImage(nil), nil
+	case db.MetricsTable:
+		return s.conn.SelectFromMetrics(nil), nil
 	default:
 		return nil, fmt.Errorf("unrecognized table: %s", table)
 	}
@@ -154,7 +237,7 @@ func (s server) queryFromDaemon(table db.TableType) (
 	interface{}, error) {
 
 	switch table {
-	case db.MachineTable, db.BlueprintTable:
+	case db.MachineTable, db.BlueprintTable, db.MetricsTable:
 		return s.queryLocal(table)
 	}
 
@@ -207,6 +290,34 @@ func (s server) QueryCounters(ctx context.Context, in *pb.CountersRequest) (
 	return &pb.CountersReply{Counters: counter.Dump()}, nil
 }
 
+func (s server) ProfileMinion(ctx context.Context, in *pb.MinionProfileRequest) (
+	*pb.ProfileReply, error) {
+	if !s.runningOnDaemon {
+		return nil, errDaemonOnlyRPC
+	}
+
+	clnt, err := newClient(api.RemoteAddress(in.Host), s.clientCreds)
+	if err != nil {
+		return nil, err
+	}
+	defer clnt.Close()
+
+	profile, err := clnt.Profile(in.Type, time.Duration(in.Duration))
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ProfileReply{Profile: profile}, nil
+}
+
+func (s server) Profile(ctx context.Context, in *pb.ProfileRequest) (
+	*pb.ProfileReply, error) {
+	profile, err := pprofile.Collect(in.Type, time.Duration(in.Duration))
+	if err != nil {
+		return nil, err
+	}
+	return &pb.ProfileReply{Profile: profile}, nil
+}
+
 func (s server) Deploy(cts context.Context, deployReq *pb.DeployRequest) (
 	*pb.DeployReply, error) {
 
@@ -219,13 +330,64 @@ func (s server) Deploy(cts context.Context, deployReq *pb.DeployRequest) (
 		return &pb.DeployReply{}, err
 	}
 
+	policy := newBlueprint.SecurityPolicy
+	hostPaths := map[string]struct{}{}
+	for _, volume := range newBlueprint.Volumes {
+		if volume.Type == "hostPath" {
+			hostPaths[volume.Name] = struct{}{}
+		}
+	}
+
 	for _, c := range newBlueprint.Containers {
-		if _, err := reference.ParseAnyReference(c.Image.Name); err != nil {
-			return &pb.DeployReply{}, fmt.Errorf("could not parse "+
-				"container image %s: %s", c.Image.Name, err.Error())
+		for _, member := range c.PodContainers() {
+			_, err := reference.ParseAnyReference(member.Image.Name)
+			if err != nil {
+				return &pb.DeployReply{}, fmt.Errorf("could not parse "+
+					"container image %s: %s", member.Image.Name,
+					err.Error())
+			}
+
+			if policy != nil && policy.ForbidPrivileged {
+				err := checkPrivileged(member, hostPaths)
+				if err != nil {
+					return &pb.DeployReply{}, err
+				}
+			}
+
+			if err := checkTemplates(member); err != nil {
+				return &pb.DeployReply{}, err
+			}
+		}
+
+		if err := checkAutoscale(c); err != nil {
+			return &pb.DeployReply{}, err
+		}
+
+		if err := checkHostValues(c); err != nil {
+			return &pb.DeployReply{}, err
+		}
+
+		if err := checkStatefulVolumes(c, hostPaths); err != nil {
+			return &pb.DeployReply{}, err
+		}
+	}
+
+	for _, m := range newBlueprint.Machines {
+		if err := checkLabels(m.Labels); err != nil {
+			return &pb.DeployReply{}, err
 		}
 	}
 
+	for _, plcm := range newBlueprint.Placements {
+		if err := checkLabels(plcm.Labels); err != nil {
+			return &pb.DeployReply{}, err
+		}
+	}
+
+	if err := checkMachinePools(newBlueprint.MachinePools); err != nil {
+		return &pb.DeployReply{}, err
+	}
+
 	// Ensure that the region is valid
 	if len(newBlueprint.Machines) > 0 {
 		// Since the Javascript code ensures that all machines have the same
@@ -279,6 +441,256 @@ func (s server) Deploy(cts context.Context, deployReq *pb.DeployRequest) (
 	return &pb.DeployReply{}, nil
 }
 
+// checkPrivileged returns an error if the given container is privileged, or has
+// security settings that give it comparable access to the host: capabilities
+// beyond Docker's defaults, unsafe sysctls, unconfined seccomp or AppArmor
+// profiles, or hostPath volumes. `hostPaths` contains the names of the
+// blueprint's hostPath volumes.
+func checkPrivileged(c blueprint.Container, hostPaths map[string]struct{}) error {
+	reason := privilegedReason(c)
+	if reason == "" {
+		for _, mount := range c.VolumeMounts {
+			if _, ok := hostPaths[mount.VolumeName]; ok {
+				reason = "mounts the hostPath volume " + mount.VolumeName
+				break
+			}
+		}
+	}
+
+	if reason == "" {
+		return nil
+	}
+	return fmt.Errorf("container %s %s, but the security policy forbids "+
+		"privileged containers", c.Hostname, reason)
+}
+
+// privilegedReason describes the security settings of the given container that
+// give it access to the host, or returns the empty string if there are none.
+func privilegedReason(c blueprint.Container) string {
+	switch {
+	case c.Privileged:
+		return "is privileged"
+	case c.Security == nil:
+		return ""
+	case c.Security.SeccompProfile == "unconfined":
+		return "has an unconfined seccomp profile"
+	case c.Security.AppArmorProfile == "unconfined":
+		return "has an unconfined AppArmor profile"
+	}
+
+	for _, capability := range c.Security.CapAdd {
+		if !blueprint.IsDefaultCapability(capability) {
+			capability = strings.TrimPrefix(strings.ToUpper(capability),
+				"CAP_")
+			return "adds the " + capability + " capability"
+		}
+	}
+
+	var unsafe []string
+	for name := range c.Security.Sysctls {
+		if !blueprint.IsSafeSysctl(name) {
+			unsafe = append(unsafe, name)
+		}
+	}
+	if len(unsafe) != 0 {
+		sort.Strings(unsafe)
+		return "sets unsafe sysctls " + strings.Join(unsafe, ", ")
+	}
+	return ""
+}
+
+// checkTemplates returns an error if any of the given container's templates
+// are invalid, or are used as environment variables.
+func checkTemplates(c blueprint.Container) error {
+	for name, val := range c.Env {
+		if _, ok := val.Value.(blueprint.Template); ok {
+			return fmt.Errorf("container %s uses a template for environment "+
+				"variable %s, but templates can only be used in files",
+				c.Hostname, name)
+		}
+	}
+
+	for path, val := range c.FilepathToContent {
+		tmpl, ok := val.Value.(blueprint.Template)
+		if !ok {
+			continue
+		}
+
+		if _, err := tmpl.ReferencedSecrets(); err != nil {
+			return fmt.Errorf(
+				"invalid template for file %s in container %s: %s",
+				path, c.Hostname, err)
+		}
+	}
+	return nil
+}
+
+// checkHostValues returns an error if any container in the given container's
+// pod sets an environment variable to the provider or region of its host, but
+// the container isn't pinned to a machine. The host of other containers isn't
+// known until after their environment is set, so they must read these values
+// from files.
+func checkHostValues(c blueprint.Container) error {
+	if c.Stateful || c.Daemon {
+		return nil
+	}
+
+	for _, member := range c.PodContainers() {
+		var names []string
+		for name, val := range member.Env {
+			rv, ok := val.Value.(blueprint.RuntimeValue)
+			if ok && (rv.ResourceKey == blueprint.HostProviderKey ||
+				rv.ResourceKey == blueprint.HostRegionKey) {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+
+		sort.Strings(names)
+		return fmt.Errorf("container %s sets environment variables %s to "+
+			"the provider or region of its host, but only stateful and "+
+			"daemon containers know their host when they start, so use "+
+			"a file instead", member.Hostname, strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// checkStatefulVolumes returns an error if the given container is stateful and
+// mounts a volume that isn't a hostPath volume. `hostPaths` contains the names
+// of the blueprint's hostPath volumes. Only hostPath volumes can be split into
+// a subdirectory per member, so other volumes would be shared by the members.
+func checkStatefulVolumes(c blueprint.Container, hostPaths map[string]struct{}) error {
+	if !c.Stateful {
+		return nil
+	}
+
+	for _, member := range c.PodContainers() {
+		for _, mount := range member.VolumeMounts {
+			if _, ok := hostPaths[mount.VolumeName]; !ok {
+				return fmt.Errorf("stateful container %s mounts "+
+					"the volume %s, which isn't a hostPath "+
+					"volume, so its members can't each have "+
+					"their own copy", c.Hostname,
+					mount.VolumeName)
+			}
+		}
+	}
+	return nil
+}
+
+// checkAutoscale returns an error if the given container's autoscaling settings
+// are invalid.
+func checkAutoscale(c blueprint.Container) error {
+	autoscale := c.Autoscale
+	if autoscale == nil {
+		return nil
+	}
+
+	if c.Stateful || c.Daemon {
+		return fmt.Errorf("container %s can't be autoscaled, since it's "+
+			"stateful or a daemon", c.Hostname)
+	}
+
+	if autoscale.Min < 1 || autoscale.Min > autoscale.Max {
+		return fmt.Errorf("autoscaled container %s must have a minimum of "+
+			"at least 1 replica, and a maximum of at least its minimum "+
+			"(was: min %d, max %d)", c.Hostname, autoscale.Min, autoscale.Max)
+	}
+
+	if autoscale.Metric != blueprint.AutoscaleCPU &&
+		autoscale.Metric != blueprint.AutoscaleHTTP {
+		return fmt.Errorf("autoscaled container %s has unknown metric %q",
+			c.Hostname, autoscale.Metric)
+	}
+
+	if autoscale.Target <= 0 {
+		return fmt.Errorf("autoscaled container %s must have a positive "+
+			"target (was: %v)", c.Hostname, autoscale.Target)
+	}
+
+	if autoscale.Metric == blueprint.AutoscaleHTTP {
+		if autoscale.Port < 1 || autoscale.Port > 65535 {
+			return fmt.Errorf("autoscaled container %s has invalid metric "+
+				"port %d", c.Hostname, autoscale.Port)
+		}
+
+		if autoscale.Path != "" && !strings.HasPrefix(autoscale.Path, "/") {
+			return fmt.Errorf("autoscaled container %s has invalid metric "+
+				"path %q: paths must start with a slash",
+				c.Hostname, autoscale.Path)
+		}
+	}
+
+	if autoscale.ScaleUpCooldown < 0 || autoscale.ScaleDownCooldown < 0 {
+		return fmt.Errorf("autoscaled container %s can't have negative "+
+			"cooldowns", c.Hostname)
+	}
+	return nil
+}
+
+// checkMachinePools returns an error if any of the given machine pools are
+// invalid.
+func checkMachinePools(pools []blueprint.MachinePool) error {
+	names := map[string]struct{}{}
+	for _, pool := range pools {
+		if pool.Name == "" {
+			return errors.New("machine pools must have a name")
+		}
+
+		if _, ok := names[pool.Name]; ok {
+			return fmt.Errorf("machine pool name %s used multiple times",
+				pool.Name)
+		}
+		names[pool.Name] = struct{}{}
+
+		if pool.Min < 0 || pool.Max < 1 || pool.Min > pool.Max {
+			return fmt.Errorf("machine pool %s must have a maximum size of "+
+				"at least 1, and a minimum size between 0 and its "+
+				"maximum size (was: min %d, max %d)",
+				pool.Name, pool.Min, pool.Max)
+		}
+
+		if pool.Machine.Role != "" && pool.Machine.Role != db.Worker {
+			return fmt.Errorf("machine pool %s can only contain workers",
+				pool.Name)
+		}
+
+		if !str.SliceContains(cloud.ValidRegions(
+			db.ProviderName(pool.Machine.Provider)), pool.Machine.Region) {
+			return fmt.Errorf("region: %s is not supported for provider: %s",
+				pool.Machine.Region, pool.Machine.Provider)
+		}
+
+		if err := checkLabels(pool.Machine.Labels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkLabels returns an error if any of the given machine labels are invalid.
+func checkLabels(labels map[string]string) error {
+	for key, value := range labels {
+		if len(key) > maxLabelLength || !labelKeyPattern.MatchString(key) {
+			return fmt.Errorf("invalid label key %q: keys must start with "+
+				"a lowercase letter, contain only lowercase letters, "+
+				"numbers, dashes, and underscores, and be at most %d "+
+				"characters", key, maxLabelLength)
+		}
+
+		if len(value) > maxLabelLength ||
+			!labelValuePattern.MatchString(value) {
+			return fmt.Errorf("invalid value %q for label %s: values must "+
+				"contain only lowercase letters, numbers, dashes, and "+
+				"underscores, and be at most %d characters",
+				value, key, maxLabelLength)
+		}
+	}
+	return nil
+}
+
 func (s server) Version(_ context.Context, _ *pb.VersionRequest) (
 	*pb.VersionReply, error) {
 	return &pb.VersionReply{Version: version.Version}, nil
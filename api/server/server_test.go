@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"golang.org/x/net/context"
 
@@ -63,9 +64,10 @@ func TestQueryMachinesDaemon(t *testing.T) {
 
 	exp := `[{"ID":1,"Provider":"Amazon","Region":"","Size":"size",` +
 		`"DiskSize":0,"SSHKeys":null,"FloatingIP":"",` +
-		`"Preemptible":false,"CloudID":"","PublicIP":"8.8.8.8",` +
+		`"Preemptible":false,"Labels":null,"Pool":"","CloudID":"",` +
+		`"PublicIP":"8.8.8.8",` +
 		`"PrivateIP":"9.9.9.9","Status":"connected","Role":"Master",` +
-		`"Connected":true}]`
+		`"Connected":true,"Draining":false}]`
 
 	checkQuery(t, server{conn, true, nil}, db.MachineTable, exp)
 }
@@ -138,6 +140,174 @@ func TestInvalidImage(t *testing.T) {
 	testInvalidImage(t, s, "hasCapital",
 		"could not parse container image hasCapital: "+
 			"invalid reference format: repository name must be lowercase")
+
+	// The images of sidecars should also be validated.
+	deployment := `{"Containers":[{"ID": "1", "Image": {"Name": "nginx"},
+		"Sidecars": [{"Image": {"Name": "hasCapital"}}]}]}`
+	_, err := s.Deploy(context.Background(),
+		&pb.DeployRequest{Deployment: deployment})
+	assert.EqualError(t, err, "could not parse container image hasCapital: "+
+		"invalid reference format: repository name must be lowercase")
+}
+
+func TestDeploySecurityPolicy(t *testing.T) {
+	conn := db.New()
+	s := server{conn: conn, runningOnDaemon: true}
+
+	// Privileged sidecars should also be rejected.
+	deployment := `{"SecurityPolicy": {"ForbidPrivileged": true},
+		"Containers":[{"ID": "1", "Hostname": "web",
+			"Image": {"Name": "nginx"},
+			"Sidecars": [{"Hostname": "proxy", "Image": {"Name": "envoy"},
+				"Privileged": true}]}]}`
+	_, err := s.Deploy(context.Background(),
+		&pb.DeployRequest{Deployment: deployment})
+	assert.EqualError(t, err, "container proxy is privileged, but the "+
+		"security policy forbids privileged containers")
+	conn.Txn(db.BlueprintTable).Run(func(view db.Database) error {
+		_, err := view.GetBlueprint()
+		assert.Error(t, err)
+		return nil
+	})
+
+	// Privileged containers are allowed without the policy.
+	deployment = `{"Containers":[{"ID": "1", "Hostname": "web",
+		"Image": {"Name": "nginx"}, "Privileged": true}]}`
+	_, err = s.Deploy(context.Background(),
+		&pb.DeployRequest{Deployment: deployment})
+	assert.NoError(t, err)
+}
+
+func TestCheckPrivileged(t *testing.T) {
+	t.Parallel()
+
+	hostPaths := map[string]struct{}{"docker": {}}
+	checkSecurity := func(security blueprint.Security) error {
+		return checkPrivileged(blueprint.Container{Hostname: "web",
+			Security: &security}, hostPaths)
+	}
+	suffix := ", but the security policy forbids privileged containers"
+
+	assert.NoError(t, checkPrivileged(blueprint.Container{Hostname: "web"},
+		hostPaths))
+	assert.NoError(t, checkPrivileged(blueprint.Container{Hostname: "web",
+		VolumeMounts: []blueprint.VolumeMount{{VolumeName: "data"}}},
+		hostPaths))
+	assert.NoError(t, checkSecurity(blueprint.Security{
+		CapAdd:          []string{"NET_BIND_SERVICE", "cap_chown"},
+		Sysctls:         map[string]string{"net.ipv4.tcp_syncookies": "1"},
+		SeccompProfile:  "runtime/default",
+		AppArmorProfile: "custom",
+	}))
+
+	assert.EqualError(t, checkSecurity(blueprint.Security{
+		CapAdd: []string{"CHOWN", "all"}}),
+		"container web adds the ALL capability"+suffix)
+	assert.EqualError(t, checkSecurity(blueprint.Security{
+		CapAdd: []string{"CAP_SYS_ADMIN"}}),
+		"container web adds the SYS_ADMIN capability"+suffix)
+	assert.EqualError(t, checkSecurity(blueprint.Security{
+		CapAdd: []string{"NET_ADMIN"}}),
+		"container web adds the NET_ADMIN capability"+suffix)
+	assert.EqualError(t, checkPrivileged(blueprint.Container{Hostname: "web",
+		VolumeMounts: []blueprint.VolumeMount{{VolumeName: "docker"}}},
+		hostPaths),
+		"container web mounts the hostPath volume docker"+suffix)
+	assert.EqualError(t, checkSecurity(blueprint.Security{
+		Sysctls: map[string]string{
+			"net.ipv4.tcp_syncookies": "1",
+			"net.core.somaxconn":      "1024",
+			"kernel.msgmax":           "65536",
+		}}),
+		"container web sets unsafe sysctls kernel.msgmax, "+
+			"net.core.somaxconn"+suffix)
+	assert.EqualError(t, checkSecurity(blueprint.Security{
+		SeccompProfile: "unconfined"}),
+		"container web has an unconfined seccomp profile"+suffix)
+	assert.EqualError(t, checkSecurity(blueprint.Security{
+		AppArmorProfile: "unconfined"}),
+		"container web has an unconfined AppArmor profile"+suffix)
+}
+
+func TestCheckHostValues(t *testing.T) {
+	t.Parallel()
+
+	env := map[string]blueprint.ContainerValue{
+		"REGION": blueprint.NewRuntimeValue(blueprint.HostRegionKey),
+		"HOST":   blueprint.NewRuntimeValue(blueprint.HostIPKey),
+	}
+	files := map[string]blueprint.ContainerValue{
+		"/etc/region": blueprint.NewRuntimeValue(blueprint.HostRegionKey),
+	}
+
+	// Pinned containers know their host before they start, and files are
+	// written after the pod is scheduled.
+	assert.NoError(t, checkHostValues(blueprint.Container{
+		Hostname: "db", Stateful: true, Env: env}))
+	assert.NoError(t, checkHostValues(blueprint.Container{
+		Hostname: "agent", Daemon: true, Env: env}))
+	assert.NoError(t, checkHostValues(blueprint.Container{
+		Hostname: "web", FilepathToContent: files}))
+
+	assert.EqualError(t, checkHostValues(blueprint.Container{
+		Hostname: "web",
+		Sidecars: []blueprint.Container{{Hostname: "proxy", Env: env}},
+	}), "container proxy sets environment variables REGION to the "+
+		"provider or region of its host, but only stateful and daemon "+
+		"containers know their host when they start, so use a file "+
+		"instead")
+}
+
+func TestCheckStatefulVolumes(t *testing.T) {
+	t.Parallel()
+
+	hostPaths := map[string]struct{}{"data": {}}
+	mounts := []blueprint.VolumeMount{{VolumeName: "data", MountPath: "/data"}}
+	assert.NoError(t, checkStatefulVolumes(blueprint.Container{
+		Hostname: "db", Stateful: true, VolumeMounts: mounts}, hostPaths))
+
+	// Only stateful containers need their own copy of each volume.
+	mounts = []blueprint.VolumeMount{{VolumeName: "shared", MountPath: "/data"}}
+	assert.NoError(t, checkStatefulVolumes(blueprint.Container{
+		Hostname: "web", VolumeMounts: mounts}, hostPaths))
+
+	assert.EqualError(t, checkStatefulVolumes(blueprint.Container{
+		Hostname: "db",
+		Stateful: true,
+		Sidecars: []blueprint.Container{{
+			Hostname: "backup", VolumeMounts: mounts}},
+	}, hostPaths), "stateful container db mounts the volume shared, "+
+		"which isn't a hostPath volume, so its members can't each have "+
+		"their own copy")
+}
+
+func TestDeployTemplates(t *testing.T) {
+	conn := db.New()
+	s := server{conn: conn, runningOnDaemon: true}
+
+	deployment := `{"Containers":[{"ID": "1", "Hostname": "web",
+		"Image": {"Name": "nginx"},
+		"FilepathToContent": {"/etc/db": {"Text": "{{ip \"db\""}}}]}`
+	_, err := s.Deploy(context.Background(),
+		&pb.DeployRequest{Deployment: deployment})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid template for file /etc/db in "+
+		"container web")
+
+	deployment = `{"Containers":[{"ID": "1", "Hostname": "web",
+		"Image": {"Name": "nginx"},
+		"Env": {"DB": {"Text": "{{ip \"db\"}}"}}}]}`
+	_, err = s.Deploy(context.Background(),
+		&pb.DeployRequest{Deployment: deployment})
+	assert.EqualError(t, err, "container web uses a template for environment "+
+		"variable DB, but templates can only be used in files")
+
+	deployment = `{"Containers":[{"ID": "1", "Hostname": "web",
+		"Image": {"Name": "nginx"},
+		"FilepathToContent": {"/etc/db": {"Text": "{{ip \"db\"}}"}}}]}`
+	_, err = s.Deploy(context.Background(),
+		&pb.DeployRequest{Deployment: deployment})
+	assert.NoError(t, err)
 }
 
 func testInvalidImage(t *testing.T, s server, img, expErr string) {
@@ -213,6 +383,116 @@ func TestDeployUnsupportedRegion(t *testing.T) {
 		"for provider: Amazon")
 }
 
+func TestDeployLabels(t *testing.T) {
+	conn := db.New()
+	s := server{conn: conn, runningOnDaemon: true}
+
+	deployment := `{"Machines":[{"Provider":"Amazon", "Role":"Worker",
+		"Region":"us-west-1", "Labels": {"Tier": "db"}}]}`
+	_, err := s.Deploy(context.Background(),
+		&pb.DeployRequest{Deployment: deployment})
+	assert.EqualError(t, err, `invalid label key "Tier": keys must start `+
+		"with a lowercase letter, contain only lowercase letters, numbers, "+
+		"dashes, and underscores, and be at most 50 characters")
+
+	deployment = `{"Placements":[{"TargetContainer":"web",
+		"Labels": {"tier": "db-"}}]}`
+	_, err = s.Deploy(context.Background(),
+		&pb.DeployRequest{Deployment: deployment})
+	assert.EqualError(t, err, `invalid value "db-" for label tier: values `+
+		"must contain only lowercase letters, numbers, dashes, and "+
+		"underscores, and be at most 50 characters")
+
+	deployment = `{"Machines":[{"Provider":"Amazon", "Role":"Worker",
+		"Region":"us-west-1", "Labels": {"tier": "db", "zone_2": "1a"}}]}`
+	_, err = s.Deploy(context.Background(),
+		&pb.DeployRequest{Deployment: deployment})
+	assert.NoError(t, err)
+}
+
+func TestDeployMachinePools(t *testing.T) {
+	conn := db.New()
+	s := server{conn: conn, runningOnDaemon: true}
+
+	deploy := func(pools string) error {
+		_, err := s.Deploy(context.Background(), &pb.DeployRequest{
+			Deployment: `{"MachinePools":` + pools + `}`})
+		return err
+	}
+
+	machine := `{"Provider":"Amazon", "Role":"Worker", "Region":"us-west-1"}`
+	assert.EqualError(t, deploy(`[{"Machine":`+machine+`, "Max": 1}]`),
+		"machine pools must have a name")
+
+	assert.EqualError(t, deploy(`[{"Name":"web", "Machine":`+machine+
+		`, "Max": 1}, {"Name":"web", "Machine":`+machine+`, "Max": 1}]`),
+		"machine pool name web used multiple times")
+
+	assert.EqualError(t, deploy(`[{"Name":"web", "Machine":`+machine+
+		`, "Min": 3, "Max": 2}]`), "machine pool web must have a maximum "+
+		"size of at least 1, and a minimum size between 0 and its maximum "+
+		"size (was: min 3, max 2)")
+
+	assert.EqualError(t, deploy(`[{"Name":"web", "Max": 1, "Machine":
+		{"Provider":"Amazon", "Role":"Master", "Region":"us-west-1"}}]`),
+		"machine pool web can only contain workers")
+
+	assert.EqualError(t, deploy(`[{"Name":"web", "Max": 1, "Machine":
+		{"Provider":"Amazon", "Role":"Worker", "Region":"nowhere"}}]`),
+		"region: nowhere is not supported for provider: Amazon")
+
+	assert.NoError(t, deploy(`[{"Name":"web", "Machine":`+machine+
+		`, "Min": 1, "Max": 3}]`))
+}
+
+func TestDeployAutoscale(t *testing.T) {
+	conn := db.New()
+	s := server{conn: conn, runningOnDaemon: true}
+
+	deploy := func(container string) error {
+		_, err := s.Deploy(context.Background(), &pb.DeployRequest{
+			Deployment: `{"Containers":[{"Hostname":"web",
+				"Image":{"Name":"nginx"},` + container + `}]}`})
+		return err
+	}
+
+	assert.EqualError(t, deploy(`"Stateful":true, "Autoscale":
+		{"Min":1, "Max":3, "Metric":"cpu", "Target":50}`),
+		"container web can't be autoscaled, since it's stateful or a daemon")
+
+	assert.EqualError(t, deploy(`"Autoscale":
+		{"Min":3, "Max":2, "Metric":"cpu", "Target":50}`),
+		"autoscaled container web must have a minimum of at least 1 "+
+			"replica, and a maximum of at least its minimum (was: min 3, "+
+			"max 2)")
+
+	assert.EqualError(t, deploy(`"Autoscale":
+		{"Min":1, "Max":3, "Metric":"memory", "Target":50}`),
+		`autoscaled container web has unknown metric "memory"`)
+
+	assert.EqualError(t, deploy(`"Autoscale":
+		{"Min":1, "Max":3, "Metric":"cpu"}`),
+		"autoscaled container web must have a positive target (was: 0)")
+
+	assert.EqualError(t, deploy(`"Autoscale":
+		{"Min":1, "Max":3, "Metric":"http", "Target":5}`),
+		"autoscaled container web has invalid metric port 0")
+
+	assert.EqualError(t, deploy(`"Autoscale":
+		{"Min":1, "Max":3, "Metric":"http", "Target":5, "Port":80,
+		"Path":"load"}`), `autoscaled container web has invalid metric `+
+		`path "load": paths must start with a slash`)
+
+	assert.EqualError(t, deploy(`"Autoscale":
+		{"Min":1, "Max":3, "Metric":"cpu", "Target":50,
+		"ScaleDownCooldown":-1}`),
+		"autoscaled container web can't have negative cooldowns")
+
+	assert.NoError(t, deploy(`"Autoscale":
+		{"Min":1, "Max":3, "Metric":"http", "Target":5, "Port":80,
+		"Path":"/load", "ScaleUpCooldown":60}`))
+}
+
 func TestDeployChangeNamespace(t *testing.T) {
 	t.Parallel()
 
@@ -279,6 +559,40 @@ func TestDaemonOnlyEndpoints(t *testing.T) {
 
 	_, err = server{runningOnDaemon: false}.Deploy(nil, nil)
 	assert.EqualError(t, err, errDaemonOnlyRPC.Error())
+
+	_, err = server{runningOnDaemon: false}.ProfileMinion(nil, nil)
+	assert.EqualError(t, err, errDaemonOnlyRPC.Error())
+}
+
+func TestProfile(t *testing.T) {
+	t.Parallel()
+
+	reply, err := server{}.Profile(nil, &pb.ProfileRequest{Type: "heap"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, reply.Profile)
+
+	_, err = server{}.Profile(nil, &pb.ProfileRequest{Type: "block"})
+	assert.EqualError(t, err, "unknown profile type: block")
+}
+
+func TestProfileMinion(t *testing.T) {
+	mockClient := new(mocks.Client)
+	mockClient.On("Profile", "cpu", time.Second).Return([]byte("profile"), nil)
+	mockClient.On("Close").Return(nil)
+
+	newClient = func(host string, _ connection.Credentials) (
+		client.Client, error) {
+		assert.Equal(t, "tcp://8.8.8.8:9000", host)
+		return mockClient, nil
+	}
+	defer func() { newClient = client.New }()
+
+	reply, err := server{runningOnDaemon: true}.ProfileMinion(nil,
+		&pb.MinionProfileRequest{Host: "8.8.8.8", Type: "cpu",
+			Duration: int64(time.Second)})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("profile"), reply.Profile)
+	mockClient.AssertExpectations(t)
 }
 
 func TestQueryImagesCluster(t *testing.T) {
@@ -293,7 +607,8 @@ func TestQueryImagesCluster(t *testing.T) {
 		return nil
 	})
 
-	exp := `[{"ID":1,"Name":"foo","Dockerfile":"","RepoDigest":"","Status":""}]`
+	exp := `[{"ID":1,"Name":"foo","Dockerfile":"","BuildArgs":null,"Target":"",` +
+		`"RepoDigest":"","Status":"","BuildLog":"","BuildError":""}]`
 	checkQuery(t, server{conn, false, nil}, db.ImageTable, exp)
 }
 
@@ -308,10 +623,29 @@ func TestQueryImagesDaemon(t *testing.T) {
 		return mc, nil
 	}
 
-	exp := `[{"ID":0,"Name":"bar","Dockerfile":"","RepoDigest":"","Status":""}]`
+	exp := `[{"ID":0,"Name":"bar","Dockerfile":"","BuildArgs":null,"Target":"",` +
+		`"RepoDigest":"","Status":"","BuildLog":"","BuildError":""}]`
 	checkQuery(t, server{db.New(), true, nil}, db.ImageTable, exp)
 }
 
+func TestQueryMetrics(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		m := view.InsertMetrics()
+		m.Machine = "machine"
+		m.CPUPercent = 50
+		view.Commit(m)
+		return nil
+	})
+
+	// The daemon tracks metrics itself, so it shouldn't query the leader.
+	exp := `[{"ID":1,"Machine":"machine","Time":"0001-01-01T00:00:00Z",` +
+		`"CPUPercent":50,"MemoryUsed":0,"MemoryTotal":0}]`
+	checkQuery(t, server{conn, true, nil}, db.MetricsTable, exp)
+}
+
 // The Daemon should get a connection to the leader of the cluster, and
 // forward the secret association.
 func TestSetSecretDaemon(t *testing.T) {
@@ -319,7 +653,8 @@ func TestSetSecretDaemon(t *testing.T) {
 	secretValue := "secretValue"
 
 	mc := new(mocks.Client)
-	mc.On("SetSecret", secretName, secretValue).Return(nil)
+	allowedHostnames := []string{"web"}
+	mc.On("SetSecret", secretName, secretValue, allowedHostnames).Return(nil)
 	mc.On("Close").Return(nil)
 	newLeaderClient = func(_ []db.Machine, _ connection.Credentials) (
 		client.Client, error) {
@@ -327,7 +662,8 @@ func TestSetSecretDaemon(t *testing.T) {
 	}
 
 	_, err := server{db.New(), true, nil}.SetSecret(nil, &pb.Secret{
-		Name: secretName, Value: secretValue,
+		Name: secretName, Value: []byte(secretValue),
+		AllowedHostnames: allowedHostnames,
 	})
 	assert.NoError(t, err)
 	mc.AssertExpectations(t)
@@ -344,9 +680,10 @@ func TestSetSecretCluster(t *testing.T) {
 		return mockClient, nil
 	}
 
-	mockClient.On("Set", secretName, secretValue).Return(nil).Once()
+	mockClient.On("Set", secretName, secretValue, []string(nil)).
+		Return(nil).Once()
 	_, err := server{db.New(), false, nil}.SetSecret(nil, &pb.Secret{
-		Name: secretName, Value: secretValue,
+		Name: secretName, Value: []byte(secretValue),
 	})
 	assert.NoError(t, err)
 	mockClient.AssertExpectations(t)
@@ -360,3 +697,75 @@ func TestSetSecretClusterError(t *testing.T) {
 	_, err := server{db.New(), false, nil}.SetSecret(nil, &pb.Secret{})
 	assert.NotNil(t, err)
 }
+
+func TestListSecretsDaemon(t *testing.T) {
+	mc := new(mocks.Client)
+	mc.On("ListSecrets").Return(map[string]time.Time{
+		"b": time.Unix(200, 0),
+		"a": time.Unix(100, 0),
+	}, nil)
+	mc.On("Close").Return(nil)
+	newLeaderClient = func(_ []db.Machine, _ connection.Credentials) (
+		client.Client, error) {
+		return mc, nil
+	}
+
+	reply, err := server{db.New(), true, nil}.ListSecrets(nil,
+		&pb.ListSecretsRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, []*pb.SecretInfo{
+		{Name: "a", LastModified: 100},
+		{Name: "b", LastModified: 200},
+	}, reply.Secrets)
+	mc.AssertExpectations(t)
+}
+
+func TestListSecretsCluster(t *testing.T) {
+	mockClient := &kubeMocks.SecretClient{}
+	newSecretClient = func() (kubernetes.SecretClient, error) {
+		return mockClient, nil
+	}
+
+	mockClient.On("List").Return(map[string]time.Time{
+		"a": time.Unix(100, 0),
+	}, nil).Once()
+	reply, err := server{db.New(), false, nil}.ListSecrets(nil,
+		&pb.ListSecretsRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, []*pb.SecretInfo{{Name: "a", LastModified: 100}},
+		reply.Secrets)
+
+	mockClient.On("List").Return(nil, assert.AnError).Once()
+	_, err = server{db.New(), false, nil}.ListSecrets(nil,
+		&pb.ListSecretsRequest{})
+	assert.Equal(t, assert.AnError, err)
+	mockClient.AssertExpectations(t)
+}
+
+func TestDeleteSecretDaemon(t *testing.T) {
+	mc := new(mocks.Client)
+	mc.On("DeleteSecret", "secretName").Return(nil)
+	mc.On("Close").Return(nil)
+	newLeaderClient = func(_ []db.Machine, _ connection.Credentials) (
+		client.Client, error) {
+		return mc, nil
+	}
+
+	_, err := server{db.New(), true, nil}.DeleteSecret(nil,
+		&pb.DeleteSecretRequest{Name: "secretName"})
+	assert.NoError(t, err)
+	mc.AssertExpectations(t)
+}
+
+func TestDeleteSecretCluster(t *testing.T) {
+	mockClient := &kubeMocks.SecretClient{}
+	newSecretClient = func() (kubernetes.SecretClient, error) {
+		return mockClient, nil
+	}
+
+	mockClient.On("Delete", "secretName").Return(nil).Once()
+	_, err := server{db.New(), false, nil}.DeleteSecret(nil,
+		&pb.DeleteSecretRequest{Name: "secretName"})
+	assert.NoError(t, err)
+	mockClient.AssertExpectations(t)
+}
@@ -19,10 +19,121 @@ type Blueprint struct {
 	Connections   []Connection   `json:",omitempty"`
 	Placements    []Placement    `json:",omitempty"`
 	Machines      []Machine      `json:",omitempty"`
+	MachinePools  []MachinePool  `json:",omitempty"`
 	Volumes       []Volume       `json:",omitempty"`
 
 	AdminACL  []string `json:",omitempty"`
 	Namespace string   `json:",omitempty"`
+
+	LogSink *LogSink `json:",omitempty"`
+
+	SecretBackend *SecretBackend `json:",omitempty"`
+
+	RegistryCredentials []RegistryCredential `json:",omitempty"`
+
+	// Whether the tags of images that aren't built by Kelda should be
+	// resolved to digests. Either PinImageDigests or WatchImageDigests.
+	ImageDigests string `json:",omitempty"`
+
+	SecurityPolicy *SecurityPolicy `json:",omitempty"`
+}
+
+// A SecurityPolicy restricts the security settings of the blueprint's
+// containers. Blueprints that violate it are rejected by the daemon.
+type SecurityPolicy struct {
+	// ForbidPrivileged rejects containers, sidecars, and init containers
+	// that are privileged, add capabilities beyond Docker's defaults, set
+	// unsafe sysctls, have unconfined seccomp or AppArmor profiles, or
+	// mount hostPath volumes.
+	ForbidPrivileged bool `json:",omitempty"`
+}
+
+// The policies for resolving image tags to digests.
+const (
+	// PinImageDigests resolves each image tag to a digest when the container
+	// is first deployed, so that all of the container's replicas run the
+	// same image even if the tag is later updated.
+	PinImageDigests = "pin"
+
+	// WatchImageDigests resolves image tags like PinImageDigests, and polls
+	// the registries so that containers are redeployed when their tags are
+	// updated.
+	WatchImageDigests = "watch"
+)
+
+// The types of LogSinks.
+const (
+	// SyslogSink ships logs to a syslog server at Address.
+	SyslogSink = "syslog"
+
+	// FileSink appends logs to the file at Path on the leader master.
+	FileSink = "file"
+
+	// HTTPSink POSTs logs as JSON lines to the URL at Address.
+	HTTPSink = "http"
+)
+
+// A LogSink specifies where the logs of blueprint containers should be shipped.
+type LogSink struct {
+	Type string `json:",omitempty"`
+
+	// The address of the syslog server, or the URL of the HTTP endpoint.
+	Address string `json:",omitempty"`
+
+	// The network protocol used to connect to the syslog server. Either "tcp"
+	// or "udp".
+	Protocol string `json:",omitempty"`
+
+	// The path of the file on the master that logs are written to.
+	Path string `json:",omitempty"`
+}
+
+// The types of SecretBackends.
+const (
+	// KubernetesSecretBackend stores secrets as Kubernetes secrets in the
+	// cluster. It's the default, and secrets are set with `kelda secret`.
+	KubernetesSecretBackend = "kubernetes"
+
+	// VaultSecretBackend reads secrets from the Vault KV version 2 secrets
+	// engine at Address.
+	VaultSecretBackend = "vault"
+
+	// FileSecretBackend reads each secret from the file named after the secret
+	// in the directory at Path on the master. It's intended for testing.
+	FileSecretBackend = "file"
+)
+
+// A SecretBackend specifies where the values of the secrets referenced by
+// containers are stored.
+type SecretBackend struct {
+	Type string `json:",omitempty"`
+
+	// The URL of the Vault server.
+	Address string `json:",omitempty"`
+
+	// The path that the Vault KV secrets engine is mounted at. Defaults to
+	// "secret".
+	Mount string `json:",omitempty"`
+
+	// The prefix of the secrets within the Vault KV secrets engine, or the
+	// directory that the file backend reads secrets from.
+	Path string `json:",omitempty"`
+
+	// The name of the secret in the Kubernetes backend that holds the token
+	// used to authenticate with Vault.
+	TokenSecret string `json:",omitempty"`
+}
+
+// A RegistryCredential specifies the credentials used to pull images from a
+// private Docker registry.
+type RegistryCredential struct {
+	// The hostname of the registry, e.g. "registry.example.com:5000". Docker
+	// Hub is "https://index.docker.io/v1/".
+	Server string `json:",omitempty"`
+
+	// The name of the secret whose value is the username and password for
+	// the registry, separated by a colon.
+	Secret string `json:",omitempty"`
 }
 
 // A Placement constraint guides on what type of machine a container can be
@@ -32,11 +143,66 @@ type Placement struct {
 
 	Exclusive bool `json:",omitempty"`
 
+	// Container Constraints. The TargetContainer is placed in the same
+	// topology domain as OtherContainer, or in a different one if Exclusive
+	// is true. Topology is either TopologyMachine (the default) or
+	// TopologyRegion.
+	OtherContainer string `json:",omitempty"`
+	Topology       string `json:",omitempty"`
+
 	// Machine Constraints
 	Provider   string `json:",omitempty"`
 	Size       string `json:",omitempty"`
 	Region     string `json:",omitempty"`
 	FloatingIP string `json:",omitempty"`
+
+	// Labels is a constraint on the labels of the machine. The machine must
+	// have all of the labels, or, if Exclusive is true, none of them.
+	Labels map[string]string `json:",omitempty"`
+
+	// If Weight is non-zero, the placement is a preference rather than a
+	// requirement. When choosing a machine, the weights of the preferences
+	// it satisfies are summed, and the machine with the highest sum is
+	// preferred. Weights range from 1 to 100.
+	Weight int `json:",omitempty"`
+}
+
+// The topology domains that container placement constraints can apply to.
+const (
+	TopologyMachine = "machine"
+	TopologyRegion  = "region"
+)
+
+// SatisfiesPlacements returns whether the given machine satisfies the machine
+// placement rules of the container with the given hostname. Preferences are
+// ignored.
+func SatisfiesPlacements(machine Machine, hostname string,
+	placements []Placement) bool {
+	for _, plcm := range placements {
+		if plcm.TargetContainer != hostname || plcm.Weight != 0 {
+			continue
+		}
+
+		constraints := []struct{ exp, actual string }{
+			{plcm.Provider, machine.Provider},
+			{plcm.Region, machine.Region},
+			{plcm.Size, machine.Size},
+			{plcm.FloatingIP, machine.FloatingIP},
+		}
+		for key, value := range plcm.Labels {
+			constraints = append(constraints, struct{ exp, actual string }{
+				value, machine.Labels[key]})
+		}
+		for _, constraint := range constraints {
+			if constraint.exp == "" {
+				continue
+			}
+			if (constraint.exp == constraint.actual) == plcm.Exclusive {
+				return false
+			}
+		}
+	}
+	return true
 }
 
 // An Image represents a Docker image that can be run. If the Dockerfile is non-empty,
@@ -44,6 +210,20 @@ type Placement struct {
 type Image struct {
 	Name       string `json:",omitempty"`
 	Dockerfile string `json:",omitempty"`
+
+	// The local directory whose files are available to COPY and ADD
+	// instructions in the Dockerfile. It's replaced by the Context archive
+	// when the blueprint is compiled.
+	ContextDir string `json:",omitempty"`
+
+	// A gzipped tar archive of the build context.
+	Context []byte `json:",omitempty"`
+
+	// The values of the ARG instructions in the Dockerfile.
+	BuildArgs map[string]string `json:",omitempty"`
+
+	// The stage of a multi-stage Dockerfile to build.
+	Target string `json:",omitempty"`
 }
 
 // A Container may be instantiated in the blueprint and queried by users.
@@ -56,6 +236,142 @@ type Container struct {
 	Hostname          string                    `json:",omitempty"`
 	Privileged        bool                      `json:",omitempty"`
 	VolumeMounts      []VolumeMount             `json:",omitempty"`
+
+	// Security contains finer-grained security settings than Privileged.
+	Security *Security `json:",omitempty"`
+
+	// Sidecars are the other members of the container's group. They run in
+	// the same pod as the container, so they share its IP address, hostname,
+	// and network namespace. The Hostname of a sidecar is only used to name
+	// it within the pod, and its ID and Sidecars are ignored.
+	Sidecars []Container `json:",omitempty"`
+
+	// InitContainers are run in order before the container and its sidecars
+	// are started, and must each exit successfully. They run in the same pod
+	// as the container, and their Hostnames are only used to name them within
+	// the pod.
+	InitContainers []Container `json:",omitempty"`
+
+	// Dependencies are the ports that must accept connections before the
+	// container is started. They're waited for before the InitContainers
+	// are run.
+	Dependencies []Dependency `json:",omitempty"`
+
+	// Daemon containers are run once on each worker machine that satisfies
+	// their placement rules, rather than once in the cluster. Each instance
+	// has its own IP address and a hostname of the form
+	// "<Hostname>.<machine>". Connections and load balancers that reference
+	// the Hostname apply to all of the instances.
+	Daemon bool `json:",omitempty"`
+
+	// Stateful containers are run as Replicas members with stable
+	// identities. Member i has the hostname "<Hostname>-<i>" and its own IP
+	// address, always runs on the same worker, and has its own subdirectory
+	// of each hostPath volume, which are the only volumes that stateful
+	// containers may mount. Members are started in order, and are restarted
+	// one at a time during updates. Connections that reference the Hostname
+	// apply to all of the members.
+	Stateful bool `json:",omitempty"`
+
+	// Replicas is the number of members of a stateful container. For
+	// autoscaled containers, it's the current number of replicas, which is
+	// set by the daemon.
+	Replicas int `json:",omitempty"`
+
+	// Autoscaled containers are run as interchangeable replicas, whose
+	// number follows a metric of their load. Replica i has the hostname
+	// "<Hostname>-<i>" and its own IP address. Connections, load balancers,
+	// and placement rules that reference the Hostname apply to all of the
+	// replicas.
+	Autoscale *Autoscale `json:",omitempty"`
+}
+
+// Autoscale describes how the number of replicas of a container is chosen.
+type Autoscale struct {
+	// The bounds on the number of replicas.
+	Min int `json:",omitempty"`
+	Max int `json:",omitempty"`
+
+	// The metric that's averaged across the replicas. Either AutoscaleCPU,
+	// or AutoscaleHTTP.
+	Metric string `json:",omitempty"`
+
+	// The average value of the metric that the number of replicas is
+	// adjusted to maintain.
+	Target float64 `json:",omitempty"`
+
+	// The port and path of the HTTP endpoint on each replica that serves
+	// the value of an AutoscaleHTTP metric.
+	Port int    `json:",omitempty"`
+	Path string `json:",omitempty"`
+
+	// The minimum number of seconds between adding replicas, and between
+	// removing them. The daemon's defaults are used if they're zero.
+	ScaleUpCooldown   int `json:",omitempty"`
+	ScaleDownCooldown int `json:",omitempty"`
+}
+
+const (
+	// AutoscaleCPU is the CPU usage of each replica, as a percentage of a
+	// single core.
+	AutoscaleCPU = "cpu"
+
+	// AutoscaleHTTP is a number served by each replica over HTTP, such as
+	// the length of its work queue. The body of the response must contain
+	// only the number.
+	AutoscaleHTTP = "http"
+)
+
+// A Dependency is a port of another container or load balancer that must
+// accept TCP connections before a container is started.
+type Dependency struct {
+	Hostname string `json:",omitempty"`
+	Port     int    `json:",omitempty"`
+}
+
+// String returns the address of the dependency.
+func (dep Dependency) String() string {
+	return fmt.Sprintf("%s:%d", dep.Hostname, dep.Port)
+}
+
+// PodContainers returns the container, followed by its sidecars and init
+// containers.
+func (c Container) PodContainers() []Container {
+	containers := append([]Container{c}, c.Sidecars...)
+	return append(containers, c.InitContainers...)
+}
+
+// Security contains the Linux security settings of a container.
+type Security struct {
+	// The user ID that the container's processes run as, rather than the
+	// image's default user.
+	RunAsUser *int64 `json:",omitempty"`
+
+	// A group ID that the container's processes are members of, and that
+	// owns the pod's volumes. The version of Kubernetes run by Kelda can't
+	// change the primary group of the processes, so it's added as a
+	// supplemental group. It applies to the entire pod, so it's only read
+	// from the first container in a group.
+	RunAsGroup *int64 `json:",omitempty"`
+
+	// Whether the container's root filesystem is mounted read-only.
+	ReadOnlyRootFilesystem bool `json:",omitempty"`
+
+	// The Linux capabilities to add to and drop from the container's
+	// default set, such as "NET_ADMIN". "ALL" refers to every capability.
+	CapAdd  []string `json:",omitempty"`
+	CapDrop []string `json:",omitempty"`
+
+	// The namespaced kernel parameters to set, such as
+	// "net.core.somaxconn". They apply to the entire pod, so they're only
+	// read from the first container in a group.
+	Sysctls map[string]string `json:",omitempty"`
+
+	// The seccomp and AppArmor profiles to run the container with. Either
+	// "runtime/default", "unconfined", or the name of a profile that's
+	// loaded on the machines.
+	SeccompProfile  string `json:",omitempty"`
+	AppArmorProfile string `json:",omitempty"`
 }
 
 // VolumeMount defines how a volume should be mounted into a container.
@@ -75,17 +391,59 @@ type Volume struct {
 
 // ContainerValue is a wrapper for the possible values that can be used in
 // the container Env and FilepathToContent maps. The only permissible types
-// are Secret and string.
+// are Secret, RuntimeValue, Template, and string.
 type ContainerValue struct {
 	Value interface{}
 }
 
-// Secret represents the name of a secret whose value is stored in Vault. The
-// caller is expected to query Vault to resolve the secret value.
+// Secret represents the name of a secret whose value is stored in the
+// blueprint's SecretBackend. The caller is expected to query the backend to
+// resolve the secret value.
 type Secret struct {
 	NameOfSecret string
 }
 
+// RuntimeValue represents a value that isn't known until the container is
+// deployed, such as the container's IP address. The value is resolved by the
+// minion when it creates the container's pod.
+type RuntimeValue struct {
+	ResourceKey string
+}
+
+// Template represents a file whose contents are rendered by the minion from a
+// Go text/template. Templates can reference the IPs of other containers and
+// load balancers, the members of load balancers, and secrets, and are
+// re-rendered when they change. Templates can only be used in
+// FilepathToContent.
+type Template struct {
+	Text string
+}
+
+// The resources that can be referenced by a RuntimeValue.
+const (
+	// ContainerIPKey resolves to the container's IP address.
+	ContainerIPKey = "containerIP"
+
+	// HostIPKey resolves to the private IP address of the machine that the
+	// container is running on.
+	HostIPKey = "host.privateIP"
+
+	// HostProviderKey resolves to the provider of the machine that the
+	// container is running on.
+	HostProviderKey = "host.provider"
+
+	// HostRegionKey resolves to the region of the machine that the container
+	// is running on.
+	HostRegionKey = "host.region"
+
+	// NamespaceKey resolves to the namespace of the blueprint.
+	NamespaceKey = "namespace"
+)
+
+// RuntimeValueKeys are the resources that can be referenced by a RuntimeValue.
+var RuntimeValueKeys = []string{ContainerIPKey, HostIPKey, HostProviderKey,
+	HostRegionKey, NamespaceKey}
+
 // A LoadBalancer represents a load balanced group of containers.
 type LoadBalancer struct {
 	Name      string   `json:",omitempty"`
@@ -114,6 +472,25 @@ type Machine struct {
 	SSHKeys     []string `json:",omitempty"`
 	FloatingIP  string   `json:",omitempty"`
 	Preemptible bool     `json:",omitempty"`
+
+	// Labels are arbitrary key-value pairs that placement rules can select
+	// on. They're also applied as tags by the cloud provider, and the tags
+	// of running machines are updated when the labels change.
+	Labels map[string]string `json:",omitempty"`
+}
+
+// A MachinePool is a group of identical worker machines whose size is adjusted
+// by the daemon. The pool grows when containers can't be scheduled, and shrinks
+// when its machines are underused.
+type MachinePool struct {
+	Name string `json:",omitempty"`
+
+	// The machine that each member of the pool is a copy of.
+	Machine Machine `json:",omitempty"`
+
+	// The bounds on the number of machines in the pool.
+	Min int `json:",omitempty"`
+	Max int `json:",omitempty"`
 }
 
 // PublicInternetLabel is a magic label that allows connections to or from the public
@@ -173,7 +550,16 @@ func FromFileWithArgs(filename string, cmdLineArgs []string) (Blueprint, error)
 	if err != nil {
 		return Blueprint{}, fmt.Errorf("failed to read deployment file: %s", err)
 	}
-	return FromJSON(string(depl))
+
+	bp, err := FromJSON(string(depl))
+	if err != nil {
+		return Blueprint{}, err
+	}
+
+	if err := bp.archiveBuildContexts(); err != nil {
+		return Blueprint{}, err
+	}
+	return bp, nil
 }
 
 // FromJSON gets a Blueprint handle from the deployment representation.
@@ -204,19 +590,37 @@ func NewString(str string) ContainerValue {
 	return ContainerValue{str}
 }
 
-// DivideContainerValues divides a map of ContainerValues into two maps -- one
-// of string values, and one of secrets.
+// NewTemplate returns a ContainerValue representing a template.
+func NewTemplate(text string) ContainerValue {
+	return ContainerValue{Template{text}}
+}
+
+// NewRuntimeValue returns a ContainerValue representing the runtime resource
+// with the given key.
+func NewRuntimeValue(key string) ContainerValue {
+	return ContainerValue{RuntimeValue{key}}
+}
+
+// DivideContainerValues divides a map of ContainerValues into four maps -- one
+// of string values, one of secrets, one of the keys of runtime values, and one
+// of the text of templates.
 func DivideContainerValues(vals map[string]ContainerValue) (
-	rawStrings, secrets map[string]string) {
+	rawStrings, secrets, runtimeValues, templates map[string]string) {
 
 	rawStrings = map[string]string{}
 	secrets = map[string]string{}
+	runtimeValues = map[string]string{}
+	templates = map[string]string{}
 	for name, valIntf := range vals {
 		switch val := valIntf.Value.(type) {
 		case string:
 			rawStrings[name] = val
 		case Secret:
 			secrets[name] = val.NameOfSecret
+		case RuntimeValue:
+			runtimeValues[name] = val.ResourceKey
+		case Template:
+			templates[name] = val.Text
 		default:
 			panic("unreached")
 		}
@@ -232,6 +636,10 @@ func (cv ContainerValue) String() string {
 		return v
 	case Secret:
 		return "Secret: " + v.NameOfSecret
+	case RuntimeValue:
+		return "RuntimeValue: " + v.ResourceKey
+	case Template:
+		return "Template: " + v.Text
 	default:
 		return fmt.Sprintf("%+v", v)
 	}
@@ -255,7 +663,20 @@ func (cv *ContainerValue) UnmarshalJSON(jsonBytes []byte) error {
 		return nil
 	}
 
-	return fmt.Errorf("not a Secret (%s) or string (%s)", secretErr, stringErr)
+	tryRuntimeValue, runtimeValueErr := unmarshalAsRuntimeValue(jsonBytes)
+	if runtimeValueErr == nil {
+		cv.Value = tryRuntimeValue
+		return nil
+	}
+
+	tryTemplate, templateErr := unmarshalAsTemplate(jsonBytes)
+	if templateErr == nil {
+		cv.Value = tryTemplate
+		return nil
+	}
+
+	return fmt.Errorf("not a Secret (%s), RuntimeValue (%s), Template (%s), "+
+		"or string (%s)", secretErr, runtimeValueErr, templateErr, stringErr)
 }
 
 func unmarshalAsSecret(jsonBytes []byte) (Secret, error) {
@@ -270,6 +691,30 @@ func unmarshalAsSecret(jsonBytes []byte) (Secret, error) {
 	return secret, nil
 }
 
+func unmarshalAsRuntimeValue(jsonBytes []byte) (RuntimeValue, error) {
+	runtimeValue := RuntimeValue{}
+	if err := json.Unmarshal(jsonBytes, &runtimeValue); err != nil {
+		return runtimeValue, err
+	}
+
+	if runtimeValue.ResourceKey == "" {
+		return runtimeValue, errors.New("missing required field: ResourceKey")
+	}
+	return runtimeValue, nil
+}
+
+func unmarshalAsTemplate(jsonBytes []byte) (Template, error) {
+	tmpl := Template{}
+	if err := json.Unmarshal(jsonBytes, &tmpl); err != nil {
+		return tmpl, err
+	}
+
+	if tmpl.Text == "" {
+		return tmpl, errors.New("missing required field: Text")
+	}
+	return tmpl, nil
+}
+
 // MarshalJSON implements the Go interface for automatically serializing
 // structs into JSON.
 func (cv ContainerValue) MarshalJSON() ([]byte, error) {
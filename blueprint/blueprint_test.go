@@ -24,6 +24,7 @@ func TestContainerValueString(t *testing.T) {
 		expString string
 	}{
 		{Secret{"foo"}, "Secret: foo"},
+		{RuntimeValue{ContainerIPKey}, "RuntimeValue: containerIP"},
 		{"bar", "bar"},
 	}
 
@@ -49,6 +50,20 @@ func TestSecretJSON(t *testing.T) {
 	checkMarshalAndUnmarshal(t, unmarshalled)
 }
 
+// TestRuntimeValueJSON tests marshalling and unmarshalling runtime values.
+func TestRuntimeValueJSON(t *testing.T) {
+	t.Parallel()
+
+	var unmarshalled ContainerValue
+	assert.NoError(t, json.Unmarshal(
+		[]byte(`{"resourceKey": "host.region"}`), &unmarshalled))
+	assert.Equal(t, NewRuntimeValue(HostRegionKey), unmarshalled)
+	checkMarshalAndUnmarshal(t, unmarshalled)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"unknown": "key"}`),
+		&unmarshalled))
+}
+
 // TestStringJSON tests marshalling and unmarshalling raw strings.
 func TestStringJSON(t *testing.T) {
 	t.Parallel()
@@ -65,6 +80,27 @@ func TestStringJSON(t *testing.T) {
 	checkMarshalAndUnmarshal(t, unmarshalled)
 }
 
+func TestSatisfiesPlacements(t *testing.T) {
+	t.Parallel()
+
+	machine := Machine{Provider: "Amazon", Region: "us-west-1",
+		Labels: map[string]string{"disk": "ssd"}}
+	placements := []Placement{
+		{TargetContainer: "db", Provider: "Amazon"},
+		{TargetContainer: "db", Labels: map[string]string{"disk": "ssd"}},
+		{TargetContainer: "db", Exclusive: true, Region: "us-east-1"},
+		{TargetContainer: "db", Weight: 10, Region: "us-east-1"},
+		{TargetContainer: "web", Provider: "Google"},
+		{TargetContainer: "cache", Exclusive: true,
+			Labels: map[string]string{"disk": "ssd"}},
+	}
+
+	assert.True(t, SatisfiesPlacements(machine, "db", placements))
+	assert.True(t, SatisfiesPlacements(machine, "unconstrained", placements))
+	assert.False(t, SatisfiesPlacements(machine, "web", placements))
+	assert.False(t, SatisfiesPlacements(machine, "cache", placements))
+}
+
 // checkMarshalAndUnmarshal checks that that the given ContainerValue marshals
 // and unmarshals to the same object.
 func checkMarshalAndUnmarshal(t *testing.T, toMarshal ContainerValue) {
@@ -0,0 +1,154 @@
+package blueprint
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/afero"
+
+	"github.com/kelda/kelda/util"
+)
+
+// maxContextSize is the largest compressed build context that may be included
+// in a blueprint. Blueprints are sent to the minions in a single gRPC message,
+// so the contexts must be small.
+const maxContextSize = 1 << 20
+
+// maxTotalContextSize is the largest combined size of the compressed build
+// contexts in a blueprint. gRPC messages are limited to 4MB, and contexts are
+// base64 encoded in the blueprint's JSON, which grows them by a third. Images
+// that share a context each include a copy of it.
+const maxTotalContextSize = 2 << 20
+
+// archiveBuildContexts replaces the ContextDir of each image with a gzipped
+// tar archive of the directory. Directories shared by multiple containers are
+// only archived once.
+func (bp *Blueprint) archiveBuildContexts() error {
+	archives := map[string][]byte{}
+	var total int
+	archive := func(img *Image) error {
+		dir := img.ContextDir
+		if dir == "" {
+			return nil
+		}
+
+		contents, ok := archives[dir]
+		if !ok {
+			var err error
+			contents, err = archiveDir(dir)
+			if err != nil {
+				return fmt.Errorf("build context for image %s: %s",
+					img.Name, err)
+			}
+			archives[dir] = contents
+		}
+
+		total += len(contents)
+		if total > maxTotalContextSize {
+			return fmt.Errorf("build contexts are more than %d bytes "+
+				"when compressed, which is too large to deploy, so "+
+				"remove unneeded files from the context directories "+
+				"or push the images to a registry instead",
+				maxTotalContextSize)
+		}
+
+		img.Context = contents
+		img.ContextDir = ""
+		return nil
+	}
+
+	for i := range bp.Containers {
+		c := &bp.Containers[i]
+		if err := archive(&c.Image); err != nil {
+			return err
+		}
+
+		for j := range c.Sidecars {
+			if err := archive(&c.Sidecars[j].Image); err != nil {
+				return err
+			}
+		}
+
+		for j := range c.InitContainers {
+			if err := archive(&c.InitContainers[j].Image); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// archiveDir returns a gzipped tar archive of the files in `dir`. The archive
+// only depends on the names, permissions, and contents of the files, so that
+// images are only rebuilt when the context changes.
+func archiveDir(dir string) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+
+	err := afero.Walk(util.AppFs, dir, func(path string, info os.FileInfo,
+		err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return fmt.Errorf("%s: only regular files and directories "+
+				"are supported", rel)
+		}
+
+		hdr := &tar.Header{
+			Name: filepath.ToSlash(rel),
+			Mode: int64(info.Mode().Perm()),
+		}
+		if info.IsDir() {
+			hdr.Typeflag = tar.TypeDir
+			hdr.Name += "/"
+			return tw.WriteHeader(hdr)
+		}
+
+		hdr.Typeflag = tar.TypeReg
+		hdr.Size = info.Size()
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		f, err := util.AppFs.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+
+	if buf.Len() > maxContextSize {
+		return nil, fmt.Errorf("compressed context is %d bytes, but must "+
+			"be at most %d bytes", buf.Len(), maxContextSize)
+	}
+	return buf.Bytes(), nil
+}
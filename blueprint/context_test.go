@@ -0,0 +1,93 @@
+package blueprint
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kelda/kelda/util"
+)
+
+func TestArchiveBuildContexts(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+	util.WriteFile("/app/main.py", []byte("print('hello')"), 0644)
+	util.WriteFile("/app/static/index.html", []byte("<html>"), 0600)
+
+	bp := Blueprint{Containers: []Container{
+		{Image: Image{Name: "a", Dockerfile: "FROM python",
+			ContextDir: "/app"}},
+		{Image: Image{Name: "a", Dockerfile: "FROM python",
+			ContextDir: "/app"}},
+		{Image: Image{Name: "nginx"}, Sidecars: []Container{
+			{Image: Image{Name: "a", Dockerfile: "FROM python",
+				ContextDir: "/app"}},
+		}},
+	}}
+	assert.NoError(t, bp.archiveBuildContexts())
+
+	assert.Empty(t, bp.Containers[0].Image.ContextDir)
+	assert.Equal(t, bp.Containers[0].Image.Context,
+		bp.Containers[1].Image.Context)
+	assert.Nil(t, bp.Containers[2].Image.Context)
+	assert.Equal(t, bp.Containers[0].Image.Context,
+		bp.Containers[2].Sidecars[0].Image.Context)
+	assert.Equal(t, map[string]string{
+		"main.py":           "print('hello')",
+		"static/":           "",
+		"static/index.html": "<html>",
+	}, readArchive(t, bp.Containers[0].Image.Context))
+
+	// Archives of unchanged directories should be identical so that images
+	// aren't rebuilt unnecessarily.
+	archive, err := archiveDir("/app")
+	assert.NoError(t, err)
+	assert.Equal(t, bp.Containers[0].Image.Context, archive)
+
+	bp = Blueprint{Containers: []Container{
+		{Image: Image{Name: "a", ContextDir: "/missing"}},
+	}}
+	assert.Error(t, bp.archiveBuildContexts())
+
+	// Each image includes a copy of its context, so contexts that are
+	// small enough on their own may be too large when shared.
+	large := make([]byte, maxContextSize*3/4)
+	rand.Read(large)
+	util.WriteFile("/large/data", large, 0644)
+	img := Image{Name: "large", ContextDir: "/large"}
+	bp = Blueprint{Containers: []Container{{Image: img}, {Image: img}}}
+	assert.NoError(t, bp.archiveBuildContexts())
+
+	bp = Blueprint{Containers: []Container{
+		{Image: img}, {Image: img}, {Image: img}}}
+	assert.EqualError(t, bp.archiveBuildContexts(), "build contexts are "+
+		"more than 2097152 bytes when compressed, which is too large to "+
+		"deploy, so remove unneeded files from the context directories "+
+		"or push the images to a registry instead")
+}
+
+func readArchive(t *testing.T, archive []byte) map[string]string {
+	gr, err := gzip.NewReader(bytes.NewReader(archive))
+	assert.NoError(t, err)
+
+	files := map[string]string{}
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+
+		contents, err := ioutil.ReadAll(tr)
+		assert.NoError(t, err)
+		files[hdr.Name] = string(contents)
+	}
+	return files
+}
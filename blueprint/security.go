@@ -0,0 +1,47 @@
+package blueprint
+
+import "strings"
+
+// safeSysctls are the sysctls that Kubernetes allows pods to set by default.
+// Other namespaced sysctls are allowed by the kubelet's
+// --experimental-allowed-unsafe-sysctls flag.
+var safeSysctls = map[string]struct{}{
+	"kernel.shm_rmid_forced":       {},
+	"net.ipv4.ip_local_port_range": {},
+	"net.ipv4.tcp_syncookies":      {},
+}
+
+// defaultCapabilities are the capabilities that Docker gives containers by
+// default. Adding them doesn't give a container any more access to the host.
+var defaultCapabilities = map[string]struct{}{
+	"AUDIT_WRITE":      {},
+	"CHOWN":            {},
+	"DAC_OVERRIDE":     {},
+	"FOWNER":           {},
+	"FSETID":           {},
+	"KILL":             {},
+	"MKNOD":            {},
+	"NET_BIND_SERVICE": {},
+	"NET_RAW":          {},
+	"SETFCAP":          {},
+	"SETGID":           {},
+	"SETPCAP":          {},
+	"SETUID":           {},
+	"SYS_CHROOT":       {},
+}
+
+// IsSafeSysctl returns whether Kubernetes allows pods to set the given sysctl
+// by default.
+func IsSafeSysctl(name string) bool {
+	_, ok := safeSysctls[name]
+	return ok
+}
+
+// IsDefaultCapability returns whether the given capability is given to
+// containers by default. Capabilities may be written in any case, and with or
+// without the CAP_ prefix.
+func IsDefaultCapability(capability string) bool {
+	_, ok := defaultCapabilities[strings.TrimPrefix(
+		strings.ToUpper(capability), "CAP_")]
+	return ok
+}
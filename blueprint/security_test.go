@@ -0,0 +1,24 @@
+package blueprint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsDefaultCapability(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, IsDefaultCapability("CHOWN"))
+	assert.True(t, IsDefaultCapability("cap_net_bind_service"))
+	assert.False(t, IsDefaultCapability("NET_ADMIN"))
+	assert.False(t, IsDefaultCapability("CAP_SYS_ADMIN"))
+	assert.False(t, IsDefaultCapability("ALL"))
+}
+
+func TestIsSafeSysctl(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, IsSafeSysctl("net.ipv4.tcp_syncookies"))
+	assert.False(t, IsSafeSysctl("net.core.somaxconn"))
+}
@@ -0,0 +1,119 @@
+package blueprint
+
+import (
+	"errors"
+	"sort"
+	"text/template"
+	"text/template/parse"
+)
+
+// The functions that can be called by Templates. They're implemented by the
+// minion that renders the template.
+const (
+	// TemplateIPFunc returns the IP of the container or load balancer with
+	// the given hostname.
+	TemplateIPFunc = "ip"
+
+	// TemplateMembersFunc returns the hostnames and IPs of the containers
+	// behind the load balancer with the given name.
+	TemplateMembersFunc = "members"
+
+	// TemplateSecretFunc returns the value of the secret with the given name.
+	TemplateSecretFunc = "secret"
+)
+
+// Parse parses the template using the given implementations of the template
+// functions.
+func (t Template) Parse(funcs template.FuncMap) (*template.Template, error) {
+	return template.New("").Funcs(funcs).Parse(t.Text)
+}
+
+// ReferencedSecrets returns the names of the secrets referenced by the
+// template. The names of secrets must be string literals so that they're known
+// before the template is rendered, which allows Kelda to wait for the secrets
+// to be set, and to check that the container is allowed to use them.
+func (t Template) ReferencedSecrets() ([]string, error) {
+	stub := func(string) (string, error) { return "", nil }
+	tmpl, err := t.Parse(template.FuncMap{
+		TemplateIPFunc:      stub,
+		TemplateMembersFunc: stub,
+		TemplateSecretFunc:  stub,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var secrets []string
+	findSecrets := func(cmd *parse.CommandNode) error {
+		ident, ok := cmd.Args[0].(*parse.IdentifierNode)
+		if !ok || ident.Ident != TemplateSecretFunc {
+			return nil
+		}
+
+		if len(cmd.Args) != 2 {
+			return errors.New("secret must be called with a single argument")
+		}
+
+		name, ok := cmd.Args[1].(*parse.StringNode)
+		if !ok {
+			return errors.New("secret names must be string literals")
+		}
+		secrets = append(secrets, name.Text)
+		return nil
+	}
+
+	// Templates can define other templates, which are parsed into their own
+	// trees.
+	for _, tmpl := range tmpl.Templates() {
+		if tmpl.Tree == nil {
+			continue
+		}
+
+		if err := walkTemplate(tmpl.Tree.Root, findSecrets); err != nil {
+			return nil, err
+		}
+	}
+
+	// Sort the secrets since the defined templates are stored in a map.
+	sort.Strings(secrets)
+	return secrets, nil
+}
+
+// walkTemplate calls `fn` on each command in the given template node.
+func walkTemplate(node parse.Node, fn func(*parse.CommandNode) error) error {
+	var children []parse.Node
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node != nil {
+			children = node.Nodes
+		}
+	case *parse.ActionNode:
+		children = []parse.Node{node.Pipe}
+	case *parse.IfNode:
+		children = []parse.Node{node.Pipe, node.List, node.ElseList}
+	case *parse.RangeNode:
+		children = []parse.Node{node.Pipe, node.List, node.ElseList}
+	case *parse.WithNode:
+		children = []parse.Node{node.Pipe, node.List, node.ElseList}
+	case *parse.TemplateNode:
+		children = []parse.Node{node.Pipe}
+	case *parse.PipeNode:
+		if node != nil {
+			for _, cmd := range node.Cmds {
+				children = append(children, cmd)
+			}
+		}
+	case *parse.CommandNode:
+		if err := fn(node); err != nil {
+			return err
+		}
+		children = node.Args
+	}
+
+	for _, child := range children {
+		if err := walkTemplate(child, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package blueprint
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTemplateJSON tests marshalling and unmarshalling templates.
+func TestTemplateJSON(t *testing.T) {
+	t.Parallel()
+
+	var unmarshalled ContainerValue
+	assert.NoError(t, json.Unmarshal([]byte(`{"text": "{{ip \"web\"}}"}`),
+		&unmarshalled))
+	assert.Equal(t, NewTemplate(`{{ip "web"}}`), unmarshalled)
+	checkMarshalAndUnmarshal(t, unmarshalled)
+}
+
+func TestTemplateReferencedSecrets(t *testing.T) {
+	t.Parallel()
+
+	secrets, err := Template{`{{ip "db"}}:{{secret "password"}}
+{{range members "web"}}{{if .IP}}{{secret "token"}}{{end}}{{end}}
+{{define "key"}}{{secret "key"}}{{end}}`}.ReferencedSecrets()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"key", "password", "token"}, secrets)
+
+	secrets, err = Template{"static"}.ReferencedSecrets()
+	assert.NoError(t, err)
+	assert.Empty(t, secrets)
+
+	// Secrets must be referenced by string literals.
+	_, err = Template{`{{secret .Hostname}}`}.ReferencedSecrets()
+	assert.EqualError(t, err, "secret names must be string literals")
+
+	_, err = Template{`{{"password" | secret}}`}.ReferencedSecrets()
+	assert.EqualError(t, err, "secret must be called with a single argument")
+
+	// Unknown functions and syntax errors should be rejected.
+	_, err = Template{`{{unknown "foo"}}`}.ReferencedSecrets()
+	assert.Error(t, err)
+
+	_, err = Template{`{{ip "db"`}.ReferencedSecrets()
+	assert.Error(t, err)
+}
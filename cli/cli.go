@@ -13,22 +13,26 @@ import (
 
 // Note the `minion` command is in cli_posix.go as it only runs on posix systems.
 var commands = map[string]command.SubCommand{
+	"cp":      command.NewCopyCommand(),
 	"daemon":  command.NewDaemonCommand(),
 	"inspect": &inspect.Inspect{},
 	"logs":    command.NewLogCommand(),
 
 	"ps":   command.NewShowCommand(),
 	"show": command.NewShowCommand(),
+	"top":  command.NewTopCommand(),
 
 	"secret":              &command.Secret{},
 	"run":                 command.NewRunCommand(),
 	"configure-provider":  &command.ConfigProvider{},
 	"base-infrastructure": &command.BaseInfra{},
-	"ssh":        command.NewSSHCommand(),
-	"stop":       command.NewStopCommand(),
-	"version":    command.NewVersionCommand(),
-	"debug-logs": command.NewDebugCommand(),
-	"counters":   &command.Counters{},
+	"ssh":                 command.NewSSHCommand(),
+	"stop":                command.NewStopCommand(),
+	"version":             command.NewVersionCommand(),
+	"debug-logs":          command.NewDebugCommand(),
+	"debug-profile":       command.NewDebugProfileCommand(),
+	"counters":            &command.Counters{},
+	"wait-for":            command.NewWaitForCommand(),
 }
 
 // Run parses and runs the cli subcommand given the command line arguments.
@@ -0,0 +1,433 @@
+package command
+
+import (
+	"archive/tar"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	apiUtil "github.com/kelda/kelda/api/util"
+	"github.com/kelda/kelda/cli/ssh"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/minion/supervisor"
+	"github.com/kelda/kelda/util"
+)
+
+// Copy contains the options for copying files between the local machine and
+// containers.
+type Copy struct {
+	privateKey string
+	quiet      bool
+
+	src copyPath
+	dst copyPath
+
+	// Where progress is reported. Nil if progress shouldn't be shown.
+	progressOut io.Writer
+
+	sshGetter ssh.Getter
+
+	connectionHelper
+}
+
+// copyPath is one side of a copy. If `container` is empty, the path refers to
+// the local filesystem.
+type copyPath struct {
+	container string
+	path      string
+}
+
+// progressThreshold is the minimum file size, in bytes, for which progress is
+// reported.
+const progressThreshold = 1 << 20
+
+// NewCopyCommand creates a new Copy command instance.
+func NewCopyCommand() *Copy {
+	return &Copy{sshGetter: ssh.New}
+}
+
+var cpCommands = `kelda cp [OPTIONS] SRC_PATH ID:DEST_PATH
+       kelda cp [OPTIONS] ID:SRC_PATH DEST_PATH`
+var cpExplanation = `Copy files or directories between a container and the local
+filesystem. Directories are copied recursively.
+
+If the destination is an existing directory, or ends with a "/", the source is
+copied into it. Otherwise, the source is copied to the destination path.
+
+Local paths containing a colon can be given by prefixing them with "./" or by
+using an absolute path.
+
+To copy the heap dump /tmp/heap.hprof out of container 8879fd2dbcee:
+kelda cp 8879fd2dbcee:/tmp/heap.hprof .
+
+To copy the local directory fixtures into /data in container 8879fd2dbcee:
+kelda cp fixtures 8879fd2dbcee:/data/`
+
+// InstallFlags sets up parsing for command line flags.
+func (cCmd *Copy) InstallFlags(flags *flag.FlagSet) {
+	cCmd.connectionHelper.InstallFlags(flags)
+	flags.StringVar(&cCmd.privateKey, "i", "",
+		"path to the private key to use when connecting to the host")
+	flags.BoolVar(&cCmd.quiet, "q", false, "don't show copy progress")
+
+	flags.Usage = func() {
+		util.PrintUsageString(cpCommands, cpExplanation, flags)
+	}
+}
+
+// Parse parses the command line arguments for the cp command.
+func (cCmd *Copy) Parse(args []string) error {
+	if len(args) != 2 {
+		return errors.New("must specify a source and a destination")
+	}
+
+	cCmd.src = parseCopyPath(args[0])
+	cCmd.dst = parseCopyPath(args[1])
+
+	switch {
+	case cCmd.src.container == "" && cCmd.dst.container == "":
+		return errors.New("either the source or the destination " +
+			"must be a container path")
+	case cCmd.src.container != "" && cCmd.dst.container != "":
+		return errors.New("copying between containers is not supported")
+	case cCmd.src.path == "" || cCmd.dst.path == "":
+		return errors.New("paths must not be empty")
+	}
+	return nil
+}
+
+func parseCopyPath(arg string) copyPath {
+	if strings.HasPrefix(arg, "/") || strings.HasPrefix(arg, ".") {
+		return copyPath{path: arg}
+	}
+
+	parts := strings.SplitN(arg, ":", 2)
+	if len(parts) != 2 {
+		return copyPath{path: arg}
+	}
+	return copyPath{container: parts[0], path: parts[1]}
+}
+
+// Run copies the files between the local machine and the container.
+func (cCmd *Copy) Run() int {
+	target := cCmd.src.container
+	if target == "" {
+		target = cCmd.dst.container
+	}
+
+	i, err := apiUtil.FuzzyLookup(cCmd.client, target)
+	if err != nil {
+		log.WithError(err).Errorf("Failed to lookup %s", target)
+		return 1
+	}
+
+	dbc, ok := i.(db.Container)
+	if !ok {
+		log.Errorf("%s is not a container", target)
+		return 1
+	}
+
+	if dbc.PodName == "" {
+		log.Error("Container not yet running")
+		return 1
+	}
+
+	machines, err := cCmd.client.QueryMachines()
+	if err != nil {
+		log.WithError(err).Error("Failed to query machines")
+		return 1
+	}
+
+	leaderIP, err := getLeaderIP(machines, cCmd.creds)
+	if err != nil {
+		log.WithError(err).Error("Failed to find leader machine")
+		return 1
+	}
+
+	sshClient, err := cCmd.sshGetter(leaderIP, cCmd.privateKey)
+	if err != nil {
+		log.WithError(err).Error("Failed to set up SSH connection")
+		return 1
+	}
+	defer sshClient.Close()
+
+	if !cCmd.quiet && isTerminal() {
+		cCmd.progressOut = os.Stderr
+	}
+
+	if cCmd.src.container != "" {
+		err = cCmd.download(sshClient, dbc)
+	} else {
+		err = cCmd.upload(sshClient, dbc)
+	}
+
+	if err != nil {
+		log.WithError(err).Error("Failed to copy")
+		return 1
+	}
+	return 0
+}
+
+// upload streams a tar archive of the local source into the container, where
+// it is extracted by `tar`.
+func (cCmd *Copy) upload(c ssh.Client, dbc db.Container) error {
+	srcPath := filepath.Clean(cCmd.src.path)
+	if _, err := util.Stat(srcPath); err != nil {
+		return err
+	}
+
+	dir, name := path.Dir(cCmd.dst.path), path.Base(cCmd.dst.path)
+	isDirCmd := podExecCommand(dbc, "", "test", "-d", cCmd.dst.path)
+	if strings.HasSuffix(cCmd.dst.path, "/") {
+		dir, name = cCmd.dst.path, filepath.Base(srcPath)
+	} else if _, err := c.CombinedOutput(isDirCmd); err == nil {
+		dir, name = cCmd.dst.path, filepath.Base(srcPath)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(cCmd.writeTar(pw, srcPath, name))
+	}()
+
+	cmd := podExecCommand(dbc, "-i", "tar", "xf", "-", "-C", dir)
+	err := c.Stream(cmd, pr, nil)
+
+	// Unblock the tar writer in case the remote command exited before
+	// consuming the entire archive.
+	pr.Close()
+	return err
+}
+
+// download streams a tar archive of the source path out of the container, and
+// extracts it locally.
+func (cCmd *Copy) download(c ssh.Client, dbc db.Container) error {
+	srcPath := path.Clean(cCmd.src.path)
+	dir, name := filepath.Dir(cCmd.dst.path), filepath.Base(cCmd.dst.path)
+	if info, err := util.Stat(cCmd.dst.path); strings.HasSuffix(cCmd.dst.path,
+		string(filepath.Separator)) || (err == nil && info.IsDir()) {
+		dir, name = cCmd.dst.path, path.Base(srcPath)
+	}
+
+	pr, pw := io.Pipe()
+	errChan := make(chan error, 1)
+	go func() {
+		err := cCmd.readTar(pr, dir, name)
+
+		// Drain any remaining output so that the SSH session can exit.
+		io.Copy(ioutil.Discard, pr)
+		errChan <- err
+	}()
+
+	cmd := podExecCommand(dbc, "", "tar", "cf", "-",
+		"-C", path.Dir(srcPath), path.Base(srcPath))
+	streamErr := c.Stream(cmd, nil, pw)
+	pw.Close()
+
+	if err := <-errChan; err != nil {
+		return err
+	}
+	return streamErr
+}
+
+// writeTar writes a tar archive of the file or directory at `root` to `w`.
+// Entries are named so that `root` is extracted as `name`.
+func (cCmd *Copy) writeTar(w io.Writer, root, name string) error {
+	tw := tar.NewWriter(w)
+	walkFn := func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			log.WithField("path", p).Warn("Skipping non-regular file")
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = path.Join(name, filepath.ToSlash(rel))
+		if info.IsDir() {
+			hdr.Name += "/"
+			hdr.Typeflag = tar.TypeDir
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		f, err := util.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		return cCmd.copyFile(tw, f, hdr.Name, hdr.Size)
+	}
+
+	if err := util.Walk(root, walkFn); err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// readTar extracts the tar archive in `r` into `dir`. The first path component
+// of each entry is replaced with `name`.
+func (cCmd *Copy) readTar(r io.Reader, dir, name string) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		entry := path.Clean(hdr.Name)
+		if path.IsAbs(entry) || entry == ".." ||
+			strings.HasPrefix(entry, "../") {
+			return fmt.Errorf("invalid path in archive: %s", hdr.Name)
+		}
+
+		parts := strings.SplitN(entry, "/", 2)
+		parts[0] = name
+		dst := filepath.Join(dir, filepath.FromSlash(strings.Join(parts, "/")))
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err := util.AppFs.MkdirAll(dst, os.FileMode(hdr.Mode))
+			if err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			err := util.AppFs.MkdirAll(filepath.Dir(dst), 0755)
+			if err != nil {
+				return err
+			}
+
+			f, err := util.AppFs.OpenFile(dst,
+				os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode))
+			if err != nil {
+				return err
+			}
+
+			err = cCmd.copyFile(f, tr, entry, hdr.Size)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		default:
+			log.WithField("path", hdr.Name).Warn(
+				"Skipping unsupported file type")
+		}
+	}
+}
+
+// copyFile copies a file of `size` bytes from `src` to `dst`, reporting
+// progress if the file is large.
+func (cCmd *Copy) copyFile(dst io.Writer, src io.Reader, name string,
+	size int64) error {
+	if cCmd.progressOut == nil || size < progressThreshold {
+		_, err := io.Copy(dst, src)
+		return err
+	}
+
+	pw := &progressWriter{out: cCmd.progressOut, name: name, total: size}
+	_, err := io.Copy(io.MultiWriter(dst, pw), src)
+	fmt.Fprintln(cCmd.progressOut)
+	return err
+}
+
+// progressWriter counts the bytes written to it, and periodically prints the
+// progress of the copy.
+type progressWriter struct {
+	out     io.Writer
+	name    string
+	total   int64
+	written int64
+
+	lastPrint time.Time
+}
+
+func (pw *progressWriter) Write(p []byte) (int, error) {
+	pw.written += int64(len(p))
+
+	now := time.Now()
+	if now.Sub(pw.lastPrint) >= 100*time.Millisecond || pw.written >= pw.total {
+		pw.lastPrint = now
+		fmt.Fprintf(pw.out, "\r%s: %s / %s (%d%%)", pw.name,
+			formatBytes(pw.written), formatBytes(pw.total),
+			pw.written*100/pw.total)
+	}
+	return len(p), nil
+}
+
+// formatBytes returns a human readable representation of `n` bytes.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for rem := n / unit; rem >= unit; rem /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// podExecCommand returns the shell command that runs `args` in the given
+// container. The command is run through kubectl in the API server container, so
+// it must be executed on the leader.
+func podExecCommand(dbc db.Container, flags string, args ...string) string {
+	cmd := []string{"docker", "exec"}
+	if flags != "" {
+		cmd = append(cmd, flags)
+	}
+	cmd = append(cmd, supervisor.KubeAPIServerName, "kubectl", "exec")
+	if flags != "" {
+		cmd = append(cmd, flags)
+	}
+	cmd = append(cmd, dbc.PodName, "-c", podContainerName(dbc), "--")
+
+	for _, arg := range args {
+		cmd = append(cmd, shellQuote(arg))
+	}
+	return strings.Join(cmd, " ")
+}
+
+// podContainerName returns the name of the given container within its pod.
+// kubectl must be told which container to use, since pods can also contain
+// sidecars.
+func podContainerName(dbc db.Container) string {
+	if dbc.DaemonSet != "" {
+		return dbc.DaemonSet
+	}
+	return dbc.Hostname
+}
+
+// shellQuote quotes `s` so that it's interpreted as a single word by the
+// remote shell.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
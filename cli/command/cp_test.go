@@ -0,0 +1,228 @@
+package command
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/kelda/kelda/api/client/mocks"
+	"github.com/kelda/kelda/cli/ssh"
+	mockSSH "github.com/kelda/kelda/cli/ssh/mocks"
+	"github.com/kelda/kelda/connection"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/util"
+)
+
+func TestCopyFlags(t *testing.T) {
+	t.Parallel()
+
+	cpCmd := NewCopyCommand()
+	err := parseHelper(cpCmd, []string{"-i", "key", "-q", "foo", "8879:/tmp"})
+	assert.NoError(t, err)
+	assert.Equal(t, "key", cpCmd.privateKey)
+	assert.True(t, cpCmd.quiet)
+	assert.Equal(t, copyPath{path: "foo"}, cpCmd.src)
+	assert.Equal(t, copyPath{container: "8879", path: "/tmp"}, cpCmd.dst)
+
+	cpCmd = NewCopyCommand()
+	err = parseHelper(cpCmd, []string{"8879:/tmp/heap", "./a:b"})
+	assert.NoError(t, err)
+	assert.Equal(t, copyPath{container: "8879", path: "/tmp/heap"}, cpCmd.src)
+	assert.Equal(t, copyPath{path: "./a:b"}, cpCmd.dst)
+
+	assert.EqualError(t, parseHelper(NewCopyCommand(), []string{"foo"}),
+		"must specify a source and a destination")
+	assert.EqualError(t, parseHelper(NewCopyCommand(), []string{"foo", "/bar"}),
+		"either the source or the destination must be a container path")
+	assert.EqualError(t, parseHelper(NewCopyCommand(), []string{"a:foo", "b:bar"}),
+		"copying between containers is not supported")
+	assert.EqualError(t, parseHelper(NewCopyCommand(), []string{"a:", "bar"}),
+		"paths must not be empty")
+}
+
+func TestPodExecCommand(t *testing.T) {
+	t.Parallel()
+
+	dbc := db.Container{Hostname: "web", PodName: "pod"}
+	assert.Equal(t, "docker exec -i kube-apiserver kubectl exec -i pod -c web "+
+		"-- 'tar' 'xf' '-' '-C' '/it'\\''s'",
+		podExecCommand(dbc, "-i", "tar", "xf", "-", "-C", "/it's"))
+	assert.Equal(t, "docker exec kube-apiserver kubectl exec pod -c web -- 'ls'",
+		podExecCommand(dbc, "", "ls"))
+
+	// Daemon containers are named after the daemon set in their pods.
+	assert.Equal(t, "docker exec kube-apiserver kubectl exec pod -c agent -- "+
+		"'ls'", podExecCommand(db.Container{Hostname: "agent.10-0-0-1",
+		DaemonSet: "agent", PodName: "pod"}, "", "ls"))
+}
+
+func TestFormatBytes(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "10 B", formatBytes(10))
+	assert.Equal(t, "1.5 KiB", formatBytes(1536))
+	assert.Equal(t, "2.0 MiB", formatBytes(2<<20))
+}
+
+func setupCopyTest(mockSSHClient *mockSSH.Client) *Copy {
+	getLeaderIP = func(_ []db.Machine, _ connection.Credentials) (string, error) {
+		return "leader", nil
+	}
+	isTerminal = func() bool { return false }
+
+	mockClient := new(mocks.Client)
+	mockClient.On("QueryMachines").Return(nil, nil)
+	mockClient.On("QueryContainers").Return([]db.Container{
+		{BlueprintID: "8879", Hostname: "web", PodName: "pod"}}, nil)
+
+	mockSSHClient.On("Close").Return(nil)
+	return &Copy{
+		sshGetter: func(host, key string) (ssh.Client, error) {
+			return mockSSHClient, nil
+		},
+		connectionHelper: connectionHelper{client: mockClient},
+	}
+}
+
+func TestCopyUpload(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+	util.AppFs.MkdirAll("/local/dir/sub", 0755)
+	util.WriteFile("/local/dir/a", []byte("a"), 0644)
+	util.WriteFile("/local/dir/sub/b", []byte("b"), 0644)
+
+	mockSSHClient := new(mockSSH.Client)
+	cpCmd := setupCopyTest(mockSSHClient)
+	cpCmd.src = copyPath{path: "/local/dir"}
+	cpCmd.dst = copyPath{container: "8879", path: "/remote"}
+
+	mockSSHClient.On("CombinedOutput",
+		"docker exec kube-apiserver kubectl exec pod -c web -- "+
+			"'test' '-d' '/remote'").
+		Return(nil, errors.New("exit status 1"))
+
+	var archive []byte
+	mockSSHClient.On("Stream", "docker exec -i kube-apiserver kubectl exec -i "+
+		"pod -c web -- 'tar' 'xf' '-' '-C' '/'", mock.Anything, nil).
+		Run(func(args mock.Arguments) {
+			archive, _ = ioutil.ReadAll(args.Get(1).(io.Reader))
+		}).Return(nil)
+
+	assert.Equal(t, 0, cpCmd.Run())
+	mockSSHClient.AssertExpectations(t)
+
+	files := map[string]string{}
+	tr := tar.NewReader(bytes.NewReader(archive))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		contents, _ := ioutil.ReadAll(tr)
+		files[hdr.Name] = string(contents)
+	}
+	assert.Equal(t, map[string]string{
+		"remote/":      "",
+		"remote/a":     "a",
+		"remote/sub/":  "",
+		"remote/sub/b": "b",
+	}, files)
+
+	// When the destination is a directory, the source should be copied into it.
+	mockSSHClient = new(mockSSH.Client)
+	cpCmd = setupCopyTest(mockSSHClient)
+	cpCmd.src = copyPath{path: "/local/dir/a"}
+	cpCmd.dst = copyPath{container: "8879", path: "/remote"}
+
+	mockSSHClient.On("CombinedOutput", mock.Anything).Return(nil, nil)
+	mockSSHClient.On("Stream", "docker exec -i kube-apiserver kubectl exec -i "+
+		"pod -c web -- 'tar' 'xf' '-' '-C' '/remote'", mock.Anything, nil).
+		Run(func(args mock.Arguments) {
+			tr := tar.NewReader(args.Get(1).(io.Reader))
+			hdr, err := tr.Next()
+			assert.NoError(t, err)
+			assert.Equal(t, "a", hdr.Name)
+			ioutil.ReadAll(args.Get(1).(io.Reader))
+		}).Return(nil)
+	assert.Equal(t, 0, cpCmd.Run())
+	mockSSHClient.AssertExpectations(t)
+}
+
+func TestCopyDownload(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+	util.AppFs.MkdirAll("/local", 0755)
+
+	var archive bytes.Buffer
+	tw := tar.NewWriter(&archive)
+	tw.WriteHeader(&tar.Header{Name: "logs/", Typeflag: tar.TypeDir, Mode: 0755})
+	tw.WriteHeader(&tar.Header{Name: "logs/out", Typeflag: tar.TypeReg,
+		Mode: 0644, Size: 3})
+	tw.Write([]byte("out"))
+	tw.Close()
+
+	mockSSHClient := new(mockSSH.Client)
+	cpCmd := setupCopyTest(mockSSHClient)
+	cpCmd.src = copyPath{container: "8879", path: "/var/logs"}
+	cpCmd.dst = copyPath{path: "/local"}
+	mockSSHClient.On("Stream", "docker exec kube-apiserver kubectl exec "+
+		"pod -c web -- 'tar' 'cf' '-' '-C' '/var' 'logs'", nil, mock.Anything).
+		Run(func(args mock.Arguments) {
+			args.Get(2).(io.Writer).Write(archive.Bytes())
+		}).Return(nil)
+
+	assert.Equal(t, 0, cpCmd.Run())
+	contents, err := util.ReadFile("/local/logs/out")
+	assert.NoError(t, err)
+	assert.Equal(t, "out", contents)
+
+	// Copying to a path that doesn't exist should rename the source.
+	mockSSHClient = new(mockSSH.Client)
+	cpCmd = setupCopyTest(mockSSHClient)
+	cpCmd.src = copyPath{container: "8879", path: "/var/logs"}
+	cpCmd.dst = copyPath{path: "/local/renamed"}
+	mockSSHClient.On("Stream", mock.Anything, nil, mock.Anything).
+		Run(func(args mock.Arguments) {
+			args.Get(2).(io.Writer).Write(archive.Bytes())
+		}).Return(nil)
+
+	assert.Equal(t, 0, cpCmd.Run())
+	contents, err = util.ReadFile("/local/renamed/out")
+	assert.NoError(t, err)
+	assert.Equal(t, "out", contents)
+
+	// Archives that escape the destination directory should be rejected.
+	archive.Reset()
+	tw = tar.NewWriter(&archive)
+	tw.WriteHeader(&tar.Header{Name: "../evil", Typeflag: tar.TypeReg})
+	tw.Close()
+
+	mockSSHClient = new(mockSSH.Client)
+	cpCmd = setupCopyTest(mockSSHClient)
+	cpCmd.src = copyPath{container: "8879", path: "/var/logs"}
+	cpCmd.dst = copyPath{path: "/local"}
+	mockSSHClient.On("Stream", mock.Anything, nil, mock.Anything).
+		Run(func(args mock.Arguments) {
+			args.Get(2).(io.Writer).Write(archive.Bytes())
+		}).Return(nil)
+	assert.Equal(t, 1, cpCmd.Run())
+}
+
+func TestCopyNotContainer(t *testing.T) {
+	mockClient := new(mocks.Client)
+	mockClient.On("QueryMachines").Return([]db.Machine{{CloudID: "8879"}}, nil)
+	mockClient.On("QueryContainers").Return(nil, nil)
+
+	cpCmd := &Copy{
+		src:              copyPath{container: "8879", path: "/tmp"},
+		dst:              copyPath{path: "."},
+		connectionHelper: connectionHelper{client: mockClient},
+	}
+	assert.Equal(t, 1, cpCmd.Run())
+}
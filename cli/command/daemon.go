@@ -17,9 +17,11 @@ import (
 	"github.com/kelda/kelda/api/server"
 	cliPath "github.com/kelda/kelda/cli/path"
 	"github.com/kelda/kelda/cloud"
+	"github.com/kelda/kelda/cloud/autoscaler"
 	"github.com/kelda/kelda/cloud/foreman"
 	tlsIO "github.com/kelda/kelda/connection/tls/io"
 	"github.com/kelda/kelda/connection/tls/rsa"
+	"github.com/kelda/kelda/counter"
 	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/util"
 	"github.com/kelda/kelda/version"
@@ -29,6 +31,8 @@ import (
 
 // Daemon contains the options for running the Kelda daemon.
 type Daemon struct {
+	metricsAddr string
+
 	*connectionFlags
 }
 
@@ -45,6 +49,9 @@ var daemonExplanation = "Start the kelda daemon, which listens for kelda API req
 // InstallFlags sets up parsing for command line flags
 func (dCmd *Daemon) InstallFlags(flags *flag.FlagSet) {
 	dCmd.connectionFlags.InstallFlags(flags)
+	flags.StringVar(&dCmd.metricsAddr, "metrics-addr",
+		fmt.Sprintf("localhost:%d", counter.MetricsPort),
+		"the address to serve OpenMetrics on, or empty to disable it")
 	flags.Usage = func() {
 		util.PrintUsageString(daemonCommands, daemonExplanation, flags)
 	}
@@ -122,6 +129,14 @@ func (dCmd *Daemon) Run() int {
 	conn := db.New()
 	go server.Run(conn, dCmd.host, true, creds)
 
+	if dCmd.metricsAddr != "" {
+		go func() {
+			err := counter.ServeMetrics(dCmd.metricsAddr)
+			log.WithError(err).WithField("addr", dCmd.metricsAddr).Error(
+				"Failed to serve metrics")
+		}()
+	}
+
 	ca, err := tlsIO.ReadCA(cliPath.DefaultTLSDir)
 	if err != nil {
 		log.WithError(err).WithField("path", cliPath.DefaultTLSDir).Error(
@@ -130,6 +145,7 @@ func (dCmd *Daemon) Run() int {
 	}
 
 	go foreman.Run(conn, creds)
+	go autoscaler.Run(conn, creds)
 	go cloud.SyncCredentials(conn, sshKey, ca, kubeSecret)
 	cloud.Run(conn, getPublicKey(sshKey))
 	return 0
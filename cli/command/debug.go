@@ -16,6 +16,7 @@ import (
 
 	"github.com/kelda/kelda/cli/ssh"
 	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/minion/pprofile"
 	"github.com/kelda/kelda/minion/supervisor"
 	"github.com/kelda/kelda/util"
 )
@@ -92,6 +93,11 @@ var (
 	workerMachineCmds = logsForContainers(supervisor.OvncontrollerName,
 		supervisor.OvsvswitchdName, supervisor.KubeletName)
 
+	// The profiles collected from the daemon and from the minion on each
+	// machine. CPU profiles aren't collected because they take a while to
+	// sample, and are only useful when debugging specific performance issues.
+	bundleProfiles = []string{pprofile.HeapProfile, pprofile.GoroutineProfile}
+
 	// A list of commands to output various container logs. Container commands
 	// are formatted with the PodID, and are executed on the leader.
 	containerCmds = []logCmd{
@@ -254,6 +260,8 @@ func (dCmd Debug) downloadLogs(targets []logTarget) int {
 		}
 	}
 
+	errno += dCmd.downloadProfiles(rootDir, daemonTarget)
+
 	for _, t := range targets {
 		path := filepath.Join(rootDir, t.dir, t.id)
 		if err := util.Mkdir(path, 0755); err != nil {
@@ -262,6 +270,10 @@ func (dCmd Debug) downloadLogs(targets []logTarget) int {
 			continue
 		}
 
+		if t.dir == machineDir {
+			errno += dCmd.downloadProfiles(path, t.ip)
+		}
+
 		conn, err := dCmd.sshGetter(t.ip, dCmd.privateKey)
 		if err != nil {
 			errno++
@@ -300,6 +312,36 @@ func (dCmd Debug) downloadLogs(targets []logTarget) int {
 	return errno
 }
 
+// downloadProfiles writes the bundleProfiles of the daemon, or of the minion at
+// `host`, into `dir`. It returns the number of profiles that failed to
+// download.
+func (dCmd Debug) downloadProfiles(dir, host string) (errno int) {
+	for _, profileType := range bundleProfiles {
+		log.Debugf("Downloading %s profile from %s", profileType, host)
+
+		var profile []byte
+		var err error
+		if host == daemonTarget {
+			profile, err = dCmd.client.Profile(profileType, 0)
+		} else {
+			profile, err = dCmd.client.ProfileMinion(host, profileType, 0)
+		}
+		if err != nil {
+			log.WithError(err).Errorf("Failed to get %s profile from %s",
+				profileType, host)
+			errno++
+			continue
+		}
+
+		file := filepath.Join(dir, profileType+".prof")
+		if err := util.WriteFile(file, profile, 0644); err != nil {
+			errno++
+			log.Error(err)
+		}
+	}
+	return errno
+}
+
 func machinesToTargets(machines []db.Machine) []logTarget {
 	targets := []logTarget{}
 	for _, m := range machines {
@@ -0,0 +1,138 @@
+package command
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/kelda/kelda/api/client"
+	apiUtil "github.com/kelda/kelda/api/util"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/minion/pprofile"
+	"github.com/kelda/kelda/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DebugProfile contains the options for profiling the daemon or a minion.
+type DebugProfile struct {
+	profileType string
+	duration    time.Duration
+	outPath     string
+	target      string
+
+	connectionHelper
+}
+
+// NewDebugProfileCommand creates a new DebugProfile command instance.
+func NewDebugProfileCommand() *DebugProfile {
+	return &DebugProfile{}
+}
+
+var debugProfileCommands = "kelda debug-profile [OPTIONS] [MACHINE|daemon]"
+var debugProfileExplanation = fmt.Sprintf(`Collect a profile of the Kelda
+daemon or of the minion running on a machine, and download it to a local file.
+If no target is given, the daemon is profiled.
+
+The profile can be viewed with `+"`go tool pprof`"+`. CPU profiles are sampled
+for the given duration, while heap and goroutine profiles are snapshots.
+
+To collect a 30 second CPU profile of the minion on machine 09ed35808a0b:
+kelda debug-profile -duration 30s 09ed35808a0b
+
+To collect a heap profile of the daemon:
+kelda debug-profile -type heap %s`, daemonTarget)
+
+// InstallFlags sets up parsing for command line flags.
+func (pCmd *DebugProfile) InstallFlags(flags *flag.FlagSet) {
+	pCmd.connectionHelper.InstallFlags(flags)
+	flags.StringVar(&pCmd.profileType, "type", pprofile.CPUProfile,
+		"the type of profile to collect (cpu, heap, or goroutine)")
+	flags.DurationVar(&pCmd.duration, "duration", 30*time.Second,
+		"how long to sample CPU profiles for")
+	flags.StringVar(&pCmd.outPath, "o", "",
+		"output path for the profile (defaults to TARGET-TYPE.prof)")
+
+	flags.Usage = func() {
+		util.PrintUsageString(debugProfileCommands, debugProfileExplanation,
+			flags)
+	}
+}
+
+// Parse parses the command line arguments for the debug-profile command.
+func (pCmd *DebugProfile) Parse(args []string) error {
+	switch pCmd.profileType {
+	case pprofile.CPUProfile:
+		if pCmd.duration <= 0 || pCmd.duration > pprofile.MaxCPUDuration {
+			return fmt.Errorf("duration must be between 0 and %s",
+				pprofile.MaxCPUDuration)
+		}
+	case pprofile.HeapProfile, pprofile.GoroutineProfile:
+	default:
+		return fmt.Errorf("unknown profile type: %s", pCmd.profileType)
+	}
+
+	switch len(args) {
+	case 0:
+		pCmd.target = daemonTarget
+	case 1:
+		pCmd.target = args[0]
+	default:
+		return errors.New("only one target may be profiled at a time")
+	}
+
+	if pCmd.outPath == "" {
+		pCmd.outPath = profileFileName(pCmd.target, pCmd.profileType)
+	}
+	return nil
+}
+
+// Run collects the profile and writes it to the output path.
+func (pCmd *DebugProfile) Run() int {
+	if pCmd.profileType == pprofile.CPUProfile {
+		log.Infof("Collecting a %s CPU profile", pCmd.duration)
+	}
+
+	profile, err := collectProfile(pCmd.client, pCmd.target,
+		pCmd.profileType, pCmd.duration)
+	if err != nil {
+		log.WithError(err).WithField("target", pCmd.target).Error(
+			"Failed to collect profile")
+		return 1
+	}
+
+	if err := util.WriteFile(pCmd.outPath, profile, 0644); err != nil {
+		log.WithError(err).Error("Failed to write profile")
+		return 1
+	}
+
+	log.Infof("Wrote profile to %s. View it with `go tool pprof %s`",
+		pCmd.outPath, pCmd.outPath)
+	return 0
+}
+
+// collectProfile collects a profile from the daemon if `tgt` is daemonTarget,
+// and otherwise from the minion on the machine with ID `tgt`.
+func collectProfile(c client.Client, tgt, profileType string,
+	duration time.Duration) ([]byte, error) {
+	if tgt == daemonTarget {
+		return c.Profile(profileType, duration)
+	}
+
+	i, err := apiUtil.FuzzyLookup(c, tgt)
+	if err != nil {
+		return nil, fmt.Errorf("resolve machine: %s", err)
+	}
+
+	m, ok := i.(db.Machine)
+	if !ok {
+		return nil, fmt.Errorf("could not find machine: %s", tgt)
+	}
+
+	return c.ProfileMinion(m.PublicIP, profileType, duration)
+}
+
+func profileFileName(target, profileType string) string {
+	return fmt.Sprintf("%s-%s.prof", target, profileType)
+}
@@ -0,0 +1,80 @@
+package command
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kelda/kelda/api/client/mocks"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/util"
+)
+
+func TestDebugProfileFlags(t *testing.T) {
+	t.Parallel()
+
+	cmd := NewDebugProfileCommand()
+	err := parseHelper(cmd, []string{"-type", "heap", "-o", "out", "1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "heap", cmd.profileType)
+	assert.Equal(t, "out", cmd.outPath)
+	assert.Equal(t, "1", cmd.target)
+
+	cmd = NewDebugProfileCommand()
+	assert.NoError(t, parseHelper(cmd, nil))
+	assert.Equal(t, "cpu", cmd.profileType)
+	assert.Equal(t, 30*time.Second, cmd.duration)
+	assert.Equal(t, daemonTarget, cmd.target)
+	assert.Equal(t, "daemon-cpu.prof", cmd.outPath)
+
+	err = parseHelper(NewDebugProfileCommand(), []string{"-type", "block"})
+	assert.EqualError(t, err, "unknown profile type: block")
+
+	err = parseHelper(NewDebugProfileCommand(), []string{"-duration", "1h"})
+	assert.EqualError(t, err, "duration must be between 0 and 5m0s")
+
+	err = parseHelper(NewDebugProfileCommand(), []string{"1", "2"})
+	assert.EqualError(t, err, "only one target may be profiled at a time")
+}
+
+func TestDebugProfileRun(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+
+	mockClient := new(mocks.Client)
+	mockClient.On("Profile", "heap", time.Second).Return(
+		[]byte("daemon"), nil)
+	mockClient.On("QueryMachines").Return([]db.Machine{
+		{CloudID: "machine", PublicIP: "8.8.8.8"}}, nil)
+	mockClient.On("QueryContainers").Return(nil, nil)
+	mockClient.On("ProfileMinion", "8.8.8.8", "cpu", time.Second).Return(
+		[]byte("minion"), nil)
+
+	cmd := &DebugProfile{profileType: "heap", duration: time.Second,
+		target: daemonTarget, outPath: "daemon.prof",
+		connectionHelper: connectionHelper{client: mockClient}}
+	assert.Equal(t, 0, cmd.Run())
+
+	cmd = &DebugProfile{profileType: "cpu", duration: time.Second,
+		target: "mach", outPath: "minion.prof",
+		connectionHelper: connectionHelper{client: mockClient}}
+	assert.Equal(t, 0, cmd.Run())
+
+	for path, exp := range map[string]string{
+		"daemon.prof": "daemon", "minion.prof": "minion"} {
+		contents, err := util.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, exp, contents)
+	}
+
+	// The target must be a machine.
+	mockClient = new(mocks.Client)
+	mockClient.On("QueryMachines").Return(nil, nil)
+	mockClient.On("QueryContainers").Return([]db.Container{
+		{BlueprintID: "container"}}, nil)
+	cmd = &DebugProfile{profileType: "heap", target: "cont",
+		outPath:          "container.prof",
+		connectionHelper: connectionHelper{client: mockClient}}
+	assert.Equal(t, 1, cmd.Run())
+}
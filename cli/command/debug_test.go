@@ -17,6 +17,7 @@ import (
 	mockSSH "github.com/kelda/kelda/cli/ssh/mocks"
 	"github.com/kelda/kelda/connection"
 	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/minion/pprofile"
 	"github.com/kelda/kelda/util"
 )
 
@@ -492,6 +493,10 @@ func TestDebug(t *testing.T) {
 		mockLocalClient.On("QueryContainers").Return(
 			test.containers, test.containersErr)
 		mockLocalClient.On("Close").Return(nil)
+		mockLocalClient.On("Profile", mock.Anything, time.Duration(0)).Return(
+			[]byte("profile"), nil)
+		mockLocalClient.On("ProfileMinion", mock.Anything, mock.Anything,
+			time.Duration(0)).Return([]byte("profile"), nil)
 		testCmd.connectionHelper = connectionHelper{
 			client: mockLocalClient,
 		}
@@ -545,7 +550,8 @@ func containerFiles(rootDir, hostname string) []string {
 }
 
 func commonMachineFiles(rootDir, id string) []string {
-	return withParentFolder(rootDir, machineDir, id, machineCmds)
+	files := withParentFolder(rootDir, machineDir, id, machineCmds)
+	return append(files, profileFiles(filepath.Join(rootDir, machineDir, id))...)
 }
 
 func masterMachineFiles(rootDir, id string) []string {
@@ -562,6 +568,13 @@ func daemonFiles(rootDir string) (exp []string) {
 	for _, cmd := range daemonCmds {
 		exp = append(exp, filepath.Join(rootDir, cmd.name))
 	}
+	return append(exp, profileFiles(rootDir)...)
+}
+
+func profileFiles(dir string) (exp []string) {
+	for _, profileType := range bundleProfiles {
+		exp = append(exp, filepath.Join(dir, profileType+".prof"))
+	}
 	return exp
 }
 
@@ -578,3 +591,20 @@ func flatten(fileLists ...[]string) (files []string) {
 	}
 	return files
 }
+
+func TestDebugProfileErrors(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+
+	mockClient := new(mocks.Client)
+	mockClient.On("Profile", pprofile.HeapProfile, time.Duration(0)).Return(
+		nil, assert.AnError)
+	mockClient.On("Profile", pprofile.GoroutineProfile,
+		time.Duration(0)).Return([]byte("goroutines"), nil)
+
+	dCmd := Debug{connectionHelper: connectionHelper{client: mockClient}}
+	assert.Equal(t, 1, dCmd.downloadProfiles("out", daemonTarget))
+
+	files, err := listFiles()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"out/goroutine.prof"}, files)
+}
@@ -1,9 +1,15 @@
 package command
 
 import (
+	"bytes"
 	"errors"
 	"flag"
+	"fmt"
+	"io"
+	"os"
 	"strings"
+	"sync"
+	"time"
 
 	apiUtil "github.com/kelda/kelda/api/util"
 	"github.com/kelda/kelda/cli/ssh"
@@ -11,35 +17,63 @@ import (
 	"github.com/kelda/kelda/minion/supervisor"
 	"github.com/kelda/kelda/util"
 
+	"github.com/fatih/color"
 	log "github.com/sirupsen/logrus"
 )
 
 // Log is the structure for the `kelda logs` command.
 type Log struct {
-	privateKey string
-	shouldTail bool
+	privateKey     string
+	shouldTail     bool
+	showTimestamps bool
+	previous       bool
+	since          time.Duration
+	hostnamePrefix string
+	image          string
 
-	target string
+	targets []string
 
 	sshGetter ssh.Getter
 
 	connectionHelper
 }
 
+// logSource is a single stream of logs, and the SSH command that retrieves it.
+type logSource struct {
+	name string
+	host string
+	cmd  string
+}
+
+// The colors used to distinguish the log lines of different sources.
+var logColors = []color.Attribute{color.FgCyan, color.FgYellow, color.FgGreen,
+	color.FgMagenta, color.FgBlue, color.FgRed}
+
 // NewLogCommand creates a new Log command instance.
 func NewLogCommand() *Log {
 	return &Log{sshGetter: ssh.New}
 }
 
-var logCommands = `kelda logs [OPTIONS] ID`
-var logExplanation = `Fetch the logs of a container or machine minion. Either a container
-or machine ID must be supplied.
+var logCommands = `kelda logs [OPTIONS] [ID...]`
+var logExplanation = `Fetch the logs of containers or machine minions. Either
+container or machine IDs, or a container selector (-hostname or -image), must
+be supplied.
+
+If logs are fetched from multiple sources, the lines are interleaved and
+prefixed with the name of their source.
 
 To get the logs of container 8879fd2dbcee with a specific private key:
 kelda logs -i ~/.ssh/kelda 8879fd2dbcee
 
 To follow the logs of the minion on machine 09ed35808a0b:
-kelda logs -f 09ed35808a0b`
+kelda logs -f 09ed35808a0b
+
+To follow the logs of all containers whose hostname starts with "spark":
+kelda logs -f -hostname spark
+
+To show the last 10 minutes of logs, with timestamps, from all containers
+running the "nginx" image:
+kelda logs -since 10m -timestamps -image nginx`
 
 // InstallFlags sets up parsing for command line flags.
 func (lCmd *Log) InstallFlags(flags *flag.FlagSet) {
@@ -48,6 +82,16 @@ func (lCmd *Log) InstallFlags(flags *flag.FlagSet) {
 	flags.StringVar(&lCmd.privateKey, "i", "",
 		"path to the private key to use when connecting to the host")
 	flags.BoolVar(&lCmd.shouldTail, "f", false, "follow log output")
+	flags.BoolVar(&lCmd.showTimestamps, "timestamps", false,
+		"show timestamps")
+	flags.BoolVar(&lCmd.previous, "previous", false, "show the logs of the "+
+		"previous instance of the container, if it crashed")
+	flags.DurationVar(&lCmd.since, "since", 0, "only show logs newer than "+
+		"the given duration (e.g. 10m or 1h30m)")
+	flags.StringVar(&lCmd.hostnamePrefix, "hostname", "", "show the logs of "+
+		"all containers whose hostname starts with the given prefix")
+	flags.StringVar(&lCmd.image, "image", "", "show the logs of all "+
+		"containers running the given image")
 
 	flags.Usage = func() {
 		util.PrintUsageString(logCommands, logExplanation, flags)
@@ -56,72 +100,316 @@ func (lCmd *Log) InstallFlags(flags *flag.FlagSet) {
 
 // Parse parses the command line arguments for the `logs` command.
 func (lCmd *Log) Parse(args []string) error {
-	if len(args) == 0 {
+	if len(args) == 0 && lCmd.hostnamePrefix == "" && lCmd.image == "" {
 		return errors.New("must specify a target container or machine")
 	}
 
-	lCmd.target = args[0]
+	if lCmd.since < 0 {
+		return errors.New("since must be a positive duration")
+	}
+
+	lCmd.targets = args
 	return nil
 }
 
-// Run finds the target container or machine minion and outputs logs.
+// Run finds the target containers or machine minions and outputs logs.
 func (lCmd *Log) Run() int {
-	i, err := apiUtil.FuzzyLookup(lCmd.client, lCmd.target)
+	sources, err := lCmd.getSources()
 	if err != nil {
-		log.WithError(err).Errorf("Failed to lookup %s", lCmd.target)
+		log.Error(err)
 		return 1
 	}
 
-	var host string
-	var cmd []string
-	switch t := i.(type) {
-	case db.Machine:
-		host = t.PublicIP
-		cmd = []string{"docker", "logs"}
-		if lCmd.shouldTail {
-			cmd = append(cmd, "--follow")
+	// If there's only one source, pass the output straight through so that
+	// the logs are unchanged.
+	if len(sources) == 1 {
+		sshClient, err := lCmd.sshGetter(sources[0].host, lCmd.privateKey)
+		if err != nil {
+			log.WithError(err).Info("Error opening SSH connection")
+			return 1
 		}
-		cmd = append(cmd, "minion")
-	case db.Container:
-		if t.PodName == "" {
-			log.Error("Container not yet running")
+		defer sshClient.Close()
+
+		if err = sshClient.Run(false, sources[0].cmd); err != nil {
+			log.WithError(err).Info("Error running command over SSH")
 			return 1
 		}
+		return 0
+	}
+
+	return lCmd.streamLogs(sources, os.Stdout)
+}
+
+// getSources resolves the targets and container selectors into the log
+// sources that should be displayed.
+func (lCmd *Log) getSources() ([]logSource, error) {
+	var leaderIP string
+	getLeader := func() (string, error) {
+		if leaderIP != "" {
+			return leaderIP, nil
+		}
 
 		machines, err := lCmd.client.QueryMachines()
 		if err != nil {
-			log.WithError(err).Error("Failed to query machines")
-			return 1
+			return "", fmt.Errorf("failed to query machines: %s", err)
 		}
 
-		host, err = getLeaderIP(machines, lCmd.creds)
+		leaderIP, err = getLeaderIP(machines, lCmd.creds)
 		if err != nil {
-			log.WithError(err).Error("Failed to find leader machine")
-			return 1
+			return "", fmt.Errorf("failed to find leader machine: %s", err)
+		}
+		return leaderIP, nil
+	}
+
+	var sources []logSource
+	seen := map[string]struct{}{}
+	addContainer := func(c db.Container) error {
+		if _, ok := seen[c.BlueprintID]; ok {
+			return nil
+		}
+		seen[c.BlueprintID] = struct{}{}
+
+		host, err := getLeader()
+		if err != nil {
+			return err
 		}
+		sources = append(sources, lCmd.containerSource(host, c))
+		return nil
+	}
 
-		cmd = []string{
-			"docker", "exec", supervisor.KubeAPIServerName, "kubectl", "logs",
+	for _, target := range lCmd.targets {
+		i, err := apiUtil.FuzzyLookup(lCmd.client, target)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lookup %s: %s", target, err)
 		}
-		if lCmd.shouldTail {
-			cmd = append(cmd, "--follow")
+
+		switch t := i.(type) {
+		case db.Machine:
+			if lCmd.previous {
+				return nil, errors.New(
+					"previous logs are only available for containers")
+			}
+
+			if _, ok := seen[t.CloudID]; !ok {
+				seen[t.CloudID] = struct{}{}
+				sources = append(sources, lCmd.machineSource(t))
+			}
+		case db.Container:
+			if t.PodName == "" {
+				return nil, fmt.Errorf("container %s not yet running",
+					target)
+			}
+
+			if err := addContainer(t); err != nil {
+				return nil, err
+			}
+		default:
+			panic("Not Reached")
 		}
-		cmd = append(cmd, t.PodName)
-	default:
-		panic("Not Reached")
 	}
 
-	sshClient, err := lCmd.sshGetter(host, lCmd.privateKey)
+	if lCmd.hostnamePrefix == "" && lCmd.image == "" {
+		return sources, nil
+	}
+
+	containers, err := lCmd.client.QueryContainers()
 	if err != nil {
-		log.WithError(err).Info("Error opening SSH connection")
-		return 1
+		return nil, fmt.Errorf("failed to query containers: %s", err)
 	}
-	defer sshClient.Close()
 
-	if err = sshClient.Run(false, strings.Join(cmd, " ")); err != nil {
-		log.WithError(err).Info("Error running command over SSH")
-		return 1
+	var matched int
+	for _, c := range containers {
+		if !lCmd.selects(c) {
+			continue
+		}
+		matched++
+
+		if c.PodName == "" {
+			log.WithField("container", c.BlueprintID).Warn(
+				"Container not yet running")
+			continue
+		}
+
+		if err := addContainer(c); err != nil {
+			return nil, err
+		}
+	}
+
+	if matched == 0 {
+		return nil, errors.New("no containers match the given selectors")
+	}
+
+	if len(sources) == 0 {
+		return nil, errors.New("none of the matching containers are running")
+	}
+	return sources, nil
+}
+
+// selects returns whether `c` matches the container selectors.
+func (lCmd *Log) selects(c db.Container) bool {
+	if lCmd.hostnamePrefix != "" &&
+		!strings.HasPrefix(c.Hostname, lCmd.hostnamePrefix) {
+		return false
+	}
+
+	if lCmd.image != "" && c.Image != lCmd.image &&
+		!strings.HasPrefix(c.Image, lCmd.image+":") {
+		return false
+	}
+	return true
+}
+
+func (lCmd *Log) machineSource(m db.Machine) logSource {
+	cmd := append([]string{"docker", "logs"}, lCmd.commonFlags()...)
+	cmd = append(cmd, "minion")
+	return logSource{
+		name: m.CloudID,
+		host: m.PublicIP,
+		cmd:  strings.Join(cmd, " "),
+	}
+}
+
+func (lCmd *Log) containerSource(leaderIP string, c db.Container) logSource {
+	cmd := []string{
+		"docker", "exec", supervisor.KubeAPIServerName, "kubectl", "logs",
+	}
+	cmd = append(cmd, lCmd.commonFlags()...)
+	if lCmd.previous {
+		cmd = append(cmd, "--previous")
+	}
+	cmd = append(cmd, c.PodName, "-c", podContainerName(c))
+
+	name := c.Hostname
+	if name == "" {
+		name = c.BlueprintID
+	}
+	return logSource{name: name, host: leaderIP, cmd: strings.Join(cmd, " ")}
+}
+
+// commonFlags returns the flags that are understood by both `docker logs` and
+// `kubectl logs`.
+func (lCmd *Log) commonFlags() (flags []string) {
+	if lCmd.shouldTail {
+		flags = append(flags, "--follow")
+	}
+	if lCmd.showTimestamps {
+		flags = append(flags, "--timestamps")
+	}
+	if lCmd.since != 0 {
+		flags = append(flags, "--since="+lCmd.since.String())
+	}
+	return flags
+}
+
+// streamLogs concurrently fetches the logs from each source, and writes them
+// to `out` with each line prefixed by the name of its source.
+func (lCmd *Log) streamLogs(sources []logSource, out io.Writer) int {
+	var nameLen int
+	for _, src := range sources {
+		if len(src.name) > nameLen {
+			nameLen = len(src.name)
+		}
+	}
+
+	// Share SSH connections between sources on the same host.
+	clients := map[string]ssh.Client{}
+	defer func() {
+		for _, c := range clients {
+			c.Close()
+		}
+	}()
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	var errCount int
+	for i, src := range sources {
+		sshClient, ok := clients[src.host]
+		if !ok {
+			var err error
+			sshClient, err = lCmd.sshGetter(src.host, lCmd.privateKey)
+			if err != nil {
+				log.WithError(err).WithField("host", src.host).Error(
+					"Error opening SSH connection")
+
+				lock.Lock()
+				errCount++
+				lock.Unlock()
+				continue
+			}
+			clients[src.host] = sshClient
+		}
+
+		prefix := color.New(logColors[i%len(logColors)]).Sprintf(
+			"%-*s | ", nameLen, src.name)
+		pw := &prefixWriter{out: out, lock: &lock, prefix: prefix}
+
+		wg.Add(1)
+		go func(src logSource, sshClient ssh.Client) {
+			defer wg.Done()
+
+			// Stderr is merged into stdout so that lines written to it,
+			// such as the minion's own logs, are prefixed as well.
+			err := sshClient.Stream(src.cmd+" 2>&1", nil, pw)
+			pw.Flush()
+			if err != nil {
+				log.WithError(err).WithField("source", src.name).Error(
+					"Error fetching logs")
+
+				lock.Lock()
+				errCount++
+				lock.Unlock()
+			}
+		}(src, sshClient)
 	}
+	wg.Wait()
 
+	if errCount != 0 {
+		return 1
+	}
 	return 0
 }
+
+// prefixWriter writes each line written to it to `out`, preceded by `prefix`.
+// Partial lines are buffered until they're completed so that lines from
+// different writers sharing `lock` aren't interleaved.
+type prefixWriter struct {
+	out    io.Writer
+	lock   *sync.Mutex
+	prefix string
+	buf    []byte
+}
+
+func (pw *prefixWriter) Write(p []byte) (int, error) {
+	pw.buf = append(pw.buf, p...)
+	for {
+		i := bytes.IndexByte(pw.buf, '\n')
+		if i < 0 {
+			break
+		}
+
+		if err := pw.writeLine(pw.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		pw.buf = pw.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes any buffered partial line.
+func (pw *prefixWriter) Flush() error {
+	if len(pw.buf) == 0 {
+		return nil
+	}
+
+	err := pw.writeLine(append(pw.buf, '\n'))
+	pw.buf = nil
+	return err
+}
+
+func (pw *prefixWriter) writeLine(line []byte) error {
+	pw.lock.Lock()
+	defer pw.lock.Unlock()
+
+	_, err := fmt.Fprintf(pw.out, "%s%s", pw.prefix, line)
+	return err
+}
@@ -0,0 +1,115 @@
+// +build !windows
+
+package command
+
+import (
+	"errors"
+	"flag"
+
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/minion/docker"
+	"github.com/kelda/kelda/minion/logship"
+	"github.com/kelda/kelda/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// LogCollector contains the options for running the log collector. The log
+// collector is booted by the minion supervisor, and shouldn't normally be run
+// by users.
+type LogCollector struct {
+	receive bool
+
+	machine   string
+	namespace string
+	leaderIP  string
+	sink      blueprint.LogSink
+}
+
+// NewLogCollectorCommand creates a new LogCollector command instance.
+func NewLogCollectorCommand() *LogCollector {
+	return &LogCollector{}
+}
+
+var logCollectorCommands = "kelda log-collector [OPTIONS]"
+var logCollectorExplanation = `Ship the logs of the containers on this machine
+to a log sink. If -receive is set, instead receive logs from the collectors on
+other machines, and append them to the file at -path.
+
+This command is run automatically by the Kelda minion.`
+
+// InstallFlags sets up parsing for command line flags.
+func (lcCmd *LogCollector) InstallFlags(flags *flag.FlagSet) {
+	flags.BoolVar(&lcCmd.receive, "receive", false,
+		"receive logs from other machines rather than collecting them")
+	flags.StringVar(&lcCmd.machine, "machine", "",
+		"the IP of the machine the collector is running on")
+	flags.StringVar(&lcCmd.namespace, "namespace", "",
+		"the namespace of the blueprint")
+	flags.StringVar(&lcCmd.leaderIP, "leader", "",
+		"the IP of the leader master, which receives logs for file sinks")
+	flags.StringVar(&lcCmd.sink.Type, "type", "",
+		"the type of the log sink (syslog, file, or http)")
+	flags.StringVar(&lcCmd.sink.Address, "address", "",
+		"the address of the syslog server, or URL of the HTTP endpoint")
+	flags.StringVar(&lcCmd.sink.Protocol, "protocol", "",
+		"the protocol used to connect to the syslog server (tcp or udp)")
+	flags.StringVar(&lcCmd.sink.Path, "path", "",
+		"the path of the file that received logs are written to")
+
+	flags.Usage = func() {
+		util.PrintUsageString(logCollectorCommands, logCollectorExplanation,
+			flags)
+	}
+}
+
+// Parse parses the command line arguments for the log-collector command.
+func (lcCmd *LogCollector) Parse(args []string) error {
+	if lcCmd.receive && lcCmd.sink.Path == "" {
+		return errors.New("must specify the path to write received logs to")
+	}
+
+	if !lcCmd.receive && lcCmd.sink.Type == "" {
+		return errors.New("must specify the type of the log sink")
+	}
+	return nil
+}
+
+// BeforeRun makes any necessary post-parsing transformations.
+func (lcCmd *LogCollector) BeforeRun() error {
+	return nil
+}
+
+// AfterRun performs any necessary post-run cleanup.
+func (lcCmd *LogCollector) AfterRun() error {
+	return nil
+}
+
+// Run collects or receives logs until the process is killed.
+func (lcCmd *LogCollector) Run() int {
+	if lcCmd.receive {
+		sink, err := logship.NewFileSink(lcCmd.sink.Path)
+		if err != nil {
+			log.WithError(err).Error("Failed to open log file")
+			return 1
+		}
+
+		log.WithField("path", lcCmd.sink.Path).Info("Receiving logs")
+		if err := logship.Receive(sink); err != nil {
+			log.WithError(err).Error("Failed to receive logs")
+			return 1
+		}
+		return 0
+	}
+
+	sink, err := logship.NewSink(lcCmd.sink, lcCmd.leaderIP)
+	if err != nil {
+		log.WithError(err).Error("Failed to create log sink")
+		return 1
+	}
+
+	log.WithField("sink", lcCmd.sink.Type).Info("Collecting logs")
+	dk := docker.New("unix:///var/run/docker.sock")
+	logship.NewCollector(dk, sink, lcCmd.machine, lcCmd.namespace).Run()
+	return 0
+}
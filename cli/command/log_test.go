@@ -1,11 +1,18 @@
 package command
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 
 	"github.com/kelda/kelda/api/client/mocks"
 	"github.com/kelda/kelda/cli/ssh"
@@ -19,25 +26,42 @@ func checkLogParsing(t *testing.T, args []string, exp Log, expErr error) {
 	err := parseHelper(logsCmd, args)
 
 	assert.Equal(t, expErr, err)
-	assert.Equal(t, exp.target, logsCmd.target)
+	assert.Equal(t, exp.targets, logsCmd.targets)
 	assert.Equal(t, exp.privateKey, logsCmd.privateKey)
 	assert.Equal(t, exp.shouldTail, logsCmd.shouldTail)
+	assert.Equal(t, exp.showTimestamps, logsCmd.showTimestamps)
+	assert.Equal(t, exp.previous, logsCmd.previous)
+	assert.Equal(t, exp.since, logsCmd.since)
+	assert.Equal(t, exp.hostnamePrefix, logsCmd.hostnamePrefix)
+	assert.Equal(t, exp.image, logsCmd.image)
 }
 
 func TestLogFlags(t *testing.T) {
 	t.Parallel()
 
 	checkLogParsing(t, []string{"1"}, Log{
-		target: "1",
+		targets: []string{"1"},
 	}, nil)
 	checkLogParsing(t, []string{"-i", "key", "1"}, Log{
-		target:     "1",
+		targets:    []string{"1"},
 		privateKey: "key",
 	}, nil)
 	checkLogParsing(t, []string{"-f", "1"}, Log{
-		target:     "1",
+		targets:    []string{"1"},
 		shouldTail: true,
 	}, nil)
+	checkLogParsing(t, []string{"-timestamps", "-previous", "-since", "1h",
+		"1", "2"}, Log{
+		targets:        []string{"1", "2"},
+		showTimestamps: true,
+		previous:       true,
+		since:          time.Hour,
+	}, nil)
+	checkLogParsing(t, []string{"-hostname", "spark", "-image", "nginx"}, Log{
+		targets:        []string{},
+		hostnamePrefix: "spark",
+		image:          "nginx",
+	}, nil)
 	checkLogParsing(t, []string{}, Log{},
 		errors.New("must specify a target container or machine"))
 }
@@ -61,29 +85,53 @@ func TestLog(t *testing.T) {
 	tests := []logTest{
 		// Target container.
 		{
-			cmd:     Log{target: targetContainer},
+			cmd:     Log{targets: []string{targetContainer}},
 			expHost: leaderHost,
 			expSSHCommand: fmt.Sprintf(
-				"docker exec kube-apiserver kubectl logs %s",
+				"docker exec kube-apiserver kubectl logs %s -c web",
 				targetContainerPodName),
 		},
 		// Target machine.
 		{
-			cmd:           Log{target: targetMachine},
+			cmd:           Log{targets: []string{targetMachine}},
 			expHost:       "machine",
 			expSSHCommand: "docker logs minion",
 		},
 		// Tail flag
 		{
 			cmd: Log{
-				target:     targetContainer,
+				targets:    []string{targetContainer},
 				shouldTail: true,
 			},
 			expHost: leaderHost,
 			expSSHCommand: fmt.Sprintf(
-				"docker exec kube-apiserver kubectl logs --follow %s",
+				"docker exec kube-apiserver kubectl logs --follow "+
+					"%s -c web",
 				targetContainerPodName),
 		},
+		// Timestamps, since, and previous flags.
+		{
+			cmd: Log{
+				targets:        []string{targetContainer},
+				showTimestamps: true,
+				since:          10 * time.Minute,
+				previous:       true,
+			},
+			expHost: leaderHost,
+			expSSHCommand: fmt.Sprintf(
+				"docker exec kube-apiserver kubectl logs --timestamps "+
+					"--since=10m0s --previous %s -c web",
+				targetContainerPodName),
+		},
+		{
+			cmd: Log{
+				targets:        []string{targetMachine},
+				showTimestamps: true,
+				since:          time.Hour,
+			},
+			expHost:       "machine",
+			expSSHCommand: "docker logs --timestamps --since=1h0m0s minion",
+		},
 	}
 
 	mockLocalClient := new(mocks.Client)
@@ -96,6 +144,7 @@ func TestLog(t *testing.T) {
 	}}, nil)
 	mockLocalClient.On("QueryContainers").Return([]db.Container{{
 		BlueprintID: targetContainer,
+		Hostname:    "web",
 		PodName:     targetContainerPodName,
 		Minion:      "containerPriv",
 	}}, nil)
@@ -134,7 +183,7 @@ func TestLogAmbiguousID(t *testing.T) {
 
 	testCmd := Log{
 		connectionHelper: connectionHelper{client: mockClient},
-		target:           "foo",
+		targets:          []string{"foo"},
 	}
 	assert.Equal(t, 1, testCmd.Run())
 }
@@ -151,7 +200,7 @@ func TestLogNoMatch(t *testing.T) {
 
 	testCmd := Log{
 		connectionHelper: connectionHelper{client: mockClient},
-		target:           "bar",
+		targets:          []string{"bar"},
 	}
 	assert.Equal(t, 1, testCmd.Run())
 }
@@ -166,7 +215,138 @@ func TestLogScheduledContainer(t *testing.T) {
 
 	testCmd := Log{
 		connectionHelper: connectionHelper{client: mockClient},
-		target:           "foo",
+		targets:          []string{"foo"},
 	}
 	assert.Equal(t, 1, testCmd.Run())
 }
+
+func TestLogPreviousMachine(t *testing.T) {
+	mockClient := new(mocks.Client)
+	mockClient.On("QueryMachines").Return([]db.Machine{{CloudID: "foo"}}, nil)
+	mockClient.On("QueryContainers").Return(nil, nil)
+
+	testCmd := Log{
+		connectionHelper: connectionHelper{client: mockClient},
+		targets:          []string{"foo"},
+		previous:         true,
+	}
+	assert.Equal(t, 1, testCmd.Run())
+}
+
+func TestLogMultipleSources(t *testing.T) {
+	getLeaderIP = func(_ []db.Machine, _ connection.Credentials) (string, error) {
+		return "leader", nil
+	}
+
+	mockClient := new(mocks.Client)
+	mockClient.On("QueryMachines").Return([]db.Machine{{
+		CloudID:  "machine",
+		PublicIP: "machineIP",
+	}}, nil)
+	mockClient.On("QueryContainers").Return([]db.Container{
+		{BlueprintID: "1", Hostname: "spark-ms", PodName: "pod1",
+			Image: "spark:2.0"},
+		{BlueprintID: "2", Hostname: "spark-wk", PodName: "pod2",
+			Image: "spark"},
+		{BlueprintID: "3", Hostname: "spark-wk-2", Image: "spark"},
+		{BlueprintID: "4", Hostname: "nginx", PodName: "pod4",
+			Image: "nginx"},
+	}, nil)
+
+	mockLeader := new(mockSSH.Client)
+	mockLeader.On("Close").Return(nil)
+	mockLeader.On("Stream", mock.Anything, nil, mock.Anything).Run(
+		func(args mock.Arguments) {
+			cmd := args.String(0)
+			pod := cmd[:strings.Index(cmd, " -c ")]
+			pod = pod[strings.LastIndex(pod, " ")+1:]
+			args.Get(2).(io.Writer).Write([]byte(pod + " a\n" + pod))
+		}).Return(nil)
+
+	mockMachine := new(mockSSH.Client)
+	mockMachine.On("Close").Return(nil)
+	mockMachine.On("Stream", "docker logs --follow minion 2>&1", nil,
+		mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(2).(io.Writer).Write([]byte("minion\n"))
+	}).Return(nil)
+
+	var out bytes.Buffer
+	testCmd := Log{
+		connectionHelper: connectionHelper{client: mockClient},
+		targets:          []string{"machine", "nginx"},
+		hostnamePrefix:   "spark",
+		shouldTail:       true,
+		sshGetter: func(host, _ string) (ssh.Client, error) {
+			if host == "leader" {
+				return mockLeader, nil
+			}
+			assert.Equal(t, "machineIP", host)
+			return mockMachine, nil
+		},
+	}
+
+	sources, err := testCmd.getSources()
+	assert.NoError(t, err)
+	assert.Len(t, sources, 4)
+
+	assert.Equal(t, 0, testCmd.streamLogs(sources, &out))
+	mockLeader.AssertExpectations(t)
+	mockMachine.AssertExpectations(t)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	sort.Strings(lines)
+	assert.Equal(t, []string{
+		"machine  | minion",
+		"nginx    | pod4",
+		"nginx    | pod4 a",
+		"spark-ms | pod1",
+		"spark-ms | pod1 a",
+		"spark-wk | pod2",
+		"spark-wk | pod2 a",
+	}, lines)
+
+	// Select by image.
+	testCmd = Log{
+		connectionHelper: connectionHelper{client: mockClient},
+		image:            "spark",
+	}
+	sources, err = testCmd.getSources()
+	assert.NoError(t, err)
+	assert.Equal(t, []logSource{
+		{
+			name: "spark-ms",
+			host: "leader",
+			cmd:  "docker exec kube-apiserver kubectl logs pod1 -c spark-ms",
+		},
+		{
+			name: "spark-wk",
+			host: "leader",
+			cmd:  "docker exec kube-apiserver kubectl logs pod2 -c spark-wk",
+		},
+	}, sources)
+
+	// No matches.
+	testCmd = Log{
+		connectionHelper: connectionHelper{client: mockClient},
+		image:            "redis",
+	}
+	_, err = testCmd.getSources()
+	assert.EqualError(t, err, "no containers match the given selectors")
+}
+
+func TestPrefixWriter(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	pw := &prefixWriter{out: &out, lock: &sync.Mutex{}, prefix: "p | "}
+
+	pw.Write([]byte("a\nb"))
+	assert.Equal(t, "p | a\n", out.String())
+
+	pw.Write([]byte("c\nd\n"))
+	assert.Equal(t, "p | a\np | bc\np | d\n", out.String())
+
+	pw.Write([]byte("e"))
+	pw.Flush()
+	assert.Equal(t, "p | a\np | bc\np | d\np | e\n", out.String())
+}
@@ -3,32 +3,158 @@ package command
 import (
 	"errors"
 	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
 
+	units "github.com/docker/go-units"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/util"
 )
 
+// The subcommands of the secret command. If no subcommand is given, the secret
+// is set.
+const (
+	secretList   = "ls"
+	secretRemove = "rm"
+	secretRotate = "rotate"
+)
+
 // Secret defines the options for the Secret command.
 type Secret struct {
+	subcommand  string
 	name, value string
+	valuePath   string
+	force       bool
+
+	// The hostnames of the containers allowed to use the secret.
+	allowedHostnames []string
+
 	connectionHelper
 }
 
-var secretCommands = "kelda secret NAME VALUE"
-var secretExplanation = `Securely set a secret association. This command must
-be run before any containers referencing the associated secret can be started.`
+// The source of secret values read with `-f -`. It's a variable so that it can
+// be mocked during unit tests.
+var secretStdin io.Reader = os.Stdin
+
+var secretCommands = `kelda secret [OPTIONS] NAME [VALUE]
+       kelda secret ls
+       kelda secret rm [-force] NAME
+       kelda secret rotate [-f FILE] NAME [VALUE]`
+var secretExplanation = `Securely manage the secrets in the cluster.
+
+The first form sets a secret association. This command must be run before any
+containers referencing the associated secret can be started. Instead of
+passing the value as an argument, it can be read from a file with -f, which is
+useful for binary content. Use "-f -" to read the value from stdin.
+
+By default, any container may use a secret. -allow restricts the secret to the
+containers with the given comma-separated hostnames, or the members of the
+stateful and replica sets with the given names. Containers that reference the
+secret without being allowed to are not started. The hostnames
+allowed to use an existing secret are unchanged unless -allow is given, and
+"-allow *" allows any container to use it again.
+
+ls lists the secrets in the cluster, when they were last modified, and which
+containers reference them.
+
+rm removes a secret from the cluster. Secrets that are referenced by
+containers are not removed unless -force is given.
+
+rotate changes the value of an existing secret. Only the containers that
+reference the secret are restarted to pick up the new value.
+
+To set the secret "key" from a file, and only allow the "web" and "proxy"
+containers to use it:
+kelda secret -f key.pem -allow web,proxy key`
 
 // InstallFlags sets up parsing for command line flags.
 func (secretCmd *Secret) InstallFlags(flags *flag.FlagSet) {
 	secretCmd.connectionHelper.InstallFlags(flags)
+	installSecretValueFlag(flags, &secretCmd.valuePath)
+	flags.Var((*commaSeparatedFlag)(&secretCmd.allowedHostnames), "allow",
+		"comma-separated hostnames of the containers allowed to use the "+
+			"secret, or * to allow any container")
 	flags.Usage = func() {
 		util.PrintUsageString(secretCommands, secretExplanation, flags)
 	}
 }
 
+func installSecretValueFlag(flags *flag.FlagSet, valuePath *string) {
+	flags.StringVar(valuePath, "f", "",
+		"read the secret value from a file, or from stdin if the file is -")
+}
+
 // Parse parses the command line arguments for the secret command.
 func (secretCmd *Secret) Parse(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case secretList, secretRemove, secretRotate:
+			if len(secretCmd.allowedHostnames) != 0 {
+				return fmt.Errorf("-allow cannot be used with %s",
+					args[0])
+			}
+		}
+
+		switch args[0] {
+		case secretList:
+			if len(args) != 1 {
+				return errors.New("ls does not take any arguments")
+			}
+			secretCmd.subcommand = secretList
+			return nil
+		case secretRemove:
+			secretCmd.subcommand = secretRemove
+			flags := flag.NewFlagSet(secretRemove, flag.ContinueOnError)
+			flags.BoolVar(&secretCmd.force, "force", false,
+				"remove the secret even if containers reference it")
+			if err := flags.Parse(args[1:]); err != nil {
+				return err
+			}
+
+			if flags.NArg() != 1 {
+				return errors.New("a single name must be supplied")
+			}
+			secretCmd.name = flags.Arg(0)
+			return nil
+		case secretRotate:
+			secretCmd.subcommand = secretRotate
+			flags := flag.NewFlagSet(secretRotate, flag.ContinueOnError)
+			installSecretValueFlag(flags, &secretCmd.valuePath)
+			if err := flags.Parse(args[1:]); err != nil {
+				return err
+			}
+			args = flags.Args()
+		}
+	}
+
+	return secretCmd.parseNameAndValue(args)
+}
+
+func (secretCmd *Secret) parseNameAndValue(args []string) error {
+	if secretCmd.valuePath != "" {
+		if len(args) != 1 {
+			return errors.New("a name must be supplied, and the value " +
+				"cannot be given as an argument when using -f")
+		}
+
+		value, err := readSecretValue(secretCmd.valuePath)
+		if err != nil {
+			return fmt.Errorf("read secret value: %s", err)
+		}
+
+		secretCmd.name = args[0]
+		secretCmd.value = value
+		return nil
+	}
+
 	if len(args) != 2 {
 		return errors.New("a name and value must be supplied")
 	}
@@ -38,12 +164,177 @@ func (secretCmd *Secret) Parse(args []string) error {
 	return nil
 }
 
+func readSecretValue(path string) (string, error) {
+	if path != "-" {
+		return util.ReadFile(path)
+	}
+
+	value, err := ioutil.ReadAll(secretStdin)
+	return string(value), err
+}
+
 // Run implements the secret command.
 func (secretCmd Secret) Run() int {
-	err := secretCmd.client.SetSecret(secretCmd.name, secretCmd.value)
+	var err error
+	switch secretCmd.subcommand {
+	case secretList:
+		err = secretCmd.list(os.Stdout)
+	case secretRemove:
+		err = secretCmd.remove()
+	case secretRotate:
+		err = secretCmd.rotate()
+	default:
+		err = secretCmd.client.SetSecret(secretCmd.name, secretCmd.value,
+			secretCmd.allowedHostnames)
+		if err != nil {
+			err = fmt.Errorf("set secret: %s", err)
+		}
+	}
+
 	if err != nil {
-		log.WithError(err).Error("Failed to set secret")
+		log.WithError(err).Error("Failed to run secret command")
 		return 1
 	}
 	return 0
 }
+
+func (secretCmd Secret) list(out io.Writer) error {
+	secrets, err := secretCmd.client.ListSecrets()
+	if err != nil {
+		return fmt.Errorf("list secrets: %s", err)
+	}
+
+	references, err := secretCmd.references()
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for name := range secrets {
+		names = append(names, name)
+	}
+	for name := range references {
+		if _, ok := secrets[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(out, 0, 0, 4, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintln(w, "NAME\tLAST MODIFIED\tCONTAINERS")
+	for _, name := range names {
+		lastModified := "not set"
+		if modTime, ok := secrets[name]; ok {
+			lastModified = fmt.Sprintf("%s ago",
+				units.HumanDuration(time.Since(modTime)))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, lastModified,
+			strings.Join(references[name], ","))
+	}
+	return nil
+}
+
+func (secretCmd Secret) remove() error {
+	references, err := secretCmd.references()
+	if err != nil {
+		return err
+	}
+
+	if dependents := references[secretCmd.name]; len(dependents) != 0 &&
+		!secretCmd.force {
+		return fmt.Errorf("secret is referenced by containers %s. "+
+			"Use -force to remove it anyway",
+			strings.Join(dependents, ", "))
+	}
+
+	if err := secretCmd.client.DeleteSecret(secretCmd.name); err != nil {
+		return fmt.Errorf("delete secret: %s", err)
+	}
+	return nil
+}
+
+func (secretCmd Secret) rotate() error {
+	secrets, err := secretCmd.client.ListSecrets()
+	if err != nil {
+		return fmt.Errorf("list secrets: %s", err)
+	}
+
+	if _, ok := secrets[secretCmd.name]; !ok {
+		return fmt.Errorf("secret %s does not exist. Use `kelda secret` "+
+			"to set it", secretCmd.name)
+	}
+
+	references, err := secretCmd.references()
+	if err != nil {
+		return err
+	}
+
+	// The hostnames allowed to use the secret aren't changed by rotations.
+	if err := secretCmd.client.SetSecret(secretCmd.name,
+		secretCmd.value, nil); err != nil {
+		return fmt.Errorf("set secret: %s", err)
+	}
+
+	// The containers that reference the secret are restarted by the
+	// scheduler because the hash of the secret value changes.
+	if dependents := references[secretCmd.name]; len(dependents) != 0 {
+		log.Infof("Rotated secret %s. Restarting containers: %s",
+			secretCmd.name, strings.Join(dependents, ", "))
+	} else {
+		log.Infof("Rotated secret %s. No containers reference it",
+			secretCmd.name)
+	}
+	return nil
+}
+
+// references returns a map from secret name to the short blueprint IDs of the
+// containers that reference the secret.
+func (secretCmd Secret) references() (map[string][]string, error) {
+	containers, err := secretCmd.client.QueryContainers()
+	if err != nil {
+		return nil, fmt.Errorf("unable to query containers: %s", err)
+	}
+
+	sort.Sort(db.ContainerSlice(containers))
+	references := map[string][]string{}
+	for _, dbc := range containers {
+		for _, name := range uniqueStrings(dbc.GetReferencedSecrets()) {
+			references[name] = append(references[name],
+				util.ShortUUID(dbc.BlueprintID))
+		}
+	}
+	return references, nil
+}
+
+func uniqueStrings(strs []string) (unique []string) {
+	seen := map[string]struct{}{}
+	for _, str := range strs {
+		if _, ok := seen[str]; !ok {
+			seen[str] = struct{}{}
+			unique = append(unique, str)
+		}
+	}
+	return unique
+}
+
+// commaSeparatedFlag is a flag.Value that parses a comma-separated list.
+type commaSeparatedFlag []string
+
+func (csf *commaSeparatedFlag) String() string {
+	return strings.Join(*csf, ",")
+}
+
+func (csf *commaSeparatedFlag) Set(value string) error {
+	*csf = nil
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*csf = append(*csf, item)
+		}
+	}
+
+	if len(*csf) == 0 {
+		return errors.New("must not be empty")
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	units "github.com/docker/go-units"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/kelda/kelda/api/client/mocks"
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/util"
+)
+
+func TestSecretFlags(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+	util.WriteFile("key.pem", []byte{0, 1, 2}, 0644)
+
+	cmd := &Secret{}
+	assert.NoError(t, parseHelper(cmd, []string{"name", "value"}))
+	assert.Equal(t, "", cmd.subcommand)
+	assert.Equal(t, "name", cmd.name)
+	assert.Equal(t, "value", cmd.value)
+
+	cmd = &Secret{}
+	assert.NoError(t, parseHelper(cmd, []string{"-f", "key.pem", "name"}))
+	assert.Equal(t, "name", cmd.name)
+	assert.Equal(t, string([]byte{0, 1, 2}), cmd.value)
+
+	secretStdin = strings.NewReader("stdin")
+	cmd = &Secret{}
+	assert.NoError(t, parseHelper(cmd, []string{"-f", "-", "name"}))
+	assert.Equal(t, "stdin", cmd.value)
+
+	cmd = &Secret{}
+	assert.NoError(t, parseHelper(cmd,
+		[]string{"-allow", "web, proxy", "name", "value"}))
+	assert.Equal(t, []string{"web", "proxy"}, cmd.allowedHostnames)
+
+	cmd = &Secret{}
+	assert.NoError(t, parseHelper(cmd, []string{"ls"}))
+	assert.Equal(t, secretList, cmd.subcommand)
+
+	cmd = &Secret{}
+	assert.NoError(t, parseHelper(cmd, []string{"rm", "-force", "name"}))
+	assert.Equal(t, secretRemove, cmd.subcommand)
+	assert.Equal(t, "name", cmd.name)
+	assert.True(t, cmd.force)
+
+	cmd = &Secret{}
+	assert.NoError(t, parseHelper(cmd,
+		[]string{"rotate", "-f", "key.pem", "name"}))
+	assert.Equal(t, secretRotate, cmd.subcommand)
+	assert.Equal(t, "name", cmd.name)
+	assert.Equal(t, string([]byte{0, 1, 2}), cmd.value)
+
+	cmd = &Secret{}
+	assert.NoError(t, parseHelper(cmd, []string{"rotate", "name", "value"}))
+	assert.Equal(t, "value", cmd.value)
+
+	assert.EqualError(t, parseHelper(&Secret{}, []string{"name"}),
+		"a name and value must be supplied")
+	assert.EqualError(t, parseHelper(&Secret{}, []string{"ls", "name"}),
+		"ls does not take any arguments")
+	assert.EqualError(t, parseHelper(&Secret{}, []string{"rm"}),
+		"a single name must be supplied")
+	assert.EqualError(t, parseHelper(&Secret{},
+		[]string{"-allow", "web", "rotate", "name", "value"}),
+		"-allow cannot be used with rotate")
+
+	var csf commaSeparatedFlag
+	assert.EqualError(t, csf.Set(" , "), "must not be empty")
+	assert.EqualError(t, parseHelper(&Secret{},
+		[]string{"-f", "key.pem", "name", "value"}),
+		"a name must be supplied, and the value cannot be given as an "+
+			"argument when using -f")
+	assert.EqualError(t, parseHelper(&Secret{},
+		[]string{"-f", "missing", "name"}),
+		"read secret value: open missing: file does not exist")
+}
+
+func secretContainers() []db.Container {
+	return []db.Container{
+		{BlueprintID: "a", Env: map[string]blueprint.ContainerValue{
+			"KEY": blueprint.NewSecret("key"),
+			"DUP": blueprint.NewSecret("key"),
+		}},
+		{BlueprintID: "b", FilepathToContent: map[string]blueprint.ContainerValue{
+			"/key": blueprint.NewSecret("key"),
+			"/tls": blueprint.NewSecret("tls"),
+		}},
+		{BlueprintID: "c"},
+	}
+}
+
+func TestSecretList(t *testing.T) {
+	t.Parallel()
+
+	modTime := time.Now().Add(-time.Hour)
+	mockClient := new(mocks.Client)
+	mockClient.On("ListSecrets").Return(map[string]time.Time{
+		"key":    modTime,
+		"unused": modTime,
+	}, nil)
+	mockClient.On("QueryContainers").Return(secretContainers(), nil)
+
+	cmd := &Secret{connectionHelper: connectionHelper{client: mockClient}}
+	var out bytes.Buffer
+	assert.NoError(t, cmd.list(&out))
+
+	modified := fmt.Sprintf("%s ago", units.HumanDuration(time.Since(modTime)))
+	exp := fmt.Sprintf("%-6s    %-*s    CONTAINERS\n",
+		"NAME", len(modified), "LAST MODIFIED") +
+		fmt.Sprintf("%-6s    %-*s    a,b\n", "key", len(modified), modified) +
+		fmt.Sprintf("%-6s    %-*s    b\n", "tls", len(modified), "not set") +
+		fmt.Sprintf("%-6s    %-*s    \n", "unused", len(modified), modified)
+	assert.Equal(t, exp, out.String())
+}
+
+func TestSecretRemove(t *testing.T) {
+	t.Parallel()
+
+	mockClient := new(mocks.Client)
+	mockClient.On("QueryContainers").Return(secretContainers(), nil)
+	mockClient.On("DeleteSecret", "unused").Return(nil)
+
+	cmd := &Secret{name: "unused",
+		connectionHelper: connectionHelper{client: mockClient}}
+	assert.NoError(t, cmd.remove())
+	mockClient.AssertCalled(t, "DeleteSecret", "unused")
+
+	cmd = &Secret{name: "key",
+		connectionHelper: connectionHelper{client: mockClient}}
+	assert.EqualError(t, cmd.remove(), "secret is referenced by containers "+
+		"a, b. Use -force to remove it anyway")
+	mockClient.AssertNotCalled(t, "DeleteSecret", "key")
+
+	mockClient.On("DeleteSecret", "key").Return(assert.AnError)
+	cmd.force = true
+	assert.EqualError(t, cmd.remove(), "delete secret: "+assert.AnError.Error())
+}
+
+func TestSecretRotate(t *testing.T) {
+	t.Parallel()
+
+	mockClient := new(mocks.Client)
+	mockClient.On("ListSecrets").Return(map[string]time.Time{
+		"key": time.Now(),
+	}, nil)
+	mockClient.On("QueryContainers").Return(secretContainers(), nil)
+	mockClient.On("SetSecret", "key", "new", []string(nil)).Return(nil)
+
+	cmd := &Secret{name: "key", value: "new",
+		connectionHelper: connectionHelper{client: mockClient}}
+	assert.NoError(t, cmd.rotate())
+	mockClient.AssertCalled(t, "SetSecret", "key", "new", []string(nil))
+
+	cmd = &Secret{name: "missing", value: "new",
+		connectionHelper: connectionHelper{client: mockClient}}
+	assert.EqualError(t, cmd.rotate(), "secret missing does not exist. "+
+		"Use `kelda secret` to set it")
+	mockClient.AssertNotCalled(t, "SetSecret", "missing", "new",
+		mock.Anything)
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 	"time"
@@ -15,6 +16,8 @@ import (
 	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/util"
 	"github.com/kelda/kelda/util/str"
+
+	log "github.com/sirupsen/logrus"
 )
 
 // An arbitrary length to truncate container commands to.
@@ -24,6 +27,10 @@ const truncLength = 30
 type Show struct {
 	noTruncate bool
 
+	// Only show the machines with all of these labels, and the containers
+	// running on them.
+	labels labelsFlag
+
 	connectionHelper
 }
 
@@ -40,6 +47,8 @@ func (pCmd *Show) InstallFlags(flags *flag.FlagSet) {
 	pCmd.connectionHelper.InstallFlags(flags)
 	flags.BoolVar(&pCmd.noTruncate, "no-trunc", false, "do not truncate container"+
 		" command output")
+	flags.Var(&pCmd.labels, "label", "only show the machines with the given "+
+		"comma-separated key=value labels, and the containers on them")
 	flags.Usage = func() {
 		util.PrintUsageString(showCommands, showExplanation, flags)
 	}
@@ -64,8 +73,16 @@ func (pCmd *Show) run() (err error) {
 	if err != nil {
 		return fmt.Errorf("unable to query machines: %s", err)
 	}
+	machines = filterMachines(machines, pCmd.labels)
+
+	// Metrics are only informational, so failing to query them shouldn't
+	// prevent the rest of the status from being shown.
+	metrics, err := pCmd.client.QueryMetrics()
+	if err != nil {
+		log.WithError(err).Debug("Unable to query metrics")
+	}
 
-	writeMachines(os.Stdout, machines)
+	writeMachines(os.Stdout, machines, metrics)
 	fmt.Println()
 
 	clusterUp := false
@@ -104,16 +121,95 @@ func (pCmd *Show) run() (err error) {
 	if err := <-containerErr; err != nil {
 		return fmt.Errorf("unable to query containers: %s", err)
 	}
+	if len(pCmd.labels) != 0 {
+		containers = filterContainers(containers, machines)
+	}
+
+	writeContainers(os.Stdout, containers, machines, connections, metrics,
+		!pCmd.noTruncate)
 
-	writeContainers(os.Stdout, containers, machines, connections, !pCmd.noTruncate)
+	// The autoscaling settings are only informational, so they're skipped
+	// if the blueprint can't be queried.
+	blueprints, err := pCmd.client.QueryBlueprints()
+	if err != nil {
+		log.WithError(err).Debug("Unable to query blueprints")
+		return nil
+	}
 
+	var autoscaled []blueprint.Container
+	for _, bp := range blueprints {
+		for _, c := range bp.Blueprint.Containers {
+			if c.Autoscale != nil {
+				autoscaled = append(autoscaled, c)
+			}
+		}
+	}
+	if len(autoscaled) != 0 {
+		fmt.Println()
+		writeAutoscaled(os.Stdout, autoscaled, containers, metrics)
+	}
+
+	return nil
+}
+
+// filterMachines returns the machines that have all of the given labels.
+func filterMachines(machines []db.Machine, labels map[string]string) []db.Machine {
+	var filtered []db.Machine
+	for _, m := range machines {
+		if db.HasLabels(m.Labels, labels) {
+			filtered = append(filtered, m)
+		}
+	}
+	return filtered
+}
+
+// filterContainers returns the containers that are running on the given
+// machines.
+func filterContainers(containers []db.Container,
+	machines []db.Machine) []db.Container {
+
+	privateIPs := map[string]struct{}{}
+	for _, m := range machines {
+		privateIPs[m.PrivateIP] = struct{}{}
+	}
+
+	var filtered []db.Container
+	for _, dbc := range containers {
+		if _, ok := privateIPs[dbc.Minion]; ok {
+			filtered = append(filtered, dbc)
+		}
+	}
+	return filtered
+}
+
+// labelsFlag is a flag.Value that parses a comma-separated list of key=value
+// labels.
+type labelsFlag map[string]string
+
+func (lf *labelsFlag) String() string {
+	return strings.Join(db.SortedLabels(*lf), ",")
+}
+
+func (lf *labelsFlag) Set(value string) error {
+	*lf = map[string]string{}
+	for _, label := range strings.Split(value, ",") {
+		kv := strings.SplitN(strings.TrimSpace(label), "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return fmt.Errorf("malformed label %q: labels must be of "+
+				"the form key=value", label)
+		}
+		(*lf)[kv[0]] = kv[1]
+	}
 	return nil
 }
 
-func writeMachines(fd io.Writer, machines []db.Machine) {
+func writeMachines(fd io.Writer, machines []db.Machine, metrics []db.Metrics) {
 	w := tabwriter.NewWriter(fd, 0, 0, 4, ' ', 0)
 	defer w.Flush()
-	fmt.Fprintln(w, "MACHINE\tROLE\tPROVIDER\tREGION\tSIZE\tPUBLIC IP\tSTATUS")
+	fmt.Fprintln(w, "MACHINE\tROLE\tPROVIDER\tREGION\tSIZE\tPUBLIC IP\tSTATUS"+
+		"\tCPU\tMEM\tDISK")
+
+	machineMetrics, _ := splitMetrics(metrics)
 
 	for _, m := range db.SortMachines(machines) {
 		// Prefer the floating IP over the public IP if it's defined.
@@ -122,20 +218,35 @@ func writeMachines(fd io.Writer, machines []db.Machine) {
 			pubIP = m.FloatingIP
 		}
 
-		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+		var cpu, mem, disk string
+		if usage, ok := machineMetrics[m.CloudID]; ok {
+			cpu = percentStr(usage.CPUPercent)
+			mem = fractionStr(usage.MemoryUsed, usage.MemoryTotal)
+			disk = fractionStr(usage.DiskUsed, usage.DiskTotal)
+		}
+
+		// Machines that are being removed from their pool are shown as
+		// draining while they're still connected.
+		status := m.Status
+		if m.Draining && status == db.Connected {
+			status = "draining"
+		}
+
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
 			util.ShortUUID(m.CloudID), m.Role, m.Provider, m.Region,
-			m.Size, pubIP, m.Status)
+			m.Size, pubIP, status, cpu, mem, disk)
 	}
 }
 
 func writeContainers(fd io.Writer, containers []db.Container, machines []db.Machine,
-	connections []db.Connection, truncate bool) {
+	connections []db.Connection, metrics []db.Metrics, truncate bool) {
 	w := tabwriter.NewWriter(fd, 0, 0, 4, ' ', 0)
 	defer w.Flush()
 	fmt.Fprintln(w, "CONTAINER\tMACHINE\tCOMMAND\tHOSTNAME"+
-		"\tSTATUS\tCREATED\tPUBLIC IP")
+		"\tSTATUS\tCPU\tMEM\tCREATED\tPUBLIC IP")
 
 	hostnamePublicPorts := connToPorts(connections)
+	_, podMetrics := splitMetrics(metrics)
 
 	ipIDMap := map[string]string{}
 	idMachineMap := map[string]db.Machine{}
@@ -161,13 +272,14 @@ func writeContainers(fd io.Writer, containers []db.Container, machines []db.Mach
 			// Insert a blank line between each machine.
 			// Need to print tabs in a blank line; otherwise, spacing will
 			// change in subsequent lines.
-			fmt.Fprintf(w, "\t\t\t\t\t\t\n")
+			fmt.Fprintf(w, "\t\t\t\t\t\t\t\t\n")
 		}
 
 		dbcs := machineDBC[machineID]
 		sort.Sort(db.ContainerSlice(dbcs))
 		for _, dbc := range dbcs {
-			container := containerStr(dbc.Image, dbc.Command, truncate)
+			image := imageStr(dbc.Image, dbc.ImageDigest, truncate)
+			container := containerStr(image, dbc.Command, truncate)
 
 			created := ""
 			if !dbc.Created.IsZero() {
@@ -179,14 +291,88 @@ func writeContainers(fd io.Writer, containers []db.Container, machines []db.Mach
 			publicPorts := hostnamePublicPorts[dbc.Hostname]
 			publicIP := publicIPStr(idMachineMap[machineID], publicPorts)
 
-			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			var cpu, mem string
+			if usage, ok := podMetrics[dbc.PodName]; ok && dbc.PodName != "" {
+				cpu = percentStr(usage.CPUPercent)
+				mem = units.BytesSize(float64(usage.MemoryUsed))
+			}
+
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
 				util.ShortUUID(dbc.BlueprintID),
 				util.ShortUUID(machineID),
-				container, dbc.Hostname, dbc.Status, created, publicIP)
+				container, dbc.Hostname, dbc.Status, cpu, mem, created,
+				publicIP)
 		}
 	}
 }
 
+// writeAutoscaled writes the number of replicas of each autoscaled container,
+// and the current value of the metric that drives it.
+func writeAutoscaled(fd io.Writer, autoscaled []blueprint.Container,
+	containers []db.Container, metrics []db.Metrics) {
+	w := tabwriter.NewWriter(fd, 0, 0, 4, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintln(w, "HOSTNAME\tREPLICAS\tMIN\tMAX\tMETRIC\tCURRENT\tTARGET")
+
+	replicas := map[string][]db.Container{}
+	for _, dbc := range containers {
+		if dbc.ReplicaSet != "" {
+			replicas[dbc.ReplicaSet] = append(replicas[dbc.ReplicaSet], dbc)
+		}
+	}
+
+	sort.Slice(autoscaled, func(i, j int) bool {
+		return autoscaled[i].Hostname < autoscaled[j].Hostname
+	})
+	for _, c := range autoscaled {
+		autoscale := *c.Autoscale
+		metricStr := func(value float64) string {
+			if autoscale.Metric == blueprint.AutoscaleCPU {
+				return percentStr(value)
+			}
+			return strconv.FormatFloat(value, 'g', -1, 64)
+		}
+
+		var current string
+		value, ok := db.AutoscaleMetric(autoscale, replicas[c.Hostname],
+			metrics)
+		if ok {
+			current = metricStr(value)
+		}
+
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n", c.Hostname,
+			len(replicas[c.Hostname]), autoscale.Min, autoscale.Max,
+			autoscale.Metric, current, metricStr(autoscale.Target))
+	}
+}
+
+// splitMetrics separates the metrics of machines, keyed by CloudID, from the
+// metrics of pods, keyed by pod name.
+func splitMetrics(metrics []db.Metrics) (machines, pods map[string]db.Metrics) {
+	machines = map[string]db.Metrics{}
+	pods = map[string]db.Metrics{}
+	for _, m := range metrics {
+		if m.PodName == "" {
+			machines[m.Machine] = m
+		} else {
+			pods[m.PodName] = m
+		}
+	}
+	return machines, pods
+}
+
+func percentStr(percent float64) string {
+	return fmt.Sprintf("%.1f%%", percent)
+}
+
+// fractionStr returns the percentage of `total` that `used` represents.
+func fractionStr(used, total uint64) string {
+	if total == 0 {
+		return ""
+	}
+	return percentStr(100 * float64(used) / float64(total))
+}
+
 func connToPorts(connections []db.Connection) map[string][]string {
 	hostnamePublicPorts := map[string][]string{}
 	for _, c := range connections {
@@ -206,6 +392,21 @@ func connToPorts(connections []db.Connection) map[string][]string {
 	return hostnamePublicPorts
 }
 
+// imageStr returns the image along with the digest of the image that's running,
+// if it's known.
+func imageStr(image, digest string, truncate bool) string {
+	if image == "" || digest == "" || strings.Contains(image, "@") {
+		return image
+	}
+
+	// Shorten the digest to the same length as Docker's short image IDs.
+	const shortDigestLength = len("sha256:") + 12
+	if truncate && len(digest) > shortDigestLength {
+		digest = digest[:shortDigestLength]
+	}
+	return image + "@" + digest
+}
+
 func containerStr(image string, args []string, truncate bool) string {
 	if image == "" {
 		return ""
@@ -12,6 +12,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/kelda/kelda/api/client/mocks"
+	"github.com/kelda/kelda/blueprint"
 	"github.com/kelda/kelda/db"
 )
 
@@ -31,6 +32,43 @@ func TestShowFlags(t *testing.T) {
 
 	assert.NoError(t, err)
 	assert.True(t, cmd.noTruncate)
+
+	cmd = NewShowCommand()
+	err = parseHelper(cmd, []string{"-label", "tier=db, env=prod"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, labelsFlag{"tier": "db", "env": "prod"}, cmd.labels)
+
+	var labels labelsFlag
+	assert.EqualError(t, labels.Set("tier=db,env"), `malformed label "env": `+
+		"labels must be of the form key=value")
+}
+
+func TestShowFilterLabels(t *testing.T) {
+	t.Parallel()
+
+	db1 := db.Machine{CloudID: "1", PrivateIP: "10.0.0.1",
+		Labels: map[string]string{"tier": "db", "env": "prod"}}
+	db2 := db.Machine{CloudID: "2", PrivateIP: "10.0.0.2",
+		Labels: map[string]string{"tier": "db"}}
+	web := db.Machine{CloudID: "3", PrivateIP: "10.0.0.3"}
+	machines := []db.Machine{db1, db2, web}
+
+	assert.Equal(t, machines, filterMachines(machines, nil))
+	assert.Equal(t, []db.Machine{db1, db2}, filterMachines(machines,
+		map[string]string{"tier": "db"}))
+	assert.Equal(t, []db.Machine{db1}, filterMachines(machines,
+		map[string]string{"tier": "db", "env": "prod"}))
+	assert.Empty(t, filterMachines(machines,
+		map[string]string{"tier": "web"}))
+
+	containers := []db.Container{
+		{Hostname: "mysql", Minion: "10.0.0.1"},
+		{Hostname: "nginx", Minion: "10.0.0.3"},
+		{Hostname: "unscheduled"},
+	}
+	assert.Equal(t, []db.Container{{Hostname: "mysql", Minion: "10.0.0.1"}},
+		filterContainers(containers, []db.Machine{db1, db2}))
 }
 
 func TestShowErrors(t *testing.T) {
@@ -42,16 +80,20 @@ func TestShowErrors(t *testing.T) {
 	mockClient := new(mocks.Client)
 	mockClient.On("QueryConnections").Return(nil, nil)
 	mockClient.On("QueryMachines").Return([]db.Machine{{Status: db.Connected}}, nil)
+	mockClient.On("QueryMetrics").Return(nil, nil)
 	mockClient.On("QueryContainers").Return(nil, mockErr)
-	cmd := &Show{false, connectionHelper{client: mockClient}}
+	mockClient.On("QueryBlueprints").Return(nil, nil)
+	cmd := &Show{connectionHelper: connectionHelper{client: mockClient}}
 	assert.EqualError(t, cmd.run(), "unable to query containers: error")
 
 	// Error querying connections from LeaderClient
 	mockClient = new(mocks.Client)
 	mockClient.On("QueryContainers").Return(nil, nil)
 	mockClient.On("QueryMachines").Return([]db.Machine{{Status: db.Connected}}, nil)
+	mockClient.On("QueryMetrics").Return(nil, nil)
 	mockClient.On("QueryConnections").Return(nil, mockErr)
-	cmd = &Show{false, connectionHelper{client: mockClient}}
+	mockClient.On("QueryBlueprints").Return(nil, nil)
+	cmd = &Show{connectionHelper: connectionHelper{client: mockClient}}
 	assert.EqualError(t, cmd.run(), "unable to query connections: error")
 }
 
@@ -60,7 +102,8 @@ func TestMachineOnly(t *testing.T) {
 	t.Parallel()
 
 	mockClient := new(mocks.Client)
-	cmd := &Show{false, connectionHelper{client: mockClient}}
+	mockClient.On("QueryMetrics").Return(nil, nil)
+	cmd := &Show{connectionHelper: connectionHelper{client: mockClient}}
 
 	// Test failing to query machines.
 	mockClient.On("QueryMachines").Once().Return(nil, assert.AnError)
@@ -86,7 +129,31 @@ func TestShowSuccess(t *testing.T) {
 	mockClient.On("QueryContainers").Return(nil, nil)
 	mockClient.On("QueryMachines").Return(nil, nil)
 	mockClient.On("QueryConnections").Return(nil, nil)
-	cmd := &Show{false, connectionHelper{client: mockClient}}
+	mockClient.On("QueryMetrics").Return(nil, nil)
+	cmd := &Show{connectionHelper: connectionHelper{client: mockClient}}
+	assert.Equal(t, 0, cmd.Run())
+
+	// Failing to query metrics shouldn't prevent the status from being shown.
+	mockClient = new(mocks.Client)
+	mockClient.On("QueryContainers").Return(nil, nil)
+	mockClient.On("QueryMachines").Return(
+		[]db.Machine{{Status: db.Connected}}, nil)
+	mockClient.On("QueryConnections").Return(nil, nil)
+	mockClient.On("QueryMetrics").Return(nil, assert.AnError)
+	mockClient.On("QueryBlueprints").Return(nil, nil)
+	cmd = &Show{connectionHelper: connectionHelper{client: mockClient}}
+	assert.Equal(t, 0, cmd.Run())
+	mockClient.AssertCalled(t, "QueryContainers")
+
+	// Neither should failing to query the blueprint.
+	mockClient = new(mocks.Client)
+	mockClient.On("QueryContainers").Return(nil, nil)
+	mockClient.On("QueryMachines").Return(
+		[]db.Machine{{Status: db.Connected}}, nil)
+	mockClient.On("QueryConnections").Return(nil, nil)
+	mockClient.On("QueryMetrics").Return(nil, nil)
+	mockClient.On("QueryBlueprints").Return(nil, assert.AnError)
+	cmd = &Show{connectionHelper: connectionHelper{client: mockClient}}
 	assert.Equal(t, 0, cmd.Run())
 }
 
@@ -111,11 +178,18 @@ func TestMachineOutput(t *testing.T) {
 			PublicIP:   "9.9.9.9",
 			FloatingIP: "10.10.10.10",
 			Status:     db.Connected,
+			Draining:   true,
 		},
 	}
 
+	metrics := []db.Metrics{
+		{Machine: "1", CPUPercent: 12.34, MemoryUsed: 1, MemoryTotal: 4,
+			DiskUsed: 1, DiskTotal: 3},
+		{Machine: "1", PodName: "pod", CPUPercent: 50},
+	}
+
 	var b bytes.Buffer
-	writeMachines(&b, machines)
+	writeMachines(&b, machines, metrics)
 	result := string(b.Bytes())
 
 	/* By replacing space with underscore, we make the spaces explicit and whitespace
@@ -123,9 +197,11 @@ func TestMachineOutput(t *testing.T) {
 	result = strings.Replace(result, " ", "_", -1)
 
 	exp := `MACHINE____ROLE______PROVIDER________REGION_______SIZE` +
-		`________PUBLIC_IP______STATUS
-1__________Master____Amazon__________us-west-1____m4.large____8.8.8.8________connected
-2__________Worker____DigitalOcean____sfo1_________2gb_________10.10.10.10____connected
+		`________PUBLIC_IP______STATUS_______CPU______MEM______DISK
+1__________Master____Amazon__________us-west-1____m4.large____8.8.8.8________` +
+		`connected____12.3%____25.0%____33.3%
+2__________Worker____DigitalOcean____sfo1_________2gb_________10.10.10.10____` +
+		`draining_______________________
 `
 
 	assert.Equal(t, exp, result)
@@ -135,7 +211,7 @@ func checkContainerOutput(t *testing.T, containers []db.Container,
 	machines []db.Machine, connections []db.Connection, truncate bool, exp string) {
 
 	var b bytes.Buffer
-	writeContainers(&b, containers, machines, connections, truncate)
+	writeContainers(&b, containers, machines, connections, nil, truncate)
 
 	/* By replacing space with underscore, we make the spaces explicit and whitespace
 	* errors easier to debug. */
@@ -179,17 +255,23 @@ func TestContainerOutput(t *testing.T) {
 	}
 
 	expected := `CONTAINER____MACHINE____COMMAND___________HOSTNAME_______` +
-		`STATUS_______CREATED____PUBLIC_IP
-3_______________________image1_cmd_1______notpublic______running_________________
-_________________________________________________________________________________
-1____________5__________image2____________frompublic1____scheduled_______________` +
-		`7.7.7.7:80
-4____________5__________image3_cmd________frompublic2____scheduled_______________` +
-		`7.7.7.7:80
-_________________________________________________________________________________
-7____________6__________image1_cmd_3_4____frompublic3____scheduled_______________
-_________________________________________________________________________________
-8____________7__________image1___________________________________________________
+		`STATUS_______CPU____MEM____CREATED____PUBLIC_IP
+3_______________________image1_cmd_1______notpublic______running_______________` +
+		`________________
+_______________________________________________________________________________` +
+		`________________
+1____________5__________image2____________frompublic1____scheduled_____________` +
+		`________________7.7.7.7:80
+4____________5__________image3_cmd________frompublic2____scheduled_____________` +
+		`________________7.7.7.7:80
+_______________________________________________________________________________` +
+		`________________
+7____________6__________image1_cmd_3_4____frompublic3____scheduled_____________` +
+		`________________
+_______________________________________________________________________________` +
+		`________________
+8____________7__________image1_________________________________________________` +
+		`________________
 `
 	checkContainerOutput(t, containers, machines, connections, true, expected)
 
@@ -208,8 +290,8 @@ ________________________________________________________________________________
 	connections = []db.Connection{}
 
 	expected = `CONTAINER____MACHINE____COMMAND_________HOSTNAME____` +
-		`STATUS_____CREATED___________________PUBLIC_IP
-3_______________________image1_cmd_1________________running____` +
+		`STATUS_____CPU____MEM____CREATED___________________PUBLIC_IP
+3_______________________image1_cmd_1________________running__________________` +
 		mockCreatedString + `____
 `
 	checkContainerOutput(t, containers, machines, connections, true, expected)
@@ -230,8 +312,8 @@ ________________________________________________________________________________
 	connections = []db.Connection{}
 
 	expected = `CONTAINER____MACHINE____COMMAND_________HOSTNAME____` +
-		`STATUS_____CREATED______________PUBLIC_IP
-3_______________________image1_cmd_1________________running____` +
+		`STATUS_____CPU____MEM____CREATED______________PUBLIC_IP
+3_______________________image1_cmd_1________________running__________________` +
 		mockCreatedString + `____
 `
 	checkContainerOutput(t, containers, machines, connections, true, expected)
@@ -247,18 +329,21 @@ ________________________________________________________________________________
 	connections = []db.Connection{}
 
 	expected = `CONTAINER____MACHINE____COMMAND______________________________` +
-		`HOSTNAME____STATUS_____CREATED______________PUBLIC_IP
-3_______________________image1_cmd_1_&&_cmd_9128340347...________________running____` +
+		`HOSTNAME____STATUS_____CPU____MEM____CREATED______________PUBLIC_IP
+3_______________________image1_cmd_1_&&_cmd_9128340347...________________running` +
+		`__________________` +
 		mockCreatedString + `____
 `
 	checkContainerOutput(t, containers, machines, connections, true, expected)
 
 	// Test that long outputs are not truncated when `truncate` is false
 	expected = `CONTAINER____MACHINE____COMMAND_________________________________` +
-		`__________________________________HOSTNAME____STATUS_____CREATED` +
-		`______________PUBLIC_IP
-3_______________________image1_cmd_1_&&_cmd_912834034729038472930143209847239084` +
-		`73248-23843984________________running____` + mockCreatedString + `____
+		`__________________________________HOSTNAME____STATUS_____` +
+		`CPU____MEM____CREATED______________PUBLIC_IP
+3_______________________image1_cmd_1_&&_cmd_` +
+		`91283403472903847293014320984723908473248-23843984________________` +
+		`running__________________` +
+		mockCreatedString + `____
 `
 	checkContainerOutput(t, containers, machines, connections, false, expected)
 
@@ -282,11 +367,64 @@ ________________________________________________________________________________
 	}
 
 	expected = `CONTAINER____MACHINE____COMMAND____HOSTNAME____STATUS_______` +
-		`CREATED____PUBLIC_IP
-3____________5__________image1_____frompub_____scheduled_______________` +
-		`7.7.7.7:[80,100-101]
+		`CPU____MEM____CREATED____PUBLIC_IP
+3____________5__________image1_____frompub_____scheduled___________________` +
+		`__________7.7.7.7:[80,100-101]
 `
 	checkContainerOutput(t, containers, machines, connections, true, expected)
+
+	// Test writing the resource usage of containers.
+	containers = []db.Container{{
+		BlueprintID: "3",
+		Minion:      "1.1.1.1",
+		Image:       "image1",
+		Hostname:    "host",
+		Status:      "running",
+		PodName:     "pod",
+	}}
+	metrics := []db.Metrics{
+		{Machine: "5", CPUPercent: 90},
+		{Machine: "5", PodName: "pod", CPUPercent: 150,
+			MemoryUsed: 3 * 1024 * 1024},
+	}
+
+	var b bytes.Buffer
+	writeContainers(&b, containers, machines, nil, metrics, true)
+	expected = `CONTAINER____MACHINE____COMMAND____HOSTNAME____STATUS_____` +
+		`CPU_______MEM_____CREATED____PUBLIC_IP
+3____________5__________image1_____host________running____150.0%____3MiB` +
+		`_______________
+`
+	assert.Equal(t, expected, strings.Replace(b.String(), " ", "_", -1))
+}
+
+func TestAutoscaledOutput(t *testing.T) {
+	t.Parallel()
+
+	autoscaled := []blueprint.Container{
+		{Hostname: "web", Autoscale: &blueprint.Autoscale{Min: 1, Max: 5,
+			Metric: blueprint.AutoscaleCPU, Target: 50}},
+		{Hostname: "queue", Autoscale: &blueprint.Autoscale{Min: 2, Max: 8,
+			Metric: blueprint.AutoscaleHTTP, Target: 10, Port: 80}},
+	}
+	containers := []db.Container{
+		{Hostname: "web-0", ReplicaSet: "web", PodName: "pod-0"},
+		{Hostname: "web-1", ReplicaSet: "web", PodName: "pod-1"},
+		{Hostname: "queue-0", ReplicaSet: "queue"},
+		{Hostname: "queue-1", ReplicaSet: "queue"},
+	}
+	metrics := []db.Metrics{
+		{PodName: "pod-0", CPUPercent: 40},
+		{PodName: "pod-1", CPUPercent: 80},
+	}
+
+	var b bytes.Buffer
+	writeAutoscaled(&b, autoscaled, containers, metrics)
+	expected := `HOSTNAME____REPLICAS____MIN____MAX____METRIC____CURRENT____TARGET
+queue_______2___________2______8______http_________________10
+web_________2___________1______5______cpu_______60.0%______50.0%
+`
+	assert.Equal(t, expected, strings.Replace(b.String(), " ", "_", -1))
 }
 
 func TestContainerStr(t *testing.T) {
@@ -297,6 +435,18 @@ func TestContainerStr(t *testing.T) {
 		containerStr("container", []string{"arg0", "arg1"}, false))
 }
 
+func TestImageStr(t *testing.T) {
+	t.Parallel()
+
+	digest := "sha256:0123456789abcdef"
+	assert.Equal(t, "nginx", imageStr("nginx", "", true))
+	assert.Equal(t, "nginx@sha256:0123456789ab",
+		imageStr("nginx", digest, true))
+	assert.Equal(t, "nginx@sha256:0123456789abcdef",
+		imageStr("nginx", digest, false))
+	assert.Equal(t, "nginx@sha256:abc", imageStr("nginx@sha256:abc", digest, false))
+}
+
 func TestPublicIPStr(t *testing.T) {
 	t.Parallel()
 	assert.Equal(t, "", publicIPStr(db.Machine{}, nil))
@@ -130,7 +130,7 @@ func (sCmd SSH) Run() int {
 			sCmd.allocatePTY = true
 			cmd = "sh"
 		}
-		cmdErr = containerExec(sshClient, t.PodName, sCmd.allocatePTY, cmd)
+		cmdErr = containerExec(sshClient, t, sCmd.allocatePTY, cmd)
 	default:
 		panic("Not Reached")
 	}
@@ -149,7 +149,9 @@ func (sCmd SSH) Run() int {
 	return 0
 }
 
-func containerExec(c ssh.Client, podName string, allocatePTY bool, cmd string) error {
+func containerExec(c ssh.Client, dbc db.Container, allocatePTY bool,
+	cmd string) error {
+
 	var flags string
 	if allocatePTY {
 		flags = "-it"
@@ -157,7 +159,8 @@ func containerExec(c ssh.Client, podName string, allocatePTY bool, cmd string) e
 
 	command := []string{
 		"docker", "exec", flags, supervisor.KubeAPIServerName,
-		"kubectl", "exec", flags, podName, "--", cmd,
+		"kubectl", "exec", flags, dbc.PodName, "-c", podContainerName(dbc),
+		"--", cmd,
 	}
 
 	return c.Run(allocatePTY, strings.Join(command, " "))
@@ -132,12 +132,13 @@ func TestSSH(t *testing.T) {
 			containers: []db.Container{{
 				Minion:      "priv",
 				BlueprintID: "tgt",
+				Hostname:    "web",
 				PodName:     "podName",
 			}},
 			expAllocatePTY: true,
 			expHost:        leaderHost,
 			expRunArgs: "docker exec -it kube-apiserver " +
-				"kubectl exec -it podName -- sh",
+				"kubectl exec -it podName -c web -- sh",
 		},
 		// Container with exec.
 		{
@@ -150,11 +151,12 @@ func TestSSH(t *testing.T) {
 			containers: []db.Container{{
 				Minion:      "priv",
 				BlueprintID: "tgt",
+				Hostname:    "web",
 				PodName:     "podName",
 			}},
 			expHost: leaderHost,
 			expRunArgs: "docker exec  kube-apiserver " +
-				"kubectl exec  podName -- foo bar",
+				"kubectl exec  podName -c web -- foo bar",
 		},
 		// Container with exec and PTY.
 		{
@@ -168,12 +170,13 @@ func TestSSH(t *testing.T) {
 			containers: []db.Container{{
 				Minion:      "priv",
 				BlueprintID: "tgt",
+				Hostname:    "web",
 				PodName:     "podName",
 			}},
 			expAllocatePTY: true,
 			expHost:        leaderHost,
 			expRunArgs: "docker exec -it kube-apiserver " +
-				"kubectl exec -it podName -- foo bar",
+				"kubectl exec -it podName -c web -- foo bar",
 		},
 	}
 	for _, test := range tests {
@@ -0,0 +1,217 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	units "github.com/docker/go-units"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/util"
+)
+
+// The columns that `kelda top` can sort by.
+const (
+	sortByCPU    = "cpu"
+	sortByMemory = "mem"
+)
+
+// Top contains the options for displaying the resource usage of machines and
+// containers.
+type Top struct {
+	sortBy   string
+	interval time.Duration
+	once     bool
+
+	connectionHelper
+}
+
+// NewTopCommand creates a new Top command instance.
+func NewTopCommand() *Top {
+	return &Top{}
+}
+
+var topCommands = "kelda top [OPTIONS]"
+var topExplanation = `Display a live view of the CPU, memory, disk, and network
+usage of kelda-managed machines and containers, sorted by usage.
+
+The metrics are sampled by the minions every 10 seconds, so refreshing more
+often than that has no effect.`
+
+// InstallFlags sets up parsing for command line flags.
+func (tCmd *Top) InstallFlags(flags *flag.FlagSet) {
+	tCmd.connectionHelper.InstallFlags(flags)
+	flags.StringVar(&tCmd.sortBy, "sort", sortByCPU,
+		"the resource to sort by (cpu or mem)")
+	flags.DurationVar(&tCmd.interval, "interval", 5*time.Second,
+		"how often to refresh the display")
+	flags.BoolVar(&tCmd.once, "once", false,
+		"print the resource usage once, rather than refreshing it")
+	flags.Usage = func() {
+		util.PrintUsageString(topCommands, topExplanation, flags)
+	}
+}
+
+// Parse parses the command line arguments for the top command.
+func (tCmd *Top) Parse(args []string) error {
+	if tCmd.sortBy != sortByCPU && tCmd.sortBy != sortByMemory {
+		return fmt.Errorf("unknown sort column: %s", tCmd.sortBy)
+	}
+
+	if tCmd.interval <= 0 {
+		return errors.New("interval must be positive")
+	}
+	return nil
+}
+
+// Run displays the resource usage until the user exits.
+func (tCmd *Top) Run() int {
+	for {
+		var out bytes.Buffer
+		if err := tCmd.writeUsage(&out); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			return 1
+		}
+
+		if tCmd.once {
+			out.WriteTo(os.Stdout)
+			return 0
+		}
+
+		// Clear the screen before redrawing.
+		fmt.Print("\033[H\033[2J")
+		out.WriteTo(os.Stdout)
+		time.Sleep(tCmd.interval)
+	}
+}
+
+func (tCmd *Top) writeUsage(out io.Writer) error {
+	machines, err := tCmd.client.QueryMachines()
+	if err != nil {
+		return fmt.Errorf("unable to query machines: %s", err)
+	}
+
+	metrics, err := tCmd.client.QueryMetrics()
+	if err != nil {
+		return fmt.Errorf("unable to query metrics: %s", err)
+	}
+
+	var containers []db.Container
+	for _, m := range machines {
+		if m.Status == db.Connected || m.Status == db.Reconnecting {
+			containers, err = tCmd.client.QueryContainers()
+			if err != nil {
+				return fmt.Errorf("unable to query containers: %s", err)
+			}
+			break
+		}
+	}
+
+	writeMachineUsage(out, machines, metrics, tCmd.sortBy)
+	fmt.Fprintln(out)
+	writeContainerUsage(out, containers, machines, metrics, tCmd.sortBy)
+	return nil
+}
+
+func writeMachineUsage(fd io.Writer, machines []db.Machine,
+	metrics []db.Metrics, sortBy string) {
+
+	w := tabwriter.NewWriter(fd, 0, 0, 4, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintln(w, "MACHINE\tROLE\tPUBLIC IP\tCPU\tMEM\tMEM USAGE\tDISK"+
+		"\tDISK USAGE")
+
+	machineMetrics, _ := splitMetrics(metrics)
+	machines = db.SortMachines(machines)
+	sort.SliceStable(machines, func(i, j int) bool {
+		return usageLess(machineMetrics[machines[j].CloudID],
+			machineMetrics[machines[i].CloudID], sortBy)
+	})
+
+	for _, m := range machines {
+		pubIP := m.PublicIP
+		if m.FloatingIP != "" {
+			pubIP = m.FloatingIP
+		}
+
+		var cpu, mem, memUsage, disk, diskUsage string
+		if usage, ok := machineMetrics[m.CloudID]; ok {
+			cpu = percentStr(usage.CPUPercent)
+			mem = fractionStr(usage.MemoryUsed, usage.MemoryTotal)
+			memUsage = bytesUsageStr(usage.MemoryUsed, usage.MemoryTotal)
+			disk = fractionStr(usage.DiskUsed, usage.DiskTotal)
+			diskUsage = bytesUsageStr(usage.DiskUsed, usage.DiskTotal)
+		}
+
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			util.ShortUUID(m.CloudID), m.Role, pubIP, cpu, mem, memUsage,
+			disk, diskUsage)
+	}
+}
+
+func writeContainerUsage(fd io.Writer, containers []db.Container,
+	machines []db.Machine, metrics []db.Metrics, sortBy string) {
+
+	w := tabwriter.NewWriter(fd, 0, 0, 4, ' ', 0)
+	defer w.Flush()
+	fmt.Fprintln(w, "CONTAINER\tMACHINE\tHOSTNAME\tCPU\tMEM USAGE\tNET I/O")
+
+	ipIDMap := map[string]string{}
+	for _, m := range machines {
+		ipIDMap[m.PrivateIP] = m.CloudID
+	}
+
+	_, podMetrics := splitMetrics(metrics)
+	containerMetrics := func(dbc db.Container) (db.Metrics, bool) {
+		if dbc.PodName == "" {
+			return db.Metrics{}, false
+		}
+		usage, ok := podMetrics[dbc.PodName]
+		return usage, ok
+	}
+
+	sort.Sort(db.ContainerSlice(containers))
+	sort.SliceStable(containers, func(i, j int) bool {
+		iUsage, _ := containerMetrics(containers[i])
+		jUsage, _ := containerMetrics(containers[j])
+		return usageLess(jUsage, iUsage, sortBy)
+	})
+
+	for _, dbc := range containers {
+		var cpu, memUsage, netIO string
+		if usage, ok := containerMetrics(dbc); ok {
+			cpu = percentStr(usage.CPUPercent)
+			memUsage = bytesUsageStr(usage.MemoryUsed, usage.MemoryTotal)
+			netIO = fmt.Sprintf("%s / %s",
+				units.HumanSize(float64(usage.NetworkRx)),
+				units.HumanSize(float64(usage.NetworkTx)))
+		}
+
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\n",
+			util.ShortUUID(dbc.BlueprintID),
+			util.ShortUUID(ipIDMap[dbc.Minion]),
+			dbc.Hostname, cpu, memUsage, netIO)
+	}
+}
+
+// usageLess returns whether `a` uses less of the `sortBy` resource than `b`.
+func usageLess(a, b db.Metrics, sortBy string) bool {
+	if sortBy == sortByMemory {
+		return a.MemoryUsed < b.MemoryUsed
+	}
+	return a.CPUPercent < b.CPUPercent
+}
+
+func bytesUsageStr(used, total uint64) string {
+	if total == 0 {
+		return units.BytesSize(float64(used))
+	}
+	return fmt.Sprintf("%s / %s", units.BytesSize(float64(used)),
+		units.BytesSize(float64(total)))
+}
@@ -0,0 +1,126 @@
+package command
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kelda/kelda/api/client/mocks"
+	"github.com/kelda/kelda/db"
+)
+
+func TestTopFlags(t *testing.T) {
+	t.Parallel()
+
+	cmd := NewTopCommand()
+	err := parseHelper(cmd, []string{"-sort", "mem", "-interval", "1s", "-once"})
+	assert.NoError(t, err)
+	assert.Equal(t, sortByMemory, cmd.sortBy)
+	assert.Equal(t, time.Second, cmd.interval)
+	assert.True(t, cmd.once)
+
+	cmd = NewTopCommand()
+	assert.NoError(t, parseHelper(cmd, nil))
+	assert.Equal(t, sortByCPU, cmd.sortBy)
+	assert.False(t, cmd.once)
+
+	err = parseHelper(NewTopCommand(), []string{"-sort", "disk"})
+	assert.EqualError(t, err, "unknown sort column: disk")
+
+	err = parseHelper(NewTopCommand(), []string{"-interval", "0s"})
+	assert.EqualError(t, err, "interval must be positive")
+}
+
+func TestTopWriteUsage(t *testing.T) {
+	t.Parallel()
+
+	machines := []db.Machine{
+		{CloudID: "1", Role: db.Master, PublicIP: "8.8.8.8",
+			PrivateIP: "10.0.0.1", Status: db.Connected},
+		{CloudID: "2", Role: db.Worker, PublicIP: "9.9.9.9",
+			PrivateIP: "10.0.0.2", Status: db.Connected},
+	}
+	containers := []db.Container{
+		{BlueprintID: "a", Hostname: "idle", PodName: "pod-a",
+			Minion: "10.0.0.2"},
+		{BlueprintID: "b", Hostname: "busy", PodName: "pod-b",
+			Minion: "10.0.0.2"},
+		{BlueprintID: "c", Hostname: "pending"},
+	}
+	metrics := []db.Metrics{
+		{Machine: "1", CPUPercent: 10, MemoryUsed: 1 << 30,
+			MemoryTotal: 4 << 30, DiskUsed: 5 << 30, DiskTotal: 10 << 30},
+		{Machine: "2", CPUPercent: 50, MemoryUsed: 512 << 20,
+			MemoryTotal: 4 << 30, DiskUsed: 1 << 30, DiskTotal: 10 << 30},
+		{Machine: "2", PodName: "pod-a", CPUPercent: 1,
+			MemoryUsed: 256 << 20, MemoryTotal: 1 << 30,
+			NetworkRx: 1000, NetworkTx: 2000},
+		{Machine: "2", PodName: "pod-b", CPUPercent: 40,
+			MemoryUsed: 128 << 20, NetworkRx: 3000, NetworkTx: 4000},
+	}
+
+	mockClient := new(mocks.Client)
+	mockClient.On("QueryMachines").Return(machines, nil)
+	mockClient.On("QueryMetrics").Return(metrics, nil)
+	mockClient.On("QueryContainers").Return(containers, nil)
+
+	cmd := &Top{sortBy: sortByCPU,
+		connectionHelper: connectionHelper{client: mockClient}}
+	var out bytes.Buffer
+	assert.NoError(t, cmd.writeUsage(&out))
+
+	machineHeader := "MACHINE    ROLE      PUBLIC IP    CPU      MEM      " +
+		"MEM USAGE        DISK     DISK USAGE\n"
+	master := "1          Master    8.8.8.8      10.0%    25.0%    " +
+		"1GiB / 4GiB      50.0%    5GiB / 10GiB\n"
+	worker := "2          Worker    9.9.9.9      50.0%    12.5%    " +
+		"512MiB / 4GiB    10.0%    1GiB / 10GiB\n"
+
+	exp := machineHeader + worker + master + `
+CONTAINER    MACHINE    HOSTNAME    CPU      MEM USAGE        NET I/O
+b            2          busy        40.0%    128MiB           3kB / 4kB
+a            2          idle        1.0%     256MiB / 1GiB    1kB / 2kB
+c                       pending` +
+		"                               \n"
+	assert.Equal(t, exp, out.String())
+
+	cmd.sortBy = sortByMemory
+	out.Reset()
+	assert.NoError(t, cmd.writeUsage(&out))
+
+	exp = machineHeader + master + worker + `
+CONTAINER    MACHINE    HOSTNAME    CPU      MEM USAGE        NET I/O
+a            2          idle        1.0%     256MiB / 1GiB    1kB / 2kB
+b            2          busy        40.0%    128MiB           3kB / 4kB
+c                       pending` +
+		"                               \n"
+	assert.Equal(t, exp, out.String())
+}
+
+func TestTopErrors(t *testing.T) {
+	t.Parallel()
+
+	mockClient := new(mocks.Client)
+	mockClient.On("QueryMachines").Return(nil, assert.AnError)
+	cmd := &Top{connectionHelper: connectionHelper{client: mockClient}}
+	assert.EqualError(t, cmd.writeUsage(&bytes.Buffer{}),
+		"unable to query machines: "+assert.AnError.Error())
+
+	mockClient = new(mocks.Client)
+	mockClient.On("QueryMachines").Return(nil, nil)
+	mockClient.On("QueryMetrics").Return(nil, assert.AnError)
+	cmd = &Top{connectionHelper: connectionHelper{client: mockClient}}
+	assert.EqualError(t, cmd.writeUsage(&bytes.Buffer{}),
+		"unable to query metrics: "+assert.AnError.Error())
+
+	// Containers aren't queried if no machines are connected.
+	mockClient = new(mocks.Client)
+	mockClient.On("QueryMachines").Return(
+		[]db.Machine{{Status: db.Booting}}, nil)
+	mockClient.On("QueryMetrics").Return(nil, nil)
+	cmd = &Top{connectionHelper: connectionHelper{client: mockClient}}
+	assert.NoError(t, cmd.writeUsage(&bytes.Buffer{}))
+	mockClient.AssertNotCalled(t, "QueryContainers")
+}
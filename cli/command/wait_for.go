@@ -0,0 +1,95 @@
+package command
+
+import (
+	"errors"
+	"flag"
+	"net"
+	"time"
+
+	"github.com/kelda/kelda/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var waitForCommands = "kelda wait-for [OPTIONS] ADDRESS..."
+var waitForExplanation = `Wait until each ADDRESS, of the form HOST:PORT, accepts
+TCP connections.
+
+Kelda runs this command before starting containers that have dependencies. Most
+users will not need to run it directly.`
+
+// How often to retry connecting to an address that isn't accepting
+// connections.
+var waitForInterval = time.Second
+
+// WaitFor implements the `kelda wait-for` command.
+type WaitFor struct {
+	addresses []string
+	timeout   time.Duration
+}
+
+// NewWaitForCommand creates a new WaitFor command instance.
+func NewWaitForCommand() *WaitFor {
+	return &WaitFor{}
+}
+
+// InstallFlags sets up parsing for command line flags.
+func (cmd *WaitFor) InstallFlags(flags *flag.FlagSet) {
+	flags.DurationVar(&cmd.timeout, "timeout", 0,
+		"how long to wait before failing, or 0 to wait forever")
+	flags.Usage = func() {
+		util.PrintUsageString(waitForCommands, waitForExplanation, flags)
+	}
+}
+
+// Parse parses the command line arguments for the wait-for command.
+func (cmd *WaitFor) Parse(args []string) error {
+	if len(args) == 0 {
+		return errors.New("must specify an address")
+	}
+
+	for _, addr := range args {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return err
+		}
+	}
+	cmd.addresses = args
+	return nil
+}
+
+// BeforeRun makes any necessary post-parsing transformations.
+func (cmd *WaitFor) BeforeRun() error {
+	return nil
+}
+
+// AfterRun performs any necessary post-run cleanup.
+func (cmd *WaitFor) AfterRun() error {
+	return nil
+}
+
+// Run blocks until all of the addresses accept connections.
+func (cmd *WaitFor) Run() int {
+	deadline := time.Now().Add(cmd.timeout)
+	for _, addr := range cmd.addresses {
+		logger := log.WithField("address", addr)
+		logger.Info("Waiting for address to accept connections")
+		for !canConnect(addr) {
+			if cmd.timeout != 0 && time.Now().After(deadline) {
+				logger.Error("Timed out waiting for address to accept " +
+					"connections")
+				return 1
+			}
+			time.Sleep(waitForInterval)
+		}
+	}
+	return 0
+}
+
+func canConnect(addr string) bool {
+	conn, err := net.DialTimeout("tcp", addr, waitForInterval)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
@@ -0,0 +1,40 @@
+package command
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitForFlags(t *testing.T) {
+	t.Parallel()
+
+	cmd := NewWaitForCommand()
+	err := parseHelper(cmd, []string{"-timeout", "1m", "db:5432", "cache:6379"})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, cmd.timeout)
+	assert.Equal(t, []string{"db:5432", "cache:6379"}, cmd.addresses)
+
+	assert.EqualError(t, parseHelper(NewWaitForCommand(), nil),
+		"must specify an address")
+	assert.Error(t, parseHelper(NewWaitForCommand(), []string{"db"}))
+}
+
+func TestWaitForRun(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := listener.Addr().String()
+
+	cmd := NewWaitForCommand()
+	cmd.addresses = []string{addr}
+	assert.Equal(t, 0, cmd.Run())
+
+	// Once the listener is closed, the command should time out.
+	listener.Close()
+	waitForInterval = 10 * time.Millisecond
+	defer func() { waitForInterval = time.Second }()
+	cmd.timeout = 50 * time.Millisecond
+	assert.Equal(t, 1, cmd.Run())
+}
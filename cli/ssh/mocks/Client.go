@@ -2,6 +2,7 @@
 
 package mocks
 
+import io "io"
 import mock "github.com/stretchr/testify/mock"
 
 // Client is an autogenerated mock type for the Client type
@@ -73,3 +74,17 @@ func (_m *Client) Shell() error {
 
 	return r0
 }
+
+// Stream provides a mock function with given fields: cmd, stdin, stdout
+func (_m *Client) Stream(cmd string, stdin io.Reader, stdout io.Writer) error {
+	ret := _m.Called(cmd, stdin, stdout)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, io.Reader, io.Writer) error); ok {
+		r0 = rf(cmd, stdin, stdout)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"os/signal"
@@ -130,6 +131,21 @@ func (c NativeClient) CombinedOutput(command string) ([]byte, error) {
 	return session.CombinedOutput(command)
 }
 
+// Stream runs an SSH command, reading its stdin from `stdin` and writing its
+// stdout to `stdout`. The command's stderr is passed through to os.Stderr.
+func (c NativeClient) Stream(command string, stdin io.Reader, stdout io.Writer) error {
+	session, err := c.NewSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	session.Stdin = stdin
+	session.Stdout = stdout
+	session.Stderr = os.Stderr
+	return session.Run(command)
+}
+
 // Shell starts a login shell.
 func (c NativeClient) Shell() error {
 	s, err := c.NewSession()
@@ -1,5 +1,7 @@
 package ssh
 
+import "io"
+
 //go:generate mockery -name=Client
 
 // Client is an SSH client used for `kelda` commands.
@@ -11,6 +13,10 @@ type Client interface {
 	// stdin and stdout.
 	CombinedOutput(string) ([]byte, error)
 
+	// Stream runs a command over the SSH connection, connecting the command's
+	// stdin and stdout to the given reader and writer.
+	Stream(cmd string, stdin io.Reader, stdout io.Writer) error
+
 	// Close closes the SSH connection.
 	Close() error
 
@@ -4,6 +4,7 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -95,6 +96,11 @@ type bootReq struct {
 	size        string
 	diskSize    int
 	preemptible bool
+
+	// labels is the comma-separated list of the machine's labels, as
+	// returned by db.SortedLabels. It's a string so that boot requests can
+	// be used as map keys.
+	labels string
 }
 
 // Boot creates instances in the `prvdr` configured according to the `bootSet`.
@@ -116,6 +122,7 @@ func (prvdr *Provider) Boot(bootSet []db.Machine) ([]string, error) {
 			size:        m.Size,
 			diskSize:    m.DiskSize,
 			preemptible: m.Preemptible,
+			labels:      strings.Join(db.SortedLabels(m.Labels), ","),
 		}
 		bootReqMap[br] = bootReqMap[br] + 1
 	}
@@ -148,8 +155,9 @@ func (prvdr *Provider) bootReserved(br bootReq, count int64) ([]string, error) {
 		SecurityGroupIds: []*string{aws.String(br.groupID)},
 		BlockDeviceMappings: []*ec2.BlockDeviceMapping{
 			blockDevice(br.diskSize)},
-		MaxCount: &count,
-		MinCount: &count,
+		MaxCount:          &count,
+		MinCount:          &count,
+		TagSpecifications: tagSpecifications(br.labels),
 	})
 	if err != nil {
 		return nil, err
@@ -183,6 +191,28 @@ func (prvdr *Provider) bootSpot(br bootReq, count int64) ([]string, error) {
 	return ids, nil
 }
 
+// tagSpecifications converts the labels of a boot request into the tags of
+// the booted instances. Spot requests can't be tagged when they're made, so
+// spot instances are tagged by UpdateLabels once they launch.
+func tagSpecifications(labels string) []*ec2.TagSpecification {
+	if labels == "" {
+		return nil
+	}
+
+	var tags []*ec2.Tag
+	for _, label := range strings.Split(labels, ",") {
+		kv := strings.SplitN(label, "=", 2)
+		tags = append(tags, &ec2.Tag{
+			Key:   aws.String(kv[0]),
+			Value: aws.String(kv[1]),
+		})
+	}
+	return []*ec2.TagSpecification{{
+		ResourceType: aws.String(ec2.ResourceTypeInstance),
+		Tags:         tags,
+	}}
+}
+
 // Stop shuts down `machines` in `prvdr`.
 func (prvdr *Provider) Stop(machines []db.Machine) error {
 	var spotIDs, instIDs []string
@@ -344,6 +374,7 @@ func (prvdr *Provider) listInstances() (instances []awsMachine, err error) {
 					FloatingIP: floatingIP,
 					Size:       resolveString(inst.InstanceType),
 					DiskSize:   diskSize,
+					Labels:     tagLabels(inst.Tags),
 				},
 			})
 		}
@@ -452,6 +483,89 @@ func (prvdr *Provider) UpdateFloatingIPs(machines []db.Machine) error {
 	return nil
 }
 
+// UpdateLabels sets the tags of the instances of `machines` to their labels.
+func (prvdr *Provider) UpdateLabels(machines []db.Machine) error {
+	for _, machine := range machines {
+		id := machine.CloudID
+		if machine.Preemptible {
+			var err error
+			id, err = prvdr.getInstanceID(id)
+			if err != nil {
+				return err
+			}
+		}
+
+		insts, err := prvdr.DescribeInstances([]*ec2.Filter{{
+			Name:   aws.String("instance-id"),
+			Values: []*string{aws.String(id)}}})
+		if err != nil {
+			return err
+		}
+
+		var currLabels map[string]string
+		for _, res := range insts.Reservations {
+			for _, inst := range res.Instances {
+				currLabels = tagLabels(inst.Tags)
+			}
+		}
+
+		var removed, changed []*ec2.Tag
+		for _, key := range sortedKeys(currLabels) {
+			if _, ok := machine.Labels[key]; !ok {
+				removed = append(removed, &ec2.Tag{Key: aws.String(key)})
+			}
+		}
+		for _, key := range sortedKeys(machine.Labels) {
+			value := machine.Labels[key]
+			if curr, ok := currLabels[key]; !ok || curr != value {
+				changed = append(changed, &ec2.Tag{
+					Key:   aws.String(key),
+					Value: aws.String(value),
+				})
+			}
+		}
+
+		if len(removed) != 0 {
+			if err := prvdr.DeleteTags(id, removed); err != nil {
+				return err
+			}
+		}
+		if len(changed) != 0 {
+			if err := prvdr.CreateTags(id, changed); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// tagLabels converts the tags of an instance into the labels of its machine.
+// Tags reserved by Amazon, which can't be changed, are skipped.
+func tagLabels(tags []*ec2.Tag) map[string]string {
+	labels := map[string]string{}
+	for _, tag := range tags {
+		key := resolveString(tag.Key)
+		if strings.HasPrefix(key, "aws:") {
+			continue
+		}
+		labels[key] = resolveString(tag.Value)
+	}
+
+	if len(labels) == 0 {
+		return nil
+	}
+	return labels
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func (prvdr Provider) getInstanceID(spotID string) (string, error) {
 	spots, err := prvdr.DescribeSpotInstanceRequests([]string{spotID}, nil)
 	if err != nil {
@@ -339,12 +339,19 @@ func TestBoot(t *testing.T) {
 			DiskSize:    32,
 			Preemptible: false,
 		},
+		{
+			Role:     db.Master,
+			Size:     "m4.large",
+			DiskSize: 32,
+			Labels:   map[string]string{"tier": "db", "env": "prod"},
+		},
 	})
 	assert.Nil(t, err)
 
 	// Subset ignores order.
-	assert.Subset(t, []string{"spot1", "spot2", "reserved1", "reserved2"}, ids)
-	assert.Len(t, ids, 4)
+	assert.Subset(t, []string{"spot1", "spot2", "reserved1", "reserved2"},
+		ids)
+	assert.Len(t, ids, 6)
 
 	cfg := cfg.Ubuntu(db.Machine{Role: db.Master}, "")
 	mc.AssertCalled(t, "RequestSpotInstances", spotPrice, int64(2),
@@ -367,6 +374,24 @@ func TestBoot(t *testing.T) {
 		MaxCount: aws.Int64(2),
 		MinCount: aws.Int64(2),
 	})
+	mc.AssertCalled(t, "RunInstances", &ec2.RunInstancesInput{
+		ImageId:      aws.String(amis[testRegion]),
+		InstanceType: aws.String("m4.large"),
+		UserData: aws.String(base64.StdEncoding.EncodeToString(
+			[]byte(cfg))),
+		SecurityGroupIds: aws.StringSlice([]string{"groupId"}),
+		BlockDeviceMappings: []*ec2.BlockDeviceMapping{
+			blockDevice(32)},
+		MaxCount: aws.Int64(1),
+		MinCount: aws.Int64(1),
+		TagSpecifications: []*ec2.TagSpecification{{
+			ResourceType: aws.String(ec2.ResourceTypeInstance),
+			Tags: []*ec2.Tag{
+				{Key: aws.String("env"), Value: aws.String("prod")},
+				{Key: aws.String("tier"), Value: aws.String("db")},
+			},
+		}},
+	})
 	mc.AssertExpectations(t)
 }
 
@@ -580,6 +605,64 @@ func TestUpdateUnknownFloatingIP(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestUpdateLabels(t *testing.T) {
+	t.Parallel()
+
+	mc := new(mocks.Client)
+	amazonProvider := newAmazon(testNamespace, testRegion)
+	amazonProvider.Client = mc
+
+	instanceFilter := func(id string) []*ec2.Filter {
+		return []*ec2.Filter{{
+			Name:   aws.String("instance-id"),
+			Values: []*string{aws.String(id)}}}
+	}
+	describeOut := func(tags ...*ec2.Tag) *ec2.DescribeInstancesOutput {
+		return &ec2.DescribeInstancesOutput{
+			Reservations: []*ec2.Reservation{{
+				Instances: []*ec2.Instance{{Tags: tags}},
+			}},
+		}
+	}
+	tag := func(key, value string) *ec2.Tag {
+		return &ec2.Tag{Key: aws.String(key), Value: aws.String(value)}
+	}
+
+	// The reserved instance has a stale label, and a tag reserved by
+	// Amazon that should be left alone.
+	mc.On("DescribeInstances", instanceFilter("i-1")).Return(describeOut(
+		tag("tier", "web"), tag("stale", "x"),
+		tag("aws:cloudformation:stack-name", "stack")), nil)
+	mc.On("DeleteTags", "i-1", []*ec2.Tag{{Key: aws.String("stale")}}).
+		Return(nil)
+	mc.On("CreateTags", "i-1", []*ec2.Tag{tag("env", "prod"),
+		tag("tier", "db")}).Return(nil)
+
+	// The spot instance was launched without any tags.
+	mc.On("DescribeSpotInstanceRequests", []string{"sir-2"}, mock.Anything).
+		Return([]*ec2.SpotInstanceRequest{{
+			SpotInstanceRequestId: aws.String("sir-2"),
+			InstanceId:            aws.String("i-2"),
+		}}, nil)
+	mc.On("DescribeInstances", instanceFilter("i-2")).Return(describeOut(), nil)
+	mc.On("CreateTags", "i-2", []*ec2.Tag{tag("tier", "web")}).Return(nil)
+
+	err := amazonProvider.UpdateLabels([]db.Machine{{
+		CloudID: "i-1",
+		Labels:  map[string]string{"tier": "db", "env": "prod"},
+	}, {
+		CloudID:     "sir-2",
+		Preemptible: true,
+		Labels:      map[string]string{"tier": "web"},
+	}})
+	assert.NoError(t, err)
+	mc.AssertExpectations(t)
+
+	assert.Equal(t, map[string]string{"tier": "web"}, tagLabels([]*ec2.Tag{
+		tag("tier", "web"), tag("aws:autoscaling:groupName", "group")}))
+	assert.Nil(t, tagLabels(nil))
+}
+
 func TestCleanup(t *testing.T) {
 	t.Parallel()
 
@@ -14,6 +14,8 @@ type Client interface {
 	DescribeInstances([]*ec2.Filter) (*ec2.DescribeInstancesOutput, error)
 	RunInstances(*ec2.RunInstancesInput) (*ec2.Reservation, error)
 	TerminateInstances(ids []string) error
+	CreateTags(id string, tags []*ec2.Tag) error
+	DeleteTags(id string, tags []*ec2.Tag) error
 
 	DescribeSpotInstanceRequests(ids []string, filters []*ec2.Filter) (
 		[]*ec2.SpotInstanceRequest, error)
@@ -58,6 +60,22 @@ func (ac awsClient) TerminateInstances(ids []string) error {
 	return err
 }
 
+func (ac awsClient) CreateTags(id string, tags []*ec2.Tag) error {
+	c.Inc("Create Tags")
+	_, err := ac.client.CreateTags(&ec2.CreateTagsInput{
+		Resources: []*string{&id},
+		Tags:      tags})
+	return err
+}
+
+func (ac awsClient) DeleteTags(id string, tags []*ec2.Tag) error {
+	c.Inc("Delete Tags")
+	_, err := ac.client.DeleteTags(&ec2.DeleteTagsInput{
+		Resources: []*string{&id},
+		Tags:      tags})
+	return err
+}
+
 func (ac awsClient) DescribeSpotInstanceRequests(ids []string, filters []*ec2.Filter) (
 	[]*ec2.SpotInstanceRequest, error) {
 	c.Inc("List Spots")
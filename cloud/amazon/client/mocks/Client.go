@@ -73,6 +73,20 @@ func (_m *Client) CreateSecurityGroup(name string, description string) (string,
 	return r0, r1
 }
 
+// CreateTags provides a mock function with given fields: id, tags
+func (_m *Client) CreateTags(id string, tags []*ec2.Tag) error {
+	ret := _m.Called(id, tags)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, []*ec2.Tag) error); ok {
+		r0 = rf(id, tags)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // DeleteSecurityGroup provides a mock function with given fields: id
 func (_m *Client) DeleteSecurityGroup(id string) error {
 	ret := _m.Called(id)
@@ -87,6 +101,20 @@ func (_m *Client) DeleteSecurityGroup(id string) error {
 	return r0
 }
 
+// DeleteTags provides a mock function with given fields: id, tags
+func (_m *Client) DeleteTags(id string, tags []*ec2.Tag) error {
+	ret := _m.Called(id, tags)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, []*ec2.Tag) error); ok {
+		r0 = rf(id, tags)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // DescribeAddresses provides a mock function with given fields:
 func (_m *Client) DescribeAddresses() ([]*ec2.Address, error) {
 	ret := _m.Called()
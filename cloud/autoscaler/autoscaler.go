@@ -0,0 +1,629 @@
+// Package autoscaler adjusts the size of the machine pools in the blueprint,
+// and the number of replicas of its autoscaled containers. Pools grow when
+// containers can't be scheduled on the existing machines, and shrink when their
+// machines are underused. Before a machine is removed from its pool, it's
+// drained so that its containers are moved elsewhere. Autoscaled containers
+// are resized so that the average of their metric across the replicas
+// approaches its target.
+//
+// The autoscaler only changes the desired size of each pool, which is stored
+// in the MachinePool table, marks machines as draining, and changes the desired
+// number of replicas of each container, which is stored in the ReplicaSet
+// table. The cloud package boots and stops the machines, the minions drain
+// them, and the foreman passes the replica counts to the minions in the
+// blueprint. The state of the autoscaler is kept in memory, so its cooldowns
+// and usage history are lost when the daemon restarts. Pools and replica sets
+// then start from the machines and replicas that are still running.
+package autoscaler
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kelda/kelda/api/client"
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/connection"
+	"github.com/kelda/kelda/counter"
+	"github.com/kelda/kelda/db"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Credentials that the autoscaler should use to query the leader.
+var credentials connection.Credentials
+
+var c = counter.New("Autoscaler")
+
+var (
+	// How long to wait after resizing a pool before growing it again, so
+	// that the containers have time to be scheduled on the new machine.
+	scaleUpCooldown = 3 * time.Minute
+
+	// How long to wait after resizing a pool before shrinking it.
+	scaleDownCooldown = 10 * time.Minute
+
+	// How long a machine must be underused before it's drained.
+	underusedDelay = 10 * time.Minute
+
+	// How long to wait for the containers on a draining machine to move
+	// before stopping the machine anyways.
+	drainTimeout = 10 * time.Minute
+)
+
+// Machines whose CPU and memory usage are both below these percentages are
+// underused.
+const (
+	underusedCPUPercent    = 30
+	underusedMemoryPercent = 30
+)
+
+// Machines are only drained if the other machines in their pool would stay
+// below these percentages of their CPU and memory once they take on the
+// drained machine's usage.
+const (
+	drainMaxCPUPercent    = 80
+	drainMaxMemoryPercent = 80
+)
+
+type autoscaler struct {
+	// The last time each pool was resized, keyed by pool name.
+	lastScaled map[string]time.Time
+
+	// When each machine was first seen to be underused, keyed by cloud ID.
+	underusedSince map[string]time.Time
+
+	// When each draining machine started draining, keyed by cloud ID.
+	drainStarted map[string]time.Time
+
+	// The last time each replica set was resized, keyed by the hostname of
+	// the autoscaled container.
+	replicasScaled map[string]time.Time
+}
+
+func newAutoscaler() *autoscaler {
+	return &autoscaler{
+		lastScaled:     map[string]time.Time{},
+		underusedSince: map[string]time.Time{},
+		drainStarted:   map[string]time.Time{},
+		replicasScaled: map[string]time.Time{},
+	}
+}
+
+// Run periodically resizes the machine pools and autoscaled containers in the
+// blueprint.
+func Run(conn db.Conn, creds connection.Credentials) {
+	credentials = creds
+	as := newAutoscaler()
+	for range conn.TriggerTick(30, db.BlueprintTable).C {
+		as.runOnce(conn, time.Now())
+	}
+}
+
+func (as *autoscaler) runOnce(conn db.Conn, now time.Time) {
+	var pools []blueprint.MachinePool
+	var autoscaled []blueprint.Container
+	var machines []db.Machine
+	conn.Txn(db.BlueprintTable, db.MachineTable, db.MachinePoolTable,
+		db.ReplicaSetTable).Run(func(view db.Database) error {
+		if bp, err := view.GetBlueprint(); err == nil {
+			pools = bp.Blueprint.MachinePools
+			autoscaled = autoscaledContainers(bp.Blueprint)
+		}
+		syncPoolRows(view, pools)
+		syncReplicaSetRows(view, autoscaled, nil)
+		machines = view.SelectFromMachine(nil)
+		return nil
+	})
+
+	if len(pools) == 0 && len(autoscaled) == 0 {
+		return
+	}
+
+	// The statuses of the containers are only known by the leader. Without
+	// them, it's impossible to tell whether the pools are too small, whether
+	// draining machines are empty, or which pods belong to which replica
+	// set, so nothing is done.
+	containers, err := getContainers(machines)
+	if err != nil {
+		log.WithError(err).Debug("Failed to get containers from the leader")
+		return
+	}
+
+	conn.Txn(db.BlueprintTable, db.MachineTable, db.MachinePoolTable,
+		db.MetricsTable, db.ReplicaSetTable).Run(func(view db.Database) error {
+		bp, err := view.GetBlueprint()
+		if err != nil {
+			return err
+		}
+		as.update(view, bp.Blueprint.MachinePools, bp.Blueprint.Placements,
+			containers, now)
+
+		autoscaled := autoscaledContainers(bp.Blueprint)
+		syncReplicaSetRows(view, autoscaled, countReplicas(containers))
+		as.updateReplicas(view, autoscaled, containers, now)
+		return nil
+	})
+}
+
+// syncPoolRows makes sure that the count of each pool's row is within the
+// pool's bounds, and removes the rows of pools that are no longer in the
+// blueprint. New rows start at the number of machines already in the pool, so
+// that pools aren't shrunk when the daemon restarts. Until the cloud has matched
+// any machines to a pool, the pool's size is unknown, so its row is left for the
+// cloud to create.
+func syncPoolRows(view db.Database, pools []blueprint.MachinePool) {
+	rows := map[string]db.MachinePool{}
+	for _, row := range view.SelectFromMachinePool(nil) {
+		rows[row.Name] = row
+	}
+
+	existing := map[string]int{}
+	for _, dbm := range view.SelectFromMachine(func(dbm db.Machine) bool {
+		return dbm.Pool != "" && dbm.Status != db.Stopping
+	}) {
+		existing[dbm.Pool]++
+	}
+
+	for _, pool := range pools {
+		row, ok := rows[pool.Name]
+		delete(rows, pool.Name)
+		if !ok {
+			if existing[pool.Name] == 0 {
+				continue
+			}
+			row = view.InsertMachinePool()
+			row.Name = pool.Name
+			row.Count = existing[pool.Name]
+		}
+
+		if row.Count < pool.Min {
+			row.Count = pool.Min
+		}
+		if row.Count > pool.Max {
+			row.Count = pool.Max
+		}
+		view.Commit(row)
+	}
+
+	for _, row := range rows {
+		view.Remove(row)
+	}
+}
+
+func (as *autoscaler) update(view db.Database, pools []blueprint.MachinePool,
+	placements []blueprint.Placement, containers []db.Container, now time.Time) {
+
+	pools = append([]blueprint.MachinePool{}, pools...)
+	sort.Slice(pools, func(i, j int) bool {
+		return pools[i].Name < pools[j].Name
+	})
+
+	rows := map[string]db.MachinePool{}
+	for _, row := range view.SelectFromMachinePool(nil) {
+		rows[row.Name] = row
+	}
+
+	members := map[string][]db.Machine{}
+	for _, dbm := range view.SelectFromMachine(func(dbm db.Machine) bool {
+		return dbm.Pool != "" && dbm.Status != db.Stopping
+	}) {
+		members[dbm.Pool] = append(members[dbm.Pool], dbm)
+	}
+
+	metrics := machineMetrics(view.SelectFromMetrics(nil))
+	as.trackUsage(metrics, members, now)
+	unschedulable := unschedulableByPool(pools, rows, placements, containers)
+
+	for _, pool := range pools {
+		row, ok := rows[pool.Name]
+		if !ok {
+			continue
+		}
+
+		poolMembers := members[pool.Name]
+		needed := unschedulable[pool.Name]
+		if draining, ok := findDraining(poolMembers); ok {
+			as.updateDrain(view, pool, row, poolMembers, draining,
+				needed, containers, now)
+			continue
+		}
+
+		// Pools aren't shrunk while any container is unschedulable, in
+		// case the machine is what the container needs.
+		if len(needed) != 0 {
+			as.scaleUp(view, pool, row, poolMembers, needed, now)
+		} else if len(unschedulable) == 0 {
+			as.startDrain(view, pool, row, poolMembers, placements,
+				containers, metrics, now)
+		}
+	}
+}
+
+// scaleUp adds a machine to the pool so that the `needed` containers can be
+// scheduled. Pools aren't grown while they have machines that haven't
+// connected yet, since the containers might fit on them once they do.
+func (as *autoscaler) scaleUp(view db.Database, pool blueprint.MachinePool,
+	row db.MachinePool, members []db.Machine, needed []string, now time.Time) {
+
+	if row.Count >= pool.Max ||
+		now.Sub(as.lastScaled[pool.Name]) < scaleUpCooldown {
+		return
+	}
+
+	if len(members) < row.Count {
+		return
+	}
+	for _, dbm := range members {
+		if dbm.Status != db.Connected {
+			return
+		}
+	}
+
+	row.Count++
+	view.Commit(row)
+	as.lastScaled[pool.Name] = now
+	logEvent(pool.Name, "scale up", row.Count,
+		"unschedulable containers: "+strings.Join(needed, ", "))
+}
+
+// startDrain starts draining the pool's machine that has been underused the
+// longest, if the pool can shrink. Machines that host members of stateful
+// containers are never drained, since the members' storage is on the machine,
+// and neither are machines whose containers wouldn't fit on the pool's other
+// machines.
+func (as *autoscaler) startDrain(view db.Database, pool blueprint.MachinePool,
+	row db.MachinePool, members []db.Machine, placements []blueprint.Placement,
+	containers []db.Container, metrics map[string]db.Metrics, now time.Time) {
+
+	if row.Count <= pool.Min || len(members) != row.Count ||
+		now.Sub(as.lastScaled[pool.Name]) < scaleDownCooldown {
+		return
+	}
+
+	var candidates []db.Machine
+	for _, dbm := range members {
+		since, ok := as.underusedSince[dbm.CloudID]
+		if ok && now.Sub(since) >= underusedDelay &&
+			!hostsStatefulMembers(dbm, containers) {
+			candidates = append(candidates, dbm)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return as.underusedSince[candidates[i].CloudID].Before(
+			as.underusedSince[candidates[j].CloudID])
+	})
+
+	for _, candidate := range candidates {
+		reason := blockingDrain(candidate, members, placements, containers,
+			metrics)
+		if reason != "" {
+			log.WithField("pool", pool.Name).
+				WithField("machine", candidate.CloudID).
+				WithField("reason", reason).
+				Debug("Not draining underused machine")
+			continue
+		}
+
+		candidate.Draining = true
+		view.Commit(candidate)
+		as.drainStarted[candidate.CloudID] = now
+		logEvent(pool.Name, "drain", row.Count, "machine "+
+			candidate.CloudID+" is underused")
+		return
+	}
+}
+
+// blockingDrain returns why the containers on `candidate` can't be moved to the
+// other machines in its pool, or the empty string if they can. Containers don't
+// request resources, so the measured usage of the machines is compared
+// instead. Each container must also be placed on a machine that satisfies its
+// placement rules, and that doesn't host a container it must be kept apart
+// from.
+func blockingDrain(candidate db.Machine, members []db.Machine,
+	placements []blueprint.Placement, containers []db.Container,
+	metrics map[string]db.Metrics) string {
+
+	var others []db.Machine
+	for _, dbm := range members {
+		if dbm.CloudID != candidate.CloudID && !dbm.Draining &&
+			dbm.Status == db.Connected {
+			others = append(others, dbm)
+		}
+	}
+
+	usage, ok := metrics[candidate.CloudID]
+	if !ok {
+		return "the machine hasn't reported its usage"
+	}
+
+	var reported int
+	var cpu float64
+	var memoryUsed, memoryTotal uint64
+	for _, dbm := range others {
+		m, ok := metrics[dbm.CloudID]
+		if !ok || m.MemoryTotal == 0 {
+			continue
+		}
+		reported++
+		cpu += m.CPUPercent
+		memoryUsed += m.MemoryUsed
+		memoryTotal += m.MemoryTotal
+	}
+
+	switch {
+	case reported == 0:
+		return "no other machine in the pool has reported its usage"
+	case cpu+usage.CPUPercent > drainMaxCPUPercent*float64(reported):
+		return "the other machines in the pool don't have enough spare CPU"
+	case (memoryUsed+usage.MemoryUsed)*100 >
+		drainMaxMemoryPercent*memoryTotal:
+		return "the other machines in the pool don't have enough spare " +
+			"memory"
+	}
+
+	hosted := map[string][]db.Container{}
+	var moving []db.Container
+	for _, dbc := range containers {
+		switch {
+		case dbc.DaemonSet != "":
+		case dbc.Minion == candidate.PrivateIP:
+			moving = append(moving, dbc)
+		default:
+			hosted[dbc.Minion] = append(hosted[dbc.Minion], dbc)
+		}
+	}
+
+	for _, dbc := range moving {
+		placed := false
+		for _, dbm := range others {
+			machine := blueprint.Machine{
+				Provider:   string(dbm.Provider),
+				Region:     dbm.Region,
+				Size:       dbm.Size,
+				FloatingIP: dbm.FloatingIP,
+				Labels:     dbm.Labels,
+			}
+			if !blueprint.SatisfiesPlacements(machine,
+				placementTarget(dbc), placements) ||
+				keptApart(dbc, hosted[dbm.PrivateIP], placements) {
+				continue
+			}
+
+			hosted[dbm.PrivateIP] = append(hosted[dbm.PrivateIP], dbc)
+			placed = true
+			break
+		}
+
+		if !placed {
+			return "no other machine in the pool can host " + dbc.Hostname
+		}
+	}
+	return ""
+}
+
+// keptApart returns whether the placement rules forbid `dbc` from running on
+// the same machine as any of the `hosted` containers.
+func keptApart(dbc db.Container, hosted []db.Container,
+	placements []blueprint.Placement) bool {
+	for _, plcm := range placements {
+		if !plcm.Exclusive || plcm.OtherContainer == "" || plcm.Weight != 0 ||
+			plcm.Topology == blueprint.TopologyRegion {
+			continue
+		}
+
+		for _, other := range hosted {
+			if (isContainer(dbc, plcm.TargetContainer) &&
+				isContainer(other, plcm.OtherContainer)) ||
+				(isContainer(dbc, plcm.OtherContainer) &&
+					isContainer(other, plcm.TargetContainer)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isContainer returns whether `dbc` is the container with the given hostname,
+// or a member of it.
+func isContainer(dbc db.Container, hostname string) bool {
+	return dbc.Hostname == hostname || dbc.StatefulSet == hostname ||
+		dbc.ReplicaSet == hostname
+}
+
+// placementTarget returns the hostname that the placement rules of `dbc` are
+// written against.
+func placementTarget(dbc db.Container) string {
+	if dbc.StatefulSet != "" {
+		return dbc.StatefulSet
+	} else if dbc.ReplicaSet != "" {
+		return dbc.ReplicaSet
+	}
+	return dbc.Hostname
+}
+
+// updateDrain checks on the pool's draining machine. Once the machine has no
+// containers left, or it has been draining for too long, the pool is shrunk,
+// which causes the cloud to stop the machine. The drain is cancelled if the
+// pool can't shrink, or if containers need the machine.
+func (as *autoscaler) updateDrain(view db.Database, pool blueprint.MachinePool,
+	row db.MachinePool, members []db.Machine, draining db.Machine,
+	needed []string, containers []db.Container, now time.Time) {
+
+	// The pool was already shrunk, and the cloud hasn't stopped the machine
+	// yet.
+	if len(members) > row.Count {
+		return
+	}
+
+	started, ok := as.drainStarted[draining.CloudID]
+	if !ok {
+		started = now
+		as.drainStarted[draining.CloudID] = now
+	}
+
+	var reason string
+	switch {
+	case row.Count <= pool.Min:
+		reason = "the pool is at its minimum size"
+	case len(needed) != 0:
+		reason = "unschedulable containers: " + strings.Join(needed, ", ")
+	}
+	if reason != "" {
+		draining.Draining = false
+		view.Commit(draining)
+		delete(as.drainStarted, draining.CloudID)
+		logEvent(pool.Name, "cancel drain", row.Count, reason)
+		return
+	}
+
+	remaining := containersOn(draining, containers)
+	timedOut := now.Sub(started) >= drainTimeout
+	if len(remaining) != 0 && !timedOut {
+		return
+	}
+
+	reason = "machine " + draining.CloudID + " is drained"
+	if len(remaining) != 0 {
+		reason = "machine " + draining.CloudID + " timed out draining with " +
+			"containers: " + strings.Join(remaining, ", ")
+	}
+
+	row.Count--
+	view.Commit(row)
+	as.lastScaled[pool.Name] = now
+	delete(as.drainStarted, draining.CloudID)
+	delete(as.underusedSince, draining.CloudID)
+	logEvent(pool.Name, "scale down", row.Count, reason)
+}
+
+// machineMetrics returns the latest metrics of each machine, keyed by cloud ID.
+func machineMetrics(metrics []db.Metrics) map[string]db.Metrics {
+	latest := map[string]db.Metrics{}
+	for _, m := range metrics {
+		if m.PodName == "" {
+			latest[m.Machine] = m
+		}
+	}
+	return latest
+}
+
+// trackUsage records when each connected pool machine became underused,
+// according to its latest metrics.
+func (as *autoscaler) trackUsage(machineMetrics map[string]db.Metrics,
+	members map[string][]db.Machine, now time.Time) {
+
+	seen := map[string]struct{}{}
+	for _, poolMembers := range members {
+		for _, dbm := range poolMembers {
+			m, ok := machineMetrics[dbm.CloudID]
+			if !ok || dbm.Status != db.Connected || !underused(m) {
+				continue
+			}
+
+			seen[dbm.CloudID] = struct{}{}
+			if _, ok := as.underusedSince[dbm.CloudID]; !ok {
+				as.underusedSince[dbm.CloudID] = now
+			}
+		}
+	}
+
+	for id := range as.underusedSince {
+		if _, ok := seen[id]; !ok {
+			delete(as.underusedSince, id)
+		}
+	}
+}
+
+func underused(m db.Metrics) bool {
+	return m.CPUPercent < underusedCPUPercent && m.MemoryTotal != 0 &&
+		m.MemoryUsed*100 < underusedMemoryPercent*m.MemoryTotal
+}
+
+// unschedulableByPool returns the hostnames of the unschedulable containers,
+// grouped by the pool that should grow to fit them. Each container is
+// assigned to the first pool, by name, that has room to grow and whose
+// machines satisfy the container's placement rules. Containers that can't be
+// fit in any pool are grouped under the empty string.
+func unschedulableByPool(pools []blueprint.MachinePool,
+	rows map[string]db.MachinePool, placements []blueprint.Placement,
+	containers []db.Container) map[string][]string {
+
+	res := map[string][]string{}
+	for _, dbc := range containers {
+		// Daemon containers run on every machine, so adding a machine
+		// doesn't help them.
+		if dbc.DaemonSet != "" ||
+			!strings.HasPrefix(dbc.Status, db.Unschedulable) {
+			continue
+		}
+
+		var poolName string
+		for _, pool := range pools {
+			row, ok := rows[pool.Name]
+			if ok && row.Count < pool.Max &&
+				blueprint.SatisfiesPlacements(pool.Machine,
+					placementTarget(dbc), placements) {
+				poolName = pool.Name
+				break
+			}
+		}
+		res[poolName] = append(res[poolName], dbc.Hostname)
+	}
+	return res
+}
+
+// containersOn returns the hostnames of the containers that still need to be
+// moved off the given machine.
+func containersOn(dbm db.Machine, containers []db.Container) []string {
+	var hostnames []string
+	for _, dbc := range containers {
+		if dbc.Minion == dbm.PrivateIP && dbc.DaemonSet == "" {
+			hostnames = append(hostnames, dbc.Hostname)
+		}
+	}
+	return hostnames
+}
+
+func hostsStatefulMembers(dbm db.Machine, containers []db.Container) bool {
+	for _, dbc := range containers {
+		if dbc.Minion == dbm.PrivateIP && dbc.StatefulSet != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func findDraining(members []db.Machine) (db.Machine, bool) {
+	for _, dbm := range members {
+		if dbm.Draining {
+			return dbm, true
+		}
+	}
+	return db.Machine{}, false
+}
+
+// logEvent records an autoscaling decision. The decisions are logged at the
+// info level with structured fields so that they're easy to find in the
+// daemon's logs.
+func logEvent(pool, action string, count int, reason string) {
+	c.Inc(action)
+	log.WithFields(log.Fields{
+		"pool":   pool,
+		"action": action,
+		"count":  count,
+		"reason": reason,
+	}).Info("Machine pool autoscaling event")
+}
+
+// getContainers is saved in a variable to facilitate injecting test
+// containers for unit testing.
+var getContainers = func(machines []db.Machine) ([]db.Container, error) {
+	leader, err := client.Leader(machines, credentials)
+	if err != nil {
+		return nil, err
+	}
+	defer leader.Close()
+
+	return leader.QueryContainers()
+}
@@ -0,0 +1,456 @@
+package autoscaler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/db"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testMachine = blueprint.Machine{
+	Provider: "Amazon",
+	Region:   "us-west-1",
+	Size:     "m4.large",
+}
+
+func TestSyncPoolRows(t *testing.T) {
+	conn := db.New()
+	for _, dbm := range []db.Machine{
+		{CloudID: "1", Pool: "batch"},
+		{CloudID: "2", Pool: "batch"},
+		{CloudID: "3", Pool: "batch"},
+		{CloudID: "4", Pool: "batch", Status: db.Stopping},
+	} {
+		insertMachine(conn, dbm)
+	}
+
+	conn.Txn(db.MachineTable, db.MachinePoolTable).Run(
+		func(view db.Database) error {
+			for _, row := range []db.MachinePool{
+				{Name: "web", Count: 5},
+				{Name: "removed", Count: 1},
+			} {
+				row.ID = view.InsertMachinePool().ID
+				view.Commit(row)
+			}
+
+			syncPoolRows(view, []blueprint.MachinePool{
+				{Name: "web", Min: 1, Max: 3},
+				{Name: "batch", Min: 2, Max: 4},
+				{Name: "empty", Min: 1, Max: 2},
+			})
+			return nil
+		})
+
+	// New rows start at the number of machines in the pool, and rows
+	// aren't created for pools that the cloud hasn't matched machines to.
+	counts := map[string]int{}
+	for _, row := range conn.SelectFromMachinePool(nil) {
+		counts[row.Name] = row.Count
+	}
+	assert.Equal(t, map[string]int{"web": 3, "batch": 3}, counts)
+}
+
+func TestUnschedulableByPool(t *testing.T) {
+	pools := []blueprint.MachinePool{
+		{Name: "a-full", Machine: testMachine, Max: 1},
+		{Name: "b-web", Machine: testMachine, Max: 2},
+		{Name: "c-db", Machine: blueprint.Machine{
+			Provider: "Amazon",
+			Size:     "m4.large",
+			Labels:   map[string]string{"tier": "db"},
+		}, Max: 2},
+	}
+	rows := map[string]db.MachinePool{
+		"a-full": {Name: "a-full", Count: 1},
+		"b-web":  {Name: "b-web", Count: 1},
+		"c-db":   {Name: "c-db", Count: 1},
+	}
+	placements := []blueprint.Placement{
+		{TargetContainer: "db", Labels: map[string]string{"tier": "db"}},
+		{TargetContainer: "big", Size: "m4.xlarge"},
+		// Preferences don't restrict which pools can fit the container.
+		{TargetContainer: "web", Size: "m4.xlarge", Weight: 10},
+	}
+	unschedulable := db.Unschedulable + ": 0/1 nodes are available"
+	containers := []db.Container{
+		{Hostname: "web", Status: unschedulable},
+		{Hostname: "db-0", StatefulSet: "db", Status: unschedulable},
+		{Hostname: "big", Status: unschedulable},
+		{Hostname: "running", Status: "running"},
+		{Hostname: "agent", DaemonSet: "agent", Status: unschedulable},
+	}
+
+	assert.Equal(t, map[string][]string{
+		"b-web": {"web"},
+		"c-db":  {"db-0"},
+		"":      {"big"},
+	}, unschedulableByPool(pools, rows, placements, containers))
+}
+
+func TestScaleUp(t *testing.T) {
+	conn := db.New()
+	as := newAutoscaler()
+	now := time.Now()
+
+	pools := []blueprint.MachinePool{
+		{Name: "web", Machine: testMachine, Min: 1, Max: 2},
+	}
+	insertPool(conn, pools, 1)
+	insertMachine(conn, db.Machine{CloudID: "1", Pool: "web",
+		Status: db.Booting})
+
+	containers := []db.Container{
+		{Hostname: "web", Status: db.Unschedulable + ": no nodes"},
+	}
+
+	// The pool shouldn't grow while its machines are still booting.
+	update(conn, as, pools, containers, now)
+	assert.Equal(t, 1, poolCount(conn, "web"))
+
+	setStatus(conn, "1", db.Connected)
+	update(conn, as, pools, containers, now)
+	assert.Equal(t, 2, poolCount(conn, "web"))
+
+	// The pool shouldn't grow past its maximum size.
+	insertMachine(conn, db.Machine{CloudID: "2", Pool: "web",
+		Status: db.Connected})
+	update(conn, as, pools, containers, now.Add(time.Hour))
+	assert.Equal(t, 2, poolCount(conn, "web"))
+}
+
+func TestScaleUpCooldown(t *testing.T) {
+	conn := db.New()
+	as := newAutoscaler()
+	now := time.Now()
+
+	pools := []blueprint.MachinePool{
+		{Name: "web", Machine: testMachine, Min: 1, Max: 3},
+	}
+	insertPool(conn, pools, 1)
+	insertMachine(conn, db.Machine{CloudID: "1", Pool: "web",
+		Status: db.Connected})
+
+	containers := []db.Container{
+		{Hostname: "web", Status: db.Unschedulable + ": no nodes"},
+	}
+	update(conn, as, pools, containers, now)
+	assert.Equal(t, 2, poolCount(conn, "web"))
+
+	insertMachine(conn, db.Machine{CloudID: "2", Pool: "web",
+		Status: db.Connected})
+	update(conn, as, pools, containers, now.Add(time.Minute))
+	assert.Equal(t, 2, poolCount(conn, "web"))
+
+	update(conn, as, pools, containers, now.Add(scaleUpCooldown))
+	assert.Equal(t, 3, poolCount(conn, "web"))
+}
+
+func TestScaleDown(t *testing.T) {
+	conn := db.New()
+	as := newAutoscaler()
+	now := time.Now()
+
+	pools := []blueprint.MachinePool{
+		{Name: "web", Machine: testMachine, Min: 1, Max: 3},
+	}
+	insertPool(conn, pools, 2)
+	insertMachine(conn, db.Machine{CloudID: "busy", PrivateIP: "10.0.0.2",
+		Pool: "web", Status: db.Connected})
+	insertMachine(conn, db.Machine{CloudID: "idle", PrivateIP: "10.0.0.3",
+		Pool: "web", Status: db.Connected})
+	conn.Txn(db.MetricsTable).Run(func(view db.Database) error {
+		for _, m := range []db.Metrics{
+			{Machine: "busy", CPUPercent: 60, MemoryUsed: 4, MemoryTotal: 10},
+			{Machine: "idle", CPUPercent: 5, MemoryUsed: 1, MemoryTotal: 10},
+			{Machine: "idle", PodName: "web", CPUPercent: 5},
+		} {
+			m.ID = view.InsertMetrics().ID
+			view.Commit(m)
+		}
+		return nil
+	})
+
+	containers := []db.Container{
+		{Hostname: "web", Minion: "10.0.0.3", Status: "running"},
+		{Hostname: "agent", DaemonSet: "agent", Minion: "10.0.0.3",
+			Status: "running"},
+	}
+
+	// The machine must be underused for a while before it's drained.
+	update(conn, as, pools, containers, now)
+	assert.False(t, isDraining(conn, "idle"))
+
+	now = now.Add(underusedDelay)
+	update(conn, as, pools, containers, now)
+	assert.True(t, isDraining(conn, "idle"))
+	assert.False(t, isDraining(conn, "busy"))
+	assert.Equal(t, 2, poolCount(conn, "web"))
+
+	// The pool shouldn't shrink until the containers have moved off the
+	// draining machine. Daemon containers don't need to move.
+	update(conn, as, pools, containers, now.Add(time.Minute))
+	assert.Equal(t, 2, poolCount(conn, "web"))
+
+	containers[0].Minion = "10.0.0.2"
+	update(conn, as, pools, containers, now.Add(time.Minute))
+	assert.Equal(t, 1, poolCount(conn, "web"))
+
+	// Nothing should change while waiting for the cloud to stop the machine.
+	update(conn, as, pools, containers, now.Add(time.Hour))
+	assert.Equal(t, 1, poolCount(conn, "web"))
+	assert.True(t, isDraining(conn, "idle"))
+}
+
+func TestScaleDownStatefulMembers(t *testing.T) {
+	conn := db.New()
+	as := newAutoscaler()
+	now := time.Now()
+
+	pools := []blueprint.MachinePool{
+		{Name: "web", Machine: testMachine, Min: 1, Max: 3},
+	}
+	insertPool(conn, pools, 2)
+	insertMachine(conn, db.Machine{CloudID: "1", PrivateIP: "10.0.0.2",
+		Pool: "web", Status: db.Connected})
+	insertMachine(conn, db.Machine{CloudID: "2", PrivateIP: "10.0.0.3",
+		Pool: "web", Status: db.Connected})
+	conn.Txn(db.MetricsTable).Run(func(view db.Database) error {
+		for _, machine := range []string{"1", "2"} {
+			m := view.InsertMetrics()
+			m.Machine = machine
+			m.CPUPercent = 5
+			m.MemoryUsed = 1
+			m.MemoryTotal = 10
+			view.Commit(m)
+		}
+		return nil
+	})
+
+	// Both machines are underused, but only the one without stateful
+	// members can be drained.
+	containers := []db.Container{
+		{Hostname: "zk-0", StatefulSet: "zk", Minion: "10.0.0.2",
+			Status: "running"},
+	}
+	update(conn, as, pools, containers, now)
+	update(conn, as, pools, containers, now.Add(underusedDelay))
+	assert.False(t, isDraining(conn, "1"))
+	assert.True(t, isDraining(conn, "2"))
+}
+
+func TestBlockingDrain(t *testing.T) {
+	candidate := db.Machine{CloudID: "1", PrivateIP: "10.0.0.1",
+		Status: db.Connected}
+	members := []db.Machine{
+		candidate,
+		{CloudID: "2", PrivateIP: "10.0.0.2", Status: db.Connected},
+		{CloudID: "3", PrivateIP: "10.0.0.3", Status: db.Connected,
+			Draining: true},
+	}
+	metrics := map[string]db.Metrics{
+		"1": {CPUPercent: 20, MemoryUsed: 2, MemoryTotal: 10},
+		"2": {CPUPercent: 50, MemoryUsed: 5, MemoryTotal: 10},
+		"3": {CPUPercent: 0, MemoryUsed: 0, MemoryTotal: 10},
+	}
+	containers := []db.Container{
+		{Hostname: "web-0", ReplicaSet: "web", Minion: "10.0.0.1"},
+		{Hostname: "agent", DaemonSet: "agent", Minion: "10.0.0.1"},
+		{Hostname: "db", Minion: "10.0.0.2"},
+	}
+	assert.Empty(t, blockingDrain(candidate, members, nil, containers,
+		metrics))
+
+	// Draining machines can't take on the candidate's usage.
+	busy := map[string]db.Metrics{
+		"1": metrics["1"],
+		"2": {CPUPercent: 70, MemoryUsed: 5, MemoryTotal: 10},
+		"3": metrics["3"],
+	}
+	assert.Equal(t, "the other machines in the pool don't have enough "+
+		"spare CPU", blockingDrain(candidate, members, nil, containers,
+		busy))
+	busy["2"] = db.Metrics{CPUPercent: 10, MemoryUsed: 7, MemoryTotal: 10}
+	assert.Equal(t, "the other machines in the pool don't have enough "+
+		"spare memory", blockingDrain(candidate, members, nil, containers,
+		busy))
+	assert.Equal(t, "the machine hasn't reported its usage",
+		blockingDrain(candidate, members, nil, containers, nil))
+
+	// The containers must be able to run on the other machines.
+	members[1].Labels = map[string]string{"tier": "db"}
+	placements := []blueprint.Placement{
+		{TargetContainer: "web", Exclusive: true,
+			Labels: map[string]string{"tier": "db"}},
+	}
+	assert.Equal(t, "no other machine in the pool can host web-0",
+		blockingDrain(candidate, members, placements, containers,
+			metrics))
+
+	members[1].Labels = nil
+	placements = []blueprint.Placement{
+		{TargetContainer: "db", Exclusive: true, OtherContainer: "web"},
+	}
+	assert.Equal(t, "no other machine in the pool can host web-0",
+		blockingDrain(candidate, members, placements, containers,
+			metrics))
+
+	placements[0].Topology = blueprint.TopologyRegion
+	assert.Empty(t, blockingDrain(candidate, members, placements,
+		containers, metrics))
+}
+
+func TestDrainTimeout(t *testing.T) {
+	conn := db.New()
+	as := newAutoscaler()
+	now := time.Now()
+
+	pools := []blueprint.MachinePool{
+		{Name: "web", Machine: testMachine, Min: 1, Max: 3},
+	}
+	insertPool(conn, pools, 2)
+	insertMachine(conn, db.Machine{CloudID: "1", PrivateIP: "10.0.0.2",
+		Pool: "web", Status: db.Connected})
+	insertMachine(conn, db.Machine{CloudID: "2", PrivateIP: "10.0.0.3",
+		Pool: "web", Status: db.Connected, Draining: true})
+
+	containers := []db.Container{
+		{Hostname: "zk-0", StatefulSet: "zk", Minion: "10.0.0.3"},
+	}
+	update(conn, as, pools, containers, now)
+	assert.Equal(t, 2, poolCount(conn, "web"))
+
+	update(conn, as, pools, containers, now.Add(drainTimeout))
+	assert.Equal(t, 1, poolCount(conn, "web"))
+}
+
+func TestCancelDrain(t *testing.T) {
+	conn := db.New()
+	as := newAutoscaler()
+	now := time.Now()
+
+	pools := []blueprint.MachinePool{
+		{Name: "web", Machine: testMachine, Min: 1, Max: 3},
+	}
+	insertPool(conn, pools, 2)
+	insertMachine(conn, db.Machine{CloudID: "1", Pool: "web",
+		Status: db.Connected})
+	insertMachine(conn, db.Machine{CloudID: "2", PrivateIP: "10.0.0.3",
+		Pool: "web", Status: db.Connected, Draining: true})
+
+	// The drain should be cancelled if containers need to be scheduled,
+	// rather than booting a new machine.
+	containers := []db.Container{
+		{Hostname: "web", Status: db.Unschedulable + ": no nodes"},
+	}
+	update(conn, as, pools, containers, now)
+	assert.False(t, isDraining(conn, "2"))
+	assert.Equal(t, 2, poolCount(conn, "web"))
+
+	// The drain should be cancelled if the pool can't shrink.
+	setDraining(conn, "2")
+	pools[0].Min = 2
+	update(conn, as, pools, nil, now)
+	assert.False(t, isDraining(conn, "2"))
+	assert.Equal(t, 2, poolCount(conn, "web"))
+}
+
+func TestRunOnce(t *testing.T) {
+	conn := db.New()
+	as := newAutoscaler()
+
+	pools := []blueprint.MachinePool{
+		{Name: "web", Machine: testMachine, Min: 1, Max: 2},
+	}
+	conn.Txn(db.BlueprintTable, db.MachineTable).Run(func(view db.Database) error {
+		bp := view.InsertBlueprint()
+		bp.Blueprint.MachinePools = pools
+		view.Commit(bp)
+		return nil
+	})
+	insertMachine(conn, db.Machine{CloudID: "1", Pool: "web",
+		Status: db.Connected})
+
+	// If the containers can't be queried, the rows should be created, but
+	// the pools shouldn't be resized.
+	getContainers = func(_ []db.Machine) ([]db.Container, error) {
+		return nil, errors.New("no leader")
+	}
+	as.runOnce(conn, time.Now())
+	assert.Equal(t, 1, poolCount(conn, "web"))
+
+	getContainers = func(_ []db.Machine) ([]db.Container, error) {
+		return []db.Container{
+			{Hostname: "web", Status: db.Unschedulable + ": no nodes"},
+		}, nil
+	}
+	as.runOnce(conn, time.Now())
+	assert.Equal(t, 2, poolCount(conn, "web"))
+}
+
+func update(conn db.Conn, as *autoscaler, pools []blueprint.MachinePool,
+	containers []db.Container, now time.Time) {
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		syncPoolRows(view, pools)
+		as.update(view, pools, nil, containers, now)
+		return nil
+	})
+}
+
+func insertPool(conn db.Conn, pools []blueprint.MachinePool, count int) {
+	conn.Txn(db.MachinePoolTable).Run(func(view db.Database) error {
+		row := view.InsertMachinePool()
+		row.Name = pools[0].Name
+		row.Count = count
+		view.Commit(row)
+		return nil
+	})
+}
+
+func insertMachine(conn db.Conn, dbm db.Machine) {
+	conn.Txn(db.MachineTable).Run(func(view db.Database) error {
+		dbm.ID = view.InsertMachine().ID
+		view.Commit(dbm)
+		return nil
+	})
+}
+
+func updateMachine(conn db.Conn, cloudID string, fn func(*db.Machine)) {
+	conn.Txn(db.MachineTable).Run(func(view db.Database) error {
+		for _, dbm := range view.SelectFromMachine(func(dbm db.Machine) bool {
+			return dbm.CloudID == cloudID
+		}) {
+			fn(&dbm)
+			view.Commit(dbm)
+		}
+		return nil
+	})
+}
+
+func setStatus(conn db.Conn, cloudID, status string) {
+	updateMachine(conn, cloudID, func(dbm *db.Machine) { dbm.Status = status })
+}
+
+func setDraining(conn db.Conn, cloudID string) {
+	updateMachine(conn, cloudID, func(dbm *db.Machine) { dbm.Draining = true })
+}
+
+func isDraining(conn db.Conn, cloudID string) bool {
+	machines := conn.SelectFromMachine(func(dbm db.Machine) bool {
+		return dbm.CloudID == cloudID
+	})
+	return len(machines) == 1 && machines[0].Draining
+}
+
+func poolCount(conn db.Conn, name string) int {
+	for _, row := range conn.SelectFromMachinePool(nil) {
+		if row.Name == name {
+			return row.Count
+		}
+	}
+	return 0
+}
@@ -0,0 +1,194 @@
+package autoscaler
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/db"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// How long to wait after resizing a replica set before growing it
+	// again, unless the container sets its own cooldown. This gives the new
+	// replicas time to start and take load.
+	defaultReplicaScaleUpCooldown = 3 * time.Minute
+
+	// How long to wait after resizing a replica set before shrinking it,
+	// unless the container sets its own cooldown.
+	defaultReplicaScaleDownCooldown = 5 * time.Minute
+)
+
+// The replica count of a set isn't changed while its average metric is within
+// this fraction of the target, so that small fluctuations don't cause churn.
+const replicaTolerance = 0.1
+
+// autoscaledContainers returns the containers in the blueprint whose replica
+// count is driven by a metric.
+func autoscaledContainers(bp blueprint.Blueprint) []blueprint.Container {
+	var res []blueprint.Container
+	for _, c := range bp.Containers {
+		if c.Autoscale != nil {
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// syncReplicaSetRows makes sure that there's a row for each of the given
+// autoscaled containers, and that the replica count of each row is within its
+// container's bounds. New rows start at the number of replicas that currently
+// exist, as given by `existing`, so that replica sets aren't shrunk when the
+// daemon restarts. If `existing` is nil, the replicas aren't known yet, so no
+// rows are created.
+func syncReplicaSetRows(view db.Database, containers []blueprint.Container,
+	existing map[string]int) {
+
+	rows := map[string]db.ReplicaSet{}
+	for _, row := range view.SelectFromReplicaSet(nil) {
+		rows[row.Hostname] = row
+	}
+
+	for _, c := range containers {
+		row, ok := rows[c.Hostname]
+		delete(rows, c.Hostname)
+		if !ok {
+			if existing == nil {
+				continue
+			}
+			row = view.InsertReplicaSet()
+			row.Hostname = c.Hostname
+			row.Replicas = existing[c.Hostname]
+		}
+
+		if row.Replicas < c.Autoscale.Min {
+			row.Replicas = c.Autoscale.Min
+		}
+		if row.Replicas > c.Autoscale.Max {
+			row.Replicas = c.Autoscale.Max
+		}
+		view.Commit(row)
+	}
+
+	for _, row := range rows {
+		view.Remove(row)
+	}
+}
+
+// countReplicas returns the number of replicas of each autoscaled container,
+// keyed by the container's hostname.
+func countReplicas(containers []db.Container) map[string]int {
+	counts := map[string]int{}
+	for _, dbc := range containers {
+		if dbc.ReplicaSet != "" {
+			counts[dbc.ReplicaSet]++
+		}
+	}
+	return counts
+}
+
+// updateReplicas resizes each replica set so that the average value of its
+// metric approaches the container's target.
+func (as *autoscaler) updateReplicas(view db.Database,
+	autoscaled []blueprint.Container, containers []db.Container, now time.Time) {
+
+	autoscaled = append([]blueprint.Container{}, autoscaled...)
+	sort.Slice(autoscaled, func(i, j int) bool {
+		return autoscaled[i].Hostname < autoscaled[j].Hostname
+	})
+
+	rows := map[string]db.ReplicaSet{}
+	for _, row := range view.SelectFromReplicaSet(nil) {
+		rows[row.Hostname] = row
+	}
+
+	replicas := map[string][]db.Container{}
+	for _, dbc := range containers {
+		if dbc.ReplicaSet != "" {
+			replicas[dbc.ReplicaSet] = append(replicas[dbc.ReplicaSet], dbc)
+		}
+	}
+
+	metrics := view.SelectFromMetrics(nil)
+	for _, c := range autoscaled {
+		row, ok := rows[c.Hostname]
+		if !ok {
+			continue
+		}
+
+		autoscale := *c.Autoscale
+		value, ok := db.AutoscaleMetric(autoscale, replicas[c.Hostname],
+			metrics)
+		if !ok {
+			continue
+		}
+
+		desired := desiredReplicas(row.Replicas, value, autoscale)
+		action, cooldown := "scale up", defaultReplicaScaleUpCooldown
+		if autoscale.ScaleUpCooldown != 0 {
+			cooldown = time.Duration(autoscale.ScaleUpCooldown) * time.Second
+		}
+		switch {
+		case desired == row.Replicas:
+			continue
+		case desired < row.Replicas:
+			action, cooldown = "scale down", defaultReplicaScaleDownCooldown
+			if autoscale.ScaleDownCooldown != 0 {
+				cooldown = time.Duration(autoscale.ScaleDownCooldown) *
+					time.Second
+			}
+		}
+
+		if now.Sub(as.replicasScaled[c.Hostname]) < cooldown {
+			continue
+		}
+
+		row.Replicas = desired
+		view.Commit(row)
+		as.replicasScaled[c.Hostname] = now
+		logReplicaEvent(c.Hostname, action, desired, fmt.Sprintf(
+			"average %s is %.2f with a target of %.2f",
+			autoscale.Metric, value, autoscale.Target))
+	}
+
+	for hostname := range as.replicasScaled {
+		if _, ok := rows[hostname]; !ok {
+			delete(as.replicasScaled, hostname)
+		}
+	}
+}
+
+// desiredReplicas returns the number of replicas needed to bring the average
+// metric to its target, assuming that the load is spread evenly across the
+// replicas.
+func desiredReplicas(current int, value float64, autoscale blueprint.Autoscale) int {
+	ratio := value / autoscale.Target
+	if math.Abs(ratio-1) <= replicaTolerance {
+		return current
+	}
+
+	desired := int(math.Ceil(float64(current) * ratio))
+	if desired < autoscale.Min {
+		desired = autoscale.Min
+	}
+	if desired > autoscale.Max {
+		desired = autoscale.Max
+	}
+	return desired
+}
+
+// logReplicaEvent records a container autoscaling decision, in the same way
+// that logEvent does for machine pools.
+func logReplicaEvent(hostname, action string, replicas int, reason string) {
+	c.Inc("Replicas " + action)
+	log.WithFields(log.Fields{
+		"container": hostname,
+		"action":    action,
+		"replicas":  replicas,
+		"reason":    reason,
+	}).Info("Container autoscaling event")
+}
@@ -0,0 +1,213 @@
+package autoscaler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/db"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncReplicaSetRows(t *testing.T) {
+	conn := db.New()
+	conn.Txn(db.ReplicaSetTable).Run(func(view db.Database) error {
+		for _, row := range []db.ReplicaSet{
+			{Hostname: "web", Replicas: 9},
+			{Hostname: "removed", Replicas: 2},
+		} {
+			row.ID = view.InsertReplicaSet().ID
+			view.Commit(row)
+		}
+		return nil
+	})
+
+	autoscaled := []blueprint.Container{
+		{Hostname: "web", Autoscale: &blueprint.Autoscale{Min: 1, Max: 4}},
+		{Hostname: "api", Autoscale: &blueprint.Autoscale{Min: 2, Max: 4}},
+		{Hostname: "new", Autoscale: &blueprint.Autoscale{Min: 2, Max: 4}},
+	}
+
+	// Rows aren't created until the existing replicas are known.
+	conn.Txn(db.ReplicaSetTable).Run(func(view db.Database) error {
+		syncReplicaSetRows(view, autoscaled, nil)
+		return nil
+	})
+	assert.Equal(t, map[string]int{"web": 4}, replicaCounts(conn))
+
+	// New rows start at the number of existing replicas.
+	conn.Txn(db.ReplicaSetTable).Run(func(view db.Database) error {
+		syncReplicaSetRows(view, autoscaled, countReplicas([]db.Container{
+			{Hostname: "api-0", ReplicaSet: "api"},
+			{Hostname: "api-1", ReplicaSet: "api"},
+			{Hostname: "api-2", ReplicaSet: "api"},
+			{Hostname: "web-0", ReplicaSet: "web"},
+			{Hostname: "db"},
+		}))
+		return nil
+	})
+	assert.Equal(t, map[string]int{"web": 4, "api": 3, "new": 2},
+		replicaCounts(conn))
+}
+
+func TestDesiredReplicas(t *testing.T) {
+	autoscale := blueprint.Autoscale{Min: 1, Max: 10, Target: 50}
+
+	// Within the tolerance of the target.
+	assert.Equal(t, 4, desiredReplicas(4, 54, autoscale))
+	assert.Equal(t, 4, desiredReplicas(4, 46, autoscale))
+
+	assert.Equal(t, 6, desiredReplicas(4, 75, autoscale))
+	assert.Equal(t, 2, desiredReplicas(4, 25, autoscale))
+
+	// Bounded by the minimum and maximum.
+	assert.Equal(t, 10, desiredReplicas(4, 500, autoscale))
+	assert.Equal(t, 1, desiredReplicas(4, 0, autoscale))
+}
+
+func TestUpdateReplicas(t *testing.T) {
+	conn := db.New()
+	as := newAutoscaler()
+	now := time.Now()
+
+	autoscaled := []blueprint.Container{{
+		Hostname: "web",
+		Autoscale: &blueprint.Autoscale{Min: 1, Max: 5,
+			Metric: blueprint.AutoscaleCPU, Target: 50},
+	}}
+	containers := []db.Container{
+		{Hostname: "web-0", ReplicaSet: "web", PodName: "pod-0"},
+		{Hostname: "web-1", ReplicaSet: "web", PodName: "pod-1"},
+		{Hostname: "other", PodName: "pod-2"},
+	}
+	conn.Txn(db.ReplicaSetTable).Run(func(view db.Database) error {
+		syncReplicaSetRows(view, autoscaled, countReplicas(containers))
+		return nil
+	})
+
+	setPodCPU(conn, map[string]float64{"pod-0": 90, "pod-1": 110, "pod-2": 0})
+	updateReplicas(conn, as, autoscaled, containers, now)
+	assert.Equal(t, 4, replicaCounts(conn)["web"])
+
+	// The replica set shouldn't shrink until the scale down cooldown has
+	// passed.
+	setPodCPU(conn, map[string]float64{"pod-0": 10, "pod-1": 10})
+	now = now.Add(defaultReplicaScaleUpCooldown)
+	updateReplicas(conn, as, autoscaled, containers, now)
+	assert.Equal(t, 4, replicaCounts(conn)["web"])
+
+	now = now.Add(defaultReplicaScaleDownCooldown)
+	updateReplicas(conn, as, autoscaled, containers, now)
+	assert.Equal(t, 1, replicaCounts(conn)["web"])
+
+	// Containers can set their own cooldowns.
+	autoscaled[0].Autoscale.ScaleUpCooldown = 1
+	setPodCPU(conn, map[string]float64{"pod-0": 100})
+	updateReplicas(conn, as, autoscaled, containers, now.Add(time.Second))
+	assert.Equal(t, 2, replicaCounts(conn)["web"])
+
+	// Without metrics for any replica, the set shouldn't change.
+	setPodCPU(conn, nil)
+	updateReplicas(conn, as, autoscaled, containers, now.Add(time.Hour))
+	assert.Equal(t, 2, replicaCounts(conn)["web"])
+}
+
+func TestUpdateReplicasHTTP(t *testing.T) {
+	conn := db.New()
+	as := newAutoscaler()
+
+	autoscaled := []blueprint.Container{{
+		Hostname: "web",
+		Autoscale: &blueprint.Autoscale{Min: 1, Max: 5,
+			Metric: blueprint.AutoscaleHTTP, Target: 10},
+	}}
+	containers := []db.Container{
+		{Hostname: "web-0", ReplicaSet: "web", PodName: "pod-0"},
+		{Hostname: "web-1", ReplicaSet: "web", PodName: "pod-1"},
+	}
+	conn.Txn(db.MetricsTable, db.ReplicaSetTable).Run(func(view db.Database) error {
+		syncReplicaSetRows(view, autoscaled, map[string]int{"web": 1})
+		for _, m := range []db.Metrics{
+			{PodName: "pod-0", CPUPercent: 100, Custom: 30, HasCustom: true},
+			{PodName: "pod-1", CPUPercent: 100},
+		} {
+			m.ID = view.InsertMetrics().ID
+			view.Commit(m)
+		}
+		return nil
+	})
+
+	// Only the replica whose endpoint was scraped is taken into account.
+	updateReplicas(conn, as, autoscaled, containers, time.Now())
+	assert.Equal(t, 3, replicaCounts(conn)["web"])
+}
+
+func TestRunOnceReplicas(t *testing.T) {
+	conn := db.New()
+	as := newAutoscaler()
+
+	conn.Txn(db.BlueprintTable, db.MetricsTable).Run(func(view db.Database) error {
+		bp := view.InsertBlueprint()
+		bp.Blueprint.Containers = []blueprint.Container{
+			{Hostname: "web", Autoscale: &blueprint.Autoscale{Min: 1, Max: 3,
+				Metric: blueprint.AutoscaleCPU, Target: 50}},
+			{Hostname: "db"},
+		}
+		view.Commit(bp)
+
+		m := view.InsertMetrics()
+		m.PodName = "pod-0"
+		m.CPUPercent = 150
+		view.Commit(m)
+		return nil
+	})
+
+	// The rows aren't created until the leader reports which replicas
+	// exist.
+	getContainers = func(_ []db.Machine) ([]db.Container, error) {
+		return nil, errors.New("no leader")
+	}
+	as.runOnce(conn, time.Now())
+	assert.Empty(t, replicaCounts(conn))
+
+	getContainers = func(_ []db.Machine) ([]db.Container, error) {
+		return []db.Container{
+			{Hostname: "web-0", ReplicaSet: "web", PodName: "pod-0"},
+		}, nil
+	}
+	as.runOnce(conn, time.Now())
+	assert.Equal(t, map[string]int{"web": 3}, replicaCounts(conn))
+}
+
+func updateReplicas(conn db.Conn, as *autoscaler, autoscaled []blueprint.Container,
+	containers []db.Container, now time.Time) {
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		as.updateReplicas(view, autoscaled, containers, now)
+		return nil
+	})
+}
+
+func setPodCPU(conn db.Conn, cpu map[string]float64) {
+	conn.Txn(db.MetricsTable).Run(func(view db.Database) error {
+		for _, m := range view.SelectFromMetrics(nil) {
+			view.Remove(m)
+		}
+		for pod, percent := range cpu {
+			m := view.InsertMetrics()
+			m.PodName = pod
+			m.CPUPercent = percent
+			view.Commit(m)
+		}
+		return nil
+	})
+}
+
+func replicaCounts(conn db.Conn) map[string]int {
+	counts := map[string]int{}
+	for _, row := range conn.SelectFromReplicaSet(nil) {
+		counts[row.Hostname] = row.Replicas
+	}
+	return counts
+}
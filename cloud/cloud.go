@@ -39,6 +39,13 @@ type provider interface {
 	Cleanup() error
 }
 
+// A labeler is a provider that applies the labels of machines as tags on its
+// instances, and reports the tags as the labels of the machines it lists. The
+// tags of running machines are updated when their labels change.
+type labeler interface {
+	UpdateLabels([]db.Machine) error
+}
+
 var c = counter.New("Cloud")
 
 type cloud struct {
@@ -99,7 +106,8 @@ func startClouds(conn db.Conn, ns string, stop chan struct{}) {
 func (cld *cloud) run(stop <-chan struct{}) {
 	log.Debugf("Start Cloud %s", cld)
 
-	dbTicker := cld.conn.Trigger(db.BlueprintTable, db.MachineTable)
+	dbTicker := cld.conn.Trigger(db.BlueprintTable, db.MachineTable,
+		db.MachinePoolTable)
 	defer dbTicker.Stop()
 
 	// This loop executes runOnce() whenever the database triggers, or the
@@ -196,7 +204,8 @@ func (cld *cloud) runOnce() (maxPoll time.Duration) {
 
 	if len(jr.boot) == 0 &&
 		len(jr.terminate) == 0 &&
-		len(jr.updateIPs) == 0 {
+		len(jr.updateIPs) == 0 &&
+		len(jr.updateLabels) == 0 {
 		// ACLs must be processed after Kelda learns about what machines
 		// are in the cloud.  If we didn't, inter-machine ACLs could get
 		// removed when the Kelda controller restarts, even if there are
@@ -235,7 +244,11 @@ func (cld *cloud) usedByCurrentBlueprint() bool {
 		// conservatively assume that it is.
 		return true
 	}
-	return len(cld.desiredMachines(bp.Blueprint.Machines)) > 0
+	machines := bp.Blueprint.Machines
+	for _, pool := range bp.Blueprint.MachinePools {
+		machines = append(machines, pool.Machine)
+	}
+	return len(cld.desiredMachines(machines)) > 0
 }
 
 // desiredMachines takes a list of all machines specified by a blueprint, and returns
@@ -264,6 +277,7 @@ func (cld *cloud) desiredMachines(bpms []blueprint.Machine) []db.Machine {
 			Size:        bpm.Size,
 			DiskSize:    bpm.DiskSize,
 			SSHKeys:     bpm.SSHKeys,
+			Labels:      bpm.Labels,
 		}
 
 		if dbm.DiskSize == 0 {
@@ -279,6 +293,74 @@ func (cld *cloud) desiredMachines(bpms []blueprint.Machine) []db.Machine {
 	return dbms
 }
 
+// desiredPoolMachines returns the machines of the given pools that are in this
+// cloud's provider and region. Each pool has as many copies of its machine as
+// the count of its row in the MachinePool table, kept within the pool's bounds.
+// Pools without a row yet keep the machines in `unclaimed` that already belong
+// to them, and a row is created with that size, so that the pools of a restarted
+// daemon aren't shrunk. Pools only contain workers.
+func (cld *cloud) desiredPoolMachines(view db.Database,
+	pools []blueprint.MachinePool, unclaimed []db.Machine) []db.Machine {
+
+	rows := map[string]db.MachinePool{}
+	for _, row := range view.SelectFromMachinePool(nil) {
+		rows[row.Name] = row
+	}
+
+	var dbms []db.Machine
+	for _, pool := range pools {
+		template := cld.desiredMachines([]blueprint.Machine{pool.Machine})
+		if len(template) == 0 {
+			continue
+		}
+
+		template[0].Pool = pool.Name
+		template[0].Role = db.Worker
+
+		row, ok := rows[pool.Name]
+		if !ok {
+			row = view.InsertMachinePool()
+			row.Name = pool.Name
+			row.Count, unclaimed = claimPoolMachines(template[0], unclaimed)
+		}
+
+		if row.Count < pool.Min {
+			row.Count = pool.Min
+		}
+		if row.Count > pool.Max {
+			row.Count = pool.Max
+		}
+		if !ok {
+			view.Commit(row)
+		}
+
+		for i := 0; i < row.Count; i++ {
+			dbms = append(dbms, template[0])
+		}
+	}
+	return dbms
+}
+
+// claimPoolMachines counts the machines in `unclaimed` that belong to the pool
+// of `template`, and returns the machines that are left. When the daemon
+// restarts, the machines listed by the provider aren't in any pool yet, so the
+// machines that fit the template are counted as well.
+func claimPoolMachines(template db.Machine, unclaimed []db.Machine) (
+	int, []db.Machine) {
+
+	var count int
+	var rest []db.Machine
+	for _, dbm := range unclaimed {
+		if dbm.Status != db.Stopping && (dbm.Pool == template.Pool ||
+			dbm.Pool == "" && machineScore(template, dbm) >= 0) {
+			count++
+		} else {
+			rest = append(rest, dbm)
+		}
+	}
+	return count, rest
+}
+
 func sanitizeMachines(machines []db.Machine) []db.Machine {
 	// As a defensive measure, we only copy over the fields that the underlying
 	// provider should care about instead of passing `machines` to updateCloud
@@ -295,6 +377,7 @@ func sanitizeMachines(machines []db.Machine) []db.Machine {
 			Provider:    m.Provider,
 			Region:      m.Region,
 			FloatingIP:  m.FloatingIP,
+			Labels:      m.Labels,
 		})
 	}
 	return cloudMachines
@@ -339,6 +422,14 @@ func (cld *cloud) updateCloud(jr joinResult) {
 		}
 	}
 
+	if lblr, ok := cld.provider.(labeler); ok && len(jr.updateLabels) > 0 {
+		err := lblr.UpdateLabels(sanitizeMachines(jr.updateLabels))
+		logAttempt(len(jr.updateLabels), "update labels", err)
+		if err != nil {
+			jr.updateLabels = nil // Don't wait if we errored.
+		}
+	}
+
 	pred := func() bool {
 		machines, err := cld.provider.List()
 		if err != nil {
@@ -370,6 +461,13 @@ func (cld *cloud) updateCloud(jr joinResult) {
 			}
 		}
 
+		for _, jrm := range jr.updateLabels {
+			m, ok := ids[jrm.CloudID]
+			if ok && !labelsEqual(m.Labels, jrm.Labels) {
+				return false
+			}
+		}
+
 		return true
 	}
 
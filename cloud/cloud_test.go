@@ -107,6 +107,21 @@ func (p *fakeProvider) Cleanup() error {
 	return nil
 }
 
+type fakeLabeler struct {
+	*fakeProvider
+	updatedLabels []db.Machine
+}
+
+func (p *fakeLabeler) UpdateLabels(machines []db.Machine) error {
+	for _, desired := range machines {
+		curr := p.machines[desired.CloudID]
+		curr.Labels = desired.Labels
+		p.machines[desired.CloudID] = curr
+	}
+	p.updatedLabels = append(p.updatedLabels, machines...)
+	return nil
+}
+
 func newTestCloud(providerName db.ProviderName, region, namespace string) *cloud {
 	sleep = func(t time.Duration) {}
 	mock()
@@ -290,6 +305,47 @@ func TestCloudRunOnce(t *testing.T) {
 	})
 }
 
+func TestCloudRunOnceLabels(t *testing.T) {
+	cloudJoin = joinImpl
+	cld := newTestCloud(FakeAmazon, testRegion, "ns")
+	labeler := &fakeLabeler{fakeProvider: cld.provider.(*fakeProvider)}
+	cld.provider = labeler
+
+	labeler.machines["1"] = db.Machine{
+		Provider:  FakeAmazon,
+		Region:    testRegion,
+		Size:      "1",
+		CloudID:   "1",
+		PrivateIP: "10.0.0.1",
+		Labels:    map[string]string{"tier": "web"},
+	}
+	desiredLabels := map[string]string{"tier": "db"}
+	cld.conn.Txn(db.BlueprintTable).Run(func(view db.Database) error {
+		bp := view.InsertBlueprint()
+		bp.Namespace = "ns"
+		bp.Blueprint.Machines = []blueprint.Machine{{
+			Provider: string(FakeAmazon),
+			Region:   testRegion,
+			Size:     "1",
+			Labels:   desiredLabels,
+		}}
+		view.Commit(bp)
+		return nil
+	})
+
+	// The tags of the running machine should be updated to match its
+	// labels in the blueprint.
+	cld.runOnce()
+	assert.Len(t, labeler.updatedLabels, 1)
+	assert.Equal(t, "1", labeler.updatedLabels[0].CloudID)
+	assert.Equal(t, desiredLabels, labeler.updatedLabels[0].Labels)
+	assert.Equal(t, desiredLabels, labeler.machines["1"].Labels)
+
+	jr, err := joinImpl(cld)
+	assert.NoError(t, err)
+	assert.Empty(t, jr.updateLabels)
+}
+
 func TestACLs(t *testing.T) {
 	myIP = func() (string, error) {
 		return "5.6.7.8", nil
@@ -373,6 +429,64 @@ func TestDesiredMachines(t *testing.T) {
 		SSHKeys:     []string{"foo", "bar"}}}, res)
 }
 
+func TestDesiredPoolMachines(t *testing.T) {
+	cld := newTestCloud(FakeAmazon, testRegion, "ns")
+	adminKey = ""
+
+	machine := blueprint.Machine{
+		Provider: string(FakeAmazon),
+		Region:   testRegion,
+		Size:     "m4.large",
+	}
+	pools := []blueprint.MachinePool{
+		{Name: "web", Machine: machine, Min: 1, Max: 3},
+		{Name: "batch", Machine: machine, Min: 0, Max: 2},
+		{Name: "db", Machine: machine, Min: 1, Max: 3},
+		{Name: "google", Machine: blueprint.Machine{Provider: "Google"},
+			Min: 1, Max: 1},
+	}
+	unclaimed := []db.Machine{
+		{CloudID: "1", Provider: FakeAmazon, Region: testRegion,
+			Size: "m4.large", Pool: "db"},
+		{CloudID: "2", Provider: FakeAmazon, Region: testRegion,
+			Size: "m4.large"},
+		{CloudID: "3", Provider: FakeAmazon, Region: testRegion,
+			Size: "m4.large", Status: db.Stopping},
+		{CloudID: "4", Provider: FakeAmazon, Region: testRegion,
+			Size: "m4.xlarge"},
+	}
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		for _, row := range []db.MachinePool{
+			{Name: "web", Count: 2},
+			{Name: "batch", Count: 5},
+		} {
+			row.ID = view.InsertMachinePool().ID
+			view.Commit(row)
+		}
+
+		// Pools with rows are kept within their bounds. Pools without
+		// rows keep the running machines that fit them, and get a row
+		// with that size.
+		counts := map[string]int{}
+		for _, dbm := range cld.desiredPoolMachines(view, pools, unclaimed) {
+			assert.Equal(t, db.Role(db.Worker), dbm.Role)
+			assert.Equal(t, "m4.large", dbm.Size)
+			counts[dbm.Pool]++
+		}
+		assert.Equal(t, map[string]int{"web": 2, "batch": 2, "db": 2},
+			counts)
+		return nil
+	})
+
+	counts := map[string]int{}
+	for _, row := range conn.SelectFromMachinePool(nil) {
+		counts[row.Name] = row.Count
+	}
+	assert.Equal(t, map[string]int{"web": 2, "batch": 5, "db": 2}, counts)
+}
+
 func TestRunOnceMaxPoll(t *testing.T) {
 	var jr joinResult
 	cloudJoin = func(cld *cloud) (joinResult, error) { return jr, nil }
@@ -358,6 +358,29 @@ func (_m *Client) RemoveRules(_a0 string, _a1 []godo.InboundRule) (*godo.Respons
 	return r0, r1
 }
 
+// TagDroplet provides a mock function with given fields: _a0, _a1
+func (_m *Client) TagDroplet(_a0 string, _a1 int) (*godo.Response, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *godo.Response
+	if rf, ok := ret.Get(0).(func(string, int) *godo.Response); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*godo.Response)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, int) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // UnassignFloatingIP provides a mock function with given fields: _a0
 func (_m *Client) UnassignFloatingIP(_a0 string) (*godo.Action, *godo.Response, error) {
 	ret := _m.Called(_a0)
@@ -389,3 +412,26 @@ func (_m *Client) UnassignFloatingIP(_a0 string) (*godo.Action, *godo.Response,
 
 	return r0, r1, r2
 }
+
+// UntagDroplet provides a mock function with given fields: _a0, _a1
+func (_m *Client) UntagDroplet(_a0 string, _a1 int) (*godo.Response, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *godo.Response
+	if rf, ok := ret.Get(0).(func(string, int) *godo.Response); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*godo.Response)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, int) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
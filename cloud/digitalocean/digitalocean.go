@@ -181,6 +181,7 @@ func (prvdr Provider) listAll() (machines []taggedMachine, err error) {
 					FloatingIP:  floatingIPs[d.ID],
 					Size:        d.SizeSlug,
 					Preemptible: false,
+					Labels:      tagLabels(d.Tags),
 				},
 				tags: d.Tags,
 			}
@@ -226,6 +227,10 @@ func (prvdr Provider) Boot(machines []db.Machine) ([]string, error) {
 	type bootRequest struct {
 		size     string
 		userData string
+
+		// The comma-separated labels of the machine, as returned by
+		// db.SortedLabels.
+		labels string
 	}
 
 	bootSet := map[bootRequest]int{}
@@ -235,12 +240,23 @@ func (prvdr Provider) Boot(machines []db.Machine) ([]string, error) {
 			return nil, err
 		}
 
-		br := bootRequest{size: m.Size, userData: cfg.Ubuntu(m, "")}
+		br := bootRequest{
+			size:     m.Size,
+			userData: cfg.Ubuntu(m, ""),
+			labels:   strings.Join(db.SortedLabels(m.Labels), ","),
+		}
 		bootSet[br] = bootSet[br] + 1
 	}
 
 	var reqs []godo.DropletMultiCreateRequest
 	for br, count := range bootSet {
+		tags := []string{prvdr.getTag()}
+		if br.labels != "" {
+			for _, label := range strings.Split(br.labels, ",") {
+				tags = append(tags, labelTag(label))
+			}
+		}
+
 		// Digital Ocean has an arbitrary limit of 10 on the number of droplets
 		// that can be created in a single request.
 		for count > 0 {
@@ -262,7 +278,7 @@ func (prvdr Provider) Boot(machines []db.Machine) ([]string, error) {
 				Image:             godo.DropletCreateImage{ID: imageID},
 				PrivateNetworking: true,
 				UserData:          br.userData,
-				Tags:              []string{prvdr.getTag()}})
+				Tags:              tags})
 		}
 	}
 
@@ -293,6 +309,81 @@ func (prvdr Provider) Boot(machines []db.Machine) ([]string, error) {
 	return ids, nil
 }
 
+// UpdateLabels sets the label tags of the droplets of `machines`.
+func (prvdr Provider) UpdateLabels(machines []db.Machine) error {
+	for _, m := range machines {
+		id, err := strconv.Atoi(m.CloudID)
+		if err != nil {
+			return err
+		}
+
+		droplet, _, err := prvdr.GetDroplet(id)
+		if err != nil {
+			return fmt.Errorf("get droplet: %s", err)
+		}
+
+		currTags := map[string]struct{}{}
+		for _, tag := range droplet.Tags {
+			currTags[tag] = struct{}{}
+		}
+
+		desiredTags := map[string]struct{}{}
+		for _, label := range db.SortedLabels(m.Labels) {
+			tag := labelTag(label)
+			desiredTags[tag] = struct{}{}
+			if _, ok := currTags[tag]; ok {
+				continue
+			}
+
+			// Droplets can only be tagged with tags that exist.
+			if _, _, err := prvdr.CreateTag(tag); err != nil {
+				return fmt.Errorf("create tag: %s", err)
+			}
+			if _, err := prvdr.TagDroplet(tag, id); err != nil {
+				return fmt.Errorf("tag droplet: %s", err)
+			}
+		}
+
+		for _, tag := range droplet.Tags {
+			if _, ok := desiredTags[tag]; ok || !isLabelTag(tag) {
+				continue
+			}
+			if _, err := prvdr.UntagDroplet(tag, id); err != nil {
+				return fmt.Errorf("untag droplet: %s", err)
+			}
+		}
+	}
+	return nil
+}
+
+// labelTag converts a label of the form "key=value" into a tag of the form
+// "key:value", since DigitalOcean tags can't contain an equals sign.
+func labelTag(label string) string {
+	return strings.Replace(label, "=", ":", 1)
+}
+
+// Only label tags contain a colon, so the namespace tag isn't a label.
+func isLabelTag(tag string) bool {
+	return strings.Contains(tag, ":")
+}
+
+// tagLabels converts the label tags of a droplet into the labels of its machine.
+func tagLabels(tags []string) map[string]string {
+	var labels map[string]string
+	for _, tag := range tags {
+		if !isLabelTag(tag) {
+			continue
+		}
+
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		kv := strings.SplitN(tag, ":", 2)
+		labels[kv[0]] = kv[1]
+	}
+	return labels
+}
+
 // Returns a unique tag to use for all entities in this namespace and region.
 func (prvdr Provider) getTag() string {
 	return fmt.Sprintf("%s-%s", prvdr.namespace, prvdr.region)
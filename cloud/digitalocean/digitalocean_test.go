@@ -68,7 +68,7 @@ func TestList(t *testing.T) {
 			SizeSlug:  "size",
 			VolumeIDs: []string{"foo"},
 			Region:    godoRegion,
-			Tags:      []string{tag},
+			Tags:      []string{tag, "tier:web"},
 		}, {
 			ID:       124,
 			Networks: network,
@@ -142,6 +142,7 @@ func TestList(t *testing.T) {
 			PrivateIP:   "privateIP",
 			Size:        "size",
 			Preemptible: false,
+			Labels:      map[string]string{"tier": "web"},
 		},
 		{
 			Provider:    "DigitalOcean",
@@ -202,7 +203,9 @@ func TestBoot(t *testing.T) {
 	for i := 0; i < 11; i++ {
 		bootSet = append(bootSet, db.Machine{Size: "size1"})
 	}
-	bootSet = append(bootSet, db.Machine{Size: "size2"}, db.Machine{Size: "size2"})
+	labels := map[string]string{"tier": "db", "env": "prod"}
+	bootSet = append(bootSet, db.Machine{Size: "size2", Labels: labels},
+		db.Machine{Size: "size2", Labels: labels})
 
 	userData := cfg.Ubuntu(bootSet[0], "")
 	mc.On("CreateDroplets", &godo.DropletMultiCreateRequest{
@@ -234,7 +237,7 @@ func TestBoot(t *testing.T) {
 		Image:             godo.DropletCreateImage{ID: imageID},
 		PrivateNetworking: true,
 		UserData:          userData,
-		Tags:              []string{doPrvdr.getTag()},
+		Tags:              []string{doPrvdr.getTag(), "env:prod", "tier:db"},
 	}).Return([]godo.Droplet{{ID: 12}, {ID: 13}}, nil, nil).Once()
 
 	ids, err = doPrvdr.Boot(bootSet)
@@ -315,6 +318,42 @@ func TestStop(t *testing.T) {
 	assert.EqualError(t, err, errMsg)
 }
 
+func TestUpdateLabels(t *testing.T) {
+	mc := new(mocks.Client)
+	doPrvdr, err := newDigitalOcean(testNamespace, testRegion)
+	assert.Nil(t, err)
+	doPrvdr.Client = mc
+
+	tag := fmt.Sprintf("%s-%s", testNamespace, testRegion)
+	mc.On("GetDroplet", 123).Return(&godo.Droplet{
+		ID:   123,
+		Tags: []string{tag, "tier:web", "env:prod"},
+	}, nil, nil).Once()
+	mc.On("CreateTag", "tier:db").Return(nil, nil, nil).Once()
+	mc.On("TagDroplet", "tier:db", 123).Return(nil, nil).Once()
+	mc.On("UntagDroplet", "tier:web", 123).Return(nil, nil).Once()
+
+	// The namespace tag and unchanged labels should be left alone.
+	err = doPrvdr.UpdateLabels([]db.Machine{{
+		CloudID: "123",
+		Labels:  map[string]string{"tier": "db", "env": "prod"},
+	}})
+	assert.NoError(t, err)
+	mc.AssertExpectations(t)
+
+	mc.On("GetDroplet", 124).Return(nil, nil, errMock).Once()
+	err = doPrvdr.UpdateLabels([]db.Machine{{CloudID: "124"}})
+	assert.EqualError(t, err, fmt.Sprintf("get droplet: %s", errMsg))
+
+	mc.On("GetDroplet", 125).Return(&godo.Droplet{ID: 125}, nil, nil).Once()
+	mc.On("CreateTag", "tier:db").Return(nil, nil, errMock).Once()
+	err = doPrvdr.UpdateLabels([]db.Machine{{
+		CloudID: "125",
+		Labels:  map[string]string{"tier": "db"},
+	}})
+	assert.EqualError(t, err, fmt.Sprintf("create tag: %s", errMsg))
+}
+
 func TestSetACLs(t *testing.T) {
 	mc := new(mocks.Client)
 	doPrvdr, err := newDigitalOcean(testNamespace, testRegion)
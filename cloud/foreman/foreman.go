@@ -8,6 +8,7 @@ import (
 
 	"golang.org/x/net/context"
 
+	"github.com/kelda/kelda/blueprint"
 	"github.com/kelda/kelda/connection"
 	"github.com/kelda/kelda/counter"
 	"github.com/kelda/kelda/db"
@@ -22,6 +23,7 @@ var credentials connection.Credentials
 type client interface {
 	setMinion(pb.MinionConfig) error
 	getMinion() (pb.MinionConfig, error)
+	getMetrics() ([]*pb.Metrics, error)
 	Close()
 }
 
@@ -74,12 +76,19 @@ func updateMinions(conn db.Conn, machines []db.Machine,
 
 var newMinion = newMinionImpl
 
+// How often the resource usage metrics of connected minions are polled.
+var metricsInterval = 10 * time.Second
+
 func newMinionImpl(conn db.Conn, cloudID string, stop chan struct{}) {
 	// Threads that aren't currently connected to their minion should run more often.
 	frequentTick := time.NewTicker(5 * time.Second)
-	tableTrigger := conn.TriggerTick(60, db.BlueprintTable, db.MachineTable)
+	metricsTick := time.NewTicker(metricsInterval)
+	tableTrigger := conn.TriggerTick(60, db.BlueprintTable, db.MachineTable,
+		db.ReplicaSetTable)
 	defer frequentTick.Stop()
+	defer metricsTick.Stop()
 	defer tableTrigger.Stop()
+	defer removeMetrics(conn, cloudID)
 
 	// If the other machines in the cluster are not ready by this time, we will
 	// configure the cluster as if they were not part of it. This grace period
@@ -97,6 +106,11 @@ func newMinionImpl(conn db.Conn, cloudID string, stop chan struct{}) {
 			if connected {
 				continue
 			}
+		case <-metricsTick.C:
+			if connected {
+				updateMetrics(conn, cloudID)
+			}
+			continue
 		case <-tableTrigger.C:
 		}
 
@@ -122,9 +136,11 @@ func runOnce(waitForMachinesCutoff time.Time, conn db.Conn, cloudID string) (
 
 	var blueprint string
 	var machines []db.Machine
-	conn.Txn(db.BlueprintTable, db.MachineTable).Run(func(view db.Database) error {
+	conn.Txn(db.BlueprintTable, db.MachineTable,
+		db.ReplicaSetTable).Run(func(view db.Database) error {
 		bp, _ := view.GetBlueprint()
-		blueprint = bp.Blueprint.String()
+		blueprint = withReplicas(bp.Blueprint,
+			view.SelectFromReplicaSet(nil)).String()
 
 		machines = view.SelectFromMachine(func(m db.Machine) bool {
 			return m.CloudID != "" && m.PublicIP != "" &&
@@ -190,6 +206,29 @@ func clusterReady(machines []db.Machine) bool {
 	return true
 }
 
+// withReplicas returns a copy of the blueprint in which the number of replicas
+// of each autoscaled container is the number chosen by the autoscaler.
+func withReplicas(bp blueprint.Blueprint,
+	replicaSets []db.ReplicaSet) blueprint.Blueprint {
+
+	replicas := map[string]int{}
+	for _, set := range replicaSets {
+		replicas[set.Hostname] = set.Replicas
+	}
+
+	// The containers are copied so that the blueprint in the database isn't
+	// modified.
+	containers := make([]blueprint.Container, len(bp.Containers))
+	copy(containers, bp.Containers)
+	for i, c := range containers {
+		if n, ok := replicas[c.Hostname]; ok && c.Autoscale != nil {
+			containers[i].Replicas = n
+		}
+	}
+	bp.Containers = containers
+	return bp
+}
+
 func makeConfig(machines []db.Machine, minionMachine db.Machine,
 	blueprint string) pb.MinionConfig {
 
@@ -209,6 +248,8 @@ func makeConfig(machines []db.Machine, minionMachine db.Machine,
 		Region:         minionMachine.Region,
 		EtcdMembers:    etcdIPs,
 		AuthorizedKeys: minionMachine.SSHKeys,
+		Labels:         minionMachine.Labels,
+		Draining:       minionMachine.Draining,
 	}
 }
 
@@ -244,6 +285,69 @@ func setMinionStatus(conn db.Conn, cloudID string, role pb.MinionConfig_Role,
 	})
 }
 
+// updateMetrics fetches the latest resource usage metrics from the minion at
+// the machine defined by `cloudID`, and replaces the machine's metrics in the
+// database with them.
+func updateMetrics(conn db.Conn, cloudID string) {
+	machines := conn.SelectFromMachine(func(m db.Machine) bool {
+		return m.CloudID == cloudID && m.PublicIP != ""
+	})
+	if len(machines) != 1 {
+		return
+	}
+
+	cli, err := newClient(machines[0].PublicIP)
+	if err != nil {
+		log.WithError(err).Debugf("Failed to connect to minion %s", cloudID)
+		return
+	}
+	defer cli.Close()
+
+	metrics, err := cli.getMetrics()
+	if err != nil {
+		log.WithError(err).Debug("Failed to get minion metrics")
+		return
+	}
+
+	conn.Txn(db.MetricsTable).Run(func(view db.Database) error {
+		removeMachineMetrics(view, cloudID)
+		for _, pbm := range metrics {
+			m := view.InsertMetrics()
+			m.Machine = cloudID
+			m.PodName = pbm.PodName
+			m.Time = time.Unix(0, pbm.Time)
+			m.CPUPercent = pbm.CPUPercent
+			m.MemoryUsed = pbm.MemoryUsed
+			m.MemoryTotal = pbm.MemoryTotal
+			m.DiskUsed = pbm.DiskUsed
+			m.DiskTotal = pbm.DiskTotal
+			m.NetworkRx = pbm.NetworkRx
+			m.NetworkTx = pbm.NetworkTx
+			m.Custom = pbm.Custom
+			m.HasCustom = pbm.HasCustom
+			view.Commit(m)
+		}
+		return nil
+	})
+}
+
+// removeMetrics removes the metrics of the machine defined by `cloudID` from
+// the database so that stale metrics aren't shown after the machine is gone.
+func removeMetrics(conn db.Conn, cloudID string) {
+	conn.Txn(db.MetricsTable).Run(func(view db.Database) error {
+		removeMachineMetrics(view, cloudID)
+		return nil
+	})
+}
+
+func removeMachineMetrics(view db.Database, cloudID string) {
+	for _, m := range view.SelectFromMetrics(func(m db.Metrics) bool {
+		return m.Machine == cloudID
+	}) {
+		view.Remove(m)
+	}
+}
+
 func newClientImpl(ip string) (client, error) {
 	c.Inc("New Minion Client")
 	cc, err := connection.Client("tcp", ip+":9999", credentials.ClientOpts())
@@ -270,6 +374,18 @@ func (cl clientImpl) getMinion() (pb.MinionConfig, error) {
 	return *cfg, nil
 }
 
+func (cl clientImpl) getMetrics() ([]*pb.Metrics, error) {
+	c.Inc("Get Metrics")
+	ctx, _ := context.WithTimeout(context.Background(), 60*time.Second)
+	reply, err := cl.GetMinionMetrics(ctx, &pb.Request{})
+	if err != nil {
+		c.Inc("Get Metrics Error")
+		return nil, err
+	}
+
+	return reply.Metrics, nil
+}
+
 func (cl clientImpl) setMinion(cfg pb.MinionConfig) error {
 	c.Inc("Set Minion")
 	ctx, _ := context.WithTimeout(context.Background(), 60*time.Second)
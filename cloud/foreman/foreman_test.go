@@ -7,6 +7,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/kelda/kelda/blueprint"
 	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/minion/pb"
 )
@@ -89,6 +90,8 @@ func TestMakeConfig(t *testing.T) {
 		Role:      db.Worker,
 		PrivateIP: "10.10.10.10",
 		CloudID:   "ID1",
+		Labels:    map[string]string{"tier": "db"},
+		Draining:  true,
 	}
 
 	machine2 := db.Machine{
@@ -104,12 +107,15 @@ func TestMakeConfig(t *testing.T) {
 	assert.Equal(t, `{"Namespace":"ns"}`, config.Blueprint)
 	assert.Len(t, config.EtcdMembers, 1)
 	assert.Contains(t, config.EtcdMembers, "20.20.20.20")
+	assert.Equal(t, map[string]string{"tier": "db"}, config.Labels)
+	assert.True(t, config.Draining)
 
 	config = makeConfig(allMachines, machine2, `{"Namespace":"ns"}`)
 	assert.Equal(t, "20.20.20.20", config.PrivateIP)
 	assert.Equal(t, `{"Namespace":"ns"}`, config.Blueprint)
 	assert.Len(t, config.EtcdMembers, 1)
 	assert.Contains(t, config.EtcdMembers, "20.20.20.20")
+	assert.False(t, config.Draining)
 
 	machine3 := db.Machine{
 		PublicIP:  "3.3.3.3",
@@ -136,6 +142,30 @@ func TestMakeConfig(t *testing.T) {
 	assert.Contains(t, config.EtcdMembers, "30.30.30.30")
 }
 
+func TestWithReplicas(t *testing.T) {
+	t.Parallel()
+
+	autoscale := &blueprint.Autoscale{Min: 1, Max: 5}
+	bp := blueprint.Blueprint{Containers: []blueprint.Container{
+		{Hostname: "web", Autoscale: autoscale},
+		{Hostname: "worker", Autoscale: autoscale},
+		{Hostname: "db"},
+	}}
+	res := withReplicas(bp, []db.ReplicaSet{
+		{Hostname: "web", Replicas: 3},
+		// Only autoscaled containers should be changed.
+		{Hostname: "db", Replicas: 2},
+	})
+	assert.Equal(t, []blueprint.Container{
+		{Hostname: "web", Autoscale: autoscale, Replicas: 3},
+		{Hostname: "worker", Autoscale: autoscale},
+		{Hostname: "db"},
+	}, res.Containers)
+
+	// The original blueprint shouldn't be modified.
+	assert.Equal(t, 0, bp.Containers[0].Replicas)
+}
+
 func TestClusterReady(t *testing.T) {
 	t.Parallel()
 
@@ -255,6 +285,63 @@ func TestSetMinionStatus(t *testing.T) {
 	assert.Equal(t, db.Stopping, dbm.Status)
 }
 
+func TestUpdateMetrics(t *testing.T) {
+	conn := db.New()
+	clients := mock(t, map[string]pb.MinionConfig_Role{
+		"1.1.1.1": pb.MinionConfig_WORKER,
+	})
+
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		m := view.InsertMachine()
+		m.CloudID = "ID1"
+		m.PublicIP = "1.1.1.1"
+		view.Commit(m)
+
+		stale := view.InsertMetrics()
+		stale.Machine = "ID1"
+		stale.PodName = "stale"
+		view.Commit(stale)
+
+		other := view.InsertMetrics()
+		other.Machine = "ID2"
+		view.Commit(other)
+		return nil
+	})
+
+	clients.clients["1.1.1.1"] = &fakeClient{
+		clients: clients,
+		ip:      "1.1.1.1",
+		metrics: []*pb.Metrics{
+			{Time: 100, CPUPercent: 10, DiskUsed: 5},
+			{PodName: "pod", Time: 100, MemoryUsed: 20, NetworkTx: 3,
+				Custom: 4.5, HasCustom: true},
+		},
+	}
+
+	updateMetrics(conn, "ID1")
+	metrics := conn.SelectFromMetrics(func(m db.Metrics) bool {
+		return m.Machine == "ID1"
+	})
+	for i := range metrics {
+		metrics[i].ID = 0
+	}
+	assert.Equal(t, []db.Metrics{
+		{Machine: "ID1", Time: time.Unix(0, 100), CPUPercent: 10, DiskUsed: 5},
+		{Machine: "ID1", PodName: "pod", Time: time.Unix(0, 100),
+			MemoryUsed: 20, NetworkTx: 3, Custom: 4.5, HasCustom: true},
+	}, metrics)
+
+	// Errors fetching the metrics should leave the old metrics in place.
+	clients.getMinionError = true
+	updateMetrics(conn, "ID1")
+	assert.Len(t, conn.SelectFromMetrics(nil), 3)
+
+	removeMetrics(conn, "ID1")
+	metrics = conn.SelectFromMetrics(nil)
+	assert.Len(t, metrics, 1)
+	assert.Equal(t, "ID2", metrics[0].Machine)
+}
+
 func mock(t *testing.T, roles map[string]pb.MinionConfig_Role) *clients {
 	clients := &clients{make(map[string]*fakeClient), false, false}
 	newClient = func(ip string) (client, error) {
@@ -286,6 +373,7 @@ type fakeClient struct {
 	ip      string
 	role    pb.MinionConfig_Role
 	mc      pb.MinionConfig
+	metrics []*pb.Metrics
 	closed  bool
 }
 
@@ -304,6 +392,13 @@ func (fc *fakeClient) getMinion() (pb.MinionConfig, error) {
 	return mc, nil
 }
 
+func (fc *fakeClient) getMetrics() ([]*pb.Metrics, error) {
+	if fc.clients.getMinionError {
+		return nil, errors.New("mock error")
+	}
+	return fc.metrics, nil
+}
+
 func (fc *fakeClient) Close() {
 	fc.clients.clients[fc.ip].closed = true
 }
@@ -28,6 +28,8 @@ type Client interface {
 		accessConfig *compute.AccessConfig) (*compute.Operation, error)
 	DeleteAccessConfig(zone, instance, accessConfig,
 		networkInterface string) (*compute.Operation, error)
+	SetLabels(zone, instance string, req *compute.InstancesSetLabelsRequest) (
+		*compute.Operation, error)
 	GetZone(zone string) (*compute.Zone, error)
 	GetZoneOperation(zone, operation string) (*compute.Operation, error)
 	GetGlobalOperation(operation string) (*compute.Operation, error)
@@ -131,6 +133,12 @@ func (ci *client) DeleteAccessConfig(zone, instance, accessConfig,
 		accessConfig, networkInterface).Do()
 }
 
+func (ci *client) SetLabels(zone, instance string,
+	req *compute.InstancesSetLabelsRequest) (*compute.Operation, error) {
+	c.Inc("Set Labels")
+	return ci.gce.Instances.SetLabels(ci.projID, zone, instance, req).Do()
+}
+
 func (ci *client) GetZone(zone string) (*compute.Zone, error) {
 	c.Inc("Get Zone")
 	return ci.gce.Zones.Get(ci.projID, zone).Do()
@@ -377,3 +377,26 @@ func (_m *Client) ListNetworks(name string) (*compute.NetworkList, error) {
 
 	return r0, r1
 }
+
+// SetLabels provides a mock function with given fields: zone, instance, req
+func (_m *Client) SetLabels(zone string, instance string, req *compute.InstancesSetLabelsRequest) (*compute.Operation, error) {
+	ret := _m.Called(zone, instance, req)
+
+	var r0 *compute.Operation
+	if rf, ok := ret.Get(0).(func(string, string, *compute.InstancesSetLabelsRequest) *compute.Operation); ok {
+		r0 = rf(zone, instance, req)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*compute.Operation)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, string, *compute.InstancesSetLabelsRequest) error); ok {
+		r1 = rf(zone, instance, req)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -136,6 +136,7 @@ func (prvdr *Provider) List() ([]db.Machine, error) {
 			FloatingIP: floatingIP,
 			PrivateIP:  privateIP,
 			Size:       mtype,
+			Labels:     instance.Labels,
 		})
 	}
 	return machines, nil
@@ -159,7 +160,8 @@ func (prvdr *Provider) Boot(bootSet []db.Machine) ([]string, error) {
 		names = append(names, name)
 
 		go func(m db.Machine) {
-			icfg := prvdr.instanceConfig(name, m.Size, cfg.Ubuntu(m, ""))
+			icfg := prvdr.instanceConfig(name, m.Size, m.Labels,
+				cfg.Ubuntu(m, ""))
 			_, err := prvdr.InsertInstance(prvdr.zone, icfg)
 			errChan <- err
 		}(m)
@@ -219,11 +221,14 @@ func (prvdr *Provider) operationWait(ops ...*compute.Operation) (err error) {
 	}, 10*time.Second, 3*time.Minute)
 }
 
-func (prvdr Provider) instanceConfig(name, size, cloudConfig string) *compute.Instance {
+func (prvdr Provider) instanceConfig(name, size string, labels map[string]string,
+	cloudConfig string) *compute.Instance {
+
 	return &compute.Instance{
 		Name:        name,
 		Description: prvdr.network,
 		MachineType: fmt.Sprintf("zones/%s/machineTypes/%s", prvdr.zone, size),
+		Labels:      labels,
 		Disks: []*compute.AttachedDisk{{
 			Boot:       true,
 			AutoDelete: true,
@@ -458,6 +463,30 @@ func (prvdr *Provider) UpdateFloatingIPs(machines []db.Machine) error {
 	return nil
 }
 
+// UpdateLabels sets the labels of the instances of `machines`.
+func (prvdr *Provider) UpdateLabels(machines []db.Machine) error {
+	for _, m := range machines {
+		instance, err := prvdr.GetInstance(prvdr.zone, m.CloudID)
+		if err != nil {
+			return err
+		}
+
+		// The fingerprint guards against overwriting labels that were
+		// changed since the instance was fetched. Labels are always sent,
+		// even if empty, so that removing all of them takes effect.
+		_, err = prvdr.SetLabels(prvdr.zone, m.CloudID,
+			&compute.InstancesSetLabelsRequest{
+				LabelFingerprint: instance.LabelFingerprint,
+				Labels:           m.Labels,
+				ForceSendFields:  []string{"Labels"},
+			})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Cleanup removes unnecessary detritus from this provider.  It's intended to be called
 // when there are no VMs running or expected to be running soon.
 func (prvdr *Provider) Cleanup() error {
@@ -40,6 +40,7 @@ func TestList(t *testing.T) {
 			{
 				MachineType: "machine/split/type-1",
 				Name:        "name-1",
+				Labels:      map[string]string{"tier": "web"},
 				NetworkInterfaces: []*compute.NetworkInterface{
 					{
 						AccessConfigs: []*compute.AccessConfig{
@@ -64,6 +65,7 @@ func TestList(t *testing.T) {
 		PublicIP:  "x.x.x.x",
 		PrivateIP: "y.y.y.y",
 		Size:      "type-1",
+		Labels:    map[string]string{"tier": "web"},
 	})
 }
 
@@ -338,12 +340,14 @@ func TestBoot(t *testing.T) {
 		return fmt.Sprintf("%d", name)
 	}
 
-	machines := []db.Machine{{Size: "size1"}, {Size: "size2"}}
+	machines := []db.Machine{{Size: "size1"},
+		{Size: "size2", Labels: map[string]string{"tier": "db"}}}
 
-	cfg1 := gce.instanceConfig("1", "size1", cfg.Ubuntu(machines[0], ""))
+	cfg1 := gce.instanceConfig("1", "size1", nil, cfg.Ubuntu(machines[0], ""))
 	mc.On("InsertInstance", "zone-1", cfg1).Return(nil, nil)
 
-	cfg2 := gce.instanceConfig("2", "size2", cfg.Ubuntu(machines[1], ""))
+	cfg2 := gce.instanceConfig("2", "size2", map[string]string{"tier": "db"},
+		cfg.Ubuntu(machines[1], ""))
 	mc.On("InsertInstance", "zone-1", cfg2).Return(nil, nil)
 
 	ids, err := gce.Boot(machines)
@@ -370,11 +374,13 @@ func TestStop(t *testing.T) {
 func TestInstanceConfig(t *testing.T) {
 	_, gce := getProvider()
 	cloudConfig := "cloudConfig"
-	res := gce.instanceConfig("name", "size", cloudConfig)
+	res := gce.instanceConfig("name", "size", map[string]string{"tier": "db"},
+		cloudConfig)
 	exp := &compute.Instance{
 		Name:        "name",
 		Description: gce.network,
 		MachineType: "zones/zone-1/machineTypes/size",
+		Labels:      map[string]string{"tier": "db"},
 		Disks: []*compute.AttachedDisk{{
 			Boot:       true,
 			AutoDelete: true,
@@ -513,3 +519,25 @@ func TestUpdateFloatingIP(t *testing.T) {
 	assert.NoError(t, err)
 	mc.AssertExpectations(t)
 }
+
+func TestUpdateLabels(t *testing.T) {
+	mc, gce := getProvider()
+
+	mc.On("GetInstance", gce.zone, "name-1").Return(&compute.Instance{
+		Labels:           map[string]string{"tier": "web"},
+		LabelFingerprint: "fingerprint",
+	}, nil)
+	mc.On("SetLabels", gce.zone, "name-1", &compute.InstancesSetLabelsRequest{
+		LabelFingerprint: "fingerprint",
+		ForceSendFields:  []string{"Labels"},
+	}).Return(&compute.Operation{}, nil)
+
+	// Removing every label should still send an empty set of labels.
+	err := gce.UpdateLabels([]db.Machine{{CloudID: "name-1"}})
+	assert.NoError(t, err)
+	mc.AssertExpectations(t)
+
+	mc.On("GetInstance", gce.zone, "name-2").Return(nil, errors.New("err"))
+	err = gce.UpdateLabels([]db.Machine{{CloudID: "name-2"}})
+	assert.EqualError(t, err, "err")
+}
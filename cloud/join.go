@@ -20,6 +20,9 @@ type joinResult struct {
 	terminate []db.Machine
 	updateIPs []db.Machine
 
+	// Machines whose labels differ from the tags on their instances.
+	updateLabels []db.Machine
+
 	// True if there's things going on in this join that warrant frequent polls.
 	isActive bool
 }
@@ -34,8 +37,8 @@ func joinImpl(cld *cloud) (joinResult, error) {
 	}
 
 	var res joinResult
-	err = cld.conn.Txn(db.BlueprintTable,
-		db.MachineTable).Run(func(view db.Database) error {
+	err = cld.conn.Txn(db.BlueprintTable, db.MachineTable,
+		db.MachinePoolTable).Run(func(view db.Database) error {
 		bp, err := view.GetBlueprint()
 		if err != nil {
 			log.WithError(err).Error("Failed to get blueprint")
@@ -50,6 +53,10 @@ func joinImpl(cld *cloud) (joinResult, error) {
 
 		cld.syncDBWithCloud(view, machines)
 		res = cld.syncDBWithBlueprint(view)
+		if _, ok := cld.provider.(labeler); ok {
+			res.updateLabels = staleLabels(cld.selectMachines(view),
+				machines)
+		}
 
 		// Regions with no machines in them should have their ACLs cleared.
 		if len(machines) > 0 {
@@ -83,11 +90,16 @@ func (cld *cloud) syncDBWithCloud(view db.Database, cloudMachines []db.Machine)
 		cm := pair.R.(db.Machine)
 
 		// Providers don't know about some fields, so we don't overwrite them.
+		// The labels that providers report are the tags of the instance,
+		// which lag behind the blueprint until staleLabels updates them.
 		cm.ID = dbm.ID
 		cm.Status = dbm.Status
 		cm.SSHKeys = dbm.SSHKeys
+		cm.Labels = dbm.Labels
+		cm.Pool = dbm.Pool
 		cm.Role = dbm.Role
 		cm.Connected = dbm.Connected
+		cm.Draining = dbm.Draining
 		view.Commit(cm)
 	}
 }
@@ -104,6 +116,8 @@ func (cld *cloud) syncDBWithBlueprint(view db.Database) joinResult {
 	dbms := cld.selectMachines(view)
 
 	bpms := cld.desiredMachines(bp.Blueprint.Machines)
+	bpms = append(bpms, cld.desiredPoolMachines(view,
+		bp.Blueprint.MachinePools, unclaimedMachines(bpms, dbms))...)
 	if len(bpms) > 0 || len(dbms) > 0 {
 		res.isActive = true
 	}
@@ -118,8 +132,18 @@ func (cld *cloud) syncDBWithBlueprint(view db.Database) joinResult {
 		// database contains the most up to date SSH keys. If we didn't,
 		// changes to the SSH keys in the blueprint would never get synced.
 		// Similarly, the SSH keys would not be properly synced if the daemon
-		// restarted when machines were already running in the cloud.
+		// restarted when machines were already running in the cloud. The
+		// labels are synced in the same way, so that they can be changed
+		// without rebooting the machine.
 		dbm.SSHKeys = bpm.SSHKeys
+		dbm.Labels = bpm.Labels
+
+		// Machines that aren't in a pool are never drained. The drain of a
+		// machine that was moved out of its pool is abandoned.
+		dbm.Pool = bpm.Pool
+		if dbm.Pool == "" {
+			dbm.Draining = false
+		}
 		status := db.ConnectionStatus(dbm)
 		if status != "" {
 			dbm.Status = status
@@ -165,6 +189,41 @@ func (cld *cloud) syncDBWithBlueprint(view db.Database) joinResult {
 	return res
 }
 
+// staleLabels returns the database machines whose labels differ from the tags
+// on their instances in `cloudMachines`. Machines are only tagged once they
+// have an IP address, since Amazon can't tag spot requests until their
+// instances launch.
+func staleLabels(dbms, cloudMachines []db.Machine) []db.Machine {
+	cloudLabels := map[string]map[string]string{}
+	for _, cm := range cloudMachines {
+		if cm.PrivateIP != "" {
+			cloudLabels[cm.CloudID] = cm.Labels
+		}
+	}
+
+	var stale []db.Machine
+	for _, dbm := range dbms {
+		labels, ok := cloudLabels[dbm.CloudID]
+		if ok && dbm.Status != db.Stopping &&
+			!labelsEqual(labels, dbm.Labels) {
+			stale = append(stale, dbm)
+		}
+	}
+	return stale
+}
+
+// unclaimedMachines returns the database machines that aren't matched to any
+// of the blueprint machines that aren't in a pool.
+func unclaimedMachines(bpms, dbms []db.Machine) []db.Machine {
+	_, _, extraDBMs := join.Join(bpms, dbms, machineScore)
+
+	var unclaimed []db.Machine
+	for _, dbm := range extraDBMs {
+		unclaimed = append(unclaimed, dbm.(db.Machine))
+	}
+	return unclaimed
+}
+
 func machineScore(left, right interface{}) int {
 	l := left.(db.Machine)
 	r := right.(db.Machine)
@@ -205,9 +264,31 @@ func machineScore(left, right interface{}) int {
 	if l.Role != db.None && r.Role != db.None {
 		score--
 	}
+
+	// Prefer leaving labels on the machines they're already on, so that the
+	// pods placed according to them don't have to move.
+	if len(l.Labels) != 0 && labelsEqual(l.Labels, r.Labels) {
+		score--
+	}
+
+	// Similarly, prefer leaving machines in the pools they're in.
+	if l.Pool != "" && l.Pool == r.Pool {
+		score--
+	}
+
+	// Draining machines are matched last, so that they're the ones stopped
+	// when their pool shrinks. The penalty outweighs all of the preferences
+	// above.
+	if l.Draining || r.Draining {
+		score += 5
+	}
 	return score
 }
 
+func labelsEqual(l, r map[string]string) bool {
+	return len(l) == len(r) && db.HasLabels(l, r)
+}
+
 func (cld *cloud) desiredACLs(bp db.Blueprint) map[acl.ACL]struct{} {
 	aclSet := map[acl.ACL]struct{}{}
 
@@ -95,8 +95,8 @@ func TestSyncDBWithBlueprint(t *testing.T) {
 	cld := newTestCloud(FakeAmazon, testRegion, "ns")
 	adminKey = ""
 
-	cld.conn.Txn(db.BlueprintTable,
-		db.MachineTable).Run(func(view db.Database) error {
+	cld.conn.Txn(db.BlueprintTable, db.MachineTable,
+		db.MachinePoolTable).Run(func(view db.Database) error {
 
 		bp := view.InsertBlueprint()
 		bp.Blueprint.Machines = []blueprint.Machine{{
@@ -155,12 +155,81 @@ func TestSyncDBWithBlueprint(t *testing.T) {
 	})
 }
 
+func TestSyncDBWithBlueprintPools(t *testing.T) {
+	cld := newTestCloud(FakeAmazon, testRegion, "ns")
+	adminKey = ""
+
+	cld.conn.Txn(db.BlueprintTable, db.MachineTable,
+		db.MachinePoolTable).Run(func(view db.Database) error {
+
+		bp := view.InsertBlueprint()
+		bp.Blueprint.MachinePools = []blueprint.MachinePool{{
+			Name: "web",
+			Machine: blueprint.Machine{
+				Provider: string(FakeAmazon),
+				Region:   testRegion,
+				Size:     "1",
+			},
+			Min: 1,
+			Max: 3,
+		}}
+		view.Commit(bp)
+
+		pool := view.InsertMachinePool()
+		pool.Name = "web"
+		pool.Count = 2
+		view.Commit(pool)
+
+		for _, id := range []string{"a", "b", "c"} {
+			m := view.InsertMachine()
+			m.Provider = FakeAmazon
+			m.Region = testRegion
+			m.Size = "1"
+			m.DiskSize = 32
+			m.CloudID = id
+			m.Role = db.Worker
+			m.Pool = "web"
+			m.Draining = id == "b"
+			view.Commit(m)
+		}
+
+		// The pool shrank, so the draining machine should be stopped.
+		res := cld.syncDBWithBlueprint(view)
+		assert.Empty(t, res.boot)
+		assert.Len(t, res.terminate, 1)
+		assert.Equal(t, "b", res.terminate[0].CloudID)
+
+		// Machines that are paired with machines outside of a pool stop
+		// draining.
+		bp.Blueprint.Machines = []blueprint.Machine{{
+			Provider: string(FakeAmazon),
+			Region:   testRegion,
+			Size:     "1",
+		}}
+		view.Commit(bp)
+
+		res = cld.syncDBWithBlueprint(view)
+		assert.Empty(t, res.boot)
+		assert.Empty(t, res.terminate)
+		for _, dbm := range view.SelectFromMachine(nil) {
+			assert.False(t, dbm.Draining)
+			if dbm.CloudID == "b" {
+				assert.Empty(t, dbm.Pool)
+			} else {
+				assert.Equal(t, "web", dbm.Pool)
+			}
+		}
+
+		return nil
+	})
+}
+
 func TestSyncDBWithBlueprintFloatingIP(t *testing.T) {
 	cld := newTestCloud(FakeAmazon, testRegion, "ns")
 
 	desiredFloatingIP := "floatingIP"
-	cld.conn.Txn(db.BlueprintTable,
-		db.MachineTable).Run(func(view db.Database) error {
+	cld.conn.Txn(db.BlueprintTable, db.MachineTable,
+		db.MachinePoolTable).Run(func(view db.Database) error {
 
 		bp := view.InsertBlueprint()
 		bp.Blueprint.Machines = []blueprint.Machine{{
@@ -210,6 +279,29 @@ func TestSyncDBWithBlueprintFloatingIP(t *testing.T) {
 	})
 }
 
+func TestStaleLabels(t *testing.T) {
+	web := map[string]string{"tier": "web"}
+	db1 := map[string]string{"tier": "db"}
+	cloudMachines := []db.Machine{
+		{CloudID: "1", PrivateIP: "10.0.0.1", Labels: web},
+		{CloudID: "2", PrivateIP: "10.0.0.2", Labels: web},
+		{CloudID: "3", PrivateIP: "10.0.0.3"},
+		// Spot requests that haven't launched can't be tagged yet.
+		{CloudID: "4"},
+		{CloudID: "5", PrivateIP: "10.0.0.5", Labels: web},
+	}
+	dbms := []db.Machine{
+		{CloudID: "1", Labels: web},
+		{CloudID: "2", Labels: db1},
+		{CloudID: "3", Labels: db1},
+		{CloudID: "4", Labels: db1},
+		{CloudID: "5", Labels: db1, Status: db.Stopping},
+		{CloudID: "6", Labels: db1},
+	}
+	assert.Equal(t, []db.Machine{dbms[1], dbms[2]},
+		staleLabels(dbms, cloudMachines))
+}
+
 func TestMachineScore(t *testing.T) {
 	m := db.Machine{
 		Provider: db.Amazon,
@@ -267,6 +359,30 @@ func TestMachineScore(t *testing.T) {
 	m1.Preemptible = true
 	assert.Equal(t, -1, machineScore(m, m1))
 
+	// Labels
+	m1 = m
+	m2 = m
+	m1.CloudID = "5"
+	m1.Labels = map[string]string{"tier": "db"}
+	assert.Equal(t, 9, machineScore(m1, m2))
+	m2.Labels = map[string]string{"tier": "db"}
+	assert.Equal(t, 8, machineScore(m1, m2))
+	m2.Labels = map[string]string{"tier": "db", "env": "prod"}
+	assert.Equal(t, 9, machineScore(m1, m2))
+
+	// Pools
+	m1 = m
+	m2 = m
+	m1.CloudID = "5"
+	m1.Pool = "web"
+	assert.Equal(t, 9, machineScore(m1, m2))
+	m2.Pool = "web"
+	assert.Equal(t, 8, machineScore(m1, m2))
+
+	// Draining
+	m2.Draining = true
+	assert.Equal(t, 13, machineScore(m1, m2))
+
 	// Prefer matching floating IPs over roles. The desired machine is a worker
 	// with a floating IP -- the match with a worker with the wrong IP should
 	// be worse than a match with a machine with an unknown role, but the same
@@ -342,8 +458,8 @@ func TestJoinSSHKeys(t *testing.T) {
 
 	adminKey = ""
 	expSSHKeys := []string{"exp", "ssh", "keys"}
-	cld.conn.Txn(db.BlueprintTable,
-		db.MachineTable).Run(func(view db.Database) error {
+	cld.conn.Txn(db.BlueprintTable, db.MachineTable,
+		db.MachinePoolTable).Run(func(view db.Database) error {
 
 		bp := view.InsertBlueprint()
 		bp.Blueprint.Machines = []blueprint.Machine{{
@@ -1,6 +1,7 @@
 package counter
 
 import (
+	"math"
 	"sync"
 	"sync/atomic"
 
@@ -18,6 +19,26 @@ type Package struct {
 // version.
 var all = syncmap.Map{}
 
+// The gauges and histograms are kept separate from the counters because they
+// can't be represented by pb.Counter, and so aren't included in Dump().
+var gauges = syncmap.Map{}
+var histograms = syncmap.Map{}
+
+type key struct{ p, n string }
+
+// The upper bounds of the buckets that histogram observations are sorted into.
+// They're chosen to cover the durations of the reconciliation loops, which
+// range from milliseconds to minutes.
+var histogramBuckets = []float64{
+	.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60, 120}
+
+type histogram struct {
+	sync.Mutex
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
 // New creates a new Package with the given Name.
 func New(name string) Package {
 	return Package{name}
@@ -25,11 +46,35 @@ func New(name string) Package {
 
 // Inc increments the counter `name` under the provided package.
 func (p Package) Inc(name string) {
-	key := struct{ p, n string }{p.name, name}
-	c, _ := all.LoadOrStore(key, &pb.Counter{Pkg: p.name, Name: name})
+	c, _ := all.LoadOrStore(key{p.name, name},
+		&pb.Counter{Pkg: p.name, Name: name})
 	atomic.AddUint64(&c.(*pb.Counter).Value, 1)
 }
 
+// Set sets the gauge `name` under the provided package to `value`.
+func (p Package) Set(name string, value float64) {
+	g, _ := gauges.LoadOrStore(key{p.name, name}, new(uint64))
+	atomic.StoreUint64(g.(*uint64), math.Float64bits(value))
+}
+
+// Observe records `value` in the histogram `name` under the provided package.
+func (p Package) Observe(name string, value float64) {
+	h, _ := histograms.LoadOrStore(key{p.name, name}, &histogram{
+		counts: make([]uint64, len(histogramBuckets))})
+
+	hist := h.(*histogram)
+	hist.Lock()
+	defer hist.Unlock()
+
+	for i, bound := range histogramBuckets {
+		if value <= bound {
+			hist.counts[i]++
+		}
+	}
+	hist.sum += value
+	hist.count++
+}
+
 var dumpMutex = sync.Mutex{}
 
 // Dump returns a list of all in no particular order.
@@ -1,6 +1,7 @@
 package counter
 
 import (
+	"math"
 	"sync"
 	"testing"
 
@@ -63,3 +64,35 @@ func TestCounter(t *testing.T) {
 	assert.Contains(t, res, &pb.Counter{
 		Pkg: "b", Name: "1", Value: 1001000, PrevValue: 1001000})
 }
+
+func TestGaugeAndHistogram(t *testing.T) {
+	p := New("gauge test")
+	p.Set("a", 1.5)
+	p.Set("a", 2.5)
+
+	value, ok := gauges.Load(key{"gauge test", "a"})
+	assert.True(t, ok)
+	assert.Equal(t, 2.5, math.Float64frombits(*value.(*uint64)))
+
+	p = New("histogram test")
+	p.Observe("a", 0.3)
+	p.Observe("a", 7)
+	p.Observe("a", 500)
+
+	h, ok := histograms.Load(key{"histogram test", "a"})
+	assert.True(t, ok)
+	hist := h.(*histogram)
+	assert.Equal(t, uint64(3), hist.count)
+	assert.Equal(t, 507.3, hist.sum)
+
+	// The bucket counts are cumulative.
+	for i, bound := range histogramBuckets {
+		var exp uint64
+		if bound >= 10 {
+			exp = 2
+		} else if bound >= 0.5 {
+			exp = 1
+		}
+		assert.Equal(t, exp, hist.counts[i], "bucket %v", bound)
+	}
+}
@@ -0,0 +1,174 @@
+package counter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/kelda/kelda/api/pb"
+	"golang.org/x/sync/syncmap"
+)
+
+// MetricsPort is the port that minions serve the OpenMetrics endpoint on.
+const MetricsPort = 9020
+
+const openMetricsContentType = "application/openmetrics-text; version=1.0.0; " +
+	"charset=utf-8"
+
+// ServeMetrics blocks serving the counters, gauges, and histograms in the
+// OpenMetrics text format at `addr`/metrics.
+func ServeMetrics(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler{})
+	return http.ListenAndServe(addr, mux)
+}
+
+// Handler is an http.Handler that responds with the counters, gauges, and
+// histograms in the OpenMetrics text format.
+type Handler struct{}
+
+// ServeHTTP responds to GET requests with the current metrics.
+func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", openMetricsContentType)
+	WriteOpenMetrics(w)
+}
+
+// WriteOpenMetrics writes the counters, gauges, and histograms to `w` in the
+// OpenMetrics text format. Each package is exposed as a metric family per
+// type, and the names within the package are distinguished by the "name"
+// label. For example, the counter "Get Metrics" in the "Foreman" package is
+// exposed as `kelda_foreman_total{name="Get Metrics"}`. If a family name is
+// already taken by another type, the type is appended to it, so that a counter
+// and a histogram in packages of the same name don't share a family.
+func WriteOpenMetrics(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	families := map[string]string{}
+
+	var counters []*pb.Counter
+	all.Range(func(_, value interface{}) bool {
+		counters = append(counters, value.(*pb.Counter))
+		return true
+	})
+	sort.Slice(counters, func(i, j int) bool {
+		return counters[i].Pkg < counters[j].Pkg ||
+			(counters[i].Pkg == counters[j].Pkg &&
+				counters[i].Name < counters[j].Name)
+	})
+
+	lastFamily := ""
+	for _, c := range counters {
+		family := claimFamily(families, familyName(c.Pkg, ""), "counter")
+		if family != lastFamily {
+			fmt.Fprintf(bw, "# TYPE %s counter\n", family)
+			lastFamily = family
+		}
+		fmt.Fprintf(bw, "%s_total{name=%s} %d\n", family, labelValue(c.Name),
+			atomic.LoadUint64(&c.Value))
+	}
+
+	lastFamily = ""
+	for _, k := range sortedKeys(&gauges) {
+		g, _ := gauges.Load(k)
+		family := claimFamily(families, familyName(k.p, "gauge"), "gauge")
+		if family != lastFamily {
+			fmt.Fprintf(bw, "# TYPE %s gauge\n", family)
+			lastFamily = family
+		}
+		value := math.Float64frombits(atomic.LoadUint64(g.(*uint64)))
+		fmt.Fprintf(bw, "%s{name=%s} %s\n", family, labelValue(k.n),
+			formatFloat(value))
+	}
+
+	lastFamily = ""
+	for _, k := range sortedKeys(&histograms) {
+		h, _ := histograms.Load(k)
+		family := claimFamily(families, familyName(k.p, ""), "histogram")
+		if family != lastFamily {
+			fmt.Fprintf(bw, "# TYPE %s histogram\n", family)
+			lastFamily = family
+		}
+		writeHistogram(bw, family, labelValue(k.n), h.(*histogram))
+	}
+
+	fmt.Fprintln(bw, "# EOF")
+	return bw.Flush()
+}
+
+func writeHistogram(w io.Writer, family, name string, h *histogram) {
+	h.Lock()
+	defer h.Unlock()
+
+	for i, bound := range histogramBuckets {
+		fmt.Fprintf(w, "%s_bucket{name=%s,le=\"%s\"} %d\n", family, name,
+			formatFloat(bound), h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{name=%s,le=\"+Inf\"} %d\n", family, name,
+		h.count)
+	fmt.Fprintf(w, "%s_sum{name=%s} %s\n", family, name, formatFloat(h.sum))
+	fmt.Fprintf(w, "%s_count{name=%s} %d\n", family, name, h.count)
+}
+
+func sortedKeys(m *syncmap.Map) []key {
+	var keys []key
+	m.Range(func(k, _ interface{}) bool {
+		keys = append(keys, k.(key))
+		return true
+	})
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].p < keys[j].p ||
+			(keys[i].p == keys[j].p && keys[i].n < keys[j].n)
+	})
+	return keys
+}
+
+// familyName converts a package name into a valid OpenMetrics metric family
+// name, e.g. "Log Ship" becomes "kelda_log_ship".
+func familyName(pkg, suffix string) string {
+	name := "kelda_" + strings.ToLower(pkg)
+	if suffix != "" {
+		name += "_" + suffix
+	}
+
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// claimFamily returns the name that the metric family `name` of type `typ` is
+// exposed as, and records it in `families`, a map from family names to their
+// types. Each family name may only be used by one type.
+func claimFamily(families map[string]string, name, typ string) string {
+	for {
+		claimedType, ok := families[name]
+		if !ok || claimedType == typ {
+			break
+		}
+		name += "_" + typ
+	}
+	families[name] = typ
+	return name
+}
+
+// labelValue quotes and escapes `s` for use as a label value.
+func labelValue(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) +
+		`"`
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,125 @@
+package counter
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sync/syncmap"
+)
+
+func TestWriteOpenMetrics(t *testing.T) {
+	all = syncmap.Map{}
+	gauges = syncmap.Map{}
+	histograms = syncmap.Map{}
+
+	foreman := New("Foreman")
+	foreman.Inc("Get Metrics")
+	foreman.Inc("Get Metrics")
+	foreman.Inc(`Quote "Error"`)
+	New("Log Ship").Inc("Ship entry")
+
+	New("Event Last End").Set("Minion-Update", 1.5e9)
+
+	timer := New("Event Duration Seconds")
+	timer.Observe("Supervisor", 0.2)
+	timer.Observe("Supervisor", 3)
+
+	// The histogram's family name is taken by a counter.
+	cluster := New("Cluster")
+	cluster.Inc("Boot")
+	cluster.Observe("Boot", 30)
+
+	var out bytes.Buffer
+	assert.NoError(t, WriteOpenMetrics(&out))
+
+	exp := `# TYPE kelda_cluster counter
+kelda_cluster_total{name="Boot"} 1
+# TYPE kelda_foreman counter
+kelda_foreman_total{name="Get Metrics"} 2
+kelda_foreman_total{name="Quote \"Error\""} 1
+# TYPE kelda_log_ship counter
+kelda_log_ship_total{name="Ship entry"} 1
+# TYPE kelda_event_last_end_gauge gauge
+kelda_event_last_end_gauge{name="Minion-Update"} 1.5e+09
+# TYPE kelda_cluster_histogram histogram
+kelda_cluster_histogram_bucket{name="Boot",le="0.005"} 0
+kelda_cluster_histogram_bucket{name="Boot",le="0.01"} 0
+kelda_cluster_histogram_bucket{name="Boot",le="0.025"} 0
+kelda_cluster_histogram_bucket{name="Boot",le="0.05"} 0
+kelda_cluster_histogram_bucket{name="Boot",le="0.1"} 0
+kelda_cluster_histogram_bucket{name="Boot",le="0.25"} 0
+kelda_cluster_histogram_bucket{name="Boot",le="0.5"} 0
+kelda_cluster_histogram_bucket{name="Boot",le="1"} 0
+kelda_cluster_histogram_bucket{name="Boot",le="2.5"} 0
+kelda_cluster_histogram_bucket{name="Boot",le="5"} 0
+kelda_cluster_histogram_bucket{name="Boot",le="10"} 0
+kelda_cluster_histogram_bucket{name="Boot",le="30"} 1
+kelda_cluster_histogram_bucket{name="Boot",le="60"} 1
+kelda_cluster_histogram_bucket{name="Boot",le="120"} 1
+kelda_cluster_histogram_bucket{name="Boot",le="+Inf"} 1
+kelda_cluster_histogram_sum{name="Boot"} 30
+kelda_cluster_histogram_count{name="Boot"} 1
+# TYPE kelda_event_duration_seconds histogram
+kelda_event_duration_seconds_bucket{name="Supervisor",le="0.005"} 0
+kelda_event_duration_seconds_bucket{name="Supervisor",le="0.01"} 0
+kelda_event_duration_seconds_bucket{name="Supervisor",le="0.025"} 0
+kelda_event_duration_seconds_bucket{name="Supervisor",le="0.05"} 0
+kelda_event_duration_seconds_bucket{name="Supervisor",le="0.1"} 0
+kelda_event_duration_seconds_bucket{name="Supervisor",le="0.25"} 1
+kelda_event_duration_seconds_bucket{name="Supervisor",le="0.5"} 1
+kelda_event_duration_seconds_bucket{name="Supervisor",le="1"} 1
+kelda_event_duration_seconds_bucket{name="Supervisor",le="2.5"} 1
+kelda_event_duration_seconds_bucket{name="Supervisor",le="5"} 2
+kelda_event_duration_seconds_bucket{name="Supervisor",le="10"} 2
+kelda_event_duration_seconds_bucket{name="Supervisor",le="30"} 2
+kelda_event_duration_seconds_bucket{name="Supervisor",le="60"} 2
+kelda_event_duration_seconds_bucket{name="Supervisor",le="120"} 2
+kelda_event_duration_seconds_bucket{name="Supervisor",le="+Inf"} 2
+kelda_event_duration_seconds_sum{name="Supervisor"} 3.2
+kelda_event_duration_seconds_count{name="Supervisor"} 2
+# EOF
+`
+	assert.Equal(t, exp, out.String())
+
+	// Test the HTTP handler.
+	recorder := httptest.NewRecorder()
+	Handler{}.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, openMetricsContentType,
+		recorder.Header().Get("Content-Type"))
+	assert.Equal(t, exp, recorder.Body.String())
+
+	recorder = httptest.NewRecorder()
+	Handler{}.ServeHTTP(recorder, httptest.NewRequest("POST", "/metrics",
+		strings.NewReader("")))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
+
+func TestFamilyName(t *testing.T) {
+	assert.Equal(t, "kelda_log_ship", familyName("Log Ship", ""))
+	assert.Equal(t, "kelda_minion_update_gauge",
+		familyName("Minion-Update", "gauge"))
+}
+
+func TestClaimFamily(t *testing.T) {
+	families := map[string]string{}
+	assert.Equal(t, "kelda_foreman",
+		claimFamily(families, "kelda_foreman", "counter"))
+	assert.Equal(t, "kelda_foreman",
+		claimFamily(families, "kelda_foreman", "counter"))
+	assert.Equal(t, "kelda_foreman_histogram",
+		claimFamily(families, "kelda_foreman", "histogram"))
+	assert.Equal(t, "kelda_foreman_histogram",
+		claimFamily(families, "kelda_foreman", "histogram"))
+
+	// A counter already uses the suffixed name.
+	families = map[string]string{}
+	claimFamily(families, "kelda_foreman", "counter")
+	claimFamily(families, "kelda_foreman_histogram", "counter")
+	assert.Equal(t, "kelda_foreman_histogram_histogram",
+		claimFamily(families, "kelda_foreman", "histogram"))
+}
@@ -19,6 +19,7 @@ type Container struct {
 	BlueprintID       string                              `json:",omitempty"`
 	PodName           string                              `json:",omitempty"`
 	Status            string                              `json:",omitempty"`
+	ImageDigest       string                              `json:",omitempty"`
 	Command           []string                            `json:",omitempty"`
 	Env               map[string]blueprint.ContainerValue `json:",omitempty"`
 	FilepathToContent map[string]blueprint.ContainerValue `json:",omitempty"`
@@ -26,22 +27,81 @@ type Container struct {
 	Created           time.Time                           `json:","`
 	Privileged        bool                                `json:",omitempty"`
 	VolumeMounts      []blueprint.VolumeMount             `json:",omitempty"`
+	Security          *blueprint.Security                 `json:",omitempty"`
+
+	// The other containers in the container's pod. See
+	// blueprint.Container.Sidecars.
+	Sidecars []blueprint.Container `json:",omitempty"`
+
+	// The containers run before the container starts, and the ports it waits
+	// for. See blueprint.Container.
+	InitContainers []blueprint.Container  `json:",omitempty"`
+	Dependencies   []blueprint.Dependency `json:",omitempty"`
+
+	// The hostname of the daemon container that this container is an instance
+	// of, if any. Instances are run on the worker given by Minion.
+	DaemonSet string `json:",omitempty"`
+
+	// The hostname of the stateful container that this container is a member
+	// of, if any. Members are pinned to the worker given by Minion.
+	StatefulSet string `json:",omitempty"`
+
+	// The hostname of the autoscaled container that this container is a
+	// replica of, if any.
+	ReplicaSet string `json:",omitempty"`
 
 	Image      string `json:",omitempty"`
 	Dockerfile string `json:"-"`
 }
 
+// Unschedulable is the prefix of the status of containers that couldn't be
+// placed on a machine, either because no machine satisfies their placement
+// rules, or because no machine has enough free resources.
+const Unschedulable = "unschedulable"
+
 // GetReferencedSecrets returns the names of all Secrets referenced in the Env
-// and FilepathToContent maps.
+// and FilepathToContent maps of the containers in the container's pod,
+// including the secrets referenced by templates.
 func (c Container) GetReferencedSecrets() []string {
-	return append(getReferencedSecrets(c.Env),
-		getReferencedSecrets(c.FilepathToContent)...)
+	var secrets []string
+	for _, podContainer := range c.PodContainers() {
+		secrets = append(secrets, getReferencedSecrets(podContainer.Env)...)
+		secrets = append(secrets,
+			getReferencedSecrets(podContainer.FilepathToContent)...)
+	}
+	return secrets
+}
+
+// PodContainers returns the specifications of the containers in the
+// container's pod: the container itself, followed by its sidecars and init
+// containers.
+func (c Container) PodContainers() []blueprint.Container {
+	return blueprint.Container{
+		Hostname: c.Hostname,
+		Image: blueprint.Image{
+			Name:       c.Image,
+			Dockerfile: c.Dockerfile,
+		},
+		Command:           c.Command,
+		Env:               c.Env,
+		FilepathToContent: c.FilepathToContent,
+		Privileged:        c.Privileged,
+		VolumeMounts:      c.VolumeMounts,
+		Security:          c.Security,
+		Sidecars:          c.Sidecars,
+		InitContainers:    c.InitContainers,
+	}.PodContainers()
 }
 
 func getReferencedSecrets(x map[string]blueprint.ContainerValue) (secrets []string) {
-	for _, maybeSecret := range x {
-		if secret, ok := maybeSecret.Value.(blueprint.Secret); ok {
-			secrets = append(secrets, secret.NameOfSecret)
+	for _, val := range x {
+		switch val := val.Value.(type) {
+		case blueprint.Secret:
+			secrets = append(secrets, val.NameOfSecret)
+		case blueprint.Template:
+			// Invalid templates are rejected when the blueprint is deployed.
+			templateSecrets, _ := val.ReferencedSecrets()
+			secrets = append(secrets, templateSecrets...)
 		}
 	}
 	return secrets
@@ -107,6 +167,18 @@ func (c Container) String() string {
 		tags = append(tags, fmt.Sprintf("Hostname: %s", c.Hostname))
 	}
 
+	if c.DaemonSet != "" {
+		tags = append(tags, fmt.Sprintf("DaemonSet: %s", c.DaemonSet))
+	}
+
+	if c.StatefulSet != "" {
+		tags = append(tags, fmt.Sprintf("StatefulSet: %s", c.StatefulSet))
+	}
+
+	if c.ReplicaSet != "" {
+		tags = append(tags, fmt.Sprintf("ReplicaSet: %s", c.ReplicaSet))
+	}
+
 	if len(c.Env) > 0 {
 		tags = append(tags, fmt.Sprintf("Env: %s", c.Env))
 	}
@@ -115,10 +187,34 @@ func (c Container) String() string {
 		tags = append(tags, "Privileged")
 	}
 
+	if len(c.Sidecars) > 0 {
+		var sidecars []string
+		for _, sidecar := range c.Sidecars {
+			sidecars = append(sidecars, sidecar.Hostname)
+		}
+		tags = append(tags, fmt.Sprintf("Sidecars: %s", sidecars))
+	}
+
+	if len(c.InitContainers) > 0 {
+		var initContainers []string
+		for _, initContainer := range c.InitContainers {
+			initContainers = append(initContainers, initContainer.Hostname)
+		}
+		tags = append(tags, fmt.Sprintf("InitContainers: %s", initContainers))
+	}
+
+	if len(c.Dependencies) > 0 {
+		tags = append(tags, fmt.Sprintf("Dependencies: %s", c.Dependencies))
+	}
+
 	if len(c.Status) > 0 {
 		tags = append(tags, fmt.Sprintf("Status: %s", c.Status))
 	}
 
+	if c.ImageDigest != "" {
+		tags = append(tags, fmt.Sprintf("ImageDigest: %s", c.ImageDigest))
+	}
+
 	if !c.Created.IsZero() {
 		tags = append(tags, fmt.Sprintf("Created: %s", c.Created.String()))
 	}
@@ -29,14 +29,28 @@ func TestContainerString(t *testing.T) {
 		Image:       "test/test",
 		Status:      "testing",
 		Hostname:    "hostname",
+		DaemonSet:   "agent",
+		StatefulSet: "zk",
+		ReplicaSet:  "web",
 		Command:     []string{"run", "/bin/sh"},
 		Env:         fakeMap,
 		Created:     fakeTime,
+		Sidecars:    []blueprint.Container{{Hostname: "envoy"}},
+		InitContainers: []blueprint.Container{
+			{Hostname: "migrate"},
+		},
+		Dependencies: []blueprint.Dependency{
+			{Hostname: "db", Port: 5432},
+		},
 	}
 
 	exp = "Container-1{run test/test run /bin/sh, " +
 		"PodName: PodName, Minion: Test, BlueprintID: 1, IP: 1.2.3.4, " +
-		"Hostname: hostname, Env: map[test:tester], " +
+		"Hostname: hostname, DaemonSet: agent, StatefulSet: zk, " +
+		"ReplicaSet: web, " +
+		"Env: map[test:tester], " +
+		"Sidecars: [envoy], " +
+		"InitContainers: [migrate], Dependencies: [db:5432], " +
 		"Status: testing, Created: " + fakeTimeString + "}"
 
 	assert.Equal(t, exp, c.String())
@@ -65,6 +79,9 @@ func TestGetReferencedSecrets(t *testing.T) {
 	secret2 := "secret2"
 	secret3 := "secret3"
 	secret4 := "secret4"
+	secret5 := "secret5"
+	secret6 := "secret6"
+	secret7 := "secret7"
 	dbc := Container{
 		Env: map[string]blueprint.ContainerValue{
 			"key1": blueprint.NewString("ignoreme"),
@@ -75,12 +92,27 @@ func TestGetReferencedSecrets(t *testing.T) {
 			"key1": blueprint.NewString("ignoreme"),
 			"key3": blueprint.NewSecret(secret3),
 			"key4": blueprint.NewSecret(secret4),
+			"key5": blueprint.NewTemplate(
+				`password={{secret "` + secret7 + `"}}`),
 		},
+		Sidecars: []blueprint.Container{{
+			Env: map[string]blueprint.ContainerValue{
+				"key5": blueprint.NewSecret(secret5),
+			},
+		}},
+		InitContainers: []blueprint.Container{{
+			FilepathToContent: map[string]blueprint.ContainerValue{
+				"key6": blueprint.NewSecret(secret6),
+			},
+		}},
 	}
 	referencedSecrets := dbc.GetReferencedSecrets()
-	assert.Len(t, referencedSecrets, 4)
+	assert.Len(t, referencedSecrets, 7)
 	assert.Contains(t, referencedSecrets, secret1)
 	assert.Contains(t, referencedSecrets, secret2)
 	assert.Contains(t, referencedSecrets, secret3)
 	assert.Contains(t, referencedSecrets, secret4)
+	assert.Contains(t, referencedSecrets, secret5)
+	assert.Contains(t, referencedSecrets, secret6)
+	assert.Contains(t, referencedSecrets, secret7)
 }
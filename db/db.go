@@ -276,7 +276,7 @@ func defaultString(r row) string {
 			formatString = fmt.Sprintf("%s=%%s", trow.Field(i).Name)
 		}
 		fieldString := fmt.Sprint(vrow.Field(i).Interface())
-		if fieldString == "" || fieldString == "0" {
+		if fieldString == "" || fieldString == "0" || fieldString == "map[]" {
 			continue
 		}
 		tags = append(tags, fmt.Sprintf(formatString, fieldString))
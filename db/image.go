@@ -1,7 +1,13 @@
 package db
 
+import (
+	"bytes"
+	"reflect"
+)
+
 // An Image row represents a Docker image that should be built by the Kelda
-// masters.
+// masters, or, if the Dockerfile is empty, a public image whose tag should be
+// resolved to a digest.
 type Image struct {
 	ID int
 
@@ -11,12 +17,27 @@ type Image struct {
 	// The Dockerfile with which to build the image.
 	Dockerfile string
 
-	// The repo digest of the built image. This is the URL from which the image
-	// can be pulled by other nodes in the cluster.
+	// A gzipped tar archive of the files available to the Dockerfile.
+	Context []byte `json:"-" rowStringer:"omit"`
+
+	// The values of the ARG instructions in the Dockerfile.
+	BuildArgs map[string]string `rowStringer:"omit"`
+
+	// The stage of a multi-stage Dockerfile to build.
+	Target string
+
+	// The repo digest of the built or resolved image. This is the URL from
+	// which the image can be pulled by other nodes in the cluster.
 	RepoDigest string
 
 	// The build status of the image.
 	Status string
+
+	// The output of the most recent build, truncated to its last lines.
+	BuildLog string `rowStringer:"omit"`
+
+	// Why the most recent build or resolution failed, if it did.
+	BuildError string
 }
 
 const (
@@ -25,8 +46,38 @@ const (
 
 	// Built is the status string for when the image has been built.
 	Built = "built"
+
+	// BuildFailed is the status string for when the most recent attempt to
+	// build the image failed. The build is retried.
+	BuildFailed = "build failed"
+
+	// Resolved is the status string for when the image's tag has been
+	// resolved to a digest.
+	Resolved = "resolved"
+
+	// ResolveFailed is the status string for when the image's tag couldn't be
+	// resolved to a digest. The resolution is retried.
+	ResolveFailed = "failed to resolve digest"
 )
 
+// SameBuild returns whether `image` and `other` are built from the same
+// inputs.
+func (image Image) SameBuild(other Image) bool {
+	return image.Name == other.Name &&
+		image.Dockerfile == other.Dockerfile &&
+		image.Target == other.Target &&
+		bytes.Equal(image.Context, other.Context) &&
+		reflect.DeepEqual(nilIfEmpty(image.BuildArgs),
+			nilIfEmpty(other.BuildArgs))
+}
+
+func nilIfEmpty(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
+
 // InsertImage creates a new image row and inserts it into the database.
 func (db Database) InsertImage() Image {
 	result := Image{ID: db.nextID()}
@@ -18,6 +18,11 @@ type Machine struct {
 	SSHKeys     []string `rowStringer:"omit"`
 	FloatingIP  string
 	Preemptible bool
+	Labels      map[string]string
+
+	// The name of the machine pool that the machine belongs to, or empty if
+	// the machine is listed individually in the blueprint.
+	Pool string
 
 	/* Populated by the cloud provider. */
 	CloudID   string //Cloud Provider ID
@@ -30,6 +35,12 @@ type Machine struct {
 	/* Populated by the foreman. */
 	Role      Role
 	Connected bool
+
+	/* Populated by the autoscaler. */
+
+	// Whether the machine's containers are being moved off it so that it can
+	// be removed from its pool.
+	Draining bool
 }
 
 const (
@@ -141,6 +152,19 @@ func (m Machine) String() string {
 		tags = append(tags, fmt.Sprintf("Disk=%dGB", m.DiskSize))
 	}
 
+	if len(m.Labels) != 0 {
+		tags = append(tags, "Labels="+strings.Join(SortedLabels(m.Labels),
+			","))
+	}
+
+	if m.Pool != "" {
+		tags = append(tags, "Pool="+m.Pool)
+	}
+
+	if m.Draining {
+		tags = append(tags, "draining")
+	}
+
 	if m.Status != "" {
 		tags = append(tags, m.Status)
 	}
@@ -178,6 +202,28 @@ func SortMachines(machines []Machine) []Machine {
 	return machines
 }
 
+// SortedLabels returns the given machine labels as "key=value" strings, sorted
+// so that equal labels always produce the same result.
+func SortedLabels(labels map[string]string) []string {
+	var strs []string
+	for key, val := range labels {
+		strs = append(strs, key+"="+val)
+	}
+	sort.Strings(strs)
+	return strs
+}
+
+// HasLabels returns whether the machine labels `labels` contain all of the
+// labels in `selector`.
+func HasLabels(labels, selector map[string]string) bool {
+	for key, val := range selector {
+		if actual, ok := labels[key]; !ok || actual != val {
+			return false
+		}
+	}
+	return true
+}
+
 // MachineSlice is an alias for []Machine to allow for joins
 type MachineSlice []Machine
 
@@ -0,0 +1,71 @@
+package db
+
+// A MachinePool row tracks the current size of a machine pool declared in the
+// blueprint. The autoscaler adjusts Count between the pool's bounds, and the
+// cloud boots or stops machines so that the pool has Count of them.
+type MachinePool struct {
+	ID int
+
+	Name  string
+	Count int
+}
+
+// InsertMachinePool creates a new machine pool row and inserts it into the
+// database.
+func (db Database) InsertMachinePool() MachinePool {
+	result := MachinePool{ID: db.nextID()}
+	db.insert(result)
+	return result
+}
+
+// SelectFromMachinePool gets all machine pools in the database that satisfy
+// 'check'.
+func (db Database) SelectFromMachinePool(check func(MachinePool) bool) []MachinePool {
+	var result []MachinePool
+	for _, row := range db.selectRows(MachinePoolTable) {
+		if check == nil || check(row.(MachinePool)) {
+			result = append(result, row.(MachinePool))
+		}
+	}
+	return result
+}
+
+// SelectFromMachinePool gets all machine pools in the database connection that
+// satisfy 'check'.
+func (conn Conn) SelectFromMachinePool(check func(MachinePool) bool) []MachinePool {
+	var result []MachinePool
+	conn.Txn(MachinePoolTable).Run(func(view Database) error {
+		result = view.SelectFromMachinePool(check)
+		return nil
+	})
+	return result
+}
+
+func (p MachinePool) getID() int {
+	return p.ID
+}
+
+func (p MachinePool) tt() TableType {
+	return MachinePoolTable
+}
+
+func (p MachinePool) String() string {
+	return defaultString(p)
+}
+
+func (p MachinePool) less(r row) bool {
+	return p.ID < r.(MachinePool).ID
+}
+
+// MachinePoolSlice is an alias for []MachinePool to allow for joins
+type MachinePoolSlice []MachinePool
+
+// Get returns the value contained at the given index
+func (slc MachinePoolSlice) Get(ii int) interface{} {
+	return slc[ii]
+}
+
+// Len returns the number of items in the slice.
+func (slc MachinePoolSlice) Len() int {
+	return len(slc)
+}
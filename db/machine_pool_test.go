@@ -0,0 +1,38 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMachinePool(t *testing.T) {
+	t.Parallel()
+
+	conn := New()
+
+	var id int
+	conn.Txn(MachinePoolTable).Run(func(view Database) error {
+		pool := view.InsertMachinePool()
+		id = pool.ID
+		pool.Name = "web"
+		pool.Count = 3
+		view.Commit(pool)
+		return nil
+	})
+
+	pools := MachinePoolSlice(conn.SelectFromMachinePool(
+		func(p MachinePool) bool { return true }))
+	assert.Equal(t, 1, pools.Len())
+
+	pool := pools[0]
+	assert.Equal(t, "web", pool.Name)
+	assert.Equal(t, id, pool.getID())
+	assert.Equal(t, MachinePoolTable, pool.tt())
+
+	assert.Equal(t, "MachinePool-1{Name=web, Count=3}", pool.String())
+
+	assert.Equal(t, pool, pools.Get(0))
+
+	assert.True(t, pool.less(MachinePool{ID: id + 1}))
+}
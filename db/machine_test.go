@@ -122,17 +122,28 @@ func TestMachineString(t *testing.T) {
 		PrivateIP:   "5.6.7.8",
 		FloatingIP:  "8.9.3.2",
 		DiskSize:    56,
+		Labels:      map[string]string{"tier": "db", "env": "prod"},
+		Pool:        "web",
+		Draining:    true,
 		Status:      Connected,
 	}
 	got = m.String()
 	exp = "Machine-1{Worker, Amazon us-west-1 m4.large preemptible, " +
 		"CloudID1234, PublicIP=1.2.3.4, PrivateIP=5.6.7.8, FloatingIP=8.9.3.2," +
-		" Disk=56GB, connected}"
+		" Disk=56GB, Labels=env=prod,tier=db, Pool=web, draining, connected}"
 	if got != exp {
 		t.Errorf("\nGot: %s\nExp: %s", got, exp)
 	}
 }
 
+func TestHasLabels(t *testing.T) {
+	labels := map[string]string{"tier": "db", "env": "prod"}
+	assert.True(t, HasLabels(labels, nil))
+	assert.True(t, HasLabels(labels, map[string]string{"tier": "db"}))
+	assert.False(t, HasLabels(labels, map[string]string{"tier": "web"}))
+	assert.False(t, HasLabels(nil, map[string]string{"zone": "a"}))
+}
+
 func TestConnectionStatus(t *testing.T) {
 	assert.Equal(t, Connected, ConnectionStatus(
 		Machine{PublicIP: "1.2.3.4", Connected: true}))
@@ -0,0 +1,162 @@
+package db
+
+import (
+	"sort"
+	"time"
+
+	"github.com/kelda/kelda/blueprint"
+)
+
+// A Metrics row is a sample of the resource usage of a machine, or of a pod
+// running on it, as reported by the machine's minion.
+type Metrics struct {
+	ID int
+
+	// The CloudID of the machine that reported the metrics. Only set on the
+	// daemon, as minions only track their own metrics.
+	Machine string `json:",omitempty"`
+
+	// The name of the pod whose usage is described. Empty for the metrics of
+	// the machine itself.
+	PodName string `json:",omitempty"`
+
+	// The time at which the sample was taken.
+	Time time.Time `rowStringer:"omit"`
+
+	// For machines, the percentage of the total CPU capacity of the machine
+	// that's in use. For pods, the percentage of a single core, so pods using
+	// multiple cores may exceed 100.
+	CPUPercent float64
+
+	// For pods, MemoryTotal is the memory limit of the pod.
+	MemoryUsed  uint64
+	MemoryTotal uint64
+
+	// Only set for machines.
+	DiskUsed  uint64 `json:",omitempty"`
+	DiskTotal uint64 `json:",omitempty"`
+
+	// Only set for pods.
+	NetworkRx uint64 `json:",omitempty"`
+	NetworkTx uint64 `json:",omitempty"`
+
+	// The value served by the HTTP autoscaling endpoint of the pod, and
+	// whether it was scraped successfully, since zero is a valid value. Only
+	// set for the replicas of containers that are autoscaled by an HTTP
+	// metric.
+	Custom    float64 `json:",omitempty"`
+	HasCustom bool    `json:",omitempty" rowStringer:"omit"`
+}
+
+// AutoscaleMetric returns the average value of the metric that `autoscale` is
+// driven by across the pods of `replicas`, and whether any of those pods
+// reported it. Replicas that aren't running yet, or whose HTTP endpoint
+// couldn't be scraped, are left out of the average.
+func AutoscaleMetric(autoscale blueprint.Autoscale, replicas []Container,
+	metrics []Metrics) (float64, bool) {
+
+	podMetrics := map[string]Metrics{}
+	for _, m := range metrics {
+		if m.PodName != "" {
+			podMetrics[m.PodName] = m
+		}
+	}
+
+	var total float64
+	var count int
+	for _, dbc := range replicas {
+		m, ok := podMetrics[dbc.PodName]
+		if dbc.PodName == "" || !ok {
+			continue
+		}
+
+		switch autoscale.Metric {
+		case blueprint.AutoscaleCPU:
+			total += m.CPUPercent
+		case blueprint.AutoscaleHTTP:
+			if !m.HasCustom {
+				continue
+			}
+			total += m.Custom
+		default:
+			continue
+		}
+		count++
+	}
+
+	if count == 0 {
+		return 0, false
+	}
+	return total / float64(count), true
+}
+
+// InsertMetrics creates a new metrics row and inserts it into the database.
+func (db Database) InsertMetrics() Metrics {
+	result := Metrics{ID: db.nextID()}
+	db.insert(result)
+	return result
+}
+
+// SelectFromMetrics gets all metrics in the database that satisfy 'check'. The
+// metrics are returned in the order they were inserted, so that samples keep a
+// stable order as they're passed from the minions to the daemon.
+func (db Database) SelectFromMetrics(check func(Metrics) bool) []Metrics {
+	var result []Metrics
+	for _, row := range db.selectRows(MetricsTable) {
+		if check == nil || check(row.(Metrics)) {
+			result = append(result, row.(Metrics))
+		}
+	}
+	sort.Sort(MetricsSlice(result))
+	return result
+}
+
+// SelectFromMetrics gets all metrics in the database connection that satisfy
+// 'check'.
+func (conn Conn) SelectFromMetrics(check func(Metrics) bool) []Metrics {
+	var result []Metrics
+	conn.Txn(MetricsTable).Run(func(view Database) error {
+		result = view.SelectFromMetrics(check)
+		return nil
+	})
+	return result
+}
+
+func (m Metrics) getID() int {
+	return m.ID
+}
+
+func (m Metrics) tt() TableType {
+	return MetricsTable
+}
+
+func (m Metrics) String() string {
+	return defaultString(m)
+}
+
+func (m Metrics) less(r row) bool {
+	return m.ID < r.(Metrics).ID
+}
+
+// MetricsSlice is an alias for []Metrics to allow for joins
+type MetricsSlice []Metrics
+
+// Less implements less than for sort.Interface.
+func (slc MetricsSlice) Less(i, j int) bool {
+	return slc[i].less(slc[j])
+}
+
+// Swap implements swapping for sort.Interface.
+func (slc MetricsSlice) Swap(i, j int) {
+	slc[i], slc[j] = slc[j], slc[i]
+}
+
+// Get returns the value contained at the given index
+func (slc MetricsSlice) Get(ii int) interface{} {
+	return slc[ii]
+}
+
+// Len returns the number of items in the slice.
+func (slc MetricsSlice) Len() int {
+	return len(slc)
+}
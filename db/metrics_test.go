@@ -0,0 +1,75 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/kelda/kelda/blueprint"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	conn := New()
+
+	var id int
+	conn.Txn(MetricsTable).Run(func(view Database) error {
+		m := view.InsertMetrics()
+		id = m.ID
+		m.PodName = "pod"
+		m.CPUPercent = 12.5
+		view.Commit(m)
+		return nil
+	})
+
+	metrics := MetricsSlice(conn.SelectFromMetrics(func(m Metrics) bool {
+		return true
+	}))
+	assert.Equal(t, 1, metrics.Len())
+
+	m := metrics[0]
+	assert.Equal(t, "pod", m.PodName)
+	assert.Equal(t, id, m.getID())
+	assert.Equal(t, MetricsTable, m.tt())
+
+	assert.Equal(t, "Metrics-1{PodName=pod, CPUPercent=12.5}", m.String())
+
+	assert.Equal(t, m, metrics.Get(0))
+
+	assert.True(t, m.less(Metrics{ID: id + 1}))
+}
+
+func TestAutoscaleMetric(t *testing.T) {
+	t.Parallel()
+
+	replicas := []Container{
+		{Hostname: "web-0", PodName: "pod-0"},
+		{Hostname: "web-1", PodName: "pod-1"},
+		{Hostname: "web-2", PodName: "pod-2"},
+		{Hostname: "web-3"},
+	}
+	metrics := []Metrics{
+		{Machine: "m1"},
+		{PodName: "pod-0", CPUPercent: 30, Custom: 4, HasCustom: true},
+		{PodName: "pod-1", CPUPercent: 60},
+		{PodName: "pod-2", CPUPercent: 90, Custom: 8, HasCustom: true},
+		{PodName: "other", CPUPercent: 100, Custom: 100, HasCustom: true},
+	}
+
+	cpu := blueprint.Autoscale{Metric: blueprint.AutoscaleCPU}
+	value, ok := AutoscaleMetric(cpu, replicas, metrics)
+	assert.True(t, ok)
+	assert.Equal(t, 60.0, value)
+
+	// pod-1 wasn't scraped, so it's left out of the average.
+	http := blueprint.Autoscale{Metric: blueprint.AutoscaleHTTP}
+	value, ok = AutoscaleMetric(http, replicas, metrics)
+	assert.True(t, ok)
+	assert.Equal(t, 6.0, value)
+
+	_, ok = AutoscaleMetric(cpu, replicas[3:], metrics)
+	assert.False(t, ok)
+
+	_, ok = AutoscaleMetric(cpu, replicas, nil)
+	assert.False(t, ok)
+}
@@ -15,7 +15,12 @@ type Minion struct {
 	Size        string
 	Region      string
 	FloatingIP  string
+	Labels      map[string]string
 	HostSubnets []string
+
+	// Whether the minion's machine is being drained before it's removed
+	// from its machine pool. Draining workers aren't given new containers.
+	Draining bool `json:",omitempty"`
 }
 
 // InsertMinion creates a new Minion and inserts it into 'db'.
@@ -28,8 +28,8 @@ func TestMinion(t *testing.T) {
 	assert.Equal(t, "Amazon", minion.Provider)
 	assert.Equal(t, id, minion.getID())
 
-	assert.Equal(t, "Minion-1{Self=true, Provider=Amazon, HostSubnets=[]}",
-		minion.String())
+	assert.Equal(t, "Minion-1{Self=true, Provider=Amazon, HostSubnets=[], "+
+		"Draining=false}", minion.String())
 
 	assert.Equal(t, minion, minions.Get(0))
 
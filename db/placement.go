@@ -12,15 +12,23 @@ type Placement struct {
 	Exclusive bool
 
 	// Constraint based on co-location with another container. If Exclusive is
-	// true, then the TargetContainer cannot be placed on the same machine as
-	// OtherContainer. OtherContainer must be a hostname.
+	// true, then the TargetContainer cannot be placed in the same topology
+	// domain as OtherContainer. Otherwise, it must be placed in the same
+	// domain. OtherContainer must be a hostname. The domain is the machine,
+	// unless Topology is blueprint.TopologyRegion.
 	OtherContainer string
+	Topology       string
 
 	// Machine Constraints
 	Provider   string
 	Size       string
 	Region     string
 	FloatingIP string
+	Labels     map[string]string
+
+	// If Weight is non-zero, the placement is a preference with the given
+	// weight rather than a requirement.
+	Weight int
 }
 
 // PlacementSlice is an alias for []Placement to allow for joins
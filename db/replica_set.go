@@ -0,0 +1,73 @@
+package db
+
+// A ReplicaSet row tracks the current number of replicas of an autoscaled
+// container in the blueprint. The autoscaler adjusts Replicas between the
+// container's bounds, and the minions run that many replicas.
+type ReplicaSet struct {
+	ID int
+
+	// The hostname of the autoscaled container in the blueprint.
+	Hostname string
+
+	Replicas int
+}
+
+// InsertReplicaSet creates a new replica set row and inserts it into the
+// database.
+func (db Database) InsertReplicaSet() ReplicaSet {
+	result := ReplicaSet{ID: db.nextID()}
+	db.insert(result)
+	return result
+}
+
+// SelectFromReplicaSet gets all replica sets in the database that satisfy
+// 'check'.
+func (db Database) SelectFromReplicaSet(check func(ReplicaSet) bool) []ReplicaSet {
+	var result []ReplicaSet
+	for _, row := range db.selectRows(ReplicaSetTable) {
+		if check == nil || check(row.(ReplicaSet)) {
+			result = append(result, row.(ReplicaSet))
+		}
+	}
+	return result
+}
+
+// SelectFromReplicaSet gets all replica sets in the database connection that
+// satisfy 'check'.
+func (conn Conn) SelectFromReplicaSet(check func(ReplicaSet) bool) []ReplicaSet {
+	var result []ReplicaSet
+	conn.Txn(ReplicaSetTable).Run(func(view Database) error {
+		result = view.SelectFromReplicaSet(check)
+		return nil
+	})
+	return result
+}
+
+func (s ReplicaSet) getID() int {
+	return s.ID
+}
+
+func (s ReplicaSet) tt() TableType {
+	return ReplicaSetTable
+}
+
+func (s ReplicaSet) String() string {
+	return defaultString(s)
+}
+
+func (s ReplicaSet) less(r row) bool {
+	return s.ID < r.(ReplicaSet).ID
+}
+
+// ReplicaSetSlice is an alias for []ReplicaSet to allow for joins
+type ReplicaSetSlice []ReplicaSet
+
+// Get returns the value contained at the given index
+func (slc ReplicaSetSlice) Get(ii int) interface{} {
+	return slc[ii]
+}
+
+// Len returns the number of items in the slice.
+func (slc ReplicaSetSlice) Len() int {
+	return len(slc)
+}
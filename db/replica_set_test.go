@@ -0,0 +1,38 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplicaSet(t *testing.T) {
+	t.Parallel()
+
+	conn := New()
+
+	var id int
+	conn.Txn(ReplicaSetTable).Run(func(view Database) error {
+		set := view.InsertReplicaSet()
+		id = set.ID
+		set.Hostname = "web"
+		set.Replicas = 3
+		view.Commit(set)
+		return nil
+	})
+
+	sets := ReplicaSetSlice(conn.SelectFromReplicaSet(
+		func(s ReplicaSet) bool { return true }))
+	assert.Equal(t, 1, sets.Len())
+
+	set := sets[0]
+	assert.Equal(t, "web", set.Hostname)
+	assert.Equal(t, id, set.getID())
+	assert.Equal(t, ReplicaSetTable, set.tt())
+
+	assert.Equal(t, "ReplicaSet-1{Hostname=web, Replicas=3}", set.String())
+
+	assert.Equal(t, set, sets.Get(0))
+
+	assert.True(t, set.less(ReplicaSet{ID: id + 1}))
+}
@@ -38,12 +38,21 @@ var ImageTable = TableType(reflect.TypeOf(Image{}).String())
 // HostnameTable is the type of the Hostname table.
 var HostnameTable = TableType(reflect.TypeOf(Hostname{}).String())
 
+// MetricsTable is the type of the metrics table.
+var MetricsTable = TableType(reflect.TypeOf(Metrics{}).String())
+
+// MachinePoolTable is the type of the machine pool table.
+var MachinePoolTable = TableType(reflect.TypeOf(MachinePool{}).String())
+
+// ReplicaSetTable is the type of the replica set table.
+var ReplicaSetTable = TableType(reflect.TypeOf(ReplicaSet{}).String())
+
 // AllTables is a slice of all the db TableTypes. It is used primarily for tests,
 // where there is no reason to put lots of thought into which tables a Transaction
 // should use.
 var AllTables = []TableType{BlueprintTable, MachineTable, ContainerTable, MinionTable,
 	ConnectionTable, LoadBalancerTable, EtcdTable, PlacementTable, ImageTable,
-	HostnameTable}
+	HostnameTable, MetricsTable, MachinePoolTable, ReplicaSetTable}
 
 type table struct {
 	rows map[int]row
@@ -100,18 +100,66 @@ class Infrastructure {
    *   add its IP address here.  These IP addresses must be in CIDR notation; e.g.,
    *   to allow access from 1.2.3.4, set adminACL to ["1.2.3.4/32"]. To allow access
    *   from all IP addresses, set adminACL to ["0.0.0.0/0"].
+   * @param {Object} [args.logSink] - Where the logs of all containers should be
+   *   shipped. `logSink.type` is one of 'syslog', 'http', or 'file'. Syslog
+   *   sinks require `logSink.address` (host:port), and optionally
+   *   `logSink.protocol` ('udp' or 'tcp'). HTTP sinks POST batches of JSON
+   *   lines to the URL in `logSink.address`. File sinks append JSON lines to
+   *   `logSink.path` on the leader master.
+   * @param {Object} [args.secretBackend] - Where the values of secrets
+   *   referenced by containers are stored. `secretBackend.type` is one of
+   *   'kubernetes' (the default), 'vault', or 'file'. Vault backends read the
+   *   "value" key of the secret from the KV version 2 secrets engine at
+   *   `secretBackend.address`, authenticating with the token stored in the
+   *   Kelda secret named by `secretBackend.tokenSecret`. The engine's mount
+   *   (default 'secret') and a prefix within it can be set with
+   *   `secretBackend.mount` and `secretBackend.path`. File backends read each
+   *   secret from the file of the same name in the `secretBackend.path`
+   *   directory on the masters, and are intended for testing.
+   * @param {Object[]} [args.registryCredentials] - The credentials for
+   *   private Docker registries. Each entry has a `server` (e.g.
+   *   'registry.example.com') and the name of the Kelda `secret` that holds
+   *   the credentials in the form USERNAME:PASSWORD. The credentials are used
+   *   both to pull containers' images and to pull the base images of custom
+   *   Dockerfiles.
+   * @param {string} [args.imageDigests] - Whether the tags of images that
+   *   aren't built by Kelda should be resolved to digests. If 'pin', each tag
+   *   is resolved when the container is first deployed, and the container
+   *   keeps running that digest even if the tag is updated. If 'watch', the
+   *   registries are polled, and containers are redeployed when their tags
+   *   are updated.
+   * @param {Object} [args.securityPolicy] - Restrictions on the security
+   *   settings of the containers. If `securityPolicy.forbidPrivileged` is
+   *   true, the daemon rejects the blueprint if any container, sidecar, or
+   *   init container is privileged, adds capabilities beyond Docker's
+   *   defaults, sets unsafe sysctls, has an unconfined seccomp or AppArmor
+   *   profile, or mounts a hostPath volume.
+   * @param {MachinePool|MachinePool[]} [args.machinePools] - Pools of
+   *   workers whose size is adjusted by Kelda according to demand. See
+   *   {@link MachinePool} for details.
    *
    * We only document properties users should care about.
-   * @property {Container[]} containers All containers that have been registered
-   *   to run on this infrastructure.
+   * @property {Array.<Container|ContainerGroup|DaemonSet|StatefulSet|ReplicaSet>}
+   *   containers All containers, container groups, daemon sets, stateful
+   *   sets, and replica sets that have been registered to run on this
+   *   infrastructure.
    * @property {LoadBalancer[]} loadBalancers All load balancers that have been
    *   registered to run on this infrastructure.
    * @property {Machine[]} masters The master machines of this infrastructure.
    * @property {Machine[]} workers The worker machines of this infrastructure.
+   * @property {MachinePool[]} machinePools The autoscaled pools of worker
+   *   machines of this infrastructure.
    * @property {string[]} adminACL A list of IP addresses that are allowed to
    *   access the deployed machines. See the description of the adminACL
    *   constructor argument for more details.
    * @property {string} namespace The namespace the blueprint should run in.
+   * @property {Object} logSink Where container logs are shipped, if anywhere.
+   * @property {Object} secretBackend Where secret values are stored, if not
+   *   in Kubernetes.
+   * @property {Object[]} registryCredentials The credentials for private
+   *   Docker registries.
+   * @property {string} imageDigests Whether image tags are resolved to
+   *   digests, if at all.
    */
   constructor(args) {
     const defaults = { namespace: 'kelda' };
@@ -121,12 +169,24 @@ class Infrastructure {
 
     this.adminACL = getStringArray('adminACL', allArgs.adminACL);
     this.namespace = getString('namespace', allArgs.namespace);
+    this.logSink = getLogSink(allArgs.logSink);
+    this.secretBackend = getSecretBackend(allArgs.secretBackend);
+    this.registryCredentials = getRegistryCredentials(
+      allArgs.registryCredentials);
+    this.imageDigests = getString('imageDigests', allArgs.imageDigests);
+    if (!['', 'pin', 'watch'].includes(this.imageDigests)) {
+      throw new Error('imageDigests must be "pin" or "watch" (was: ' +
+        `${stringify(this.imageDigests)})`);
+    }
+    this.securityPolicy = getSecurityPolicy(allArgs.securityPolicy);
     this.containers = new Set();
     this.loadBalancers = [];
     this.volumes = new Set();
 
     this.masters = boxObjects('Infrastructure.masters', allArgs.masters, Machine);
     this.workers = boxObjects('Infrastructure.workers', allArgs.workers, Machine);
+    this.machinePools = (allArgs.machinePools === undefined) ? [] :
+      boxObjects('Infrastructure.machinePools', allArgs.machinePools, MachinePool);
     if (this.masters.length < 1) {
       throw new Error('masters must include 1 or more Machines to use as ' +
         'Kelda masters.');
@@ -183,6 +243,7 @@ class Infrastructure {
     const workersWithRole = this.workers.map(worker => machineWithRole(worker, 'Worker'));
     const machinesWithRole = mastersWithRole.concat(workersWithRole);
     const machines = machinesWithRole.map(m => m.toKeldaRepresentation());
+    const machinePools = this.machinePools.map(pool => pool.toKeldaRepresentation());
 
     const keldaInfrastructure = {
       machines,
@@ -195,6 +256,24 @@ class Infrastructure {
       namespace: this.namespace,
       adminACL: this.adminACL,
     };
+    if (this.logSink !== undefined) {
+      keldaInfrastructure.logSink = this.logSink;
+    }
+    if (this.secretBackend !== undefined) {
+      keldaInfrastructure.secretBackend = this.secretBackend;
+    }
+    if (this.registryCredentials.length !== 0) {
+      keldaInfrastructure.registryCredentials = this.registryCredentials;
+    }
+    if (this.imageDigests !== '') {
+      keldaInfrastructure.imageDigests = this.imageDigests;
+    }
+    if (this.securityPolicy !== undefined) {
+      keldaInfrastructure.securityPolicy = this.securityPolicy;
+    }
+    if (machinePools.length !== 0) {
+      keldaInfrastructure.machinePools = machinePools;
+    }
     vet(keldaInfrastructure);
     return keldaInfrastructure;
   }
@@ -368,18 +447,37 @@ function vet(infrastructure) {
   });
 
   const dockerfiles = {};
+  const buildOptions = {};
+  const members = [];
   infrastructure.containers.forEach((c) => {
+    members.push(c);
+    (c.sidecars || []).forEach(sidecar => members.push(sidecar));
+    (c.initContainers || []).forEach(init => members.push(init));
+  });
+  members.forEach((c) => {
     const name = c.image.name;
     if (dockerfiles[name] !== undefined &&
                 dockerfiles[name] !== c.image.dockerfile) {
       throw new Error(`${name} has differing Dockerfiles`);
     }
     dockerfiles[name] = c.image.dockerfile;
+
+    const options = stringify({
+      contextDir: c.image.contextDir,
+      buildArgs: c.image.buildArgs,
+      target: c.image.target,
+    });
+    if (buildOptions[name] !== undefined && buildOptions[name] !== options) {
+      throw new Error(`${name} has differing build options`);
+    }
+    buildOptions[name] = options;
   });
 
   // Check to make sure all machines have the same region and provider.
   let lastMachine;
-  infrastructure.machines.forEach((m) => {
+  const pools = infrastructure.machinePools || [];
+  const poolMachines = pools.map(pool => pool.machine);
+  infrastructure.machines.concat(poolMachines).forEach((m) => {
     if (lastMachine !== undefined &&
       (lastMachine.region !== m.region || lastMachine.provider !== m.provider)) {
       throw new Error('All machines must have the same provider and region. '
@@ -399,16 +497,26 @@ class LoadBalancer {
    *
    * @param {Object} args - All required arguments.
    * @param {string} args.name - The name of the load balancer.
-   * @param {Container[]} args.containers - The containers behind the load balancer.
+   * @param {Array.<Container|ReplicaSet>} args.containers - The containers
+   *   behind the load balancer. The load balancer follows the replicas of
+   *   ReplicaSets as they're added and removed.
    *
    * We only document properties users should care about.
-   * @property {Container[]} containers The containers behind the load balancer.
+   * @property {Array.<Container|ReplicaSet>} containers The containers behind
+   *   the load balancer.
    */
   constructor(args) {
     checkRequiredArguments('LoadBalancer', args, ['name', 'containers']);
 
     this.name = hostnameGenerator.getName(getString('LoadBalancer name', args.name));
-    this.containers = boxObjects('LoadBalancer.containers', args.containers, Container);
+    this.containers = Array.isArray(args.containers) ? args.containers :
+      [args.containers];
+    this.containers.forEach((c) => {
+      if (!(c instanceof Container) && !(c instanceof ReplicaSet)) {
+        throw new Error('LoadBalancer.containers must be Containers or ' +
+          `ReplicaSets (was ${stringify(c)})`);
+      }
+    });
 
     checkExtraKeys(args, this);
     validateHostname(this.name);
@@ -583,6 +691,107 @@ function checkExtraKeys(opts, obj) {
   }
 }
 
+/**
+ * @private
+ * @param {Object} [options] - The options passed to a placement method.
+ * @param {boolean} allowTopology - Whether the placement can set a topology.
+ * @returns {Object} Ensures that the weight, if given, is an integer between 1
+ *   and 100, and that the topology, if allowed and given, is 'machine' or
+ *   'region'. Returns the options with only the keys understood by Kelda.
+ */
+function getPlacementOptions(options, allowTopology) {
+  if (options === undefined) {
+    return { weight: 0 };
+  }
+
+  const validated = { weight: getNumber('weight', options.weight) };
+  if (allowTopology) {
+    validated.topology = getString('topology', options.topology);
+  }
+  checkExtraKeys(options, validated);
+
+  if (options.weight !== undefined && (!Number.isInteger(validated.weight) ||
+      validated.weight < 1 || validated.weight > 100)) {
+    throw new Error('weight must be an integer between 1 and 100 ' +
+      `(was: ${stringify(options.weight)})`);
+  }
+  if (![undefined, '', 'machine', 'region'].includes(validated.topology)) {
+    throw new Error('topology must be "machine" or "region" ' +
+      `(was: ${stringify(validated.topology)})`);
+  }
+  return validated;
+}
+
+/**
+ * @private
+ * @param {Container|ContainerGroup} target - The container being placed.
+ * @param {Container|ContainerGroup} other - The container that `target` is
+ *   placed relative to.
+ * @param {boolean} exclusive - Whether `target` is placed away from `other`,
+ *   rather than with it.
+ * @param {Object} [options] - The options passed to the placement method.
+ * @returns {Object} The placement of `target` relative to `other`.
+ */
+function makeContainerPlacement(target, other, exclusive, options) {
+  if (!(other instanceof Container) && !(other instanceof ContainerGroup)) {
+    throw new Error('containers can only be placed relative to a Container ' +
+      `or ContainerGroup (was: ${stringify(other)})`);
+  }
+  return Object.assign({
+    targetContainer: target.hostname,
+    otherContainer: other.getHostname(),
+    exclusive,
+  }, getPlacementOptions(options, true));
+}
+
+/**
+ * Spreads the given containers across machines or regions, so that the
+ * failure of a single machine or region is less likely to take down all of
+ * them. Spreading is a preference, so the containers are still placed
+ * together if there's no room to spread them.
+ *
+ * @example <caption>Spread three replicas of a web server across
+ * machines.</caption>
+ * const replicas = [];
+ * for (let i = 0; i < 3; i += 1) {
+ *   replicas.push(new Container({ name: 'web', image: 'nginx' }));
+ * }
+ * spread(replicas);
+ *
+ * @param {Container[]|ContainerGroup[]} containers - The containers to spread.
+ * @param {Object} [options] - Optional arguments.
+ * @param {string} [options.across=machine] - Either 'machine' or 'region'.
+ * @param {number} [options.weight=100] - How strongly to prefer spreading the
+ *   containers, relative to their other placement preferences. Must be an
+ *   integer between 1 and 100.
+ * @returns {void}
+ */
+function spread(containers, options = {}) {
+  if (!Array.isArray(containers)) {
+    throw new Error('containers must be an array of Containers or ' +
+      `ContainerGroups (was: ${stringify(containers)})`);
+  }
+
+  const extras = Object.keys(options).filter(
+    key => !['across', 'weight'].includes(key));
+  if (extras.length > 0) {
+    throw new Error(`Unrecognized keys passed to spread: ${extras}`);
+  }
+  const placementOptions = {
+    topology: options.across,
+    weight: options.weight === undefined ? 100 : options.weight,
+  };
+
+  containers.forEach((target) => {
+    containers.forEach((other) => {
+      if (target !== other) {
+        target.placements.push(makeContainerPlacement(target, other, true,
+          placementOptions));
+      }
+    });
+  });
+}
+
 /**
  * Forces `arg` to be a number, even if it's undefined.
  * @private
@@ -621,16 +830,94 @@ function getString(argName, arg) {
   throw new Error(`${argName} must be a string (was: ${stringify(arg)})`);
 }
 
+/**
+ * @private
+ * @param {Object} [secretBackend] - The secret backend passed to the
+ *   Infrastructure.
+ * @returns {Object|undefined} Ensures that `secretBackend` is a valid secret
+ *   backend, and returns it with only the keys understood by Kelda.
+ */
+function getSecretBackend(secretBackend) {
+  if (secretBackend === undefined) {
+    return undefined;
+  }
+
+  const backend = {
+    type: getString('secretBackend.type', secretBackend.type),
+    address: getString('secretBackend.address', secretBackend.address),
+    mount: getString('secretBackend.mount', secretBackend.mount),
+    path: getString('secretBackend.path', secretBackend.path),
+    tokenSecret: getString('secretBackend.tokenSecret',
+      secretBackend.tokenSecret),
+  };
+  checkExtraKeys(secretBackend, backend);
+
+  switch (backend.type) {
+    case 'kubernetes':
+      break;
+    case 'vault':
+      if (backend.address === '') {
+        throw new Error('vault secret backends require an address');
+      }
+      if (backend.tokenSecret === '') {
+        throw new Error('vault secret backends require a tokenSecret');
+      }
+      break;
+    case 'file':
+      if (backend.path === '') {
+        throw new Error('file secret backends require a path');
+      }
+      break;
+    default:
+      throw new Error('secretBackend.type must be "kubernetes", "vault", or ' +
+        `"file" (was: ${stringify(backend.type)})`);
+  }
+  return backend;
+}
+
+/**
+ * @private
+ * @param {Object[]} [registryCredentials] - The registry credentials passed to
+ *   the Infrastructure.
+ * @returns {Object[]} Ensures that each credential names a server and a
+ *   secret, and returns them with only the keys understood by Kelda.
+ */
+function getRegistryCredentials(registryCredentials) {
+  if (registryCredentials === undefined) {
+    return [];
+  }
+  if (!Array.isArray(registryCredentials)) {
+    throw new Error('registryCredentials must be an array (was: ' +
+      `${stringify(registryCredentials)})`);
+  }
+
+  return registryCredentials.map((cred, i) => {
+    const argName = `registryCredentials[${i}]`;
+    const validated = {
+      server: getString(`${argName}.server`, cred.server),
+      secret: getString(`${argName}.secret`, cred.secret),
+    };
+    checkExtraKeys(cred, validated);
+
+    if (validated.server === '' || validated.secret === '') {
+      throw new Error(`${argName} requires a server and a secret`);
+    }
+    return validated;
+  });
+}
+
 /**
  * @private
  * @param {string} argName - The name of `arg` (for logging).
- * @param {Object.<string, string|Secret>} arg - The map of
- *   strings to Secrets or Strings.
- * @returns {Object.<string, string|Secret>} An empty object
- *   if `arg` is not defined, and otherwise ensures that `arg` is an object with
- *   string keys and string or Secret values and then returns it.
+ * @param {Object.<string, string|Secret|RuntimeValue|Template>} arg - The map
+ *   of strings to Secrets, RuntimeValues, Templates, or Strings.
+ * @param {boolean} [allowTemplates] - Whether the values may be Templates.
+ * @returns {Object.<string, string|Secret|RuntimeValue|Template>} An empty
+ *   object if `arg` is not defined, and otherwise ensures that `arg` is an
+ *   object with string keys and string, Secret, or RuntimeValue values (or
+ *   Template values, if `allowTemplates` is set) and then returns it.
  */
-function getSecretOrStringMap(argName, arg) {
+function getSecretOrStringMap(argName, arg, allowTemplates) {
   if (arg === undefined) {
     return {};
   }
@@ -644,15 +931,73 @@ function getSecretOrStringMap(argName, arg) {
                 `${stringify(k)} is not a string)`);
     }
     const val = arg[k];
-    if (typeof val !== 'string' && !(val instanceof Secret)) {
-      throw new Error(`${argName} must be a map with string or Secret ` +
-        `values (value ${stringify(arg[k])} associated ` +
-        `with ${k} is not a string or Secret)`);
+    if (val instanceof Template && !allowTemplates) {
+      throw new Error(`${argName} cannot contain Templates (value ` +
+        `associated with ${k} is a Template)`);
+    }
+    if (typeof val !== 'string' && !(val instanceof Secret) &&
+      !(val instanceof RuntimeValue) && !(val instanceof Template)) {
+      throw new Error(`${argName} must be a map with string, Secret, or ` +
+        `RuntimeValue values (value ${stringify(arg[k])} associated ` +
+        `with ${k} is not a string, Secret, or RuntimeValue)`);
+    }
+  });
+  return arg;
+}
+
+/**
+ * @private
+ * @param {string} argName - The name of `arg` (for logging).
+ * @param {Object.<string, string>} arg - The map of strings to strings.
+ * @returns {Object.<string, string>} Ensures that `arg` is an object with
+ *   string values and then returns it.
+ */
+function getStringMap(argName, arg) {
+  if (typeof arg !== 'object' || arg === null || Array.isArray(arg)) {
+    throw new Error(`${argName} must be a map (was: ${stringify(arg)})`);
+  }
+  Object.keys(arg).forEach((k) => {
+    if (typeof arg[k] !== 'string') {
+      throw new Error(`${argName} must be a map with string values (value ` +
+        `${stringify(arg[k])} associated with ${k} is not a string)`);
     }
   });
   return arg;
 }
 
+/**
+ * Verifies that `arg` is a map of machine labels, or undefined.
+ * @private
+ *
+ * @param {string} argName - The name of `arg` (for logging).
+ * @param {Object.<string, string>} [arg] - The machine labels.
+ * @returns {Object.<string, string>} An empty map if `arg` is not defined,
+ *   and otherwise ensures that the keys and values of `arg` are valid labels
+ *   and then returns it.
+ */
+function getMachineLabels(argName, arg) {
+  if (arg === undefined) {
+    return {};
+  }
+
+  const labels = getStringMap(argName, arg);
+  Object.keys(labels).forEach((key) => {
+    if (key.length > 50 || !/^[a-z]([-_a-z0-9]*[a-z0-9])?$/.test(key)) {
+      throw new Error(`${argName} has an invalid key ${stringify(key)}: ` +
+        'keys must start with a lowercase letter, contain only lowercase ' +
+        'letters, numbers, dashes, and underscores, and be at most 50 ' +
+        'characters');
+    }
+    const value = labels[key];
+    if (value.length > 50 || !/^[a-z0-9]([-_a-z0-9]*[a-z0-9])?$/.test(value)) {
+      throw new Error(`${argName} has an invalid value ${stringify(value)} ` +
+        `for ${key}: values must contain only lowercase letters, numbers, ` +
+        'dashes, and underscores, and be at most 50 characters');
+    }
+  });
+  return labels;
+}
+
 /**
  * Verifies `arg` is an array of strings or undefined.
  * @private
@@ -681,6 +1026,108 @@ function getStringArray(argName, arg) {
   return arg;
 }
 
+/**
+ * @private
+ * @param {Object} [security] - The security settings passed to a Container.
+ * @returns {Object|undefined} Ensures that `security` contains valid security
+ *   settings, and returns the settings that are set.
+ */
+function getSecurity(security) {
+  if (security === undefined) {
+    return undefined;
+  }
+
+  const getID = (argName, arg) => {
+    if (arg !== undefined && (!Number.isInteger(arg) || arg < 0)) {
+      throw new Error(`${argName} must be a non-negative integer ` +
+        `(was: ${stringify(arg)})`);
+    }
+    return arg;
+  };
+  const settings = {
+    runAsUser: getID('security.runAsUser', security.runAsUser),
+    runAsGroup: getID('security.runAsGroup', security.runAsGroup),
+    readOnlyRootFilesystem: getBoolean('security.readOnlyRootFilesystem',
+      security.readOnlyRootFilesystem),
+    capAdd: getStringArray('security.capAdd', security.capAdd),
+    capDrop: getStringArray('security.capDrop', security.capDrop),
+    sysctls: security.sysctls === undefined ? {} :
+      getStringMap('security.sysctls', security.sysctls),
+    seccompProfile: getString('security.seccompProfile',
+      security.seccompProfile),
+    appArmorProfile: getString('security.appArmorProfile',
+      security.appArmorProfile),
+  };
+  checkExtraKeys(security, settings);
+
+  // Drop the unset settings so that they don't clutter the blueprint.
+  return _.pick(settings, val => val !== undefined && val !== false &&
+    val !== '' && !(typeof val === 'object' && _.isEmpty(val)));
+}
+
+/**
+ * @private
+ * @param {Object} [securityPolicy] - The security policy passed to the
+ *   Infrastructure.
+ * @returns {Object|undefined} Ensures that `securityPolicy` is a valid
+ *   security policy, and returns it with only the keys understood by Kelda.
+ */
+function getSecurityPolicy(securityPolicy) {
+  if (securityPolicy === undefined) {
+    return undefined;
+  }
+
+  const policy = {
+    forbidPrivileged: getBoolean('securityPolicy.forbidPrivileged',
+      securityPolicy.forbidPrivileged),
+  };
+  checkExtraKeys(securityPolicy, policy);
+  return policy;
+}
+
+/**
+ * @private
+ * @param {Object} [logSink] - The log sink passed to the Infrastructure.
+ * @returns {Object|undefined} Ensures that `logSink` is a valid log sink, and
+ *   returns it with only the keys understood by Kelda.
+ */
+function getLogSink(logSink) {
+  if (logSink === undefined) {
+    return undefined;
+  }
+
+  const sink = {
+    type: getString('logSink.type', logSink.type),
+    address: getString('logSink.address', logSink.address),
+    protocol: getString('logSink.protocol', logSink.protocol),
+    path: getString('logSink.path', logSink.path),
+  };
+  checkExtraKeys(logSink, sink);
+
+  switch (sink.type) {
+    case 'syslog':
+      if (sink.protocol !== '' && sink.protocol !== 'udp' && sink.protocol !== 'tcp') {
+        throw new Error('logSink.protocol must be "udp" or "tcp" ' +
+          `(was: ${stringify(sink.protocol)})`);
+      }
+      // Fall through.
+    case 'http':
+      if (sink.address === '') {
+        throw new Error(`${sink.type} log sinks require an address`);
+      }
+      break;
+    case 'file':
+      if (sink.path === '') {
+        throw new Error('file log sinks require a path');
+      }
+      break;
+    default:
+      throw new Error('logSink.type must be "syslog", "http", or "file" ' +
+        `(was: ${stringify(sink.type)})`);
+  }
+  return sink;
+}
+
 /**
  * @private
  * @param {string} argName - The name of `arg` (for logging).
@@ -747,6 +1194,11 @@ class Machine {
    *   in to the machine and containers running on it.
    * @param {boolean} [opts.preemptible=false] - Whether the machine
    *   should be preemptible. Only supported on the Amazon provider.
+   * @param {Object.<string, string>} [opts.labels] - Labels that containers
+   *   can be placed on with {@link Container#placeOn}, such as
+   *   `{ tier: 'db' }`. The labels are also applied as tags by the cloud
+   *   provider. Keys and values may only contain lowercase letters, numbers,
+   *   dashes, and underscores, and keys must start with a letter.
    */
   constructor(opts) {
     this._refID = uniqueID();
@@ -763,6 +1215,7 @@ class Machine {
     this.diskSize = getNumber('diskSize', opts.diskSize);
     this.sshKeys = getStringArray('sshKeys', opts.sshKeys);
     this.preemptible = getBoolean('preemptible', opts.preemptible);
+    this.labels = getMachineLabels('labels', opts.labels);
 
     this.chooseSize(boxRange(opts.cpu), boxRange(opts.ram));
     this.chooseRegion();
@@ -924,10 +1377,13 @@ class Machine {
    * @returns {Machine} A new machine with the same attributes.
    */
   clone() {
-    // _.clone only creates a shallow copy, so we must clone sshKeys ourselves.
+    // _.clone only creates a shallow copy, so we must clone sshKeys and
+    // labels ourselves.
     const keyClone = _.clone(this.sshKeys);
+    const labelsClone = _.clone(this.labels);
     const cloned = _.clone(this);
     cloned.sshKeys = keyClone;
+    cloned.labels = labelsClone;
     return new Machine(cloned);
   }
 
@@ -960,6 +1416,7 @@ class Machine {
       floatingIp: this.floatingIp,
       diskSize: this.diskSize,
       preemptible: this.preemptible,
+      labels: this.labels,
     });
   }
 
@@ -978,6 +1435,75 @@ class Machine {
   }
 }
 
+class MachinePool {
+  /**
+   * Creates a new MachinePool. A MachinePool is a group of identical worker
+   * machines whose size Kelda adjusts according to demand. Kelda adds a
+   * machine when containers can't be scheduled because the workers lack
+   * resources, and drains and removes a machine when the pool's machines
+   * have been underused for a while.
+   * @constructor
+   *
+   * @example <caption>Create a pool of between two and ten workers.</caption>
+   * const pool = new MachinePool({
+   *   name: 'web',
+   *   machine: new Machine({ provider: 'Amazon', size: 'm4.large' }),
+   *   min: 2,
+   *   max: 10,
+   * });
+   * const infrastructure = new Infrastructure({
+   *   masters: new Machine({ provider: 'Amazon' }),
+   *   workers: new Machine({ provider: 'Amazon' }),
+   *   machinePools: pool,
+   * });
+   *
+   * @param {Object} args - All required and optional arguments.
+   * @param {string} args.name - The name of the pool. Names must be unique
+   *   within an infrastructure.
+   * @param {Machine} args.machine - The template for the machines in the
+   *   pool. The machines must use the same provider and region as the other
+   *   machines in the infrastructure.
+   * @param {number} [args.min=0] - The minimum number of machines in the
+   *   pool.
+   * @param {number} args.max - The maximum number of machines in the pool.
+   */
+  constructor(args) {
+    checkRequiredArguments('MachinePool', args, ['name', 'machine', 'max']);
+
+    this.name = getString('name', args.name);
+    if (!(args.machine instanceof Machine)) {
+      throw new Error('machine field must be of type Machine');
+    }
+    this.machine = args.machine;
+    this.min = getNumber('min', args.min);
+    this.max = getNumber('max', args.max);
+    if (this.max < 1 || this.min < 0 || this.min > this.max) {
+      throw new Error('the maximum size of a MachinePool must be at least 1, ' +
+        'and its minimum size must be between 0 and its maximum size ' +
+        `(was: min ${this.min}, max ${this.max})`);
+    }
+
+    checkExtraKeys(args, this);
+  }
+
+  /**
+   * Converts the MachinePool to the JSON format expected by the Kelda go code.
+   * @private
+   * @returns {Object} A map that can be converted to JSON and interpreted by the Kelda
+   *   Go code.
+   */
+  toKeldaRepresentation() {
+    const machine = this.machine.clone();
+    machine.role = 'Worker';
+    return {
+      name: this.name,
+      machine: machine.toKeldaRepresentation(),
+      min: this.min,
+      max: this.max,
+    };
+  }
+}
+
 class Image {
   /**
    * Creates a Docker Image.
@@ -1024,6 +1550,15 @@ class Image {
    *   Docker Hub (e.g., nginx or nginx:1.13.3).
    * @param {string} [args.dockerfile] - The string contents of the Dockerfile that
    *   constructs the Image.
+   * @param {string} [args.contextDir] - A local directory whose files are
+   *   available to COPY and ADD instructions in the Dockerfile. Relative paths
+   *   are resolved against the current working directory. The directory is
+   *   uploaded with the blueprint, so it must be small (at most 1MB once
+   *   compressed).
+   * @param {Object.<string, string>} [args.buildArgs] - The values of the ARG
+   *   instructions in the Dockerfile.
+   * @param {string} [args.target] - The stage of a multi-stage Dockerfile to
+   *   build.
    */
   constructor(args) {
     checkRequiredArguments('Image', args, ['name']);
@@ -1031,7 +1566,25 @@ class Image {
     this.name = getString('Image name', args.name);
     this.dockerfile = getString('dockerfile', args.dockerfile);
 
+    // The build options are only set when given, so that they don't change
+    // the IDs of containers that use images without them.
+    if (args.contextDir !== undefined) {
+      this.contextDir = path.resolve(getString('contextDir', args.contextDir));
+    }
+    if (args.buildArgs !== undefined) {
+      this.buildArgs = getStringMap('buildArgs', args.buildArgs);
+    }
+    if (args.target !== undefined) {
+      this.target = getString('target', args.target);
+    }
+
     checkExtraKeys(args, this);
+
+    if (this.dockerfile === '' && (this.contextDir !== undefined ||
+      this.buildArgs !== undefined || this.target !== undefined)) {
+      throw new Error('contextDir, buildArgs, and target require a ' +
+        'dockerfile');
+    }
   }
 
   /**
@@ -1078,6 +1631,16 @@ class Container {
    *   },
    * });
    *
+   * @example <caption>Create a Container that learns its own IP address
+   * through an environment variable, and the region of its machine through a
+   * file.</caption>
+   * const container = new Container({
+   *   name: 'my-app',
+   *   image: 'nginx',
+   *   env: { MY_IP: new RuntimeValue('containerIP') },
+   *   filepathToContent: { '/etc/region': new RuntimeValue('host.region') },
+   * });
+   *
    * @example <caption>Create a Container that mounts the Docker run socket
    * from the host.</caption>
    *
@@ -1112,33 +1675,64 @@ class Container {
    *   the equivalent of granting root access to a user. The majority of
    *   containers do not require this flag, so make sure it is necessary before
    *   enabling it.
-   * @param {Object.<string, string|Secret>} [args.env] -
+   * @param {Object} [args.security] - Finer-grained security settings than
+   *   `privileged`.
+   * @param {number} [args.security.runAsUser] - The user ID to run the
+   *   container's processes as.
+   * @param {number} [args.security.runAsGroup] - A group ID that the
+   *   container's processes are members of, and that owns its volumes. It's
+   *   added as a supplemental group rather than replacing the primary group.
+   * @param {bool} [args.security.readOnlyRootFilesystem] - Whether the
+   *   container's root filesystem is read-only.
+   * @param {string[]} [args.security.capAdd] - Linux capabilities to add,
+   *   e.g. 'NET_ADMIN'.
+   * @param {string[]} [args.security.capDrop] - Linux capabilities to drop.
+   *   'ALL' drops every capability.
+   * @param {Object.<string, string>} [args.security.sysctls] - Namespaced
+   *   kernel parameters to set, e.g. `{ 'net.core.somaxconn': '1024' }`.
+   * @param {string} [args.security.seccompProfile] - The seccomp profile to
+   *   run the container with. Either 'runtime/default', 'unconfined', or the
+   *   name of a profile on the machines.
+   * @param {string} [args.security.appArmorProfile] - The AppArmor profile to
+   *   run the container with, in the same format as `seccompProfile`.
+   * @param {Object.<string, string|Secret|RuntimeValue>} [args.env] -
    *   Environment variables to set in the booted container. The key is the name
    *   of the environment variable.
-   * @param {Object.<string, string|Secret>} [args.filepathToContent] -
+   * @param {Object.<string, string|Secret|RuntimeValue|Template>}
+   *   [args.filepathToContent] -
    *   Text files to be installed on the container before it starts.  The key is
    *   the path on the container where the text file should be installed, and
    *   the value is the contents of the text file. If the file content specified
    *   by this argument changes and the blueprint is re-run, Kelda will re-start
-   *   the container using the new files. Files are installed with permissions
-   *   0644 and parent directories are automatically created.
+   *   the container using the new files. Templates are re-rendered, and the
+   *   container re-started, whenever the values they reference change. Files
+   *   are installed with permissions 0644 and parent directories are
+   *   automatically created.
    * @param {VolumeMount[]} [args.volumeMounts] - A list of volumes to mount
    *   within the container. Referenced volumes are automatically created by
    *   Kelda.
+   * @param {Container[]} [args.initContainers] - Containers that are run in
+   *   order, each to completion, before the container is started. They run on
+   *   the same machine as the container and can share its volumes, which
+   *   makes them useful for tasks such as running database migrations. The
+   *   names of the init containers are only used to identify them, and their
+   *   own init containers are ignored.
    *
    * We only document properties users should care about.
    * @property {Image} image The image of the container.
    * @property {string[]} command The command to run when the container starts.
-   * @property {Object.<string, string|Secret>} env An object containing the
-   *   environment variables to set in the container. The key is the name of the
-   *   variable and the value is the variable's value.
-   * @property {Object.<string, string|Secret>} filepathToContent An object
-   *   of the files that should be created in the container. The key is the
-   *   path where the file should be created and the value is the desired
-   *   content of the file. For more details, see the description of the
-   *   `filepathToContent` constructor argument.
+   * @property {Object.<string, string|Secret|RuntimeValue>} env An object
+   *   containing the environment variables to set in the container. The key
+   *   is the name of the variable and the value is the variable's value.
+   * @property {Object.<string, string|Secret|RuntimeValue|Template>}
+   *   filepathToContent An object of the files that should be created in the
+   *   container. The key is the path where the file should be created and the
+   *   value is the desired content of the file. For more details, see the
+   *   description of the `filepathToContent` constructor argument.
    * @param {VolumeMount[]} volumeMounts - A list of volumes to mount
    *   within the container.
+   * @property {Container[]} initContainers The containers that are run before
+   *   the container is started.
    */
   constructor(args) {
     // refID is used to distinguish infrastructures with multiple references to the
@@ -1164,21 +1758,27 @@ class Container {
     this.command = getStringArray('command', args.command);
     this.env = getSecretOrStringMap('env', args.env);
     this.filepathToContent = getSecretOrStringMap('filepathToContent',
-      args.filepathToContent);
+      args.filepathToContent, true);
     this.privileged = getBoolean('privileged', args.privileged);
+    this.security = getSecurity(args.security);
 
     this.volumeMounts = args.volumeMounts || [];
     assertArrayOfType('VolumeMount', this.volumeMounts, VolumeMount);
 
+    this.initContainers = args.initContainers || [];
+    assertArrayOfType('Container', this.initContainers, Container);
+
     // Don't allow callers to modify the arguments by reference.
     this.command = _.clone(this.command);
     this.env = _.clone(this.env);
     this.filepathToContent = _.clone(this.filepathToContent);
     this.image = this.image.clone();
+    this.initContainers = _.clone(this.initContainers);
 
     checkExtraKeys(args, this);
 
     this.placements = [];
+    this.dependencies = [];
   }
 
   /**
@@ -1221,9 +1821,18 @@ class Container {
    * @param {string} [machineAttrs.region] - Region that the Container should be placed in.
    * @param {string} [machineAttrs.floatingIp] - Floating IP address that must be assigned to
    *   the machine that the Container gets placed on.
+   * @param {Object.<string, string>} [machineAttrs.labels] - Labels that the
+   *   machine the Container gets placed on must have, as set with the
+   *   `labels` option of {@link Machine}.
+   * @param {Object} [options] - Optional arguments.
+   * @param {number} [options.weight] - If set, the requirements are treated as
+   *   a preference rather than a requirement, and the Container is placed on
+   *   a machine that doesn't satisfy them if no machine does. Machines are
+   *   chosen by summing the weights of the preferences they satisfy. Must be
+   *   an integer between 1 and 100.
    * @returns {void}
    */
-  placeOn(machineAttrs) {
+  placeOn(machineAttrs, options) {
     this.placements.push({
       targetContainer: this.hostname,
       exclusive: false,
@@ -1231,9 +1840,64 @@ class Container {
       size: getString('size', machineAttrs.size),
       region: getString('region', machineAttrs.region),
       floatingIp: getString('floatingIp', machineAttrs.floatingIp),
+      labels: getMachineLabels('labels', machineAttrs.labels),
+      weight: getPlacementOptions(options, false).weight,
     });
   }
 
+  /**
+   * Places the Container in the same place as `other`. This is useful for
+   * containers that communicate often, such as an application and its cache.
+   *
+   * @param {Container|ContainerGroup} other - The container to place the
+   *   Container with.
+   * @param {Object} [options] - Optional arguments.
+   * @param {string} [options.topology=machine] - Either 'machine', to place
+   *   the Container on the same machine as `other`, or 'region', to place it
+   *   in the same region.
+   * @param {number} [options.weight] - If set, the placement is a preference
+   *   rather than a requirement, as for {@link Container#placeOn}.
+   * @returns {void}
+   */
+  placeWith(other, options) {
+    this.placements.push(makeContainerPlacement(this, other, false, options));
+  }
+
+  /**
+   * Places the Container away from `other`, so that they don't share a
+   * machine or region. The arguments are the same as for
+   * {@link Container#placeWith}.
+   *
+   * @param {Container|ContainerGroup} other - The container to place the
+   *   Container away from.
+   * @param {Object} [options] - Optional arguments.
+   * @returns {void}
+   */
+  placeAwayFrom(other, options) {
+    this.placements.push(makeContainerPlacement(this, other, true, options));
+  }
+
+  /**
+   * Delays starting the Container until `target` accepts connections on
+   * `port`, and allows traffic from the Container to `target` on that port.
+   * This is useful for applications that fail if the services they use, such
+   * as databases, aren't yet ready when they start. If the Container has init
+   * containers, they're run after the dependencies are ready.
+   *
+   * @example <caption>Start the application once the database is ready.
+   * </caption>
+   * const db = new Container({ name: 'db', image: 'postgres' });
+   * const app = new Container({ name: 'app', image: 'myapp' });
+   * app.dependsOn(db, 5432);
+   *
+   * @param {Connectable} target - The Connectable to wait for.
+   * @param {number} port - The port on which `target` must accept connections.
+   * @returns {void}
+   */
+  dependsOn(target, port) {
+    this.dependencies.push(makeDependency(this, target, port));
+  }
+
   /**
    * @private
    * @returns {string} the name of this Container for use in connections
@@ -1250,11 +1914,22 @@ class Container {
    */
   deploy(infrastructure) {
     infrastructure.containers.add(this);
-    this.volumeMounts.forEach((mount) => {
-      infrastructure.volumes.add(mount.volume);
+    this.getVolumes().forEach((volume) => {
+      infrastructure.volumes.add(volume);
     });
   }
 
+  /**
+   * @private
+   * @returns {Volume[]} The volumes mounted by the Container and its init
+   *   containers.
+   */
+  getVolumes() {
+    const mounts = this.initContainers.reduce(
+      (all, c) => all.concat(c.volumeMounts), this.volumeMounts);
+    return mounts.map(mount => mount.volume);
+  }
+
   /**
    * Converts the Container to the JSON format expected by the Kelda go code.
    * @private
@@ -1270,27 +1945,887 @@ class Container {
       filepathToContent: this.filepathToContent,
       hostname: this.hostname,
       privileged: this.privileged,
+      security: this.security,
       volumeMounts: this.volumeMounts.map(mount => mount.toKeldaRepresentation()),
+      initContainers: this.initContainers.map(representPodMember),
+      dependencies: this.dependencies,
     };
   }
 }
 
-class Secret {
+/**
+ * Returns the Kelda representation of a container that's run as part of
+ * another container's pod, such as an init container or sidecar. These
+ * containers don't have their own IDs.
+ * @private
+ *
+ * @param {Container} container - The container to convert.
+ * @returns {Object} The container's Kelda representation.
+ */
+function representPodMember(container) {
+  const rep = container.toKeldaRepresentation();
+  delete rep.id;
+  delete rep.initContainers;
+  delete rep.dependencies;
+  return rep;
+}
+
+/**
+ * Allows traffic from `src` to the dependency, and returns the dependency's
+ * Kelda representation.
+ * @private
+ *
+ * @param {Container|ContainerGroup} src - The dependent container.
+ * @param {Connectable} target - The Connectable that `src` depends on.
+ * @param {number} port - The port that `src` connects to.
+ * @returns {Object} The dependency's Kelda representation.
+ */
+function makeDependency(src, target, port) {
+  if (!Number.isInteger(port) || port <= 0 || port > 65535) {
+    throw new Error(`port must be a valid port number (was: ${stringify(port)})`);
+  }
+  if (!target || target === publicInternet || target instanceof DaemonSet ||
+    target instanceof StatefulSet || target instanceof ReplicaSet ||
+    !isConnectable(target)) {
+    throw new Error('dependencies must be a Container, ContainerGroup, or ' +
+      `LoadBalancer (was: ${stringify(target)})`);
+  }
+
+  allowTraffic(src, target, port);
+  return { hostname: target.getConnectableName(), port };
+}
+
+class ContainerGroup {
   /**
-   * Secret represents a secret to extract from the Vault secret
-   * store. The value is stored encrypted in a Vault instance running in the
-   * cluster. Only the value is considered secret -- names should not contain
-   * private information as they are expected to be saved in insecure locations
-   * such as user blueprints.
+   * Creates a new ContainerGroup, which represents a group of containers that
+   * are deployed together, similar to a Kubernetes pod. The containers in a
+   * group share a single IP address and hostname, can reach each other over
+   * localhost, and are always scheduled on the same machine. Groups are useful
+   * for running sidecars, such as log shippers and proxies, alongside an
+   * application.
    *
-   * A secret association is created by running the Kelda `secret` command. For
-   * example, running `kelda secret foo bar` creates a secret named `foo` that
-   * can be referenced using this type.
+   * Connections are made to and from the group as a whole, so the containers
+   * in a group should not be used in connections, or deployed on their own.
    *
-   * @param {string} name - The name of the Secret.
+   * @constructor
+   * @implements {Connectable}
+   *
+   * @example <caption>Run nginx with an Envoy proxy sidecar, and allow the
+   * public internet to connect to the proxy.</caption>
+   * const web = new ContainerGroup({
+   *   name: 'web',
+   *   containers: [
+   *     new Container({ name: 'nginx', image: 'nginx' }),
+   *     new Container({ name: 'envoy', image: 'envoyproxy/envoy' }),
+   *   ],
+   * });
+   * allowTraffic(publicInternet, web, 10000);
+   * web.deploy(infrastructure);
+   *
+   * @param {Object} args - Required arguments.
+   * @param {string} args.name - The prefix of the group's network hostname.
+   *   Hostnames are made unique in the same way as the hostnames of
+   *   Containers.
+   * @param {Container|Container[]} args.containers - The containers in the
+   *   group. The names of the containers are only used to identify them
+   *   within the group.
+   *
+   * The init containers of the containers in the group are run, in order,
+   * before any of the containers are started.
+   *
+   * We only document properties users should care about.
+   * @property {Container[]} containers The containers in the group.
    */
-  constructor(name) {
-    this.nameOfSecret = name;
+  constructor(args) {
+    // refID is used to distinguish infrastructures with multiple references
+    // to the same group, as for Containers.
+    this._refID = uniqueID();
+
+    checkRequiredArguments('ContainerGroup', args, ['name', 'containers']);
+
+    this.name = getString('name', args.name);
+    this.hostname = hostnameGenerator.getName(this.name);
+    validateHostname(this.hostname);
+
+    this.containers = boxObjects('ContainerGroup.containers', args.containers,
+      Container);
+    if (this.containers.length < 1) {
+      throw new Error('containers must include 1 or more Containers');
+    }
+    this.containers = _.clone(this.containers);
+
+    checkExtraKeys(args, this);
+
+    this.placements = [];
+    this.dependencies = [];
+  }
+
+  /**
+   * @returns {string} The group's hostname.
+   */
+  getHostname() {
+    return this.hostname;
+  }
+
+  /**
+   * @private
+   * @returns {string} A string describing all attributes of the group.
+   */
+  hash() {
+    return stringify({
+      hostname: this.hostname,
+      containers: this.containers.map(c => c.hash()),
+    });
+  }
+
+  /**
+   * Sets placement requirements for the Machine that the group is placed on.
+   * The arguments are the same as for {@link Container#placeOn}.
+   *
+   * @param {Object.<string, string>} machineAttrs - Requirements for the
+   *   machine the group gets placed on.
+   * @returns {void}
+   */
+  placeOn(machineAttrs, options) {
+    this.placements.push({
+      targetContainer: this.hostname,
+      exclusive: false,
+      provider: getString('provider', machineAttrs.provider),
+      size: getString('size', machineAttrs.size),
+      region: getString('region', machineAttrs.region),
+      floatingIp: getString('floatingIp', machineAttrs.floatingIp),
+      labels: getMachineLabels('labels', machineAttrs.labels),
+      weight: getPlacementOptions(options, false).weight,
+    });
+  }
+
+  /**
+   * Places the group in the same place as `other`. The arguments are the same
+   * as for {@link Container#placeWith}.
+   *
+   * @param {Container|ContainerGroup} other - The container to place the
+   *   group with.
+   * @param {Object} [options] - Optional arguments.
+   * @returns {void}
+   */
+  placeWith(other, options) {
+    this.placements.push(makeContainerPlacement(this, other, false, options));
+  }
+
+  /**
+   * Places the group away from `other`. The arguments are the same as for
+   * {@link Container#placeWith}.
+   *
+   * @param {Container|ContainerGroup} other - The container to place the
+   *   group away from.
+   * @param {Object} [options] - Optional arguments.
+   * @returns {void}
+   */
+  placeAwayFrom(other, options) {
+    this.placements.push(makeContainerPlacement(this, other, true, options));
+  }
+
+  /**
+   * Delays starting the containers in the group until `target` accepts
+   * connections on `port`. The arguments are the same as for
+   * {@link Container#dependsOn}.
+   *
+   * @param {Connectable} target - The Connectable to wait for.
+   * @param {number} port - The port on which `target` must accept connections.
+   * @returns {void}
+   */
+  dependsOn(target, port) {
+    this.dependencies.push(makeDependency(this, target, port));
+  }
+
+  /**
+   * @private
+   * @returns {string} the name of this ContainerGroup for use in connections
+   */
+  getConnectableName() {
+    return this.hostname;
+  }
+
+  /**
+   * Adds this group to be deployed as part of the given infrastructure.
+   *
+   * @param {Infrastructure} infrastructure - The infrastructure that this
+   *   should be added to.
+   * @returns {void}
+   */
+  deploy(infrastructure) {
+    infrastructure.containers.add(this);
+    this.getVolumes().forEach((volume) => {
+      infrastructure.volumes.add(volume);
+    });
+  }
+
+  /**
+   * @private
+   * @returns {Volume[]} The volumes mounted by the containers in the group.
+   */
+  getVolumes() {
+    return this.containers.reduce((all, c) => all.concat(c.getVolumes()), []);
+  }
+
+  /**
+   * Converts the ContainerGroup to the JSON format expected by the Kelda go
+   * code. The first container in the group takes on the group's hostname,
+   * and the others are its sidecars.
+   * @private
+   * @returns {Object} A map that can be converted to JSON and interpreted by
+   *   the Kelda Go code.
+   */
+  toKeldaRepresentation() {
+    const [first, ...sidecars] = this.containers;
+    return Object.assign(first.toKeldaRepresentation(), {
+      id: this.id,
+      hostname: this.hostname,
+      sidecars: sidecars.map(representPodMember),
+      initContainers: this.containers.reduce((all, c) =>
+        all.concat(c.initContainers.map(representPodMember)), []),
+      dependencies: this.dependencies,
+    });
+  }
+}
+
+class DaemonSet {
+  /**
+   * Creates a new DaemonSet, which runs an instance of a container on every
+   * worker machine. DaemonSets are useful for monitoring agents and
+   * machine-local caches. Instances are added and removed automatically as
+   * workers boot and are stopped.
+   *
+   * Each instance has its own IP address, and a hostname made up of the
+   * DaemonSet's hostname and the machine's private IP address, e.g.
+   * `agent.10-0-1-5`. Connections that reference the DaemonSet apply to all
+   * of its instances. Placement rules limit which workers the DaemonSet runs
+   * on.
+   *
+   * @constructor
+   * @implements {Connectable}
+   *
+   * @example <caption>Run a monitoring agent on every worker, and allow it to
+   * report to a collector.</caption>
+   * const agent = new DaemonSet({
+   *   name: 'agent',
+   *   container: new Container({ name: 'agent', image: 'prom/node-exporter' }),
+   * });
+   * allowTraffic(agent, collector, 9091);
+   * agent.deploy(infrastructure);
+   *
+   * @param {Object} args - Required arguments.
+   * @param {string} args.name - The prefix of the DaemonSet's hostname.
+   *   Hostnames are made unique in the same way as the hostnames of
+   *   Containers.
+   * @param {Container|ContainerGroup} args.container - The container to run
+   *   on each worker. Its name is not used.
+   *
+   * We only document properties users should care about.
+   * @property {Container|ContainerGroup} container The container run on each
+   *   worker.
+   */
+  constructor(args) {
+    // refID is used to distinguish infrastructures with multiple references
+    // to the same DaemonSet, as for Containers.
+    this._refID = uniqueID();
+
+    checkRequiredArguments('DaemonSet', args, ['name', 'container']);
+
+    this.name = getString('name', args.name);
+    this.hostname = hostnameGenerator.getName(this.name);
+    validateHostname(this.hostname);
+
+    this.container = args.container;
+    if (!(this.container instanceof Container) &&
+      !(this.container instanceof ContainerGroup)) {
+      throw new Error('container must be a Container or ContainerGroup ' +
+        `(was ${stringify(this.container)})`);
+    }
+
+    checkExtraKeys(args, this);
+
+    this.placements = [];
+    this.dependencies = [];
+  }
+
+  /**
+   * @returns {string} The DaemonSet's hostname.
+   */
+  getHostname() {
+    return this.hostname;
+  }
+
+  /**
+   * @private
+   * @returns {string} A string describing all attributes of the DaemonSet.
+   */
+  hash() {
+    return stringify({
+      hostname: this.hostname,
+      container: this.container.hash(),
+    });
+  }
+
+  /**
+   * Limits the workers that the DaemonSet runs on. The arguments are the same
+   * as for {@link Container#placeOn}.
+   *
+   * @param {Object.<string, string>} machineAttrs - Requirements for the
+   *   machines the DaemonSet runs on.
+   * @returns {void}
+   */
+  placeOn(machineAttrs, options) {
+    this.placements.push({
+      targetContainer: this.hostname,
+      exclusive: false,
+      provider: getString('provider', machineAttrs.provider),
+      size: getString('size', machineAttrs.size),
+      region: getString('region', machineAttrs.region),
+      floatingIp: getString('floatingIp', machineAttrs.floatingIp),
+      labels: getMachineLabels('labels', machineAttrs.labels),
+      weight: getPlacementOptions(options, false).weight,
+    });
+  }
+
+  /**
+   * Delays starting each instance until `target` accepts connections on
+   * `port`. The arguments are the same as for {@link Container#dependsOn}.
+   *
+   * @param {Connectable} target - The Connectable to wait for.
+   * @param {number} port - The port on which `target` must accept connections.
+   * @returns {void}
+   */
+  dependsOn(target, port) {
+    this.dependencies.push(makeDependency(this, target, port));
+  }
+
+  /**
+   * @private
+   * @returns {string} the name of this DaemonSet for use in connections
+   */
+  getConnectableName() {
+    return this.hostname;
+  }
+
+  /**
+   * Adds this DaemonSet to be deployed as part of the given infrastructure.
+   *
+   * @param {Infrastructure} infrastructure - The infrastructure that this
+   *   should be added to.
+   * @returns {void}
+   */
+  deploy(infrastructure) {
+    infrastructure.containers.add(this);
+    this.container.getVolumes().forEach((volume) => {
+      infrastructure.volumes.add(volume);
+    });
+  }
+
+  /**
+   * Converts the DaemonSet to the JSON format expected by the Kelda go code.
+   * @private
+   * @returns {Object} A map that can be converted to JSON and interpreted by
+   *   the Kelda Go code.
+   */
+  toKeldaRepresentation() {
+    return Object.assign(this.container.toKeldaRepresentation(), {
+      id: this.id,
+      hostname: this.hostname,
+      dependencies: this.dependencies,
+      daemon: true,
+    });
+  }
+}
+
+class StatefulSet {
+  /**
+   * Creates a new StatefulSet, which runs a fixed number of members of a
+   * container with stable identities. StatefulSets are useful for clustered
+   * databases, such as ZooKeeper, etcd, and Elasticsearch, whose members must
+   * be able to find each other and keep their data across restarts.
+   *
+   * Member `i` has the hostname `<name>-<i>`, e.g. `zk-0`, and its own IP
+   * address. Each member is pinned to a worker, and mounts its own
+   * subdirectory of each hostPath volume, so its data is still there when it's
+   * restarted. Members are started in order, each once the previous members
+   * are ready. When the container changes, members are restarted one at a
+   * time, starting from the highest. Connections that reference the
+   * StatefulSet apply to all of its members. If a member's machine is removed,
+   * the member is moved to another worker, and starts with empty storage.
+   *
+   * @constructor
+   * @implements {Connectable}
+   *
+   * @example <caption>Run a three member ZooKeeper cluster.</caption>
+   * const data = new Volume({ name: 'zk', type: 'hostPath', path: '/var/lib/zk' });
+   * const zk = new StatefulSet({
+   *   name: 'zk',
+   *   replicas: 3,
+   *   container: new Container({
+   *     name: 'zk',
+   *     image: 'zookeeper',
+   *     volumeMounts: [new VolumeMount({ volume: data, mountPath: '/data' })],
+   *   }),
+   * });
+   * zk.container.env.ZOO_SERVERS = zk.getHostnames()
+   *   .map((host, i) => `server.${i + 1}=${host}:2888:3888`).join(' ');
+   * allowTraffic(zk, zk, new PortRange(2888, 3888));
+   * zk.deploy(infrastructure);
+   *
+   * @param {Object} args - Required arguments.
+   * @param {string} args.name - The prefix of the hostnames of the members.
+   *   It's made unique in the same way as the hostnames of Containers.
+   * @param {number} args.replicas - The number of members.
+   * @param {Container|ContainerGroup} args.container - The container run by
+   *   each member. Its name is not used.
+   *
+   * We only document properties users should care about.
+   * @property {Container|ContainerGroup} container The container run by each
+   *   member.
+   * @property {number} replicas The number of members.
+   */
+  constructor(args) {
+    // refID is used to distinguish infrastructures with multiple references
+    // to the same StatefulSet, as for Containers.
+    this._refID = uniqueID();
+
+    checkRequiredArguments('StatefulSet', args,
+      ['name', 'replicas', 'container']);
+
+    this.name = getString('name', args.name);
+    this.hostname = hostnameGenerator.getName(this.name);
+    validateHostname(this.hostname);
+
+    this.replicas = args.replicas;
+    if (!Number.isInteger(this.replicas) || this.replicas < 1) {
+      throw new Error('replicas must be a positive integer ' +
+        `(was ${stringify(this.replicas)})`);
+    }
+
+    this.container = args.container;
+    if (!(this.container instanceof Container) &&
+      !(this.container instanceof ContainerGroup)) {
+      throw new Error('container must be a Container or ContainerGroup ' +
+        `(was ${stringify(this.container)})`);
+    }
+
+    checkExtraKeys(args, this);
+
+    this.placements = [];
+    this.dependencies = [];
+  }
+
+  /**
+   * @returns {string[]} The hostnames of the members, in order.
+   */
+  getHostnames() {
+    return _.range(this.replicas).map(i => `${this.hostname}-${i}`);
+  }
+
+  /**
+   * @private
+   * @returns {string} A string describing all attributes of the StatefulSet.
+   */
+  hash() {
+    return stringify({
+      hostname: this.hostname,
+      replicas: this.replicas,
+      container: this.container.hash(),
+    });
+  }
+
+  /**
+   * Limits the workers that the members run on. The arguments are the same as
+   * for {@link Container#placeOn}.
+   *
+   * @param {Object.<string, string>} machineAttrs - Requirements for the
+   *   machines the members run on.
+   * @returns {void}
+   */
+  placeOn(machineAttrs, options) {
+    this.placements.push({
+      targetContainer: this.hostname,
+      exclusive: false,
+      provider: getString('provider', machineAttrs.provider),
+      size: getString('size', machineAttrs.size),
+      region: getString('region', machineAttrs.region),
+      floatingIp: getString('floatingIp', machineAttrs.floatingIp),
+      labels: getMachineLabels('labels', machineAttrs.labels),
+      weight: getPlacementOptions(options, false).weight,
+    });
+  }
+
+  /**
+   * Delays starting each member until `target` accepts connections on `port`.
+   * The arguments are the same as for {@link Container#dependsOn}.
+   *
+   * @param {Connectable} target - The Connectable to wait for.
+   * @param {number} port - The port on which `target` must accept connections.
+   * @returns {void}
+   */
+  dependsOn(target, port) {
+    this.dependencies.push(makeDependency(this, target, port));
+  }
+
+  /**
+   * @private
+   * @returns {string} the name of this StatefulSet for use in connections
+   */
+  getConnectableName() {
+    return this.hostname;
+  }
+
+  /**
+   * Adds this StatefulSet to be deployed as part of the given infrastructure.
+   *
+   * @param {Infrastructure} infrastructure - The infrastructure that this
+   *   should be added to.
+   * @returns {void}
+   */
+  deploy(infrastructure) {
+    infrastructure.containers.add(this);
+    this.container.getVolumes().forEach((volume) => {
+      infrastructure.volumes.add(volume);
+    });
+  }
+
+  /**
+   * Converts the StatefulSet to the JSON format expected by the Kelda go code.
+   * @private
+   * @returns {Object} A map that can be converted to JSON and interpreted by
+   *   the Kelda Go code.
+   */
+  toKeldaRepresentation() {
+    return Object.assign(this.container.toKeldaRepresentation(), {
+      id: this.id,
+      hostname: this.hostname,
+      dependencies: this.dependencies,
+      stateful: true,
+      replicas: this.replicas,
+    });
+  }
+}
+
+class ReplicaSet {
+  /**
+   * Creates a new ReplicaSet, which runs interchangeable replicas of a
+   * container, and adjusts the number of replicas so that their average load
+   * stays near a target. ReplicaSets are useful for stateless services, such
+   * as web servers and queue workers, whose load varies over time.
+   *
+   * The load is measured either by the CPU usage of each replica, as a
+   * percentage of a single core, or by a number that each replica serves over
+   * HTTP, such as the length of its work queue. Kelda adds replicas when the
+   * average is above the target, and removes them when it's below, within the
+   * set's bounds. After each change, Kelda waits for a cooldown before
+   * changing the set again, so that the new replicas have time to take load.
+   *
+   * Replica `i` has the hostname `<name>-<i>`, e.g. `web-0`, and its own IP
+   * address. Connections, load balancers, and placement rules that reference
+   * the ReplicaSet apply to all of its replicas, including those added later.
+   * Put a {@link LoadBalancer} in front of the ReplicaSet so that other
+   * containers can reach whichever replicas are running.
+   *
+   * @constructor
+   * @implements {Connectable}
+   *
+   * @example <caption>Run between two and ten web servers, each at about half
+   * of a core.</caption>
+   * const web = new ReplicaSet({
+   *   name: 'web',
+   *   container: new Container({ name: 'web', image: 'nginx' }),
+   *   min: 2,
+   *   max: 10,
+   *   target: 50,
+   * });
+   * const lb = new LoadBalancer({ name: 'web-lb', containers: web });
+   * allowTraffic(publicInternet, lb, 80);
+   * web.deploy(infrastructure);
+   * lb.deploy(infrastructure);
+   *
+   * @example <caption>Scale queue workers on the length of their queues,
+   * served at http://<replica>:9000/queue.</caption>
+   * const workers = new ReplicaSet({
+   *   name: 'worker',
+   *   container: new Container({ name: 'worker', image: 'myapp/worker' }),
+   *   min: 1,
+   *   max: 20,
+   *   metric: 'http',
+   *   target: 100,
+   *   port: 9000,
+   *   path: '/queue',
+   * });
+   *
+   * @param {Object} args - All required and optional arguments.
+   * @param {string} args.name - The prefix of the hostnames of the replicas.
+   *   It's made unique in the same way as the hostnames of Containers.
+   * @param {Container|ContainerGroup} args.container - The container run by
+   *   each replica. Its name is not used.
+   * @param {number} args.min - The minimum number of replicas. Must be at
+   *   least 1.
+   * @param {number} args.max - The maximum number of replicas.
+   * @param {number} args.target - The average value of the metric across the
+   *   replicas that Kelda tries to maintain.
+   * @param {string} [args.metric=cpu] - Either 'cpu', for the CPU usage of
+   *   each replica as a percentage of a single core, or 'http', for a number
+   *   served by each replica over HTTP. The body of the HTTP response must
+   *   contain only the number.
+   * @param {number} [args.port] - The port of the HTTP endpoint. Required if
+   *   the metric is 'http'.
+   * @param {string} [args.path=/] - The path of the HTTP endpoint.
+   * @param {number} [args.scaleUpCooldown] - The minimum number of seconds
+   *   between changing the number of replicas and adding replicas. Defaults
+   *   to three minutes.
+   * @param {number} [args.scaleDownCooldown] - The minimum number of seconds
+   *   between changing the number of replicas and removing replicas. Defaults
+   *   to five minutes.
+   *
+   * We only document properties users should care about.
+   * @property {Container|ContainerGroup} container The container run by each
+   *   replica.
+   */
+  constructor(args) {
+    // refID is used to distinguish infrastructures with multiple references
+    // to the same ReplicaSet, as for Containers.
+    this._refID = uniqueID();
+
+    checkRequiredArguments('ReplicaSet', args,
+      ['name', 'container', 'min', 'max', 'target']);
+
+    this.name = getString('name', args.name);
+    this.hostname = hostnameGenerator.getName(this.name);
+    validateHostname(this.hostname);
+
+    this.container = args.container;
+    if (!(this.container instanceof Container) &&
+      !(this.container instanceof ContainerGroup)) {
+      throw new Error('container must be a Container or ContainerGroup ' +
+        `(was ${stringify(this.container)})`);
+    }
+
+    this.min = getNumber('min', args.min);
+    this.max = getNumber('max', args.max);
+    if (!Number.isInteger(this.min) || !Number.isInteger(this.max) ||
+      this.min < 1 || this.min > this.max) {
+      throw new Error('the minimum number of replicas must be at least 1, ' +
+        'and the maximum must be at least the minimum ' +
+        `(was: min ${this.min}, max ${this.max})`);
+    }
+
+    this.metric = getString('metric', args.metric) || 'cpu';
+    if (this.metric !== 'cpu' && this.metric !== 'http') {
+      throw new Error('metric must be either "cpu" or "http" ' +
+        `(was: ${stringify(this.metric)})`);
+    }
+
+    this.target = getNumber('target', args.target);
+    if (this.target <= 0) {
+      throw new Error(`target must be positive (was: ${this.target})`);
+    }
+
+    this.port = getNumber('port', args.port);
+    this.path = getString('path', args.path);
+    if (this.metric === 'http') {
+      if (!Number.isInteger(this.port) || this.port <= 0 || this.port > 65535) {
+        throw new Error('port must be a valid port number when the metric is ' +
+          `"http" (was: ${stringify(args.port)})`);
+      }
+      if (this.path !== '' && !this.path.startsWith('/')) {
+        throw new Error(`path must start with a slash (was: ${this.path})`);
+      }
+    } else if (args.port !== undefined || args.path !== undefined) {
+      throw new Error('port and path can only be set when the metric is "http"');
+    }
+
+    this.scaleUpCooldown = getNumber('scaleUpCooldown', args.scaleUpCooldown);
+    this.scaleDownCooldown = getNumber('scaleDownCooldown',
+      args.scaleDownCooldown);
+    if (this.scaleUpCooldown < 0 || this.scaleDownCooldown < 0) {
+      throw new Error('cooldowns must not be negative');
+    }
+
+    checkExtraKeys(args, this);
+
+    this.placements = [];
+    this.dependencies = [];
+  }
+
+  /**
+   * @returns {string} The ReplicaSet's hostname.
+   */
+  getHostname() {
+    return this.hostname;
+  }
+
+  /**
+   * The autoscaling settings are left out, so that tuning them doesn't restart
+   * the replicas.
+   * @private
+   * @returns {string} A string describing the attributes of the ReplicaSet
+   *   that affect its replicas.
+   */
+  hash() {
+    return stringify({
+      hostname: this.hostname,
+      container: this.container.hash(),
+    });
+  }
+
+  /**
+   * Limits the workers that the replicas run on. The arguments are the same as
+   * for {@link Container#placeOn}.
+   *
+   * @param {Object.<string, string>} machineAttrs - Requirements for the
+   *   machines the replicas run on.
+   * @returns {void}
+   */
+  placeOn(machineAttrs, options) {
+    this.placements.push({
+      targetContainer: this.hostname,
+      exclusive: false,
+      provider: getString('provider', machineAttrs.provider),
+      size: getString('size', machineAttrs.size),
+      region: getString('region', machineAttrs.region),
+      floatingIp: getString('floatingIp', machineAttrs.floatingIp),
+      labels: getMachineLabels('labels', machineAttrs.labels),
+      weight: getPlacementOptions(options, false).weight,
+    });
+  }
+
+  /**
+   * Delays starting each replica until `target` accepts connections on
+   * `port`. The arguments are the same as for {@link Container#dependsOn}.
+   *
+   * @param {Connectable} target - The Connectable to wait for.
+   * @param {number} port - The port on which `target` must accept connections.
+   * @returns {void}
+   */
+  dependsOn(target, port) {
+    this.dependencies.push(makeDependency(this, target, port));
+  }
+
+  /**
+   * @private
+   * @returns {string} the name of this ReplicaSet for use in connections
+   */
+  getConnectableName() {
+    return this.hostname;
+  }
+
+  /**
+   * Adds this ReplicaSet to be deployed as part of the given infrastructure.
+   *
+   * @param {Infrastructure} infrastructure - The infrastructure that this
+   *   should be added to.
+   * @returns {void}
+   */
+  deploy(infrastructure) {
+    infrastructure.containers.add(this);
+    this.container.getVolumes().forEach((volume) => {
+      infrastructure.volumes.add(volume);
+    });
+  }
+
+  /**
+   * Converts the ReplicaSet to the JSON format expected by the Kelda go code.
+   * @private
+   * @returns {Object} A map that can be converted to JSON and interpreted by
+   *   the Kelda Go code.
+   */
+  toKeldaRepresentation() {
+    return Object.assign(this.container.toKeldaRepresentation(), {
+      id: this.id,
+      hostname: this.hostname,
+      dependencies: this.dependencies,
+      autoscale: {
+        min: this.min,
+        max: this.max,
+        metric: this.metric,
+        target: this.target,
+        port: this.port,
+        path: this.path,
+        scaleUpCooldown: this.scaleUpCooldown,
+        scaleDownCooldown: this.scaleDownCooldown,
+      },
+    });
+  }
+}
+
+class Secret {
+  /**
+   * Secret represents a secret to extract from the infrastructure's secret
+   * backend. By default, the value is stored as a Kubernetes secret in the
+   * cluster. Only the value is considered secret -- names should not contain
+   * private information as they are expected to be saved in insecure locations
+   * such as user blueprints.
+   *
+   * With the default backend, a secret association is created by running the
+   * Kelda `secret` command. For example, running `kelda secret foo bar`
+   * creates a secret named `foo` that can be referenced using this type. When
+   * the infrastructure's `secretBackend` is Vault, the secret is read from
+   * Vault instead.
+   *
+   * @param {string} name - The name of the Secret.
+   */
+  constructor(name) {
+    this.nameOfSecret = name;
+  }
+}
+
+class Template {
+  /**
+   * Template represents a file whose contents are rendered by Kelda from a Go
+   * text/template (https://golang.org/pkg/text/template/). Templates can only
+   * be used in `filepathToContent`. In addition to the builtin functions,
+   * templates can call:
+   *   - `ip "hostname"`: The IP address of the container or load balancer
+   *     with the given hostname.
+   *   - `members "name"`: The containers behind the load balancer with the
+   *     given name, sorted by hostname. Each has a `Hostname` and an `IP`.
+   *   - `secret "name"`: The value of the secret with the given name. The
+   *     name must be a string literal.
+   * `.Hostname` is the hostname of the container that the file is rendered
+   * for.
+   *
+   * @example <caption>Generate an HAProxy configuration that lists the
+   * containers behind a load balancer.</caption>
+   * const config = new Template(`backend web
+   * {{range members "web"}}  server {{.Hostname}} {{.IP}}:80
+   * {{end}}`);
+   *
+   * @param {string} text - The text of the template.
+   */
+  constructor(text) {
+    this.text = getString('text', text);
+    if (this.text === '') {
+      throw new Error('Template text must not be empty');
+    }
+  }
+}
+
+const runtimeValueKeys = ['containerIP', 'host.privateIP', 'host.provider',
+  'host.region', 'namespace'];
+
+class RuntimeValue {
+  /**
+   * RuntimeValue represents a value that isn't known until the container is
+   * deployed. It can be used as the value of an environment variable or file.
+   * The value is resolved by Kelda when the container is started.
+   *
+   * @param {string} resourceKey - The value to reference. Either
+   *   'containerIP' (the container's IP address), 'host.privateIP' (the
+   *   private IP address of the machine running the container),
+   *   'host.provider' or 'host.region' (the provider or region of the
+   *   machine running the container), or 'namespace' (the namespace of the
+   *   blueprint).
+   */
+  constructor(resourceKey) {
+    if (!runtimeValueKeys.includes(resourceKey)) {
+      throw new Error(`RuntimeValue must be one of ` +
+        `${stringify(runtimeValueKeys)} (was: ${stringify(resourceKey)})`);
+    }
+    this.resourceKey = resourceKey;
   }
 }
 
@@ -1599,13 +3134,20 @@ function resetGlobals() {
 
 module.exports = {
   Container,
+  ContainerGroup,
+  DaemonSet,
   Infrastructure,
   Image,
   Machine,
+  MachinePool,
   Port,
   PortRange,
   Range,
+  ReplicaSet,
+  RuntimeValue,
   Secret,
+  StatefulSet,
+  Template,
   LoadBalancer,
   Volume,
   VolumeMount,
@@ -1614,6 +3156,7 @@ module.exports = {
   githubKeys,
   publicInternet,
   resetGlobals,
+  spread,
   baseInfraLocation,
   baseInfrastructure,
 };
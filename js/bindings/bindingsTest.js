@@ -3,6 +3,7 @@
 /* eslint-disable import/no-extraneous-dependencies, no-underscore-dangle, require-jsdoc */
 const chai = require('chai');
 const chaiSubset = require('chai-subset');
+const path = require('path');
 const rewire = require('rewire');
 const sinon = require('sinon');
 
@@ -438,6 +439,33 @@ describe('Bindings', () => {
         preemptible: true,
       }]);
     });
+    it('labels', () => {
+      const machine = new b.Machine({
+        provider: 'Amazon',
+        labels: { tier: 'db', disk_type: 'ssd' },
+      });
+      const [replica] = machine.replicate(1);
+      replica.labels.tier = 'web';
+      infra = new b.Infrastructure({ masters: machine, workers: replica });
+      checkMachines([
+        {
+          role: 'Master',
+          labels: { tier: 'db', disk_type: 'ssd' },
+        },
+        {
+          role: 'Worker',
+          labels: { tier: 'web', disk_type: 'ssd' },
+        },
+      ]);
+    });
+    it('errors on invalid labels', () => {
+      expect(() => new b.Machine({ provider: 'Amazon', labels: ['db'] }))
+        .to.throw('labels must be a map (was: ["db"])');
+      expect(() => new b.Machine({ provider: 'Amazon', labels: { Tier: 'db' } }))
+        .to.throw('labels has an invalid key "Tier"');
+      expect(() => new b.Machine({ provider: 'Amazon', labels: { tier: 'd b' } }))
+        .to.throw('labels has an invalid value "d b" for tier');
+    });
   });
 
   describe('Image', () => {
@@ -466,6 +494,37 @@ describe('Bindings', () => {
       expect(() => new b.Image({ dockerfile: 'dockerfile' })).to
         .throw("missing required attribute: Image requires 'name'");
     });
+    it('build options', () => {
+      const image = new b.Image({
+        name: 'image',
+        dockerfile: 'dockerfile',
+        contextDir: '/app',
+        buildArgs: { VERSION: '1.0' },
+        target: 'release',
+      });
+      expect(image).to.eql({
+        name: 'image',
+        dockerfile: 'dockerfile',
+        contextDir: '/app',
+        buildArgs: { VERSION: '1.0' },
+        target: 'release',
+      });
+      expect(image.clone()).to.eql(image);
+    });
+    it('resolves relative context directories', () => {
+      const image = new b.Image({
+        name: 'image', dockerfile: 'dockerfile', contextDir: 'app',
+      });
+      expect(image.contextDir).to.equal(path.resolve('app'));
+    });
+    it('errors when passed invalid build options', () => {
+      expect(() => new b.Image({
+        name: 'image', dockerfile: 'dockerfile', buildArgs: { VERSION: 1 },
+      })).to.throw('buildArgs must be a map with string values (value 1 ' +
+        'associated with VERSION is not a string)');
+      expect(() => new b.Image({ name: 'image', target: 'release' })).to
+        .throw('contextDir, buildArgs, and target require a dockerfile');
+    });
   });
 
   describe('Container', () => {
@@ -674,6 +733,92 @@ describe('Bindings', () => {
       }]);
     });
 
+    it('security', () => {
+      const container = new b.Container({
+        name: hostname,
+        image,
+        security: {
+          runAsUser: 1000,
+          runAsGroup: 0,
+          readOnlyRootFilesystem: true,
+          capDrop: ['ALL'],
+          sysctls: { 'net.core.somaxconn': '1024' },
+          seccompProfile: 'runtime/default',
+        },
+      });
+      container.deploy(infra);
+      const { containers } = infra.toKeldaRepresentation();
+      expect(containers[0].security).to.deep.equal({
+        runAsUser: 1000,
+        runAsGroup: 0,
+        readOnlyRootFilesystem: true,
+        capDrop: ['ALL'],
+        sysctls: { 'net.core.somaxconn': '1024' },
+        seccompProfile: 'runtime/default',
+      });
+      expect(container.clone().security).to.deep.equal(container.security);
+    });
+
+    it('invalid security settings', () => {
+      expect(() => new b.Container({
+        name: hostname, image, security: { runAsUser: -1 } })).to.throw(
+        'security.runAsUser must be a non-negative integer (was: -1)');
+      expect(() => new b.Container({
+        name: hostname, image, security: { capAdd: 'NET_ADMIN' } })).to.throw(
+        'security.capAdd must be an array of strings');
+      expect(() => new b.Container({
+        name: hostname, image, security: { runAsRoot: true } })).to.throw(
+        'Unrecognized keys passed to Object constructor: runAsRoot');
+    });
+
+    it('runtime values', () => {
+      const container = new b.Container({
+        name: hostname,
+        image,
+        env: { IP: new b.RuntimeValue('containerIP') },
+        filepathToContent: { '/region': new b.RuntimeValue('host.region') },
+      });
+      container.deploy(infra);
+      checkContainers([{
+        hostname,
+        env: { IP: { resourceKey: 'containerIP' } },
+        filepathToContent: { '/region': { resourceKey: 'host.region' } },
+      }]);
+    });
+
+    it('templates', () => {
+      const container = new b.Container({
+        name: hostname,
+        image,
+        filepathToContent: { '/etc/db': new b.Template('{{ip "db"}}') },
+      });
+      container.deploy(infra);
+      checkContainers([{
+        hostname,
+        filepathToContent: { '/etc/db': { text: '{{ip "db"}}' } },
+      }]);
+    });
+
+    it('invalid templates', () => {
+      expect(() => new b.Template('')).to.throw(
+        'Template text must not be empty');
+      expect(() => new b.Template(1)).to.throw(
+        'text must be a string (was: 1)');
+      expect(() => new b.Container({
+        name: hostname, image, env: { DB: new b.Template('{{ip "db"}}') },
+      })).to.throw('env cannot contain Templates (value associated with DB ' +
+        'is a Template)');
+    });
+
+    it('invalid runtime values', () => {
+      expect(() => new b.RuntimeValue('host.size')).to.throw(
+        'RuntimeValue must be one of');
+      expect(() => new b.Container({
+        name: hostname, image, env: { IP: { resourceKey: 'containerIP' } },
+      })).to.throw('env must be a map with string, Secret, or RuntimeValue ' +
+        'values');
+    });
+
     it('privileged should be false by default', () => {
       const container = new b.Container({
         name: hostname,
@@ -688,6 +833,460 @@ describe('Bindings', () => {
     });
   });
 
+  describe('ContainerGroup', () => {
+    beforeEach(createBasicInfra);
+    it('basic', () => {
+      const volume = new b.Volume({
+        name: 'logs', type: 'hostPath', path: '/var/log' });
+      const group = new b.ContainerGroup({
+        name: 'web',
+        containers: [
+          new b.Container({ name: 'nginx', image: 'nginx', command: ['run'] }),
+          new b.Container({
+            name: 'envoy',
+            image: 'envoy',
+            env: { key: 'val' },
+            volumeMounts: [new b.VolumeMount({ volume, mountPath: '/logs' })],
+          }),
+        ],
+      });
+      group.deploy(infra);
+
+      const { containers, volumes } = infra.toKeldaRepresentation();
+      expect(containers).to.have.lengthOf(1);
+      expect(containers[0]).to.containSubset({
+        hostname: 'web',
+        image: new b.Image({ name: 'nginx' }),
+        command: ['run'],
+        sidecars: [{
+          hostname: 'envoy',
+          image: new b.Image({ name: 'envoy' }),
+          env: { key: 'val' },
+          volumeMounts: [{ volumeName: 'logs', mountPath: '/logs' }],
+        }],
+      });
+      expect(containers[0].id).to.be.a('string');
+      expect(containers[0].sidecars[0]).to.not.have.property('id');
+      expect(volumes).to.have.lengthOf(1);
+    });
+    it('single container', () => {
+      const group = new b.ContainerGroup({
+        name: 'web',
+        containers: new b.Container({ name: 'nginx', image: 'nginx' }),
+      });
+      group.deploy(infra);
+      checkContainers([{ hostname: 'web', sidecars: [] }]);
+    });
+    it('connections target the group', () => {
+      const group = new b.ContainerGroup({
+        name: 'web',
+        containers: [new b.Container({ name: 'nginx', image: 'nginx' })],
+      });
+      group.deploy(infra);
+      b.allowTraffic(b.publicInternet, group, 80);
+      checkConnections([{ from: ['public'], to: ['web'], minPort: 80, maxPort: 80 }]);
+    });
+    it('placements target the group', () => {
+      const group = new b.ContainerGroup({
+        name: 'web',
+        containers: [new b.Container({ name: 'nginx', image: 'nginx' })],
+      });
+      group.placeOn({ size: 'm4.large' });
+      group.deploy(infra);
+      checkPlacements([{ targetContainer: 'web', size: 'm4.large' }]);
+    });
+    it('errors when given invalid arguments', () => {
+      expect(() => new b.ContainerGroup({ name: 'web' })).to
+        .throw("missing required attribute: ContainerGroup requires 'containers'");
+      expect(() => new b.ContainerGroup({ name: 'web', containers: [] })).to
+        .throw('containers must include 1 or more Containers');
+      expect(() => new b.ContainerGroup({ name: 'web', containers: ['nginx'] })).to
+        .throw('ContainerGroup.containers is not an array of Containers');
+      expect(() => new b.ContainerGroup({
+        name: 'web',
+        containers: [new b.Container({ name: 'nginx', image: 'nginx' })],
+        badArg: 'foo',
+      })).to.throw('Unrecognized keys passed to ContainerGroup constructor: badArg');
+    });
+    it('errors when a member is used in a connection', () => {
+      const nginx = new b.Container({ name: 'nginx', image: 'nginx' });
+      const group = new b.ContainerGroup({ name: 'web', containers: [nginx] });
+      group.deploy(infra);
+      b.allowTraffic(b.publicInternet, nginx, 80);
+      expect(() => infra.toKeldaRepresentation()).to.throw(
+        'references an undefined hostname: nginx');
+    });
+    it('vets the images of sidecars', () => {
+      (new b.Container({
+        name: 'host',
+        image: new b.Image({ name: 'img', dockerfile: 'dk' }),
+      })).deploy(infra);
+      (new b.ContainerGroup({
+        name: 'group',
+        containers: [
+          new b.Container({ name: 'main', image: 'nginx' }),
+          new b.Container({
+            name: 'sidecar',
+            image: new b.Image({ name: 'img', dockerfile: 'dk2' }),
+          }),
+        ],
+      })).deploy(infra);
+      expect(() => infra.toKeldaRepresentation()).to.throw(
+        'img has differing Dockerfiles');
+    });
+  });
+
+  describe('Init containers and dependencies', () => {
+    beforeEach(createBasicInfra);
+    it('init containers', () => {
+      const volume = new b.Volume({
+        name: 'data', type: 'hostPath', path: '/data' });
+      const app = new b.Container({
+        name: 'app',
+        image: 'app',
+        initContainers: [new b.Container({
+          name: 'migrate',
+          image: 'app',
+          command: ['migrate'],
+          volumeMounts: [new b.VolumeMount({ volume, mountPath: '/data' })],
+        })],
+      });
+      app.deploy(infra);
+
+      const { containers, volumes } = infra.toKeldaRepresentation();
+      expect(containers[0]).to.containSubset({
+        hostname: 'app',
+        initContainers: [{
+          hostname: 'migrate',
+          image: new b.Image({ name: 'app' }),
+          command: ['migrate'],
+          volumeMounts: [{ volumeName: 'data', mountPath: '/data' }],
+        }],
+      });
+      expect(containers[0].initContainers[0]).to.not.have.property('id');
+      expect(volumes).to.have.lengthOf(1);
+    });
+    it('rejects init containers of the wrong type', () => {
+      expect(() => new b.Container({
+        name: 'app', image: 'app', initContainers: ['migrate'] }))
+        .to.throw('not an array of Containers');
+    });
+    it('dependsOn', () => {
+      const db = new b.Container({ name: 'db', image: 'postgres' });
+      const app = new b.Container({ name: 'app', image: 'app' });
+      app.dependsOn(db, 5432);
+      db.deploy(infra);
+      app.deploy(infra);
+      checkContainers([
+        { hostname: 'db', dependencies: [] },
+        { hostname: 'app', dependencies: [{ hostname: 'db', port: 5432 }] },
+      ]);
+      checkConnections([{ from: ['app'], to: ['db'], minPort: 5432, maxPort: 5432 }]);
+    });
+    it('dependsOn with invalid arguments', () => {
+      const app = new b.Container({ name: 'app', image: 'app' });
+      expect(() => app.dependsOn(b.publicInternet, 80)).to.throw(
+        'dependencies must be a Container');
+      expect(() => app.dependsOn(app, '80')).to.throw(
+        'port must be a valid port number');
+    });
+    it('groups', () => {
+      const db = new b.Container({ name: 'db', image: 'postgres' });
+      const group = new b.ContainerGroup({
+        name: 'web',
+        containers: [
+          new b.Container({
+            name: 'nginx',
+            image: 'nginx',
+            initContainers: [new b.Container({ name: 'a', image: 'a' })],
+          }),
+          new b.Container({
+            name: 'envoy',
+            image: 'envoy',
+            initContainers: [new b.Container({ name: 'b', image: 'b' })],
+          }),
+        ],
+      });
+      group.dependsOn(db, 5432);
+      db.deploy(infra);
+      group.deploy(infra);
+
+      const { containers } = infra.toKeldaRepresentation();
+      expect(containers[1]).to.containSubset({
+        hostname: 'web',
+        initContainers: [{ hostname: 'a' }, { hostname: 'b' }],
+        dependencies: [{ hostname: 'db', port: 5432 }],
+      });
+      expect(containers[1].sidecars[0]).to.not.have.property('initContainers');
+      checkConnections([{ from: ['web'], to: ['db'], minPort: 5432, maxPort: 5432 }]);
+    });
+  });
+
+  describe('DaemonSet', () => {
+    beforeEach(createBasicInfra);
+    it('basic', () => {
+      const volume = new b.Volume({
+        name: 'logs', type: 'hostPath', path: '/var/log' });
+      const agent = new b.DaemonSet({
+        name: 'agent',
+        container: new b.Container({
+          name: 'exporter',
+          image: 'prom/node-exporter',
+          volumeMounts: [new b.VolumeMount({ volume, mountPath: '/logs' })],
+        }),
+      });
+      agent.deploy(infra);
+
+      const { containers, volumes } = infra.toKeldaRepresentation();
+      expect(containers).to.have.lengthOf(1);
+      expect(containers[0]).to.containSubset({
+        hostname: 'agent',
+        image: new b.Image({ name: 'prom/node-exporter' }),
+        daemon: true,
+      });
+      expect(containers[0].id).to.be.a('string');
+      expect(volumes).to.have.lengthOf(1);
+    });
+    it('groups', () => {
+      const agent = new b.DaemonSet({
+        name: 'agent',
+        container: new b.ContainerGroup({
+          name: 'group',
+          containers: [
+            new b.Container({ name: 'exporter', image: 'exporter' }),
+            new b.Container({ name: 'shipper', image: 'shipper' }),
+          ],
+        }),
+      });
+      agent.deploy(infra);
+      checkContainers([{
+        hostname: 'agent',
+        daemon: true,
+        sidecars: [{ hostname: 'shipper' }],
+      }]);
+    });
+    it('connections and placements target the daemon set', () => {
+      const collector = new b.Container({ name: 'collector', image: 'collector' });
+      const agent = new b.DaemonSet({
+        name: 'agent',
+        container: new b.Container({ name: 'exporter', image: 'exporter' }),
+      });
+      agent.placeOn({ size: 'm4.large' });
+      b.allowTraffic(agent, collector, 9091);
+      collector.deploy(infra);
+      agent.deploy(infra);
+      checkConnections([{ from: ['agent'], to: ['collector'], minPort: 9091, maxPort: 9091 }]);
+      checkPlacements([{ targetContainer: 'agent', size: 'm4.large' }]);
+    });
+    it('errors when given invalid arguments', () => {
+      expect(() => new b.DaemonSet({ name: 'agent' })).to
+        .throw("missing required attribute: DaemonSet requires 'container'");
+      expect(() => new b.DaemonSet({ name: 'agent', container: 'exporter' })).to
+        .throw('container must be a Container or ContainerGroup');
+      expect(() => new b.DaemonSet({
+        name: 'agent',
+        container: new b.Container({ name: 'exporter', image: 'exporter' }),
+        badArg: 'foo',
+      })).to.throw('Unrecognized keys passed to DaemonSet constructor: badArg');
+    });
+    it('cannot be depended on', () => {
+      const app = new b.Container({ name: 'app', image: 'app' });
+      const agent = new b.DaemonSet({
+        name: 'agent',
+        container: new b.Container({ name: 'exporter', image: 'exporter' }),
+      });
+      expect(() => app.dependsOn(agent, 80)).to.throw(
+        'dependencies must be a Container');
+    });
+  });
+
+  describe('StatefulSet', () => {
+    beforeEach(createBasicInfra);
+    it('basic', () => {
+      const volume = new b.Volume({
+        name: 'data', type: 'hostPath', path: '/var/lib/zk' });
+      const zk = new b.StatefulSet({
+        name: 'zk',
+        replicas: 3,
+        container: new b.Container({
+          name: 'zookeeper',
+          image: 'zookeeper',
+          volumeMounts: [new b.VolumeMount({ volume, mountPath: '/data' })],
+        }),
+      });
+      zk.deploy(infra);
+
+      const { containers, volumes } = infra.toKeldaRepresentation();
+      expect(containers).to.have.lengthOf(1);
+      expect(containers[0]).to.containSubset({
+        hostname: 'zk',
+        image: new b.Image({ name: 'zookeeper' }),
+        stateful: true,
+        replicas: 3,
+      });
+      expect(containers[0].id).to.be.a('string');
+      expect(volumes).to.have.lengthOf(1);
+    });
+    it('getHostnames', () => {
+      const zk = new b.StatefulSet({
+        name: 'zk',
+        replicas: 3,
+        container: new b.Container({ name: 'zookeeper', image: 'zookeeper' }),
+      });
+      expect(zk.getHostnames()).to.deep.equal(['zk-0', 'zk-1', 'zk-2']);
+    });
+    it('connections and placements target the stateful set', () => {
+      const zk = new b.StatefulSet({
+        name: 'zk',
+        replicas: 3,
+        container: new b.Container({ name: 'zookeeper', image: 'zookeeper' }),
+      });
+      zk.placeOn({ size: 'm4.large' });
+      b.allowTraffic(zk, zk, new b.PortRange(2888, 3888));
+      zk.deploy(infra);
+      checkConnections([{ from: ['zk'], to: ['zk'], minPort: 2888, maxPort: 3888 }]);
+      checkPlacements([{ targetContainer: 'zk', size: 'm4.large' }]);
+    });
+    it('errors when given invalid arguments', () => {
+      const container = new b.Container({ name: 'zookeeper', image: 'zookeeper' });
+      expect(() => new b.StatefulSet({ name: 'zk', container })).to
+        .throw("missing required attribute: StatefulSet requires 'replicas'");
+      expect(() => new b.StatefulSet({ name: 'zk', replicas: 0, container })).to
+        .throw('replicas must be a positive integer');
+      expect(() => new b.StatefulSet({ name: 'zk', replicas: 1, container: 'zk' }))
+        .to.throw('container must be a Container or ContainerGroup');
+      expect(() => new b.StatefulSet({
+        name: 'zk', replicas: 1, container, badArg: 'foo',
+      })).to.throw('Unrecognized keys passed to StatefulSet constructor: badArg');
+    });
+    it('cannot be depended on', () => {
+      const app = new b.Container({ name: 'app', image: 'app' });
+      const zk = new b.StatefulSet({
+        name: 'zk',
+        replicas: 3,
+        container: new b.Container({ name: 'zookeeper', image: 'zookeeper' }),
+      });
+      expect(() => app.dependsOn(zk, 2181)).to.throw(
+        'dependencies must be a Container');
+    });
+  });
+
+  describe('ReplicaSet', () => {
+    beforeEach(createBasicInfra);
+    const container = () => new b.Container({ name: 'nginx', image: 'nginx' });
+    it('basic', () => {
+      const web = new b.ReplicaSet({
+        name: 'web',
+        container: container(),
+        min: 2,
+        max: 10,
+        target: 50,
+      });
+      web.deploy(infra);
+
+      const { containers } = infra.toKeldaRepresentation();
+      expect(containers).to.have.lengthOf(1);
+      expect(containers[0]).to.containSubset({
+        hostname: 'web',
+        image: new b.Image({ name: 'nginx' }),
+        autoscale: {
+          min: 2,
+          max: 10,
+          metric: 'cpu',
+          target: 50,
+          port: 0,
+          path: '',
+          scaleUpCooldown: 0,
+          scaleDownCooldown: 0,
+        },
+      });
+      expect(containers[0].id).to.be.a('string');
+    });
+    it('http metric', () => {
+      const worker = new b.ReplicaSet({
+        name: 'worker',
+        container: container(),
+        min: 1,
+        max: 20,
+        metric: 'http',
+        target: 100,
+        port: 9000,
+        path: '/queue',
+        scaleUpCooldown: 60,
+        scaleDownCooldown: 600,
+      });
+      worker.deploy(infra);
+      expect(infra.toKeldaRepresentation().containers[0].autoscale).to.deep.equal({
+        min: 1,
+        max: 20,
+        metric: 'http',
+        target: 100,
+        port: 9000,
+        path: '/queue',
+        scaleUpCooldown: 60,
+        scaleDownCooldown: 600,
+      });
+    });
+    it('tuning the autoscaling settings keeps the ID', () => {
+      const args = { name: 'web', min: 1, max: 3, target: 50 };
+      const first = new b.ReplicaSet(Object.assign({ container: container() }, args));
+      b.resetGlobals();
+      const second = new b.ReplicaSet(Object.assign({ container: container() },
+        args, { max: 5, target: 70 }));
+      expect(first.hash()).to.equal(second.hash());
+    });
+    it('connections, load balancers, and placements target the set', () => {
+      const web = new b.ReplicaSet({
+        name: 'web', container: container(), min: 1, max: 3, target: 50 });
+      const lb = new b.LoadBalancer({ name: 'web-lb', containers: web });
+      web.placeOn({ size: 'm4.large' });
+      b.allowTraffic(b.publicInternet, lb, 80);
+      b.allowTraffic(web, web, 7946);
+      web.deploy(infra);
+      lb.deploy(infra);
+      checkLoadBalancers([{ name: 'web-lb', hostnames: ['web'] }]);
+      checkConnections([
+        { from: ['public'], to: ['web-lb'], minPort: 80, maxPort: 80 },
+        { from: ['web'], to: ['web'], minPort: 7946, maxPort: 7946 },
+      ]);
+      checkPlacements([{ targetContainer: 'web', size: 'm4.large' }]);
+    });
+    it('errors when given invalid arguments', () => {
+      const args = extra => Object.assign({
+        name: 'web', container: container(), min: 1, max: 3, target: 50,
+      }, extra);
+      expect(() => new b.ReplicaSet({ name: 'web', container: container() })).to
+        .throw("missing required attribute: ReplicaSet requires 'min'");
+      expect(() => new b.ReplicaSet(args({ min: 0 }))).to
+        .throw('the minimum number of replicas must be at least 1');
+      expect(() => new b.ReplicaSet(args({ min: 4 }))).to
+        .throw('the maximum must be at least the minimum (was: min 4, max 3)');
+      expect(() => new b.ReplicaSet(args({ metric: 'memory' }))).to
+        .throw('metric must be either "cpu" or "http" (was: "memory")');
+      expect(() => new b.ReplicaSet(args({ target: 0 }))).to
+        .throw('target must be positive (was: 0)');
+      expect(() => new b.ReplicaSet(args({ metric: 'http' }))).to
+        .throw('port must be a valid port number when the metric is "http"');
+      expect(() => new b.ReplicaSet(args({ metric: 'http', port: 80, path: 'q' })))
+        .to.throw('path must start with a slash (was: q)');
+      expect(() => new b.ReplicaSet(args({ port: 80 }))).to
+        .throw('port and path can only be set when the metric is "http"');
+      expect(() => new b.ReplicaSet(args({ scaleDownCooldown: -1 }))).to
+        .throw('cooldowns must not be negative');
+      expect(() => new b.ReplicaSet(args({ container: 'web' }))).to
+        .throw('container must be a Container or ContainerGroup');
+      expect(() => new b.ReplicaSet(args({ badArg: 'foo' }))).to
+        .throw('Unrecognized keys passed to ReplicaSet constructor: badArg');
+    });
+    it('cannot be depended on', () => {
+      const app = new b.Container({ name: 'app', image: 'app' });
+      const web = new b.ReplicaSet({
+        name: 'web', container: container(), min: 1, max: 3, target: 50 });
+      expect(() => app.dependsOn(web, 80)).to.throw(
+        'dependencies must be a Container');
+    });
+  });
+
   describe('Placement', () => {
     let target;
     beforeEach(() => {
@@ -731,6 +1330,87 @@ describe('Bindings', () => {
         floatingIp: 'xxx.xxx.xxx.xxx',
       }]);
     });
+    it('MachineRule labels', () => {
+      target.placeOn({ labels: { tier: 'db' } });
+      checkPlacements([{
+        targetContainer: 'host',
+        exclusive: false,
+        labels: { tier: 'db' },
+      }]);
+      expect(() => target.placeOn({ labels: { tier: 'DB' } })).to.throw(
+        'labels has an invalid value "DB" for tier');
+    });
+    it('MachineRule preference', () => {
+      target.placeOn({ region: 'us-west-2' }, { weight: 50 });
+      checkPlacements([{
+        targetContainer: 'host',
+        exclusive: false,
+        region: 'us-west-2',
+        weight: 50,
+      }]);
+    });
+    it('ContainerRule', () => {
+      const cache = new b.Container({ name: 'cache', image: 'redis' });
+      const db = new b.ContainerGroup({
+        name: 'db',
+        containers: [new b.Container({ name: 'postgres', image: 'postgres' })],
+      });
+      cache.deploy(infra);
+      db.deploy(infra);
+      target.placeWith(cache);
+      target.placeAwayFrom(db, { topology: 'region', weight: 10 });
+      checkPlacements([
+        {
+          targetContainer: 'host',
+          otherContainer: 'cache',
+          exclusive: false,
+          weight: 0,
+        },
+        {
+          targetContainer: 'host',
+          otherContainer: 'db',
+          exclusive: true,
+          topology: 'region',
+          weight: 10,
+        },
+      ]);
+    });
+    it('spreads containers', () => {
+      const other = new b.Container({ name: 'host', image: 'image' });
+      other.deploy(infra);
+      b.spread([target, other], { across: 'region' });
+      checkPlacements([
+        {
+          targetContainer: 'host',
+          otherContainer: 'host2',
+          exclusive: true,
+          topology: 'region',
+          weight: 100,
+        },
+        {
+          targetContainer: 'host2',
+          otherContainer: 'host',
+          exclusive: true,
+          topology: 'region',
+          weight: 100,
+        },
+      ]);
+    });
+    it('errors when given invalid arguments', () => {
+      expect(() => target.placeOn({ size: 'm4.large' }, { weight: 0 })).to
+        .throw('weight must be an integer between 1 and 100 (was: 0)');
+      expect(() => target.placeOn({ size: 'm4.large' }, { weight: 1.5 })).to
+        .throw('weight must be an integer between 1 and 100 (was: 1.5)');
+      expect(() => target.placeWith('cache')).to.throw(
+        'containers can only be placed relative to a Container or ' +
+        'ContainerGroup (was: "cache")');
+      expect(() => target.placeAwayFrom(target, { topology: 'rack' })).to
+        .throw('topology must be "machine" or "region" (was: "rack")');
+      expect(() => b.spread(target)).to.throw(
+        'containers must be an array of Containers or ContainerGroups');
+      expect(() => b.spread([target], { badArg: 'foo' })).to.throw(
+        'Unrecognized keys passed to spread: badArg');
+    });
   });
   describe('LoadBalancer', () => {
     beforeEach(createBasicInfra);
@@ -745,6 +1425,10 @@ describe('Bindings', () => {
         hostnames: ['host'],
       }]);
     });
+    it('should error when given invalid containers', () => {
+      expect(() => new b.LoadBalancer({ name: 'lb', containers: ['host'] })).to
+        .throw('LoadBalancer.containers must be Containers or ReplicaSets (was "host")');
+    });
     it('should error when given an invalid argument', () => {
       expect(() => new b.LoadBalancer([])).to
         .throw('the LoadBalancer constructor must be given a valid object (was: [])');
@@ -982,6 +1666,18 @@ describe('Bindings', () => {
       })).deploy(infra);
       expect(deploy).to.throw('img has differing Dockerfiles');
     });
+    it('duplicate image with different build options', () => {
+      createBasicInfra();
+      (new b.Container({
+        name: 'host',
+        image: new b.Image({ name: 'img', dockerfile: 'dk', target: 'a' }),
+      })).deploy(infra);
+      (new b.Container({
+        name: 'host',
+        image: new b.Image({ name: 'img', dockerfile: 'dk', target: 'b' }),
+      })).deploy(infra);
+      expect(deploy).to.throw('img has differing build options');
+    });
     it('machines with same regions/providers', () => {
       const machine = new b.Machine({
         provider: 'Amazon',
@@ -1215,6 +1911,187 @@ describe('Bindings', () => {
       createBasicInfra();
       expect(infra.toKeldaRepresentation().adminACL).to.eql([]);
     });
+    it('log sink', () => {
+      infra = new b.Infrastructure({
+        masters: machine,
+        workers: machine,
+        logSink: { type: 'syslog', address: 'logs:514', protocol: 'tcp' },
+      });
+      expect(infra.toKeldaRepresentation().logSink).to.eql({
+        type: 'syslog', address: 'logs:514', protocol: 'tcp', path: '',
+      });
+    });
+    it('no log sink', () => {
+      createBasicInfra();
+      expect(infra.toKeldaRepresentation()).to.not.have.property('logSink');
+    });
+    it('invalid log sink', () => {
+      expect(() => new b.Infrastructure({
+        masters: machine, workers: machine, logSink: { type: 'kafka' },
+      })).to.throw('logSink.type must be "syslog", "http", or "file" ' +
+        '(was: "kafka")');
+
+      expect(() => new b.Infrastructure({
+        masters: machine, workers: machine, logSink: { type: 'file' },
+      })).to.throw('file log sinks require a path');
+
+      expect(() => new b.Infrastructure({
+        masters: machine, workers: machine, logSink: { type: 'http' },
+      })).to.throw('http log sinks require an address');
+    });
+    it('secret backend', () => {
+      infra = new b.Infrastructure({
+        masters: machine,
+        workers: machine,
+        secretBackend: {
+          type: 'vault', address: 'https://vault:8200', tokenSecret: 'token',
+        },
+      });
+      expect(infra.toKeldaRepresentation().secretBackend).to.eql({
+        type: 'vault',
+        address: 'https://vault:8200',
+        mount: '',
+        path: '',
+        tokenSecret: 'token',
+      });
+    });
+    it('no secret backend', () => {
+      createBasicInfra();
+      expect(infra.toKeldaRepresentation()).to.not.have.property(
+        'secretBackend');
+    });
+    it('invalid secret backend', () => {
+      expect(() => new b.Infrastructure({
+        masters: machine, workers: machine, secretBackend: { type: 'aws' },
+      })).to.throw('secretBackend.type must be "kubernetes", "vault", or ' +
+        '"file" (was: "aws")');
+
+      expect(() => new b.Infrastructure({
+        masters: machine,
+        workers: machine,
+        secretBackend: { type: 'vault', address: 'https://vault:8200' },
+      })).to.throw('vault secret backends require a tokenSecret');
+
+      expect(() => new b.Infrastructure({
+        masters: machine, workers: machine, secretBackend: { type: 'file' },
+      })).to.throw('file secret backends require a path');
+    });
+    it('registry credentials', () => {
+      infra = new b.Infrastructure({
+        masters: machine,
+        workers: machine,
+        registryCredentials: [
+          { server: 'registry.example.com', secret: 'regcred' },
+        ],
+      });
+      expect(infra.toKeldaRepresentation().registryCredentials).to.eql([
+        { server: 'registry.example.com', secret: 'regcred' },
+      ]);
+    });
+    it('no registry credentials', () => {
+      createBasicInfra();
+      expect(infra.toKeldaRepresentation()).to.not.have.property(
+        'registryCredentials');
+    });
+    it('invalid registry credentials', () => {
+      expect(() => new b.Infrastructure({
+        masters: machine, workers: machine, registryCredentials: 'regcred',
+      })).to.throw('registryCredentials must be an array (was: "regcred")');
+
+      expect(() => new b.Infrastructure({
+        masters: machine,
+        workers: machine,
+        registryCredentials: [{ server: 'registry.example.com' }],
+      })).to.throw('registryCredentials[0] requires a server and a secret');
+
+      expect(() => new b.Infrastructure({
+        masters: machine,
+        workers: machine,
+        registryCredentials: [{ server: 'a', secret: 'b', user: 'c' }],
+      })).to.throw();
+    });
+    it('image digests', () => {
+      infra = new b.Infrastructure({
+        masters: machine, workers: machine, imageDigests: 'watch',
+      });
+      expect(infra.toKeldaRepresentation().imageDigests).to.equal('watch');
+
+      createBasicInfra();
+      expect(infra.toKeldaRepresentation()).to.not.have.property(
+        'imageDigests');
+    });
+    it('invalid image digests', () => {
+      expect(() => new b.Infrastructure({
+        masters: machine, workers: machine, imageDigests: 'always',
+      })).to.throw('imageDigests must be "pin" or "watch" (was: "always")');
+    });
+    it('security policy', () => {
+      infra = new b.Infrastructure({
+        masters: machine,
+        workers: machine,
+        securityPolicy: { forbidPrivileged: true },
+      });
+      expect(infra.toKeldaRepresentation().securityPolicy).to.deep.equal({
+        forbidPrivileged: true });
+
+      createBasicInfra();
+      expect(infra.toKeldaRepresentation()).to.not.have.property(
+        'securityPolicy');
+
+      expect(() => new b.Infrastructure({
+        masters: machine,
+        workers: machine,
+        securityPolicy: { forbidPrivileged: 'yes' },
+      })).to.throw('securityPolicy.forbidPrivileged must be a boolean');
+    });
+    it('machine pools', () => {
+      const pool = new b.MachinePool({
+        name: 'web',
+        machine: new b.Machine({ provider: 'Amazon', size: 'm4.large' }),
+        min: 1,
+        max: 3,
+      });
+      infra = new b.Infrastructure({
+        masters: machine, workers: machine, machinePools: pool });
+      expect(infra.toKeldaRepresentation().machinePools).to.containSubset([{
+        name: 'web',
+        machine: {
+          provider: 'Amazon',
+          size: 'm4.large',
+          role: 'Worker',
+        },
+        min: 1,
+        max: 3,
+      }]);
+
+      createBasicInfra();
+      expect(infra.toKeldaRepresentation()).to.not.have.property(
+        'machinePools');
+    });
+    it('machine pools must use the same provider', () => {
+      const pool = new b.MachinePool({
+        name: 'web',
+        machine: new b.Machine({ provider: 'Google' }),
+        max: 3,
+      });
+      infra = new b.Infrastructure({
+        masters: machine, workers: machine, machinePools: [pool] });
+      expect(() => infra.toKeldaRepresentation()).to.throw(
+        'All machines must have the same provider and region.');
+    });
+    it('invalid machine pools', () => {
+      expect(() => new b.MachinePool({
+        name: 'web', machine: 'm4.large', max: 3,
+      })).to.throw('machine field must be of type Machine');
+      expect(() => new b.MachinePool({
+        name: 'web', machine, min: 4, max: 3,
+      })).to.throw('the maximum size of a MachinePool must be at least 1, ' +
+        'and its minimum size must be between 0 and its maximum size ' +
+        '(was: min 4, max 3)');
+      expect(() => new b.Infrastructure({
+        masters: machine, workers: machine, machinePools: [machine],
+      })).to.throw('Infrastructure.machinePools is not an array of MachinePools');
+    });
   });
   describe('githubKeys()', () => {});
   describe('baseInfrastructure()', () => {
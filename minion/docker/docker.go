@@ -1,10 +1,16 @@
 package docker
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -44,6 +50,31 @@ type Container struct {
 	Running  bool
 }
 
+// An Image is a Docker image stored on the machine.
+type Image struct {
+	ID          string
+	RepoTags    []string
+	RepoDigests []string
+	Size        int64
+	Created     time.Time
+}
+
+// Stats is a sample of the resource usage of a container.
+type Stats struct {
+	// The percentage of a single CPU core used by the container. Containers
+	// using multiple cores may exceed 100.
+	CPUPercent float64
+
+	// The memory used by the container, and its memory limit, in bytes. If
+	// the container isn't limited, the limit is the memory of the host.
+	MemoryUsage uint64
+	MemoryLimit uint64
+
+	// The total bytes received and transmitted by the container.
+	NetworkRx uint64
+	NetworkTx uint64
+}
+
 // ContainerSlice is an alias for []Container to allow for joins
 type ContainerSlice []Container
 
@@ -92,9 +123,15 @@ type client interface {
 	ListContainers(opts dkc.ListContainersOptions) ([]dkc.APIContainers, error)
 	InspectContainer(id string) (*dkc.Container, error)
 	InspectImage(id string) (*dkc.Image, error)
+	ListImages(opts dkc.ListImagesOptions) ([]dkc.APIImages, error)
+	RemoveImageExtended(name string, opts dkc.RemoveImageOptions) error
+	CreateExec(opts dkc.CreateExecOptions) (*dkc.Exec, error)
+	StartExec(id string, opts dkc.StartExecOptions) error
 	CreateContainer(dkc.CreateContainerOptions) (*dkc.Container, error)
 	CreateNetwork(dkc.CreateNetworkOptions) (*dkc.Network, error)
 	ListNetworks() ([]dkc.Network, error)
+	Logs(opts dkc.LogsOptions) error
+	Stats(opts dkc.StatsOptions) error
 }
 
 var c = counter.New("Docker")
@@ -198,6 +235,33 @@ func (dk Client) RemoveID(id string) error {
 	return nil
 }
 
+// Exec runs `cmd` in the container with the given name, and returns its
+// output.
+func (dk Client) Exec(name string, cmd ...string) (string, error) {
+	c.Inc("Exec")
+	id, err := dk.getID(name)
+	if err != nil {
+		return "", err
+	}
+
+	exec, err := dk.CreateExec(dkc.CreateExecOptions{
+		Container:    id,
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var output bytes.Buffer
+	err = dk.StartExec(exec.ID, dkc.StartExecOptions{
+		OutputStream: &output,
+		ErrorStream:  &output,
+	})
+	return output.String(), err
+}
+
 // RenameContainer changes the friendly name of the container with the given ID.
 func (dk Client) RenameContainer(id string, newName string) error {
 	return dk.client.RenameContainer(dkc.RenameContainerOptions{
@@ -206,23 +270,188 @@ func (dk Client) RenameContainer(id string, newName string) error {
 	})
 }
 
-// Build builds an image with the given name and Dockerfile.
-func (dk Client) Build(name, dockerfile string, useCache bool) error {
+// BuildOptions are the parameters of an image build.
+type BuildOptions struct {
+	// The name to tag the built image with.
+	Name string
+
+	// The contents of the Dockerfile.
+	Dockerfile string
+
+	// A gzipped tar archive of the files available to the Dockerfile. It may
+	// be empty.
+	Context []byte
+
+	// The values of the ARG instructions in the Dockerfile.
+	BuildArgs map[string]string
+
+	// The stage of a multi-stage Dockerfile to build.
+	Target string
+
+	UseCache bool
+
+	// Maps registry servers to the credentials used to pull base images from
+	// them.
+	Auths map[string]dkc.AuthConfiguration
+
+	// Where the output of the build is written. It's discarded if nil.
+	Output io.Writer
+}
+
+// Build builds an image according to `opts`.
+func (dk Client) Build(opts BuildOptions) error {
 	c.Inc("Build")
-	tarBuf, err := util.ToTar("Dockerfile", 0644, dockerfile)
+	tarBuf, err := makeBuildContext(opts.Dockerfile, opts.Context)
 	if err != nil {
-		return err
+		return fmt.Errorf("make build context: %s", err)
+	}
+
+	var buildArgs []dkc.BuildArg
+	for name, value := range opts.BuildArgs {
+		buildArgs = append(buildArgs, dkc.BuildArg{Name: name, Value: value})
+	}
+	sort.Slice(buildArgs, func(i, j int) bool {
+		return buildArgs[i].Name < buildArgs[j].Name
+	})
+
+	output := opts.Output
+	if output == nil {
+		output = ioutil.Discard
 	}
 
 	return dk.BuildImage(dkc.BuildImageOptions{
 		NetworkMode:  "host",
-		Name:         name,
+		Name:         opts.Name,
 		InputStream:  tarBuf,
-		OutputStream: ioutil.Discard,
-		NoCache:      !useCache,
+		OutputStream: output,
+		NoCache:      !opts.UseCache,
+		BuildArgs:    buildArgs,
+		Target:       opts.Target,
+		AuthConfigs:  dkc.AuthConfigurations{Configs: opts.Auths},
 	})
 }
 
+// makeBuildContext returns a tar archive containing the files in the gzipped
+// tar archive `context`, and the Dockerfile. The Dockerfile replaces any
+// Dockerfile in `context`.
+func makeBuildContext(dockerfile string, context []byte) (io.Reader, error) {
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+
+	if len(context) != 0 {
+		gr, err := gzip.NewReader(bytes.NewReader(context))
+		if err != nil {
+			return nil, err
+		}
+
+		tr := tar.NewReader(gr)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			if path.Clean(hdr.Name) == "Dockerfile" {
+				continue
+			}
+
+			if err := tw.WriteHeader(hdr); err != nil {
+				return nil, err
+			}
+			if _, err := io.Copy(tw, tr); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	hdr := &tar.Header{
+		Name:    "Dockerfile",
+		Mode:    0644,
+		Size:    int64(len(dockerfile)),
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write([]byte(dockerfile)); err != nil {
+		return nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// FollowLogs streams the logs of the container with the given ID to `stdout`
+// and `stderr`, blocking until the container exits. Only logs written after
+// `since` are streamed. Each line is prefixed with its RFC3339 timestamp.
+func (dk Client) FollowLogs(id string, since time.Time, stdout,
+	stderr io.Writer) error {
+	c.Inc("Logs")
+	return dk.Logs(dkc.LogsOptions{
+		Container:    id,
+		OutputStream: stdout,
+		ErrorStream:  stderr,
+		Stdout:       true,
+		Stderr:       true,
+		Follow:       true,
+		Timestamps:   true,
+		Since:        since.Unix(),
+	})
+}
+
+// ContainerStats returns a sample of the resource usage of the container with
+// the given ID.
+func (dk Client) ContainerStats(id string) (Stats, error) {
+	c.Inc("Stats")
+
+	statsChan := make(chan *dkc.Stats, 1)
+	err := dk.Stats(dkc.StatsOptions{
+		ID:      id,
+		Stats:   statsChan,
+		Stream:  false,
+		Timeout: networkTimeout,
+	})
+	if err != nil {
+		return Stats{}, err
+	}
+
+	dkStats, ok := <-statsChan
+	if !ok || dkStats == nil {
+		return Stats{}, errors.New("no stats returned")
+	}
+
+	stats := Stats{
+		MemoryUsage: dkStats.MemoryStats.Usage,
+		MemoryLimit: dkStats.MemoryStats.Limit,
+		NetworkRx:   dkStats.Network.RxBytes,
+		NetworkTx:   dkStats.Network.TxBytes,
+	}
+	for _, network := range dkStats.Networks {
+		stats.NetworkRx += network.RxBytes
+		stats.NetworkTx += network.TxBytes
+	}
+
+	// Docker reports the cumulative CPU time used by the container and the
+	// host. The usage of the container is the fraction of the host's CPU time
+	// used between the previous sample and this one.
+	cpu := dkStats.CPUStats
+	preCPU := dkStats.PreCPUStats
+	if cpu.CPUUsage.TotalUsage > preCPU.CPUUsage.TotalUsage &&
+		cpu.SystemCPUUsage > preCPU.SystemCPUUsage {
+		cpuDelta := float64(cpu.CPUUsage.TotalUsage -
+			preCPU.CPUUsage.TotalUsage)
+		systemDelta := float64(cpu.SystemCPUUsage - preCPU.SystemCPUUsage)
+		numCPUs := float64(len(cpu.CPUUsage.PercpuUsage))
+		stats.CPUPercent = cpuDelta / systemDelta * numCPUs * 100
+	}
+	return stats, nil
+}
+
 // Pull retrieves the given docker image from an image cache.
 // The `image` argument can be of the form <repo>, <repo>:<tag>, or
 // <repo>:<tag>@<digestFormat>:<digest>.
@@ -296,6 +525,34 @@ func (dk Client) Push(registry, image string) (string, error) {
 	return img.RepoDigests[0], nil
 }
 
+// ListImages returns the images stored on the machine.
+func (dk Client) ListImages() ([]Image, error) {
+	c.Inc("List Images")
+	apiImages, err := dk.client.ListImages(dkc.ListImagesOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var images []Image
+	for _, img := range apiImages {
+		images = append(images, Image{
+			ID:          img.ID,
+			RepoTags:    img.RepoTags,
+			RepoDigests: img.RepoDigests,
+			Size:        img.Size,
+			Created:     time.Unix(img.Created, 0),
+		})
+	}
+	return images, nil
+}
+
+// RemoveImage deletes the image with the given ID, along with all of its tags.
+// Images that are used by running containers can't be removed.
+func (dk Client) RemoveImage(id string) error {
+	c.Inc("Remove Image")
+	return dk.RemoveImageExtended(id, dkc.RemoveImageOptions{Force: true})
+}
+
 // List returns a slice of all containers. The containers can be be filtered
 // with the supplied `filters` map. If `all` is false, only running containers
 // are returned.
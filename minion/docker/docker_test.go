@@ -1,6 +1,9 @@
 package docker
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
 	"fmt"
 	"testing"
 	"time"
@@ -310,7 +313,18 @@ func TestBuild(t *testing.T) {
 	t.Parallel()
 	md, dk := NewMock()
 
-	err := dk.Build("foo", "bar", false)
+	auths := map[string]dkc.AuthConfiguration{
+		"registry.example.com": {Username: "user", Password: "pass"},
+	}
+	md.BuildOutput = "Step 1/1 : FROM bar"
+	var output bytes.Buffer
+	err := dk.Build(BuildOptions{
+		Name:       "foo",
+		Dockerfile: "bar",
+		BuildArgs:  map[string]string{"b": "2", "a": "1"},
+		Auths:      auths,
+		Output:     &output,
+	})
 	assert.NoError(t, err)
 	assert.Equal(t, map[BuildImageOptions]struct{}{
 		{
@@ -319,17 +333,80 @@ func TestBuild(t *testing.T) {
 			NoCache:    true,
 		}: {},
 	}, md.Built)
+	assert.Equal(t, dkc.AuthConfigurations{Configs: auths},
+		md.BuildAuthConfigs["foo"])
+	assert.Equal(t, []dkc.BuildArg{{Name: "a", Value: "1"},
+		{Name: "b", Value: "2"}}, md.BuildArgs["foo"])
+	assert.Equal(t, "Step 1/1 : FROM bar", output.String())
 
 	md.BuildError = true
-	err = dk.Build("foo", "bar", false)
+	err = dk.Build(BuildOptions{Name: "foo", Dockerfile: "bar"})
 	assert.NotNil(t, err)
 }
 
+func TestBuildContext(t *testing.T) {
+	t.Parallel()
+	md, dk := NewMock()
+
+	buf := new(bytes.Buffer)
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+	for name, content := range map[string]string{
+		"app.py":     "print('hello')",
+		"Dockerfile": "ignored",
+	} {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644,
+			Size: int64(len(content))})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gw.Close()
+
+	err := dk.Build(BuildOptions{
+		Name:       "foo",
+		Dockerfile: "FROM alpine\nCOPY app.py /",
+		Context:    buf.Bytes(),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[BuildImageOptions]struct{}{
+		{
+			Name:       "foo",
+			Dockerfile: "FROM alpine\nCOPY app.py /",
+			NoCache:    true,
+		}: {},
+	}, md.Built)
+	assert.Equal(t, []string{"app.py"}, md.BuildContexts["foo"])
+
+	err = dk.Build(BuildOptions{Name: "bar", Dockerfile: "FROM alpine",
+		Context: []byte("malformed")})
+	assert.Error(t, err)
+}
+
+func TestBuildTarget(t *testing.T) {
+	t.Parallel()
+	md, dk := NewMock()
+
+	dockerfile := "FROM golang AS build\nRUN go build\n" +
+		"FROM alpine as release\nCOPY --from=build /app /\n" +
+		"FROM release\nRUN test"
+	err := dk.Build(BuildOptions{Name: "foo", Dockerfile: dockerfile,
+		Target: "release"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[BuildImageOptions]struct{}{
+		{
+			Name:       "foo",
+			Dockerfile: dockerfile,
+			Target:     "release",
+			NoCache:    true,
+		}: {},
+	}, md.Built)
+}
+
 func TestPush(t *testing.T) {
 	t.Parallel()
 	md, dk := NewMock()
 
-	err := dk.Build("bar:baz", "dockerfile", false)
+	err := dk.Build(BuildOptions{Name: "bar:baz", Dockerfile: "dockerfile"})
 	assert.NoError(t, err)
 
 	repoDigest, err := dk.Push("foo", "bar:baz")
@@ -348,6 +425,87 @@ func TestPush(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestListRemoveImages(t *testing.T) {
+	t.Parallel()
+	md, dk := NewMock()
+
+	created := time.Unix(100, 0)
+	image := &dkc.Image{ID: "id", Size: 10, Created: created}
+	md.Images["foo:latest"] = image
+	md.Images["foo:1.0"] = image
+
+	images, err := dk.ListImages()
+	assert.NoError(t, err)
+	assert.Equal(t, []Image{{
+		ID:       "id",
+		RepoTags: []string{"foo:1.0", "foo:latest"},
+		Size:     10,
+		Created:  created,
+	}}, images)
+
+	assert.NoError(t, dk.RemoveImage("id"))
+	images, err = dk.ListImages()
+	assert.NoError(t, err)
+	assert.Empty(t, images)
+
+	assert.Error(t, dk.RemoveImage("id"))
+
+	md.ListImagesError = true
+	_, err = dk.ListImages()
+	assert.Error(t, err)
+}
+
+func TestExec(t *testing.T) {
+	t.Parallel()
+	md, dk := NewMock()
+
+	id, err := dk.Run(RunOptions{Name: "registry", Image: "registry"})
+	assert.NoError(t, err)
+
+	_, err = dk.Exec("registry", "registry", "garbage-collect")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"registry garbage-collect"}, md.Executions[id])
+
+	_, err = dk.Exec("missing", "ls")
+	assert.Error(t, err)
+}
+
+func TestContainerStats(t *testing.T) {
+	t.Parallel()
+	md, dk := NewMock()
+
+	var stats dkc.Stats
+	stats.CPUStats.CPUUsage.TotalUsage = 400
+	stats.CPUStats.CPUUsage.PercpuUsage = []uint64{100, 100, 100, 100}
+	stats.CPUStats.SystemCPUUsage = 2000
+	stats.PreCPUStats.CPUUsage.TotalUsage = 300
+	stats.PreCPUStats.SystemCPUUsage = 1800
+	stats.MemoryStats.Usage = 10
+	stats.MemoryStats.Limit = 20
+	stats.Networks = map[string]dkc.NetworkStats{
+		"eth0": {RxBytes: 1, TxBytes: 2},
+		"eth1": {RxBytes: 3, TxBytes: 4},
+	}
+	md.ContainerStats["id"] = stats
+
+	res, err := dk.ContainerStats("id")
+	assert.NoError(t, err)
+	assert.Equal(t, Stats{
+		CPUPercent:  200,
+		MemoryUsage: 10,
+		MemoryLimit: 20,
+		NetworkRx:   4,
+		NetworkTx:   6,
+	}, res)
+
+	_, err = dk.ContainerStats("missing")
+	assert.Equal(t, ErrNoSuchContainer, err)
+
+	md.StatsError = true
+	_, err = dk.ContainerStats("id")
+	assert.EqualError(t, err, "stats error")
+}
+
 func cacheKeys(cache map[string]*cacheEntry) map[string]struct{} {
 	res := map[string]struct{}{}
 	for k := range cache {
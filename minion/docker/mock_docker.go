@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"sort"
 	"strings"
 	"sync"
 
@@ -20,8 +21,8 @@ type mockContainer struct {
 
 // BuildImageOptions represents the parameters in a call to BuildImage.
 type BuildImageOptions struct {
-	Name, Dockerfile string
-	NoCache          bool
+	Name, Dockerfile, Target string
+	NoCache                  bool
 }
 
 // UploadToContainerOptions represents the parameters in a call to UploadToContainer.
@@ -41,6 +42,23 @@ type MockClient struct {
 	Uploads    map[UploadToContainerOptions]struct{}
 	Images     map[string]*dkc.Image
 
+	// The registry credentials passed to BuildImage for each image name.
+	BuildAuthConfigs map[string]dkc.AuthConfigurations
+
+	// The names of the files other than the Dockerfile in the build context,
+	// and the build args, passed to BuildImage for each image name.
+	BuildContexts map[string][]string
+	BuildArgs     map[string][]dkc.BuildArg
+
+	// The output written by BuildImage.
+	BuildOutput string
+
+	// The logs returned for each container ID by Logs.
+	ContainerLogs map[string]string
+
+	// The stats returned for each container ID by Stats.
+	ContainerStats map[string]dkc.Stats
+
 	createdExecs map[string]dkc.CreateExecOptions
 	Executions   map[string][]string
 
@@ -50,7 +68,11 @@ type MockClient struct {
 	CreateExecError       bool
 	InspectContainerError bool
 	InspectImageError     bool
+	ListImagesError       bool
+	RemoveImageError      bool
 	ListError             bool
+	LogsError             bool
+	StatsError            bool
 	BuildError            bool
 	PullError             bool
 	PushError             bool
@@ -74,6 +96,12 @@ func NewMock() (*MockClient, Client) {
 		Images:       map[string]*dkc.Image{},
 		createdExecs: map[string]dkc.CreateExecOptions{},
 		Executions:   map[string][]string{},
+
+		BuildAuthConfigs: map[string]dkc.AuthConfigurations{},
+		BuildContexts:    map[string][]string{},
+		BuildArgs:        map[string][]dkc.BuildArg{},
+		ContainerLogs:    map[string]string{},
+		ContainerStats:   map[string]dkc.Stats{},
 	}
 	return md, Client{md, &sync.Mutex{}, map[string]*cacheEntry{}}
 }
@@ -129,17 +157,36 @@ func (dk MockClient) RenameContainer(opts dkc.RenameContainerOptions) error {
 	return nil
 }
 
-func readDockerfile(inp io.Reader) ([]byte, error) {
+// readBuildContext returns the Dockerfile in the build tarball, and the names
+// of the other files in it.
+func readBuildContext(inp io.Reader) (dockerfile []byte, files []string,
+	err error) {
 	tarball := tar.NewReader(inp)
 	for {
 		hdr, err := tarball.Next()
+		if err == io.EOF {
+			break
+		}
 		if err != nil {
-			return nil, fmt.Errorf("malformed build tarball: %s", err.Error())
+			return nil, nil, fmt.Errorf("malformed build tarball: %s",
+				err.Error())
 		}
-		if hdr.Name == "Dockerfile" {
-			return ioutil.ReadAll(tarball)
+
+		if hdr.Name != "Dockerfile" {
+			files = append(files, hdr.Name)
+			continue
 		}
+
+		dockerfile, err = ioutil.ReadAll(tarball)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if dockerfile == nil {
+		return nil, nil, errors.New("malformed build tarball: no Dockerfile")
 	}
+	return dockerfile, files, nil
 }
 
 // BuildImage builds the requested image.
@@ -147,16 +194,24 @@ func (dk MockClient) BuildImage(opts dkc.BuildImageOptions) error {
 	dk.Lock()
 	defer dk.Unlock()
 
+	if opts.OutputStream != nil {
+		fmt.Fprint(opts.OutputStream, dk.BuildOutput)
+	}
+
 	if dk.BuildError {
 		return errors.New("build error")
 	}
 
-	dockerfile, err := readDockerfile(opts.InputStream)
+	dockerfile, files, err := readBuildContext(opts.InputStream)
 	dk.Built[BuildImageOptions{
 		Name:       opts.Name,
 		Dockerfile: string(dockerfile),
+		Target:     opts.Target,
 		NoCache:    opts.NoCache,
 	}] = struct{}{}
+	dk.BuildAuthConfigs[opts.Name] = opts.AuthConfigs
+	dk.BuildContexts[opts.Name] = files
+	dk.BuildArgs[opts.Name] = opts.BuildArgs
 	dk.Images[opts.Name] = &dkc.Image{ID: uuid.NewV4().String()}
 	return err
 }
@@ -185,6 +240,66 @@ func (dk MockClient) InspectImage(name string) (*dkc.Image, error) {
 	return img, nil
 }
 
+// ListImages lists the images in the Images map. Each name that maps to an
+// image is a tag of the image.
+func (dk MockClient) ListImages(opts dkc.ListImagesOptions) ([]dkc.APIImages,
+	error) {
+	dk.Lock()
+	defer dk.Unlock()
+
+	if dk.ListImagesError {
+		return nil, errors.New("list images error")
+	}
+
+	imageMap := map[string]*dkc.APIImages{}
+	var ids []string
+	for name, img := range dk.Images {
+		apiImage, ok := imageMap[img.ID]
+		if !ok {
+			apiImage = &dkc.APIImages{
+				ID:          img.ID,
+				RepoDigests: img.RepoDigests,
+				Size:        img.Size,
+				Created:     img.Created.Unix(),
+			}
+			imageMap[img.ID] = apiImage
+			ids = append(ids, img.ID)
+		}
+		apiImage.RepoTags = append(apiImage.RepoTags, name)
+	}
+
+	sort.Strings(ids)
+	var images []dkc.APIImages
+	for _, id := range ids {
+		sort.Strings(imageMap[id].RepoTags)
+		images = append(images, *imageMap[id])
+	}
+	return images, nil
+}
+
+// RemoveImageExtended removes all the names of the image with the given ID.
+func (dk MockClient) RemoveImageExtended(id string,
+	opts dkc.RemoveImageOptions) error {
+	dk.Lock()
+	defer dk.Unlock()
+
+	if dk.RemoveImageError {
+		return errors.New("remove image error")
+	}
+
+	var found bool
+	for name, img := range dk.Images {
+		if img.ID == id {
+			delete(dk.Images, name)
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("no image with ID %s", id)
+	}
+	return nil
+}
+
 // PullImage pulls the requested image.
 func (dk MockClient) PullImage(opts dkc.PullImageOptions,
 	auth dkc.AuthConfiguration) error {
@@ -256,6 +371,46 @@ func (dk MockClient) ListContainers(opts dkc.ListContainersOptions) ([]dkc.APICo
 	return apics, nil
 }
 
+// Logs writes the logs set in ContainerLogs for the requested container to the
+// output stream.
+func (dk MockClient) Logs(opts dkc.LogsOptions) error {
+	dk.Lock()
+	if dk.LogsError {
+		dk.Unlock()
+		return errors.New("logs error")
+	}
+	logs, ok := dk.ContainerLogs[opts.Container]
+	dk.Unlock()
+
+	if !ok {
+		return ErrNoSuchContainer
+	}
+
+	_, err := io.WriteString(opts.OutputStream, logs)
+	return err
+}
+
+// Stats sends the stats set in ContainerStats for the requested container to
+// the stats channel.
+func (dk MockClient) Stats(opts dkc.StatsOptions) error {
+	defer close(opts.Stats)
+
+	dk.Lock()
+	defer dk.Unlock()
+
+	if dk.StatsError {
+		return errors.New("stats error")
+	}
+
+	stats, ok := dk.ContainerStats[opts.ID]
+	if !ok {
+		return ErrNoSuchContainer
+	}
+
+	opts.Stats <- &stats
+	return nil
+}
+
 // CreateNetwork creates a network according to opts.
 func (dk MockClient) CreateNetwork(opts dkc.CreateNetworkOptions) (*dkc.Network, error) {
 	dk.Lock()
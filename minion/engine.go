@@ -1,7 +1,12 @@
 package minion
 
 import (
+	"crypto/sha1"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/kelda/kelda/blueprint"
 	"github.com/kelda/kelda/db"
@@ -16,11 +21,13 @@ import (
 // to run.
 var updatePolicyTables = []db.TableType{db.BlueprintTable, db.ConnectionTable,
 	db.ContainerTable, db.EtcdTable, db.PlacementTable, db.ImageTable,
-	db.LoadBalancerTable}
+	db.LoadBalancerTable, db.MinionTable}
 
 func syncPolicy(conn db.Conn) {
 	loopLog := util.NewEventTimer("Minion-Update")
-	for range conn.Trigger(db.EtcdTable).C {
+	// The Minion table is watched so that daemon containers are started on
+	// new workers.
+	for range conn.Trigger(db.EtcdTable, db.MinionTable).C {
 		loopLog.LogStart()
 		conn.Txn(updatePolicyTables...).Run(func(view db.Database) error {
 			updatePolicy(view)
@@ -93,23 +100,78 @@ func portPlacements(connections []db.Connection) (placements []db.Placement) {
 }
 
 func updatePlacements(view db.Database, bp blueprint.Blueprint) {
-	connections := view.SelectFromConnection(nil)
-	placements := db.PlacementSlice(portPlacements(connections))
-	for _, sp := range bp.Placements {
-		placements = append(placements, db.Placement{
-			TargetContainer: sp.TargetContainer,
-			Exclusive:       sp.Exclusive,
-			Provider:        sp.Provider,
-			Size:            sp.Size,
-			Region:          sp.Region,
-			FloatingIP:      sp.FloatingIP,
-		})
+	// The connections refer to the instances of daemon containers, but the
+	// placement rules must refer to the daemon containers themselves because
+	// they're what's deployed.
+	daemonSets := map[string]string{}
+	for _, dbc := range view.SelectFromContainer(nil) {
+		if dbc.DaemonSet != "" {
+			daemonSets[dbc.Hostname] = dbc.DaemonSet
+		}
+	}
+	toDaemonSet := func(hostname string) string {
+		if daemonSet, ok := daemonSets[hostname]; ok {
+			return daemonSet
+		}
+		return hostname
 	}
 
+	// Placements contain a map of labels, so they can't be compared directly.
+	// Instead, they're keyed by their string representation, which ignores
+	// the ID and sorts the labels.
 	key := func(val interface{}) interface{} {
 		p := val.(db.Placement)
 		p.ID = 0
-		return p
+		return p.String()
+	}
+
+	var placements db.PlacementSlice
+	seen := map[interface{}]struct{}{}
+	for _, plcm := range portPlacements(view.SelectFromConnection(nil)) {
+		plcm.TargetContainer = toDaemonSet(plcm.TargetContainer)
+		plcm.OtherContainer = toDaemonSet(plcm.OtherContainer)
+		if _, ok := seen[key(plcm)]; ok ||
+			plcm.TargetContainer == plcm.OtherContainer {
+			continue
+		}
+		seen[key(plcm)] = struct{}{}
+		placements = append(placements, plcm)
+	}
+
+	// Each member of a replica or stateful set is deployed separately, so
+	// the placement rules of the set apply to each member. Rules that refer
+	// to a set as the other container match all of its members.
+	containerSets := map[string][]string{}
+	for _, dbc := range view.SelectFromContainer(nil) {
+		set := dbc.ReplicaSet
+		if dbc.StatefulSet != "" {
+			set = dbc.StatefulSet
+		}
+		if set != "" {
+			containerSets[set] = append(containerSets[set], dbc.Hostname)
+		}
+	}
+
+	for _, sp := range bp.Placements {
+		targets := []string{sp.TargetContainer}
+		if members, ok := containerSets[sp.TargetContainer]; ok {
+			targets = members
+		}
+
+		for _, target := range targets {
+			placements = append(placements, db.Placement{
+				TargetContainer: target,
+				Exclusive:       sp.Exclusive,
+				OtherContainer:  sp.OtherContainer,
+				Topology:        sp.Topology,
+				Provider:        sp.Provider,
+				Size:            sp.Size,
+				Region:          sp.Region,
+				FloatingIP:      sp.FloatingIP,
+				Labels:          sp.Labels,
+				Weight:          sp.Weight,
+			})
+		}
 	}
 
 	dbPlacements := db.PlacementSlice(view.SelectFromPlacement(nil))
@@ -129,11 +191,12 @@ func updatePlacements(view db.Database, bp blueprint.Blueprint) {
 }
 
 func updateLoadBalancers(view db.Database, bp blueprint.Blueprint) {
+	members := containerSetMembers(view)
 	var bpLoadBalancers db.LoadBalancerSlice
 	for _, lb := range bp.LoadBalancers {
 		bpLoadBalancers = append(bpLoadBalancers, db.LoadBalancer{
 			Name:      lb.Name,
-			Hostnames: lb.Hostnames,
+			Hostnames: expandContainerSets(lb.Hostnames, members),
 		})
 	}
 
@@ -191,6 +254,18 @@ func updateConnections(view db.Database, bp blueprint.Blueprint) {
 		}
 	}
 
+	// Connections to and from daemon and stateful containers apply to all of
+	// their members. A new slice is built so that the blueprint isn't
+	// modified.
+	members := containerSetMembers(view)
+	var expanded blueprint.ConnectionSlice
+	for _, c := range scs {
+		c.From = expandContainerSets(c.From, members)
+		c.To = expandContainerSets(c.To, members)
+		expanded = append(expanded, c)
+	}
+	scs = expanded
+
 	dbcKey := func(val interface{}) interface{} {
 		c := val.(db.Connection)
 		return fmt.Sprintf("%s %s %d %d", c.From, c.To, c.MinPort, c.MaxPort)
@@ -220,10 +295,57 @@ func updateConnections(view db.Database, bp blueprint.Blueprint) {
 	}
 }
 
-func queryContainers(bp blueprint.Blueprint) []db.Container {
+// containerSetMembers returns the hostnames of the instances of each daemon
+// container, of the members of each stateful container, and of the replicas of
+// each autoscaled container, keyed by the hostname of the container in the
+// blueprint.
+func containerSetMembers(view db.Database) map[string][]string {
+	members := map[string][]string{}
+	for _, dbc := range view.SelectFromContainer(nil) {
+		set := dbc.DaemonSet
+		if dbc.StatefulSet != "" {
+			set = dbc.StatefulSet
+		} else if dbc.ReplicaSet != "" {
+			set = dbc.ReplicaSet
+		}
+		if set != "" {
+			members[set] = append(members[set], dbc.Hostname)
+		}
+	}
+
+	// Sort the members so that the connections and load balancers don't
+	// change unnecessarily.
+	for _, hostnames := range members {
+		sort.Strings(hostnames)
+	}
+	return members
+}
+
+// expandContainerSets replaces the hostnames of daemon, stateful, and
+// autoscaled containers with the hostnames of their members.
+func expandContainerSets(hostnames []string, members map[string][]string) []string {
+	var expanded []string
+	for _, hostname := range hostnames {
+		if setMembers, ok := members[hostname]; ok {
+			expanded = append(expanded, setMembers...)
+		} else {
+			expanded = append(expanded, hostname)
+		}
+	}
+	return expanded
+}
+
+// queryContainers returns the containers that should be stored in the Container
+// table. Daemon containers are replaced by an instance for each worker that
+// satisfies their placement rules, and stateful and autoscaled containers are
+// replaced by their members. `existing` is the number of replicas of each
+// autoscaled container that are already in the table.
+func queryContainers(bp blueprint.Blueprint, workers []db.Minion,
+	existing map[string]int) []db.Container {
+
 	containers := map[string]*db.Container{}
 	for _, c := range bp.Containers {
-		containers[c.Hostname] = &db.Container{
+		dbc := db.Container{
 			BlueprintID:       c.ID,
 			Command:           c.Command,
 			Env:               c.Env,
@@ -233,6 +355,51 @@ func queryContainers(bp blueprint.Blueprint) []db.Container {
 			Hostname:          c.Hostname,
 			Privileged:        c.Privileged,
 			VolumeMounts:      c.VolumeMounts,
+			Security:          c.Security,
+			Sidecars:          queryPodContainers(c.Sidecars),
+			InitContainers:    queryPodContainers(c.InitContainers),
+			Dependencies:      c.Dependencies,
+		}
+		if c.Stateful {
+			for i := 0; i < c.Replicas; i++ {
+				member := dbc
+				member.BlueprintID = hashStr(c.ID + " " + strconv.Itoa(i))
+				member.Hostname = fmt.Sprintf("%s-%d", c.Hostname, i)
+				member.StatefulSet = c.Hostname
+				containers[member.Hostname] = &member
+			}
+			continue
+		}
+
+		if c.Autoscale != nil {
+			for i := 0; i < replicas(c, existing[c.Hostname]); i++ {
+				replica := dbc
+				replica.Hostname = fmt.Sprintf("%s-%d", c.Hostname, i)
+				replica.BlueprintID = hashStr(c.ID + " " +
+					strconv.Itoa(i))
+				replica.ReplicaSet = c.Hostname
+				containers[replica.Hostname] = &replica
+			}
+			continue
+		}
+
+		if !c.Daemon {
+			containers[c.Hostname] = &dbc
+			continue
+		}
+
+		for _, worker := range workers {
+			if !satisfiesPlacements(worker, c.Hostname, bp.Placements) {
+				continue
+			}
+
+			instance := dbc
+			instance.BlueprintID = hashStr(c.ID + " " + worker.PrivateIP)
+			instance.Hostname = fmt.Sprintf("%s.%s", c.Hostname,
+				strings.Replace(worker.PrivateIP, ".", "-", -1))
+			instance.DaemonSet = c.Hostname
+			instance.Minion = worker.PrivateIP
+			containers[instance.Hostname] = &instance
 		}
 	}
 
@@ -244,12 +411,83 @@ func queryContainers(bp blueprint.Blueprint) []db.Container {
 	return ret
 }
 
+// replicas returns the number of replicas of the given autoscaled container.
+// The daemon sets the number within the container's bounds. Until it has
+// chosen a number, such as right after it restarts, the `existing` replicas are
+// kept, within the bounds.
+func replicas(c blueprint.Container, existing int) int {
+	n := c.Replicas
+	if n == 0 {
+		n = existing
+	}
+	if n < c.Autoscale.Min {
+		n = c.Autoscale.Min
+	}
+	if n > c.Autoscale.Max {
+		n = c.Autoscale.Max
+	}
+	return n
+}
+
+// satisfiesPlacements returns whether the given worker satisfies the machine
+// placement rules of the container with the given hostname.
+func satisfiesPlacements(worker db.Minion, hostname string,
+	placements []blueprint.Placement) bool {
+	machine := blueprint.Machine{
+		Provider:   worker.Provider,
+		Region:     worker.Region,
+		Size:       worker.Size,
+		FloatingIP: worker.FloatingIP,
+		Labels:     worker.Labels,
+	}
+	return blueprint.SatisfiesPlacements(machine, hostname, placements)
+}
+
+func hashStr(str string) string {
+	return fmt.Sprintf("%x", sha1.Sum([]byte(str)))
+}
+
+// queryPodContainers returns the given sidecars or init containers, as they
+// should be stored in the Container table. The build options of their images
+// are dropped because they're already tracked by the Image table, and the
+// build contexts would bloat the Container table.
+func queryPodContainers(members []blueprint.Container) []blueprint.Container {
+	var result []blueprint.Container
+	for _, c := range members {
+		result = append(result, blueprint.Container{
+			Hostname: c.Hostname,
+			Image: blueprint.Image{
+				Name:       c.Image.Name,
+				Dockerfile: c.Image.Dockerfile,
+			},
+			Command:           c.Command,
+			Env:               c.Env,
+			FilepathToContent: c.FilepathToContent,
+			Privileged:        c.Privileged,
+			VolumeMounts:      c.VolumeMounts,
+			Security:          c.Security,
+		})
+	}
+	return result
+}
+
 func updateContainers(view db.Database, bp blueprint.Blueprint) {
 	key := func(val interface{}) interface{} {
 		return val.(db.Container).BlueprintID
 	}
 
-	pairs, news, dbcs := join.HashJoin(db.ContainerSlice(queryContainers(bp)),
+	workers := view.SelectFromMinion(func(m db.Minion) bool {
+		return m.Role == db.Worker && m.PrivateIP != ""
+	})
+	existing := map[string]int{}
+	for _, dbc := range view.SelectFromContainer(nil) {
+		if dbc.ReplicaSet != "" {
+			existing[dbc.ReplicaSet]++
+		}
+	}
+
+	pairs, news, dbcs := join.HashJoin(
+		db.ContainerSlice(queryContainers(bp, workers, existing)),
 		db.ContainerSlice(view.SelectFromContainer(nil)), key, key)
 
 	for _, dbc := range dbcs {
@@ -273,26 +511,118 @@ func updateContainers(view db.Database, bp blueprint.Blueprint) {
 		dbc.Hostname = newc.Hostname
 		dbc.Privileged = newc.Privileged
 		dbc.VolumeMounts = newc.VolumeMounts
+		dbc.Security = newc.Security
+		dbc.Sidecars = newc.Sidecars
+		dbc.InitContainers = newc.InitContainers
+		dbc.Dependencies = newc.Dependencies
+		dbc.DaemonSet = newc.DaemonSet
+		dbc.StatefulSet = newc.StatefulSet
+		dbc.ReplicaSet = newc.ReplicaSet
+
+		// The Minion of stateful members is set by placeStatefulSets, and
+		// the Minion of other containers is set once Kubernetes schedules
+		// them.
+		if newc.DaemonSet != "" {
+			dbc.Minion = newc.Minion
+		}
 		view.Commit(dbc)
 	}
+
+	placeStatefulSets(view, workers, bp.Placements)
+}
+
+// placeStatefulSets pins each member of a stateful container to a worker.
+// Members keep their worker for as long as it satisfies their placement rules,
+// so that they're restarted next to their storage. Members without a worker
+// are spread across the workers with the fewest members of the same container.
+// Draining workers are about to be removed, so their members are moved.
+func placeStatefulSets(view db.Database, allWorkers []db.Minion,
+	placements []blueprint.Placement) {
+
+	var workers []db.Minion
+	for _, worker := range allWorkers {
+		if !worker.Draining {
+			workers = append(workers, worker)
+		}
+	}
+	sort.Slice(workers, func(i, j int) bool {
+		return workers[i].PrivateIP < workers[j].PrivateIP
+	})
+	eligible := func(set, ip string) bool {
+		for _, worker := range workers {
+			if worker.PrivateIP == ip {
+				return satisfiesPlacements(worker, set, placements)
+			}
+		}
+		return false
+	}
+
+	members := view.SelectFromContainer(func(dbc db.Container) bool {
+		return dbc.StatefulSet != ""
+	})
+	sort.Sort(db.ContainerSlice(members))
+
+	counts := map[string]map[string]int{}
+	var unplaced []db.Container
+	for _, dbc := range members {
+		if counts[dbc.StatefulSet] == nil {
+			counts[dbc.StatefulSet] = map[string]int{}
+		}
+
+		if dbc.Minion != "" && eligible(dbc.StatefulSet, dbc.Minion) {
+			counts[dbc.StatefulSet][dbc.Minion]++
+		} else {
+			unplaced = append(unplaced, dbc)
+		}
+	}
+
+	for _, dbc := range unplaced {
+		setCounts := counts[dbc.StatefulSet]
+		var best string
+		for _, worker := range workers {
+			if !satisfiesPlacements(worker, dbc.StatefulSet, placements) {
+				continue
+			}
+			if best == "" || setCounts[worker.PrivateIP] < setCounts[best] {
+				best = worker.PrivateIP
+			}
+		}
+
+		if best != "" {
+			setCounts[best]++
+		}
+		if dbc.Minion != best {
+			dbc.Minion = best
+			view.Commit(dbc)
+		}
+	}
 }
 
 func updateImages(view db.Database, bp blueprint.Blueprint) {
+	blueprintImageKey := func(intf interface{}) interface{} {
+		img := intf.(blueprint.Image)
+		return makeImageKey(img.Name, img.Dockerfile, img.Target, img.Context,
+			img.BuildArgs)
+	}
 	dbImageKey := func(intf interface{}) interface{} {
-		return blueprint.Image{
-			Name:       intf.(db.Image).Name,
-			Dockerfile: intf.(db.Image).Dockerfile,
-		}
+		img := intf.(db.Image)
+		return makeImageKey(img.Name, img.Dockerfile, img.Target, img.Context,
+			img.BuildArgs)
 	}
 
 	blueprintImages := blueprintImageSlice(queryImages(bp))
 	dbImages := db.ImageSlice(view.SelectFromImage(nil))
-	_, toAdd, toRemove := join.HashJoin(blueprintImages, dbImages, nil, dbImageKey)
+	_, toAdd, toRemove := join.HashJoin(blueprintImages, dbImages,
+		blueprintImageKey, dbImageKey)
 
 	for _, intf := range toAdd {
+		bpImg := intf.(blueprint.Image)
 		im := view.InsertImage()
-		im.Name = intf.(blueprint.Image).Name
-		im.Dockerfile = intf.(blueprint.Image).Dockerfile
+		im.Name = bpImg.Name
+		im.Dockerfile = bpImg.Dockerfile
+		im.Context = bpImg.Context
+		im.BuildArgs = bpImg.BuildArgs
+		im.Target = bpImg.Target
 		view.Commit(im)
 	}
 
@@ -301,20 +631,62 @@ func updateImages(view db.Database, bp blueprint.Blueprint) {
 	}
 }
 
+// queryImages returns the images that need to be built. If the blueprint
+// resolves image digests, it also returns the public images whose tags need
+// to be resolved.
 func queryImages(bp blueprint.Blueprint) (images []blueprint.Image) {
-	addedImages := map[blueprint.Image]struct{}{}
+	var allImages []blueprint.Image
 	for _, c := range bp.Containers {
-		_, addedImage := addedImages[c.Image]
-		if c.Image.Dockerfile == "" || addedImage {
+		for _, podContainer := range c.PodContainers() {
+			allImages = append(allImages, podContainer.Image)
+		}
+	}
+
+	addedImages := map[imageKey]struct{}{}
+	for _, img := range allImages {
+		key := makeImageKey(img.Name, img.Dockerfile, img.Target,
+			img.Context, img.BuildArgs)
+		_, addedImage := addedImages[key]
+		if addedImage {
 			continue
 		}
 
-		images = append(images, c.Image)
-		addedImages[c.Image] = struct{}{}
+		// Images that are already referenced by digest don't need to be
+		// resolved.
+		if img.Dockerfile == "" && (bp.ImageDigests == "" ||
+			strings.Contains(img.Name, "@")) {
+			continue
+		}
+
+		images = append(images, img)
+		addedImages[key] = struct{}{}
 	}
 	return images
 }
 
+// imageKey is a comparable representation of the inputs used to build an
+// image.
+type imageKey struct {
+	name, dockerfile, target, context, buildArgs string
+}
+
+func makeImageKey(name, dockerfile, target string, context []byte,
+	buildArgs map[string]string) imageKey {
+
+	key := imageKey{
+		name:       name,
+		dockerfile: dockerfile,
+		target:     target,
+		context:    string(context),
+	}
+	if len(buildArgs) != 0 {
+		// The keys of marshalled maps are sorted, so the encoding is stable.
+		argsJSON, _ := json.Marshal(buildArgs)
+		key.buildArgs = string(argsJSON)
+	}
+	return key
+}
+
 type blueprintImageSlice []blueprint.Image
 
 func (slc blueprintImageSlice) Get(ii int) interface{} {
@@ -2,6 +2,7 @@ package minion
 
 import (
 	"fmt"
+	"sort"
 	"testing"
 	"time"
 
@@ -238,13 +239,395 @@ func TestContainerTxn(t *testing.T) {
 	bp.Containers[0].Privileged = true
 	testContainerTxn(t, conn, bp)
 	assert.True(t, fired(trigg))
+
+	// Test that sidecars are stored without the build options of their
+	// images.
+	bp.Containers[0].Sidecars = []blueprint.Container{
+		{
+			Hostname: "envoy",
+			Image: blueprint.Image{Name: "envoy", Dockerfile: "1",
+				Context: []byte("context")},
+		},
+	}
+	testContainerTxn(t, conn, bp)
+	assert.True(t, fired(trigg))
+
+	dbc := conn.SelectFromContainer(func(dbc db.Container) bool {
+		return dbc.Hostname == "foo"
+	})[0]
+	assert.Equal(t, []blueprint.Container{
+		{
+			Hostname: "envoy",
+			Image:    blueprint.Image{Name: "envoy", Dockerfile: "1"},
+		},
+	}, dbc.Sidecars)
+
+	// Test that changes to the init containers and dependencies are
+	// recognized.
+	bp.Containers[0].InitContainers = []blueprint.Container{
+		{Hostname: "migrate", Image: blueprint.Image{Name: "app"}},
+	}
+	bp.Containers[0].Dependencies = []blueprint.Dependency{
+		{Hostname: "db", Port: 5432},
+	}
+	testContainerTxn(t, conn, bp)
+	assert.True(t, fired(trigg))
+
+	dbc = conn.SelectFromContainer(func(dbc db.Container) bool {
+		return dbc.Hostname == "foo"
+	})[0]
+	assert.Equal(t, bp.Containers[0].InitContainers, dbc.InitContainers)
+	assert.Equal(t, bp.Containers[0].Dependencies, dbc.Dependencies)
+}
+
+func TestDaemonSetTxn(t *testing.T) {
+	conn := db.New()
+	conn.Txn(db.MinionTable).Run(func(view db.Database) error {
+		for _, m := range []db.Minion{
+			{Role: db.Worker, PrivateIP: "10.0.0.2", Size: "m4.large"},
+			{Role: db.Worker, PrivateIP: "10.0.0.3", Size: "m4.xlarge",
+				Labels: map[string]string{"tier": "db"}},
+			{Role: db.Master, PrivateIP: "10.0.0.4", Size: "m4.large"},
+		} {
+			m.ID = view.InsertMinion().ID
+			view.Commit(m)
+		}
+		return nil
+	})
+
+	bp := blueprint.Blueprint{
+		Containers: []blueprint.Container{
+			{ID: "agentID", Hostname: "agent", Daemon: true,
+				Image: blueprint.Image{Name: "agent"}},
+			{ID: "webID", Hostname: "web",
+				Image: blueprint.Image{Name: "nginx"}},
+		},
+		Connections: []blueprint.Connection{
+			{From: []string{"agent"}, To: []string{"web"},
+				MinPort: 80, MaxPort: 80},
+			{From: []string{blueprint.PublicInternetLabel},
+				To: []string{"agent"}, MinPort: 9100, MaxPort: 9100},
+			{From: []string{blueprint.PublicInternetLabel},
+				To: []string{"web"}, MinPort: 9100, MaxPort: 9100},
+		},
+		LoadBalancers: []blueprint.LoadBalancer{
+			{Name: "lb", Hostnames: []string{"agent"}},
+		},
+	}
+	testUpdatePolicy(conn, bp)
+
+	// An instance of the daemon container should be created for each worker.
+	var instances []db.Container
+	for _, dbc := range conn.SelectFromContainer(func(dbc db.Container) bool {
+		return dbc.DaemonSet != ""
+	}) {
+		assert.NotEqual(t, "agentID", dbc.BlueprintID)
+		dbc.ID = 0
+		dbc.BlueprintID = ""
+		instances = append(instances, dbc)
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].Hostname < instances[j].Hostname
+	})
+	assert.Equal(t, []db.Container{
+		{Hostname: "agent.10-0-0-2", DaemonSet: "agent", Minion: "10.0.0.2",
+			Image: "agent"},
+		{Hostname: "agent.10-0-0-3", DaemonSet: "agent", Minion: "10.0.0.3",
+			Image: "agent"},
+	}, instances)
+
+	// Connections and load balancers should refer to the instances.
+	instanceHostnames := []string{"agent.10-0-0-2", "agent.10-0-0-3"}
+	var connectionEndpoints [][]string
+	for _, c := range conn.SelectFromConnection(nil) {
+		connectionEndpoints = append(connectionEndpoints, c.From, c.To)
+	}
+	assert.Contains(t, connectionEndpoints, instanceHostnames)
+	assert.NotContains(t, connectionEndpoints, []string{"agent"})
+	assert.Equal(t, instanceHostnames,
+		conn.SelectFromLoadBalancer(nil)[0].Hostnames)
+
+	// Placement rules should refer to the daemon container rather than its
+	// instances.
+	var placements []db.Placement
+	for _, plcm := range conn.SelectFromPlacement(nil) {
+		plcm.ID = 0
+		placements = append(placements, plcm)
+	}
+	assert.Len(t, placements, 2)
+	assert.Contains(t, placements, db.Placement{TargetContainer: "agent",
+		OtherContainer: "web", Exclusive: true})
+	assert.Contains(t, placements, db.Placement{TargetContainer: "web",
+		OtherContainer: "agent", Exclusive: true})
+
+	// Placement rules should limit the workers the daemon runs on.
+	bp.Placements = []blueprint.Placement{
+		{TargetContainer: "agent", Size: "m4.large"},
+	}
+	testUpdatePolicy(conn, bp)
+	instances = conn.SelectFromContainer(func(dbc db.Container) bool {
+		return dbc.DaemonSet != ""
+	})
+	assert.Len(t, instances, 1)
+	assert.Equal(t, "agent.10-0-0-2", instances[0].Hostname)
+	assert.Equal(t, []string{"agent.10-0-0-2"},
+		conn.SelectFromLoadBalancer(nil)[0].Hostnames)
+
+	// Label placement rules should select on the worker's labels.
+	bp.Placements = []blueprint.Placement{
+		{TargetContainer: "agent", Labels: map[string]string{"tier": "db"}},
+	}
+	testUpdatePolicy(conn, bp)
+	instances = conn.SelectFromContainer(func(dbc db.Container) bool {
+		return dbc.DaemonSet != ""
+	})
+	assert.Len(t, instances, 1)
+	assert.Equal(t, "agent.10-0-0-3", instances[0].Hostname)
+
+	bp.Placements[0].Exclusive = true
+	testUpdatePolicy(conn, bp)
+	instances = conn.SelectFromContainer(func(dbc db.Container) bool {
+		return dbc.DaemonSet != ""
+	})
+	assert.Len(t, instances, 1)
+	assert.Equal(t, "agent.10-0-0-2", instances[0].Hostname)
+
+	// Preferences shouldn't limit the workers the daemon runs on.
+	bp.Placements = []blueprint.Placement{
+		{TargetContainer: "agent", Size: "m4.large", Weight: 10},
+	}
+	testUpdatePolicy(conn, bp)
+	instances = conn.SelectFromContainer(func(dbc db.Container) bool {
+		return dbc.DaemonSet != ""
+	})
+	assert.Len(t, instances, 2)
+}
+
+func TestStatefulSetTxn(t *testing.T) {
+	conn := db.New()
+	conn.Txn(db.MinionTable).Run(func(view db.Database) error {
+		for _, m := range []db.Minion{
+			{Role: db.Worker, PrivateIP: "10.0.0.2", Size: "m4.large"},
+			{Role: db.Worker, PrivateIP: "10.0.0.3", Size: "m4.large"},
+			{Role: db.Worker, PrivateIP: "10.0.0.4", Size: "m4.xlarge"},
+		} {
+			m.ID = view.InsertMinion().ID
+			view.Commit(m)
+		}
+		return nil
+	})
+
+	bp := blueprint.Blueprint{
+		Containers: []blueprint.Container{
+			{ID: "zkID", Hostname: "zk", Stateful: true, Replicas: 3,
+				Image: blueprint.Image{Name: "zookeeper"}},
+		},
+		Connections: []blueprint.Connection{
+			{From: []string{"zk"}, To: []string{"zk"},
+				MinPort: 2888, MaxPort: 2888},
+		},
+		Placements: []blueprint.Placement{
+			{TargetContainer: "zk", Size: "m4.large"},
+		},
+	}
+	testUpdatePolicy(conn, bp)
+
+	getMembers := func() map[string]db.Container {
+		members := map[string]db.Container{}
+		for _, dbc := range conn.SelectFromContainer(nil) {
+			assert.Equal(t, "zk", dbc.StatefulSet)
+			members[dbc.Hostname] = dbc
+		}
+		return members
+	}
+
+	// The members should be spread across the workers that satisfy the
+	// placement rules.
+	members := getMembers()
+	assert.Len(t, members, 3)
+	minionCounts := map[string]int{}
+	for _, hostname := range []string{"zk-0", "zk-1", "zk-2"} {
+		dbc, ok := members[hostname]
+		assert.True(t, ok)
+		assert.NotEqual(t, "zkID", dbc.BlueprintID)
+		minionCounts[dbc.Minion]++
+	}
+	assert.Equal(t, map[string]int{"10.0.0.2": 2, "10.0.0.3": 1},
+		minionCounts)
+
+	// Connections should refer to the members.
+	memberHostnames := []string{"zk-0", "zk-1", "zk-2"}
+	connections := conn.SelectFromConnection(nil)
+	assert.Len(t, connections, 1)
+	assert.Equal(t, memberHostnames, connections[0].From)
+	assert.Equal(t, memberHostnames, connections[0].To)
+
+	// Placement rules should apply to each member.
+	var targets []string
+	for _, plcm := range conn.SelectFromPlacement(func(p db.Placement) bool {
+		return p.Size == "m4.large"
+	}) {
+		targets = append(targets, plcm.TargetContainer)
+	}
+	sort.Strings(targets)
+	assert.Equal(t, memberHostnames, targets)
+
+	// Members should keep their workers when the blueprint changes.
+	bp.Containers[0].Image.Name = "zookeeper:3.5"
+	testUpdatePolicy(conn, bp)
+	for hostname, dbc := range getMembers() {
+		assert.Equal(t, members[hostname].ID, dbc.ID)
+		assert.Equal(t, members[hostname].Minion, dbc.Minion)
+		assert.Equal(t, "zookeeper:3.5", dbc.Image)
+	}
+
+	// Members should only be moved if their worker no longer satisfies the
+	// placement rules.
+	conn.Txn(db.MinionTable).Run(func(view db.Database) error {
+		for _, m := range view.SelectFromMinion(nil) {
+			if m.PrivateIP == members["zk-1"].Minion {
+				m.Size = "m4.xlarge"
+				view.Commit(m)
+			}
+		}
+		return nil
+	})
+	testUpdatePolicy(conn, bp)
+	moved := getMembers()
+	for hostname, dbc := range moved {
+		if members[hostname].Minion == members["zk-1"].Minion {
+			assert.NotEqual(t, members["zk-1"].Minion, dbc.Minion)
+		} else {
+			assert.Equal(t, members[hostname].Minion, dbc.Minion)
+		}
+	}
+
+	// Members should be moved off draining workers.
+	draining := moved["zk-0"].Minion
+	conn.Txn(db.MinionTable).Run(func(view db.Database) error {
+		for _, m := range view.SelectFromMinion(nil) {
+			switch m.PrivateIP {
+			case members["zk-1"].Minion:
+				m.Size = "m4.large"
+			case draining:
+				m.Draining = true
+			}
+			view.Commit(m)
+		}
+		return nil
+	})
+	testUpdatePolicy(conn, bp)
+	for _, dbc := range getMembers() {
+		assert.NotEqual(t, draining, dbc.Minion)
+		assert.Equal(t, members["zk-1"].Minion, dbc.Minion)
+	}
+
+	// Removing replicas should remove the highest ordinals.
+	bp.Containers[0].Replicas = 1
+	testUpdatePolicy(conn, bp)
+	members = getMembers()
+	assert.Len(t, members, 1)
+	assert.Contains(t, members, "zk-0")
+}
+
+func TestReplicaSetTxn(t *testing.T) {
+	conn := db.New()
+	bp := blueprint.Blueprint{
+		Containers: []blueprint.Container{
+			{ID: "webID", Hostname: "web", Replicas: 2,
+				Image: blueprint.Image{Name: "nginx"},
+				Autoscale: &blueprint.Autoscale{Min: 1, Max: 3,
+					Metric: blueprint.AutoscaleCPU, Target: 50}},
+		},
+		LoadBalancers: []blueprint.LoadBalancer{
+			{Name: "web-lb", Hostnames: []string{"web"}},
+		},
+		Connections: []blueprint.Connection{
+			{From: []string{"public"}, To: []string{"web"},
+				MinPort: 80, MaxPort: 80},
+		},
+		Placements: []blueprint.Placement{
+			{TargetContainer: "web", Size: "m4.large"},
+		},
+	}
+	testUpdatePolicy(conn, bp)
+
+	getReplicas := func() map[string]db.Container {
+		replicas := map[string]db.Container{}
+		for _, dbc := range conn.SelectFromContainer(nil) {
+			assert.Equal(t, "web", dbc.ReplicaSet)
+			replicas[dbc.Hostname] = dbc
+		}
+		return replicas
+	}
+
+	replicas := getReplicas()
+	assert.Len(t, replicas, 2)
+	assert.Contains(t, replicas, "web-0")
+	assert.Contains(t, replicas, "web-1")
+
+	// Load balancers, connections, and placement rules should refer to the
+	// replicas.
+	replicaHostnames := []string{"web-0", "web-1"}
+	assert.Equal(t, replicaHostnames,
+		conn.SelectFromLoadBalancer(nil)[0].Hostnames)
+	connections := conn.SelectFromConnection(nil)
+	assert.Len(t, connections, 1)
+	assert.Equal(t, replicaHostnames, connections[0].To)
+	var targets []string
+	for _, plcm := range conn.SelectFromPlacement(nil) {
+		if plcm.Size == "m4.large" {
+			targets = append(targets, plcm.TargetContainer)
+		}
+	}
+	sort.Strings(targets)
+	assert.Equal(t, replicaHostnames, targets)
+
+	// Adding replicas shouldn't change the existing ones.
+	bp.Containers[0].Replicas = 3
+	testUpdatePolicy(conn, bp)
+	newReplicas := getReplicas()
+	assert.Len(t, newReplicas, 3)
+	for hostname, dbc := range replicas {
+		assert.Equal(t, dbc.ID, newReplicas[hostname].ID)
+		assert.Equal(t, dbc.BlueprintID, newReplicas[hostname].BlueprintID)
+	}
+	assert.Equal(t, []string{"web-0", "web-1", "web-2"},
+		conn.SelectFromLoadBalancer(nil)[0].Hostnames)
+
+	// Until the daemon chooses a number of replicas, the existing replicas
+	// are kept.
+	bp.Containers[0].Replicas = 0
+	testUpdatePolicy(conn, bp)
+	assert.Len(t, getReplicas(), 3)
+
+	// The number of replicas should be kept within the bounds.
+	bp.Containers[0].Autoscale.Max = 2
+	testUpdatePolicy(conn, bp)
+	assert.Len(t, getReplicas(), 2)
+
+	bp.Containers[0].Replicas = 1
+	testUpdatePolicy(conn, bp)
+	replicas = getReplicas()
+	assert.Len(t, replicas, 1)
+	assert.Contains(t, replicas, "web-0")
+	assert.Equal(t, []string{"web-0"},
+		conn.SelectFromLoadBalancer(nil)[0].Hostnames)
+
+	bp.Containers[0].Autoscale.Min = 2
+	testUpdatePolicy(conn, bp)
+	assert.Len(t, getReplicas(), 2)
+
+	bp.Containers[0].Replicas = 5
+	testUpdatePolicy(conn, bp)
+	assert.Len(t, getReplicas(), 2)
 }
 
 func testContainerTxn(t *testing.T, conn db.Conn, bp blueprint.Blueprint) {
 	testUpdatePolicy(conn, bp)
 	containers := conn.SelectFromContainer(nil)
 
-	for _, e := range queryContainers(bp) {
+	for _, e := range queryContainers(bp, nil, nil) {
 		found := false
 		for i, c := range containers {
 			if e.BlueprintID == c.BlueprintID {
@@ -350,7 +733,7 @@ func TestPlacementTxn(t *testing.T) {
 		key := func(plcmIntf interface{}) interface{} {
 			plcm := plcmIntf.(db.Placement)
 			plcm.ID = 0 // Ignore the Database ID.
-			return plcm
+			return plcm.String()
 		}
 		_, missing, extra := join.HashJoin(db.PlacementSlice(exp), actual,
 			key, key)
@@ -393,6 +776,39 @@ func TestPlacementTxn(t *testing.T) {
 		},
 	)
 
+	// Label placement
+	bp.Placements = []blueprint.Placement{
+		{TargetContainer: "foo", Exclusive: true,
+			Labels: map[string]string{"tier": "db", "env": "prod"}},
+	}
+	checkPlacement(bp,
+		db.Placement{
+			TargetContainer: "foo",
+			Exclusive:       true,
+			Labels:          map[string]string{"env": "prod", "tier": "db"},
+		},
+	)
+
+	// Container placements and preferences
+	bp.Placements = []blueprint.Placement{
+		{TargetContainer: "foo", OtherContainer: "bar", Weight: 50,
+			Topology: blueprint.TopologyRegion},
+		{TargetContainer: "bar", Exclusive: true, OtherContainer: "baz"},
+	}
+	checkPlacement(bp,
+		db.Placement{
+			TargetContainer: "foo",
+			OtherContainer:  "bar",
+			Topology:        blueprint.TopologyRegion,
+			Weight:          50,
+		},
+		db.Placement{
+			TargetContainer: "bar",
+			Exclusive:       true,
+			OtherContainer:  "baz",
+		},
+	)
+
 	// Port placement
 	bp.Placements = nil
 	bp.Connections = []blueprint.Connection{
@@ -444,7 +860,7 @@ func checkImage(t *testing.T, conn db.Conn, bp blueprint.Blueprint, exp ...db.Im
 	key := func(intf interface{}) interface{} {
 		im := intf.(db.Image)
 		im.ID = 0
-		return im
+		return fmt.Sprintf("%+v", im)
 	}
 	_, lonelyLeft, lonelyRight := join.HashJoin(
 		db.ImageSlice(images), db.ImageSlice(exp), key, key)
@@ -527,6 +943,103 @@ func TestImageTxn(t *testing.T) {
 			Dockerfile: "2",
 		},
 	)
+
+	// Ensure images are rebuilt when their context, build args, or target
+	// change.
+	buildImage := blueprint.Image{Name: "a", Dockerfile: "1",
+		Context: []byte("context"), BuildArgs: map[string]string{"k": "v"},
+		Target: "release"}
+	checkImage(t, conn, blueprint.Blueprint{
+		Containers: []blueprint.Container{
+			{
+				ID:    "96189e4ea36c80171fd842ccc4c3438d06061991",
+				Image: buildImage,
+			},
+			{
+				ID:    "c51d206a1414f1fadf5020e5db35feee91410f79",
+				Image: buildImage,
+			},
+		},
+	},
+		db.Image{
+			Name:       "a",
+			Dockerfile: "1",
+			Context:    []byte("context"),
+			BuildArgs:  map[string]string{"k": "v"},
+			Target:     "release",
+		},
+	)
+
+	// Ensure public images are resolved if the blueprint pins digests,
+	// unless they're already referenced by digest.
+	checkImage(t, db.New(), blueprint.Blueprint{
+		ImageDigests: blueprint.PinImageDigests,
+		Containers: []blueprint.Container{
+			{
+				ID:    "475c40d6070969839ba0f88f7a9bd0cc7936aa30",
+				Image: blueprint.Image{Name: "image"},
+			},
+			{
+				ID:    "133c61c61ef4b49ea26717efe0f0468d455fd317",
+				Image: blueprint.Image{Name: "image"},
+			},
+			{
+				ID:    "96189e4ea36c80171fd842ccc4c3438d06061991",
+				Image: blueprint.Image{Name: "pinned@sha256:abc"},
+			},
+			{
+				ID:    "ede1e03efba48e66be3e51aabe03ec77d9f9def9",
+				Image: blueprint.Image{Name: "b", Dockerfile: "1"},
+			},
+		},
+	},
+		db.Image{
+			Name: "image",
+		},
+		db.Image{
+			Name:       "b",
+			Dockerfile: "1",
+		},
+	)
+
+	// Ensure the images of sidecars are built.
+	checkImage(t, db.New(), blueprint.Blueprint{
+		Containers: []blueprint.Container{
+			{
+				ID:    "475c40d6070969839ba0f88f7a9bd0cc7936aa30",
+				Image: blueprint.Image{Name: "image"},
+				Sidecars: []blueprint.Container{
+					{Image: blueprint.Image{
+						Name: "a", Dockerfile: "1"}},
+					{Image: blueprint.Image{Name: "c"}},
+				},
+			},
+		},
+	},
+		db.Image{
+			Name:       "a",
+			Dockerfile: "1",
+		},
+	)
+
+	// Ensure the images of init containers are built.
+	checkImage(t, db.New(), blueprint.Blueprint{
+		Containers: []blueprint.Container{
+			{
+				ID:    "475c40d6070969839ba0f88f7a9bd0cc7936aa30",
+				Image: blueprint.Image{Name: "image"},
+				InitContainers: []blueprint.Container{
+					{Image: blueprint.Image{Name: "migrate",
+						Dockerfile: "2"}},
+				},
+			},
+		},
+	},
+		db.Image{
+			Name:       "migrate",
+			Dockerfile: "2",
+		},
+	)
 }
 
 func checkLoadBalancer(t *testing.T, conn db.Conn, bp blueprint.Blueprint,
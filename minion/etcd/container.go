@@ -87,6 +87,10 @@ func joinContainers(view db.Database, etcdDBCs []db.Container) {
 			FilepathToContent string
 			Privileged        bool
 			VolumeMounts      string
+			Security          string
+			Sidecars          string
+			InitContainers    string
+			Dependencies      string
 		}{
 			Hostname:          dbc.Hostname,
 			IP:                dbc.IP,
@@ -97,6 +101,10 @@ func joinContainers(view db.Database, etcdDBCs []db.Container) {
 			FilepathToContent: containerValueMapKey(dbc.FilepathToContent),
 			Privileged:        dbc.Privileged,
 			VolumeMounts:      fmt.Sprintf("%v", dbc.VolumeMounts),
+			Security:          securityKey(dbc.Security),
+			Sidecars:          podContainersKey(dbc.Sidecars),
+			InitContainers:    podContainersKey(dbc.InitContainers),
+			Dependencies:      fmt.Sprintf("%v", dbc.Dependencies),
 		}
 	}
 
@@ -127,6 +135,13 @@ func joinContainers(view db.Database, etcdDBCs []db.Container) {
 		dbc.Hostname = edbc.Hostname
 		dbc.Privileged = edbc.Privileged
 		dbc.VolumeMounts = edbc.VolumeMounts
+		dbc.Security = edbc.Security
+		dbc.Sidecars = edbc.Sidecars
+		dbc.InitContainers = edbc.InitContainers
+		dbc.Dependencies = edbc.Dependencies
+		dbc.DaemonSet = edbc.DaemonSet
+		dbc.StatefulSet = edbc.StatefulSet
+		dbc.ReplicaSet = edbc.ReplicaSet
 		view.Commit(dbc)
 	}
 }
@@ -140,3 +155,18 @@ func containerValueMapKey(x map[string]blueprint.ContainerValue) string {
 	}
 	return str.MapAsString(m)
 }
+
+// securityKey converts the given security settings into a consistent string.
+func securityKey(security *blueprint.Security) string {
+	// The keys of marshalled maps are sorted, so the encoding is stable.
+	securityJSON, _ := json.Marshal(security)
+	return string(securityJSON)
+}
+
+// podContainersKey converts the given sidecars or init containers into a
+// consistent string.
+func podContainersKey(podContainers []blueprint.Container) string {
+	// The keys of marshalled maps are sorted, so the encoding is stable.
+	podContainersJSON, _ := json.Marshal(podContainers)
+	return string(podContainersJSON)
+}
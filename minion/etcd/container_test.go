@@ -48,6 +48,9 @@ func TestRunContainerOnce(t *testing.T) {
 			"foo": blueprint.NewString("bar"),
 		}
 		dbc.Privileged = true
+		dbc.Sidecars = []blueprint.Container{
+			{Hostname: "envoy", Image: blueprint.Image{Name: "envoy"}},
+		}
 		view.Commit(dbc)
 		return nil
 	})
@@ -81,6 +84,14 @@ func TestRunContainerOnce(t *testing.T) {
         "Hostname": "host",
         "Created": "0001-01-01T00:00:00Z",
         "Privileged": true,
+        "Sidecars": [
+            {
+                "Image": {
+                    "Name": "envoy"
+                },
+                "Hostname": "envoy"
+            }
+        ],
         "Image": "ubuntu"
     }
 ]`
@@ -127,6 +138,9 @@ func TestRunContainerOnce(t *testing.T) {
 		},
 		Hostname:   "host",
 		Privileged: true,
+		Sidecars: []blueprint.Container{
+			{Hostname: "envoy", Image: blueprint.Image{Name: "envoy"}},
+		},
 	}
 	dbcs := conn.SelectFromContainer(nil)
 	assert.Len(t, dbcs, 1)
@@ -91,9 +91,12 @@ func diffMinion(dbMinions, storeMinions []db.Minion) (del, add []db.Minion) {
 		return struct {
 			Role, PrivateIP, HostSubnets       string
 			Provider, Size, Region, FloatingIP string
+			Labels                             string
+			Draining                           bool
 		}{
 			string(m.Role), m.PrivateIP, strings.Join(m.HostSubnets, " "),
 			m.Provider, m.Size, m.Region, m.FloatingIP,
+			strings.Join(db.SortedLabels(m.Labels), ","), m.Draining,
 		}
 	}
 
@@ -26,6 +26,7 @@ func TestWriteMinion(t *testing.T) {
 		m.Provider = "Amazon"
 		m.Size = "Big"
 		m.Region = "Somewhere"
+		m.Labels = map[string]string{"tier": "db"}
 		m.HostSubnets = []string{"foo", "bar"}
 		view.Commit(m)
 		return nil
@@ -53,6 +54,9 @@ func TestWriteMinion(t *testing.T) {
     "Size": "Big",
     "Region": "Somewhere",
     "FloatingIP": "",
+    "Labels": {
+        "tier": "db"
+    },
     "HostSubnets": [
         "foo",
         "bar"
@@ -132,7 +136,9 @@ func randMinion() db.Minion {
 		Provider:    randStr(),
 		Size:        randStr(),
 		Region:      randStr(),
+		Labels:      map[string]string{randStr(): randStr()},
 		HostSubnets: []string{randStr(), randStr()},
+		Draining:    rand.Intn(2) == 0,
 	}
 }
 
@@ -0,0 +1,270 @@
+package gc
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kelda/kelda/counter"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/minion/docker"
+	"github.com/kelda/kelda/minion/registry"
+	"github.com/kelda/kelda/minion/supervisor"
+	"github.com/kelda/kelda/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+/*
+The gc submodule frees disk space used by Docker images that are no longer
+needed. When the disk usage of the machine, as sampled by the metrics submodule,
+exceeds highThreshold, the images that have been unused for the grace period are
+removed, oldest first, until the usage is estimated to be below lowThreshold.
+
+An image is in use if a container on the machine was created from it, or if
+it's referenced by the Container or Image table. Referencing images from the
+tables prevents images that are about to be run, such as images that were just
+built on the leader, from being removed.
+
+On the masters, the registry's garbage collector is also run to free the layers
+of the images that the registry submodule deleted from the registry. While it
+runs, the registry is read-only and the registry submodule is paused.
+*/
+
+var (
+	// Images are removed when the fraction of the disk that's used exceeds
+	// highThreshold, until the fraction is below lowThreshold.
+	highThreshold = 0.8
+	lowThreshold  = 0.7
+
+	// How long an image must be unused before it may be removed.
+	gracePeriod = time.Hour
+
+	// The minimum time between runs of the registry's garbage collector.
+	registryGCInterval = time.Hour
+
+	// How long to wait for the registry to restart in read-only mode.
+	registryRestartTimeout = 5 * time.Minute
+
+	collectInterval = time.Minute
+)
+
+var c = counter.New("GC")
+
+// Run blocks removing unused images when the machine is low on disk space.
+func Run(conn db.Conn, dk docker.Client) {
+	col := newCollector()
+	for range time.Tick(collectInterval) {
+		col.runOnce(conn, dk)
+	}
+}
+
+type collector struct {
+	// The time at which each image, keyed by ID, was first seen unused.
+	unusedSince map[string]time.Time
+
+	lastRegistryGC time.Time
+}
+
+func newCollector() *collector {
+	return &collector{unusedSince: map[string]time.Time{}}
+}
+
+func (col *collector) runOnce(conn db.Conn, dk docker.Client) {
+	candidates, err := col.updateUnused(conn, dk)
+	if err != nil {
+		log.WithError(err).Warn("Failed to find unused images")
+		return
+	}
+
+	used, total, ok := diskUsage(conn)
+	if !ok || float64(used) < highThreshold*float64(total) {
+		return
+	}
+
+	c.Inc("Collect")
+	if conn.MinionSelf().Role == db.Master &&
+		time.Since(col.lastRegistryGC) >= registryGCInterval {
+		col.lastRegistryGC = time.Now()
+		collectRegistry(dk)
+	}
+
+	toFree := float64(used) - lowThreshold*float64(total)
+	var freed int64
+	for _, img := range candidates {
+		if float64(freed) >= toFree {
+			break
+		}
+
+		log.WithField("image", imageName(img)).Info("Removing unused image")
+		if err := dk.RemoveImage(img.ID); err != nil {
+			log.WithError(err).WithField("image", imageName(img)).
+				Warn("Failed to remove image")
+			continue
+		}
+		delete(col.unusedSince, img.ID)
+		freed += img.Size
+	}
+}
+
+// updateUnused records when images were first seen unused, and returns the
+// images that have been unused for longer than the grace period, ordered by
+// how long they've been unused.
+func (col *collector) updateUnused(conn db.Conn, dk docker.Client) (
+	[]docker.Image, error) {
+	images, err := dk.ListImages()
+	if err != nil {
+		return nil, err
+	}
+
+	containers, err := dk.List(nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	inUse := referencedImages(conn)
+	for _, dkc := range containers {
+		inUse[dkc.ImageID] = struct{}{}
+	}
+
+	now := time.Now()
+	current := map[string]struct{}{}
+	var candidates []docker.Image
+	for _, img := range images {
+		current[img.ID] = struct{}{}
+		if isInUse(img, inUse) {
+			delete(col.unusedSince, img.ID)
+			continue
+		}
+
+		since, ok := col.unusedSince[img.ID]
+		if !ok {
+			col.unusedSince[img.ID] = now
+		} else if now.Sub(since) >= gracePeriod {
+			candidates = append(candidates, img)
+		}
+	}
+
+	// Forget about images that have been removed.
+	for id := range col.unusedSince {
+		if _, ok := current[id]; !ok {
+			delete(col.unusedSince, id)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		iSince := col.unusedSince[candidates[i].ID]
+		jSince := col.unusedSince[candidates[j].ID]
+		if !iSince.Equal(jSince) {
+			return iSince.Before(jSince)
+		}
+		return candidates[i].Created.Before(candidates[j].Created)
+	})
+	return candidates, nil
+}
+
+// referencedImages returns the names of the images referenced by the Container
+// and Image tables.
+func referencedImages(conn db.Conn) map[string]struct{} {
+	refs := map[string]struct{}{}
+	conn.Txn(db.ContainerTable, db.ImageTable).Run(func(view db.Database) error {
+		for _, dbc := range view.SelectFromContainer(nil) {
+			for _, podContainer := range dbc.PodContainers() {
+				refs[normalizeName(podContainer.Image.Name)] = struct{}{}
+			}
+		}
+		for _, img := range view.SelectFromImage(nil) {
+			if img.RepoDigest != "" {
+				refs[img.RepoDigest] = struct{}{}
+			}
+		}
+		return nil
+	})
+	return refs
+}
+
+func isInUse(img docker.Image, inUse map[string]struct{}) bool {
+	if _, ok := inUse[img.ID]; ok {
+		return true
+	}
+
+	for _, name := range append(img.RepoTags, img.RepoDigests...) {
+		if _, ok := inUse[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeName adds the implicit "latest" tag to image names without a tag or
+// digest, so that they match the tags reported by Docker.
+func normalizeName(name string) string {
+	if strings.Contains(name, "@") {
+		return name
+	}
+
+	// The last colon may be part of the registry's address rather than a
+	// tag, e.g. "localhost:5000/image".
+	if i := strings.LastIndex(name, ":"); i == -1 ||
+		strings.Contains(name[i:], "/") {
+		return name + ":latest"
+	}
+	return name
+}
+
+func imageName(img docker.Image) string {
+	if len(img.RepoTags) != 0 {
+		return img.RepoTags[0]
+	}
+	if len(img.RepoDigests) != 0 {
+		return img.RepoDigests[0]
+	}
+	return img.ID
+}
+
+// diskUsage returns the used and total bytes of the machine's disk, according
+// to the most recent sample in the Metrics table.
+func diskUsage(conn db.Conn) (used, total uint64, ok bool) {
+	conn.Txn(db.MetricsTable).Run(func(view db.Database) error {
+		for _, m := range view.SelectFromMetrics(func(m db.Metrics) bool {
+			return m.PodName == ""
+		}) {
+			used, total = m.DiskUsed, m.DiskTotal
+		}
+		return nil
+	})
+	return used, total, total != 0
+}
+
+// collectRegistry frees the layers in the registry that aren't used by any
+// image. The garbage collector would delete layers that are uploaded while it
+// runs, so the registry submodule is kept from writing to the registry, and the
+// registry is restarted in read-only mode to reject images replicated from
+// the other masters.
+func collectRegistry(dk docker.Client) {
+	registry.Lock.Lock()
+	defer registry.Lock.Unlock()
+
+	supervisor.SetRegistryReadOnly(true)
+	defer supervisor.SetRegistryReadOnly(false)
+
+	err := util.BackoffWaitFor(func() bool {
+		registries, err := dk.List(map[string][]string{
+			"name": {supervisor.RegistryName}}, false)
+		return err == nil && len(registries) == 1 &&
+			supervisor.IsRegistryReadOnly(registries[0])
+	}, 5*time.Second, registryRestartTimeout)
+	if err != nil {
+		log.Warn("Registry didn't restart in read-only mode. Skipping " +
+			"registry garbage collection")
+		return
+	}
+
+	log.Info("Running registry garbage collector")
+	output, err := dk.Exec(supervisor.RegistryName, "registry",
+		"garbage-collect", "/etc/docker/registry/config.yml")
+	if err != nil {
+		log.WithError(err).WithField("output", output).
+			Warn("Failed to run registry garbage collector")
+	}
+}
@@ -0,0 +1,149 @@
+package gc
+
+import (
+	"testing"
+	"time"
+
+	dkc "github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/minion/docker"
+	"github.com/kelda/kelda/minion/supervisor"
+)
+
+func TestRunOnce(t *testing.T) {
+	t.Parallel()
+
+	md, dk := docker.NewMock()
+	md.Images["running:latest"] = &dkc.Image{ID: "running", Size: 100}
+	md.Images["scheduled:latest"] = &dkc.Image{ID: "scheduled", Size: 100}
+	md.Images["sidecar:latest"] = &dkc.Image{ID: "sidecar", Size: 100}
+	md.Images["built"] = &dkc.Image{ID: "built", Size: 100,
+		RepoDigests: []string{"localhost:5000/built@sha256:1"}}
+	md.Images["old:latest"] = &dkc.Image{ID: "old", Size: 200,
+		Created: time.Unix(1, 0)}
+	md.Images["new:latest"] = &dkc.Image{ID: "new", Size: 200,
+		Created: time.Unix(2, 0)}
+	_, err := dk.Run(docker.RunOptions{Name: "app", Image: "running:latest"})
+	assert.NoError(t, err)
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		dbc := view.InsertContainer()
+		dbc.Image = "scheduled"
+		dbc.Sidecars = []blueprint.Container{
+			{Image: blueprint.Image{Name: "sidecar"}},
+		}
+		view.Commit(dbc)
+
+		img := view.InsertImage()
+		img.RepoDigest = "localhost:5000/built@sha256:1"
+		view.Commit(img)
+
+		m := view.InsertMinion()
+		m.Self = true
+		m.Role = db.Worker
+		view.Commit(m)
+
+		metrics := view.InsertMetrics()
+		metrics.DiskUsed = 850
+		metrics.DiskTotal = 1000
+		view.Commit(metrics)
+		return nil
+	})
+
+	// Images shouldn't be removed until they've been unused for the grace
+	// period.
+	col := newCollector()
+	col.runOnce(conn, dk)
+	assert.Len(t, md.Images, 6)
+	assert.Len(t, col.unusedSince, 2)
+
+	// Once the grace period passes, the unused images should be removed,
+	// oldest first, until enough space is freed to get below the low
+	// threshold.
+	past := time.Now().Add(-2 * gracePeriod)
+	for id := range col.unusedSince {
+		col.unusedSince[id] = past
+	}
+	col.runOnce(conn, dk)
+	assert.Len(t, md.Images, 5)
+	assert.NotContains(t, md.Images, "old:latest")
+
+	// Images shouldn't be removed if there's enough disk space.
+	setDiskUsage(conn, 500)
+	col.runOnce(conn, dk)
+	assert.Len(t, md.Images, 5)
+
+	setDiskUsage(conn, 950)
+	col.runOnce(conn, dk)
+	assert.Equal(t, []string{"built", "running:latest", "scheduled:latest",
+		"sidecar:latest"}, imageNames(md))
+	assert.Empty(t, col.unusedSince)
+}
+
+func TestCollectRegistry(t *testing.T) {
+	t.Parallel()
+
+	md, dk := docker.NewMock()
+	// The supervisor isn't running, so the registry is started in read-only
+	// mode as if the supervisor had already restarted it.
+	id, err := dk.Run(docker.RunOptions{Name: supervisor.RegistryName,
+		Image: "registry", Env: map[string]string{
+			"REGISTRY_STORAGE_MAINTENANCE_READONLY": `{"enabled": true}`,
+		}})
+	assert.NoError(t, err)
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		m := view.InsertMinion()
+		m.Self = true
+		m.Role = db.Master
+		view.Commit(m)
+
+		metrics := view.InsertMetrics()
+		metrics.DiskUsed = 900
+		metrics.DiskTotal = 1000
+		view.Commit(metrics)
+		return nil
+	})
+
+	col := newCollector()
+	col.runOnce(conn, dk)
+	assert.Equal(t, []string{"registry garbage-collect " +
+		"/etc/docker/registry/config.yml"}, md.Executions[id])
+
+	// The registry's garbage collector shouldn't be run too often.
+	col.runOnce(conn, dk)
+	assert.Len(t, md.Executions[id], 1)
+}
+
+func TestNormalizeName(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "nginx:latest", normalizeName("nginx"))
+	assert.Equal(t, "nginx:1.15", normalizeName("nginx:1.15"))
+	assert.Equal(t, "nginx@sha256:1", normalizeName("nginx@sha256:1"))
+	assert.Equal(t, "localhost:5000/app:latest",
+		normalizeName("localhost:5000/app"))
+}
+
+func setDiskUsage(conn db.Conn, used uint64) {
+	conn.Txn(db.MetricsTable).Run(func(view db.Database) error {
+		m := view.SelectFromMetrics(nil)[0]
+		m.DiskUsed = used
+		view.Commit(m)
+		return nil
+	})
+}
+
+func imageNames(md *docker.MockClient) []string {
+	images, _ := md.ListImages(dkc.ListImagesOptions{})
+	var names []string
+	for _, img := range images {
+		names = append(names, img.RepoTags...)
+	}
+	return names
+}
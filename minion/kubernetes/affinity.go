@@ -3,7 +3,9 @@ package kubernetes
 import (
 	"errors"
 	"sort"
+	"strings"
 
+	"github.com/kelda/kelda/blueprint"
 	"github.com/kelda/kelda/db"
 
 	log "github.com/sirupsen/logrus"
@@ -18,15 +20,35 @@ const regionKey = "kelda.io/host.region"
 const sizeKey = "kelda.io/host.size"
 const floatingIPKey = "kelda.io/host.floatingIP"
 
+// privateIPKey is used to pin the members of stateful containers to their
+// machines.
+const privateIPKey = "kelda.io/host.privateIP"
+
+// userLabelPrefix is prepended to the keys of the labels defined on machines
+// in the blueprint so that they don't conflict with the labels managed by
+// Kelda and Kubernetes.
+const userLabelPrefix = "kelda.io/label."
+
+// topologyKeys maps the topologies of container placement constraints to the
+// node labels that define their domains.
+var topologyKeys = map[string]string{
+	"":                        "kubernetes.io/hostname",
+	blueprint.TopologyMachine: "kubernetes.io/hostname",
+	blueprint.TopologyRegion:  regionKey,
+}
+
 // toAffinities converts the Kelda placement rules into the format expected by
 // the Kubernetes deployment engine. It aggregates all of the placement rules
 // for each TargetContainer into a single Kubernetes Affinity rule. The
 // placements in terms of other containers are combined under the
-// Affinity.PodAntiAffinity field, and the machine placements are combined
-// under Affinity.NodeAffinity.
+// Affinity.PodAffinity and Affinity.PodAntiAffinity fields, and the machine
+// placements are combined under Affinity.NodeAffinity. Placements with a
+// weight are converted into preferred terms rather than requirements.
 // The labels referenced in Affinity.NodeAffinity are managed by the
-// updateNodeLabels function.
-func toAffinities(placements []db.Placement) map[string]*corev1.Affinity {
+// updateNodeLabels function. Placements relative to the replica and stateful
+// sets in `containerSets` select all of the members of the set.
+func toAffinities(placements []db.Placement,
+	containerSets map[string]struct{}) map[string]*corev1.Affinity {
 	// Sort the placements so the generated affinities will be consistent.
 	// Otherwise, Kubernetes might treat a difference in affinity term
 	// orderings as a different deployment spec, and restart the existing
@@ -42,7 +64,7 @@ func toAffinities(placements []db.Placement) map[string]*corev1.Affinity {
 		}
 
 		if plcm.OtherContainer != "" {
-			handlePodAffinity(affinity, plcm.OtherContainer, plcm.Exclusive)
+			handlePodAffinity(affinity, plcm, containerSets)
 		}
 
 		// Note that we don't use a map because the order of the constraints in
@@ -55,44 +77,123 @@ func toAffinities(placements []db.Placement) map[string]*corev1.Affinity {
 			{sizeKey, plcm.Size},
 			{floatingIPKey, plcm.FloatingIP},
 		}
+		for _, label := range db.SortedLabels(plcm.Labels) {
+			kv := strings.SplitN(label, "=", 2)
+			nodeConstraints = append(nodeConstraints,
+				struct{ key, val string }{userLabelPrefix + kv[0], kv[1]})
+		}
+
+		// All of the constraints of a preference must be satisfied for the
+		// preference to apply, so they're combined into a single term.
+		var preference corev1.NodeSelectorTerm
 		for _, nodeConstraint := range nodeConstraints {
 			if nodeConstraint.val == "" {
 				continue
 			}
-			handleNodeAffinity(affinity, nodeConstraint.key,
-				nodeConstraint.val, plcm.Exclusive)
+
+			if plcm.Weight == 0 {
+				handleNodeAffinity(affinity, nodeConstraint.key,
+					nodeConstraint.val, plcm.Exclusive)
+				continue
+			}
+
+			preference.MatchExpressions = append(preference.MatchExpressions,
+				nodeSelectorRequirement(nodeConstraint.key,
+					nodeConstraint.val, plcm.Exclusive))
+		}
+
+		if len(preference.MatchExpressions) != 0 {
+			if affinity.NodeAffinity == nil {
+				affinity.NodeAffinity = &corev1.NodeAffinity{}
+			}
+			preferences := &affinity.NodeAffinity.
+				PreferredDuringSchedulingIgnoredDuringExecution
+			*preferences = append(*preferences,
+				corev1.PreferredSchedulingTerm{
+					Weight:     int32(plcm.Weight),
+					Preference: preference,
+				})
 		}
 	}
 	return targetToAffinity
 }
 
 // handlePodAffinity modifies the given affinity to account for the given pod
-// placement constraint.
-func handlePodAffinity(affinity *corev1.Affinity, otherContainer string, exclusive bool) {
-	if exclusive {
+// placement constraint. Exclusive constraints are converted into pod
+// anti-affinities, and other constraints into pod affinities.
+func handlePodAffinity(affinity *corev1.Affinity, plcm db.Placement,
+	containerSets map[string]struct{}) {
+	topologyKey, ok := topologyKeys[plcm.Topology]
+	if !ok {
+		log.WithField("topology", plcm.Topology).Warning(
+			"Ignoring placement constraint with unknown topology")
+		return
+	}
+
+	selectorKey := hostnameKey
+	if _, ok := containerSets[plcm.OtherContainer]; ok {
+		selectorKey = containerSetKey
+	}
+
+	term := corev1.PodAffinityTerm{
+		LabelSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{
+				selectorKey: plcm.OtherContainer,
+			},
+		},
+		TopologyKey: topologyKey,
+	}
+
+	var required *[]corev1.PodAffinityTerm
+	var preferred *[]corev1.WeightedPodAffinityTerm
+	if plcm.Exclusive {
 		if affinity.PodAntiAffinity == nil {
 			affinity.PodAntiAffinity = &corev1.PodAntiAffinity{}
 		}
-
-		term := corev1.PodAffinityTerm{
-			LabelSelector: &metav1.LabelSelector{
-				MatchLabels: map[string]string{
-					hostnameKey: otherContainer,
-				},
-			},
-			TopologyKey: "kubernetes.io/hostname",
+		required = &affinity.PodAntiAffinity.
+			RequiredDuringSchedulingIgnoredDuringExecution
+		preferred = &affinity.PodAntiAffinity.
+			PreferredDuringSchedulingIgnoredDuringExecution
+	} else {
+		if affinity.PodAffinity == nil {
+			affinity.PodAffinity = &corev1.PodAffinity{}
 		}
-		podAffinities := &affinity.PodAntiAffinity.
+		required = &affinity.PodAffinity.
 			RequiredDuringSchedulingIgnoredDuringExecution
-		*podAffinities = append(*podAffinities, term)
+		preferred = &affinity.PodAffinity.
+			PreferredDuringSchedulingIgnoredDuringExecution
+	}
+
+	if plcm.Weight == 0 {
+		*required = append(*required, term)
 	} else {
-		// XXX: This is not difficult to implement with
-		// Kubernetes, but it was not supported in the
-		// original Kelda scheduler.
-		log.WithField("otherContainer", otherContainer).Warning(
-			"Kelda currently does not support inclusive " +
-				"container placement constraints")
+		*preferred = append(*preferred, corev1.WeightedPodAffinityTerm{
+			Weight:          int32(plcm.Weight),
+			PodAffinityTerm: term,
+		})
+	}
+}
+
+// containerSetName returns the name of the replica or stateful set that the
+// given container is a member of, or the empty string if it isn't a member of
+// either.
+func containerSetName(dbc db.Container) string {
+	if dbc.StatefulSet != "" {
+		return dbc.StatefulSet
 	}
+	return dbc.ReplicaSet
+}
+
+// containerSetNames returns the names of the replica and stateful sets that
+// the given containers are members of.
+func containerSetNames(containers []db.Container) map[string]struct{} {
+	sets := map[string]struct{}{}
+	for _, dbc := range containers {
+		if set := containerSetName(dbc); set != "" {
+			sets[set] = struct{}{}
+		}
+	}
+	return sets
 }
 
 // handleNodeAffinity modifies the given affinity to account for the given node
@@ -107,20 +208,27 @@ func handleNodeAffinity(affinity *corev1.Affinity, key, value string, exclusive
 		}
 	}
 
+	matchExpressions := &affinity.NodeAffinity.
+		RequiredDuringSchedulingIgnoredDuringExecution.
+		NodeSelectorTerms[0].MatchExpressions
+	*matchExpressions = append(*matchExpressions,
+		nodeSelectorRequirement(key, value, exclusive))
+}
+
+// nodeSelectorRequirement returns a requirement that the node label with the
+// given key has the given value, or, if exclusive, doesn't have the value.
+func nodeSelectorRequirement(key, value string,
+	exclusive bool) corev1.NodeSelectorRequirement {
+
 	operator := corev1.NodeSelectorOpIn
 	if exclusive {
 		operator = corev1.NodeSelectorOpNotIn
 	}
-	match := corev1.NodeSelectorRequirement{
+	return corev1.NodeSelectorRequirement{
 		Key:      key,
 		Operator: operator,
 		Values:   []string{value},
 	}
-
-	matchExpressions := &affinity.NodeAffinity.
-		RequiredDuringSchedulingIgnoredDuringExecution.
-		NodeSelectorTerms[0].MatchExpressions
-	*matchExpressions = append(*matchExpressions, match)
 }
 
 // updateNodeLabels should be called regularly to sync the metadata about nodes
@@ -134,6 +242,10 @@ func updateNodeLabels(nodes []db.Minion, nodesClient clientv1.NodeInterface) {
 			regionKey:     node.Region,
 			sizeKey:       node.Size,
 			floatingIPKey: node.FloatingIP,
+			privateIPKey:  node.PrivateIP,
+		}
+		for key, value := range node.Labels {
+			nodeToLabels[node.PrivateIP][userLabelPrefix+key] = value
 		}
 	}
 
@@ -165,6 +277,15 @@ func updateNodeLabels(nodes []db.Minion, nodesClient clientv1.NodeInterface) {
 			}
 		}
 
+		// Remove the labels that were removed from the machine.
+		for key := range node.Labels {
+			_, ok := labels[key]
+			if strings.HasPrefix(key, userLabelPrefix) && !ok {
+				delete(node.Labels, key)
+				needsUpdate = true
+			}
+		}
+
 		if !needsUpdate {
 			continue
 		}
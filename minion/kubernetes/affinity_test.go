@@ -3,6 +3,7 @@ package kubernetes
 import (
 	"testing"
 
+	"github.com/kelda/kelda/blueprint"
 	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/minion/kubernetes/mocks"
 
@@ -25,7 +26,7 @@ func TestToAffinities(t *testing.T) {
 			Provider: "Amazon", Size: "m3.medium"},
 	}
 
-	affinityMap := toAffinities(placements)
+	affinityMap := toAffinities(placements, nil)
 	assert.Len(t, affinityMap, 2)
 
 	exp := &corev1.Affinity{
@@ -80,6 +81,145 @@ func TestToAffinities(t *testing.T) {
 	assert.Equal(t, exp, affinityMap[targetContainerB])
 }
 
+func TestToAffinitiesPreferences(t *testing.T) {
+	t.Parallel()
+
+	affinity := toAffinities([]db.Placement{
+		{ID: 1, TargetContainer: "web", OtherContainer: "cache"},
+		{ID: 2, TargetContainer: "web", OtherContainer: "web", Exclusive: true,
+			Topology: blueprint.TopologyRegion, Weight: 100},
+		{ID: 3, TargetContainer: "web", OtherContainer: "db",
+			Topology: blueprint.TopologyMachine, Weight: 50},
+		{ID: 4, TargetContainer: "web", Provider: "Amazon",
+			Region: "us-west-1", Weight: 10},
+		{ID: 5, TargetContainer: "web", Size: "m3.medium", Exclusive: true,
+			Weight: 20},
+
+		// Placements with unknown topologies should be ignored.
+		{ID: 6, TargetContainer: "web", OtherContainer: "db",
+			Topology: "unknown"},
+	}, nil)["web"]
+
+	selector := func(hostname string) *metav1.LabelSelector {
+		return &metav1.LabelSelector{
+			MatchLabels: map[string]string{hostnameKey: hostname},
+		}
+	}
+	exp := &corev1.Affinity{
+		PodAffinity:     &corev1.PodAffinity{},
+		PodAntiAffinity: &corev1.PodAntiAffinity{},
+		NodeAffinity:    &corev1.NodeAffinity{},
+	}
+	exp.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution =
+		[]corev1.PodAffinityTerm{{
+			LabelSelector: selector("cache"),
+			TopologyKey:   "kubernetes.io/hostname",
+		}}
+	exp.PodAffinity.PreferredDuringSchedulingIgnoredDuringExecution =
+		[]corev1.WeightedPodAffinityTerm{{
+			Weight: 50,
+			PodAffinityTerm: corev1.PodAffinityTerm{
+				LabelSelector: selector("db"),
+				TopologyKey:   "kubernetes.io/hostname",
+			},
+		}}
+	exp.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution =
+		[]corev1.WeightedPodAffinityTerm{{
+			Weight: 100,
+			PodAffinityTerm: corev1.PodAffinityTerm{
+				LabelSelector: selector("web"),
+				TopologyKey:   regionKey,
+			},
+		}}
+	requirement := func(key string, op corev1.NodeSelectorOperator,
+		val string) corev1.NodeSelectorRequirement {
+		return corev1.NodeSelectorRequirement{
+			Key: key, Operator: op, Values: []string{val}}
+	}
+	exp.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution =
+		[]corev1.PreferredSchedulingTerm{
+			{Weight: 10, Preference: corev1.NodeSelectorTerm{
+				MatchExpressions: []corev1.NodeSelectorRequirement{
+					requirement(providerKey, corev1.NodeSelectorOpIn,
+						"Amazon"),
+					requirement(regionKey, corev1.NodeSelectorOpIn,
+						"us-west-1"),
+				},
+			}},
+			{Weight: 20, Preference: corev1.NodeSelectorTerm{
+				MatchExpressions: []corev1.NodeSelectorRequirement{
+					requirement(sizeKey, corev1.NodeSelectorOpNotIn,
+						"m3.medium"),
+				},
+			}},
+		}
+	assert.Equal(t, exp, affinity)
+}
+
+func TestToAffinitiesLabels(t *testing.T) {
+	t.Parallel()
+
+	placements := []db.Placement{
+		{ID: 1, TargetContainer: "db", Size: "m4.large",
+			Labels: map[string]string{"tier": "db", "env": "prod"}},
+		{ID: 2, TargetContainer: "db", Exclusive: true,
+			Labels: map[string]string{"spot": "true"}},
+		{ID: 3, TargetContainer: "db", Weight: 10,
+			Labels: map[string]string{"ssd": "true"}},
+	}
+
+	exp := &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{}}
+	exp.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution =
+		&corev1.NodeSelector{
+			NodeSelectorTerms: []corev1.NodeSelectorTerm{
+				{MatchExpressions: []corev1.NodeSelectorRequirement{
+					nodeSelectorRequirement(sizeKey, "m4.large",
+						false),
+					nodeSelectorRequirement(userLabelPrefix+"env",
+						"prod", false),
+					nodeSelectorRequirement(userLabelPrefix+"tier",
+						"db", false),
+					nodeSelectorRequirement(userLabelPrefix+"spot",
+						"true", true),
+				}},
+			},
+		}
+	exp.NodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution =
+		[]corev1.PreferredSchedulingTerm{{
+			Weight: 10,
+			Preference: corev1.NodeSelectorTerm{
+				MatchExpressions: []corev1.NodeSelectorRequirement{
+					nodeSelectorRequirement(userLabelPrefix+"ssd",
+						"true", false),
+				},
+			},
+		}}
+	assert.Equal(t, exp, toAffinities(placements, nil)["db"])
+}
+
+func TestToAffinitiesContainerSets(t *testing.T) {
+	t.Parallel()
+
+	affinity := toAffinities([]db.Placement{
+		{TargetContainer: "web-0", OtherContainer: "db"},
+		{TargetContainer: "web-0", OtherContainer: "cache"},
+	}, map[string]struct{}{"db": {}})["web-0"]
+	assert.Equal(t, []corev1.PodAffinityTerm{
+		{
+			LabelSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{containerSetKey: "db"},
+			},
+			TopologyKey: "kubernetes.io/hostname",
+		},
+		{
+			LabelSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{hostnameKey: "cache"},
+			},
+			TopologyKey: "kubernetes.io/hostname",
+		},
+	}, affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution)
+}
+
 func TestUpdateNodeLabels(t *testing.T) {
 	t.Parallel()
 	nodesClient := &mocks.NodeInterface{}
@@ -132,6 +272,7 @@ func TestUpdateNodeLabels(t *testing.T) {
 			Labels: map[string]string{
 				providerKey:       minionA.Provider,
 				floatingIPKey:     minionA.FloatingIP,
+				privateIPKey:      minionA.PrivateIP,
 				regionKey:         "",
 				sizeKey:           "",
 				kubeHostnameLabel: minionA.PrivateIP,
@@ -144,6 +285,7 @@ func TestUpdateNodeLabels(t *testing.T) {
 			Labels: map[string]string{
 				providerKey:       minionB.Provider,
 				floatingIPKey:     "",
+				privateIPKey:      minionB.PrivateIP,
 				regionKey:         "",
 				sizeKey:           "",
 				kubeHostnameLabel: minionB.PrivateIP,
@@ -176,6 +318,30 @@ func TestUpdateNodeLabels(t *testing.T) {
 
 	updateNodeLabels([]db.Minion{minionA, minionB}, nodesClient)
 	nodesClient.AssertExpectations(t)
+
+	// Test that labels defined in the blueprint are added with a prefix.
+	nodesClient.On("List", mock.Anything).Return(&corev1.NodeList{
+		Items: []corev1.Node{copyNode(newNodeToUpdate), nodeToUpdateB},
+	}, nil).Once()
+
+	minionA.Labels = map[string]string{"tier": "db"}
+	labeledNode := copyNode(newNodeToUpdate)
+	labeledNode.Labels[userLabelPrefix+"tier"] = "db"
+	nodesClient.On("Update", &labeledNode).Return(nil, nil).Once()
+
+	updateNodeLabels([]db.Minion{minionA, minionB}, nodesClient)
+	nodesClient.AssertExpectations(t)
+
+	// Test that labels removed from the blueprint are removed from the node.
+	nodesClient.On("List", mock.Anything).Return(&corev1.NodeList{
+		Items: []corev1.Node{copyNode(labeledNode), nodeToUpdateB},
+	}, nil).Once()
+
+	minionA.Labels = nil
+	nodesClient.On("Update", &newNodeToUpdate).Return(nil, nil).Once()
+
+	updateNodeLabels([]db.Minion{minionA, minionB}, nodesClient)
+	nodesClient.AssertExpectations(t)
 }
 
 func privateIPAddress(ip string) corev1.NodeStatus {
@@ -64,20 +64,28 @@ func updateConfigMaps(conn db.Conn, configMapsClient clientv1.ConfigMapInterface
 	return noErrors
 }
 
-// getDesiredConfigMaps creates a config map for each unique container
-// filepathToContent. The name of the config map is a unique and consistent
+// getDesiredConfigMaps creates a config map for each unique filepathToContent
+// of the containers in the pods. The name of the config map is a unique and consistent
 // identifier based on the contents of the map. See the documentation for
 // configMapName for more details.
 func getDesiredConfigMaps(conn db.Conn) (configMaps []corev1.ConfigMap) {
 	// Filter out duplicate configMaps to avoid attempting to create two
 	// configMaps with the same name.
-	hashes := map[string]struct{}{}
+	var filepathToContents []map[string]blueprint.ContainerValue
 	for _, dbc := range conn.SelectFromContainer(nil) {
-		if len(dbc.FilepathToContent) == 0 {
+		for _, podContainer := range dbc.PodContainers() {
+			filepathToContents = append(filepathToContents,
+				podContainer.FilepathToContent)
+		}
+	}
+
+	hashes := map[string]struct{}{}
+	for _, filepathToContent := range filepathToContents {
+		if len(filepathToContent) == 0 {
 			continue
 		}
 
-		rawStrings, _ := blueprint.DivideContainerValues(dbc.FilepathToContent)
+		rawStrings, _, _, _ := blueprint.DivideContainerValues(filepathToContent)
 		if len(rawStrings) == 0 {
 			continue
 		}
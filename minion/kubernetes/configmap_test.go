@@ -44,6 +44,18 @@ func TestUpdateConfigMaps(t *testing.T) {
 		},
 	}
 
+	fileMapD := map[string]blueprint.ContainerValue{
+		"sidecar": blueprint.NewString("conf"),
+	}
+	configMapD := corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: configMapName(map[string]string{"sidecar": "conf"}),
+		},
+		Data: map[string]string{
+			configMapKey("sidecar"): "conf",
+		},
+	}
+
 	var fileMapAID int
 	conn.Txn(db.ContainerTable).Run(func(view db.Database) error {
 		dbc := view.InsertContainer()
@@ -63,14 +75,20 @@ func TestUpdateConfigMaps(t *testing.T) {
 		// A container without a filepathToContent.
 		dbc = view.InsertContainer()
 		view.Commit(dbc)
+
+		// A container whose sidecar has a filepathToContent.
+		dbc = view.InsertContainer()
+		dbc.Sidecars = []blueprint.Container{{FilepathToContent: fileMapD}}
+		view.Commit(dbc)
 		return nil
 	})
 
 	// At first, there are no existing config maps, so updateConfigMaps should
-	// create both config maps.
+	// create all of the config maps.
 	client.On("List", mock.Anything).Return(&corev1.ConfigMapList{}, nil).Once()
 	client.On("Create", &configMapA).Return(nil, nil).Once()
 	client.On("Create", &configMapB).Return(nil, nil).Once()
+	client.On("Create", &configMapD).Return(nil, nil).Once()
 	assert.True(t, updateConfigMaps(conn, client))
 	client.AssertExpectations(t)
 
@@ -98,7 +116,7 @@ func TestUpdateConfigMaps(t *testing.T) {
 	})
 
 	client.On("List", mock.Anything).Return(&corev1.ConfigMapList{
-		Items: []corev1.ConfigMap{configMapA, configMapB},
+		Items: []corev1.ConfigMap{configMapA, configMapB, configMapD},
 	}, nil).Once()
 	client.On("Create", &configMapC).Return(nil, nil).Once()
 	client.On("Delete", configMapA.Name, mock.Anything).Return(nil).Once()
@@ -109,7 +127,7 @@ func TestUpdateConfigMaps(t *testing.T) {
 	// If there are no changes to the filepathToContent maps, then no actions
 	// need to be taken.
 	client.On("List", mock.Anything).Return(&corev1.ConfigMapList{
-		Items: []corev1.ConfigMap{configMapC, configMapB},
+		Items: []corev1.ConfigMap{configMapC, configMapB, configMapD},
 	}, nil).Once()
 	assert.True(t, updateConfigMaps(conn, client))
 
@@ -121,10 +139,11 @@ func TestUpdateConfigMaps(t *testing.T) {
 		return nil
 	})
 	client.On("List", mock.Anything).Return(&corev1.ConfigMapList{
-		Items: []corev1.ConfigMap{configMapC, configMapB},
+		Items: []corev1.ConfigMap{configMapC, configMapB, configMapD},
 	}, nil).Once()
 	client.On("Delete", configMapB.Name, mock.Anything).Return(nil).Once()
 	client.On("Delete", configMapC.Name, mock.Anything).Return(nil).Once()
+	client.On("Delete", configMapD.Name, mock.Anything).Return(nil).Once()
 	assert.True(t, updateConfigMaps(conn, client))
 }
 
@@ -0,0 +1,192 @@
+package kubernetes
+
+import (
+	"encoding/json"
+
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/join"
+
+	log "github.com/sirupsen/logrus"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	appsclient "k8s.io/client-go/kubernetes/typed/apps/v1"
+	clientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// updateDaemonSets syncs the daemon containers specified by the user into
+// Kubernetes daemon sets. As with deployments, existing daemon sets are always
+// updated, and Kubernetes figures out whether they changed.
+func updateDaemonSets(conn db.Conn, daemonSetsClient appsclient.DaemonSetInterface,
+	secretClient SecretClient) {
+
+	currentDaemonSets, err := daemonSetsClient.List(metav1.ListOptions{})
+	if err != nil {
+		log.WithError(err).Error("Failed to list current daemon sets")
+		return
+	}
+
+	desiredDaemonSets, err := makeDesiredDaemonSets(conn, secretClient)
+	if err != nil {
+		if err == errNoBlueprint {
+			return
+		}
+		log.WithError(err).Error("Failed to create desired daemon sets")
+	}
+
+	key := func(intf interface{}) interface{} {
+		return intf.(appsv1.DaemonSet).Name
+	}
+	pairs, toCreate, toDelete := join.HashJoin(
+		daemonSetSlice(desiredDaemonSets),
+		daemonSetSlice(currentDaemonSets.Items),
+		key, key)
+
+	for _, pair := range pairs {
+		daemonSet := pair.L.(appsv1.DaemonSet)
+		c.Inc("Update daemon set")
+		err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+			_, err := daemonSetsClient.Update(&daemonSet)
+			return err
+		})
+		if err != nil {
+			log.WithError(err).WithField("daemonSet", daemonSet.Name).
+				Error("Failed to update daemon set")
+		}
+	}
+
+	for _, intf := range toCreate {
+		daemonSet := intf.(appsv1.DaemonSet)
+		log.WithField("daemonSet", daemonSet.Name).Info("Creating daemon set")
+		c.Inc("Create daemon set")
+		if _, err := daemonSetsClient.Create(&daemonSet); err != nil {
+			log.WithError(err).WithField("daemonSet", daemonSet.Name).
+				Error("Failed to create daemon set")
+		}
+	}
+
+	for _, intf := range toDelete {
+		daemonSet := intf.(appsv1.DaemonSet)
+		log.WithField("daemonSet", daemonSet.Name).Info("Deleting daemon set")
+		c.Inc("Delete daemon set")
+		err := daemonSetsClient.Delete(daemonSet.Name, &metav1.DeleteOptions{})
+		if err != nil {
+			log.WithError(err).WithField("daemonSet", daemonSet.Name).
+				Error("Failed to delete daemon set")
+		}
+	}
+}
+
+func makeDesiredDaemonSets(conn db.Conn, secretClient SecretClient) (
+	[]appsv1.DaemonSet, error) {
+
+	pods, err := makeDesiredPods(conn, secretClient)
+	if err != nil {
+		return nil, err
+	}
+
+	var daemonSets []appsv1.DaemonSet
+	for _, pod := range pods {
+		if pod.dbc.DaemonSet != "" {
+			daemonSets = append(daemonSets, makeDaemonSet(pod.dbc, pod.spec))
+		}
+	}
+	return daemonSets, nil
+}
+
+// makeDaemonSet returns the daemon set that runs the instances of the given
+// daemon container. The pods of a daemon set share a template, so their IP
+// addresses are set by annotateDaemonSetPods once they're scheduled.
+func makeDaemonSet(dbc db.Container, pod corev1.PodSpec) appsv1.DaemonSet {
+	annotations := podAnnotations(dbc)
+	annotations[daemonSetKey] = dbc.DaemonSet
+	return appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: dbc.DaemonSet,
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						hostnameKey: dbc.DaemonSet,
+					},
+					Annotations: annotations,
+				},
+				Spec: pod,
+			},
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					hostnameKey: dbc.DaemonSet,
+				},
+			},
+			// Rolling updates of daemon sets destroy the pod on a machine
+			// before creating its replacement, so there are never two pods
+			// with the same keldaIP.
+			UpdateStrategy: appsv1.DaemonSetUpdateStrategy{
+				Type: appsv1.RollingUpdateDaemonSetStrategyType,
+			},
+		},
+	}
+}
+
+// annotateDaemonSetPods sets the keldaIP annotation of each daemon set pod to
+// the IP of the instance on the pod's machine. The CNI plugin can't set up the
+// pod's network until the annotation is set. Pods with an outdated IP are
+// deleted so that the daemon set recreates them.
+func annotateDaemonSetPods(podsClient clientv1.PodInterface,
+	dbcs []db.Container, pods []corev1.Pod) {
+
+	type instanceKey struct{ daemonSet, minion string }
+	instanceIPs := map[instanceKey]string{}
+	for _, dbc := range dbcs {
+		if dbc.DaemonSet != "" && dbc.IP != "" {
+			instanceIPs[instanceKey{dbc.DaemonSet, dbc.Minion}] = dbc.IP
+		}
+	}
+
+	for _, pod := range pods {
+		daemonSet := pod.Annotations[daemonSetKey]
+		if daemonSet == "" || pod.Status.HostIP == "" {
+			continue
+		}
+
+		ip, ok := instanceIPs[instanceKey{daemonSet, pod.Status.HostIP}]
+		if !ok || pod.Annotations[ipKey] == ip {
+			continue
+		}
+
+		logger := log.WithField("pod", pod.Name)
+		if pod.Annotations[ipKey] != "" {
+			logger.Info("Deleting daemon set pod with outdated IP")
+			c.Inc("Delete daemon set pod")
+			err := podsClient.Delete(pod.Name, &metav1.DeleteOptions{})
+			if err != nil {
+				logger.WithError(err).Error("Failed to delete pod")
+			}
+			continue
+		}
+
+		c.Inc("Annotate daemon set pod")
+		patch, _ := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"annotations": map[string]string{ipKey: ip},
+			},
+		})
+		_, err := podsClient.Patch(pod.Name, types.MergePatchType, patch)
+		if err != nil {
+			logger.WithError(err).Error("Failed to set pod IP")
+		}
+	}
+}
+
+type daemonSetSlice []appsv1.DaemonSet
+
+func (slc daemonSetSlice) Get(ii int) interface{} {
+	return slc[ii]
+}
+
+func (slc daemonSetSlice) Len() int {
+	return len(slc)
+}
@@ -0,0 +1,163 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/join"
+	"github.com/kelda/kelda/minion/kubernetes/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestUpdateDaemonSets(t *testing.T) {
+	t.Parallel()
+	conn := db.New()
+	daemonSetsClient := &mocks.DaemonSetInterface{}
+
+	// No actions should be taken if we were unable to list the current
+	// daemon sets.
+	daemonSetsClient.On("List", mock.Anything).Return(nil, assert.AnError).Once()
+	updateDaemonSets(conn, daemonSetsClient, nil)
+	daemonSetsClient.AssertExpectations(t)
+
+	conn.Txn(db.ContainerTable, db.BlueprintTable).Run(func(view db.Database) error {
+		for _, dbc := range []db.Container{
+			{Hostname: "agent.10-0-0-2", DaemonSet: "agent",
+				Minion: "10.0.0.2", Image: "agent", IP: "ip1"},
+			{Hostname: "agent.10-0-0-3", DaemonSet: "agent",
+				Minion: "10.0.0.3", Image: "agent", IP: "ip2"},
+			{Hostname: "web", Image: "nginx", IP: "ip3"},
+		} {
+			dbc.ID = view.InsertContainer().ID
+			view.Commit(dbc)
+		}
+
+		view.InsertBlueprint()
+		return nil
+	})
+
+	// The instances should be deployed by a single daemon set, and shouldn't
+	// be deployed as deployments.
+	deployments, err := makeDesiredDeployments(conn, nil)
+	assert.NoError(t, err)
+	assert.Len(t, deployments, 1)
+	assert.Equal(t, "web", deployments[0].Name)
+
+	daemonSets, err := makeDesiredDaemonSets(conn, nil)
+	assert.NoError(t, err)
+	assert.Len(t, daemonSets, 1)
+	daemonSet := daemonSets[0]
+	assert.Equal(t, "agent", daemonSet.Name)
+	assert.Equal(t, "agent", daemonSet.Spec.Template.Spec.Hostname)
+	assert.Equal(t, "agent", daemonSet.Spec.Template.Spec.Containers[0].Name)
+	assert.Equal(t, map[string]string{hostnameKey: "agent"},
+		daemonSet.Spec.Template.Labels)
+	assert.Equal(t, "agent", daemonSet.Spec.Template.Annotations[daemonSetKey])
+	assert.NotContains(t, daemonSet.Spec.Template.Annotations, ipKey)
+
+	daemonSetsClient.On("List", mock.Anything).Return(
+		&appsv1.DaemonSetList{}, nil).Once()
+	daemonSetsClient.On("Create", &daemonSet).Return(nil, nil).Once()
+	updateDaemonSets(conn, daemonSetsClient, nil)
+	daemonSetsClient.AssertExpectations(t)
+
+	// When the daemon set already exists, it should be updated.
+	daemonSetsClient.On("List", mock.Anything).Return(
+		&appsv1.DaemonSetList{Items: []appsv1.DaemonSet{daemonSet}}, nil).Once()
+	daemonSetsClient.On("Update", &daemonSet).Return(nil, nil).Once()
+	updateDaemonSets(conn, daemonSetsClient, nil)
+	daemonSetsClient.AssertExpectations(t)
+
+	// When the daemon container is removed, its daemon set should be
+	// removed.
+	conn.Txn(db.ContainerTable).Run(func(view db.Database) error {
+		for _, dbc := range view.SelectFromContainer(nil) {
+			if dbc.DaemonSet != "" {
+				view.Remove(dbc)
+			}
+		}
+		return nil
+	})
+	daemonSetsClient.On("List", mock.Anything).Return(
+		&appsv1.DaemonSetList{Items: []appsv1.DaemonSet{daemonSet}}, nil).Once()
+	daemonSetsClient.On("Delete", "agent", mock.Anything).Return(nil).Once()
+	updateDaemonSets(conn, daemonSetsClient, nil)
+	daemonSetsClient.AssertExpectations(t)
+}
+
+func TestAnnotateDaemonSetPods(t *testing.T) {
+	t.Parallel()
+
+	dbcs := []db.Container{
+		{Hostname: "agent.10-0-0-2", DaemonSet: "agent", Minion: "10.0.0.2",
+			IP: "ip1"},
+		{Hostname: "agent.10-0-0-3", DaemonSet: "agent", Minion: "10.0.0.3",
+			IP: "ip2"},
+		{Hostname: "agent.10-0-0-4", DaemonSet: "agent", Minion: "10.0.0.4",
+			IP: "ip3"},
+	}
+	daemonPod := func(name, hostIP, ip string) corev1.Pod {
+		annotations := map[string]string{daemonSetKey: "agent"}
+		if ip != "" {
+			annotations[ipKey] = ip
+		}
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Annotations: annotations,
+			},
+			Status: corev1.PodStatus{HostIP: hostIP},
+		}
+	}
+	pods := []corev1.Pod{
+		daemonPod("new", "10.0.0.2", ""),
+		daemonPod("current", "10.0.0.3", "ip2"),
+		daemonPod("outdated", "10.0.0.4", "oldIP"),
+		daemonPod("unscheduled", "", ""),
+		daemonPod("unknownMachine", "10.0.0.5", ""),
+		{ObjectMeta: metav1.ObjectMeta{Name: "deployment"}},
+	}
+
+	podsClient := &mocks.PodInterface{}
+	podsClient.On("Patch", "new", types.MergePatchType,
+		[]byte(`{"metadata":{"annotations":{"keldaIP":"ip1"}}}`)).
+		Return(nil, nil).Once()
+	podsClient.On("Delete", "outdated", mock.Anything).Return(nil).Once()
+	annotateDaemonSetPods(podsClient, dbcs, pods)
+	podsClient.AssertExpectations(t)
+}
+
+func TestJoinContainersToPodsDaemonSet(t *testing.T) {
+	t.Parallel()
+
+	instanceA := db.Container{Hostname: "agent.10-0-0-2", DaemonSet: "agent",
+		Minion: "10.0.0.2", Image: "agent"}
+	instanceB := db.Container{Hostname: "agent.10-0-0-3", DaemonSet: "agent",
+		Minion: "10.0.0.3", Image: "agent"}
+
+	template := instanceA
+	template.Hostname = "agent"
+	podSpec, ok := makePod(nil, map[string]*corev1.Affinity{}, nil, nil,
+		nil, template)
+	assert.True(t, ok)
+	daemonSet := makeDaemonSet(template, podSpec)
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: daemonSet.Spec.Template.Annotations,
+		},
+		Spec:   podSpec,
+		Status: corev1.PodStatus{HostIP: "10.0.0.3"},
+	}
+
+	// The pod should only be matched with the instance on its machine.
+	pairs, noInfoContainers := joinContainersToPodsImpl(
+		[]db.Container{instanceA, instanceB}, []corev1.Pod{pod})
+	assert.Equal(t, []join.Pair{{L: instanceB, R: pod}}, pairs)
+	assert.Equal(t, []interface{}{instanceA}, noInfoContainers)
+}
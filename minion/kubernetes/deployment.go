@@ -2,14 +2,17 @@ package kubernetes
 
 import (
 	"crypto/sha1"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/kelda/kelda/blueprint"
 	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/join"
 	"github.com/kelda/kelda/util/str"
+	"github.com/kelda/kelda/version"
 
 	log "github.com/sirupsen/logrus"
 	appsv1 "k8s.io/api/apps/v1"
@@ -52,6 +55,9 @@ func updateDeployments(conn db.Conn, deploymentsClient appsclient.DeploymentInte
 		log.WithError(err).Error("Failed to create desired deployments")
 	}
 
+	held := heldStatefulSetMembers(desiredDeployments,
+		currentDeployments.Items)
+
 	key := func(intf interface{}) interface{} {
 		return intf.(appsv1.Deployment).Name
 	}
@@ -66,6 +72,10 @@ func updateDeployments(conn db.Conn, deploymentsClient appsclient.DeploymentInte
 		// conflict if Kubernetes updated the deployment to change the pod
 		// status.
 		deployment := pair.L.(appsv1.Deployment)
+		if _, ok := held[deployment.Name]; ok {
+			continue
+		}
+
 		c.Inc("Update deployment")
 		err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
 			_, err := deploymentsClient.Update(&deployment)
@@ -79,6 +89,10 @@ func updateDeployments(conn db.Conn, deploymentsClient appsclient.DeploymentInte
 
 	for _, intf := range toCreate {
 		deployment := intf.(appsv1.Deployment)
+		if _, ok := held[deployment.Name]; ok {
+			continue
+		}
+
 		log.WithField("deployment", deployment.Name).Info("Creating deployment")
 		c.Inc("Create deployment")
 		if _, err := deploymentsClient.Create(&deployment); err != nil {
@@ -104,18 +118,64 @@ const (
 	envHashKey        = "env-hash"
 	filesHashKey      = "files-hash"
 	dockerfileHashKey = "dockerfile-hash"
+	sidecarsHashKey   = "sidecars-hash"
+	initHashKey       = "init-hash"
 	imageKey          = "friendly-image"
+	daemonSetKey      = "daemon-set"
+	ipKey             = "keldaIP"
+	statefulSetKey    = "stateful-set"
+	ordinalKey        = "ordinal"
+	templateHashKey   = "template-hash"
+
+	// containerSetKey labels the pods of the members of replica and
+	// stateful sets with the name of their set, so that placement rules
+	// can refer to the whole set.
+	containerSetKey = "container-set"
 )
 
+// The prefix of the names of the init containers that wait for dependencies.
+const dependencyContainerPrefix = "kelda-dependency-"
+
 func makeDesiredDeployments(conn db.Conn, secretClient SecretClient) (
 	[]appsv1.Deployment, error) {
 
+	pods, err := makeDesiredPods(conn, secretClient)
+	if err != nil {
+		return nil, err
+	}
+
+	var deployments []appsv1.Deployment
+	for _, pod := range pods {
+		if pod.dbc.DaemonSet == "" {
+			deployments = append(deployments,
+				makeDeployment(pod.dbc, pod.spec))
+		}
+	}
+	return deployments, nil
+}
+
+// A desiredPod is the pod that should be deployed for a container.
+type desiredPod struct {
+	dbc  db.Container
+	spec corev1.PodSpec
+}
+
+// makeDesiredPods returns the pods that should be deployed. All of the
+// instances of a daemon container share a single pod spec, which is named
+// after the daemon container. The members of stateful containers aren't
+// deployed until they're assigned a worker.
+func makeDesiredPods(conn db.Conn, secretClient SecretClient) (
+	[]desiredPod, error) {
+
 	var containers []db.Container
 	var images []db.Image
 	var idToAffinity map[string]*corev1.Affinity
 	var volumes []blueprint.Volume
+	var runtime *runtimeData
+	var imagePullSecrets []corev1.LocalObjectReference
 	tables := []db.TableType{db.ContainerTable, db.ImageTable, db.PlacementTable,
-		db.BlueprintTable}
+		db.BlueprintTable, db.HostnameTable, db.LoadBalancerTable,
+		db.MinionTable}
 	err := conn.Txn(tables...).Run(func(view db.Database) error {
 		bp, err := view.GetBlueprint()
 		if err != nil {
@@ -126,8 +186,15 @@ func makeDesiredDeployments(conn db.Conn, secretClient SecretClient) (
 			return dbc.IP != ""
 		})
 		images = view.SelectFromImage(nil)
-		idToAffinity = toAffinities(view.SelectFromPlacement(nil))
+		idToAffinity = toAffinities(view.SelectFromPlacement(nil),
+			containerSetNames(containers))
 		volumes = bp.Volumes
+		runtime = getRuntimeData(view, bp)
+		if len(bp.RegistryCredentials) != 0 {
+			imagePullSecrets = []corev1.LocalObjectReference{
+				{Name: registryCredentialsName},
+			}
+		}
 		return nil
 	})
 	if err != nil {
@@ -142,36 +209,51 @@ func makeDesiredDeployments(conn db.Conn, secretClient SecretClient) (
 		}
 	}
 
-	var deployments []appsv1.Deployment
+	var pods []desiredPod
+	daemonSets := map[string]struct{}{}
 	for _, dbc := range containers {
-		pod, ok := makePod(images, idToAffinity, secretClient, volumeMap, dbc)
+		if dbc.DaemonSet != "" {
+			if _, ok := daemonSets[dbc.DaemonSet]; ok {
+				continue
+			}
+			daemonSets[dbc.DaemonSet] = struct{}{}
+			dbc.Hostname = dbc.DaemonSet
+		}
+
+		if dbc.StatefulSet != "" {
+			if dbc.Minion == "" {
+				continue
+			}
+			pinStatefulSetMember(idToAffinity, dbc)
+		}
+
+		pod, ok := makePod(images, idToAffinity, secretClient, volumeMap,
+			runtime, dbc)
 		if ok {
-			deployments = append(deployments, makeDeployment(dbc, pod))
+			pod.ImagePullSecrets = imagePullSecrets
+			pods = append(pods, desiredPod{dbc, pod})
 		}
 	}
-	return deployments, nil
+	return pods, nil
 }
 
 func makeDeployment(dbc db.Container, pod corev1.PodSpec) appsv1.Deployment {
-	// These annotations are used by the join in `updateStatuses` to match
-	// up Kubernetes pods with the containers in the database.
-	annotations := map[string]string{
-		dockerfileHashKey: hashStr(dbc.Dockerfile),
-		filesHashKey:      hashContainerValueMap(dbc.FilepathToContent),
-		envHashKey:        hashContainerValueMap(dbc.Env),
-		imageKey:          dbc.Image,
-		"keldaIP":         dbc.IP,
+	annotations := podAnnotations(dbc)
+	annotations[ipKey] = dbc.IP
+
+	labels := map[string]string{hostnameKey: dbc.Hostname}
+	if set := containerSetName(dbc); set != "" {
+		labels[containerSetKey] = set
 	}
-	return appsv1.Deployment{
+
+	deployment := appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: dbc.Hostname,
 		},
 		Spec: appsv1.DeploymentSpec{
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{
-						hostnameKey: dbc.Hostname,
-					},
+					Labels:      labels,
 					Annotations: annotations,
 				},
 				Spec: pod,
@@ -191,85 +273,246 @@ func makeDeployment(dbc db.Container, pod corev1.PodSpec) appsv1.Deployment {
 			},
 		},
 	}
+
+	// The deployments of stateful members are annotated so that
+	// heldStatefulSetMembers can order their creation and updates.
+	if dbc.StatefulSet != "" {
+		deployment.Annotations = map[string]string{
+			statefulSetKey: dbc.StatefulSet,
+			ordinalKey: strings.TrimPrefix(dbc.Hostname,
+				dbc.StatefulSet+"-"),
+			templateHashKey: hashJSON(deployment.Spec.Template),
+		}
+	}
+	return deployment
+}
+
+// podAnnotations returns the annotations of the pods of the given container.
+// These annotations are used by the join in `updateStatuses` to match up
+// Kubernetes pods with the containers in the database.
+func podAnnotations(dbc db.Container) map[string]string {
+	annotations := map[string]string{
+		dockerfileHashKey: hashStr(dbc.Dockerfile),
+		filesHashKey:      hashContainerValueMap(dbc.FilepathToContent),
+		envHashKey:        hashContainerValueMap(dbc.Env),
+		imageKey:          dbc.Image,
+	}
+
+	// These annotations are only set for the pods that use the features, so
+	// that adding them doesn't restart the other pods.
+	if len(dbc.Sidecars) != 0 {
+		annotations[sidecarsHashKey] = hashSidecars(dbc.Sidecars)
+	}
+	if initHash := hashInit(dbc); initHash != "" {
+		annotations[initHashKey] = initHash
+	}
+	if securityHash := hashSecurity(dbc); securityHash != "" {
+		annotations[securityHashKey] = securityHash
+		for key, val := range securityAnnotations(dbc) {
+			annotations[key] = val
+		}
+	}
+	return annotations
 }
 
 // makePod returns a Kubernetes representation of the given containers as a pod.
+// The container and its sidecars are each run as a container in the pod, with
+// the container itself first. The dependencies and init containers are run as
+// init containers.
 // The returned boolean indicates whether it's possible to create a pod spec at
 // this time. It's not necessarily an error if a pod can't be created -- for
 // example, the user might need to run `kelda secret`, or images might still be
-// building.
+// building. Pods that reference secrets their hostname isn't allowed to use
+// are never created.
+// Runtime values are resolved using `runtime` and the Kubernetes downward API,
+// and templates are rendered using `runtime`.
 func makePod(images []db.Image, idToAffinity map[string]*corev1.Affinity,
 	secretClient SecretClient, volumeMap map[string]corev1.Volume,
-	dbc db.Container) (corev1.PodSpec, bool) {
+	runtime *runtimeData, dbc db.Container) (corev1.PodSpec, bool) {
 
-	// If the container isn't built by Kelda, the image doesn't have to be
-	// rewritten to the version hosted by the local registry.
-	image := dbc.Image
-	if dbc.Dockerfile != "" {
-		var foundImage bool
-		for _, img := range images {
-			if img.Name == dbc.Image && img.Dockerfile == dbc.Dockerfile &&
-				img.Status == db.Built {
-				foundImage = true
-				image = img.RepoDigest
-				break
-			}
+	secretHashEnv, missing := makeSecretHashEnvVars(secretClient,
+		dbc.GetReferencedSecrets())
+	if len(missing) != 0 {
+		return corev1.PodSpec{}, false
+	}
+
+	// Refuse to create pods that use secrets they aren't allowed to. The
+	// reason is shown to the user by the container status.
+	unauthorized := unauthorizedSecrets(secretClient, dbc)
+	if len(unauthorized) != 0 {
+		log.WithField("container", dbc.Hostname).
+			WithField("secrets", unauthorized).
+			Warn("Container is not allowed to use secrets")
+		return corev1.PodSpec{}, false
+	}
+
+	values := runtime.podValues(dbc)
+
+	// Volumes may be mounted by multiple containers in the pod, but must only
+	// be defined once.
+	podVolumes := map[string]corev1.Volume{}
+	var containers, initContainers []corev1.Container
+	for i, member := range dbc.PodContainers() {
+		image, ok := podImage(images, member.Image)
+		if !ok {
+			return corev1.PodSpec{}, false
 		}
-		if !foundImage {
+
+		// The hashes of the secrets only need to be set in one container,
+		// since changing them restarts the entire pod.
+		var env []corev1.EnvVar
+		filesVolumeName := "filepath-to-content"
+		templatesVolumeName := "templates"
+		if i == 0 {
+			env = secretHashEnv
+		} else {
+			filesVolumeName += "-" + member.Hostname
+			templatesVolumeName += "-" + member.Hostname
+		}
+		podEnv, ok := makePodEnvVars(values, member.Env)
+		if !ok {
+			log.WithField("container", member.Hostname).
+				Warn("Invalid environment variable value")
 			return corev1.PodSpec{}, false
 		}
-	}
+		env = append(env, podEnv...)
 
-	env, missing := makeSecretHashEnvVars(secretClient, dbc.GetReferencedSecrets())
-	if len(missing) != 0 {
-		return corev1.PodSpec{}, false
+		_, _, _, templates := blueprint.DivideContainerValues(
+			member.FilepathToContent)
+		rendered, err := runtime.renderTemplates(secretClient, dbc.Hostname,
+			templates)
+		if err != nil {
+			log.WithError(err).WithField("container", member.Hostname).
+				Warn("Failed to render template")
+			return corev1.PodSpec{}, false
+		}
+
+		volumes, volumeMounts := makeVolumesForFilepathToContent(
+			filesVolumeName, templatesVolumeName, member.FilepathToContent,
+			rendered)
+		for _, volumeMount := range member.VolumeMounts {
+			volume, ok := volumeMap[volumeMount.VolumeName]
+			if !ok {
+				log.WithField("volume", volumeMount.VolumeName).
+					WithField("container", dbc.Hostname).
+					Warn("Unknown volume reference")
+				return corev1.PodSpec{}, false
+			}
+
+			if dbc.StatefulSet != "" {
+				volume = memberVolume(volume, dbc.Hostname)
+			}
+
+			volumes = append(volumes, volume)
+			volumeMounts = append(volumeMounts, corev1.VolumeMount{
+				MountPath: volumeMount.MountPath,
+				Name:      volumeMount.VolumeName,
+			})
+		}
+
+		for _, volume := range volumes {
+			podVolumes[volume.Name] = volume
+		}
+
+		// Sort the volume mounts and environment variables so that the pod
+		// config is consistent. Otherwise, Kubernetes will treat differences
+		// in orderings as a reason to restart the pod.
+		sort.Sort(volumeMountSlice(volumeMounts))
+		sort.Sort(envVarSlice(env))
+
+		container := corev1.Container{
+			Name:            member.Hostname,
+			Image:           image,
+			Env:             env,
+			Args:            member.Command,
+			VolumeMounts:    volumeMounts,
+			SecurityContext: makeSecurityContext(member),
+		}
+
+		if i <= len(dbc.Sidecars) {
+			containers = append(containers, container)
+		} else {
+			initContainers = append(initContainers, container)
+		}
 	}
-	env = append(env, makePodEnvVars(dbc.Env)...)
 
-	volumes, volumeMounts := makeVolumesForFilepathToContent(dbc.FilepathToContent)
-	for _, volumeMount := range dbc.VolumeMounts {
-		volume, ok := volumeMap[volumeMount.VolumeName]
+	// The dependencies are waited for before the user's init containers are
+	// run, so that the init containers can also rely on them.
+	initContainers = append(makeDependencyContainers(dbc.Dependencies),
+		initContainers...)
+
+	// The runtime values are written before any of the other containers
+	// start, so that all of them can read the files.
+	if runtimeKeys := runtimeFileKeys(dbc); len(runtimeKeys) != 0 {
+		container, volumes, ok := makeRuntimeValuesContainer(values,
+			runtimeKeys)
 		if !ok {
-			log.WithField("volume", volumeMount.VolumeName).
-				WithField("container", dbc.Hostname).
-				Warn("Unknown volume reference")
+			log.WithField("container", dbc.Hostname).
+				Warn("Unknown runtime value reference")
 			return corev1.PodSpec{}, false
 		}
+		initContainers = append([]corev1.Container{container},
+			initContainers...)
+		for _, volume := range volumes {
+			podVolumes[volume.Name] = volume
+		}
+	}
 
+	var volumes []corev1.Volume
+	for _, volume := range podVolumes {
 		volumes = append(volumes, volume)
-		volumeMounts = append(volumeMounts, corev1.VolumeMount{
-			MountPath: volumeMount.MountPath,
-			Name:      volumeMount.VolumeName,
-		})
 	}
-
-	// Sort the volumes and volume mounts so that the pod config is
-	// consistent. Otherwise, Kubernetes will treat differences in
-	// orderings as a reason to restart the pod.
-	sort.Sort(volumeMountSlice(volumeMounts))
 	sort.Sort(volumeSlice(volumes))
-	sort.Sort(envVarSlice(env))
 
 	return corev1.PodSpec{
-		Hostname: dbc.Hostname,
-		Containers: []corev1.Container{
-			{
-				Name:         dbc.Hostname,
-				Image:        image,
-				Env:          env,
-				Args:         dbc.Command,
-				VolumeMounts: volumeMounts,
-				SecurityContext: &corev1.SecurityContext{
-					Privileged: &dbc.Privileged,
-				},
-			},
-		},
-		Affinity:  idToAffinity[dbc.Hostname],
-		DNSPolicy: corev1.DNSDefault,
-		Volumes:   volumes,
+		Hostname:        dbc.Hostname,
+		InitContainers:  initContainers,
+		Containers:      containers,
+		Affinity:        idToAffinity[dbc.Hostname],
+		DNSPolicy:       corev1.DNSDefault,
+		Volumes:         volumes,
+		SecurityContext: makePodSecurityContext(dbc),
 	}, true
 }
 
+// makeDependencyContainers returns an init container for each dependency that
+// blocks until the dependency accepts connections. The containers run the
+// Kelda image, which is already on every machine.
+func makeDependencyContainers(deps []blueprint.Dependency) []corev1.Container {
+	var containers []corev1.Container
+	for i, dep := range deps {
+		containers = append(containers, corev1.Container{
+			Name:    fmt.Sprintf("%s%d", dependencyContainerPrefix, i),
+			Image:   version.Image,
+			Command: []string{"kelda", "wait-for", dep.String()},
+		})
+	}
+	return containers
+}
+
+// podImage returns the name of the image that should be run for the given
+// blueprint image. The returned boolean indicates whether the image is ready to
+// be run.
+func podImage(images []db.Image, img blueprint.Image) (string, bool) {
+	// If the container isn't built by Kelda, the image doesn't have to be
+	// rewritten to the version hosted by the local registry. However, if the
+	// blueprint pins image digests, it's rewritten to the resolved digest.
+	readyStatus := db.Built
+	if img.Dockerfile == "" {
+		readyStatus = db.Resolved
+	}
+
+	for _, dbImg := range images {
+		if dbImg.Name == img.Name && dbImg.Dockerfile == img.Dockerfile {
+			if dbImg.Status != readyStatus {
+				return "", false
+			}
+			return dbImg.RepoDigest, true
+		}
+	}
+	return img.Name, img.Dockerfile == ""
+}
+
 // makeSecretHashEnvVars creates environment variables that represent the value
 // of the secrets referenced by the container. This way, if a secret value
 // changes, these environment variables will change, and Kubernetes will
@@ -291,9 +534,19 @@ func makeSecretHashEnvVars(secretClient SecretClient, secretNames []string) (
 	return
 }
 
-func makePodEnvVars(dbcEnv map[string]blueprint.ContainerValue) []corev1.EnvVar {
+// makePodEnvVars returns the environment variables for the given Env. The
+// returned boolean is false if a runtime value is unknown, or if the Env
+// contains templates, which are only supported in files.
+func makePodEnvVars(values map[string]string,
+	dbcEnv map[string]blueprint.ContainerValue) ([]corev1.EnvVar, bool) {
+
 	var envVars []corev1.EnvVar
-	rawStrings, secrets := blueprint.DivideContainerValues(dbcEnv)
+	rawStrings, secrets, runtimeValues, templates := blueprint.DivideContainerValues(
+		dbcEnv)
+	if len(templates) != 0 {
+		return nil, false
+	}
+
 	for key, val := range rawStrings {
 		envVars = append(envVars, corev1.EnvVar{
 			Name:  key,
@@ -315,19 +568,33 @@ func makePodEnvVars(dbcEnv map[string]blueprint.ContainerValue) []corev1.EnvVar
 			},
 		})
 	}
-	return envVars
+
+	for key, runtimeKey := range runtimeValues {
+		envVar, ok := makeRuntimeEnvVar(values, key, runtimeKey)
+		if !ok {
+			return nil, false
+		}
+		envVars = append(envVars, envVar)
+	}
+	return envVars, true
 }
 
 // makeVolumesForFilepathToContent returns the appropriate Volumes and
 // VolumeMounts such that the given filepathToContent will be reflected in the
-// container.
+// container. The string files are mounted from the volume named
+// `filesVolumeName`.
 // For string files it uses ConfigMap volumes (whose contents are created in
-// configmap.go), and for secrets it uses Secret volumes.
-func makeVolumesForFilepathToContent(
-	filepathToContent map[string]blueprint.ContainerValue) (
+// configmap.go), and for secrets it uses Secret volumes. Runtime values are
+// mounted from the volume written by the runtime values container. The
+// rendered templates are mounted from the Secret named after their contents
+// (created in template.go) as the volume `templatesVolumeName`.
+func makeVolumesForFilepathToContent(filesVolumeName, templatesVolumeName string,
+	filepathToContent map[string]blueprint.ContainerValue,
+	renderedTemplates map[string]string) (
 	volumes []corev1.Volume, mounts []corev1.VolumeMount) {
 
-	rawStrings, secrets := blueprint.DivideContainerValues(filepathToContent)
+	rawStrings, secrets, runtimeValues, _ := blueprint.DivideContainerValues(
+		filepathToContent)
 	mountedSecretVolumes := map[string]struct{}{}
 	for path, secret := range secrets {
 		kubeName, key := secretRef(secret)
@@ -356,7 +623,6 @@ func makeVolumesForFilepathToContent(
 
 	// Mount the raw string values by mounting the ConfigMap corresponding to
 	// the filepathToContent.
-	const filesVolumeName = "filepath-to-content"
 	if len(rawStrings) != 0 {
 		volumes = append(volumes, corev1.Volume{
 			Name: filesVolumeName,
@@ -378,6 +644,35 @@ func makeVolumesForFilepathToContent(
 			SubPath:   configMapKey(path),
 		})
 	}
+
+	for path, key := range runtimeValues {
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      runtimeValuesVolumeName,
+			MountPath: path,
+			SubPath:   key,
+		})
+	}
+
+	if len(renderedTemplates) != 0 {
+		volumes = append(volumes, corev1.Volume{
+			Name: templatesVolumeName,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: templateSecretName(
+						renderedTemplates),
+					DefaultMode: &filepathToContentMode,
+				},
+			},
+		})
+	}
+
+	for path := range renderedTemplates {
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      templatesVolumeName,
+			MountPath: path,
+			SubPath:   configMapKey(path),
+		})
+	}
 	return
 }
 
@@ -408,6 +703,35 @@ func hashContainerValueMap(containerValMap map[string]blueprint.ContainerValue)
 	return hashStr(str.MapAsString(strValMap))
 }
 
+// hashSidecars returns a hash of the given sidecars, or the empty string if
+// there are none.
+func hashSidecars(sidecars []blueprint.Container) string {
+	if len(sidecars) == 0 {
+		return ""
+	}
+
+	return hashJSON(sidecars)
+}
+
+// hashInit returns a hash of the init containers and dependencies of the
+// given container, or the empty string if it has neither.
+func hashInit(dbc db.Container) string {
+	if len(dbc.InitContainers) == 0 && len(dbc.Dependencies) == 0 {
+		return ""
+	}
+
+	return hashJSON(struct {
+		InitContainers []blueprint.Container
+		Dependencies   []blueprint.Dependency
+	}{dbc.InitContainers, dbc.Dependencies})
+}
+
+func hashJSON(val interface{}) string {
+	// The keys of marshalled maps are sorted, so the encoding is stable.
+	valJSON, _ := json.Marshal(val)
+	return hashStr(string(valJSON))
+}
+
 type deploymentSlice []appsv1.Deployment
 
 func (slc deploymentSlice) Get(ii int) interface{} {
@@ -6,6 +6,7 @@ import (
 	"github.com/kelda/kelda/blueprint"
 	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/minion/kubernetes/mocks"
+	"github.com/kelda/kelda/version"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -143,6 +144,7 @@ func TestMakePodConsistent(t *testing.T) {
 	secretClient.On("Get", fileSecretNameA).Return("fileSecretValueA", nil)
 	secretClient.On("Get", fileSecretNameB).Return("fileSecretValueB", nil)
 	secretClient.On("Get", sharedSecretName).Return("sharedSecretValue", nil)
+	secretClient.On("GetAllowedHostnames", mock.Anything).Return(nil, nil)
 
 	dbc := db.Container{
 		Hostname: "hostname",
@@ -162,11 +164,12 @@ func TestMakePodConsistent(t *testing.T) {
 			"e": blueprint.NewSecret(sharedSecretName),
 		},
 	}
-	pod, ok := makePod(nil, map[string]*corev1.Affinity{}, secretClient, nil, dbc)
+	pod, ok := makePod(nil, map[string]*corev1.Affinity{},
+		secretClient, nil, nil, dbc)
 	assert.True(t, ok)
 	for i := 0; i < 10; i++ {
 		newPod, ok := makePod(nil, map[string]*corev1.Affinity{},
-			secretClient, nil, dbc)
+			secretClient, nil, nil, dbc)
 		assert.True(t, ok)
 		assert.Equal(t, pod, newPod)
 	}
@@ -181,7 +184,7 @@ func TestMakePodConfigMap(t *testing.T) {
 			"foo/bar": blueprint.NewString("baz"),
 		},
 	}
-	pod, ok := makePod(nil, map[string]*corev1.Affinity{}, nil, nil, dbc)
+	pod, ok := makePod(nil, map[string]*corev1.Affinity{}, nil, nil, nil, dbc)
 	assert.True(t, ok)
 	assert.Len(t, pod.Volumes, 1)
 	assert.Len(t, pod.Containers[0].VolumeMounts, 1)
@@ -210,13 +213,16 @@ func TestDeploymentBuilderSecret(t *testing.T) {
 	dbc := db.Container{
 		FilepathToContent: containerValueMap,
 	}
-	_, ok := makePod(nil, map[string]*corev1.Affinity{}, secretClient, nil, dbc)
+	_, ok := makePod(nil, map[string]*corev1.Affinity{},
+		secretClient, nil, nil, dbc)
 	assert.False(t, ok)
 	secretClient.AssertExpectations(t)
 
 	// Once the value is set, we should be able to make the pod.
 	secretClient.On("Get", mySecretName).Return(mySecretVal, nil).Once()
-	pod, ok := makePod(nil, map[string]*corev1.Affinity{}, secretClient, nil, dbc)
+	secretClient.On("GetAllowedHostnames", mySecretName).Return(nil, nil)
+	pod, ok := makePod(nil, map[string]*corev1.Affinity{},
+		secretClient, nil, nil, dbc)
 	assert.True(t, ok)
 	secretClient.AssertExpectations(t)
 
@@ -235,7 +241,8 @@ func TestDeploymentBuilderSecret(t *testing.T) {
 	dbc = db.Container{
 		Env: containerValueMap,
 	}
-	pod, ok = makePod(nil, map[string]*corev1.Affinity{}, secretClient, nil, dbc)
+	pod, ok = makePod(nil, map[string]*corev1.Affinity{},
+		secretClient, nil, nil, dbc)
 	assert.True(t, ok)
 	secretClient.AssertExpectations(t)
 	assert.Contains(t, pod.Containers[0].Env, corev1.EnvVar{
@@ -260,7 +267,8 @@ func TestDeploymentBuilderSecret(t *testing.T) {
 	// should change.
 	mySecretVal = "changed"
 	secretClient.On("Get", mySecretName).Return(mySecretVal, nil).Once()
-	pod, ok = makePod(nil, map[string]*corev1.Affinity{}, secretClient, nil, dbc)
+	pod, ok = makePod(nil, map[string]*corev1.Affinity{},
+		secretClient, nil, nil, dbc)
 	assert.True(t, ok)
 	secretClient.AssertExpectations(t)
 
@@ -269,6 +277,30 @@ func TestDeploymentBuilderSecret(t *testing.T) {
 	assert.NotEqual(t, secretHashEnv, newSecretHash)
 }
 
+func TestMakePodUnauthorizedSecret(t *testing.T) {
+	t.Parallel()
+
+	secretClient := &mocks.SecretClient{}
+	secretClient.On("Get", "tls").Return("key", nil)
+	secretClient.On("GetAllowedHostnames", "tls").Return(
+		[]string{"web", "proxy"}, nil)
+
+	dbc := db.Container{
+		Hostname: "web",
+		Env: map[string]blueprint.ContainerValue{
+			"KEY": blueprint.NewSecret("tls"),
+		},
+	}
+	_, ok := makePod(nil, map[string]*corev1.Affinity{},
+		secretClient, nil, nil, dbc)
+	assert.True(t, ok)
+
+	dbc.Hostname = "worker"
+	_, ok = makePod(nil, map[string]*corev1.Affinity{},
+		secretClient, nil, nil, dbc)
+	assert.False(t, ok)
+}
+
 func TestMakePodCustomImage(t *testing.T) {
 	t.Parallel()
 
@@ -297,7 +329,7 @@ func TestMakePodCustomImage(t *testing.T) {
 	// unchanged.
 	regularImage := "alpine"
 	dbc := db.Container{Image: regularImage}
-	pod, ok := makePod(images, map[string]*corev1.Affinity{}, nil, nil, dbc)
+	pod, ok := makePod(images, map[string]*corev1.Affinity{}, nil, nil, nil, dbc)
 	assert.Equal(t, regularImage, pod.Containers[0].Image)
 	assert.True(t, ok)
 
@@ -306,7 +338,7 @@ func TestMakePodCustomImage(t *testing.T) {
 		Image:      readyImage,
 		Dockerfile: readyDockerfile,
 	}
-	pod, ok = makePod(images, map[string]*corev1.Affinity{}, nil, nil, dbc)
+	pod, ok = makePod(images, map[string]*corev1.Affinity{}, nil, nil, nil, dbc)
 	assert.Equal(t, readyRepoDigest, pod.Containers[0].Image)
 	assert.True(t, ok)
 
@@ -315,7 +347,28 @@ func TestMakePodCustomImage(t *testing.T) {
 		Image:      buildingImage,
 		Dockerfile: buildingDockerfile,
 	}
-	pod, ok = makePod(images, map[string]*corev1.Affinity{}, nil, nil, dbc)
+	pod, ok = makePod(images, map[string]*corev1.Affinity{}, nil, nil, nil, dbc)
+	assert.False(t, ok)
+}
+
+func TestMakePodResolvedImage(t *testing.T) {
+	t.Parallel()
+
+	images := []db.Image{
+		{Name: "nginx", RepoDigest: "nginx@sha256:abc", Status: db.Resolved},
+		{Name: "redis", Status: db.ResolveFailed},
+	}
+
+	// Test that a container whose image's tag is resolved runs the digest.
+	pod, ok := makePod(images, map[string]*corev1.Affinity{}, nil, nil,
+		nil, db.Container{Image: "nginx"})
+	assert.True(t, ok)
+	assert.Equal(t, "nginx@sha256:abc", pod.Containers[0].Image)
+
+	// Test that a container whose image's tag hasn't been resolved isn't
+	// started.
+	_, ok = makePod(images, map[string]*corev1.Affinity{}, nil, nil,
+		nil, db.Container{Image: "redis"})
 	assert.False(t, ok)
 }
 
@@ -334,7 +387,8 @@ func TestMakePodHostPathVolume(t *testing.T) {
 			{VolumeName: volumeName, MountPath: mountPath},
 		},
 	}
-	pod, ok := makePod(nil, map[string]*corev1.Affinity{}, nil, volumeMap, dbc)
+	pod, ok := makePod(nil, map[string]*corev1.Affinity{},
+		nil, volumeMap, nil, dbc)
 	assert.True(t, ok)
 	assert.Equal(t, []corev1.Volume{volumeMap[volumeName]}, pod.Volumes)
 	assert.Equal(t, []corev1.VolumeMount{
@@ -347,10 +401,155 @@ func TestMakePodHostPathVolume(t *testing.T) {
 			{VolumeName: "unknown", MountPath: mountPath},
 		},
 	}
-	_, ok = makePod(nil, map[string]*corev1.Affinity{}, nil, volumeMap, dbc)
+	_, ok = makePod(nil, map[string]*corev1.Affinity{}, nil, volumeMap, nil, dbc)
 	assert.False(t, ok)
 }
 
+func TestMakePodSidecars(t *testing.T) {
+	t.Parallel()
+
+	secretClient := &mocks.SecretClient{}
+	secretClient.On("Get", "token").Return("val", nil)
+	secretClient.On("GetAllowedHostnames", mock.Anything).Return(nil, nil)
+
+	images := []db.Image{
+		{Name: "proxy", Dockerfile: "FROM envoy", RepoDigest: "proxyDigest",
+			Status: db.Built},
+	}
+	volumeMap := map[string]corev1.Volume{"logs": {Name: "logs"}}
+	logsMount := blueprint.VolumeMount{VolumeName: "logs", MountPath: "/logs"}
+
+	dbc := db.Container{
+		Hostname:     "web",
+		Image:        "nginx",
+		VolumeMounts: []blueprint.VolumeMount{logsMount},
+		Sidecars: []blueprint.Container{
+			{
+				Hostname: "proxy",
+				Image: blueprint.Image{Name: "proxy",
+					Dockerfile: "FROM envoy"},
+				Command: []string{"envoy"},
+				Env: map[string]blueprint.ContainerValue{
+					"TOKEN": blueprint.NewSecret("token"),
+				},
+				FilepathToContent: map[string]blueprint.ContainerValue{
+					"/etc/envoy.yaml": blueprint.NewString("config"),
+				},
+				Privileged: true,
+			},
+			{
+				Hostname:     "shipper",
+				Image:        blueprint.Image{Name: "fluentd"},
+				VolumeMounts: []blueprint.VolumeMount{logsMount},
+			},
+		},
+	}
+	pod, ok := makePod(images, map[string]*corev1.Affinity{}, secretClient,
+		volumeMap, nil, dbc)
+	assert.True(t, ok)
+	assert.Equal(t, "web", pod.Hostname)
+
+	// The container should be first, followed by its sidecars.
+	assert.Len(t, pod.Containers, 3)
+	assert.Equal(t, "web", pod.Containers[0].Name)
+	assert.Equal(t, "nginx", pod.Containers[0].Image)
+	assert.Equal(t, "proxy", pod.Containers[1].Name)
+	assert.Equal(t, "proxyDigest", pod.Containers[1].Image)
+	assert.Equal(t, []string{"envoy"}, pod.Containers[1].Args)
+	assert.True(t, *pod.Containers[1].SecurityContext.Privileged)
+	assert.False(t, *pod.Containers[2].SecurityContext.Privileged)
+
+	// The hashes of the sidecars' secrets should be set in the first
+	// container, and the secret values in the sidecar.
+	assert.Equal(t, "SECRET_HASH_token", pod.Containers[0].Env[0].Name)
+	assert.Len(t, pod.Containers[1].Env, 1)
+	assert.Equal(t, "TOKEN", pod.Containers[1].Env[0].Name)
+
+	// Shared volumes should only be defined once, and the sidecars' files
+	// should be mounted from their own volume.
+	assert.Equal(t, []corev1.Volume{
+		{
+			Name: "filepath-to-content-proxy",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: configMapName(map[string]string{
+							"/etc/envoy.yaml": "config"}),
+					},
+					DefaultMode: &filepathToContentMode,
+				},
+			},
+		},
+		{Name: "logs"},
+	}, pod.Volumes)
+	assert.Equal(t, []corev1.VolumeMount{
+		{Name: "filepath-to-content-proxy", MountPath: "/etc/envoy.yaml",
+			SubPath: configMapKey("/etc/envoy.yaml")},
+	}, pod.Containers[1].VolumeMounts)
+	assert.Equal(t, []corev1.VolumeMount{
+		{Name: "logs", MountPath: "/logs"},
+	}, pod.Containers[2].VolumeMounts)
+
+	// The pod can't be created until the images of all of its containers are
+	// ready.
+	images[0].Status = db.Building
+	_, ok = makePod(images, map[string]*corev1.Affinity{}, secretClient,
+		volumeMap, nil, dbc)
+	assert.False(t, ok)
+}
+
+func TestMakePodInitContainers(t *testing.T) {
+	t.Parallel()
+
+	dbc := db.Container{
+		Hostname: "web",
+		Image:    "app",
+		InitContainers: []blueprint.Container{
+			{
+				Hostname: "migrate",
+				Image:    blueprint.Image{Name: "app"},
+				Command:  []string{"migrate", "up"},
+			},
+		},
+		Dependencies: []blueprint.Dependency{
+			{Hostname: "db", Port: 5432},
+			{Hostname: "cache", Port: 6379},
+		},
+	}
+	pod, ok := makePod(nil, map[string]*corev1.Affinity{}, nil, nil, nil, dbc)
+	assert.True(t, ok)
+
+	// The init containers shouldn't be run alongside the container.
+	assert.Len(t, pod.Containers, 1)
+	assert.Equal(t, "web", pod.Containers[0].Name)
+
+	// The dependencies should be waited for before the user's init containers
+	// are run.
+	assert.Len(t, pod.InitContainers, 3)
+	assert.Equal(t, "kelda-dependency-0", pod.InitContainers[0].Name)
+	assert.Equal(t, version.Image, pod.InitContainers[0].Image)
+	assert.Equal(t, []string{"kelda", "wait-for", "db:5432"},
+		pod.InitContainers[0].Command)
+	assert.Equal(t, []string{"kelda", "wait-for", "cache:6379"},
+		pod.InitContainers[1].Command)
+	assert.Equal(t, "migrate", pod.InitContainers[2].Name)
+	assert.Equal(t, "app", pod.InitContainers[2].Image)
+	assert.Equal(t, []string{"migrate", "up"}, pod.InitContainers[2].Args)
+
+	// Changing the init containers should change the pod's annotations so
+	// that it's restarted.
+	annotations := makeDeployment(dbc, pod).Spec.Template.Annotations
+	assert.NotEmpty(t, annotations[initHashKey])
+
+	dbc.Dependencies = nil
+	assert.NotEqual(t, annotations[initHashKey],
+		makeDeployment(dbc, pod).Spec.Template.Annotations[initHashKey])
+
+	dbc.InitContainers = nil
+	assert.NotContains(t, makeDeployment(dbc, pod).Spec.Template.Annotations,
+		initHashKey)
+}
+
 func TestMakeVolume(t *testing.T) {
 	t.Parallel()
 
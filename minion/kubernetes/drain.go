@@ -0,0 +1,136 @@
+package kubernetes
+
+import (
+	"sort"
+
+	"github.com/kelda/kelda/db"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	kubeerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// evictionBatchSize is the most pods that are evicted from the draining nodes
+// at once. More pods aren't evicted until the previous ones have terminated,
+// so that the cluster isn't left without many of its pods at the same time.
+const evictionBatchSize = 5
+
+// drainNodes cordons the nodes of draining minions so that no new pods are
+// scheduled on them, and evicts the pods running on them so that they're
+// recreated elsewhere. The daemon removes the machine once its pods are gone.
+// Pods are evicted in small batches through the Eviction API, which respects
+// any disruption budgets. Daemon set pods are left alone because they're meant
+// to run on every node. Nodes are uncordoned if their minion stops draining.
+func drainNodes(nodes []db.Minion, nodesClient clientv1.NodeInterface,
+	podsClient clientv1.PodInterface) {
+
+	draining := map[string]bool{}
+	for _, node := range nodes {
+		draining[node.PrivateIP] = node.Draining
+	}
+
+	nodesList, err := nodesClient.List(metav1.ListOptions{})
+	if err != nil {
+		log.WithError(err).Error("Failed to get current nodes")
+		return
+	}
+
+	var drainingIPs []string
+	for _, node := range nodesList.Items {
+		privateIP, err := getPrivateIP(node)
+		if err != nil {
+			continue
+		}
+
+		shouldDrain, ok := draining[privateIP]
+		if !ok {
+			continue
+		}
+
+		if shouldDrain {
+			drainingIPs = append(drainingIPs, privateIP)
+		}
+
+		if node.Spec.Unschedulable == shouldDrain {
+			continue
+		}
+
+		c.Inc("Cordon node")
+		log.WithField("node", node.Name).WithField("cordon", shouldDrain).
+			Info("Updating whether node is schedulable")
+		node.Spec.Unschedulable = shouldDrain
+		err = retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+			_, err := nodesClient.Update(&node)
+			return err
+		})
+		if err != nil {
+			log.WithError(err).Error("Failed to update node")
+		}
+	}
+
+	if len(drainingIPs) == 0 {
+		return
+	}
+
+	pods, err := podsClient.List(metav1.ListOptions{})
+	if err != nil {
+		log.WithError(err).Error("Failed to list current pods")
+		return
+	}
+
+	var toEvict []corev1.Pod
+	for _, pod := range pods.Items {
+		if !onNodes(pod, drainingIPs) ||
+			pod.Annotations[daemonSetKey] != "" {
+			continue
+		}
+
+		// Wait for the pods that were already evicted to terminate before
+		// evicting more.
+		if pod.DeletionTimestamp != nil {
+			return
+		}
+		toEvict = append(toEvict, pod)
+	}
+
+	sort.Slice(toEvict, func(i, j int) bool {
+		return toEvict[i].Name < toEvict[j].Name
+	})
+	if len(toEvict) > evictionBatchSize {
+		toEvict = toEvict[:evictionBatchSize]
+	}
+
+	for _, pod := range toEvict {
+		c.Inc("Evict pod")
+		logger := log.WithField("pod", pod.Name)
+		logger.Info("Evicting pod on draining node")
+		err := podsClient.Evict(&policyv1beta1.Eviction{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      pod.Name,
+				Namespace: pod.Namespace,
+			},
+		})
+		switch {
+		case kubeerrors.IsTooManyRequests(err):
+			logger.WithError(err).Info(
+				"Pod can't be evicted yet, will retry")
+		case err != nil && !kubeerrors.IsNotFound(err):
+			logger.WithError(err).Error("Failed to evict pod")
+		}
+	}
+}
+
+// onNodes returns whether the pod is running on any of the nodes with the given
+// private IPs.
+func onNodes(pod corev1.Pod, ips []string) bool {
+	for _, ip := range ips {
+		if pod.Status.HostIP == ip {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+package kubernetes
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/minion/kubernetes/mocks"
+
+	"github.com/stretchr/testify/mock"
+	corev1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDrainNodes(t *testing.T) {
+	t.Parallel()
+	nodesClient := &mocks.NodeInterface{}
+	podsClient := &mocks.PodInterface{}
+
+	minions := []db.Minion{
+		{PrivateIP: "10.0.0.2", Draining: true},
+		{PrivateIP: "10.0.0.3"},
+	}
+	drainingNode := corev1.Node{Status: privateIPAddress("10.0.0.2")}
+	activeNode := corev1.Node{Status: privateIPAddress("10.0.0.3")}
+	nodes := []corev1.Node{
+		drainingNode,
+		activeNode,
+		// Nodes without minions are ignored.
+		{Status: privateIPAddress("10.0.0.4")},
+	}
+
+	podOn := func(name, ip string, annotations map[string]string) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Annotations: annotations,
+			},
+			Status: corev1.PodStatus{HostIP: ip},
+		}
+	}
+	pods := []corev1.Pod{
+		podOn("web", "10.0.0.2", nil),
+		podOn("agent", "10.0.0.2", map[string]string{daemonSetKey: "agent"}),
+		podOn("db", "10.0.0.3", nil),
+	}
+
+	// The draining node should be cordoned, and its pods other than the
+	// daemon set pods should be evicted.
+	cordonedNode := drainingNode
+	cordonedNode.Spec.Unschedulable = true
+	nodesClient.On("List", mock.Anything).Return(
+		&corev1.NodeList{Items: nodes}, nil).Once()
+	nodesClient.On("Update", &cordonedNode).Return(nil, nil).Once()
+	podsClient.On("List", mock.Anything).Return(
+		&corev1.PodList{Items: pods}, nil).Once()
+	podsClient.On("Evict", evictionOf("web")).Return(nil).Once()
+	drainNodes(minions, nodesClient, podsClient)
+	nodesClient.AssertExpectations(t)
+	podsClient.AssertExpectations(t)
+
+	// Nothing should be done if no minions are draining, and the nodes are
+	// already schedulable.
+	nodesClient.On("List", mock.Anything).Return(
+		&corev1.NodeList{Items: nodes}, nil).Once()
+	drainNodes([]db.Minion{{PrivateIP: "10.0.0.2"}, {PrivateIP: "10.0.0.3"}},
+		nodesClient, podsClient)
+	nodesClient.AssertExpectations(t)
+	podsClient.AssertExpectations(t)
+
+	// Nodes should be uncordoned if their minion stops draining.
+	nodesClient.On("List", mock.Anything).Return(
+		&corev1.NodeList{Items: []corev1.Node{cordonedNode}}, nil).Once()
+	nodesClient.On("Update", &drainingNode).Return(nil, nil).Once()
+	drainNodes([]db.Minion{{PrivateIP: "10.0.0.2"}}, nodesClient, podsClient)
+	nodesClient.AssertExpectations(t)
+	podsClient.AssertExpectations(t)
+}
+
+func TestDrainNodesGradually(t *testing.T) {
+	t.Parallel()
+	nodesClient := &mocks.NodeInterface{}
+	podsClient := &mocks.PodInterface{}
+
+	minions := []db.Minion{{PrivateIP: "10.0.0.2", Draining: true}}
+	node := corev1.Node{Status: privateIPAddress("10.0.0.2")}
+	node.Spec.Unschedulable = true
+	nodesClient.On("List", mock.Anything).Return(
+		&corev1.NodeList{Items: []corev1.Node{node}}, nil)
+
+	var pods []corev1.Pod
+	for i := evictionBatchSize; i >= 0; i-- {
+		pods = append(pods, corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("web-%d", i)},
+			Status:     corev1.PodStatus{HostIP: "10.0.0.2"},
+		})
+	}
+
+	// Only a batch of pods should be evicted at a time.
+	podsClient.On("List", mock.Anything).Return(
+		&corev1.PodList{Items: pods}, nil).Once()
+	for i := 0; i < evictionBatchSize; i++ {
+		podsClient.On("Evict", evictionOf(fmt.Sprintf("web-%d", i))).
+			Return(nil).Once()
+	}
+	drainNodes(minions, nodesClient, podsClient)
+	podsClient.AssertExpectations(t)
+
+	// No more pods should be evicted while evicted pods are terminating.
+	terminating := pods[len(pods)-1]
+	terminating.DeletionTimestamp = &metav1.Time{}
+	podsClient.On("List", mock.Anything).Return(&corev1.PodList{
+		Items: []corev1.Pod{pods[0], terminating}}, nil).Once()
+	drainNodes(minions, nodesClient, podsClient)
+	podsClient.AssertExpectations(t)
+
+	podsClient.On("List", mock.Anything).Return(&corev1.PodList{
+		Items: []corev1.Pod{pods[0]}}, nil).Once()
+	podsClient.On("Evict", evictionOf(pods[0].Name)).Return(nil).Once()
+	drainNodes(minions, nodesClient, podsClient)
+	podsClient.AssertExpectations(t)
+}
+
+func evictionOf(name string) *policyv1beta1.Eviction {
+	return &policyv1beta1.Eviction{ObjectMeta: metav1.ObjectMeta{Name: name}}
+}
@@ -21,13 +21,14 @@ import (
 
 var c = counter.New("Kubernetes")
 
-// Run converts the containers specified by the user into deployments in the
-// Kubernetes cluster. It also syncs the status of the deployment into the
-// database.
+// Run converts the containers specified by the user into deployments and
+// daemon sets in the Kubernetes cluster. It also syncs the status of the
+// deployment into the database.
 // The module is implemented as several goroutines. One goroutine creates the
 // ConfigMap and deployment objects for Kubernetes to deploy. Another goroutine
-// tags the Kubernetes workers with metadata to be used by placement rules. The
-// final goroutine syncs the status of the deployment into the database.
+// tags the Kubernetes workers with metadata to be used by placement rules, and
+// drains the workers that are being removed from machine pools. The final
+// goroutine syncs the status of the deployment into the database.
 func Run(conn db.Conn, dk docker.Client) {
 	var clientset *kubernetes.Clientset
 	var err error
@@ -66,27 +67,42 @@ func Run(conn db.Conn, dk docker.Client) {
 
 	configMapsClient := clientset.CoreV1().ConfigMaps(corev1.NamespaceDefault)
 	deploymentsClient := clientset.AppsV1().Deployments(corev1.NamespaceDefault)
+	daemonSetsClient := clientset.AppsV1().DaemonSets(corev1.NamespaceDefault)
 	nodesClient := clientset.CoreV1().Nodes()
 	podsClient := clientset.CoreV1().Pods(corev1.NamespaceDefault)
-	secretClient := secretClientImpl{
-		clientset.CoreV1().Secrets(corev1.NamespaceDefault)}
+	secretsClient := clientset.CoreV1().Secrets(corev1.NamespaceDefault)
+	secretClient := secretClientImpl{secretsClient}
 	go func() {
 		trig := util.JoinNotifiers(toStructChan(secretWatcher.ResultChan()),
 			conn.TriggerTick(60, db.ContainerTable, db.PlacementTable,
-				db.EtcdTable, db.ImageTable).C)
+				db.EtcdTable, db.ImageTable, db.BlueprintTable,
+				db.HostnameTable, db.LoadBalancerTable).C)
 		for range trig {
-			// Update config maps before updating deployments. This way, any
-			// config maps referenced in updateDeployments will most likely
-			// exist.
-			if updateConfigMaps(conn, configMapsClient) {
+			// Copy secrets from external secret backends before updating
+			// deployments so that the pods referencing them can be created.
+			// Because this runs at least every minute, secrets rotated in the
+			// backend are picked up within a minute.
+			syncSecrets(conn, secretClient)
+			labelLegacySecrets(conn, secretsClient)
+			updateRegistryCredentials(conn, secretsClient, secretClient)
+
+			// Update config maps and templates before updating deployments.
+			// This way, any config maps and templates referenced in
+			// updateDeployments will most likely exist.
+			configMapsOK := updateConfigMaps(conn, configMapsClient)
+			templatesOK := updateTemplates(conn, secretsClient, secretClient)
+			if configMapsOK && templatesOK {
 				updateDeployments(conn, deploymentsClient, secretClient)
+				updateDaemonSets(conn, daemonSetsClient, secretClient)
 			}
 		}
 	}()
 
 	go func() {
 		for range conn.TriggerTick(60, db.MinionTable, db.EtcdTable).C {
-			updateNodeLabels(conn.SelectFromMinion(nil), nodesClient)
+			minions := conn.SelectFromMinion(nil)
+			updateNodeLabels(minions, nodesClient)
+			drainNodes(minions, nodesClient, podsClient)
 		}
 	}()
 
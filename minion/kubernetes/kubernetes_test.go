@@ -3,6 +3,7 @@
 //go:generate mockery -dir ../../vendor/k8s.io/client-go/kubernetes/typed/core/v1 -name NodeInterface
 //go:generate mockery -dir ../../vendor/k8s.io/client-go/kubernetes/typed/core/v1 -name PodInterface
 //go:generate mockery -dir ../../vendor/k8s.io/client-go/kubernetes/typed/apps/v1 -name DeploymentInterface
+//go:generate mockery -dir ../../vendor/k8s.io/client-go/kubernetes/typed/apps/v1 -name DaemonSetInterface
 //go:generate mockery -name=SecretClient
 package kubernetes
 
@@ -2,12 +2,27 @@
 package mocks
 
 import mock "github.com/stretchr/testify/mock"
+import time "time"
 
 // SecretClient is an autogenerated mock type for the SecretClient type
 type SecretClient struct {
 	mock.Mock
 }
 
+// Delete provides a mock function with given fields: name
+func (_m *SecretClient) Delete(name string) error {
+	ret := _m.Called(name)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(name)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Exists provides a mock function with given fields: name
 func (_m *SecretClient) Exists(name string) bool {
 	ret := _m.Called(name)
@@ -43,13 +58,59 @@ func (_m *SecretClient) Get(name string) (string, error) {
 	return r0, r1
 }
 
-// Set provides a mock function with given fields: name, val
-func (_m *SecretClient) Set(name string, val string) error {
-	ret := _m.Called(name, val)
+// GetAllowedHostnames provides a mock function with given fields: name
+func (_m *SecretClient) GetAllowedHostnames(name string) ([]string, error) {
+	ret := _m.Called(name)
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func(string) []string); ok {
+		r0 = rf(name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// List provides a mock function with given fields:
+func (_m *SecretClient) List() (map[string]time.Time, error) {
+	ret := _m.Called()
+
+	var r0 map[string]time.Time
+	if rf, ok := ret.Get(0).(func() map[string]time.Time); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]time.Time)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Set provides a mock function with given fields: name, val, allowedHostnames
+func (_m *SecretClient) Set(name string, val string, allowedHostnames []string) error {
+	ret := _m.Called(name, val, allowedHostnames)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string, string) error); ok {
-		r0 = rf(name, val)
+	if rf, ok := ret.Get(0).(func(string, string, []string) error); ok {
+		r0 = rf(name, val, allowedHostnames)
 	} else {
 		r0 = ret.Error(0)
 	}
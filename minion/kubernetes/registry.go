@@ -0,0 +1,146 @@
+package kubernetes
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/db"
+
+	dkc "github.com/fsouza/go-dockerclient"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreclient "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// registryCredentialsName is the name of the Kubernetes secret that holds the
+// credentials for the private registries in the blueprint. It's referenced by
+// the imagePullSecrets of every pod.
+const registryCredentialsName = "kelda-registry-credentials"
+
+// RegistryAuths returns the credentials for the registries in `creds`, keyed by
+// registry server. The credentials are read from the secrets named by each
+// RegistryCredential. Registries whose secrets aren't set, or aren't of the
+// form USERNAME:PASSWORD, are skipped and logged.
+func RegistryAuths(creds []blueprint.RegistryCredential,
+	secretClient SecretClient) map[string]dkc.AuthConfiguration {
+
+	auths := map[string]dkc.AuthConfiguration{}
+	for _, cred := range creds {
+		val, err := secretClient.Get(cred.Secret)
+		if err != nil {
+			log.WithError(err).WithField("registry", cred.Server).
+				WithField("secret", cred.Secret).
+				Warn("Failed to get registry credentials")
+			continue
+		}
+
+		parts := strings.SplitN(val, ":", 2)
+		if len(parts) != 2 {
+			log.WithField("registry", cred.Server).
+				WithField("secret", cred.Secret).
+				Warn("Malformed registry credentials. The secret " +
+					"must be of the form USERNAME:PASSWORD")
+			continue
+		}
+
+		auths[cred.Server] = dkc.AuthConfiguration{
+			Username:      parts[0],
+			Password:      parts[1],
+			ServerAddress: cred.Server,
+		}
+	}
+	return auths
+}
+
+// updateRegistryCredentials syncs the credentials for the private registries
+// in the blueprint into a Kubernetes secret of the type expected by
+// imagePullSecrets. The secret is removed if there are no credentials.
+func updateRegistryCredentials(conn db.Conn,
+	secretsClient coreclient.SecretInterface, secretClient SecretClient) {
+
+	var creds []blueprint.RegistryCredential
+	conn.Txn(db.BlueprintTable).Run(func(view db.Database) error {
+		if bp, err := view.GetBlueprint(); err == nil {
+			creds = bp.RegistryCredentials
+		}
+		return nil
+	})
+
+	current, err := secretsClient.Get(registryCredentialsName,
+		metav1.GetOptions{})
+	exists := err == nil
+
+	if len(creds) == 0 {
+		if exists {
+			c.Inc("Delete registry credentials")
+			err := secretsClient.Delete(registryCredentialsName,
+				&metav1.DeleteOptions{})
+			if err != nil {
+				log.WithError(err).Error(
+					"Failed to delete registry credentials")
+			}
+		}
+		return
+	}
+
+	dockerConfig, err := makeDockerConfig(RegistryAuths(creds, secretClient))
+	if err != nil {
+		log.WithError(err).Error("Failed to create registry credentials")
+		return
+	}
+
+	desired := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: registryCredentialsName},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			corev1.DockerConfigJsonKey: dockerConfig,
+		},
+	}
+
+	// Only write the secret when it changes. Otherwise, the write would
+	// trigger the secret watcher, and cause another write.
+	switch {
+	case !exists:
+		c.Inc("Create registry credentials")
+		_, err = secretsClient.Create(&desired)
+	case !reflect.DeepEqual(current.Data, desired.Data):
+		c.Inc("Update registry credentials")
+		_, err = secretsClient.Update(&desired)
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to write registry credentials")
+	}
+}
+
+// makeDockerConfig returns the contents of a Docker config.json file
+// containing the given credentials.
+func makeDockerConfig(auths map[string]dkc.AuthConfiguration) ([]byte, error) {
+	type dockerAuth struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Auth     string `json:"auth"`
+	}
+
+	config := struct {
+		Auths map[string]dockerAuth `json:"auths"`
+	}{map[string]dockerAuth{}}
+	for server, auth := range auths {
+		config.Auths[server] = dockerAuth{
+			Username: auth.Username,
+			Password: auth.Password,
+			Auth: base64.StdEncoding.EncodeToString(
+				[]byte(auth.Username + ":" + auth.Password)),
+		}
+	}
+
+	dockerConfig, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("marshal docker config: %s", err)
+	}
+	return dockerConfig, nil
+}
@@ -0,0 +1,150 @@
+package kubernetes
+
+import (
+	"errors"
+	"testing"
+
+	dkc "github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/minion/kubernetes/mocks"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRegistryAuths(t *testing.T) {
+	t.Parallel()
+
+	secretClient := &mocks.SecretClient{}
+	secretClient.On("Get", "creds").Return("user:pass:word", nil)
+	secretClient.On("Get", "malformed").Return("user", nil)
+	secretClient.On("Get", "missing").Return("", assert.AnError)
+
+	auths := RegistryAuths([]blueprint.RegistryCredential{
+		{Server: "registry.example.com", Secret: "creds"},
+		{Server: "malformed.example.com", Secret: "malformed"},
+		{Server: "missing.example.com", Secret: "missing"},
+	}, secretClient)
+	assert.Equal(t, map[string]dkc.AuthConfiguration{
+		"registry.example.com": {
+			Username:      "user",
+			Password:      "pass:word",
+			ServerAddress: "registry.example.com",
+		},
+	}, auths)
+}
+
+func TestMakeDockerConfig(t *testing.T) {
+	t.Parallel()
+
+	config, err := makeDockerConfig(map[string]dkc.AuthConfiguration{
+		"registry.example.com": {Username: "user", Password: "pass"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"auths":{"registry.example.com":{"username":"user",`+
+		`"password":"pass","auth":"dXNlcjpwYXNz"}}}`, string(config))
+
+	config, err = makeDockerConfig(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"auths":{}}`, string(config))
+}
+
+func TestUpdateRegistryCredentials(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	conn.Txn(db.BlueprintTable).Run(func(view db.Database) error {
+		bp := view.InsertBlueprint()
+		bp.RegistryCredentials = []blueprint.RegistryCredential{
+			{Server: "registry.example.com", Secret: "creds"},
+		}
+		view.Commit(bp)
+		return nil
+	})
+
+	secretClient := &mocks.SecretClient{}
+	secretClient.On("Get", "creds").Return("user:pass", nil)
+
+	config, _ := makeDockerConfig(map[string]dkc.AuthConfiguration{
+		"registry.example.com": {Username: "user", Password: "pass"},
+	})
+	desired := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: registryCredentialsName},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: config},
+	}
+
+	// Test creating the secret.
+	kubeClient := &mocks.SecretInterface{}
+	kubeClient.On("Get", registryCredentialsName, mock.Anything).
+		Return(nil, errors.New("does not exist")).Once()
+	kubeClient.On("Create", &desired).Return(nil, nil).Once()
+	updateRegistryCredentials(conn, kubeClient, secretClient)
+	kubeClient.AssertExpectations(t)
+
+	// Test that the secret isn't written if it's unchanged.
+	kubeClient = &mocks.SecretInterface{}
+	kubeClient.On("Get", registryCredentialsName, mock.Anything).
+		Return(&desired, nil).Once()
+	updateRegistryCredentials(conn, kubeClient, secretClient)
+	kubeClient.AssertExpectations(t)
+
+	// Test updating the secret when the credentials change.
+	stale := corev1.Secret{
+		ObjectMeta: desired.ObjectMeta,
+		Type:       desired.Type,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte("{}")},
+	}
+	kubeClient = &mocks.SecretInterface{}
+	kubeClient.On("Get", registryCredentialsName, mock.Anything).
+		Return(&stale, nil).Once()
+	kubeClient.On("Update", &desired).Return(nil, nil).Once()
+	updateRegistryCredentials(conn, kubeClient, secretClient)
+	kubeClient.AssertExpectations(t)
+
+	// Test deleting the secret once the blueprint has no credentials.
+	conn.Txn(db.BlueprintTable).Run(func(view db.Database) error {
+		bp, _ := view.GetBlueprint()
+		bp.RegistryCredentials = nil
+		view.Commit(bp)
+		return nil
+	})
+	kubeClient = &mocks.SecretInterface{}
+	kubeClient.On("Get", registryCredentialsName, mock.Anything).
+		Return(&desired, nil).Once()
+	kubeClient.On("Delete", registryCredentialsName, mock.Anything).
+		Return(nil).Once()
+	updateRegistryCredentials(conn, kubeClient, secretClient)
+	kubeClient.AssertExpectations(t)
+}
+
+func TestMakeDesiredDeploymentsImagePullSecrets(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		bp := view.InsertBlueprint()
+		bp.RegistryCredentials = []blueprint.RegistryCredential{
+			{Server: "registry.example.com", Secret: "creds"},
+		}
+		view.Commit(bp)
+
+		dbc := view.InsertContainer()
+		dbc.Hostname = "web"
+		dbc.Image = "registry.example.com/web"
+		dbc.IP = "10.0.0.2"
+		view.Commit(dbc)
+		return nil
+	})
+
+	deployments, err := makeDesiredDeployments(conn, nil)
+	assert.NoError(t, err)
+	assert.Len(t, deployments, 1)
+	assert.Equal(t, []corev1.LocalObjectReference{
+		{Name: registryCredentialsName},
+	}, deployments[0].Spec.Template.Spec.ImagePullSecrets)
+}
@@ -0,0 +1,241 @@
+package kubernetes
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/version"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Runtime values that depend on where the pod is running are resolved by
+// Kubernetes through the downward API. Runtime values that are the same for
+// every pod are resolved by the leader. Files with runtime values are written
+// by an init container into a volume shared by the pod's containers, since
+// the downward API can't write the pod and host IPs to files.
+//
+// The provider and region of the host are resolved by the leader if the pod is
+// pinned to a worker. Otherwise, the init container copies them from the files
+// that each worker's minion writes into HostValuesDir, so that the pod doesn't
+// change when workers are added or removed. Environment variables can't be set
+// after the pod is scheduled, so the API server only allows them for pinned
+// containers.
+
+const (
+	runtimeValuesContainerName = "kelda-runtime-values"
+	runtimeValuesVolumeName    = "kelda-runtime-values"
+	runtimeValuesDir           = "/kelda-runtime-values"
+)
+
+// HostValuesDir is the directory on each worker that contains a file for each
+// of the runtime values that describe the worker, named after the value's key.
+const HostValuesDir = "/var/lib/kubelet/kelda-host-values"
+
+const (
+	hostValuesVolumeName = "kelda-host-values"
+	hostValuesMountDir   = "/kelda-host-values"
+)
+
+// downwardAPIFields are the fields that the downward API uses to resolve the
+// runtime values that depend on where the pod is running.
+var downwardAPIFields = map[string]string{
+	blueprint.ContainerIPKey: "status.podIP",
+	blueprint.HostIPKey:      "status.hostIP",
+}
+
+// HostValueFiles returns the contents of the files in HostValuesDir for a
+// worker with the given provider and region, keyed by path.
+func HostValueFiles(provider, region string) map[string]string {
+	return map[string]string{
+		path.Join(HostValuesDir, blueprint.HostProviderKey): provider,
+		path.Join(HostValuesDir, blueprint.HostRegionKey):   region,
+	}
+}
+
+// isHostValue returns whether the given runtime value describes the pod's
+// host, but can't be resolved by the downward API.
+func isHostValue(key string) bool {
+	return key == blueprint.HostProviderKey || key == blueprint.HostRegionKey
+}
+
+// runtimeData contains the information used to resolve the runtime values and
+// templates of pods.
+type runtimeData struct {
+	// clusterValues are the runtime values that are the same for every pod.
+	clusterValues map[string]string
+
+	// nodeValues maps the name of each node, which is the private IP of the
+	// worker, to the runtime values that describe it.
+	nodeValues map[string]map[string]string
+
+	// hostnameToIP maps the hostnames of containers and load balancers to
+	// their IPs.
+	hostnameToIP map[string]string
+
+	// loadBalancers maps the names of load balancers to the hostnames of
+	// their members.
+	loadBalancers map[string][]string
+}
+
+// getRuntimeData returns the runtime data of the pods in the given blueprint.
+func getRuntimeData(view db.Database, bp db.Blueprint) *runtimeData {
+	data := &runtimeData{
+		clusterValues: map[string]string{
+			blueprint.NamespaceKey: bp.Blueprint.Namespace,
+		},
+		nodeValues:    map[string]map[string]string{},
+		hostnameToIP:  map[string]string{},
+		loadBalancers: map[string][]string{},
+	}
+	for _, worker := range view.SelectFromMinion(func(m db.Minion) bool {
+		return m.Role == db.Worker && m.PrivateIP != ""
+	}) {
+		data.nodeValues[worker.PrivateIP] = map[string]string{
+			blueprint.HostProviderKey: worker.Provider,
+			blueprint.HostRegionKey:   worker.Region,
+		}
+	}
+	for _, hostname := range view.SelectFromHostname(nil) {
+		data.hostnameToIP[hostname.Hostname] = hostname.IP
+	}
+	for _, lb := range view.SelectFromLoadBalancer(nil) {
+		data.loadBalancers[lb.Name] = lb.Hostnames
+	}
+	return data
+}
+
+// podValues returns the runtime values that the leader can resolve for the
+// given container's pod. The host's values are only known if the pod is pinned
+// to a worker.
+func (runtime *runtimeData) podValues(dbc db.Container) map[string]string {
+	if runtime == nil {
+		return nil
+	}
+
+	values := map[string]string{}
+	for key, val := range runtime.clusterValues {
+		values[key] = val
+	}
+	for key, val := range runtime.nodeValues[dbc.Minion] {
+		values[key] = val
+	}
+	return values
+}
+
+// makeRuntimeEnvVar returns the environment variable with the given name that
+// is set to the runtime value with the given key. The returned boolean is
+// false if the value can't be resolved.
+func makeRuntimeEnvVar(values map[string]string, name, key string) (
+	corev1.EnvVar, bool) {
+
+	if field, ok := downwardAPIFields[key]; ok {
+		return corev1.EnvVar{
+			Name: name,
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: field},
+			},
+		}, true
+	}
+
+	val, ok := values[key]
+	return corev1.EnvVar{Name: name, Value: val}, ok
+}
+
+// makeRuntimeValuesContainer returns an init container that writes the runtime
+// values with the given keys into the runtime values volume, so that they can
+// be mounted as files by the pod's containers, along with the volumes that the
+// container needs. The host's values that aren't in `values` are copied from
+// HostValuesDir on the pod's node. The returned boolean is false if any of the
+// keys are unknown.
+func makeRuntimeValuesContainer(values map[string]string,
+	keys map[string]struct{}) (corev1.Container, []corev1.Volume, bool) {
+
+	var sortedKeys []string
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+
+	var env []corev1.EnvVar
+	var cmds []string
+	volumes := []corev1.Volume{runtimeValuesVolume()}
+	mounts := []corev1.VolumeMount{{
+		Name:      runtimeValuesVolumeName,
+		MountPath: runtimeValuesDir,
+	}}
+	for i, key := range sortedKeys {
+		file := fmt.Sprintf("%s/%s", runtimeValuesDir, key)
+		if _, ok := values[key]; !ok && isHostValue(key) {
+			cmds = append(cmds, fmt.Sprintf("cat %s/%s > %s",
+				hostValuesMountDir, key, file))
+			continue
+		}
+
+		name := fmt.Sprintf("RUNTIME_VALUE_%d", i)
+		envVar, ok := makeRuntimeEnvVar(values, name, key)
+		if !ok {
+			return corev1.Container{}, nil, false
+		}
+
+		env = append(env, envVar)
+		cmds = append(cmds, fmt.Sprintf(`printf %%s "$%s" > %s`, name, file))
+	}
+
+	if len(cmds) != len(env) {
+		volumes = append(volumes, hostValuesVolume())
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      hostValuesVolumeName,
+			MountPath: hostValuesMountDir,
+			ReadOnly:  true,
+		})
+	}
+
+	return corev1.Container{
+		Name:         runtimeValuesContainerName,
+		Image:        version.Image,
+		Command:      []string{"sh", "-c", strings.Join(cmds, " && ")},
+		Env:          env,
+		VolumeMounts: mounts,
+	}, volumes, true
+}
+
+// hostValuesVolume returns the volume that contains the runtime values that
+// describe the pod's node.
+func hostValuesVolume() corev1.Volume {
+	return corev1.Volume{
+		Name: hostValuesVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			HostPath: &corev1.HostPathVolumeSource{Path: HostValuesDir},
+		},
+	}
+}
+
+// runtimeValuesVolume returns the volume that the runtime values container
+// writes the runtime values into.
+func runtimeValuesVolume() corev1.Volume {
+	return corev1.Volume{
+		Name: runtimeValuesVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		},
+	}
+}
+
+// runtimeFileKeys returns the keys of the runtime values that are mounted as
+// files by the containers in the given container's pod.
+func runtimeFileKeys(dbc db.Container) map[string]struct{} {
+	keys := map[string]struct{}{}
+	for _, member := range dbc.PodContainers() {
+		_, _, runtimeValues, _ := blueprint.DivideContainerValues(
+			member.FilepathToContent)
+		for _, key := range runtimeValues {
+			keys[key] = struct{}{}
+		}
+	}
+	return keys
+}
@@ -0,0 +1,189 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/version"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestGetRuntimeData(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		for _, m := range []db.Minion{
+			{Role: db.Worker, PrivateIP: "10.0.0.2", Provider: "Amazon",
+				Region: "us-west-1"},
+			{Role: db.Worker, PrivateIP: "10.0.0.3", Provider: "Google",
+				Region: "us-east1-b"},
+			{Role: db.Master, PrivateIP: "10.0.0.4", Provider: "Amazon",
+				Region: "us-east-1"},
+		} {
+			m.ID = view.InsertMinion().ID
+			view.Commit(m)
+		}
+
+		runtime := getRuntimeData(view, db.Blueprint{
+			Blueprint: blueprint.Blueprint{Namespace: "ns"}})
+		assert.Equal(t, map[string]string{blueprint.NamespaceKey: "ns"},
+			runtime.clusterValues)
+		assert.Equal(t, map[string]map[string]string{
+			"10.0.0.2": {blueprint.HostProviderKey: "Amazon",
+				blueprint.HostRegionKey: "us-west-1"},
+			"10.0.0.3": {blueprint.HostProviderKey: "Google",
+				blueprint.HostRegionKey: "us-east1-b"},
+		}, runtime.nodeValues)
+
+		// Pods pinned to a worker use its values.
+		assert.Equal(t, map[string]string{
+			blueprint.NamespaceKey:    "ns",
+			blueprint.HostProviderKey: "Google",
+			blueprint.HostRegionKey:   "us-east1-b",
+		}, runtime.podValues(db.Container{Minion: "10.0.0.3"}))
+		return nil
+	})
+}
+
+func TestMakePodRuntimeValues(t *testing.T) {
+	t.Parallel()
+
+	runtime := &runtimeData{clusterValues: map[string]string{
+		blueprint.NamespaceKey:  "ns",
+		blueprint.HostRegionKey: "us-west-1",
+	}}
+	dbc := db.Container{
+		Hostname: "web",
+		Image:    "nginx",
+		Env: map[string]blueprint.ContainerValue{
+			"IP":        blueprint.NewRuntimeValue(blueprint.ContainerIPKey),
+			"HOST":      blueprint.NewRuntimeValue(blueprint.HostIPKey),
+			"NAMESPACE": blueprint.NewRuntimeValue(blueprint.NamespaceKey),
+		},
+		FilepathToContent: map[string]blueprint.ContainerValue{
+			"/etc/ip": blueprint.NewRuntimeValue(
+				blueprint.ContainerIPKey),
+			"/etc/region": blueprint.NewRuntimeValue(
+				blueprint.HostRegionKey),
+		},
+		Sidecars: []blueprint.Container{{
+			Hostname: "proxy",
+			FilepathToContent: map[string]blueprint.ContainerValue{
+				"/ip": blueprint.NewRuntimeValue(
+					blueprint.ContainerIPKey),
+			},
+		}},
+	}
+	pod, ok := makePod(nil, map[string]*corev1.Affinity{}, nil, nil,
+		runtime, dbc)
+	assert.True(t, ok)
+
+	// The values that depend on where the pod runs should be resolved by the
+	// downward API, and the others by the leader.
+	assert.Equal(t, []corev1.EnvVar{
+		{Name: "HOST", ValueFrom: &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{FieldPath: "status.hostIP"},
+		}},
+		{Name: "IP", ValueFrom: &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{FieldPath: "status.podIP"},
+		}},
+		{Name: "NAMESPACE", Value: "ns"},
+	}, pod.Containers[0].Env)
+
+	// The files should be written by a single init container, and mounted
+	// from its volume.
+	assert.Equal(t, []corev1.Container{{
+		Name:  runtimeValuesContainerName,
+		Image: version.Image,
+		Command: []string{"sh", "-c",
+			`printf %s "$RUNTIME_VALUE_0" > ` +
+				`/kelda-runtime-values/containerIP && ` +
+				`printf %s "$RUNTIME_VALUE_1" > ` +
+				`/kelda-runtime-values/host.region`},
+		Env: []corev1.EnvVar{
+			{Name: "RUNTIME_VALUE_0", ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{
+					FieldPath: "status.podIP"},
+			}},
+			{Name: "RUNTIME_VALUE_1", Value: "us-west-1"},
+		},
+		VolumeMounts: []corev1.VolumeMount{{
+			Name:      runtimeValuesVolumeName,
+			MountPath: runtimeValuesDir,
+		}},
+	}}, pod.InitContainers)
+	assert.Equal(t, []corev1.Volume{runtimeValuesVolume()}, pod.Volumes)
+	assert.Equal(t, []corev1.VolumeMount{
+		{Name: runtimeValuesVolumeName, MountPath: "/etc/ip",
+			SubPath: blueprint.ContainerIPKey},
+		{Name: runtimeValuesVolumeName, MountPath: "/etc/region",
+			SubPath: blueprint.HostRegionKey},
+	}, pod.Containers[0].VolumeMounts)
+	assert.Equal(t, []corev1.VolumeMount{
+		{Name: runtimeValuesVolumeName, MountPath: "/ip",
+			SubPath: blueprint.ContainerIPKey},
+	}, pod.Containers[1].VolumeMounts)
+
+	// Pods that reference unknown runtime values shouldn't be created.
+	dbc.Env["UNKNOWN"] = blueprint.NewRuntimeValue("unknown")
+	_, ok = makePod(nil, map[string]*corev1.Affinity{}, nil, nil,
+		runtime, dbc)
+	assert.False(t, ok)
+
+	// Host values that can't be resolved before the pod is scheduled are
+	// copied from the node's files, and can't be used in the environment.
+	delete(dbc.Env, "UNKNOWN")
+	runtime.clusterValues = map[string]string{blueprint.NamespaceKey: "ns"}
+	runtime.nodeValues = map[string]map[string]string{
+		"10.0.0.3": {blueprint.HostProviderKey: "Google"},
+		"10.0.0.2": {blueprint.HostProviderKey: "Amazon"},
+	}
+	dbc.FilepathToContent["/provider"] = blueprint.NewRuntimeValue(
+		blueprint.HostProviderKey)
+	pod, ok = makePod(nil, map[string]*corev1.Affinity{}, nil, nil,
+		runtime, dbc)
+	assert.True(t, ok)
+
+	init := pod.InitContainers[0]
+	assert.Equal(t, []string{"sh", "-c",
+		`printf %s "$RUNTIME_VALUE_0" > /kelda-runtime-values/containerIP && ` +
+			`cat /kelda-host-values/host.provider > ` +
+			`/kelda-runtime-values/host.provider && ` +
+			`cat /kelda-host-values/host.region > ` +
+			`/kelda-runtime-values/host.region`},
+		init.Command)
+	assert.Contains(t, init.VolumeMounts, corev1.VolumeMount{
+		Name:      hostValuesVolumeName,
+		MountPath: hostValuesMountDir,
+		ReadOnly:  true,
+	})
+	assert.Equal(t, []corev1.Volume{hostValuesVolume(),
+		runtimeValuesVolume()}, pod.Volumes)
+
+	// The pod shouldn't change when workers are added or removed.
+	runtime.nodeValues["10.0.0.4"] = map[string]string{
+		blueprint.HostProviderKey: "DigitalOcean"}
+	newPod, ok := makePod(nil, map[string]*corev1.Affinity{}, nil, nil,
+		runtime, dbc)
+	assert.True(t, ok)
+	assert.Equal(t, pod, newPod)
+
+	dbc.Env["PROVIDER"] = blueprint.NewRuntimeValue(blueprint.HostProviderKey)
+	_, ok = makePod(nil, map[string]*corev1.Affinity{}, nil, nil,
+		runtime, dbc)
+	assert.False(t, ok)
+
+	// Pods pinned to a worker use its values.
+	dbc.Minion = "10.0.0.2"
+	pod, ok = makePod(nil, map[string]*corev1.Affinity{}, nil, nil,
+		runtime, dbc)
+	assert.True(t, ok)
+	assert.Contains(t, pod.Containers[0].Env,
+		corev1.EnvVar{Name: "PROVIDER", Value: "Amazon"})
+	assert.Contains(t, pod.InitContainers[0].Command[2],
+		`printf %s "$RUNTIME_VALUE_1" > /kelda-runtime-values/host.provider`)
+}
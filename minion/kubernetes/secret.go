@@ -3,7 +3,15 @@ package kubernetes
 import (
 	"crypto/sha1"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/util/str"
+
+	log "github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	coreclient "k8s.io/client-go/kubernetes/typed/core/v1"
@@ -18,10 +26,48 @@ type SecretClient interface {
 	// Get returns the secret value associated with the given name.
 	Get(name string) (string, error)
 
-	// Set associates the given name with the secret value.
-	Set(name, val string) error
+	// Set associates the given name with the secret value. If
+	// allowedHostnames is non-empty, only containers with those hostnames
+	// may use the secret, or any container if it contains "*". Otherwise,
+	// the hostnames allowed to use an existing secret are unchanged, and new
+	// secrets may be used by any container.
+	Set(name, val string, allowedHostnames []string) error
+
+	// GetAllowedHostnames returns the hostnames of the containers that are
+	// allowed to use the secret. A nil slice means that any container may
+	// use it.
+	GetAllowedHostnames(name string) ([]string, error)
+
+	// List returns the names of all secrets that have been set, mapped to
+	// when they were last modified.
+	List() (map[string]time.Time, error)
+
+	// Delete removes the secret value associated with the given name.
+	Delete(name string) error
 }
 
+const (
+	// secretLabelKey labels the Kubernetes secrets created by Kelda so that
+	// they can be listed.
+	secretLabelKey = "kelda.io/secret"
+
+	// Kubernetes secrets are named after the hash of the secret name, so the
+	// original name is stored in an annotation.
+	secretNameKey         = "kelda.io/secret.name"
+	secretLastModifiedKey = "kelda.io/secret.lastModified"
+
+	// secretAllowedHostnamesKey stores the comma-separated hostnames of the
+	// containers allowed to use the secret. If it's not set, any container
+	// may use the secret.
+	secretAllowedHostnamesKey = "kelda.io/secret.allowedHostnames"
+
+	// anyHostname allows all containers to use a secret.
+	anyHostname = "*"
+)
+
+// Stored in a variable so that it can be mocked out by the unit tests.
+var now = time.Now
+
 type secretClientImpl struct {
 	client coreclient.SecretInterface
 }
@@ -57,19 +103,54 @@ func (sc secretClientImpl) Get(name string) (string, error) {
 	return string(val), nil
 }
 
-func (sc secretClientImpl) Set(name, val string) error {
+func (sc secretClientImpl) GetAllowedHostnames(name string) ([]string, error) {
+	kubeName, _ := secretRef(name)
+	secret, err := sc.client.Get(kubeName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("query secret: %s", err)
+	}
+
+	allowed, ok := secret.Annotations[secretAllowedHostnamesKey]
+	if !ok {
+		return nil, nil
+	}
+	return strings.Split(allowed, ","), nil
+}
+
+func (sc secretClientImpl) Set(name, val string, allowedHostnames []string) error {
 	kubeName, key := secretRef(name)
 	desiredSecret := corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: kubeName,
+			Name:   kubeName,
+			Labels: map[string]string{secretLabelKey: "true"},
+			Annotations: map[string]string{
+				secretNameKey:         name,
+				secretLastModifiedKey: now().UTC().Format(time.RFC3339),
+			},
 		},
 		Data: map[string][]byte{
 			key: []byte(val),
 		},
 	}
 
-	var err error
-	if sc.Exists(name) {
+	currSecret, err := sc.client.Get(kubeName, metav1.GetOptions{})
+	exists := err == nil
+
+	switch {
+	case len(allowedHostnames) == 0:
+		if exists {
+			allowed, ok := currSecret.Annotations[secretAllowedHostnamesKey]
+			if ok {
+				desiredSecret.Annotations[secretAllowedHostnamesKey] =
+					allowed
+			}
+		}
+	case !str.SliceContains(allowedHostnames, anyHostname):
+		desiredSecret.Annotations[secretAllowedHostnamesKey] =
+			strings.Join(allowedHostnames, ",")
+	}
+
+	if exists {
 		_, err = sc.client.Update(&desiredSecret)
 	} else {
 		_, err = sc.client.Create(&desiredSecret)
@@ -77,6 +158,126 @@ func (sc secretClientImpl) Set(name, val string) error {
 	return err
 }
 
+func (sc secretClientImpl) List() (map[string]time.Time, error) {
+	secrets, err := sc.client.List(metav1.ListOptions{
+		LabelSelector: secretLabelKey + "=true",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list secrets: %s", err)
+	}
+
+	nameToLastModified := map[string]time.Time{}
+	for _, secret := range secrets.Items {
+		name, ok := secret.Annotations[secretNameKey]
+		if !ok {
+			continue
+		}
+
+		// If the timestamp is malformed, the zero time is used.
+		lastModified, _ := time.Parse(time.RFC3339,
+			secret.Annotations[secretLastModifiedKey])
+		nameToLastModified[name] = lastModified
+	}
+	return nameToLastModified, nil
+}
+
+func (sc secretClientImpl) Delete(name string) error {
+	kubeName, _ := secretRef(name)
+	if err := sc.client.Delete(kubeName, &metav1.DeleteOptions{}); err != nil {
+		return fmt.Errorf("delete secret: %s", err)
+	}
+	return nil
+}
+
+// labelLegacySecrets adds the label and name annotation to the Kubernetes
+// secrets referenced by the blueprint that were created before secrets were
+// labeled, so that they are returned by List. Because Kubernetes secrets are
+// named after the hash of the secret name, only the secrets whose names are
+// referenced can be recovered. Secrets without a lastModified timestamp use
+// their creation time.
+func labelLegacySecrets(conn db.Conn, client coreclient.SecretInterface) {
+	var containers []db.Container
+	var registryCreds []blueprint.RegistryCredential
+	conn.Txn(db.BlueprintTable, db.ContainerTable).Run(
+		func(view db.Database) error {
+			if bp, err := view.GetBlueprint(); err == nil {
+				registryCreds = bp.RegistryCredentials
+			}
+			containers = view.SelectFromContainer(nil)
+			return nil
+		})
+
+	for name := range referencedSecrets(containers, registryCreds) {
+		kubeName, _ := secretRef(name)
+		secret, err := client.Get(kubeName, metav1.GetOptions{})
+		if err != nil {
+			continue
+		}
+
+		_, hasName := secret.Annotations[secretNameKey]
+		if secret.Labels[secretLabelKey] == "true" && hasName {
+			continue
+		}
+
+		if secret.Labels == nil {
+			secret.Labels = map[string]string{}
+		}
+		if secret.Annotations == nil {
+			secret.Annotations = map[string]string{}
+		}
+		secret.Labels[secretLabelKey] = "true"
+		secret.Annotations[secretNameKey] = name
+		if _, ok := secret.Annotations[secretLastModifiedKey]; !ok {
+			secret.Annotations[secretLastModifiedKey] =
+				secret.CreationTimestamp.UTC().Format(time.RFC3339)
+		}
+
+		c.Inc("Label legacy secret")
+		if _, err := client.Update(secret); err != nil {
+			log.WithError(err).WithField("secret", name).Error(
+				"Failed to label secret")
+		}
+	}
+}
+
+// unauthorizedSecrets returns the secrets referenced by `dbc` that neither its
+// hostname nor the stateful or replica set it belongs to is allowed to use.
+// Only secrets without a scope may be used by any container, so secrets whose
+// scope can't be read are treated as unauthorized until it can be.
+func unauthorizedSecrets(secretClient SecretClient, dbc db.Container) (
+	unauthorized []string) {
+
+	seen := map[string]struct{}{}
+	for _, name := range dbc.GetReferencedSecrets() {
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+
+		allowed, err := secretClient.GetAllowedHostnames(name)
+		if err != nil {
+			log.WithError(err).WithField("secret", name).Warn(
+				"Failed to get the hostnames allowed to use secret")
+			unauthorized = append(unauthorized, name)
+			continue
+		}
+
+		if allowed == nil {
+			continue
+		}
+
+		if !str.SliceContains(allowed, dbc.Hostname) &&
+			(dbc.StatefulSet == "" ||
+				!str.SliceContains(allowed, dbc.StatefulSet)) &&
+			(dbc.ReplicaSet == "" ||
+				!str.SliceContains(allowed, dbc.ReplicaSet)) {
+			unauthorized = append(unauthorized, name)
+		}
+	}
+	sort.Strings(unauthorized)
+	return unauthorized
+}
+
 // Each secret name maps to a unique Kubernetes secret. Because a Kubernetes
 // secret is a map of values rather than a single value, we only use a single
 // key in the map.
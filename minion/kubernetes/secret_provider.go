@@ -0,0 +1,222 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// A SecretProvider resolves the values of the secrets referenced by
+// containers. The Kubernetes SecretClient is the default provider. Values from
+// other providers are copied into Kubernetes secrets by syncSecrets so that
+// they can be referenced by pods.
+type SecretProvider interface {
+	// Get returns the secret value associated with the given name.
+	Get(name string) (string, error)
+}
+
+// The default path that the Vault KV secrets engine is mounted at.
+const defaultVaultMount = "secret"
+
+// vaultProvider reads secrets from the Vault KV version 2 secrets engine over
+// Vault's HTTP API. Each secret is stored in the "value" key of the Vault
+// secret at `mount`/data/`prefix`/`name`.
+type vaultProvider struct {
+	address string
+	mount   string
+	prefix  string
+	token   string
+	client  *http.Client
+}
+
+// Stored in a variable so that it can be mocked out by the unit tests.
+var vaultHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+func (vp vaultProvider) Get(name string) (string, error) {
+	url := strings.TrimSuffix(vp.address, "/") + "/" +
+		path.Join("v1", vp.mount, "data", vp.prefix, name)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("create request: %s", err)
+	}
+	req.Header.Set("X-Vault-Token", vp.token)
+
+	resp, err := vp.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("query vault: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("secret %s does not exist in vault", name)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("query vault: unexpected status %s",
+			resp.Status)
+	}
+
+	var secret struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return "", fmt.Errorf("parse vault response: %s", err)
+	}
+
+	val, ok := secret.Data.Data["value"].(string)
+	if !ok {
+		return "", errors.New("malformed secret: missing key value")
+	}
+	return val, nil
+}
+
+// fileProvider reads each secret from the file named after the secret in
+// `dir`.
+type fileProvider struct {
+	dir string
+}
+
+func (fp fileProvider) Get(name string) (string, error) {
+	// Secret names must not be able to escape the secrets directory.
+	if name == "" || strings.ContainsRune(name, filepath.Separator) ||
+		name == "." || name == ".." {
+		return "", fmt.Errorf("invalid secret name: %q", name)
+	}
+
+	val, err := util.ReadFile(filepath.Join(fp.dir, name))
+	if err != nil {
+		return "", fmt.Errorf("read secret: %s", err)
+	}
+	return val, nil
+}
+
+// newSecretProvider returns the SecretProvider configured by `backend`. The
+// Kubernetes secretClient is returned if `backend` is nil, and is used to look
+// up the Vault token.
+func newSecretProvider(backend *blueprint.SecretBackend,
+	secretClient SecretClient) (SecretProvider, error) {
+
+	if backend == nil {
+		return secretClient, nil
+	}
+
+	switch backend.Type {
+	case "", blueprint.KubernetesSecretBackend:
+		return secretClient, nil
+	case blueprint.VaultSecretBackend:
+		if backend.Address == "" {
+			return nil, errors.New("vault secret backends require an address")
+		}
+
+		if backend.TokenSecret == "" {
+			return nil, errors.New(
+				"vault secret backends require a token secret")
+		}
+
+		token, err := secretClient.Get(backend.TokenSecret)
+		if err != nil {
+			return nil, fmt.Errorf("get vault token from secret %q: %s",
+				backend.TokenSecret, err)
+		}
+
+		mount := backend.Mount
+		if mount == "" {
+			mount = defaultVaultMount
+		}
+		return vaultProvider{
+			address: backend.Address,
+			mount:   mount,
+			prefix:  backend.Path,
+			token:   token,
+			client:  vaultHTTPClient,
+		}, nil
+	case blueprint.FileSecretBackend:
+		if backend.Path == "" {
+			return nil, errors.New("file secret backends require a path")
+		}
+		return fileProvider{backend.Path}, nil
+	default:
+		return nil, fmt.Errorf("unknown secret backend type: %s", backend.Type)
+	}
+}
+
+// syncSecrets copies the values of the secrets referenced by containers and
+// registry credentials from the blueprint's secret backend into Kubernetes
+// secrets. Nothing is copied when the Kubernetes backend is in use. Secrets are
+// only written when their values change, so that the lastModified timestamps
+// remain accurate. The hostnames allowed to use each secret are left
+// unchanged.
+func syncSecrets(conn db.Conn, secretClient SecretClient) {
+	var backend *blueprint.SecretBackend
+	var containers []db.Container
+	var registryCreds []blueprint.RegistryCredential
+	conn.Txn(db.BlueprintTable, db.ContainerTable).Run(
+		func(view db.Database) error {
+			if bp, err := view.GetBlueprint(); err == nil {
+				backend = bp.SecretBackend
+				registryCreds = bp.RegistryCredentials
+			}
+			containers = view.SelectFromContainer(nil)
+			return nil
+		})
+
+	provider, err := newSecretProvider(backend, secretClient)
+	if err != nil {
+		log.WithError(err).Error("Failed to connect to secret backend")
+		return
+	}
+
+	// The Kubernetes backend is already the source of the pods' secrets.
+	if _, ok := provider.(SecretClient); ok {
+		return
+	}
+
+	for name := range referencedSecrets(containers, registryCreds) {
+		val, err := provider.Get(name)
+		if err != nil {
+			log.WithError(err).WithField("secret", name).Debug(
+				"Failed to get secret from backend")
+			continue
+		}
+
+		current, err := secretClient.Get(name)
+		if err == nil && current == val {
+			continue
+		}
+
+		c.Inc("Sync secret")
+		if err := secretClient.Set(name, val, nil); err != nil {
+			log.WithError(err).WithField("secret", name).Error(
+				"Failed to copy secret into Kubernetes")
+		}
+	}
+}
+
+// referencedSecrets returns the names of the secrets referenced by the
+// containers and registry credentials.
+func referencedSecrets(containers []db.Container,
+	registryCreds []blueprint.RegistryCredential) map[string]struct{} {
+
+	names := map[string]struct{}{}
+	for _, dbc := range containers {
+		for _, name := range dbc.GetReferencedSecrets() {
+			names[name] = struct{}{}
+		}
+	}
+	for _, cred := range registryCreds {
+		names[cred.Secret] = struct{}{}
+	}
+	return names
+}
@@ -0,0 +1,164 @@
+package kubernetes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/minion/kubernetes/mocks"
+	"github.com/kelda/kelda/util"
+)
+
+func TestVaultProvider(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Vault-Token") != "token" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			switch r.URL.Path {
+			case "/v1/kv/data/kelda/key":
+				w.Write([]byte(`{"data": {"data": {"value": "val"}, ` +
+					`"metadata": {"version": 1}}}`))
+			case "/v1/kv/data/kelda/malformed":
+				w.Write([]byte(`{"data": {"data": {"other": "val"}}}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+	defer server.Close()
+
+	provider := vaultProvider{address: server.URL + "/", mount: "kv",
+		prefix: "kelda", token: "token", client: server.Client()}
+	val, err := provider.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, "val", val)
+
+	_, err = provider.Get("missing")
+	assert.EqualError(t, err, "secret missing does not exist in vault")
+
+	_, err = provider.Get("malformed")
+	assert.EqualError(t, err, "malformed secret: missing key value")
+
+	provider.token = "wrong"
+	_, err = provider.Get("key")
+	assert.EqualError(t, err, "query vault: unexpected status 403 Forbidden")
+}
+
+func TestFileProvider(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+	util.WriteFile("/secrets/key", []byte("val"), 0600)
+
+	provider := fileProvider{"/secrets"}
+	val, err := provider.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, "val", val)
+
+	_, err = provider.Get("missing")
+	assert.Error(t, err)
+
+	_, err = provider.Get("../secrets/key")
+	assert.EqualError(t, err, `invalid secret name: "../secrets/key"`)
+}
+
+func TestNewSecretProvider(t *testing.T) {
+	t.Parallel()
+
+	secretClient := &mocks.SecretClient{}
+	secretClient.On("Get", "vaultToken").Return("token", nil)
+	secretClient.On("Get", "missing").Return("", assert.AnError)
+
+	provider, err := newSecretProvider(nil, secretClient)
+	assert.NoError(t, err)
+	assert.Equal(t, secretClient, provider)
+
+	provider, err = newSecretProvider(&blueprint.SecretBackend{
+		Type: blueprint.KubernetesSecretBackend}, secretClient)
+	assert.NoError(t, err)
+	assert.Equal(t, secretClient, provider)
+
+	provider, err = newSecretProvider(&blueprint.SecretBackend{
+		Type:        blueprint.VaultSecretBackend,
+		Address:     "https://vault:8200",
+		TokenSecret: "vaultToken",
+	}, secretClient)
+	assert.NoError(t, err)
+	assert.Equal(t, vaultProvider{address: "https://vault:8200",
+		mount: "secret", token: "token", client: vaultHTTPClient}, provider)
+
+	_, err = newSecretProvider(&blueprint.SecretBackend{
+		Type:        blueprint.VaultSecretBackend,
+		Address:     "https://vault:8200",
+		TokenSecret: "missing",
+	}, secretClient)
+	assert.EqualError(t, err, `get vault token from secret "missing": `+
+		assert.AnError.Error())
+
+	_, err = newSecretProvider(&blueprint.SecretBackend{
+		Type: blueprint.VaultSecretBackend, TokenSecret: "vaultToken",
+	}, secretClient)
+	assert.EqualError(t, err, "vault secret backends require an address")
+
+	provider, err = newSecretProvider(&blueprint.SecretBackend{
+		Type: blueprint.FileSecretBackend, Path: "/secrets"}, secretClient)
+	assert.NoError(t, err)
+	assert.Equal(t, fileProvider{"/secrets"}, provider)
+
+	_, err = newSecretProvider(&blueprint.SecretBackend{Type: "aws"},
+		secretClient)
+	assert.EqualError(t, err, "unknown secret backend type: aws")
+}
+
+func TestSyncSecrets(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+	util.WriteFile("/secrets/unchanged", []byte("same"), 0600)
+	util.WriteFile("/secrets/changed", []byte("new"), 0600)
+	util.WriteFile("/secrets/unreferenced", []byte("val"), 0600)
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		bp := view.InsertBlueprint()
+		bp.SecretBackend = &blueprint.SecretBackend{
+			Type: blueprint.FileSecretBackend, Path: "/secrets"}
+		view.Commit(bp)
+
+		dbc := view.InsertContainer()
+		dbc.Env = map[string]blueprint.ContainerValue{
+			"A": blueprint.NewSecret("unchanged"),
+			"B": blueprint.NewSecret("changed"),
+			"C": blueprint.NewSecret("missing"),
+		}
+		view.Commit(dbc)
+		return nil
+	})
+
+	secretClient := &mocks.SecretClient{}
+	secretClient.On("Get", "unchanged").Return("same", nil)
+	secretClient.On("Get", "changed").Return("old", nil)
+	secretClient.On("Set", "changed", "new", []string(nil)).Return(nil).Once()
+
+	syncSecrets(conn, secretClient)
+	secretClient.AssertExpectations(t)
+	secretClient.AssertNotCalled(t, "Set", "unchanged", "same", mock.Anything)
+	secretClient.AssertNotCalled(t, "Get", "missing")
+
+	// Nothing should be synced when the Kubernetes backend is used.
+	conn.Txn(db.BlueprintTable).Run(func(view db.Database) error {
+		bp, _ := view.GetBlueprint()
+		bp.SecretBackend = nil
+		view.Commit(bp)
+		return nil
+	})
+	secretClient = &mocks.SecretClient{}
+	syncSecrets(conn, secretClient)
+	secretClient.AssertExpectations(t)
+}
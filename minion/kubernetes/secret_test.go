@@ -3,7 +3,10 @@ package kubernetes
 import (
 	"errors"
 	"testing"
+	"time"
 
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/minion/kubernetes/mocks"
 
 	"github.com/stretchr/testify/assert"
@@ -13,7 +16,10 @@ import (
 )
 
 func TestSecretSet(t *testing.T) {
-	t.Parallel()
+	now = func() time.Time {
+		return time.Date(2017, 10, 1, 12, 0, 0, 0, time.UTC)
+	}
+	defer func() { now = time.Now }()
 
 	kubeClient := &mocks.SecretInterface{}
 	secretClient := secretClientImpl{kubeClient}
@@ -23,7 +29,12 @@ func TestSecretSet(t *testing.T) {
 	kubeSecretName, _ := secretRef(secretName)
 	kubeSecret := corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: kubeSecretName,
+			Name:   kubeSecretName,
+			Labels: map[string]string{"kelda.io/secret": "true"},
+			Annotations: map[string]string{
+				"kelda.io/secret.name":         secretName,
+				"kelda.io/secret.lastModified": "2017-10-01T12:00:00Z",
+			},
 		},
 		Data: map[string][]byte{
 			"value": []byte(secretVal),
@@ -34,7 +45,7 @@ func TestSecretSet(t *testing.T) {
 	kubeClient.On("Get", kubeSecretName, mock.Anything).
 		Return(nil, errors.New("does not exist")).Once()
 	kubeClient.On("Create", &kubeSecret).Return(nil, nil).Once()
-	err := secretClient.Set(secretName, secretVal)
+	err := secretClient.Set(secretName, secretVal, nil)
 	assert.NoError(t, err)
 	kubeClient.AssertExpectations(t)
 
@@ -46,11 +57,139 @@ func TestSecretSet(t *testing.T) {
 	kubeClient.On("Get", kubeSecretName, mock.Anything).
 		Return(&kubeSecret, nil).Once()
 	kubeClient.On("Update", &changedKubeSecret).Return(nil, nil).Once()
-	err = secretClient.Set(secretName, secretVal)
+	err = secretClient.Set(secretName, secretVal, nil)
 	assert.NoError(t, err)
 	kubeClient.AssertExpectations(t)
 }
 
+func TestSecretSetAllowedHostnames(t *testing.T) {
+	t.Parallel()
+
+	kubeClient := &mocks.SecretInterface{}
+	secretClient := secretClientImpl{kubeClient}
+	kubeSecretName, _ := secretRef("secretName")
+
+	allowedHostnames := func(secret *corev1.Secret) string {
+		return secret.Annotations["kelda.io/secret.allowedHostnames"]
+	}
+	hasAllowedHostnames := func(exp string) interface{} {
+		return mock.MatchedBy(func(secret *corev1.Secret) bool {
+			return allowedHostnames(secret) == exp
+		})
+	}
+
+	// New secrets are scoped to the given hostnames.
+	kubeClient.On("Get", kubeSecretName, mock.Anything).
+		Return(nil, errors.New("does not exist")).Once()
+	kubeClient.On("Create", hasAllowedHostnames("web,proxy")).
+		Return(nil, nil).Once()
+	assert.NoError(t, secretClient.Set("secretName", "val",
+		[]string{"web", "proxy"}))
+	kubeClient.AssertExpectations(t)
+
+	// The scope of existing secrets is preserved if no hostnames are given.
+	scopedSecret := corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name: kubeSecretName,
+		Annotations: map[string]string{
+			"kelda.io/secret.allowedHostnames": "web,proxy",
+		},
+	}}
+	kubeClient.On("Get", kubeSecretName, mock.Anything).
+		Return(&scopedSecret, nil).Once()
+	kubeClient.On("Update", hasAllowedHostnames("web,proxy")).
+		Return(nil, nil).Once()
+	assert.NoError(t, secretClient.Set("secretName", "changed", nil))
+	kubeClient.AssertExpectations(t)
+
+	// "*" allows any container to use the secret.
+	kubeClient.On("Get", kubeSecretName, mock.Anything).
+		Return(&scopedSecret, nil).Once()
+	kubeClient.On("Update", mock.MatchedBy(func(secret *corev1.Secret) bool {
+		_, ok := secret.Annotations["kelda.io/secret.allowedHostnames"]
+		return !ok
+	})).Return(nil, nil).Once()
+	assert.NoError(t, secretClient.Set("secretName", "changed",
+		[]string{"*"}))
+	kubeClient.AssertExpectations(t)
+}
+
+func TestSecretGetAllowedHostnames(t *testing.T) {
+	t.Parallel()
+
+	kubeClient := &mocks.SecretInterface{}
+	secretClient := secretClientImpl{kubeClient}
+	kubeSecretName, _ := secretRef("secretName")
+
+	kubeClient.On("Get", kubeSecretName, mock.Anything).
+		Return(nil, errors.New("does not exist")).Once()
+	_, err := secretClient.GetAllowedHostnames("secretName")
+	assert.EqualError(t, err, "query secret: does not exist")
+
+	kubeClient.On("Get", kubeSecretName, mock.Anything).
+		Return(&corev1.Secret{}, nil).Once()
+	allowed, err := secretClient.GetAllowedHostnames("secretName")
+	assert.NoError(t, err)
+	assert.Nil(t, allowed)
+
+	kubeClient.On("Get", kubeSecretName, mock.Anything).
+		Return(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{
+				"kelda.io/secret.allowedHostnames": "web,proxy",
+			},
+		}}, nil).Once()
+	allowed, err = secretClient.GetAllowedHostnames("secretName")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web", "proxy"}, allowed)
+}
+
+func TestUnauthorizedSecrets(t *testing.T) {
+	t.Parallel()
+
+	secretClient := &mocks.SecretClient{}
+	secretClient.On("GetAllowedHostnames", "unscoped").Return(nil, nil)
+	secretClient.On("GetAllowedHostnames", "allowed").Return(
+		[]string{"web"}, nil)
+	secretClient.On("GetAllowedHostnames", "denied").Return(
+		[]string{"proxy"}, nil)
+	secretClient.On("GetAllowedHostnames", "unreadable").Return(
+		nil, assert.AnError)
+
+	dbc := db.Container{
+		Hostname: "web",
+		Env: map[string]blueprint.ContainerValue{
+			"A": blueprint.NewSecret("unscoped"),
+			"B": blueprint.NewSecret("allowed"),
+			"C": blueprint.NewSecret("denied"),
+			"D": blueprint.NewSecret("unreadable"),
+		},
+		FilepathToContent: map[string]blueprint.ContainerValue{
+			"/denied": blueprint.NewSecret("denied"),
+		},
+	}
+	assert.Equal(t, []string{"denied", "unreadable"},
+		unauthorizedSecrets(secretClient, dbc))
+
+	// Members of stateful and replica sets may use the secrets that their
+	// set is allowed to use.
+	secretClient.On("GetAllowedHostnames", "set").Return(
+		[]string{"db", "api"}, nil)
+	setEnv := map[string]blueprint.ContainerValue{
+		"A": blueprint.NewSecret("set"),
+		"B": blueprint.NewSecret("denied"),
+	}
+	for _, dbc := range []db.Container{
+		{Hostname: "db-0", StatefulSet: "db", Env: setEnv},
+		{Hostname: "api-1", ReplicaSet: "api", Env: setEnv},
+	} {
+		assert.Equal(t, []string{"denied"},
+			unauthorizedSecrets(secretClient, dbc))
+	}
+
+	dbc = db.Container{Hostname: "cache-0", StatefulSet: "cache", Env: setEnv}
+	assert.Equal(t, []string{"denied", "set"},
+		unauthorizedSecrets(secretClient, dbc))
+}
+
 func TestSecretGet(t *testing.T) {
 	t.Parallel()
 
@@ -131,6 +270,113 @@ func TestSecretExists(t *testing.T) {
 	kubeClient.AssertExpectations(t)
 }
 
+func TestSecretList(t *testing.T) {
+	t.Parallel()
+
+	kubeClient := &mocks.SecretInterface{}
+	secretClient := secretClientImpl{kubeClient}
+
+	kubeClient.On("List", metav1.ListOptions{
+		LabelSelector: "kelda.io/secret=true",
+	}).Return(&corev1.SecretList{Items: []corev1.Secret{
+		{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+			"kelda.io/secret.name":         "a",
+			"kelda.io/secret.lastModified": "2017-10-01T12:00:00Z",
+		}}},
+		// Secrets with a malformed timestamp are still listed.
+		{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+			"kelda.io/secret.name": "b",
+		}}},
+		// Secrets without a name are ignored.
+		{ObjectMeta: metav1.ObjectMeta{Name: "kelda-123"}},
+	}}, nil).Once()
+
+	secrets, err := secretClient.List()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]time.Time{
+		"a": time.Date(2017, 10, 1, 12, 0, 0, 0, time.UTC),
+		"b": {},
+	}, secrets)
+
+	kubeClient.On("List", mock.Anything).Return(nil, assert.AnError).Once()
+	_, err = secretClient.List()
+	assert.EqualError(t, err, "list secrets: "+assert.AnError.Error())
+}
+
+func TestLabelLegacySecrets(t *testing.T) {
+	t.Parallel()
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		bp := view.InsertBlueprint()
+		bp.RegistryCredentials = []blueprint.RegistryCredential{
+			{Server: "registry", Secret: "labeled"}}
+		view.Commit(bp)
+
+		dbc := view.InsertContainer()
+		dbc.Env = map[string]blueprint.ContainerValue{
+			"A": blueprint.NewSecret("legacy"),
+			"B": blueprint.NewSecret("missing"),
+		}
+		view.Commit(dbc)
+		return nil
+	})
+
+	created := time.Date(2017, 10, 1, 12, 0, 0, 0, time.UTC)
+	legacyName, _ := secretRef("legacy")
+	labeledName, _ := secretRef("labeled")
+	missingName, _ := secretRef("missing")
+
+	kubeClient := &mocks.SecretInterface{}
+	kubeClient.On("Get", legacyName, mock.Anything).Return(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              legacyName,
+			CreationTimestamp: metav1.NewTime(created),
+		},
+	}, nil)
+	kubeClient.On("Get", labeledName, mock.Anything).Return(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   labeledName,
+			Labels: map[string]string{"kelda.io/secret": "true"},
+			Annotations: map[string]string{
+				"kelda.io/secret.name": "labeled",
+			},
+		},
+	}, nil)
+	kubeClient.On("Get", missingName, mock.Anything).Return(
+		nil, errors.New("does not exist"))
+	kubeClient.On("Update", &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              legacyName,
+			CreationTimestamp: metav1.NewTime(created),
+			Labels:            map[string]string{"kelda.io/secret": "true"},
+			Annotations: map[string]string{
+				"kelda.io/secret.name":         "legacy",
+				"kelda.io/secret.lastModified": "2017-10-01T12:00:00Z",
+			},
+		},
+	}).Return(nil, nil).Once()
+
+	labelLegacySecrets(conn, kubeClient)
+	kubeClient.AssertExpectations(t)
+}
+
+func TestSecretDelete(t *testing.T) {
+	t.Parallel()
+
+	kubeClient := &mocks.SecretInterface{}
+	secretClient := secretClientImpl{kubeClient}
+
+	kubeSecretName, _ := secretRef("secretName")
+	kubeClient.On("Delete", kubeSecretName, mock.Anything).Return(nil).Once()
+	assert.NoError(t, secretClient.Delete("secretName"))
+
+	kubeClient.On("Delete", kubeSecretName, mock.Anything).Return(
+		assert.AnError).Once()
+	assert.EqualError(t, secretClient.Delete("secretName"),
+		"delete secret: "+assert.AnError.Error())
+}
+
 func copySecret(src corev1.Secret) (copy corev1.Secret) {
 	dataCopy := map[string][]byte{}
 	for k, v := range src.Data {
@@ -0,0 +1,145 @@
+package kubernetes
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/db"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	securityHashKey = "security-hash"
+
+	appArmorAnnotationKeyPrefix = "container.apparmor.security.beta.kubernetes.io/"
+)
+
+// makeSecurityContext returns the security context of the given member of a
+// pod.
+func makeSecurityContext(member blueprint.Container) *corev1.SecurityContext {
+	privileged := member.Privileged
+	context := &corev1.SecurityContext{
+		Privileged: &privileged,
+	}
+
+	security := member.Security
+	if security == nil {
+		return context
+	}
+
+	context.RunAsUser = security.RunAsUser
+	if security.ReadOnlyRootFilesystem {
+		readOnly := true
+		context.ReadOnlyRootFilesystem = &readOnly
+	}
+
+	if len(security.CapAdd) != 0 || len(security.CapDrop) != 0 {
+		context.Capabilities = &corev1.Capabilities{
+			Add:  toCapabilities(security.CapAdd),
+			Drop: toCapabilities(security.CapDrop),
+		}
+	}
+	return context
+}
+
+func toCapabilities(names []string) []corev1.Capability {
+	var capabilities []corev1.Capability
+	for _, name := range names {
+		capabilities = append(capabilities, corev1.Capability(name))
+	}
+	return capabilities
+}
+
+// makePodSecurityContext returns the pod-level security context for the given
+// container, or nil if it doesn't have any pod-level settings.
+func makePodSecurityContext(dbc db.Container) *corev1.PodSecurityContext {
+	if dbc.Security == nil || dbc.Security.RunAsGroup == nil {
+		return nil
+	}
+
+	group := *dbc.Security.RunAsGroup
+	return &corev1.PodSecurityContext{
+		SupplementalGroups: []int64{group},
+		FSGroup:            &group,
+	}
+}
+
+// securityAnnotations returns the annotations that configure the security
+// settings that are set through annotations in the version of Kubernetes run
+// by Kelda.
+func securityAnnotations(dbc db.Container) map[string]string {
+	annotations := map[string]string{}
+	for _, member := range dbc.PodContainers() {
+		if member.Security == nil {
+			continue
+		}
+
+		if profile := member.Security.SeccompProfile; profile != "" {
+			if profile == "runtime/default" {
+				profile = "docker/default"
+			}
+			key := corev1.SeccompContainerAnnotationKeyPrefix +
+				member.Hostname
+			annotations[key] = securityProfile(profile)
+		}
+
+		if profile := member.Security.AppArmorProfile; profile != "" {
+			key := appArmorAnnotationKeyPrefix + member.Hostname
+			annotations[key] = securityProfile(profile)
+		}
+	}
+
+	if dbc.Security != nil && len(dbc.Security.Sysctls) != 0 {
+		var safe, unsafe []string
+		for name, val := range dbc.Security.Sysctls {
+			sysctl := fmt.Sprintf("%s=%s", name, val)
+			if blueprint.IsSafeSysctl(name) {
+				safe = append(safe, sysctl)
+			} else {
+				unsafe = append(unsafe, sysctl)
+			}
+		}
+
+		// Sort the sysctls so that the annotations are consistent.
+		// Otherwise, Kubernetes would restart the pod.
+		sort.Strings(safe)
+		sort.Strings(unsafe)
+		if len(safe) != 0 {
+			annotations[corev1.SysctlsPodAnnotationKey] =
+				strings.Join(safe, ",")
+		}
+		if len(unsafe) != 0 {
+			annotations[corev1.UnsafeSysctlsPodAnnotationKey] =
+				strings.Join(unsafe, ",")
+		}
+	}
+	return annotations
+}
+
+// securityProfile converts the given seccomp or AppArmor profile into the
+// format expected by Kubernetes.
+func securityProfile(profile string) string {
+	if strings.Contains(profile, "/") || profile == "unconfined" {
+		return profile
+	}
+	return "localhost/" + profile
+}
+
+// hashSecurity returns a hash of the security settings of the containers in
+// the given container's pod, or the empty string if none of them have any.
+func hashSecurity(dbc db.Container) string {
+	var settings []*blueprint.Security
+	var hasSettings bool
+	for _, member := range dbc.PodContainers() {
+		settings = append(settings, member.Security)
+		hasSettings = hasSettings || member.Security != nil
+	}
+
+	if !hasSettings {
+		return ""
+	}
+	return hashJSON(settings)
+}
@@ -0,0 +1,103 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/db"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestMakeSecurityContext(t *testing.T) {
+	t.Parallel()
+
+	notPrivileged := false
+	assert.Equal(t, &corev1.SecurityContext{Privileged: &notPrivileged},
+		makeSecurityContext(blueprint.Container{}))
+
+	user := int64(1000)
+	readOnly := true
+	assert.Equal(t, &corev1.SecurityContext{
+		Privileged:             &notPrivileged,
+		RunAsUser:              &user,
+		ReadOnlyRootFilesystem: &readOnly,
+		Capabilities: &corev1.Capabilities{
+			Add:  []corev1.Capability{"NET_ADMIN"},
+			Drop: []corev1.Capability{"ALL"},
+		},
+	}, makeSecurityContext(blueprint.Container{
+		Security: &blueprint.Security{
+			RunAsUser:              &user,
+			ReadOnlyRootFilesystem: true,
+			CapAdd:                 []string{"NET_ADMIN"},
+			CapDrop:                []string{"ALL"},
+		},
+	}))
+}
+
+func TestMakePodSecurityContext(t *testing.T) {
+	t.Parallel()
+
+	assert.Nil(t, makePodSecurityContext(db.Container{}))
+	assert.Nil(t, makePodSecurityContext(db.Container{
+		Security: &blueprint.Security{ReadOnlyRootFilesystem: true},
+	}))
+
+	group := int64(2000)
+	assert.Equal(t, &corev1.PodSecurityContext{
+		SupplementalGroups: []int64{2000},
+		FSGroup:            &group,
+	}, makePodSecurityContext(db.Container{
+		Security: &blueprint.Security{RunAsGroup: &group},
+	}))
+}
+
+func TestSecurityAnnotations(t *testing.T) {
+	t.Parallel()
+
+	dbc := db.Container{
+		Hostname: "web",
+		Security: &blueprint.Security{
+			SeccompProfile:  "runtime/default",
+			AppArmorProfile: "web-profile",
+			Sysctls: map[string]string{
+				"net.ipv4.tcp_syncookies": "1",
+				"net.core.somaxconn":      "1024",
+				"kernel.msgmax":           "65536",
+			},
+		},
+		Sidecars: []blueprint.Container{
+			{Hostname: "proxy", Security: &blueprint.Security{
+				SeccompProfile: "unconfined",
+				// Sysctls are only read from the first container.
+				Sysctls: map[string]string{"net.ipv4.ip_forward": "1"},
+			}},
+			{Hostname: "logger"},
+		},
+	}
+	assert.Equal(t, map[string]string{
+		"container.seccomp.security.alpha.kubernetes.io/web": "docker/default",
+		"container.apparmor.security.beta.kubernetes.io/web": "localhost/" +
+			"web-profile",
+		"container.seccomp.security.alpha.kubernetes.io/proxy": "unconfined",
+		"security.alpha.kubernetes.io/sysctls": "net.ipv4." +
+			"tcp_syncookies=1",
+		"security.alpha.kubernetes.io/unsafe-sysctls": "kernel.msgmax=65536," +
+			"net.core.somaxconn=1024",
+	}, securityAnnotations(dbc))
+
+	// The annotations should be added to the pod, along with the hash used
+	// to join it with the container.
+	annotations := podAnnotations(dbc)
+	assert.Equal(t, hashSecurity(dbc), annotations[securityHashKey])
+	assert.Equal(t, "docker/default",
+		annotations["container.seccomp.security.alpha.kubernetes.io/web"])
+
+	// Pods without security settings shouldn't be annotated, so that they
+	// aren't restarted.
+	assert.Empty(t, hashSecurity(db.Container{Hostname: "web"}))
+	assert.NotContains(t, podAnnotations(db.Container{Hostname: "web"}),
+		securityHashKey)
+}
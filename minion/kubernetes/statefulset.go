@@ -0,0 +1,128 @@
+package kubernetes
+
+import (
+	"path"
+	"sort"
+	"strconv"
+
+	"github.com/kelda/kelda/db"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Each member of a stateful container is deployed as its own deployment, so
+// that it keeps its IP address, is pinned to its worker, and mounts its own
+// storage. This file contains the logic that differs from other deployments.
+
+// pinStatefulSetMember requires the pod of the given stateful member to run on
+// the worker that the member was assigned to by the leader's policy engine.
+func pinStatefulSetMember(idToAffinity map[string]*corev1.Affinity,
+	dbc db.Container) {
+
+	affinity, ok := idToAffinity[dbc.Hostname]
+	if !ok {
+		affinity = &corev1.Affinity{}
+		idToAffinity[dbc.Hostname] = affinity
+	}
+	handleNodeAffinity(affinity, privateIPKey, dbc.Minion, false)
+}
+
+// memberVolume returns the volume that the stateful member with the given
+// hostname should mount in place of the given volume. Each member mounts its
+// own subdirectory of hostPath volumes, which is created if it doesn't exist.
+// Because members are pinned to their workers, the subdirectory survives
+// restarts of the member. Stateful containers can only mount hostPath volumes,
+// which is checked when the blueprint is deployed.
+func memberVolume(volume corev1.Volume, hostname string) corev1.Volume {
+	if volume.HostPath == nil {
+		return volume
+	}
+
+	hostPathType := corev1.HostPathDirectoryOrCreate
+	volume.HostPath = &corev1.HostPathVolumeSource{
+		Path: path.Join(volume.HostPath.Path, hostname),
+		Type: &hostPathType,
+	}
+	return volume
+}
+
+// heldStatefulSetMembers returns the names of the deployments of stateful
+// members that shouldn't be created or updated yet. Members are created in
+// order, each once all lower members are ready. Updates are rolled out in
+// reverse order, each once all higher members are updated and ready, so that
+// only one member restarts at a time.
+func heldStatefulSetMembers(desired, current []appsv1.Deployment) map[string]struct{} {
+	currentMap := map[string]appsv1.Deployment{}
+	for _, deployment := range current {
+		currentMap[deployment.Name] = deployment
+	}
+
+	sets := map[string][]appsv1.Deployment{}
+	for _, deployment := range desired {
+		if set := deployment.Annotations[statefulSetKey]; set != "" {
+			sets[set] = append(sets[set], deployment)
+		}
+	}
+
+	held := map[string]struct{}{}
+	for _, members := range sets {
+		sort.Slice(members, func(i, j int) bool {
+			return memberOrdinal(members[i]) < memberOrdinal(members[j])
+		})
+
+		for i, member := range members {
+			curr, exists := currentMap[member.Name]
+			switch {
+			case !exists:
+				if !membersReady(members[:i], currentMap, false) {
+					held[member.Name] = struct{}{}
+				}
+			case curr.Annotations[templateHashKey] !=
+				member.Annotations[templateHashKey]:
+				// Higher members that haven't been created yet don't
+				// block the update, since they'll be created with the new
+				// spec.
+				if !membersReady(members[i+1:], currentMap, true) {
+					held[member.Name] = struct{}{}
+				}
+			}
+		}
+	}
+	return held
+}
+
+// membersReady returns whether the given members are running their desired
+// spec, and are ready. If skipMissing is set, members that haven't been
+// created are ignored.
+func membersReady(members []appsv1.Deployment,
+	currentMap map[string]appsv1.Deployment, skipMissing bool) bool {
+
+	for _, member := range members {
+		curr, ok := currentMap[member.Name]
+		if !ok && skipMissing {
+			continue
+		}
+
+		if !ok || !deploymentReady(curr) || curr.Annotations[templateHashKey] !=
+			member.Annotations[templateHashKey] {
+			return false
+		}
+	}
+	return true
+}
+
+// memberOrdinal returns the ordinal of the stateful member deployed by the
+// given deployment.
+func memberOrdinal(deployment appsv1.Deployment) int {
+	ordinal, _ := strconv.Atoi(deployment.Annotations[ordinalKey])
+	return ordinal
+}
+
+// deploymentReady returns whether the latest spec of the given deployment has
+// been rolled out, and its pod is ready.
+func deploymentReady(deployment appsv1.Deployment) bool {
+	return deployment.Status.ObservedGeneration >= deployment.Generation &&
+		deployment.Status.UpdatedReplicas >= 1 &&
+		deployment.Status.ReadyReplicas >= 1
+}
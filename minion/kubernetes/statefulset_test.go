@@ -0,0 +1,159 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/db"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMakeDesiredDeploymentsStatefulSet(t *testing.T) {
+	t.Parallel()
+	conn := db.New()
+
+	conn.Txn(db.ContainerTable, db.BlueprintTable,
+		db.PlacementTable).Run(func(view db.Database) error {
+		mount := []blueprint.VolumeMount{
+			{VolumeName: "data", MountPath: "/data"},
+		}
+		for _, dbc := range []db.Container{
+			{Hostname: "zk-0", StatefulSet: "zk", Minion: "10.0.0.2",
+				Image: "zookeeper", IP: "ip1", VolumeMounts: mount},
+			{Hostname: "zk-1", StatefulSet: "zk", Image: "zookeeper",
+				IP: "ip2", VolumeMounts: mount},
+		} {
+			dbc.ID = view.InsertContainer().ID
+			view.Commit(dbc)
+		}
+
+		plcm := view.InsertPlacement()
+		plcm.TargetContainer = "zk-0"
+		plcm.OtherContainer = "zk"
+		plcm.Exclusive = true
+		view.Commit(plcm)
+
+		bp := view.InsertBlueprint()
+		bp.Volumes = []blueprint.Volume{{
+			Name: "data",
+			Type: "hostPath",
+			Conf: map[string]string{"path": "/var/lib/zk"},
+		}}
+		view.Commit(bp)
+		return nil
+	})
+
+	// The member without a worker shouldn't be deployed.
+	deployments, err := makeDesiredDeployments(conn, nil)
+	assert.NoError(t, err)
+	assert.Len(t, deployments, 1)
+	deployment := deployments[0]
+	assert.Equal(t, "zk-0", deployment.Name)
+	assert.Equal(t, "zk", deployment.Annotations[statefulSetKey])
+	assert.Equal(t, "0", deployment.Annotations[ordinalKey])
+	assert.Equal(t, hashJSON(deployment.Spec.Template),
+		deployment.Annotations[templateHashKey])
+
+	assert.Equal(t, map[string]string{hostnameKey: "zk-0",
+		containerSetKey: "zk"}, deployment.Spec.Template.Labels)
+
+	// The member should be pinned to its worker.
+	pod := deployment.Spec.Template.Spec
+	assert.Equal(t, "zk-0", pod.Hostname)
+	assert.Equal(t, []corev1.NodeSelectorRequirement{{
+		Key:      privateIPKey,
+		Operator: corev1.NodeSelectorOpIn,
+		Values:   []string{"10.0.0.2"},
+	}}, pod.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.
+		NodeSelectorTerms[0].MatchExpressions)
+
+	// Its pod placement rules should be kept, and rules relative to the set
+	// should select all of its members.
+	assert.Equal(t, []corev1.PodAffinityTerm{{
+		LabelSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{containerSetKey: "zk"},
+		},
+		TopologyKey: "kubernetes.io/hostname",
+	}}, pod.Affinity.PodAntiAffinity.
+		RequiredDuringSchedulingIgnoredDuringExecution)
+
+	// The member should mount its own subdirectory of the volume.
+	hostPathType := corev1.HostPathDirectoryOrCreate
+	assert.Equal(t, []corev1.Volume{{
+		Name: "data",
+		VolumeSource: corev1.VolumeSource{
+			HostPath: &corev1.HostPathVolumeSource{
+				Path: "/var/lib/zk/zk-0",
+				Type: &hostPathType,
+			},
+		},
+	}}, pod.Volumes)
+}
+
+func TestHeldStatefulSetMembers(t *testing.T) {
+	t.Parallel()
+
+	member := func(ordinal, hash string) appsv1.Deployment {
+		return appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "zk-" + ordinal,
+				Annotations: map[string]string{
+					statefulSetKey:  "zk",
+					ordinalKey:      ordinal,
+					templateHashKey: hash,
+				},
+			},
+		}
+	}
+	ready := func(deployment appsv1.Deployment) appsv1.Deployment {
+		deployment.Status = appsv1.DeploymentStatus{
+			UpdatedReplicas: 1,
+			ReadyReplicas:   1,
+		}
+		return deployment
+	}
+	other := appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web"}}
+
+	// Members should be created in order, once the lower members are ready.
+	desired := []appsv1.Deployment{
+		member("2", "a"), member("0", "a"), member("1", "a"), other}
+	assert.Equal(t, map[string]struct{}{"zk-1": {}, "zk-2": {}},
+		heldStatefulSetMembers(desired, nil))
+	assert.Equal(t, map[string]struct{}{"zk-1": {}, "zk-2": {}},
+		heldStatefulSetMembers(desired,
+			[]appsv1.Deployment{member("0", "a")}))
+	assert.Equal(t, map[string]struct{}{"zk-2": {}},
+		heldStatefulSetMembers(desired,
+			[]appsv1.Deployment{ready(member("0", "a"))}))
+	assert.Empty(t, heldStatefulSetMembers(desired, []appsv1.Deployment{
+		ready(member("0", "a")), ready(member("1", "a"))}))
+
+	// Updates should be rolled out from the highest member down.
+	desired = []appsv1.Deployment{
+		member("0", "b"), member("1", "b"), member("2", "b")}
+	current := []appsv1.Deployment{
+		ready(member("0", "a")), ready(member("1", "a")),
+		ready(member("2", "a"))}
+	assert.Equal(t, map[string]struct{}{"zk-0": {}, "zk-1": {}},
+		heldStatefulSetMembers(desired, current))
+
+	// The next member shouldn't be updated until the updated member is
+	// ready.
+	current[2] = member("2", "b")
+	assert.Equal(t, map[string]struct{}{"zk-0": {}, "zk-1": {}},
+		heldStatefulSetMembers(desired, current))
+
+	current[2] = ready(member("2", "b"))
+	assert.Equal(t, map[string]struct{}{"zk-0": {}},
+		heldStatefulSetMembers(desired, current))
+
+	// Members that haven't been created don't block updates, but aren't
+	// created until the lower members are updated.
+	assert.Equal(t, map[string]struct{}{"zk-1": {}, "zk-2": {}},
+		heldStatefulSetMembers(desired,
+			[]appsv1.Deployment{ready(member("0", "a"))}))
+}
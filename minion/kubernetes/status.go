@@ -3,6 +3,7 @@ package kubernetes
 import (
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/kelda/kelda/db"
@@ -33,6 +34,9 @@ func updateContainerStatuses(conn db.Conn, podsClient clientv1.PodInterface,
 		return
 	}
 
+	annotateDaemonSetPods(podsClient, conn.SelectFromContainer(nil),
+		pods.Items)
+
 	conn.Txn(db.ImageTable, db.ContainerTable).Run(func(view db.Database) error {
 		pairs, noInfoContainers := joinContainersToPods(
 			view.SelectFromContainer(nil), pods.Items)
@@ -41,25 +45,24 @@ func updateContainerStatuses(conn db.Conn, podsClient clientv1.PodInterface,
 			pod := pair.R.(corev1.Pod)
 
 			dbc.Status, dbc.Created = statusForPod(pod)
+			dbc.ImageDigest = imageDigestForPod(pod)
 			dbc.PodName = pod.GetName()
 			dbc.Minion = pod.Status.HostIP
 			view.Commit(dbc)
 		}
 
-		imageMap := map[db.Image]db.Image{}
+		imageMap := map[imageRef]db.Image{}
 		for _, img := range view.SelectFromImage(nil) {
-			imageMap[db.Image{
-				Name:       img.Name,
-				Dockerfile: img.Dockerfile,
-			}] = img
+			imageMap[imageRef{img.Name, img.Dockerfile}] = img
 		}
 
 		for _, intf := range noInfoContainers {
 			dbc := intf.(db.Container)
 			dbc.Status = statusForContainer(imageMap, secretClient, dbc)
-			// Unset the Created field in case it was set when the container
-			// was running in the past.
+			// Unset the Created and ImageDigest fields in case they were set
+			// when the container was running in the past.
 			dbc.Created = time.Time{}
+			dbc.ImageDigest = ""
 			view.Commit(dbc)
 		}
 		return nil
@@ -76,11 +79,18 @@ func joinContainersToPodsImpl(dbcs []db.Container, pods []corev1.Pod) (
 		EnvHash               string
 		FilepathToContentHash string
 		DockerfileHash        string
+		SidecarsHash          string
+		InitHash              string
+		SecurityHash          string
 		Privileged            bool
+
+		// The IP of the machine that the instance of a daemon container
+		// runs on, or empty for other containers.
+		Minion string
 	}
 	dbcKey := func(intf interface{}) interface{} {
 		dbc := intf.(db.Container)
-		return joinKey{
+		key := joinKey{
 			Hostname: dbc.Hostname,
 			Image:    dbc.Image,
 			Command:  fmt.Sprintf("%v", dbc.Command),
@@ -91,14 +101,27 @@ func joinContainersToPodsImpl(dbcs []db.Container, pods []corev1.Pod) (
 			FilepathToContentHash: hashContainerValueMap(
 				dbc.FilepathToContent),
 			DockerfileHash: hashStr(dbc.Dockerfile),
+			SidecarsHash:   hashSidecars(dbc.Sidecars),
+			InitHash:       hashInit(dbc),
+			SecurityHash:   hashSecurity(dbc),
 			Privileged:     dbc.Privileged,
 		}
+
+		// The pods of a daemon container are named after it, and run on the
+		// instances' machines.
+		if dbc.DaemonSet != "" {
+			key.Hostname = dbc.DaemonSet
+			key.Minion = dbc.Minion
+		}
+		return key
 	}
 	podKey := func(intf interface{}) interface{} {
 		pod := intf.(corev1.Pod)
-		if len(pod.Spec.Containers) != 1 {
+		// The first container in the pod is the Kelda container, and the
+		// rest are its sidecars.
+		if len(pod.Spec.Containers) == 0 {
 			log.WithField("pod", pod.Name).Error("Pods managed by Kelda " +
-				"should have exactly one container. Ignoring.")
+				"should have at least one container. Ignoring.")
 			return nil
 		}
 
@@ -106,7 +129,7 @@ func joinContainersToPodsImpl(dbcs []db.Container, pods []corev1.Pod) (
 		if pod.Spec.Containers[0].SecurityContext != nil {
 			privileged = *pod.Spec.Containers[0].SecurityContext.Privileged
 		}
-		return joinKey{
+		key := joinKey{
 			Hostname: pod.Spec.Hostname,
 			Command: fmt.Sprintf("%v",
 				pod.Spec.Containers[0].Args),
@@ -114,8 +137,15 @@ func joinContainersToPodsImpl(dbcs []db.Container, pods []corev1.Pod) (
 			EnvHash:               pod.Annotations[envHashKey],
 			FilepathToContentHash: pod.Annotations[filesHashKey],
 			DockerfileHash:        pod.Annotations[dockerfileHashKey],
+			SidecarsHash:          pod.Annotations[sidecarsHashKey],
+			InitHash:              pod.Annotations[initHashKey],
+			SecurityHash:          pod.Annotations[securityHashKey],
 			Privileged:            privileged,
 		}
+		if pod.Annotations[daemonSetKey] != "" {
+			key.Minion = pod.Status.HostIP
+		}
+		return key
 	}
 	pairs, noInfoContainers, _ = join.HashJoin(
 		db.ContainerSlice(dbcs), podSlice(pods), dbcKey, podKey)
@@ -123,8 +153,9 @@ func joinContainersToPodsImpl(dbcs []db.Container, pods []corev1.Pod) (
 }
 
 // statusForContainer attempts to return a helpful status for why a container
-// has not yet been scheduled by inspecting the image and secret statuses.
-func statusForContainerImpl(imageMap map[db.Image]db.Image, secretClient SecretClient,
+// has not yet been scheduled by inspecting the image and secret statuses, and
+// whether the container could be pinned to a worker.
+func statusForContainerImpl(imageMap map[imageRef]db.Image, secretClient SecretClient,
 	dbc db.Container) (status string) {
 	_, missing := makeSecretHashEnvVars(secretClient, dbc.GetReferencedSecrets())
 	if len(missing) != 0 {
@@ -132,13 +163,62 @@ func statusForContainerImpl(imageMap map[db.Image]db.Image, secretClient SecretC
 		return fmt.Sprintf("Waiting for secrets: %v", missing)
 	}
 
-	// Check for image information.
-	img, ok := imageMap[db.Image{
-		Name:       dbc.Image,
-		Dockerfile: dbc.Dockerfile,
-	}]
-	if ok {
-		return img.Status
+	unauthorized := unauthorizedSecrets(secretClient, dbc)
+	if len(unauthorized) != 0 {
+		return fmt.Sprintf("Not authorized to use secrets: %v", unauthorized)
+	}
+
+	// Check for image information. If the container's image is ready, but
+	// the image of another container in its pod isn't, the other image's
+	// status is shown instead.
+	for _, podContainer := range dbc.PodContainers() {
+		img, ok := imageMap[imageRef{podContainer.Image.Name,
+			podContainer.Image.Dockerfile}]
+		if !ok {
+			continue
+		}
+
+		if (img.Status == db.BuildFailed || img.Status == db.ResolveFailed) &&
+			img.BuildError != "" {
+			return fmt.Sprintf("%s: %s", img.Status, img.BuildError)
+		}
+
+		if status == "" || status == db.Built || status == db.Resolved {
+			status = img.Status
+		}
+	}
+
+	// Pods are only created for the members of stateful containers once
+	// they're pinned to a worker, so Kubernetes never reports the members
+	// that no worker can host.
+	if dbc.StatefulSet != "" && dbc.Minion == "" &&
+		(status == "" || status == db.Built || status == db.Resolved) {
+		return fmt.Sprintf("%s: no worker satisfies the placement rules "+
+			"of %s", db.Unschedulable, dbc.StatefulSet)
+	}
+	return status
+}
+
+// imageRef identifies the image used by a container.
+type imageRef struct {
+	name, dockerfile string
+}
+
+// imageDigestForPod returns the digest of the image that the pod's Kelda
+// container is running, or the empty string if it's unknown. The digests of the
+// sidecars' images aren't reported.
+func imageDigestForPod(pod corev1.Pod) string {
+	statuses := pod.Status.ContainerStatuses
+	for _, status := range statuses {
+		if len(statuses) != 1 && status.Name != pod.Spec.Hostname {
+			continue
+		}
+
+		// The image ID is of the form "docker-pullable://repo@sha256:...".
+		imageID := status.ImageID
+		if i := strings.LastIndex(imageID, "@"); i != -1 {
+			return imageID[i+1:]
+		}
 	}
 	return ""
 }
@@ -146,33 +226,101 @@ func statusForContainerImpl(imageMap map[db.Image]db.Image, secretClient SecretC
 // statusForPod parses the status information for the given pod into a single
 // string. If the status is running, it also returns when the pod was started.
 func statusForPodImpl(pod corev1.Pod) (status string, createdTime time.Time) {
-	// Try to get the status of the actual container.
-	if len(pod.Status.ContainerStatuses) == 1 {
-		status := pod.Status.ContainerStatuses[0]
-		switch {
-		case status.State.Running != nil:
-			return "running", status.State.Running.StartedAt.Time
-		case status.State.Waiting != nil:
-			return "waiting: " + status.State.Waiting.Reason, time.Time{}
-		case status.State.Terminated != nil:
-			return "terminated: " + status.State.Terminated.Reason,
+	// The containers aren't started until all of the init containers have
+	// succeeded, so the status of the first init container that hasn't is
+	// reported.
+	for _, initStatus := range pod.Status.InitContainerStatuses {
+		state := initStatus.State
+		if state.Terminated != nil && state.Terminated.ExitCode == 0 {
+			continue
+		}
+
+		if dep, ok := dependencyForContainer(pod, initStatus.Name); ok {
+			return "waiting for dependency: " + dep, time.Time{}
+		}
+
+		if state.Running != nil {
+			return fmt.Sprintf("initializing (%s)", initStatus.Name),
 				time.Time{}
-		default:
-			return "unrecognized container state", time.Time{}
 		}
+		return fmt.Sprintf("%s (%s)", statusForContainerState(state),
+			initStatus.Name), time.Time{}
 	}
 
-	// Check if the pod is scheduled.
+	// Try to get the status of the actual containers. If the pod has
+	// sidecars, it's only running once all of its containers are, and the
+	// status of the first container that isn't running is reported instead.
+	statuses := pod.Status.ContainerStatuses
+	if len(statuses) != 0 {
+		var started time.Time
+		for _, containerStatus := range statuses {
+			state := containerStatus.State
+			if state.Running != nil {
+				if state.Running.StartedAt.After(started) {
+					started = state.Running.StartedAt.Time
+				}
+				continue
+			}
+
+			status := statusForContainerState(state)
+			if len(statuses) != 1 {
+				status = fmt.Sprintf("%s (%s)", status,
+					containerStatus.Name)
+			}
+			return status, time.Time{}
+		}
+		return "running", started
+	}
+
+	// Check if the pod is scheduled. Pods that the scheduler failed to place
+	// are reported as unschedulable so that the daemon knows to add machines
+	// to a machine pool.
 	for _, status := range pod.Status.Conditions {
-		if status.Status == corev1.ConditionTrue &&
-			status.Type == corev1.PodScheduled {
+		if status.Type != corev1.PodScheduled {
+			continue
+		}
+
+		if status.Status == corev1.ConditionTrue {
 			return "scheduled", time.Time{}
 		}
+
+		if status.Reason == corev1.PodReasonUnschedulable {
+			return fmt.Sprintf("%s: %s", db.Unschedulable, status.Message),
+				time.Time{}
+		}
 	}
 
 	return "no status information", time.Time{}
 }
 
+// dependencyForContainer returns the address of the dependency that the init
+// container with the given name waits for, if it's a dependency container.
+func dependencyForContainer(pod corev1.Pod, name string) (string, bool) {
+	if !strings.HasPrefix(name, dependencyContainerPrefix) {
+		return "", false
+	}
+
+	for _, container := range pod.Spec.InitContainers {
+		if container.Name == name && len(container.Command) != 0 {
+			return container.Command[len(container.Command)-1], true
+		}
+	}
+	return "", false
+}
+
+// statusForContainerState returns a description of the given state of a
+// container that isn't running.
+func statusForContainerState(state corev1.ContainerState) string {
+	switch {
+	case state.Waiting != nil:
+		return "waiting: " + state.Waiting.Reason
+	case state.Terminated != nil:
+		return "terminated: " + state.Terminated.Reason
+	default:
+		return "unrecognized container state"
+	}
+}
+
 type podSlice []corev1.Pod
 
 func (slc podSlice) Get(ii int) interface{} {
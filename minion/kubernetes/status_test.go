@@ -78,7 +78,7 @@ func TestUpdateStatus(t *testing.T) {
 		return "running", mockTime
 	}
 
-	statusForContainer = func(_ map[db.Image]db.Image, _ SecretClient,
+	statusForContainer = func(_ map[imageRef]db.Image, _ SecretClient,
 		dbc db.Container) string {
 		if dbc.Hostname != rebuildingContainer.Hostname {
 			assert.FailNow(t, "unexpected call to statusForContainer "+
@@ -141,7 +141,19 @@ func TestStatusFailedList(t *testing.T) {
 func TestStatusForPod(t *testing.T) {
 	t.Parallel()
 
+	running := corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+	succeeded := corev1.ContainerState{
+		Terminated: &corev1.ContainerStateTerminated{},
+	}
+	failed := corev1.ContainerState{
+		Terminated: &corev1.ContainerStateTerminated{
+			Reason:   "Error",
+			ExitCode: 1,
+		},
+	}
+
 	mockCreatedTime := time.Now()
+	earlierStart := metav1.NewTime(mockCreatedTime.Add(-time.Minute))
 	tests := []struct {
 		expStatus      string
 		expCreatedTime time.Time
@@ -197,6 +209,18 @@ func TestStatusForPod(t *testing.T) {
 				},
 			},
 		},
+	}, {
+		expStatus: "unschedulable: 0/2 nodes are available",
+		pod: corev1.Pod{
+			Status: corev1.PodStatus{
+				Conditions: []corev1.PodCondition{
+					{Status: corev1.ConditionFalse,
+						Type:    corev1.PodScheduled,
+						Reason:  corev1.PodReasonUnschedulable,
+						Message: "0/2 nodes are available"},
+				},
+			},
+		},
 	}, {
 		// "Running" should supersede "scheduled".
 		expStatus:      "running",
@@ -230,6 +254,89 @@ func TestStatusForPod(t *testing.T) {
 	}, {
 		expStatus: "no status information",
 		pod:       corev1.Pod{Status: corev1.PodStatus{}},
+	}, {
+		// Pods with sidecars are only running once all of their containers
+		// are running.
+		expStatus: "waiting: pulling image (proxy)",
+		pod: corev1.Pod{
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "web", State: corev1.ContainerState{
+						Running: &corev1.ContainerStateRunning{
+							StartedAt: metav1.Time{
+								Time: mockCreatedTime,
+							},
+						},
+					}},
+					{Name: "proxy", State: corev1.ContainerState{
+						Waiting: &corev1.ContainerStateWaiting{
+							Reason: "pulling image",
+						},
+					}},
+				},
+			},
+		},
+	}, {
+		expStatus:      "running",
+		expCreatedTime: mockCreatedTime,
+		pod: corev1.Pod{
+			Status: corev1.PodStatus{
+				ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "web", State: corev1.ContainerState{
+						Running: &corev1.ContainerStateRunning{
+							StartedAt: earlierStart,
+						},
+					}},
+					{Name: "proxy", State: corev1.ContainerState{
+						Running: &corev1.ContainerStateRunning{
+							StartedAt: metav1.Time{
+								Time: mockCreatedTime,
+							},
+						},
+					}},
+				},
+			},
+		},
+	}, {
+		expStatus: "waiting for dependency: db:5432",
+		pod: corev1.Pod{
+			Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{
+					{Name: "kelda-dependency-0", Command: []string{
+						"kelda", "wait-for", "db:5432"}},
+					{Name: "migrate"},
+				},
+			},
+			Status: corev1.PodStatus{
+				InitContainerStatuses: []corev1.ContainerStatus{
+					{Name: "kelda-dependency-0", State: running},
+					{Name: "migrate", State: corev1.ContainerState{
+						Waiting: &corev1.ContainerStateWaiting{
+							Reason: "PodInitializing",
+						},
+					}},
+				},
+			},
+		},
+	}, {
+		expStatus: "initializing (migrate)",
+		pod: corev1.Pod{
+			Status: corev1.PodStatus{
+				InitContainerStatuses: []corev1.ContainerStatus{
+					{Name: "kelda-dependency-0", State: succeeded},
+					{Name: "migrate", State: running},
+				},
+			},
+		},
+	}, {
+		expStatus: "terminated: Error (migrate)",
+		pod: corev1.Pod{
+			Status: corev1.PodStatus{
+				InitContainerStatuses: []corev1.ContainerStatus{
+					{Name: "migrate", State: failed},
+				},
+			},
+		},
 	}}
 
 	for _, test := range tests {
@@ -246,6 +353,9 @@ func TestStatusForContainer(t *testing.T) {
 	buildingImage := "buildingImage"
 	builtDockerfile := "builtDockerfile"
 	builtImage := "builtImage"
+	failedDockerfile := "failedDockerfile"
+	failedImage := "failedImage"
+	unresolvedImage := "unresolvedImage"
 
 	secrets := map[string]string{
 		"defined": "val",
@@ -254,6 +364,10 @@ func TestStatusForContainer(t *testing.T) {
 		{Name: buildingImage, Dockerfile: buildingDockerfile,
 			Status: db.Building},
 		{Name: builtImage, Dockerfile: builtDockerfile, Status: db.Built},
+		{Name: failedImage, Dockerfile: failedDockerfile,
+			Status: db.BuildFailed, BuildError: "exit status 1"},
+		{Name: unresolvedImage, Status: db.ResolveFailed,
+			BuildError: "unresolvedImage not found"},
 	}
 
 	// Test building status.
@@ -280,6 +394,41 @@ func TestStatusForContainer(t *testing.T) {
 		Image:      builtImage,
 	}, images, secrets, "built")
 
+	// Test that the reason for failed builds is shown.
+	checkStatusForContainer(t, db.Container{
+		Hostname:   "hostname",
+		Dockerfile: failedDockerfile,
+		Image:      failedImage,
+	}, images, secrets, "build failed: exit status 1")
+
+	// Test that the reason for failed digest resolutions is shown.
+	checkStatusForContainer(t, db.Container{
+		Hostname: "hostname",
+		Image:    unresolvedImage,
+	}, images, secrets, "failed to resolve digest: unresolvedImage not found")
+
+	// Test that the status of a sidecar's image is shown if the container's
+	// image is ready.
+	checkStatusForContainer(t, db.Container{
+		Hostname:   "hostname",
+		Dockerfile: builtDockerfile,
+		Image:      builtImage,
+		Sidecars: []blueprint.Container{
+			{Image: blueprint.Image{Name: buildingImage,
+				Dockerfile: buildingDockerfile}},
+		},
+	}, images, secrets, "building")
+
+	checkStatusForContainer(t, db.Container{
+		Hostname:   "hostname",
+		Dockerfile: buildingDockerfile,
+		Image:      buildingImage,
+		Sidecars: []blueprint.Container{
+			{Image: blueprint.Image{Name: failedImage,
+				Dockerfile: failedDockerfile}},
+		},
+	}, images, secrets, "build failed: exit status 1")
+
 	// Test waiting for secrets.
 	checkStatusForContainer(t, db.Container{
 		Hostname: "hostname",
@@ -291,15 +440,64 @@ func TestStatusForContainer(t *testing.T) {
 	}, nil, secrets, "Waiting for secrets: [undefined undefined2]")
 }
 
+func TestStatusForStatefulContainer(t *testing.T) {
+	t.Parallel()
+
+	images := []db.Image{
+		{Name: "building", Dockerfile: "dockerfile", Status: db.Building},
+	}
+
+	// Members that aren't pinned to a worker are unschedulable.
+	checkStatusForContainer(t, db.Container{
+		Hostname:    "db-0",
+		StatefulSet: "db",
+		Image:       "image",
+	}, images, nil, db.Unschedulable+": no worker satisfies the "+
+		"placement rules of db")
+
+	// Image statuses are more important, since the member can't be
+	// started until its image is ready.
+	checkStatusForContainer(t, db.Container{
+		Hostname:    "db-0",
+		StatefulSet: "db",
+		Image:       "building",
+		Dockerfile:  "dockerfile",
+	}, images, nil, db.Building)
+
+	checkStatusForContainer(t, db.Container{
+		Hostname:    "db-0",
+		StatefulSet: "db",
+		Image:       "image",
+		Minion:      "10.0.0.1",
+	}, images, nil, "")
+}
+
+func TestStatusForContainerUnauthorized(t *testing.T) {
+	t.Parallel()
+
+	secretClient := &mocks.SecretClient{}
+	secretClient.On("Get", mock.Anything).Return("val", nil)
+	secretClient.On("GetAllowedHostnames", "scoped").Return(
+		[]string{"other"}, nil)
+	secretClient.On("GetAllowedHostnames", "unscoped").Return(nil, nil)
+
+	dbc := db.Container{
+		Hostname: "hostname",
+		Env: map[string]blueprint.ContainerValue{
+			"foo": blueprint.NewSecret("scoped"),
+			"bar": blueprint.NewSecret("unscoped"),
+		},
+	}
+	assert.Equal(t, "Not authorized to use secrets: [scoped]",
+		statusForContainerImpl(nil, secretClient, dbc))
+}
+
 func checkStatusForContainer(t *testing.T, dbc db.Container, images []db.Image,
 	secrets map[string]string, expStatus string) {
 
-	imageMap := map[db.Image]db.Image{}
+	imageMap := map[imageRef]db.Image{}
 	for _, img := range images {
-		imageMap[db.Image{
-			Name:       img.Name,
-			Dockerfile: img.Dockerfile,
-		}] = img
+		imageMap[imageRef{img.Name, img.Dockerfile}] = img
 	}
 
 	secretClient := &mocks.SecretClient{}
@@ -312,6 +510,7 @@ func checkStatusForContainer(t *testing.T, dbc db.Container, images []db.Image,
 	}
 	secretClient.On("Get", mock.MatchedBy(testSecretUndefined)).
 		Return("", errors.New("secret does not exist"))
+	secretClient.On("GetAllowedHostnames", mock.Anything).Return(nil, nil)
 	for name, val := range secrets {
 		secretClient.On("Get", name).Return(val, nil)
 	}
@@ -359,9 +558,74 @@ func TestJoinContainersToPods(t *testing.T) {
 	assert.Equal(t, []interface{}{unmatchedContainerA}, noInfoContainers)
 }
 
+func TestJoinContainersToPodsSidecars(t *testing.T) {
+	t.Parallel()
+
+	sidecars := []blueprint.Container{
+		{Hostname: "proxy", Image: blueprint.Image{Name: "envoy"}},
+	}
+	matchContainer := db.Container{
+		Hostname: "hostname1",
+		Image:    "image",
+		Sidecars: sidecars,
+	}
+
+	// A container whose pod has outdated sidecars.
+	unmatchedContainer := db.Container{
+		Hostname: "hostname2",
+		Image:    "image",
+		Sidecars: []blueprint.Container{
+			{Hostname: "proxy", Image: blueprint.Image{Name: "envoy"},
+				Command: []string{"new", "args"}},
+		},
+	}
+	outdatedContainer := unmatchedContainer
+	outdatedContainer.Sidecars = sidecars
+
+	pods, ok := dbcsToPods([]db.Container{matchContainer, outdatedContainer})
+	assert.True(t, ok)
+	assert.Len(t, pods[0].Spec.Containers, 2)
+
+	pairs, noInfoContainers := joinContainersToPodsImpl([]db.Container{
+		matchContainer, unmatchedContainer,
+	}, pods)
+	assert.Equal(t, []join.Pair{{L: matchContainer, R: pods[0]}}, pairs)
+	assert.Equal(t, []interface{}{unmatchedContainer}, noInfoContainers)
+}
+
+func TestJoinContainersToPodsInit(t *testing.T) {
+	t.Parallel()
+
+	matchContainer := db.Container{
+		Hostname:     "hostname1",
+		Image:        "image",
+		Dependencies: []blueprint.Dependency{{Hostname: "db", Port: 5432}},
+	}
+
+	// A container whose pod waits for an outdated dependency.
+	unmatchedContainer := db.Container{
+		Hostname:     "hostname2",
+		Image:        "image",
+		Dependencies: []blueprint.Dependency{{Hostname: "db", Port: 3306}},
+	}
+	outdatedContainer := unmatchedContainer
+	outdatedContainer.Dependencies = matchContainer.Dependencies
+
+	pods, ok := dbcsToPods([]db.Container{matchContainer, outdatedContainer})
+	assert.True(t, ok)
+	assert.Len(t, pods[0].Spec.InitContainers, 1)
+
+	pairs, noInfoContainers := joinContainersToPodsImpl([]db.Container{
+		matchContainer, unmatchedContainer,
+	}, pods)
+	assert.Equal(t, []join.Pair{{L: matchContainer, R: pods[0]}}, pairs)
+	assert.Equal(t, []interface{}{unmatchedContainer}, noInfoContainers)
+}
+
 func dbcsToPods(dbcs []db.Container) (pods []corev1.Pod, ok bool) {
 	for _, dbc := range dbcs {
-		podSpec, ok := makePod(nil, map[string]*corev1.Affinity{}, nil, nil, dbc)
+		podSpec, ok := makePod(nil, map[string]*corev1.Affinity{},
+			nil, nil, nil, dbc)
 		if !ok {
 			return nil, false
 		}
@@ -376,3 +640,34 @@ func dbcsToPods(dbcs []db.Container) (pods []corev1.Pod, ok bool) {
 	}
 	return pods, true
 }
+
+func TestImageDigestForPod(t *testing.T) {
+	t.Parallel()
+
+	pod := corev1.Pod{Status: corev1.PodStatus{
+		ContainerStatuses: []corev1.ContainerStatus{{
+			ImageID: "docker-pullable://nginx@sha256:abc",
+		}},
+	}}
+	assert.Equal(t, "sha256:abc", imageDigestForPod(pod))
+
+	pod.Status.ContainerStatuses[0].ImageID = "docker://sha256:abc"
+	assert.Equal(t, "", imageDigestForPod(pod))
+
+	assert.Equal(t, "", imageDigestForPod(corev1.Pod{}))
+
+	// Only the digest of the Kelda container should be reported for pods
+	// with sidecars.
+	pod = corev1.Pod{
+		Spec: corev1.PodSpec{Hostname: "web"},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: "proxy",
+					ImageID: "docker-pullable://envoy@sha256:1"},
+				{Name: "web",
+					ImageID: "docker-pullable://nginx@sha256:2"},
+			},
+		},
+	}
+	assert.Equal(t, "sha256:2", imageDigestForPod(pod))
+}
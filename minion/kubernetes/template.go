@@ -0,0 +1,247 @@
+package kubernetes
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"text/template"
+
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/join"
+
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientv1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// templateLabelKey labels the Kubernetes secrets that contain rendered
+// templates, so that they can be listed separately from the secrets created by
+// `kelda secret`.
+const templateLabelKey = "kelda.io/template"
+
+// Rendered templates are stored in Kubernetes secrets rather than config maps
+// because they may contain secret values. Each secret is named after the hash
+// of its contents, so when a template's inputs change, a new secret is
+// created, and only the pods that mount it are restarted.
+
+// A templateMember is a member of a load balancer, as returned by the
+// `members` template function.
+type templateMember struct {
+	Hostname string
+	IP       string
+}
+
+// templateContext is the data passed to templates when they're rendered.
+type templateContext struct {
+	// Hostname is the hostname of the container whose file is rendered.
+	Hostname string
+}
+
+// updateTemplates creates a Kubernetes secret for each unique set of rendered
+// templates of the containers in the pods, and deletes the secrets that are no
+// longer referenced. These secrets are mounted by the pods created by
+// updateDeployments and updateDaemonSets.
+func updateTemplates(conn db.Conn, secretsClient clientv1.SecretInterface,
+	secretClient SecretClient) (noErrors bool) {
+
+	noErrors = true
+	currentSecrets, err := secretsClient.List(metav1.ListOptions{
+		LabelSelector: templateLabelKey + "=true",
+	})
+	if err != nil {
+		log.WithError(err).Error("Failed to list current templates")
+		return false
+	}
+
+	key := func(intf interface{}) interface{} {
+		return intf.(corev1.Secret).Name
+	}
+	_, toCreate, toDelete := join.HashJoin(
+		secretSlice(getDesiredTemplates(conn, secretClient)),
+		secretSlice(currentSecrets.Items), key, key)
+
+	for _, intf := range toCreate {
+		secret := intf.(corev1.Secret)
+		c.Inc("Create Template")
+		if _, err := secretsClient.Create(&secret); err != nil {
+			log.WithError(err).WithField("secret", secret.Name).
+				Error("Failed to create template")
+			noErrors = false
+		}
+	}
+
+	for _, intf := range toDelete {
+		secret := intf.(corev1.Secret)
+		c.Inc("Delete Template")
+		err := secretsClient.Delete(secret.Name, &metav1.DeleteOptions{})
+		if err != nil {
+			log.WithError(err).WithField("secret", secret.Name).
+				Error("Failed to delete template")
+			noErrors = false
+		}
+	}
+	return noErrors
+}
+
+// getDesiredTemplates returns the secrets containing the rendered templates of
+// the containers in the pods. Templates that can't be rendered yet, such as
+// templates that reference secrets that haven't been set, are skipped.
+func getDesiredTemplates(conn db.Conn, secretClient SecretClient) (
+	secrets []corev1.Secret) {
+
+	var containers []db.Container
+	var runtime *runtimeData
+	conn.Txn(db.ContainerTable, db.BlueprintTable, db.HostnameTable,
+		db.LoadBalancerTable, db.MinionTable).Run(func(view db.Database) error {
+
+		containers = view.SelectFromContainer(func(dbc db.Container) bool {
+			return dbc.IP != ""
+		})
+
+		bp, _ := view.GetBlueprint()
+		runtime = getRuntimeData(view, bp)
+		return nil
+	})
+
+	names := map[string]struct{}{}
+	for _, dbc := range containers {
+		// The instances of daemon containers share a pod spec that's named
+		// after the daemon container.
+		hostname := dbc.Hostname
+		if dbc.DaemonSet != "" {
+			hostname = dbc.DaemonSet
+		}
+
+		for _, member := range dbc.PodContainers() {
+			_, _, _, templates := blueprint.DivideContainerValues(
+				member.FilepathToContent)
+			if len(templates) == 0 {
+				continue
+			}
+
+			rendered, err := runtime.renderTemplates(secretClient, hostname,
+				templates)
+			if err != nil {
+				log.WithError(err).WithField("container",
+					member.Hostname).Debug(
+					"Failed to render template")
+				continue
+			}
+
+			secret := makeTemplateSecret(rendered)
+			if _, ok := names[secret.Name]; !ok {
+				secrets = append(secrets, secret)
+				names[secret.Name] = struct{}{}
+			}
+		}
+	}
+	return secrets
+}
+
+// renderTemplates renders the given templates, which map file paths to the
+// text of the templates, for the container with the given hostname.
+func (runtime *runtimeData) renderTemplates(secretClient SecretClient,
+	hostname string, templates map[string]string) (map[string]string, error) {
+
+	if len(templates) == 0 {
+		return nil, nil
+	}
+
+	if runtime == nil {
+		return nil, errors.New("no runtime data")
+	}
+
+	funcs := template.FuncMap{
+		blueprint.TemplateIPFunc:      runtime.ip,
+		blueprint.TemplateMembersFunc: runtime.members,
+		blueprint.TemplateSecretFunc: func(name string) (string, error) {
+			if secretClient == nil {
+				return "", errors.New("no secret client")
+			}
+			return secretClient.Get(name)
+		},
+	}
+
+	rendered := map[string]string{}
+	for path, text := range templates {
+		tmpl, err := blueprint.Template{Text: text}.Parse(funcs)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %s", path, err)
+		}
+
+		var buf bytes.Buffer
+		err = tmpl.Execute(&buf, templateContext{Hostname: hostname})
+		if err != nil {
+			return nil, fmt.Errorf("render %s: %s", path, err)
+		}
+		rendered[path] = buf.String()
+	}
+	return rendered, nil
+}
+
+// ip implements the `ip` template function.
+func (runtime *runtimeData) ip(hostname string) (string, error) {
+	ip := runtime.hostnameToIP[hostname]
+	if ip == "" {
+		return "", fmt.Errorf("no IP for hostname %s", hostname)
+	}
+	return ip, nil
+}
+
+// members implements the `members` template function. Members that haven't
+// been assigned an IP are omitted.
+func (runtime *runtimeData) members(name string) ([]templateMember, error) {
+	hostnames, ok := runtime.loadBalancers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown load balancer %s", name)
+	}
+
+	var members []templateMember
+	for _, hostname := range hostnames {
+		if ip := runtime.hostnameToIP[hostname]; ip != "" {
+			members = append(members, templateMember{hostname, ip})
+		}
+	}
+
+	// Sort the members so that the rendered file is consistent. Otherwise,
+	// the pod would be restarted.
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Hostname < members[j].Hostname
+	})
+	return members, nil
+}
+
+// makeTemplateSecret returns the Kubernetes secret containing the given
+// rendered templates, which map file paths to their contents.
+func makeTemplateSecret(rendered map[string]string) corev1.Secret {
+	secret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   templateSecretName(rendered),
+			Labels: map[string]string{templateLabelKey: "true"},
+		},
+		Data: map[string][]byte{},
+	}
+	for path, content := range rendered {
+		secret.Data[configMapKey(path)] = []byte(content)
+	}
+	return secret
+}
+
+// templateSecretName returns the name of the Kubernetes secret containing the
+// given rendered templates.
+func templateSecretName(rendered map[string]string) string {
+	return "kelda-template-" + configMapName(rendered)
+}
+
+type secretSlice []corev1.Secret
+
+func (slc secretSlice) Get(ii int) interface{} {
+	return slc[ii]
+}
+
+func (slc secretSlice) Len() int {
+	return len(slc)
+}
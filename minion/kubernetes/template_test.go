@@ -0,0 +1,182 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/minion/kubernetes/mocks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRenderTemplates(t *testing.T) {
+	t.Parallel()
+
+	secretClient := &mocks.SecretClient{}
+	secretClient.On("Get", "password").Return("hunter2", nil)
+	secretClient.On("Get", "missing").Return("", assert.AnError)
+
+	runtime := &runtimeData{
+		hostnameToIP: map[string]string{
+			"db":    "10.0.0.2",
+			"web-1": "10.0.0.4",
+			"web-0": "10.0.0.3",
+			"lb":    "10.0.0.5",
+		},
+		loadBalancers: map[string][]string{
+			"lb": {"web-1", "web-0", "web-2"},
+		},
+	}
+
+	rendered, err := runtime.renderTemplates(secretClient, "proxy",
+		map[string]string{
+			"/etc/db": `{{.Hostname}} {{ip "db"}}:{{secret "password"}}`,
+			"/etc/haproxy.cfg": `{{range members "lb"}}` +
+				`server {{.Hostname}} {{.IP}}:80
+{{end}}`,
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"/etc/db": "proxy 10.0.0.2:hunter2",
+		// Members without IPs should be omitted, and the members should be
+		// sorted.
+		"/etc/haproxy.cfg": "server web-0 10.0.0.3:80\n" +
+			"server web-1 10.0.0.4:80\n",
+	}, rendered)
+
+	// Templates that can't be resolved shouldn't be rendered.
+	for _, text := range []string{
+		`{{ip "unknown"}}`, `{{ip "web-2"}}`, `{{members "unknown"}}`,
+		`{{secret "missing"}}`, `{{ip "db"`,
+	} {
+		_, err = runtime.renderTemplates(secretClient, "proxy",
+			map[string]string{"/file": text})
+		assert.Error(t, err, text)
+	}
+}
+
+func TestMakePodTemplates(t *testing.T) {
+	t.Parallel()
+
+	runtime := &runtimeData{hostnameToIP: map[string]string{"db": "10.0.0.2"}}
+	dbc := db.Container{
+		Hostname: "web",
+		Image:    "nginx",
+		FilepathToContent: map[string]blueprint.ContainerValue{
+			"/etc/db": blueprint.NewTemplate(`{{ip "db"}}`),
+		},
+	}
+	pod, ok := makePod(nil, map[string]*corev1.Affinity{}, nil, nil,
+		runtime, dbc)
+	assert.True(t, ok)
+
+	rendered := map[string]string{"/etc/db": "10.0.0.2"}
+	assert.Equal(t, []corev1.Volume{{
+		Name: "templates",
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName:  templateSecretName(rendered),
+				DefaultMode: &filepathToContentMode,
+			},
+		},
+	}}, pod.Volumes)
+	assert.Equal(t, []corev1.VolumeMount{{
+		Name:      "templates",
+		MountPath: "/etc/db",
+		SubPath:   configMapKey("/etc/db"),
+	}}, pod.Containers[0].VolumeMounts)
+
+	// When the template's inputs change, the pod should mount a different
+	// secret.
+	runtime.hostnameToIP["db"] = "10.0.0.3"
+	newPod, ok := makePod(nil, map[string]*corev1.Affinity{}, nil, nil,
+		runtime, dbc)
+	assert.True(t, ok)
+	assert.NotEqual(t, pod.Volumes, newPod.Volumes)
+
+	// Pods whose templates can't be rendered shouldn't be created.
+	delete(runtime.hostnameToIP, "db")
+	_, ok = makePod(nil, map[string]*corev1.Affinity{}, nil, nil, runtime, dbc)
+	assert.False(t, ok)
+
+	// Templates are only supported in files.
+	runtime.hostnameToIP["db"] = "10.0.0.2"
+	dbc.Env = map[string]blueprint.ContainerValue{
+		"DB": blueprint.NewTemplate(`{{ip "db"}}`),
+	}
+	_, ok = makePod(nil, map[string]*corev1.Affinity{}, nil, nil, runtime, dbc)
+	assert.False(t, ok)
+}
+
+func TestUpdateTemplates(t *testing.T) {
+	t.Parallel()
+	conn := db.New()
+	client := &mocks.SecretInterface{}
+
+	conn.Txn(db.ContainerTable, db.HostnameTable).Run(func(view db.Database) error {
+		hostname := view.InsertHostname()
+		hostname.Hostname = "db"
+		hostname.IP = "10.0.0.2"
+		view.Commit(hostname)
+
+		// Containers with the same rendered templates should share a secret.
+		for _, host := range []string{"web-0", "web-1"} {
+			dbc := view.InsertContainer()
+			dbc.Hostname = host
+			dbc.IP = "10.0.0.3"
+			dbc.FilepathToContent = map[string]blueprint.ContainerValue{
+				"/etc/db":   blueprint.NewTemplate(`{{ip "db"}}`),
+				"/etc/conf": blueprint.NewString("ignored"),
+			}
+			view.Commit(dbc)
+		}
+
+		// Templates that can't be rendered are skipped.
+		dbc := view.InsertContainer()
+		dbc.IP = "10.0.0.4"
+		dbc.FilepathToContent = map[string]blueprint.ContainerValue{
+			"/etc/cache": blueprint.NewTemplate(`{{ip "cache"}}`),
+		}
+		view.Commit(dbc)
+		return nil
+	})
+
+	rendered := map[string]string{"/etc/db": "10.0.0.2"}
+	secret := corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   templateSecretName(rendered),
+			Labels: map[string]string{templateLabelKey: "true"},
+		},
+		Data: map[string][]byte{configMapKey("/etc/db"): []byte("10.0.0.2")},
+	}
+	stale := corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "stale"}}
+
+	listOpts := metav1.ListOptions{LabelSelector: "kelda.io/template=true"}
+	client.On("List", listOpts).Return(&corev1.SecretList{
+		Items: []corev1.Secret{stale},
+	}, nil).Once()
+	client.On("Create", &secret).Return(nil, nil).Once()
+	client.On("Delete", "stale", mock.Anything).Return(nil).Once()
+	assert.True(t, updateTemplates(conn, client, nil))
+	client.AssertExpectations(t)
+
+	// If the templates are up to date, no actions need to be taken.
+	client.On("List", listOpts).Return(&corev1.SecretList{
+		Items: []corev1.Secret{secret},
+	}, nil).Once()
+	assert.True(t, updateTemplates(conn, client, nil))
+	client.AssertExpectations(t)
+
+	// Errors should be reported.
+	client.On("List", listOpts).Return(nil, assert.AnError).Once()
+	assert.False(t, updateTemplates(conn, client, nil))
+
+	client.On("List", listOpts).Return(&corev1.SecretList{}, nil).Once()
+	client.On("Create", &secret).Return(nil, assert.AnError).Once()
+	assert.False(t, updateTemplates(conn, client, nil))
+	client.AssertExpectations(t)
+}
@@ -0,0 +1,246 @@
+package logship
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kelda/kelda/counter"
+	"github.com/kelda/kelda/minion/docker"
+
+	log "github.com/sirupsen/logrus"
+)
+
+/*
+The logship module ships the logs of blueprint containers to the LogSink
+configured in the blueprint. On each worker, the supervisor boots a log
+collector container that follows the Docker logs of the containers created by
+the Kubelet. Each line is tagged with the hostname of the container, the
+machine it's running on, and the blueprint namespace, and is then written to
+the sink.
+
+Syslog and HTTP sinks are written to directly by the workers. For file sinks,
+the workers POST their logs to a receiver running on the leader master, which
+appends them to the file.
+*/
+
+// ReceiverPort is the port that the log receiver on the leader listens on.
+const ReceiverPort = 9010
+
+// The Docker label the Kubelet uses to name the containers in a pod. Pod
+// infrastructure containers are named "POD".
+const kubeContainerNameLabel = "io.kubernetes.container.name"
+
+// An Entry is a single line of a container's logs.
+type Entry struct {
+	Time      time.Time
+	Hostname  string
+	Machine   string
+	Namespace string
+	Stream    string
+	Message   string
+}
+
+var c = counter.New("Log Ship")
+
+// Collector follows the logs of the containers running on the local Docker
+// daemon, and writes them to a Sink.
+type Collector struct {
+	Machine   string
+	Namespace string
+
+	sink Sink
+	dk   docker.Client
+
+	// The IDs of the containers whose logs are currently being followed.
+	following map[string]struct{}
+
+	// How far the logs of each running container have been shipped, so that
+	// following a container again after its stream drops doesn't ship the
+	// same lines twice.
+	positions map[string]*logPosition
+	lock      sync.Mutex
+}
+
+// logPosition records how far the logs of a container have been shipped.
+type logPosition struct {
+	// The time the logs were first followed from.
+	start time.Time
+
+	// The timestamp of the last entry shipped from each stream.
+	shipped map[string]time.Time
+}
+
+// resumeTime returns the time to follow the container's logs from so that no
+// entries are missed. Docker only accepts whole seconds, and the streams may
+// have been shipped to different points, so some entries may have already
+// been shipped, and must be skipped.
+func (pos logPosition) resumeTime() time.Time {
+	var since time.Time
+	for _, stream := range []string{"stdout", "stderr"} {
+		shipped, ok := pos.shipped[stream]
+		if !ok {
+			return pos.start
+		}
+		if since.IsZero() || shipped.Before(since) {
+			since = shipped
+		}
+	}
+	return since
+}
+
+// NewCollector creates a Collector that writes to the given sink.
+func NewCollector(dk docker.Client, sink Sink, machine, namespace string) *Collector {
+	return &Collector{
+		Machine:   machine,
+		Namespace: namespace,
+		sink:      sink,
+		dk:        dk,
+		following: map[string]struct{}{},
+		positions: map[string]*logPosition{},
+	}
+}
+
+// Run blocks following the logs of blueprint containers. Containers that are
+// already running when the collector starts only have their new logs shipped
+// so that restarting the collector doesn't duplicate entries.
+func (col *Collector) Run() {
+	bootTime := time.Now()
+	for range time.Tick(5 * time.Second) {
+		col.runOnce(bootTime)
+	}
+}
+
+func (col *Collector) runOnce(bootTime time.Time) {
+	containers, err := col.dk.List(nil, false)
+	if err != nil {
+		log.WithError(err).Error("Failed to list containers")
+		return
+	}
+
+	col.lock.Lock()
+	defer col.lock.Unlock()
+
+	running := map[string]struct{}{}
+	for _, dkc := range containers {
+		hostname := dkc.Labels[kubeContainerNameLabel]
+		if hostname == "" || hostname == "POD" {
+			continue
+		}
+		running[dkc.ID] = struct{}{}
+
+		if _, ok := col.following[dkc.ID]; ok {
+			continue
+		}
+		col.following[dkc.ID] = struct{}{}
+
+		pos, ok := col.positions[dkc.ID]
+		if !ok {
+			since := dkc.Created
+			if since.Before(bootTime) {
+				since = bootTime
+			}
+			pos = &logPosition{start: since,
+				shipped: map[string]time.Time{}}
+			col.positions[dkc.ID] = pos
+		}
+
+		log.WithField("container", hostname).Debug("Following logs")
+		go col.follow(dkc.ID, hostname, pos.resumeTime())
+	}
+
+	for id := range col.positions {
+		_, isRunning := running[id]
+		_, isFollowed := col.following[id]
+		if !isRunning && !isFollowed {
+			delete(col.positions, id)
+		}
+	}
+}
+
+// follow ships the logs of the given container until it exits.
+func (col *Collector) follow(id, hostname string, since time.Time) {
+	stdout := col.lineWriter(id, hostname, "stdout")
+	stderr := col.lineWriter(id, hostname, "stderr")
+
+	err := col.dk.FollowLogs(id, since, stdout, stderr)
+	stdout.Close()
+	stderr.Close()
+	if err != nil {
+		log.WithError(err).WithField("container", hostname).Warn(
+			"Failed to follow container logs")
+	}
+
+	// Forget the container so that it's followed again from where it left
+	// off if it's still running, e.g. if the Docker daemon closed the
+	// connection.
+	col.lock.Lock()
+	delete(col.following, id)
+	col.lock.Unlock()
+}
+
+// lineWriter returns a writer that parses the timestamped lines written by
+// Docker into Entries, and writes them to the sink. Entries that were already
+// shipped before the container's logs were last followed are skipped. Closing
+// the writer waits for the written lines to be shipped.
+func (col *Collector) lineWriter(id, hostname, stream string) io.WriteCloser {
+	col.lock.Lock()
+	pos := col.positions[id]
+	lastShipped, resumed := pos.shipped[stream]
+	col.lock.Unlock()
+
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		scanner := bufio.NewScanner(pr)
+		for scanner.Scan() {
+			entry := parseLine(scanner.Text())
+			if resumed && !entry.Time.After(lastShipped) {
+				continue
+			}
+
+			col.lock.Lock()
+			pos.shipped[stream] = entry.Time
+			col.lock.Unlock()
+
+			entry.Hostname = hostname
+			entry.Machine = col.Machine
+			entry.Namespace = col.Namespace
+			entry.Stream = stream
+
+			c.Inc("Ship entry")
+			if err := col.sink.Write(entry); err != nil {
+				c.Inc("Ship entry error")
+				log.WithError(err).Debug("Failed to ship log entry")
+			}
+		}
+		pr.CloseWithError(scanner.Err())
+	}()
+	return waitCloser{pw, done}
+}
+
+// waitCloser is a pipe writer whose Close blocks until the reader is done.
+type waitCloser struct {
+	*io.PipeWriter
+	done chan struct{}
+}
+
+func (w waitCloser) Close() error {
+	err := w.PipeWriter.Close()
+	<-w.done
+	return err
+}
+
+// parseLine splits a log line written by Docker into its timestamp and message.
+func parseLine(line string) Entry {
+	parts := strings.SplitN(line, " ", 2)
+	if len(parts) == 2 {
+		if t, err := time.Parse(time.RFC3339Nano, parts[0]); err == nil {
+			return Entry{Time: t, Message: parts[1]}
+		}
+	}
+	return Entry{Time: time.Now(), Message: line}
+}
@@ -0,0 +1,146 @@
+package logship
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kelda/kelda/minion/docker"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockSink struct {
+	entries []Entry
+	lock    sync.Mutex
+}
+
+func (s *mockSink) Write(e Entry) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+func (s *mockSink) get() []Entry {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Entry{}, s.entries...)
+}
+
+func TestCollector(t *testing.T) {
+	md, dk := docker.NewMock()
+
+	appID, err := dk.Run(docker.RunOptions{
+		Name:   "k8s_app",
+		Image:  "app",
+		Labels: map[string]string{kubeContainerNameLabel: "app"},
+	})
+	assert.NoError(t, err)
+
+	podID, err := dk.Run(docker.RunOptions{
+		Name:   "k8s_POD",
+		Image:  "pause",
+		Labels: map[string]string{kubeContainerNameLabel: "POD"},
+	})
+	assert.NoError(t, err)
+
+	sysID, err := dk.Run(docker.RunOptions{Name: "etcd", Image: "etcd"})
+	assert.NoError(t, err)
+
+	md.ContainerLogs[appID] = "2018-01-02T03:04:05.000000006Z hello\n" +
+		"2018-01-02T03:04:06Z world\n"
+	md.ContainerLogs[podID] = "2018-01-02T03:04:05Z pod\n"
+	md.ContainerLogs[sysID] = "2018-01-02T03:04:05Z system\n"
+
+	sink := &mockSink{}
+	col := NewCollector(dk, sink, "10.0.0.1", "ns")
+	col.runOnce(time.Now())
+
+	var entries []Entry
+	for i := 0; i < 100 && len(entries) < 2; i++ {
+		time.Sleep(10 * time.Millisecond)
+		entries = sink.get()
+	}
+
+	assert.Equal(t, []Entry{
+		{
+			Time:      time.Date(2018, 1, 2, 3, 4, 5, 6, time.UTC),
+			Hostname:  "app",
+			Machine:   "10.0.0.1",
+			Namespace: "ns",
+			Stream:    "stdout",
+			Message:   "hello",
+		},
+		{
+			Time:      time.Date(2018, 1, 2, 3, 4, 6, 0, time.UTC),
+			Hostname:  "app",
+			Machine:   "10.0.0.1",
+			Namespace: "ns",
+			Stream:    "stdout",
+			Message:   "world",
+		},
+	}, entries)
+
+	// The mock ends the stream once the logs are written, so following the
+	// container again resumes after the shipped entries.
+	md.Lock()
+	md.ContainerLogs[appID] += "2018-01-02T03:04:06.5Z again\n"
+	md.Unlock()
+	waitForStreams(col)
+	col.runOnce(time.Now())
+
+	for i := 0; i < 100 && len(entries) < 3; i++ {
+		time.Sleep(10 * time.Millisecond)
+		entries = sink.get()
+	}
+	waitForStreams(col)
+	entries = sink.get()
+	assert.Len(t, entries, 3)
+	assert.Equal(t, "again", entries[2].Message)
+
+	// Positions are forgotten once the container stops.
+	assert.NoError(t, dk.RemoveID(appID))
+	col.runOnce(time.Now())
+	col.lock.Lock()
+	assert.Empty(t, col.positions)
+	col.lock.Unlock()
+}
+
+func waitForStreams(col *Collector) {
+	for i := 0; i < 100; i++ {
+		col.lock.Lock()
+		n := len(col.following)
+		col.lock.Unlock()
+		if n == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestResumeTime(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	pos := logPosition{start: start, shipped: map[string]time.Time{}}
+	assert.Equal(t, start, pos.resumeTime())
+
+	pos.shipped["stdout"] = start.Add(time.Minute)
+	assert.Equal(t, start, pos.resumeTime())
+
+	pos.shipped["stderr"] = start.Add(time.Second)
+	assert.Equal(t, start.Add(time.Second), pos.resumeTime())
+}
+
+func TestParseLine(t *testing.T) {
+	t.Parallel()
+
+	entry := parseLine("2018-01-02T03:04:05Z a message")
+	assert.Equal(t, time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC), entry.Time)
+	assert.Equal(t, "a message", entry.Message)
+
+	entry = parseLine("no timestamp")
+	assert.Equal(t, "no timestamp", entry.Message)
+	assert.False(t, entry.Time.IsZero())
+}
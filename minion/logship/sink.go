@@ -0,0 +1,280 @@
+package logship
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kelda/kelda/blueprint"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// A Sink is a destination for log entries.
+type Sink interface {
+	Write(Entry) error
+}
+
+// NewSink returns the Sink that workers should write to in order to ship logs
+// to `sinkCfg`. File sinks are written to through the receiver on the leader
+// at `leaderIP`.
+func NewSink(sinkCfg blueprint.LogSink, leaderIP string) (Sink, error) {
+	switch sinkCfg.Type {
+	case blueprint.SyslogSink:
+		protocol := sinkCfg.Protocol
+		if protocol == "" {
+			protocol = "udp"
+		}
+		if protocol != "udp" && protocol != "tcp" {
+			return nil, fmt.Errorf("unknown syslog protocol: %s", protocol)
+		}
+		if sinkCfg.Address == "" {
+			return nil, errors.New("syslog sinks require an address")
+		}
+		return &syslogSink{protocol: protocol, address: sinkCfg.Address}, nil
+	case blueprint.HTTPSink:
+		if sinkCfg.Address == "" {
+			return nil, errors.New("http sinks require an address")
+		}
+		return newHTTPSink(sinkCfg.Address), nil
+	case blueprint.FileSink:
+		if leaderIP == "" {
+			return nil, errors.New("no leader to receive logs")
+		}
+		return newHTTPSink(ReceiverURL(leaderIP)), nil
+	default:
+		return nil, fmt.Errorf("unknown log sink type: %s", sinkCfg.Type)
+	}
+}
+
+// ReceiverURL returns the URL of the log receiver running on `ip`.
+func ReceiverURL(ip string) string {
+	return fmt.Sprintf("http://%s:%d/logs", ip, ReceiverPort)
+}
+
+// The JSON representation of Entries written by the HTTP and file sinks.
+type jsonEntry struct {
+	Time      time.Time `json:"time"`
+	Hostname  string    `json:"hostname"`
+	Machine   string    `json:"machine"`
+	Namespace string    `json:"namespace"`
+	Stream    string    `json:"stream"`
+	Message   string    `json:"message"`
+}
+
+func (e Entry) toJSON() jsonEntry {
+	return jsonEntry(e)
+}
+
+// syslogSink writes entries to a remote syslog server in the RFC 5424 format.
+type syslogSink struct {
+	protocol string
+	address  string
+
+	conn net.Conn
+	lock sync.Mutex
+}
+
+var dial = net.Dial
+
+func (s *syslogSink) Write(e Entry) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.conn == nil {
+		conn, err := dial(s.protocol, s.address)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+
+	if _, err := io.WriteString(s.conn, s.format(e)); err != nil {
+		// Reconnect on the next write.
+		s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+// The syslog priorities of log entries, computed as the "user-level messages"
+// facility (1) multiplied by 8, plus the severity.
+const (
+	syslogInfoPriority = 1*8 + 6
+	syslogErrPriority  = 1*8 + 3
+)
+
+func (s *syslogSink) format(e Entry) string {
+	priority := syslogInfoPriority
+	if e.Stream == "stderr" {
+		priority = syslogErrPriority
+	}
+
+	msg := fmt.Sprintf("<%d>1 %s %s kelda - - "+
+		`[kelda machine="%s" namespace="%s" stream="%s"] %s`,
+		priority, e.Time.UTC().Format(time.RFC3339Nano), e.Hostname,
+		escapeSDParam(e.Machine), escapeSDParam(e.Namespace), e.Stream,
+		e.Message)
+
+	// TCP connections use octet counting to frame messages (RFC 6587).
+	if s.protocol == "tcp" {
+		return fmt.Sprintf("%d %s", len(msg), msg)
+	}
+	return msg
+}
+
+// escapeSDParam escapes the characters that aren't allowed in syslog
+// structured data parameter values.
+func escapeSDParam(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(s)
+}
+
+// httpSink POSTs batches of entries as JSON lines to a URL.
+type httpSink struct {
+	url     string
+	entries chan Entry
+}
+
+// The maximum number of entries that are buffered by an httpSink before new
+// entries are dropped, and the maximum number of entries sent in one request.
+const (
+	httpBufferSize   = 4096
+	httpMaxBatchSize = 512
+)
+
+var httpPost = http.Post
+
+func newHTTPSink(url string) *httpSink {
+	s := &httpSink{url: url, entries: make(chan Entry, httpBufferSize)}
+	go s.run()
+	return s
+}
+
+func (s *httpSink) Write(e Entry) error {
+	select {
+	case s.entries <- e:
+		return nil
+	default:
+		c.Inc("Drop entry")
+		return errors.New("log buffer full")
+	}
+}
+
+func (s *httpSink) run() {
+	for e := range s.entries {
+		batch := []Entry{e}
+	fill:
+		for len(batch) < httpMaxBatchSize {
+			select {
+			case e := <-s.entries:
+				batch = append(batch, e)
+			default:
+				break fill
+			}
+		}
+
+		if err := s.post(batch); err != nil {
+			log.WithError(err).WithField("url", s.url).Warn(
+				"Failed to ship logs")
+			time.Sleep(time.Second)
+		}
+	}
+}
+
+func (s *httpSink) post(batch []Entry) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, e := range batch {
+		if err := encoder.Encode(e.toJSON()); err != nil {
+			return err
+		}
+	}
+
+	resp, err := httpPost(s.url, "application/x-ndjson", &body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return nil
+}
+
+// fileSink appends entries as JSON lines to a file.
+type fileSink struct {
+	file io.WriteCloser
+	lock sync.Mutex
+}
+
+// NewFileSink returns a Sink that appends entries to the file at `path`.
+func NewFileSink(path string) (Sink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSink{file: f}, nil
+}
+
+func (s *fileSink) Write(e Entry) error {
+	line, err := json.Marshal(e.toJSON())
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, err = s.file.Write(append(line, '\n'))
+	return err
+}
+
+// Receive blocks serving the HTTP endpoint that workers POST their logs to
+// when the blueprint uses a file sink. Received entries are written to `sink`.
+func Receive(sink Sink) error {
+	http.Handle("/logs", receiveHandler{sink})
+	return http.ListenAndServe(fmt.Sprintf(":%d", ReceiverPort), nil)
+}
+
+type receiveHandler struct {
+	sink Sink
+}
+
+func (h receiveHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	for {
+		var je jsonEntry
+		err := decoder.Decode(&je)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := h.sink.Write(Entry(je)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
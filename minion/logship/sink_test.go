@@ -0,0 +1,137 @@
+package logship
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kelda/kelda/blueprint"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testEntry = Entry{
+	Time:      time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC),
+	Hostname:  "app",
+	Machine:   "10.0.0.1",
+	Namespace: "ns",
+	Stream:    "stderr",
+	Message:   "oops",
+}
+
+const testEntryJSON = `{"time":"2018-01-02T03:04:05Z","hostname":"app",` +
+	`"machine":"10.0.0.1","namespace":"ns","stream":"stderr","message":"oops"}`
+
+func TestNewSink(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewSink(blueprint.LogSink{Type: "unknown"}, "")
+	assert.EqualError(t, err, "unknown log sink type: unknown")
+
+	_, err = NewSink(blueprint.LogSink{Type: blueprint.SyslogSink}, "")
+	assert.EqualError(t, err, "syslog sinks require an address")
+
+	_, err = NewSink(blueprint.LogSink{Type: blueprint.SyslogSink,
+		Address: "logs:514", Protocol: "sctp"}, "")
+	assert.EqualError(t, err, "unknown syslog protocol: sctp")
+
+	sink, err := NewSink(blueprint.LogSink{Type: blueprint.SyslogSink,
+		Address: "logs:514"}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "udp", sink.(*syslogSink).protocol)
+
+	_, err = NewSink(blueprint.LogSink{Type: blueprint.FileSink}, "")
+	assert.EqualError(t, err, "no leader to receive logs")
+
+	sink, err = NewSink(blueprint.LogSink{Type: blueprint.FileSink}, "10.0.0.2")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://10.0.0.2:9010/logs", sink.(*httpSink).url)
+}
+
+func TestSyslogSink(t *testing.T) {
+	msg := `<11>1 2018-01-02T03:04:05Z app kelda - - ` +
+		`[kelda machine="10.0.0.1" namespace="ns" stream="stderr"] oops`
+
+	udp := &syslogSink{protocol: "udp"}
+	assert.Equal(t, msg, udp.format(testEntry))
+
+	tcp := &syslogSink{protocol: "tcp"}
+	assert.Equal(t, fmt.Sprintf("%d %s", len(msg), msg), tcp.format(testEntry))
+
+	client, server := net.Pipe()
+	defer server.Close()
+	dial = func(network, address string) (net.Conn, error) {
+		assert.Equal(t, "tcp", network)
+		assert.Equal(t, "logs:514", address)
+		return client, nil
+	}
+
+	sink := &syslogSink{protocol: "tcp", address: "logs:514"}
+	go sink.Write(testEntry)
+
+	buf := make([]byte, len(tcp.format(testEntry)))
+	_, err := io.ReadFull(server, buf)
+	assert.NoError(t, err)
+	assert.Equal(t, tcp.format(testEntry), string(buf))
+}
+
+func TestHTTPSink(t *testing.T) {
+	bodies := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "application/x-ndjson",
+				r.Header.Get("Content-Type"))
+			body, _ := ioutil.ReadAll(r.Body)
+			bodies <- string(body)
+		}))
+	defer server.Close()
+
+	sink := newHTTPSink(server.URL)
+	assert.NoError(t, sink.Write(testEntry))
+
+	select {
+	case body := <-bodies:
+		assert.Equal(t, testEntryJSON+"\n", body)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for logs")
+	}
+}
+
+func TestReceiver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logship")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "logs", "containers.log")
+	sink, err := NewFileSink(path)
+	assert.NoError(t, err)
+
+	server := httptest.NewServer(receiveHandler{sink})
+	defer server.Close()
+
+	resp, err := http.Post(server.URL, "application/x-ndjson",
+		strings.NewReader(testEntryJSON+"\n"+testEntryJSON+"\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, err = http.Post(server.URL, "application/x-ndjson",
+		strings.NewReader("malformed"))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Get(server.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	contents, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, testEntryJSON+"\n"+testEntryJSON+"\n", string(contents))
+}
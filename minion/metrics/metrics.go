@@ -0,0 +1,342 @@
+package metrics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/counter"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/minion/docker"
+	"github.com/kelda/kelda/util"
+
+	log "github.com/sirupsen/logrus"
+)
+
+/*
+The metrics submodule periodically samples the resource usage of the machine,
+and of the pods running on it, and writes the samples to the Metrics table. The
+daemon's foreman polls the Metrics table through the minion API, and stores the
+samples of every machine in its own database for `kelda show` and `kelda top`.
+
+Machine CPU usage is computed from the difference between consecutive samples
+of /proc/stat, so the CPU usage of the machine isn't reported until the second
+sample.
+
+The replicas of containers that are autoscaled by an HTTP metric are also
+polled for the metric's value, which is stored in the Custom field of the pod's
+sample. The replicas are reached through the gateway address on the machine,
+which every container is allowed to communicate with.
+*/
+
+// The Docker labels the Kubelet uses to identify the pod and container name of
+// the containers it creates. Pod infrastructure containers are named "POD".
+const (
+	kubePodNameLabel       = "io.kubernetes.pod.name"
+	kubeContainerNameLabel = "io.kubernetes.container.name"
+)
+
+var sampleInterval = 10 * time.Second
+
+var c = counter.New("Metrics")
+
+// Run blocks sampling the resource usage of the machine and its pods.
+func Run(conn db.Conn, dk docker.Client) {
+	var prevCPU cpuTimes
+	for range time.Tick(sampleInterval) {
+		prevCPU = runOnce(conn, dk, prevCPU)
+	}
+}
+
+func runOnce(conn db.Conn, dk docker.Client, prevCPU cpuTimes) cpuTimes {
+	c.Inc("Sample")
+
+	now := time.Now()
+	machine, currCPU, err := sampleMachine(prevCPU)
+	if err != nil {
+		log.WithError(err).Warn("Failed to sample machine metrics")
+	}
+	machine.Time = now
+
+	pods, podHostnames := samplePods(dk)
+	endpoints := customEndpoints(conn)
+	for i := range pods {
+		pods[i].Time = now
+
+		url, ok := endpoints[podHostnames[pods[i].PodName]]
+		if !ok {
+			continue
+		}
+
+		value, err := scrape(url)
+		if err != nil {
+			c.Inc("Scrape error")
+			log.WithError(err).WithField("url", url).Debug(
+				"Failed to scrape autoscaling metric")
+			continue
+		}
+		pods[i].Custom = value
+		pods[i].HasCustom = true
+	}
+
+	conn.Txn(db.MetricsTable).Run(func(view db.Database) error {
+		for _, m := range view.SelectFromMetrics(nil) {
+			view.Remove(m)
+		}
+
+		for _, sample := range append([]db.Metrics{machine}, pods...) {
+			m := view.InsertMetrics()
+			sample.ID = m.ID
+			view.Commit(sample)
+		}
+		return nil
+	})
+	return currCPU
+}
+
+// sampleMachine returns the resource usage of the machine, and the CPU times
+// that should be used as `prevCPU` for the next sample.
+func sampleMachine(prevCPU cpuTimes) (db.Metrics, cpuTimes, error) {
+	var m db.Metrics
+
+	currCPU, err := readCPUTimes()
+	if err != nil {
+		return m, prevCPU, err
+	}
+
+	if totalDelta := currCPU.total - prevCPU.total; prevCPU.total != 0 &&
+		currCPU.total > prevCPU.total {
+		idleDelta := currCPU.idle - prevCPU.idle
+		m.CPUPercent = 100 * (1 - float64(idleDelta)/float64(totalDelta))
+	}
+
+	m.MemoryTotal, m.MemoryUsed, err = readMemory()
+	if err != nil {
+		return m, currCPU, err
+	}
+
+	m.DiskTotal, m.DiskUsed, err = diskUsage("/")
+	return m, currCPU, err
+}
+
+// samplePods returns the resource usage of each pod running on the machine,
+// and the hostname of each pod, keyed by pod name. The usage of a pod is the
+// sum of the usage of its containers.
+func samplePods(dk docker.Client) ([]db.Metrics, map[string]string) {
+	containers, err := dk.List(nil, false)
+	if err != nil {
+		log.WithError(err).Warn("Failed to list containers")
+		return nil, nil
+	}
+
+	podMetrics := map[string]db.Metrics{}
+	podHostnames := map[string]string{}
+	for _, dkc := range containers {
+		podName := dkc.Labels[kubePodNameLabel]
+		if podName == "" {
+			continue
+		}
+
+		// The pod infrastructure container is the one that's given the
+		// pod's hostname.
+		if dkc.Labels[kubeContainerNameLabel] == "POD" {
+			podHostnames[podName] = dkc.Hostname
+		}
+
+		stats, err := dk.ContainerStats(dkc.ID)
+		if err != nil {
+			c.Inc("Container stats error")
+			log.WithError(err).WithField("container", dkc.ID).Debug(
+				"Failed to get container stats")
+			continue
+		}
+
+		m := podMetrics[podName]
+		m.PodName = podName
+		m.CPUPercent += stats.CPUPercent
+		m.MemoryUsed += stats.MemoryUsage
+		m.NetworkRx += stats.NetworkRx
+		m.NetworkTx += stats.NetworkTx
+
+		// The pod infrastructure container owns the network namespace of
+		// the pod, but doesn't have the memory limit of the pod.
+		if dkc.Labels[kubeContainerNameLabel] != "POD" &&
+			stats.MemoryLimit > m.MemoryTotal {
+			m.MemoryTotal = stats.MemoryLimit
+		}
+		podMetrics[podName] = m
+	}
+
+	var pods []db.Metrics
+	for _, m := range podMetrics {
+		pods = append(pods, m)
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].PodName < pods[j].PodName
+	})
+	return pods, podHostnames
+}
+
+// customEndpoints returns the URLs of the HTTP autoscaling endpoints of the
+// replicas running on the machine, keyed by hostname.
+func customEndpoints(conn db.Conn) map[string]string {
+	endpoints := map[string]string{}
+	conn.Txn(db.BlueprintTable, db.ContainerTable).Run(func(view db.Database) error {
+		bp, err := view.GetBlueprint()
+		if err != nil {
+			return err
+		}
+
+		autoscaled := map[string]blueprint.Autoscale{}
+		for _, c := range bp.Blueprint.Containers {
+			if c.Autoscale != nil &&
+				c.Autoscale.Metric == blueprint.AutoscaleHTTP {
+				autoscaled[c.Hostname] = *c.Autoscale
+			}
+		}
+
+		for _, dbc := range view.SelectFromContainer(nil) {
+			autoscale, ok := autoscaled[dbc.ReplicaSet]
+			if !ok || dbc.IP == "" {
+				continue
+			}
+			endpoints[dbc.Hostname] = fmt.Sprintf("http://%s:%d%s",
+				dbc.IP, autoscale.Port, autoscale.Path)
+		}
+		return nil
+	})
+	return endpoints
+}
+
+var scrape = scrapeImpl
+
+// The longest that scraping an autoscaling endpoint may take, so that an
+// unresponsive replica doesn't delay the samples of the other pods.
+const scrapeTimeout = 2 * time.Second
+
+var scrapeClient = &http.Client{Timeout: scrapeTimeout}
+
+// scrapeImpl returns the number served at the given URL.
+func scrapeImpl(url string) (float64, error) {
+	resp, err := scrapeClient.Get(url)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	// The body should only contain a number, so there's no need to read
+	// more than a small amount of it.
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 64))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(strings.TrimSpace(string(body)), 64)
+}
+
+// cpuTimes is the cumulative time the machine's CPUs have spent in total, and
+// idle, measured in USER_HZ.
+type cpuTimes struct {
+	total, idle uint64
+}
+
+func readCPUTimes() (cpuTimes, error) {
+	stat, err := util.ReadFile("/proc/stat")
+	if err != nil {
+		return cpuTimes{}, err
+	}
+
+	// The first line contains the aggregate times of all CPUs:
+	// cpu user nice system idle iowait irq softirq steal guest guest_nice
+	line := strings.SplitN(stat, "\n", 2)[0]
+	fields := strings.Fields(line)
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return cpuTimes{}, fmt.Errorf("malformed /proc/stat: %q", line)
+	}
+
+	var times cpuTimes
+	for i, field := range fields[1:] {
+		// The guest times are already included in the user times.
+		if i >= 8 {
+			break
+		}
+
+		val, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return cpuTimes{}, fmt.Errorf("malformed /proc/stat: %s", err)
+		}
+
+		times.total += val
+		// Both the idle and iowait times are counted as idle.
+		if i == 3 || i == 4 {
+			times.idle += val
+		}
+	}
+	return times, nil
+}
+
+// readMemory returns the total and used memory of the machine in bytes.
+func readMemory() (total, used uint64, err error) {
+	meminfo, err := util.ReadFile("/proc/meminfo")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	values := map[string]uint64{}
+	scanner := bufio.NewScanner(strings.NewReader(meminfo))
+	for scanner.Scan() {
+		// Lines are of the form "MemTotal:       16390216 kB".
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+
+		val, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		values[strings.TrimSuffix(fields[0], ":")] = val * 1024
+	}
+
+	total, ok := values["MemTotal"]
+	if !ok {
+		return 0, 0, errors.New("MemTotal missing from /proc/meminfo")
+	}
+
+	available, ok := values["MemAvailable"]
+	if !ok {
+		available = values["MemFree"] + values["Buffers"] + values["Cached"]
+	}
+
+	if available > total {
+		available = total
+	}
+	return total, total - available, nil
+}
+
+var diskUsage = diskUsageImpl
+
+// diskUsageImpl returns the total and used bytes of the filesystem containing
+// `path`.
+func diskUsageImpl(path string) (total, used uint64, err error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, 0, err
+	}
+
+	total = stat.Blocks * uint64(stat.Bsize)
+	free := stat.Bfree * uint64(stat.Bsize)
+	return total, total - free, nil
+}
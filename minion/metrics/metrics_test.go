@@ -0,0 +1,173 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/db"
+	"github.com/kelda/kelda/minion/docker"
+	"github.com/kelda/kelda/util"
+
+	dkc "github.com/fsouza/go-dockerclient"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+const meminfo = `MemTotal:        4000 kB
+MemFree:         1000 kB
+MemAvailable:    3000 kB
+Buffers:          500 kB
+`
+
+func TestRunOnce(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+	util.WriteFile("/proc/stat", []byte(
+		"cpu  100 0 100 700 100 0 0 0 0 0\ncpu0 100 0 100 700 100 0 0 0 0 0\n"),
+		0644)
+	util.WriteFile("/proc/meminfo", []byte(meminfo), 0644)
+	diskUsage = func(path string) (uint64, uint64, error) {
+		assert.Equal(t, "/", path)
+		return 100, 40, nil
+	}
+
+	md, dk := docker.NewMock()
+	appID, _ := dk.Run(docker.RunOptions{Name: "app", Image: "app",
+		Labels: map[string]string{
+			kubePodNameLabel:       "pod",
+			kubeContainerNameLabel: "app",
+		}})
+	pauseID, _ := dk.Run(docker.RunOptions{Name: "pause", Image: "pause",
+		Hostname: "web-0",
+		Labels: map[string]string{
+			kubePodNameLabel:       "pod",
+			kubeContainerNameLabel: "POD",
+		}})
+	dk.Run(docker.RunOptions{Name: "etcd", Image: "etcd"})
+
+	var appStats, pauseStats dkc.Stats
+	appStats.CPUStats.CPUUsage.TotalUsage = 300
+	appStats.CPUStats.CPUUsage.PercpuUsage = []uint64{150, 150}
+	appStats.CPUStats.SystemCPUUsage = 1000
+	appStats.PreCPUStats.CPUUsage.TotalUsage = 200
+	appStats.PreCPUStats.SystemCPUUsage = 600
+	appStats.MemoryStats.Usage = 10
+	appStats.MemoryStats.Limit = 100
+	pauseStats.MemoryStats.Usage = 1
+	pauseStats.MemoryStats.Limit = 1000
+	pauseStats.Networks = map[string]dkc.NetworkStats{
+		"eth0": {RxBytes: 5, TxBytes: 6},
+	}
+	md.ContainerStats[appID] = appStats
+	md.ContainerStats[pauseID] = pauseStats
+
+	// The pod is a replica of a container that's autoscaled by an HTTP
+	// metric.
+	conn := db.New()
+	conn.Txn(db.BlueprintTable, db.ContainerTable).Run(func(view db.Database) error {
+		bp := view.InsertBlueprint()
+		bp.Blueprint.Containers = []blueprint.Container{{
+			Hostname: "web",
+			Autoscale: &blueprint.Autoscale{Metric: blueprint.AutoscaleHTTP,
+				Port: 9000, Path: "/load"},
+		}}
+		view.Commit(bp)
+
+		dbc := view.InsertContainer()
+		dbc.Hostname = "web-0"
+		dbc.ReplicaSet = "web"
+		dbc.IP = "10.0.0.5"
+		view.Commit(dbc)
+		return nil
+	})
+	scrape = func(url string) (float64, error) {
+		assert.Equal(t, "http://10.0.0.5:9000/load", url)
+		return 7, nil
+	}
+
+	prevCPU := runOnce(conn, dk, cpuTimes{})
+	assert.Equal(t, cpuTimes{total: 1000, idle: 800}, prevCPU)
+
+	metrics := conn.SelectFromMetrics(nil)
+	assert.Len(t, metrics, 2)
+	for i := range metrics {
+		assert.False(t, metrics[i].Time.IsZero())
+		metrics[i].ID = 0
+		metrics[i].Time = metrics[0].Time
+	}
+
+	sampleTime := metrics[0].Time
+	assert.Equal(t, []db.Metrics{
+		{
+			Time:        sampleTime,
+			MemoryUsed:  1000 * 1024,
+			MemoryTotal: 4000 * 1024,
+			DiskUsed:    40,
+			DiskTotal:   100,
+		},
+		{
+			PodName:     "pod",
+			Time:        sampleTime,
+			CPUPercent:  50,
+			MemoryUsed:  11,
+			MemoryTotal: 100,
+			NetworkRx:   5,
+			NetworkTx:   6,
+			Custom:      7,
+			HasCustom:   true,
+		},
+	}, metrics)
+
+	// The machine's CPU usage should be computed from the previous sample.
+	util.WriteFile("/proc/stat", []byte("cpu  200 0 200 1400 200 0 0 0 0 0\n"),
+		0644)
+	runOnce(conn, dk, prevCPU)
+	machine := conn.SelectFromMetrics(func(m db.Metrics) bool {
+		return m.PodName == ""
+	})
+	assert.Len(t, machine, 1)
+	assert.InDelta(t, 20.0, machine[0].CPUPercent, 0.001)
+}
+
+func TestReadErrors(t *testing.T) {
+	util.AppFs = afero.NewMemMapFs()
+	_, _, err := sampleMachine(cpuTimes{})
+	assert.Error(t, err)
+
+	util.WriteFile("/proc/stat", []byte("intr 1 2 3\n"), 0644)
+	_, err = readCPUTimes()
+	assert.EqualError(t, err, `malformed /proc/stat: "intr 1 2 3"`)
+
+	util.WriteFile("/proc/meminfo", []byte("MemFree: 10 kB\n"), 0644)
+	_, _, err = readMemory()
+	assert.EqualError(t, err, "MemTotal missing from /proc/meminfo")
+}
+
+func TestScrape(t *testing.T) {
+	t.Parallel()
+
+	var body string
+	var status int
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/load", r.URL.Path)
+			w.WriteHeader(status)
+			fmt.Fprint(w, body)
+		}))
+	defer server.Close()
+
+	body, status = "12.5\n", http.StatusOK
+	value, err := scrapeImpl(server.URL + "/load")
+	assert.NoError(t, err)
+	assert.Equal(t, 12.5, value)
+
+	body = "load: 12"
+	_, err = scrapeImpl(server.URL + "/load")
+	assert.Error(t, err)
+
+	body, status = "12", http.StatusNotFound
+	_, err = scrapeImpl(server.URL + "/load")
+	assert.EqualError(t, err, "unexpected status: 404 Not Found")
+}
@@ -204,16 +204,21 @@ func (table *dnsTable) genResponse(req *dns.Msg) *dns.Msg {
 
 func (table *dnsTable) lookupA(name string) []net.IP {
 	name = strings.TrimRight(strings.ToLower(name), ".")
+
+	// Most internal hostnames don't contain dots, but the hostnames of the
+	// instances of daemon containers do, e.g. "agent.10-0-0-2".
+	table.recordLock.Lock()
+	ip := table.records[name]
+	table.recordLock.Unlock()
+	if ip != nil {
+		dnsC.Inc("Lookup Internal")
+		return []net.IP{ip}
+	}
+
 	isInternalHostname := strings.Count(name, ".") == 0
 	if isInternalHostname {
 		dnsC.Inc("Lookup Internal")
-		table.recordLock.Lock()
-		ip := table.records[name]
-		table.recordLock.Unlock()
-		if ip == nil {
-			return nil
-		}
-		return []net.IP{ip}
+		return nil
 	}
 
 	dnsC.Inc("Lookup External")
@@ -82,13 +82,20 @@ func TestLookupA(t *testing.T) {
 	t.Parallel()
 
 	table := makeTable(map[string]net.IP{
-		"a": net.IPv4(1, 2, 3, 4),
+		"a":              net.IPv4(1, 2, 3, 4),
+		"agent.10-0-0-2": net.IPv4(5, 6, 7, 8),
 	})
 
 	assert.Empty(t, table.lookupA("bad"))
 	assert.Equal(t, []net.IP{net.IPv4(1, 2, 3, 4)}, table.lookupA("a"))
 	assert.Equal(t, []net.IP{net.IPv4(1, 2, 3, 4)}, table.lookupA("A"))
 
+	// The hostnames of daemon container instances contain dots, but
+	// shouldn't be looked up externally.
+	lookupHost = func(string) ([]string, error) { return nil, assert.AnError }
+	assert.Equal(t, []net.IP{net.IPv4(5, 6, 7, 8)},
+		table.lookupA("agent.10-0-0-2."))
+
 	lookupHost = func(string) ([]string, error) { return nil, assert.AnError }
 	assert.Empty(t, table.lookupA("kelda.io."))
 
@@ -9,6 +9,8 @@ It is generated from these files:
 
 It has these top-level messages:
 	MinionConfig
+	MinionMetrics
+	Metrics
 	Reply
 	Request
 */
@@ -69,6 +71,8 @@ type MinionConfig struct {
 	FloatingIP     string            `protobuf:"bytes,8,opt,name=FloatingIP" json:"FloatingIP,omitempty"`
 	EtcdMembers    []string          `protobuf:"bytes,9,rep,name=EtcdMembers" json:"EtcdMembers,omitempty"`
 	AuthorizedKeys []string          `protobuf:"bytes,10,rep,name=AuthorizedKeys" json:"AuthorizedKeys,omitempty"`
+	Labels         map[string]string `protobuf:"bytes,11,rep,name=Labels" json:"Labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Draining       bool              `protobuf:"varint,12,opt,name=Draining" json:"Draining,omitempty"`
 }
 
 func (m *MinionConfig) Reset()                    { *m = MinionConfig{} }
@@ -146,13 +150,139 @@ func (m *MinionConfig) GetAuthorizedKeys() []string {
 	return nil
 }
 
+func (m *MinionConfig) GetLabels() map[string]string {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
+func (m *MinionConfig) GetDraining() bool {
+	if m != nil {
+		return m.Draining
+	}
+	return false
+}
+
+type MinionMetrics struct {
+	Metrics []*Metrics `protobuf:"bytes,1,rep,name=Metrics" json:"Metrics,omitempty"`
+}
+
+func (m *MinionMetrics) Reset()                    { *m = MinionMetrics{} }
+func (m *MinionMetrics) String() string            { return proto.CompactTextString(m) }
+func (*MinionMetrics) ProtoMessage()               {}
+func (*MinionMetrics) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *MinionMetrics) GetMetrics() []*Metrics {
+	if m != nil {
+		return m.Metrics
+	}
+	return nil
+}
+
+type Metrics struct {
+	PodName     string  `protobuf:"bytes,1,opt,name=PodName" json:"PodName,omitempty"`
+	Time        int64   `protobuf:"varint,2,opt,name=Time" json:"Time,omitempty"`
+	CPUPercent  float64 `protobuf:"fixed64,3,opt,name=CPUPercent" json:"CPUPercent,omitempty"`
+	MemoryUsed  uint64  `protobuf:"varint,4,opt,name=MemoryUsed" json:"MemoryUsed,omitempty"`
+	MemoryTotal uint64  `protobuf:"varint,5,opt,name=MemoryTotal" json:"MemoryTotal,omitempty"`
+	DiskUsed    uint64  `protobuf:"varint,6,opt,name=DiskUsed" json:"DiskUsed,omitempty"`
+	DiskTotal   uint64  `protobuf:"varint,7,opt,name=DiskTotal" json:"DiskTotal,omitempty"`
+	NetworkRx   uint64  `protobuf:"varint,8,opt,name=NetworkRx" json:"NetworkRx,omitempty"`
+	NetworkTx   uint64  `protobuf:"varint,9,opt,name=NetworkTx" json:"NetworkTx,omitempty"`
+	Custom      float64 `protobuf:"fixed64,10,opt,name=Custom" json:"Custom,omitempty"`
+	HasCustom   bool    `protobuf:"varint,11,opt,name=HasCustom" json:"HasCustom,omitempty"`
+}
+
+func (m *Metrics) Reset()                    { *m = Metrics{} }
+func (m *Metrics) String() string            { return proto.CompactTextString(m) }
+func (*Metrics) ProtoMessage()               {}
+func (*Metrics) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *Metrics) GetPodName() string {
+	if m != nil {
+		return m.PodName
+	}
+	return ""
+}
+
+func (m *Metrics) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *Metrics) GetCPUPercent() float64 {
+	if m != nil {
+		return m.CPUPercent
+	}
+	return 0
+}
+
+func (m *Metrics) GetMemoryUsed() uint64 {
+	if m != nil {
+		return m.MemoryUsed
+	}
+	return 0
+}
+
+func (m *Metrics) GetMemoryTotal() uint64 {
+	if m != nil {
+		return m.MemoryTotal
+	}
+	return 0
+}
+
+func (m *Metrics) GetDiskUsed() uint64 {
+	if m != nil {
+		return m.DiskUsed
+	}
+	return 0
+}
+
+func (m *Metrics) GetDiskTotal() uint64 {
+	if m != nil {
+		return m.DiskTotal
+	}
+	return 0
+}
+
+func (m *Metrics) GetNetworkRx() uint64 {
+	if m != nil {
+		return m.NetworkRx
+	}
+	return 0
+}
+
+func (m *Metrics) GetNetworkTx() uint64 {
+	if m != nil {
+		return m.NetworkTx
+	}
+	return 0
+}
+
+func (m *Metrics) GetCustom() float64 {
+	if m != nil {
+		return m.Custom
+	}
+	return 0
+}
+
+func (m *Metrics) GetHasCustom() bool {
+	if m != nil {
+		return m.HasCustom
+	}
+	return false
+}
+
 type Reply struct {
 }
 
 func (m *Reply) Reset()                    { *m = Reply{} }
 func (m *Reply) String() string            { return proto.CompactTextString(m) }
 func (*Reply) ProtoMessage()               {}
-func (*Reply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+func (*Reply) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
 
 type Request struct {
 }
@@ -160,10 +290,12 @@ type Request struct {
 func (m *Request) Reset()                    { *m = Request{} }
 func (m *Request) String() string            { return proto.CompactTextString(m) }
 func (*Request) ProtoMessage()               {}
-func (*Request) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+func (*Request) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
 
 func init() {
 	proto.RegisterType((*MinionConfig)(nil), "MinionConfig")
+	proto.RegisterType((*MinionMetrics)(nil), "MinionMetrics")
+	proto.RegisterType((*Metrics)(nil), "Metrics")
 	proto.RegisterType((*Reply)(nil), "Reply")
 	proto.RegisterType((*Request)(nil), "Request")
 	proto.RegisterEnum("MinionConfig_Role", MinionConfig_Role_name, MinionConfig_Role_value)
@@ -182,6 +314,7 @@ const _ = grpc.SupportPackageIsVersion4
 type MinionClient interface {
 	SetMinionConfig(ctx context.Context, in *MinionConfig, opts ...grpc.CallOption) (*Reply, error)
 	GetMinionConfig(ctx context.Context, in *Request, opts ...grpc.CallOption) (*MinionConfig, error)
+	GetMinionMetrics(ctx context.Context, in *Request, opts ...grpc.CallOption) (*MinionMetrics, error)
 }
 
 type minionClient struct {
@@ -210,11 +343,21 @@ func (c *minionClient) GetMinionConfig(ctx context.Context, in *Request, opts ..
 	return out, nil
 }
 
+func (c *minionClient) GetMinionMetrics(ctx context.Context, in *Request, opts ...grpc.CallOption) (*MinionMetrics, error) {
+	out := new(MinionMetrics)
+	err := grpc.Invoke(ctx, "/Minion/GetMinionMetrics", in, out, c.cc, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // Server API for Minion service
 
 type MinionServer interface {
 	SetMinionConfig(context.Context, *MinionConfig) (*Reply, error)
 	GetMinionConfig(context.Context, *Request) (*MinionConfig, error)
+	GetMinionMetrics(context.Context, *Request) (*MinionMetrics, error)
 }
 
 func RegisterMinionServer(s *grpc.Server, srv MinionServer) {
@@ -257,6 +400,24 @@ func _Minion_GetMinionConfig_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Minion_GetMinionMetrics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Request)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MinionServer).GetMinionMetrics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Minion/GetMinionMetrics",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MinionServer).GetMinionMetrics(ctx, req.(*Request))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Minion_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "Minion",
 	HandlerType: (*MinionServer)(nil),
@@ -269,6 +430,10 @@ var _Minion_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetMinionConfig",
 			Handler:    _Minion_GetMinionConfig_Handler,
 		},
+		{
+			MethodName: "GetMinionMetrics",
+			Handler:    _Minion_GetMinionMetrics_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "minion/pb/pb.proto",
@@ -277,26 +442,42 @@ var _Minion_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("minion/pb/pb.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 332 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x5c, 0x91, 0x51, 0x8b, 0xda, 0x40,
-	0x10, 0xc7, 0x4d, 0x8c, 0x31, 0x99, 0xb6, 0x2a, 0xf3, 0x50, 0x16, 0x29, 0x25, 0xe4, 0x41, 0x42,
-	0x29, 0x11, 0xec, 0x27, 0xb0, 0x35, 0x3d, 0x82, 0xa8, 0x61, 0x3d, 0xb8, 0x67, 0x73, 0xce, 0x79,
-	0x0b, 0x31, 0x9b, 0x5b, 0x57, 0x41, 0x3f, 0xec, 0x7d, 0x96, 0xc3, 0x55, 0x3c, 0xf5, 0x6d, 0xe6,
-	0xf7, 0x9b, 0xff, 0xc2, 0xce, 0x00, 0xae, 0x45, 0x29, 0x64, 0xd9, 0xaf, 0xf2, 0x7e, 0x95, 0xc7,
-	0x95, 0x92, 0x5a, 0x86, 0xef, 0x36, 0x7c, 0x9d, 0x18, 0xfc, 0x4f, 0x96, 0x2f, 0x62, 0x85, 0x2d,
-	0xb0, 0xd3, 0x11, 0xb3, 0x02, 0x2b, 0xf2, 0xb9, 0x9d, 0x8e, 0xb0, 0x07, 0x8e, 0x92, 0x05, 0x31,
-	0x3b, 0xb0, 0xa2, 0xd6, 0x00, 0xe3, 0xeb, 0xe1, 0x98, 0xcb, 0x82, 0xb8, 0xf1, 0xf8, 0x03, 0xfc,
-	0x4c, 0x89, 0xdd, 0x42, 0x53, 0x9a, 0xb1, 0xba, 0x89, 0x7f, 0x82, 0xa3, 0xfd, 0x5b, 0x6c, 0xa9,
-	0x52, 0xa2, 0xd4, 0xcc, 0x39, 0xd9, 0x0b, 0xc0, 0x2e, 0x78, 0x99, 0x92, 0x3b, 0xb1, 0x24, 0xc5,
-	0x1a, 0x46, 0x5e, 0x7a, 0x44, 0x70, 0xe6, 0xe2, 0x40, 0xcc, 0x35, 0xdc, 0xd4, 0xf8, 0x1d, 0x5c,
-	0x4e, 0x2b, 0x21, 0x4b, 0xd6, 0x34, 0xf4, 0xdc, 0xe1, 0x4f, 0x80, 0xff, 0x85, 0x5c, 0x68, 0x51,
-	0xae, 0xd2, 0x8c, 0x79, 0xc6, 0x5d, 0x11, 0x0c, 0xe0, 0x4b, 0xa2, 0x9f, 0x97, 0x13, 0x5a, 0xe7,
-	0xa4, 0x36, 0xcc, 0x0f, 0xea, 0x91, 0xcf, 0xaf, 0x11, 0xf6, 0xa0, 0x35, 0xdc, 0xea, 0x57, 0xa9,
-	0xc4, 0x81, 0x96, 0x63, 0xda, 0x6f, 0x18, 0x98, 0xa1, 0x3b, 0x1a, 0x46, 0xe0, 0x1c, 0xff, 0x8e,
-	0x1e, 0x38, 0xd3, 0xd9, 0x34, 0xe9, 0xd4, 0x10, 0xc0, 0x7d, 0x9a, 0xf1, 0x71, 0xc2, 0x3b, 0xd6,
-	0xb1, 0x9e, 0x0c, 0xe7, 0x8f, 0x09, 0xef, 0xd8, 0x61, 0x13, 0x1a, 0x9c, 0xaa, 0x62, 0x1f, 0xfa,
-	0xd0, 0xe4, 0xf4, 0xb6, 0xa5, 0x8d, 0x1e, 0xe4, 0xe0, 0x9e, 0xd6, 0x88, 0xbf, 0xa0, 0x3d, 0x27,
-	0x7d, 0x73, 0x80, 0x6f, 0x37, 0x2b, 0xee, 0xba, 0xf1, 0x29, 0x5e, 0xc3, 0xdf, 0xd0, 0x7e, 0xb8,
-	0x9b, 0xf5, 0xe2, 0xf3, 0x93, 0xdd, 0xdb, 0x54, 0x58, 0xcb, 0x5d, 0x73, 0xdf, 0x3f, 0x1f, 0x01,
-	0x00, 0x00, 0xff, 0xff, 0x2e, 0xbe, 0xc6, 0x53, 0xf5, 0x01, 0x00, 0x00,
+	// 583 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x5c, 0x94, 0xd1, 0x6e, 0xd3, 0x3e,
+	0x14, 0xc6, 0x9b, 0x36, 0x4b, 0x9b, 0xd3, 0xad, 0xab, 0xac, 0xbf, 0xfe, 0x32, 0x15, 0x42, 0x51,
+	0x2e, 0xa6, 0x08, 0xa1, 0x4c, 0x6c, 0x37, 0xc0, 0xdd, 0xd8, 0x0a, 0x54, 0xa3, 0x5d, 0xe4, 0x75,
+	0xe2, 0x3a, 0x5d, 0x0f, 0xc5, 0x5a, 0x12, 0x17, 0xc7, 0x1d, 0xeb, 0x1e, 0x83, 0x77, 0xe2, 0x5d,
+	0x78, 0x0c, 0x64, 0x27, 0x4b, 0x93, 0xdd, 0x9d, 0xef, 0xfb, 0x9d, 0x53, 0xd9, 0xfe, 0x4e, 0x03,
+	0x24, 0xe5, 0x19, 0x17, 0xd9, 0xf1, 0x7a, 0x71, 0xbc, 0x5e, 0x84, 0x6b, 0x29, 0x94, 0xf0, 0xff,
+	0x76, 0x60, 0x7f, 0x6a, 0xec, 0x73, 0x91, 0x7d, 0xe7, 0x2b, 0x32, 0x80, 0xf6, 0xe4, 0x82, 0x5a,
+	0x9e, 0x15, 0xb8, 0xac, 0x3d, 0xb9, 0x20, 0x47, 0x60, 0x4b, 0x91, 0x20, 0x6d, 0x7b, 0x56, 0x30,
+	0x38, 0x21, 0x61, 0xbd, 0x39, 0x64, 0x22, 0x41, 0x66, 0x38, 0x79, 0x09, 0x6e, 0x24, 0xf9, 0x7d,
+	0xac, 0x70, 0x12, 0xd1, 0x8e, 0x19, 0xdf, 0x19, 0x9a, 0x7e, 0x4c, 0x36, 0xb8, 0x96, 0x3c, 0x53,
+	0xd4, 0x2e, 0x68, 0x65, 0x90, 0x11, 0xf4, 0x22, 0x29, 0xee, 0xf9, 0x12, 0x25, 0xdd, 0x33, 0xb0,
+	0xd2, 0x84, 0x80, 0x7d, 0xcd, 0x1f, 0x91, 0x3a, 0xc6, 0x37, 0x35, 0xf9, 0x1f, 0x1c, 0x86, 0x2b,
+	0x2e, 0x32, 0xda, 0x35, 0x6e, 0xa9, 0xc8, 0x2b, 0x80, 0x4f, 0x89, 0x88, 0x15, 0xcf, 0x56, 0x93,
+	0x88, 0xf6, 0x0c, 0xab, 0x39, 0xc4, 0x83, 0xfe, 0x58, 0xdd, 0x2e, 0xa7, 0x98, 0x2e, 0x50, 0xe6,
+	0xd4, 0xf5, 0x3a, 0x81, 0xcb, 0xea, 0x16, 0x39, 0x82, 0xc1, 0xd9, 0x46, 0xfd, 0x10, 0x92, 0x3f,
+	0xe2, 0xf2, 0x12, 0xb7, 0x39, 0x05, 0xd3, 0xf4, 0xcc, 0x25, 0x6f, 0xc1, 0xf9, 0x1a, 0x2f, 0x30,
+	0xc9, 0x69, 0xdf, 0xeb, 0x04, 0xfd, 0x93, 0x17, 0xcd, 0x77, 0x29, 0xd8, 0x38, 0x53, 0x72, 0xcb,
+	0xca, 0x46, 0x7d, 0xc9, 0x0b, 0x19, 0xf3, 0x8c, 0x67, 0x2b, 0xba, 0xef, 0x59, 0x41, 0x8f, 0x55,
+	0x7a, 0xf4, 0x1e, 0xfa, 0xb5, 0x11, 0x32, 0x84, 0xce, 0x1d, 0x6e, 0xcb, 0x10, 0x74, 0x49, 0xfe,
+	0x83, 0xbd, 0xfb, 0x38, 0xd9, 0x14, 0x31, 0xb8, 0xac, 0x10, 0x1f, 0xda, 0xef, 0x2c, 0x3f, 0x00,
+	0x5b, 0xa7, 0x40, 0x7a, 0x60, 0xcf, 0xae, 0x66, 0xe3, 0x61, 0x8b, 0x00, 0x38, 0xdf, 0xae, 0xd8,
+	0xe5, 0x98, 0x0d, 0x2d, 0x5d, 0x4f, 0xcf, 0xae, 0xe7, 0x63, 0x36, 0x6c, 0xfb, 0xa7, 0x70, 0x50,
+	0x1c, 0x72, 0x8a, 0x4a, 0xf2, 0xdb, 0x9c, 0xf8, 0xd0, 0x2d, 0x4b, 0x6a, 0x99, 0x5b, 0xf4, 0xc2,
+	0x52, 0xb3, 0x27, 0xe0, 0xff, 0x69, 0x57, 0x4d, 0x84, 0x42, 0x37, 0x12, 0xcb, 0x59, 0x9c, 0x62,
+	0x79, 0xb4, 0x27, 0xa9, 0x43, 0x9a, 0xf3, 0xb4, 0x38, 0x5d, 0x87, 0x99, 0x5a, 0x87, 0x71, 0x1e,
+	0xdd, 0x44, 0x28, 0x6f, 0x31, 0x53, 0x66, 0x23, 0x2c, 0x56, 0x73, 0x34, 0x9f, 0x62, 0x2a, 0xe4,
+	0xf6, 0x26, 0xc7, 0xa5, 0xd9, 0x09, 0x9b, 0xd5, 0x1c, 0x1d, 0x56, 0xa1, 0xe6, 0x42, 0xc5, 0x89,
+	0xd9, 0x0b, 0x9b, 0xd5, 0x2d, 0xf3, 0xa2, 0x3c, 0xbf, 0x33, 0xf3, 0x8e, 0xc1, 0x95, 0xd6, 0x0b,
+	0xa7, 0xeb, 0x62, 0xb6, 0x6b, 0xe0, 0xce, 0xd0, 0x74, 0x86, 0xea, 0x97, 0x90, 0x77, 0xec, 0xc1,
+	0xec, 0x89, 0xcd, 0x76, 0x46, 0x8d, 0xce, 0x1f, 0xa8, 0xdb, 0xa0, 0xf3, 0x07, 0xbd, 0x7c, 0xe7,
+	0x9b, 0x5c, 0x89, 0x94, 0x82, 0xb9, 0x53, 0xa9, 0xf4, 0xd4, 0x97, 0x38, 0x2f, 0x51, 0xdf, 0x04,
+	0xbc, 0x33, 0xfc, 0x2e, 0xec, 0x31, 0x5c, 0x27, 0x5b, 0xdf, 0x85, 0x2e, 0xc3, 0x9f, 0x1b, 0xcc,
+	0xd5, 0xc9, 0x6f, 0x0b, 0x9c, 0x22, 0x11, 0xf2, 0x1a, 0x0e, 0xaf, 0x51, 0x35, 0xfe, 0x88, 0x07,
+	0x8d, 0x95, 0x1a, 0x39, 0x61, 0x31, 0xdf, 0x22, 0x6f, 0xe0, 0xf0, 0xf3, 0xb3, 0xde, 0x5e, 0x58,
+	0xfe, 0xe6, 0xa8, 0x39, 0xe5, 0xb7, 0x48, 0x08, 0xc3, 0xaa, 0xfb, 0x29, 0xc8, 0x5d, 0xfb, 0x20,
+	0x6c, 0x10, 0xbf, 0xb5, 0x70, 0xcc, 0x77, 0xe1, 0xf4, 0x5f, 0x00, 0x00, 0x00, 0xff, 0xff, 0xb7,
+	0x05, 0xe0, 0xe3, 0x2d, 0x04, 0x00, 0x00,
 }
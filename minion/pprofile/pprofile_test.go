@@ -0,0 +1,34 @@
+package pprofile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollect(t *testing.T) {
+	for _, profileType := range []string{CPUProfile, HeapProfile,
+		GoroutineProfile} {
+		prof, err := Collect(profileType, 10*time.Millisecond)
+		assert.NoError(t, err, profileType)
+
+		// Profiles are gzipped protobufs.
+		reader, err := gzip.NewReader(bytes.NewReader(prof))
+		assert.NoError(t, err, profileType)
+		_, err = ioutil.ReadAll(reader)
+		assert.NoError(t, err, profileType)
+	}
+
+	_, err := Collect("block", time.Second)
+	assert.EqualError(t, err, "unknown profile type: block")
+
+	_, err = Collect(CPUProfile, 0)
+	assert.EqualError(t, err, "CPU profile duration must be between 0 and 5m0s")
+
+	_, err = Collect(CPUProfile, time.Hour)
+	assert.EqualError(t, err, "CPU profile duration must be between 0 and 5m0s")
+}
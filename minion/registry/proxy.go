@@ -0,0 +1,98 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/kelda/kelda/db"
+)
+
+// The address the proxy listens on. It only accepts local connections since
+// it's only used by the Docker daemon on the same machine.
+const proxyAddr = "127.0.0.1:5000"
+
+// runProxy serves a read-only proxy to the masters' registries, so that workers
+// can pull images even if some of the masters are down.
+func runProxy(conn db.Conn) {
+	handler := newProxy(func() []string {
+		var registries []string
+		for _, etcdRow := range conn.SelectFromEtcd(nil) {
+			for _, ip := range etcdRow.EtcdIPs {
+				registries = append(registries,
+					fmt.Sprintf("%s:%d", ip, registryPort))
+			}
+		}
+		return registries
+	})
+
+	for {
+		err := http.ListenAndServe(proxyAddr, handler)
+		log.WithError(err).Error("Registry proxy failed")
+		time.Sleep(10 * time.Second)
+	}
+}
+
+// newProxy returns a handler that forwards read requests to the registries
+// returned by `getRegistries`. Each request is tried against the registries in
+// order until one of them has the requested object.
+func newProxy(getRegistries func() []string) http.Handler {
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = "http"
+		},
+		Transport: failoverTransport{getRegistries, http.DefaultTransport},
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" && r.Method != "HEAD" {
+			http.Error(w, "the registry proxy is read-only",
+				http.StatusMethodNotAllowed)
+			return
+		}
+		proxy.ServeHTTP(w, r)
+	})
+}
+
+type failoverTransport struct {
+	getRegistries func() []string
+	transport     http.RoundTripper
+}
+
+func (t failoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	registries := t.getRegistries()
+	if len(registries) == 0 {
+		return nil, errors.New("no registries")
+	}
+
+	var resp *http.Response
+	var err error
+	for i, registry := range registries {
+		tryReq := new(http.Request)
+		*tryReq = *req
+		tryURL := *req.URL
+		tryURL.Host = registry
+		tryReq.URL = &tryURL
+		tryReq.Host = registry
+
+		resp, err = t.transport.RoundTrip(tryReq)
+		if err != nil {
+			log.WithError(err).WithField("registry", registry).
+				Debug("Failed to proxy registry request")
+			continue
+		}
+
+		// Another registry may have the object if this one is still
+		// being replicated to.
+		if resp.StatusCode == http.StatusNotFound && i != len(registries)-1 {
+			resp.Body.Close()
+			continue
+		}
+		return resp, nil
+	}
+	return resp, err
+}
@@ -0,0 +1,66 @@
+package registry
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProxy(t *testing.T) {
+	t.Parallel()
+
+	// The first registry is down, and the second hasn't had the image
+	// replicated to it yet.
+	down := httptest.NewServer(http.NotFoundHandler())
+	downAddr := strings.TrimPrefix(down.URL, "http://")
+	down.Close()
+
+	empty := httptest.NewServer(newFakeRegistry())
+	defer empty.Close()
+
+	full := newFakeRegistry()
+	full.manifests["repo:tag"] = "manifest"
+	fullServer := httptest.NewServer(full)
+	defer fullServer.Close()
+
+	registries := []string{
+		downAddr,
+		strings.TrimPrefix(empty.URL, "http://"),
+		strings.TrimPrefix(fullServer.URL, "http://"),
+	}
+	proxy := httptest.NewServer(newProxy(func() []string {
+		return registries
+	}))
+	defer proxy.Close()
+
+	resp, err := http.Get(proxy.URL + "/v2/repo/manifests/tag")
+	assert.NoError(t, err)
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "manifest", string(body))
+
+	// Objects that don't exist in any registry should return the last
+	// registry's response.
+	resp, err = http.Get(proxy.URL + "/v2/repo/manifests/missing")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// The proxy shouldn't allow writes.
+	resp, err = http.Post(proxy.URL+"/v2/repo/blobs/uploads/", "", nil)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	// Requests should fail if there aren't any registries.
+	registries = nil
+	resp, err = http.Get(proxy.URL + "/v2/repo/manifests/tag")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+}
@@ -0,0 +1,202 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/kelda/kelda/db"
+)
+
+// How long a tag in the registries must be unused by the Image table before
+// it's deleted. This gives a new leader time to populate the Image table.
+var pruneGracePeriod = time.Hour
+
+// The prefix of the tags created by Kelda. Other tags are never deleted.
+const tagPrefix = "kelda-"
+
+// A pruner deletes the images in the registries that aren't referenced by the
+// Image table. Deleting the manifests doesn't free the space used by the image
+// layers -- the registries' blobs are freed by the minion's garbage collector.
+type pruner struct {
+	// The time at which each unreferenced image was first seen, keyed by the
+	// registry and image.
+	unreferencedSince map[string]time.Time
+}
+
+func newPruner() pruner {
+	return pruner{unreferencedSince: map[string]time.Time{}}
+}
+
+// prune deletes the images that have been unreferenced for longer than the
+// grace period from the registries of all the masters. It should only be run
+// on the leader, because only the leader's Image table is populated.
+func (p pruner) prune(conn db.Conn) {
+	referenced := map[string]struct{}{}
+	var etcdIPs []string
+	var hasBlueprint bool
+	conn.Txn(db.BlueprintTable, db.ImageTable, db.EtcdTable).Run(
+		func(view db.Database) error {
+			_, err := view.GetBlueprint()
+			hasBlueprint = err == nil
+			for _, img := range view.SelectFromImage(nil) {
+				if img.Dockerfile != "" {
+					repo, tag := registryRef(img)
+					referenced[repo+":"+tag] = struct{}{}
+				}
+			}
+			if etcdRow, err := view.GetEtcd(); err == nil {
+				etcdIPs = etcdRow.EtcdIPs
+			}
+			return nil
+		})
+
+	// Without a blueprint, the Image table is empty because the engine hasn't
+	// run yet, rather than because the images are unused.
+	if !hasBlueprint {
+		return
+	}
+
+	seen := map[string]struct{}{}
+	myIP := conn.MinionSelf().PrivateIP
+	for _, ip := range etcdIPs {
+		registry := fmt.Sprintf("%s:%d", ip, registryPort)
+		if ip == myIP {
+			registry = localRegistry
+		}
+
+		err := p.pruneRegistry(registry, referenced, seen)
+		if err != nil {
+			log.WithError(err).WithField("registry", registry).
+				Warn("Failed to prune registry")
+		}
+	}
+
+	// Forget about images that have been deleted, or are referenced again.
+	for key := range p.unreferencedSince {
+		if _, ok := seen[key]; !ok {
+			delete(p.unreferencedSince, key)
+		}
+	}
+}
+
+// pruneRegistry deletes the images in `registry` that have been unreferenced
+// for longer than the grace period. The keys of the unreferenced images are
+// added to `seen`.
+func (p pruner) pruneRegistry(registry string, referenced,
+	seen map[string]struct{}) error {
+	repos, err := listRepositories(registry)
+	if err != nil {
+		return err
+	}
+
+	for _, repo := range repos {
+		tags, err := listTags(registry, repo)
+		if err != nil {
+			return err
+		}
+
+		var toDelete []string
+		referencedDigests := map[string]struct{}{}
+		for _, tag := range tags {
+			if !strings.HasPrefix(tag, tagPrefix) {
+				continue
+			}
+
+			if _, ok := referenced[repo+":"+tag]; ok {
+				digest, err := getManifestDigest(registry, repo, tag)
+				if err != nil {
+					return err
+				}
+				referencedDigests[digest] = struct{}{}
+				continue
+			}
+
+			key := registry + "/" + repo + ":" + tag
+			seen[key] = struct{}{}
+			since, ok := p.unreferencedSince[key]
+			if !ok {
+				p.unreferencedSince[key] = time.Now()
+			} else if time.Since(since) >= pruneGracePeriod {
+				toDelete = append(toDelete, tag)
+			}
+		}
+
+		for _, tag := range toDelete {
+			digest, err := getManifestDigest(registry, repo, tag)
+			if err != nil {
+				return err
+			}
+
+			// Deleting a manifest deletes all of the tags that refer to it,
+			// so manifests shared with referenced tags must be kept.
+			if _, ok := referencedDigests[digest]; ok || digest == "" {
+				continue
+			}
+
+			log.WithFields(log.Fields{
+				"image":    repo + ":" + tag,
+				"registry": registry,
+			}).Info("Deleting unused image from registry")
+			if err := deleteManifest(registry, repo, digest); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// listRepositories returns the names of the repositories in `registry`.
+func listRepositories(registry string) ([]string, error) {
+	var catalog struct {
+		Repositories []string `json:"repositories"`
+	}
+	err := getRegistryJSON(fmt.Sprintf("http://%s/v2/_catalog?n=10000",
+		registry), &catalog)
+	return catalog.Repositories, err
+}
+
+// listTags returns the tags of `repo` in `registry`.
+func listTags(registry, repo string) ([]string, error) {
+	var tags struct {
+		Tags []string `json:"tags"`
+	}
+	err := getRegistryJSON(fmt.Sprintf("http://%s/v2/%s/tags/list", registry,
+		repo), &tags)
+	return tags.Tags, err
+}
+
+func getRegistryJSON(url string, result interface{}) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := doRegistryRequest(req, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// deleteManifest deletes the manifest with the given digest from `registry`.
+// The registry must have deletes enabled.
+func deleteManifest(registry, repo, digest string) error {
+	req, err := http.NewRequest("DELETE", manifestURL(registry, repo, digest),
+		nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := doRegistryRequest(req, http.StatusAccepted)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,52 @@
+package registry
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPruneRegistry(t *testing.T) {
+	t.Parallel()
+
+	reg := newFakeRegistry()
+	reg.manifests["app:kelda-used"] = "used"
+	reg.manifests["app:kelda-unused"] = "unused"
+	reg.manifests["app:kelda-shared"] = "used"
+	reg.manifests["app:v1"] = "pushed by the user"
+	server := httptest.NewServer(reg)
+	defer server.Close()
+	addr := strings.TrimPrefix(server.URL, "http://")
+
+	p := newPruner()
+	referenced := map[string]struct{}{"app:kelda-used": {}}
+
+	// Unreferenced images shouldn't be deleted until the grace period has
+	// passed.
+	seen := map[string]struct{}{}
+	assert.NoError(t, p.pruneRegistry(addr, referenced, seen))
+	assert.Len(t, reg.manifests, 4)
+	assert.Equal(t, map[string]struct{}{
+		addr + "/app:kelda-unused": {},
+		addr + "/app:kelda-shared": {},
+	}, seen)
+
+	for key := range p.unreferencedSince {
+		p.unreferencedSince[key] = time.Now().Add(-2 * pruneGracePeriod)
+	}
+	assert.NoError(t, p.pruneRegistry(addr, referenced, seen))
+
+	// The shared tag can't be deleted without deleting the used tag, and
+	// tags that weren't created by Kelda should be left alone.
+	assert.Equal(t, map[string]string{
+		"app:kelda-used":   "used",
+		"app:kelda-shared": "used",
+		"app:v1":           "pushed by the user",
+	}, reg.manifests)
+
+	server.Close()
+	assert.Error(t, p.pruneRegistry(addr, referenced, seen))
+}
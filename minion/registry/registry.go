@@ -1,15 +1,23 @@
 package registry
 
 import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strings"
 	"time"
 
+	dkc "github.com/fsouza/go-dockerclient"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/kelda/kelda/blueprint"
 	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/minion/docker"
+	"github.com/kelda/kelda/minion/kubernetes"
 	"sync"
 )
 
@@ -23,22 +31,61 @@ blueprint, and writes them to the Image table.
 their image ID with the ID of the built image.
 3) The scheduler schedules containers for which the image has been built.
 When scheduling Containers with custom images, it modifies the image to
-be pointed at the registry running on the leader. A side effect of this is that
-if the leader dies, the scheduler updates the image names in etcd, and the workers
-restart containers running the custom image.
-4) The workers pull and run the image just like any other image.
+be pointed at the image's digest in the registry at localhost:5000.
+4) The workers pull and run the image just like any other image. Each worker
+runs a read-only proxy at localhost:5000 that forwards requests to the
+registries running on the masters.
+
+The leader copies each built image into the registries of the other masters.
+Images are tagged with a hash of their build inputs, so if the leader dies, the
+new leader finds the images in its own registry rather than rebuilding them.
+Because the replicated images have the same digests, the containers using them
+aren't restarted. Once an image is no longer in the Image table, the leader
+deletes it from the registries after a grace period.
+
+If the blueprint specifies credentials for private registries, they're used to
+pull the base images of the Dockerfiles.
+
+If the blueprint pins image digests, the engine also writes the public images
+used by containers to the Image table, and the registry submodule resolves their
+tags to digests. The scheduler then runs the resolved digests rather than the
+tags. If the blueprint watches image digests, the tags are periodically resolved
+again, and containers are redeployed when a tag is updated.
 */
 
-// Run builds Docker images according to the Image table if the minion's Role is
-// Master, and does nothing otherwise.
+// The address of the registry that images are pulled from. Masters run a
+// registry at this address, and workers run a proxy to the masters'
+// registries.
+const localRegistry = "localhost:5000"
+
+// The port that the registries on the masters listen on.
+const registryPort = 5000
+
+// Lock is held while images are built, replicated, and pruned, so that the
+// registry's garbage collector doesn't run at the same time.
+var Lock sync.Mutex
+
+// Run builds, replicates, and resolves the digests of Docker images according
+// to the Image table if the minion's Role is Master, and runs the registry proxy
+// otherwise.
 func Run(conn db.Conn, dk docker.Client) {
 	if conn.MinionSelf().Role != db.Master {
+		runProxy(conn)
 		return
 	}
 
+	go runResolver(conn)
+
 	bootWait()
-	for range conn.TriggerTick(30, db.ImageTable).C {
+	pruner := newPruner()
+	for range conn.TriggerTick(30, db.ImageTable, db.EtcdTable).C {
+		Lock.Lock()
 		syncImages(conn, dk)
+		replicateImages(conn)
+		if conn.EtcdLeader() {
+			pruner.prune(conn)
+		}
+		Lock.Unlock()
 	}
 }
 
@@ -46,18 +93,26 @@ func Run(conn db.Conn, dk docker.Client) {
 // built, and builds them.
 func syncImages(conn db.Conn, dk docker.Client) {
 	var toBuild []db.Image
-	conn.Txn(db.ImageTable).Run(func(view db.Database) error {
+	var registryCreds []blueprint.RegistryCredential
+	conn.Txn(db.ImageTable, db.BlueprintTable).Run(func(view db.Database) error {
 		toBuild = view.SelectFromImage(func(img db.Image) bool {
-			return img.Status != db.Built
+			return img.Dockerfile != "" && img.Status != db.Built
 		})
+		if bp, err := view.GetBlueprint(); err == nil {
+			registryCreds = bp.RegistryCredentials
+		}
 		return nil
 	})
 
+	var auths map[string]dkc.AuthConfiguration
+	if len(toBuild) != 0 && len(registryCreds) != 0 {
+		auths = registryAuths(registryCreds)
+	}
+
 	var wg sync.WaitGroup
 	wg.Add(len(toBuild))
 	sema := make(chan struct{}, 8)
 
-	myIP := conn.MinionSelf().PrivateIP
 	builder := func(img db.Image) {
 		sema <- struct{}{}
 		defer func() {
@@ -66,13 +121,33 @@ func syncImages(conn db.Conn, dk docker.Client) {
 			wg.Done()
 		}()
 
+		// The image may have been built by a previous leader, and replicated
+		// to this master.
+		repo, tag := registryRef(img)
+		digest, err := getManifestDigest(localRegistry, repo, tag)
+		if err != nil {
+			log.WithError(err).WithField("image", img.Name).
+				Debug("Failed to check registry for image")
+		} else if digest != "" {
+			img.RepoDigest = fmt.Sprintf("%s/%s@%s", localRegistry, repo,
+				digest)
+			img.Status = db.Built
+			img.BuildError = ""
+			log.WithField("image", img.Name).Info(
+				"Using image already in registry.")
+			return
+		}
+
 		img.Status = db.Building
 		writeImage(conn, img)
 
 		log.WithField("image", img.Name).Info("Building image...")
-		repoDigest, err := updateRegistry(dk, myIP, img)
+		var buildLog bytes.Buffer
+		repoDigest, err := updateRegistry(dk, img, auths, &buildLog)
+		img.BuildLog = tailLines(buildLog.String(), maxBuildLogLines)
 		if err != nil {
-			img.Status = "" // Unset the building status.
+			img.Status = db.BuildFailed
+			img.BuildError = err.Error()
 
 			log.WithError(err).WithField("image", img.Name).
 				Error("Failed to update registry")
@@ -81,6 +156,7 @@ func syncImages(conn db.Conn, dk docker.Client) {
 
 		img.RepoDigest = repoDigest
 		img.Status = db.Built
+		img.BuildError = ""
 
 		log.WithField("image", img.Name).Info("Built image.")
 	}
@@ -91,17 +167,69 @@ func syncImages(conn db.Conn, dk docker.Client) {
 	wg.Wait()
 }
 
-func updateRegistry(dk docker.Client, registryIP string, img db.Image) (string, error) {
-	registryImg := fmt.Sprintf("%s:5000/%s", registryIP, img.Name)
-	err := dk.Build(registryImg, img.Dockerfile, false)
+// The number of lines of build output saved for each image.
+const maxBuildLogLines = 100
+
+func updateRegistry(dk docker.Client, img db.Image,
+	auths map[string]dkc.AuthConfiguration, buildLog io.Writer) (string, error) {
+	repo, tag := registryRef(img)
+	registryImg := fmt.Sprintf("%s/%s:%s", localRegistry, repo, tag)
+	err := dk.Build(docker.BuildOptions{
+		Name:       registryImg,
+		Dockerfile: img.Dockerfile,
+		Context:    img.Context,
+		BuildArgs:  img.BuildArgs,
+		Target:     img.Target,
+		Auths:      auths,
+		Output:     buildLog,
+	})
 	if err != nil {
 		return "", err
 	}
-	return dk.Push(registryIP+":5000", registryImg)
+	return dk.Push(localRegistry, registryImg)
+}
+
+// registryRef returns the repository and tag that `img` is stored under in the
+// registries. The tag is a hash of the build inputs, so that a new leader can
+// find the images built by the previous leader.
+func registryRef(img db.Image) (repo, tag string) {
+	repo, _ = dkc.ParseRepositoryTag(img.Name)
+	inputs, _ := json.Marshal(struct {
+		Name, Dockerfile, Target string
+		Context                  []byte
+		BuildArgs                map[string]string
+	}{img.Name, img.Dockerfile, img.Target, img.Context, img.BuildArgs})
+	return repo, fmt.Sprintf("kelda-%x", sha1.Sum(inputs))
+}
+
+// tailLines returns the last `n` lines of `str`.
+func tailLines(str string, n int) string {
+	lines := strings.Split(strings.TrimRight(str, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
 }
 
+// registryAuths returns the credentials for the private registries in
+// `creds`. The credentials are stored as secrets in Kubernetes. If the secrets
+// can't be read, images are built without credentials.
+func registryAuths(
+	creds []blueprint.RegistryCredential) map[string]dkc.AuthConfiguration {
+	secretClient, err := newSecretClient()
+	if err != nil {
+		log.WithError(err).Warn("Failed to get Kubernetes secret client. " +
+			"Building images without registry credentials.")
+		return nil
+	}
+	return kubernetes.RegistryAuths(creds, secretClient)
+}
+
+// Stored in a variable so that it can be mocked out by the unit tests.
+var newSecretClient = kubernetes.NewSecretClient
+
 // writeImage updates the attributes of the image committed to the database that
-// has the same Name and Dockerfile.
+// is built from the same inputs.
 func writeImage(conn db.Conn, img db.Image) {
 	err := conn.Txn(db.ImageTable).Run(
 		func(view db.Database) error {
@@ -120,7 +248,7 @@ func writeImage(conn db.Conn, img db.Image) {
 
 func getImageHandle(view db.Database, ref db.Image) (db.Image, error) {
 	matchingImages := view.SelectFromImage(func(img db.Image) bool {
-		return img.Dockerfile == ref.Dockerfile && img.Name == ref.Name
+		return img.SameBuild(ref)
 	})
 	switch len(matchingImages) {
 	case 0:
@@ -135,7 +263,7 @@ func getImageHandle(view db.Database, ref db.Image) (db.Image, error) {
 // bootWait blocks until the registry is ready to be pushed to.
 func bootWait() {
 	for {
-		_, err := http.Get("http://localhost:5000")
+		_, err := http.Get("http://" + localRegistry)
 		if err != nil {
 			log.WithError(err).Debug("Registry not up yet")
 		} else {
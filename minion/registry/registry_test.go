@@ -1,22 +1,28 @@
 package registry
 
 import (
+	"strings"
 	"testing"
 
 	dkc "github.com/fsouza/go-dockerclient"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/kelda/kelda/blueprint"
 	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/minion/docker"
+	"github.com/kelda/kelda/minion/kubernetes"
+	"github.com/kelda/kelda/minion/kubernetes/mocks"
 )
 
 func TestSyncImages(t *testing.T) {
+	defer mockManifestDigests(nil)()
 	md, dk := docker.NewMock()
 	conn := db.New()
 
 	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
 		im := view.InsertImage()
 		im.Name = "image"
+		im.Dockerfile = "FROM alpine"
 		view.Commit(im)
 
 		m := view.InsertMinion()
@@ -26,13 +32,17 @@ func TestSyncImages(t *testing.T) {
 		return nil
 	})
 
-	// Test building an image that fails. The status should not be "built".
+	// Test building an image that fails. The status should not be "built",
+	// and the reason should be saved.
 	md.BuildError = true
+	md.BuildOutput = "Step 1/1 : RUN false\n"
 	syncImages(conn, dk)
 	images := getImages(conn)
 	assert.Len(t, images, 1)
 	assert.Empty(t, images[0].RepoDigest)
-	assert.Empty(t, images[0].Status)
+	assert.Equal(t, db.BuildFailed, images[0].Status)
+	assert.Equal(t, "build error", images[0].BuildError)
+	assert.Equal(t, "Step 1/1 : RUN false", images[0].BuildLog)
 
 	// Test successfully building an image.
 	md.BuildError = false
@@ -42,6 +52,7 @@ func TestSyncImages(t *testing.T) {
 	builtDigest := images[0].RepoDigest
 	assert.NotEmpty(t, builtDigest, "should save repo digest of built image")
 	assert.Equal(t, db.Built, images[0].Status)
+	assert.Empty(t, images[0].BuildError)
 
 	// Test ignoring already-built image.
 	md.ResetBuilt()
@@ -55,18 +66,46 @@ func TestSyncImages(t *testing.T) {
 		"should not attempt to rebuild")
 }
 
+func TestSyncImagesAlreadyInRegistry(t *testing.T) {
+	img := db.Image{Name: "image", Dockerfile: "FROM alpine"}
+	repo, tag := registryRef(img)
+	defer mockManifestDigests(map[string]string{
+		repo + ":" + tag: "sha256:digest",
+	})()
+
+	md, dk := docker.NewMock()
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		im := view.InsertImage()
+		im.Name = img.Name
+		im.Dockerfile = img.Dockerfile
+		view.Commit(im)
+		return nil
+	})
+
+	// An image built by a previous leader shouldn't be rebuilt.
+	syncImages(conn, dk)
+	images := getImages(conn)
+	assert.Len(t, images, 1)
+	assert.Equal(t, db.Built, images[0].Status)
+	assert.Equal(t, "localhost:5000/image@sha256:digest", images[0].RepoDigest)
+	assert.Empty(t, md.Built)
+}
+
 func TestUpdateRegistry(t *testing.T) {
 	md, dk := docker.NewMock()
 
-	_, err := updateRegistry(dk, "myIP", db.Image{
+	img := db.Image{
 		Name:       "mean:tag",
 		Dockerfile: "dockerfile",
-	})
+	}
+	_, err := updateRegistry(dk, img, nil, nil)
 	assert.NoError(t, err)
 
+	_, tag := registryRef(img)
 	assert.Equal(t, map[docker.BuildImageOptions]struct{}{
 		{
-			Name:       "myIP:5000/mean:tag",
+			Name:       "localhost:5000/mean:" + tag,
 			Dockerfile: "dockerfile",
 			NoCache:    true,
 		}: {},
@@ -74,13 +113,31 @@ func TestUpdateRegistry(t *testing.T) {
 
 	assert.Equal(t, map[dkc.PushImageOptions]struct{}{
 		{
-			Registry: "myIP:5000",
-			Name:     "myIP:5000/mean",
-			Tag:      "tag",
+			Registry: "localhost:5000",
+			Name:     "localhost:5000/mean",
+			Tag:      tag,
 		}: {},
 	}, md.Pushed)
 }
 
+func TestRegistryRef(t *testing.T) {
+	t.Parallel()
+
+	img := db.Image{Name: "mean:tag", Dockerfile: "dockerfile"}
+	repo, tag := registryRef(img)
+	assert.Equal(t, "mean", repo)
+	assert.True(t, strings.HasPrefix(tag, "kelda-"))
+
+	// The tag should be deterministic so that new leaders can find the images
+	// built by previous leaders.
+	_, sameTag := registryRef(img)
+	assert.Equal(t, tag, sameTag)
+
+	img.BuildArgs = map[string]string{"key": "val"}
+	_, changedTag := registryRef(img)
+	assert.NotEqual(t, tag, changedTag)
+}
+
 func TestGetImageHandle(t *testing.T) {
 	t.Parallel()
 
@@ -129,3 +186,88 @@ func getImages(conn db.Conn) (images []db.Image) {
 	})
 	return images
 }
+
+func TestSyncImagesRegistryCredentials(t *testing.T) {
+	defer mockManifestDigests(nil)()
+	md, dk := docker.NewMock()
+	conn := db.New()
+
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		bp := view.InsertBlueprint()
+		bp.RegistryCredentials = []blueprint.RegistryCredential{
+			{Server: "registry.example.com", Secret: "creds"},
+		}
+		view.Commit(bp)
+
+		im := view.InsertImage()
+		im.Name = "image"
+		im.Dockerfile = "FROM alpine"
+		view.Commit(im)
+
+		m := view.InsertMinion()
+		m.Self = true
+		m.PrivateIP = "myIP"
+		view.Commit(m)
+		return nil
+	})
+
+	secretClient := &mocks.SecretClient{}
+	secretClient.On("Get", "creds").Return("user:pass", nil)
+	newSecretClient = func() (kubernetes.SecretClient, error) {
+		return secretClient, nil
+	}
+	defer func() { newSecretClient = kubernetes.NewSecretClient }()
+
+	syncImages(conn, dk)
+	_, tag := registryRef(db.Image{Name: "image", Dockerfile: "FROM alpine"})
+	assert.Equal(t, map[string]dkc.AuthConfigurations{
+		"localhost:5000/image:" + tag: {Configs: map[string]dkc.AuthConfiguration{
+			"registry.example.com": {
+				Username:      "user",
+				Password:      "pass",
+				ServerAddress: "registry.example.com",
+			},
+		}},
+	}, md.BuildAuthConfigs)
+}
+
+func TestUpdateRegistryBuildInputs(t *testing.T) {
+	md, dk := docker.NewMock()
+
+	img := db.Image{
+		Name:       "mean:tag",
+		Dockerfile: "FROM alpine AS base\nFROM base",
+		BuildArgs:  map[string]string{"VERSION": "1.0"},
+		Target:     "base",
+	}
+	_, err := updateRegistry(dk, img, nil, nil)
+	assert.NoError(t, err)
+
+	_, tag := registryRef(img)
+	assert.Equal(t, map[docker.BuildImageOptions]struct{}{
+		{
+			Name:       "localhost:5000/mean:" + tag,
+			Dockerfile: "FROM alpine AS base\nFROM base",
+			Target:     "base",
+			NoCache:    true,
+		}: {},
+	}, md.Built)
+	assert.Equal(t, []dkc.BuildArg{{Name: "VERSION", Value: "1.0"}},
+		md.BuildArgs["localhost:5000/mean:"+tag])
+}
+
+func TestTailLines(t *testing.T) {
+	assert.Equal(t, "", tailLines("", 2))
+	assert.Equal(t, "a\nb", tailLines("a\nb\n", 2))
+	assert.Equal(t, "b\nc", tailLines("a\nb\nc", 2))
+}
+
+// mockManifestDigests mocks out the local registry so that it contains the
+// given images, keyed by "repo:tag". It returns a function that restores the
+// original implementation.
+func mockManifestDigests(digests map[string]string) func() {
+	getManifestDigest = func(registry, repo, tag string) (string, error) {
+		return digests[repo+":"+tag], nil
+	}
+	return func() { getManifestDigest = getManifestDigestImpl }
+}
@@ -0,0 +1,248 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/kelda/kelda/db"
+)
+
+// The media type of the manifests pushed by Docker.
+const manifestMediaType = "application/vnd.docker.distribution.manifest.v2+json"
+
+var registryHTTPClient = &http.Client{Timeout: 5 * time.Minute}
+
+// replicateImages copies the built images in the local registry into the
+// registries of the other masters, so that they don't have to be rebuilt if
+// the leader changes.
+func replicateImages(conn db.Conn) {
+	var images []db.Image
+	var etcdIPs []string
+	conn.Txn(db.ImageTable, db.EtcdTable).Run(func(view db.Database) error {
+		images = view.SelectFromImage(func(img db.Image) bool {
+			return img.Dockerfile != "" && img.Status == db.Built
+		})
+		if etcdRow, err := view.GetEtcd(); err == nil {
+			etcdIPs = etcdRow.EtcdIPs
+		}
+		return nil
+	})
+
+	myIP := conn.MinionSelf().PrivateIP
+	for _, ip := range etcdIPs {
+		if ip == myIP {
+			continue
+		}
+
+		dst := fmt.Sprintf("%s:%d", ip, registryPort)
+		for _, img := range images {
+			repo, tag := registryRef(img)
+			err := replicateImage(localRegistry, dst, repo, tag)
+			if err != nil {
+				log.WithError(err).WithFields(log.Fields{
+					"image":    img.Name,
+					"registry": dst,
+				}).Warn("Failed to replicate image")
+			}
+		}
+	}
+}
+
+// replicateImage copies the image at `repo:tag` from the `src` registry to the
+// `dst` registry, unless `dst` already has an identical copy.
+func replicateImage(src, dst, repo, tag string) error {
+	srcDigest, err := getManifestDigest(src, repo, tag)
+	if err != nil {
+		return err
+	}
+	if srcDigest == "" {
+		return fmt.Errorf("%s:%s not found in %s", repo, tag, src)
+	}
+
+	dstDigest, err := getManifestDigest(dst, repo, tag)
+	if err != nil {
+		return err
+	}
+	if dstDigest == srcDigest {
+		return nil
+	}
+
+	log.WithFields(log.Fields{
+		"image":    repo + ":" + tag,
+		"registry": dst,
+	}).Info("Replicating image")
+	return copyImage(src, dst, repo, tag)
+}
+
+// copyImage copies the manifest and blobs of `repo:tag` from the `src`
+// registry to the `dst` registry. The manifest is copied byte for byte, so the
+// image has the same digest in both registries.
+func copyImage(src, dst, repo, tag string) error {
+	manifest, contentType, err := getManifest(src, repo, tag)
+	if err != nil {
+		return err
+	}
+
+	var parsed struct {
+		Config struct {
+			Digest string `json:"digest"`
+		} `json:"config"`
+		Layers []struct {
+			Digest string `json:"digest"`
+		} `json:"layers"`
+	}
+	if err := json.Unmarshal(manifest, &parsed); err != nil {
+		return fmt.Errorf("parse manifest: %s", err)
+	}
+
+	blobs := []string{parsed.Config.Digest}
+	for _, layer := range parsed.Layers {
+		blobs = append(blobs, layer.Digest)
+	}
+
+	for _, digest := range blobs {
+		if err := copyBlob(src, dst, repo, digest); err != nil {
+			return fmt.Errorf("copy blob %s: %s", digest, err)
+		}
+	}
+
+	req, err := http.NewRequest("PUT", manifestURL(dst, repo, tag),
+		bytes.NewReader(manifest))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	_, err = doRegistryRequest(req, http.StatusCreated)
+	return err
+}
+
+// copyBlob copies the blob `digest` from the `src` registry to the `dst`
+// registry if `dst` doesn't already have it.
+func copyBlob(src, dst, repo, digest string) error {
+	blobURL := fmt.Sprintf("http://%s/v2/%s/blobs/%s", dst, repo, digest)
+	resp, err := registryHTTPClient.Head(blobURL)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	req, err := http.NewRequest("GET",
+		fmt.Sprintf("http://%s/v2/%s/blobs/%s", src, repo, digest), nil)
+	if err != nil {
+		return err
+	}
+	blob, err := doRegistryRequest(req, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	defer blob.Body.Close()
+
+	req, err = http.NewRequest("POST",
+		fmt.Sprintf("http://%s/v2/%s/blobs/uploads/", dst, repo), nil)
+	if err != nil {
+		return err
+	}
+	upload, err := doRegistryRequest(req, http.StatusAccepted)
+	if err != nil {
+		return err
+	}
+	upload.Body.Close()
+
+	// The upload location may be relative to the registry's address.
+	base, _ := url.Parse(fmt.Sprintf("http://%s/", dst))
+	location, err := base.Parse(upload.Header.Get("Location"))
+	if err != nil {
+		return fmt.Errorf("parse upload location: %s", err)
+	}
+	query := location.Query()
+	query.Set("digest", digest)
+	location.RawQuery = query.Encode()
+
+	req, err = http.NewRequest("PUT", location.String(), blob.Body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = blob.ContentLength
+	req.Header.Set("Content-Type", "application/octet-stream")
+	_, err = doRegistryRequest(req, http.StatusCreated)
+	return err
+}
+
+// getManifest returns the manifest of `repo:tag` in `registry`, and its
+// content type.
+func getManifest(registry, repo, tag string) ([]byte, string, error) {
+	req, err := http.NewRequest("GET", manifestURL(registry, repo, tag), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Accept", manifestMediaType)
+	resp, err := doRegistryRequest(req, http.StatusOK)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	manifest, err := ioutil.ReadAll(resp.Body)
+	return manifest, resp.Header.Get("Content-Type"), err
+}
+
+// Stored in a variable so that it can be mocked out by the unit tests.
+var getManifestDigest = getManifestDigestImpl
+
+// getManifestDigestImpl returns the digest of `repo:tag` in `registry`, or the
+// empty string if the registry doesn't have the image.
+func getManifestDigestImpl(registry, repo, tag string) (string, error) {
+	req, err := http.NewRequest("HEAD", manifestURL(registry, repo, tag), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", manifestMediaType)
+	resp, err := registryHTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Header.Get("Docker-Content-Digest"), nil
+	case http.StatusNotFound:
+		return "", nil
+	default:
+		return "", fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+}
+
+func manifestURL(registry, repo, tag string) string {
+	return fmt.Sprintf("http://%s/v2/%s/manifests/%s", registry, repo, tag)
+}
+
+// doRegistryRequest sends `req`, and returns an error if the response doesn't
+// have the expected status code. The caller must close the body of the
+// returned response.
+func doRegistryRequest(req *http.Request, expStatus int) (*http.Response,
+	error) {
+	resp, err := registryHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != expStatus {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s: unexpected status %s: %s",
+			req.Method, req.URL, resp.Status, bytes.TrimSpace(msg))
+	}
+	return resp, nil
+}
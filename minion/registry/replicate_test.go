@@ -0,0 +1,229 @@
+package registry
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplicateImage(t *testing.T) {
+	t.Parallel()
+
+	src := newFakeRegistry()
+	srcServer := httptest.NewServer(src)
+	defer srcServer.Close()
+
+	dst := newFakeRegistry()
+	dstServer := httptest.NewServer(dst)
+	defer dstServer.Close()
+
+	config := src.addBlob("config")
+	layer := src.addBlob("layer")
+	manifest := fmt.Sprintf(`{"schemaVersion":2,"config":{"digest":"%s"},`+
+		`"layers":[{"digest":"%s"}]}`, config, layer)
+	src.manifests["repo:tag"] = manifest
+
+	srcAddr := strings.TrimPrefix(srcServer.URL, "http://")
+	dstAddr := strings.TrimPrefix(dstServer.URL, "http://")
+
+	// Test copying an image to an empty registry.
+	assert.NoError(t, replicateImage(srcAddr, dstAddr, "repo", "tag"))
+	assert.Equal(t, src.blobs, dst.blobs)
+	assert.Equal(t, manifest, dst.manifests["repo:tag"],
+		"the manifest should be copied exactly so that the digest matches")
+
+	srcDigest, err := getManifestDigestImpl(srcAddr, "repo", "tag")
+	assert.NoError(t, err)
+	dstDigest, err := getManifestDigestImpl(dstAddr, "repo", "tag")
+	assert.NoError(t, err)
+	assert.Equal(t, srcDigest, dstDigest)
+	assert.NotEmpty(t, dstDigest)
+
+	// Test that nothing is uploaded if the image is already replicated.
+	dst.uploads = 0
+	assert.NoError(t, replicateImage(srcAddr, dstAddr, "repo", "tag"))
+	assert.Equal(t, 0, dst.uploads)
+
+	// Test that blobs the destination already has aren't uploaded again.
+	newLayer := src.addBlob("new layer")
+	src.manifests["repo:tag2"] = fmt.Sprintf(`{"schemaVersion":2,`+
+		`"config":{"digest":"%s"},"layers":[{"digest":"%s"},{"digest":"%s"}]}`,
+		config, layer, newLayer)
+	assert.NoError(t, replicateImage(srcAddr, dstAddr, "repo", "tag2"))
+	assert.Equal(t, 1, dst.uploads)
+	assert.Equal(t, src.manifests["repo:tag2"], dst.manifests["repo:tag2"])
+
+	// Test replicating an image that doesn't exist.
+	assert.Error(t, replicateImage(srcAddr, dstAddr, "repo", "missing"))
+}
+
+func TestGetManifestDigest(t *testing.T) {
+	t.Parallel()
+
+	reg := newFakeRegistry()
+	reg.manifests["repo:tag"] = "manifest"
+	server := httptest.NewServer(reg)
+	defer server.Close()
+	addr := strings.TrimPrefix(server.URL, "http://")
+
+	digest, err := getManifestDigestImpl(addr, "repo", "tag")
+	assert.NoError(t, err)
+	assert.Equal(t, sha256Digest("manifest"), digest)
+
+	digest, err = getManifestDigestImpl(addr, "repo", "missing")
+	assert.NoError(t, err)
+	assert.Empty(t, digest)
+
+	server.Close()
+	_, err = getManifestDigestImpl(addr, "repo", "tag")
+	assert.Error(t, err)
+}
+
+// fakeRegistry implements the subset of the Docker registry API used to
+// replicate, resolve, and prune images.
+type fakeRegistry struct {
+	sync.Mutex
+	blobs     map[string]string
+	manifests map[string]string
+	uploads   int
+
+	// If set, requests must be authenticated with a token obtained using
+	// these credentials.
+	username, password string
+}
+
+// The token issued by fakeRegistry.
+const fakeToken = "token"
+
+func newFakeRegistry() *fakeRegistry {
+	return &fakeRegistry{
+		blobs:     map[string]string{},
+		manifests: map[string]string{},
+	}
+}
+
+func (reg *fakeRegistry) addBlob(contents string) string {
+	digest := sha256Digest(contents)
+	reg.blobs[digest] = contents
+	return digest
+}
+
+func (reg *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	reg.Lock()
+	defer reg.Unlock()
+
+	if reg.username != "" {
+		if r.URL.Path == "/token" {
+			username, password, _ := r.BasicAuth()
+			if username != reg.username || password != reg.password {
+				http.Error(w, "bad credentials", http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprintf(w, `{"token": "%s"}`, fakeToken)
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer "+fakeToken {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+				`Bearer realm="http://%s/token",service="fake"`, r.Host))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+	}
+
+	if r.URL.Path == "/v2/_catalog" {
+		repos := map[string]struct{}{}
+		for key := range reg.manifests {
+			repos[strings.Split(key, ":")[0]] = struct{}{}
+		}
+		var catalog []string
+		for repo := range repos {
+			catalog = append(catalog, repo)
+		}
+		sort.Strings(catalog)
+		json.NewEncoder(w).Encode(map[string][]string{"repositories": catalog})
+		return
+	}
+
+	// Paths are of the form /v2/<repo>/<kind>/<reference>.
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/v2/"), "/")
+	if len(parts) < 3 {
+		http.NotFound(w, r)
+		return
+	}
+	repo, kind, ref := parts[0], parts[1], parts[2]
+
+	switch {
+	case kind == "manifests" && (r.Method == "GET" || r.Method == "HEAD"):
+		manifest, ok := reg.manifests[repo+":"+ref]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", manifestMediaType)
+		w.Header().Set("Docker-Content-Digest", sha256Digest(manifest))
+		w.Write([]byte(manifest))
+	case kind == "manifests" && r.Method == "DELETE":
+		var found bool
+		for key, manifest := range reg.manifests {
+			if strings.HasPrefix(key, repo+":") &&
+				sha256Digest(manifest) == ref {
+				delete(reg.manifests, key)
+				found = true
+			}
+		}
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	case kind == "tags" && ref == "list" && r.Method == "GET":
+		var tags []string
+		for key := range reg.manifests {
+			if strings.HasPrefix(key, repo+":") {
+				tags = append(tags, strings.TrimPrefix(key, repo+":"))
+			}
+		}
+		sort.Strings(tags)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"name": repo, "tags": tags})
+	case kind == "manifests" && r.Method == "PUT":
+		manifest, _ := ioutil.ReadAll(r.Body)
+		reg.manifests[repo+":"+ref] = string(manifest)
+		w.WriteHeader(http.StatusCreated)
+	case kind == "blobs" && ref == "uploads" && r.Method == "POST":
+		w.Header().Set("Location", fmt.Sprintf("/v2/%s/blobs/uploads/id", repo))
+		w.WriteHeader(http.StatusAccepted)
+	case kind == "blobs" && ref == "uploads" && r.Method == "PUT":
+		blob, _ := ioutil.ReadAll(r.Body)
+		if sha256Digest(string(blob)) != r.URL.Query().Get("digest") {
+			http.Error(w, "digest mismatch", http.StatusBadRequest)
+			return
+		}
+		reg.blobs[r.URL.Query().Get("digest")] = string(blob)
+		reg.uploads++
+		w.WriteHeader(http.StatusCreated)
+	case kind == "blobs" && (r.Method == "GET" || r.Method == "HEAD"):
+		blob, ok := reg.blobs[ref]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(blob))
+	default:
+		http.Error(w, "unsupported", http.StatusMethodNotAllowed)
+	}
+}
+
+func sha256Digest(contents string) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(contents)))
+}
@@ -0,0 +1,284 @@
+package registry
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	dkc "github.com/fsouza/go-dockerclient"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/db"
+)
+
+// How often the tags of images are checked for updates when the blueprint
+// watches image digests.
+const tagPollInterval = 5 * time.Minute
+
+// The manifest types that an image tag may refer to. Manifest lists are
+// preferred so that the digest matches the one Docker reports after pulling a
+// multi-architecture image.
+var resolveMediaTypes = strings.Join([]string{
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.index.v1+json",
+	manifestMediaType,
+	"application/vnd.oci.image.manifest.v1+json",
+}, ", ")
+
+const (
+	dockerHubRegistry = "registry-1.docker.io"
+	dockerHubIndex    = "https://index.docker.io/v1/"
+)
+
+// runResolver resolves the tags of the public images in the Image table to
+// digests. If the blueprint watches image digests, the tags are periodically
+// resolved again so that containers are redeployed when their tags are
+// updated.
+func runResolver(conn db.Conn) {
+	var lastPoll time.Time
+	for range conn.TriggerTick(60, db.ImageTable, db.BlueprintTable).C {
+		poll := time.Since(lastPoll) >= tagPollInterval
+		if resolveImages(conn, poll) {
+			lastPoll = time.Now()
+		}
+	}
+}
+
+// resolveImages resolves the tags of the public images that haven't been
+// resolved yet. If `poll` is true and the blueprint watches image digests, the
+// tags of resolved images are checked for updates as well. It returns whether
+// the resolved images were polled.
+func resolveImages(conn db.Conn, poll bool) bool {
+	var toResolve []db.Image
+	var registryCreds []blueprint.RegistryCredential
+	var watch bool
+	conn.Txn(db.ImageTable, db.BlueprintTable).Run(func(view db.Database) error {
+		bp, err := view.GetBlueprint()
+		if err != nil {
+			return err
+		}
+
+		watch = bp.ImageDigests == blueprint.WatchImageDigests
+		poll = poll && watch
+		registryCreds = bp.RegistryCredentials
+		toResolve = view.SelectFromImage(func(img db.Image) bool {
+			return img.Dockerfile == "" &&
+				(poll || img.Status != db.Resolved)
+		})
+		return nil
+	})
+
+	var auths map[string]dkc.AuthConfiguration
+	if len(toResolve) != 0 && len(registryCreds) != 0 {
+		auths = registryAuths(registryCreds)
+	}
+
+	for _, img := range toResolve {
+		digest, err := resolveDigest(img.Name, auths)
+		switch {
+		case err != nil && img.Status == db.Resolved:
+			// Keep running the previously resolved image rather than
+			// disrupting the containers.
+			log.WithError(err).WithField("image", img.Name).
+				Warn("Failed to check image for updates")
+			continue
+		case err != nil:
+			img.Status = db.ResolveFailed
+			img.BuildError = err.Error()
+			log.WithError(err).WithField("image", img.Name).
+				Error("Failed to resolve image digest")
+		case img.RepoDigest != digest:
+			if img.Status == db.Resolved {
+				log.WithFields(log.Fields{
+					"image":  img.Name,
+					"digest": digest,
+				}).Info("Image tag updated. Redeploying containers.")
+			}
+			img.RepoDigest = digest
+			img.Status = db.Resolved
+			img.BuildError = ""
+		default:
+			continue
+		}
+		writeImage(conn, img)
+	}
+	return poll && watch
+}
+
+// Stored in a variable so that it can be mocked out by the unit tests.
+var resolveDigest = resolveDigestImpl
+
+// resolveDigestImpl returns a reference to the image that `name` currently
+// refers to, pinned by its digest. For example, "nginx:1.15" might resolve to
+// "nginx@sha256:...".
+func resolveDigestImpl(name string, auths map[string]dkc.AuthConfiguration) (
+	string, error) {
+	registry, repo, tag := parseImageName(name)
+
+	scheme := "https"
+	if host := strings.Split(registry, ":")[0]; host == "localhost" ||
+		host == "127.0.0.1" {
+		scheme = "http"
+	}
+	manifestURL := fmt.Sprintf("%s://%s/v2/%s/manifests/%s", scheme, registry,
+		repo, tag)
+
+	auth, hasAuth := authForRegistry(auths, registry)
+	var authHeader string
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest("HEAD", manifestURL, nil)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Accept", resolveMediaTypes)
+		if authHeader != "" {
+			req.Header.Set("Authorization", authHeader)
+		}
+
+		resp, err := registryHTTPClient.Do(req)
+		if err != nil {
+			return "", err
+		}
+		resp.Body.Close()
+
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			digest := resp.Header.Get("Docker-Content-Digest")
+			if digest == "" {
+				return "", errors.New("registry did not return a digest")
+			}
+			repoName, _ := dkc.ParseRepositoryTag(name)
+			return repoName + "@" + digest, nil
+		case resp.StatusCode == http.StatusUnauthorized && attempt == 0:
+			authHeader, err = authenticate(
+				resp.Header.Get("WWW-Authenticate"), auth, hasAuth)
+			if err != nil {
+				return "", fmt.Errorf("authenticate: %s", err)
+			}
+		case resp.StatusCode == http.StatusNotFound:
+			return "", fmt.Errorf("%s not found", name)
+		default:
+			return "", fmt.Errorf("unexpected status: %s", resp.Status)
+		}
+	}
+}
+
+// parseImageName returns the registry, repository, and tag that `name` refers
+// to, following Docker's defaults.
+func parseImageName(name string) (registry, repo, tag string) {
+	repo, tag = dkc.ParseRepositoryTag(name)
+	if tag == "" {
+		tag = "latest"
+	}
+
+	registry = dockerHubRegistry
+	parts := strings.SplitN(repo, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") ||
+		parts[0] == "localhost") {
+		registry, repo = parts[0], parts[1]
+	}
+
+	// Docker Hub images can also be named by the hostnames of the Docker Hub
+	// index, but they're served by the same registry as bare names.
+	if registry == "docker.io" || registry == "index.docker.io" {
+		registry = dockerHubRegistry
+	}
+
+	if registry == dockerHubRegistry && !strings.Contains(repo, "/") {
+		repo = "library/" + repo
+	}
+	return registry, repo, tag
+}
+
+// authForRegistry returns the credentials for `registry`, if there are any.
+func authForRegistry(auths map[string]dkc.AuthConfiguration, registry string) (
+	dkc.AuthConfiguration, bool) {
+	if registry == dockerHubRegistry {
+		for _, server := range []string{dockerHubIndex, "docker.io",
+			"index.docker.io"} {
+			if auth, ok := auths[server]; ok {
+				return auth, true
+			}
+		}
+	}
+	auth, ok := auths[registry]
+	return auth, ok
+}
+
+var challengeParamRegex = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// authenticate returns the Authorization header that satisfies the registry's
+// `challenge`. Bearer challenges are answered by fetching a token from the
+// challenge's realm.
+func authenticate(challenge string, auth dkc.AuthConfiguration, hasAuth bool) (
+	string, error) {
+	scheme := strings.SplitN(challenge, " ", 2)[0]
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if !hasAuth {
+			return "", errors.New("registry requires credentials")
+		}
+		credentials := auth.Username + ":" + auth.Password
+		return "Basic " + base64.StdEncoding.EncodeToString(
+			[]byte(credentials)), nil
+	case "bearer":
+	default:
+		return "", fmt.Errorf("unsupported challenge: %q", challenge)
+	}
+
+	params := map[string]string{}
+	for _, match := range challengeParamRegex.FindAllStringSubmatch(
+		challenge, -1) {
+		params[match[1]] = match[2]
+	}
+	if params["realm"] == "" {
+		return "", errors.New("challenge has no realm")
+	}
+
+	tokenURL, err := url.Parse(params["realm"])
+	if err != nil {
+		return "", err
+	}
+	query := tokenURL.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequest("GET", tokenURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if hasAuth {
+		req.SetBasicAuth(auth.Username, auth.Password)
+	}
+	resp, err := doRegistryRequest(req, http.StatusOK)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("parse token: %s", err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	if token.Token == "" {
+		return "", errors.New("no token in response")
+	}
+	return "Bearer " + token.Token, nil
+}
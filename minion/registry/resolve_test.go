@@ -0,0 +1,192 @@
+package registry
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	dkc "github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kelda/kelda/blueprint"
+	"github.com/kelda/kelda/db"
+)
+
+func TestResolveDigest(t *testing.T) {
+	t.Parallel()
+
+	reg := newFakeRegistry()
+	reg.manifests["repo:1.0"] = "manifest"
+	reg.manifests["repo:latest"] = "latest manifest"
+	server := httptest.NewServer(reg)
+	defer server.Close()
+	addr := strings.TrimPrefix(server.URL, "http://")
+
+	digest, err := resolveDigestImpl(addr+"/repo:1.0", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, addr+"/repo@"+sha256Digest("manifest"), digest)
+
+	// Test that images without a tag resolve the latest tag.
+	digest, err = resolveDigestImpl(addr+"/repo", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, addr+"/repo@"+sha256Digest("latest manifest"), digest)
+
+	// Test that the digest changes when the tag is updated.
+	reg.Lock()
+	reg.manifests["repo:1.0"] = "updated manifest"
+	reg.Unlock()
+	digest, err = resolveDigestImpl(addr+"/repo:1.0", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, addr+"/repo@"+sha256Digest("updated manifest"), digest)
+
+	_, err = resolveDigestImpl(addr+"/repo:missing", nil)
+	assert.EqualError(t, err, addr+"/repo:missing not found")
+}
+
+func TestResolveDigestAuth(t *testing.T) {
+	t.Parallel()
+
+	reg := newFakeRegistry()
+	reg.manifests["repo:tag"] = "manifest"
+	reg.username = "user"
+	reg.password = "pass"
+	server := httptest.NewServer(reg)
+	defer server.Close()
+	addr := strings.TrimPrefix(server.URL, "http://")
+
+	digest, err := resolveDigestImpl(addr+"/repo:tag",
+		map[string]dkc.AuthConfiguration{
+			addr: {Username: "user", Password: "pass"},
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, addr+"/repo@"+sha256Digest("manifest"), digest)
+
+	_, err = resolveDigestImpl(addr+"/repo:tag", nil)
+	assert.Error(t, err)
+}
+
+func TestParseImageName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name, expRegistry, expRepo, expTag string
+	}{
+		{"nginx", "registry-1.docker.io", "library/nginx", "latest"},
+		{"nginx:1.15", "registry-1.docker.io", "library/nginx", "1.15"},
+		{"kelda/kelda:latest", "registry-1.docker.io", "kelda/kelda", "latest"},
+		{"docker.io/nginx", "registry-1.docker.io", "library/nginx", "latest"},
+		{"index.docker.io/kelda/kelda:1.0", "registry-1.docker.io",
+			"kelda/kelda", "1.0"},
+		{"quay.io/coreos/etcd:v3", "quay.io", "coreos/etcd", "v3"},
+		{"localhost:5000/app", "localhost:5000", "app", "latest"},
+	}
+	for _, test := range tests {
+		registry, repo, tag := parseImageName(test.name)
+		assert.Equal(t, test.expRegistry, registry, test.name)
+		assert.Equal(t, test.expRepo, repo, test.name)
+		assert.Equal(t, test.expTag, tag, test.name)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	t.Parallel()
+
+	auth := dkc.AuthConfiguration{Username: "user", Password: "pass"}
+	header, err := authenticate(`Basic realm="registry"`, auth, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "Basic dXNlcjpwYXNz", header)
+
+	_, err = authenticate(`Basic realm="registry"`, auth, false)
+	assert.Error(t, err)
+
+	_, err = authenticate(`Bearer service="registry"`, auth, true)
+	assert.EqualError(t, err, "challenge has no realm")
+
+	_, err = authenticate(`Digest realm="registry"`, auth, true)
+	assert.Error(t, err)
+}
+
+func TestResolveImages(t *testing.T) {
+	digests := map[string]string{"nginx": "nginx@sha256:1"}
+	var resolveErr error
+	resolveDigest = func(name string, _ map[string]dkc.AuthConfiguration) (
+		string, error) {
+		return digests[name], resolveErr
+	}
+	defer func() { resolveDigest = resolveDigestImpl }()
+
+	conn := db.New()
+	conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		bp := view.InsertBlueprint()
+		bp.ImageDigests = blueprint.PinImageDigests
+		view.Commit(bp)
+
+		im := view.InsertImage()
+		im.Name = "nginx"
+		view.Commit(im)
+
+		// Built images shouldn't be resolved.
+		im = view.InsertImage()
+		im.Name = "custom"
+		im.Dockerfile = "FROM nginx"
+		view.Commit(im)
+		return nil
+	})
+
+	assert.False(t, resolveImages(conn, true))
+	nginx := getImage(conn, "nginx")
+	assert.Equal(t, db.Resolved, nginx.Status)
+	assert.Equal(t, "nginx@sha256:1", nginx.RepoDigest)
+	assert.Empty(t, getImage(conn, "custom").Status)
+
+	// Pinned images shouldn't change when their tag is updated.
+	digests["nginx"] = "nginx@sha256:2"
+	assert.False(t, resolveImages(conn, true))
+	assert.Equal(t, "nginx@sha256:1", getImage(conn, "nginx").RepoDigest)
+
+	// Watched images should be updated when the tags are polled.
+	setImageDigests(conn, blueprint.WatchImageDigests)
+	assert.False(t, resolveImages(conn, false))
+	assert.Equal(t, "nginx@sha256:1", getImage(conn, "nginx").RepoDigest)
+
+	assert.True(t, resolveImages(conn, true))
+	assert.Equal(t, "nginx@sha256:2", getImage(conn, "nginx").RepoDigest)
+
+	// Failing to poll an image shouldn't affect the resolved digest.
+	resolveErr = errors.New("registry unavailable")
+	assert.True(t, resolveImages(conn, true))
+	nginx = getImage(conn, "nginx")
+	assert.Equal(t, db.Resolved, nginx.Status)
+	assert.Equal(t, "nginx@sha256:2", nginx.RepoDigest)
+
+	// Images that have never been resolved should report the error.
+	conn.Txn(db.ImageTable).Run(func(view db.Database) error {
+		im := view.InsertImage()
+		im.Name = "redis"
+		view.Commit(im)
+		return nil
+	})
+	resolveImages(conn, false)
+	redis := getImage(conn, "redis")
+	assert.Equal(t, db.ResolveFailed, redis.Status)
+	assert.Equal(t, "registry unavailable", redis.BuildError)
+}
+
+func getImage(conn db.Conn, name string) db.Image {
+	for _, img := range getImages(conn) {
+		if img.Name == name {
+			return img
+		}
+	}
+	return db.Image{}
+}
+
+func setImageDigests(conn db.Conn, imageDigests string) {
+	conn.Txn(db.BlueprintTable).Run(func(view db.Database) error {
+		bp, _ := view.GetBlueprint()
+		bp.ImageDigests = imageDigests
+		view.Commit(bp)
+		return nil
+	})
+}
@@ -15,9 +15,10 @@ import (
 	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/minion/docker"
 	"github.com/kelda/kelda/minion/etcd"
+	"github.com/kelda/kelda/minion/gc"
 	"github.com/kelda/kelda/minion/kubernetes"
+	"github.com/kelda/kelda/minion/metrics"
 	"github.com/kelda/kelda/minion/network"
-	"github.com/kelda/kelda/minion/pprofile"
 	"github.com/kelda/kelda/minion/registry"
 	"github.com/kelda/kelda/minion/supervisor"
 	"github.com/kelda/kelda/util"
@@ -29,9 +30,6 @@ var c = counter.New("Minion")
 
 // Run blocks executing the minion.
 func Run(role db.Role, inboundPubIntf, outboundPubIntf string) {
-	// XXX Uncomment the following line to run the profiler
-	//runProfiler(5 * time.Minute)
-
 	conn := db.New()
 	dk := docker.New("unix:///var/run/docker.sock")
 
@@ -58,7 +56,10 @@ func Run(role db.Role, inboundPubIntf, outboundPubIntf string) {
 	go network.Run(conn, inboundPubIntf, outboundPubIntf)
 	go registry.Run(conn, dk)
 	go etcd.Run(conn)
+	go metrics.Run(conn, dk)
+	go gc.Run(conn, dk)
 	go syncAuthorizedKeys(conn)
+	go serveMetrics(conn)
 
 	// Block until the credentials are in place on the local filesystem. We
 	// can't simply fail if the first read fails because the daemon might still
@@ -90,13 +91,20 @@ func Run(role db.Role, inboundPubIntf, outboundPubIntf string) {
 
 }
 
-func runProfiler(duration time.Duration) {
-	go func() {
-		p := pprofile.New("minion")
-		for {
-			if err := p.TimedRun(duration); err != nil {
-				log.Error(err)
-			}
+// serveMetrics serves the OpenMetrics endpoint on the minion's private IP so
+// that the counters aren't exposed on the public interface.
+func serveMetrics(conn db.Conn) {
+	var privateIP string
+	trigger := conn.TriggerTick(30, db.MinionTable)
+	for range trigger.C {
+		if privateIP = conn.MinionSelf().PrivateIP; privateIP != "" {
+			break
 		}
-	}()
+	}
+	trigger.Stop()
+
+	addr := fmt.Sprintf("%s:%d", privateIP, counter.MetricsPort)
+	if err := counter.ServeMetrics(addr); err != nil {
+		log.WithError(err).Error("Failed to serve metrics")
+	}
 }
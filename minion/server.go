@@ -39,6 +39,8 @@ func (s server) GetMinionConfig(cts context.Context,
 	cfg.Provider = m.Provider
 	cfg.Size = m.Size
 	cfg.Region = m.Region
+	cfg.Labels = m.Labels
+	cfg.Draining = m.Draining
 	cfg.AuthorizedKeys = strings.Split(m.AuthorizedKeys, "\n")
 
 	s.Txn(db.EtcdTable, db.BlueprintTable).Run(func(view db.Database) error {
@@ -54,6 +56,30 @@ func (s server) GetMinionConfig(cts context.Context,
 	return &cfg, nil
 }
 
+func (s server) GetMinionMetrics(ctx context.Context,
+	_ *pb.Request) (*pb.MinionMetrics, error) {
+
+	c.Inc("GetMinionMetrics")
+
+	var reply pb.MinionMetrics
+	for _, m := range s.SelectFromMetrics(nil) {
+		reply.Metrics = append(reply.Metrics, &pb.Metrics{
+			PodName:     m.PodName,
+			Time:        m.Time.UnixNano(),
+			CPUPercent:  m.CPUPercent,
+			MemoryUsed:  m.MemoryUsed,
+			MemoryTotal: m.MemoryTotal,
+			DiskUsed:    m.DiskUsed,
+			DiskTotal:   m.DiskTotal,
+			NetworkRx:   m.NetworkRx,
+			NetworkTx:   m.NetworkTx,
+			Custom:      m.Custom,
+			HasCustom:   m.HasCustom,
+		})
+	}
+	return &reply, nil
+}
+
 func (s server) SetMinionConfig(ctx context.Context,
 	msg *pb.MinionConfig) (*pb.Reply, error) {
 
@@ -79,6 +105,8 @@ func (s server) SetMinionConfig(ctx context.Context,
 		minion.Size = msg.Size
 		minion.Region = msg.Region
 		minion.FloatingIP = msg.FloatingIP
+		minion.Labels = msg.Labels
+		minion.Draining = msg.Draining
 		minion.AuthorizedKeys = strings.Join(msg.AuthorizedKeys, "\n")
 		minion.Self = true
 		view.Commit(minion)
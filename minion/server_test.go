@@ -37,6 +37,8 @@ func TestSetMinionConfig(t *testing.T) {
 		Region:         "region",
 		EtcdMembers:    []string{"etcd1", "etcd2"},
 		AuthorizedKeys: []string{"key1", "key2"},
+		Labels:         map[string]string{"tier": "db"},
+		Draining:       true,
 	}
 	expMinion := db.Minion{
 		ID:             1,
@@ -47,6 +49,8 @@ func TestSetMinionConfig(t *testing.T) {
 		Size:           "size",
 		Region:         "region",
 		AuthorizedKeys: "key1\nkey2",
+		Labels:         map[string]string{"tier": "db"},
+		Draining:       true,
 	}
 	_, err := s.SetMinionConfig(nil, &cfg)
 	assert.NoError(t, err)
@@ -149,6 +153,8 @@ func TestGetMinionConfig(t *testing.T) {
 		m.Size = "selfsize"
 		m.Region = "selfregion"
 		m.AuthorizedKeys = "key1\nkey2"
+		m.Labels = map[string]string{"tier": "db"}
+		m.Draining = true
 		view.Commit(m)
 
 		bpRow := view.InsertBlueprint()
@@ -180,6 +186,8 @@ func TestGetMinionConfig(t *testing.T) {
 		Size:           "selfsize",
 		Region:         "selfregion",
 		AuthorizedKeys: []string{"key1", "key2"},
+		Labels:         map[string]string{"tier": "db"},
+		Draining:       true,
 	}, *cfg)
 
 	// Test returning a full config.
@@ -200,5 +208,39 @@ func TestGetMinionConfig(t *testing.T) {
 		Region:         "selfregion",
 		EtcdMembers:    []string{"etcd1", "etcd2"},
 		AuthorizedKeys: []string{"key1", "key2"},
+		Labels:         map[string]string{"tier": "db"},
+		Draining:       true,
 	}, *cfg)
 }
+
+func TestGetMinionMetrics(t *testing.T) {
+	t.Parallel()
+	s := server{db.New()}
+
+	sampleTime := time.Unix(0, 100)
+	s.Conn.Txn(db.MetricsTable).Run(func(view db.Database) error {
+		m := view.InsertMetrics()
+		m.Time = sampleTime
+		m.CPUPercent = 50
+		m.DiskTotal = 10
+		view.Commit(m)
+
+		m = view.InsertMetrics()
+		m.PodName = "pod"
+		m.Time = sampleTime
+		m.MemoryUsed = 5
+		m.NetworkRx = 7
+		m.Custom = 12.5
+		m.HasCustom = true
+		view.Commit(m)
+		return nil
+	})
+
+	reply, err := s.GetMinionMetrics(nil, &pb.Request{})
+	assert.NoError(t, err)
+	assert.Equal(t, []*pb.Metrics{
+		{Time: 100, CPUPercent: 50, DiskTotal: 10},
+		{PodName: "pod", Time: 100, MemoryUsed: 5, NetworkRx: 7,
+			Custom: 12.5, HasCustom: true},
+	}, reply.Metrics)
+}
@@ -3,7 +3,9 @@ package supervisor
 import (
 	"fmt"
 	"strings"
+	"sync"
 
+	"github.com/kelda/kelda/blueprint"
 	cliPath "github.com/kelda/kelda/cli/path"
 	tlsIO "github.com/kelda/kelda/connection/tls/io"
 	"github.com/kelda/kelda/db"
@@ -15,13 +17,53 @@ import (
 
 const encryptionConfigPath = "/var/lib/kubernetes/encryption-config.yaml"
 
+// registryReadOnlyEnv configures whether the registry rejects writes.
+const registryReadOnlyEnv = "REGISTRY_STORAGE_MAINTENANCE_READONLY"
+
+var registryMode struct {
+	sync.Mutex
+	readOnly bool
+}
+
+var registryModeChanged = make(chan struct{}, 1)
+
+// SetRegistryReadOnly restarts the registry in read-only mode if `readOnly` is
+// true, and in read-write mode otherwise.
+func SetRegistryReadOnly(readOnly bool) {
+	registryMode.Lock()
+	registryMode.readOnly = readOnly
+	registryMode.Unlock()
+
+	select {
+	case registryModeChanged <- struct{}{}:
+	default:
+	}
+}
+
+// IsRegistryReadOnly returns whether `registry` was started in read-only mode.
+func IsRegistryReadOnly(registry docker.Container) bool {
+	return registry.Env[registryReadOnlyEnv] == registryReadOnlyConfig(true)
+}
+
+func registryReadOnly() bool {
+	registryMode.Lock()
+	defer registryMode.Unlock()
+	return registryMode.readOnly
+}
+
+func registryReadOnlyConfig(readOnly bool) string {
+	return fmt.Sprintf(`{"enabled": %t}`, readOnly)
+}
+
 func runMaster() {
 	go runMasterSystem()
 }
 
 func runMasterSystem() {
 	loopLog := util.NewEventTimer("Supervisor")
-	for range conn.TriggerTick(30, db.MinionTable, db.EtcdTable).C {
+	trig := util.JoinNotifiers(registryModeChanged, conn.TriggerTick(30,
+		db.MinionTable, db.EtcdTable, db.BlueprintTable).C)
+	for range trig {
 		loopLog.LogStart()
 		runMasterOnce()
 		loopLog.LogEnd()
@@ -47,6 +89,21 @@ func runMasterOnce() {
 		{
 			Name:  RegistryName,
 			Image: registryImage,
+			// Store the images in a named volume so that they're kept
+			// when the registry is restarted in or out of read-only mode.
+			Mounts: []dkc.HostMount{
+				{
+					Source: RegistryName,
+					Target: "/var/lib/registry",
+					Type:   "volume",
+				},
+			},
+			Env: map[string]string{
+				// Allow unused images to be deleted from the registry.
+				"REGISTRY_STORAGE_DELETE_ENABLED": "true",
+				registryReadOnlyEnv: registryReadOnlyConfig(
+					registryReadOnly()),
+			},
 		},
 	}
 
@@ -120,6 +177,12 @@ resources:
 			Args:        []string{"ovn-northd"},
 			VolumesFrom: []string{"minion"},
 		})
+
+		sink, _ := logSink()
+		if sink != nil && sink.Type == blueprint.FileSink {
+			desiredContainers = append(desiredContainers,
+				logReceiverContainer(sink.Path))
+		}
 	}
 	joinContainers(desiredContainers)
 }
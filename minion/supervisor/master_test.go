@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/kelda/kelda/blueprint"
 	cliPath "github.com/kelda/kelda/cli/path"
 	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/util"
@@ -12,6 +13,35 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+func TestRegistryReadOnly(t *testing.T) {
+	ctx := initTest()
+	ctx.conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		m := view.MinionSelf()
+		m.Role = db.Master
+		view.Commit(m)
+		return nil
+	})
+
+	registryReadOnly := func() bool {
+		registries, err := dk.List(map[string][]string{
+			"name": {RegistryName}}, false)
+		assert.NoError(t, err)
+		assert.Len(t, registries, 1)
+		return IsRegistryReadOnly(registries[0])
+	}
+
+	runMasterOnce()
+	assert.False(t, registryReadOnly())
+
+	SetRegistryReadOnly(true)
+	runMasterOnce()
+	assert.True(t, registryReadOnly())
+
+	SetRegistryReadOnly(false)
+	runMasterOnce()
+	assert.False(t, registryReadOnly())
+}
+
 func TestMaster(t *testing.T) {
 	util.AppFs = afero.NewMemMapFs()
 	ctx := initTest()
@@ -84,6 +114,48 @@ func TestMaster(t *testing.T) {
 	assert.Empty(t, ctx.execs)
 }
 
+func TestMasterLogReceiver(t *testing.T) {
+	ctx := initTest()
+	ip := "1.2.3.4"
+	ctx.conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		m := view.MinionSelf()
+		e := view.SelectFromEtcd(nil)[0]
+		m.Role = db.Master
+		m.PrivateIP = ip
+		e.EtcdIPs = []string{ip}
+		e.Leader = true
+		view.Commit(m)
+		view.Commit(e)
+
+		bp := view.InsertBlueprint()
+		bp.LogSink = &blueprint.LogSink{
+			Type: blueprint.FileSink,
+			Path: "/var/log/kelda/containers.log",
+		}
+		view.Commit(bp)
+		return nil
+	})
+	runMasterOnce()
+
+	assert.Equal(t, []string{"kelda", "log-collector", "-receive", "-path",
+		"/var/log/kelda/containers.log"}, ctx.fd.running()[LogCollectorName])
+
+	// Only file sinks need a receiver.
+	ctx.conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		bp, _ := view.GetBlueprint()
+		bp.LogSink = &blueprint.LogSink{
+			Type:    blueprint.HTTPSink,
+			Address: "http://logs",
+		}
+		view.Commit(bp)
+		return nil
+	})
+	runMasterOnce()
+
+	_, ok := ctx.fd.running()[LogCollectorName]
+	assert.False(t, ok)
+}
+
 func TestEtcdAdd(t *testing.T) {
 	ctx := initTest()
 	ip := "1.2.3.4"
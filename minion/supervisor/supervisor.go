@@ -4,8 +4,10 @@ import (
 	"crypto/sha1"
 	"fmt"
 	"os/exec"
+	"path/filepath"
 	"strings"
 
+	"github.com/kelda/kelda/blueprint"
 	"github.com/kelda/kelda/counter"
 	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/join"
@@ -13,6 +15,7 @@ import (
 	"github.com/kelda/kelda/util/str"
 	"github.com/kelda/kelda/version"
 
+	dkc "github.com/fsouza/go-dockerclient"
 	"github.com/satori/go.uuid"
 	log "github.com/sirupsen/logrus"
 )
@@ -50,6 +53,11 @@ const (
 
 	// KubeSchedulerName is the name of the Kubernetes scheduler container.
 	KubeSchedulerName = "kube-scheduler"
+
+	// LogCollectorName is the name of the container that ships container logs
+	// to the blueprint's log sink. On the leader, it's the name of the
+	// container that receives logs for file sinks.
+	LogCollectorName = "log-collector"
 )
 
 // The names of the images to be run. These are identifier that could be used
@@ -218,6 +226,60 @@ func filesHash(filepathToContent map[string]string) string {
 	return fmt.Sprintf("%x", sha1.Sum([]byte(toHash)))
 }
 
+// logSink returns the log sink configured in the blueprint, and the blueprint's
+// namespace. The returned sink is nil if logs shouldn't be shipped.
+func logSink() (*blueprint.LogSink, string) {
+	blueprints := conn.SelectFromBlueprint(nil)
+	if len(blueprints) != 1 {
+		return nil, ""
+	}
+	return blueprints[0].LogSink, blueprints[0].Namespace
+}
+
+// logCollectorContainer returns the container that ships the logs of the
+// containers on this machine to `sink`.
+func logCollectorContainer(sink blueprint.LogSink, machine, namespace,
+	leaderIP string) docker.RunOptions {
+	args := []string{"kelda", "log-collector",
+		"-type", sink.Type,
+		"-machine", machine,
+		"-namespace", namespace,
+	}
+	if sink.Type == blueprint.FileSink {
+		args = append(args, "-leader", leaderIP)
+	} else {
+		args = append(args, "-address", sink.Address)
+	}
+	if sink.Protocol != "" {
+		args = append(args, "-protocol", sink.Protocol)
+	}
+
+	return docker.RunOptions{
+		Name:        LogCollectorName,
+		Image:       kubeImage,
+		Args:        args,
+		VolumesFrom: []string{"minion"},
+	}
+}
+
+// logReceiverContainer returns the container that appends the logs received
+// from the workers to the file at `path` on this machine.
+func logReceiverContainer(path string) docker.RunOptions {
+	dir := filepath.Dir(path)
+	return docker.RunOptions{
+		Name:  LogCollectorName,
+		Image: kubeImage,
+		Args:  []string{"kelda", "log-collector", "-receive", "-path", path},
+		Mounts: []dkc.HostMount{
+			{
+				Source: dir,
+				Target: dir,
+				Type:   "bind",
+			},
+		},
+	}
+}
+
 // execRun() is a global variable so that it can be mocked out by the unit tests.
 var execRun = func(name string, arg ...string) ([]byte, error) {
 	c.Inc(name)
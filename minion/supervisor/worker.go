@@ -51,7 +51,8 @@ func setupWorker() {
 
 func runWorkerSystem() {
 	loopLog := util.NewEventTimer("Supervisor")
-	for range conn.TriggerTick(30, db.MinionTable, db.EtcdTable).C {
+	for range conn.TriggerTick(30, db.MinionTable, db.EtcdTable,
+		db.BlueprintTable).C {
 		loopLog.LogStart()
 		runWorkerOnce()
 		loopLog.LogEnd()
@@ -152,19 +153,32 @@ func runWorkerOnce() {
 					},
 				},
 				Args: kubeletArgs(minion.PrivateIP),
-				FilepathToContent: map[string]string{
-					"/var/lib/kubelet/kubeconfig": string(
-						kubeconfigBytes),
-				},
+				FilepathToContent: kubeletFiles(minion,
+					kubeconfigBytes),
 			})
 		} else {
 			log.WithError(err).Error("Failed to generate Kubeconfig")
 		}
+
+		if sink, namespace := logSink(); sink != nil {
+			desiredContainers = append(desiredContainers,
+				logCollectorContainer(*sink, minion.PrivateIP,
+					namespace, etcdRow.LeaderIP))
+		}
 	}
 
 	joinContainers(desiredContainers)
 }
 
+// kubeletFiles returns the files written into the Kubelet's container. Since
+// /var/lib/kubelet is mounted from the host, the runtime values that describe
+// the worker are written onto the host, where pods mount them from.
+func kubeletFiles(minion db.Minion, kubeconfig []byte) map[string]string {
+	files := kubernetes.HostValueFiles(minion.Provider, minion.Region)
+	files["/var/lib/kubelet/kubeconfig"] = string(kubeconfig)
+	return files
+}
+
 func kubeletArgs(myIP string) []string {
 	return []string{"kubelet",
 		"--pod-cidr=10.0.0.0/24",
@@ -178,6 +192,10 @@ func kubeletArgs(myIP string) []string {
 		"--tls-cert-file", tlsIO.SignedCertPath(cliPath.MinionTLSDir),
 		"--tls-private-key-file", tlsIO.SignedKeyPath(cliPath.MinionTLSDir),
 		"--allow-privileged",
+		// Allow containers to set the namespaced sysctls that Kubernetes
+		// doesn't consider safe by default.
+		"--experimental-allowed-unsafe-sysctls=" +
+			"kernel.msg*,kernel.sem,kernel.shm*,fs.mqueue.*,net.*",
 	}
 }
 
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/kelda/kelda/blueprint"
 	"github.com/kelda/kelda/db"
 	"github.com/kelda/kelda/minion/ipdef"
 	"github.com/kelda/kelda/minion/nl"
@@ -70,6 +71,65 @@ func TestWorker(t *testing.T) {
 	}, ctx.execs)
 }
 
+func TestWorkerLogCollector(t *testing.T) {
+	ctx := initTest()
+	ip := "1.2.3.4"
+	leaderIP := "5.6.7.8"
+	ctx.conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		m := view.MinionSelf()
+		e := view.SelectFromEtcd(nil)[0]
+		m.Role = db.Worker
+		m.PrivateIP = ip
+		e.EtcdIPs = []string{leaderIP}
+		e.LeaderIP = leaderIP
+		view.Commit(m)
+		view.Commit(e)
+
+		bp := view.InsertBlueprint()
+		bp.Namespace = "ns"
+		bp.LogSink = &blueprint.LogSink{
+			Type:     blueprint.SyslogSink,
+			Address:  "logs:514",
+			Protocol: "tcp",
+		}
+		view.Commit(bp)
+		return nil
+	})
+	runWorkerOnce()
+
+	assert.Equal(t, []string{"kelda", "log-collector", "-type", "syslog",
+		"-machine", ip, "-namespace", "ns", "-address", "logs:514",
+		"-protocol", "tcp"}, ctx.fd.running()[LogCollectorName])
+
+	// File sinks should be shipped to the leader.
+	ctx.conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		bp, _ := view.GetBlueprint()
+		bp.LogSink = &blueprint.LogSink{
+			Type: blueprint.FileSink,
+			Path: "/var/log/kelda.log",
+		}
+		view.Commit(bp)
+		return nil
+	})
+	runWorkerOnce()
+
+	assert.Equal(t, []string{"kelda", "log-collector", "-type", "file",
+		"-machine", ip, "-namespace", "ns", "-leader", leaderIP},
+		ctx.fd.running()[LogCollectorName])
+
+	// Removing the sink should stop the collector.
+	ctx.conn.Txn(db.AllTables...).Run(func(view db.Database) error {
+		bp, _ := view.GetBlueprint()
+		bp.LogSink = nil
+		view.Commit(bp)
+		return nil
+	})
+	runWorkerOnce()
+
+	_, ok := ctx.fd.running()[LogCollectorName]
+	assert.False(t, ok)
+}
+
 func TestSetupWorker(t *testing.T) {
 	ctx := initTest()
 
@@ -6,6 +6,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/kelda/kelda/counter"
+
 	log "github.com/sirupsen/logrus"
 )
 
@@ -28,6 +30,12 @@ func (f Formatter) Format(entry *log.Entry) ([]byte, error) {
 	return b.Bytes(), nil
 }
 
+// The durations of the events timed by EventTimers are recorded in histograms
+// named after the event, and the time each event last ended is recorded in a
+// gauge so that stuck loops can be detected.
+var durationCounter = counter.New("Event Duration Seconds")
+var lastEndCounter = counter.New("Event Last End Timestamp Seconds")
+
 // EventTimer is a utility struct that allows us to time how long loops take, as
 // well as how often they are triggered.
 type EventTimer struct {
@@ -55,6 +63,9 @@ func (ltl *EventTimer) LogStart() {
 // LogEnd logs the end of a loop and how long it took to run.
 func (ltl *EventTimer) LogEnd() {
 	ltl.lastEnd = time.Now()
-	log.Debugf("%s event ended. It took %v", ltl.eventName,
-		ltl.lastEnd.Sub(ltl.lastStart))
+	duration := ltl.lastEnd.Sub(ltl.lastStart)
+	log.Debugf("%s event ended. It took %v", ltl.eventName, duration)
+
+	durationCounter.Observe(ltl.eventName, duration.Seconds())
+	lastEndCounter.Set(ltl.eventName, float64(ltl.lastEnd.UnixNano())/1e9)
 }